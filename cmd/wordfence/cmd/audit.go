@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api/wporg"
+	"github.com/greysquirr3l/wordfence-go/internal/audit"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+var (
+	auditOutput       string
+	auditOutputFormat string
+	auditMySQLClient  string
+	auditRecentDays   int
+	auditMinSeverity  string
+	auditSkipDB       bool
+	auditSkipHarden   bool
+	auditSkipServer   bool
+	auditServerConfig []string
+	auditSkipTimes    bool
+	auditOffline      bool
+	auditRedact       bool
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [path]",
+	Short: "Audit a WordPress site's configuration and database for signs of compromise",
+	Long: `Audit a WordPress site for signs of compromise and weak configuration that
+file scanning misses.
+
+Hardening checks inspect the site on disk and report:
+
+  - risky wp-config.php settings (WP_DEBUG, DISALLOW_FILE_EDIT, FS_METHOD)
+  - world-writable directories and PHP files, and a readable wp-config.php
+  - backups, database dumps, logs and git repositories in the web root
+
+Server configuration checks analyze .htaccess, web.config, .user.ini and
+nginx files in the site (and any given with --server-config) for hidden
+redirects, cloaking rules, auto_prepend_file injection and handlers that
+execute non-PHP files as PHP.
+
+Timestamp checks flag files that look back-dated ("timestomped"): files whose
+modification time predates their inode change time while the rest of their
+directory changed earlier, and plugin files dated before the installed
+plugin version was released on wordpress.org.
+
+Database checks read credentials from the site's wp-config.php and run
+queries with the mysql command-line client. They report:
+
+  - administrator accounts, flagging recently created or suspicious ones
+  - tampered siteurl/home options and administrator self-registration
+  - active_plugins entries that are injected or missing from disk
+  - scheduled cron events not registered by core or an active plugin`,
+	Example: `  # Audit the site in the current directory
+  wordfence audit
+
+  # Audit a site and only show high and critical findings
+  wordfence audit --min-severity high /var/www/wordpress
+
+  # Only run the hardening checks, without connecting to the database
+  wordfence audit --skip-db /var/www/wordpress
+
+  # Include the site's nginx configuration
+  wordfence audit --server-config /etc/nginx/sites-enabled/example.conf /var/www/wordpress
+
+  # JSON output
+  wordfence audit --output-format json /var/www/wordpress`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "."
+		if len(args) == 1 {
+			path = args[0]
+		}
+		return runAudit(cmd.Context(), path)
+	},
+}
+
+func init() {
+	auditCmd.Flags().StringVarP(&auditOutput, "output", "o", "", "output file or s3:// or gs:// location (default: stdout)")
+	addCustodyFlags(auditCmd)
+	auditCmd.Flags().StringVar(&auditOutputFormat, "output-format", "human", "output format: csv, tsv, json, human")
+	auditCmd.Flags().StringVar(&auditMySQLClient, "mysql-client", audit.DefaultMySQLClient, "mysql command-line client binary")
+	auditCmd.Flags().IntVar(&auditRecentDays, "recent-days", 30, "report administrators created within this many days")
+	auditCmd.Flags().StringVar(&auditMinSeverity, "min-severity", "info", "minimum severity to report: info, low, medium, high, critical")
+	auditCmd.Flags().BoolVar(&auditSkipDB, "skip-db", false, "skip the database checks")
+	auditCmd.Flags().BoolVar(&auditSkipHarden, "skip-hardening", false, "skip the configuration, permission and exposed file checks")
+	auditCmd.Flags().BoolVar(&auditSkipServer, "skip-server-config", false, "skip the .htaccess, web.config and nginx checks")
+	auditCmd.Flags().StringSliceVar(&auditServerConfig, "server-config", nil, "additional web server config files to analyze (repeatable)")
+	auditCmd.Flags().BoolVar(&auditSkipTimes, "skip-timestamps", false, "skip the suspicious timestamp checks")
+	auditCmd.Flags().BoolVar(&auditOffline, "offline", false, "don't look up plugin release dates on wordpress.org")
+	auditCmd.Flags().BoolVar(&auditRedact, "redact", false, "mask passwords, API keys, tokens and email addresses, such as administrators' addresses, in findings")
+
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAudit(ctx context.Context, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+
+	configPath, err := wordpress.FindWPConfig(absPath)
+	if err != nil {
+		return err
+	}
+	wpConfig, err := wordpress.ParseWPConfig(configPath)
+	if err != nil {
+		return err
+	}
+	logging.Verbose("Using %s (database %s on %s)", configPath, wpConfig.DBName, wpConfig.DBHost)
+
+	contentPath := ""
+	var plugins []*wordpress.Plugin
+	var dbOpts []audit.DatabaseOption
+	dbOpts = append(dbOpts, audit.WithRecentAdminAge(time.Duration(auditRecentDays)*24*time.Hour))
+	if site, err := wordpress.Detect(absPath); err == nil {
+		contentPath = site.ContentPath
+		plugins = site.Plugins
+		dbOpts = append(dbOpts, audit.WithPluginsDir(site.PluginsPath))
+	}
+
+	var findings []*audit.Finding
+	if !auditSkipHarden {
+		logging.Info("Checking configuration hardening...")
+		findings = append(findings, audit.NewHardeningChecker(absPath, contentPath, wpConfig).Run()...)
+	}
+
+	if !auditSkipServer {
+		logging.Info("Analyzing web server configuration...")
+		serverFindings, err := audit.ScanServerConfigs(absPath)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, serverFindings...)
+
+		for _, path := range auditServerConfig {
+			fileFindings, err := audit.AnalyzeServerConfigFile(path)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, fileFindings...)
+		}
+	}
+
+	if !auditSkipTimes {
+		logging.Info("Checking file timestamps...")
+		var timeOpts []audit.TimestampOption
+		if !auditOffline {
+			timeOpts = append(timeOpts, audit.WithReleaseDates(wporgReleaseDates(wporg.NewClient(wporg.WithClientOptions(clientOpts...)))))
+		}
+		timeFindings, err := audit.NewTimestampChecker(absPath, plugins, timeOpts...).Run(ctx)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, timeFindings...)
+	}
+
+	if !auditSkipDB {
+		db := audit.NewMySQLClient(wpConfig, audit.WithMySQLBinary(auditMySQLClient))
+		auditor, err := audit.NewDatabaseAuditor(db, wpConfig, dbOpts...)
+		if err != nil {
+			return err
+		}
+
+		logging.Info("Auditing database %s...", wpConfig.DBName)
+		dbFindings, err := auditor.Run(ctx)
+		if err != nil {
+			return fmt.Errorf("database audit failed: %w", err)
+		}
+		findings = append(findings, dbFindings...)
+	}
+	audit.SortFindings(findings)
+	if auditRedact {
+		redactFindings(findings)
+	}
+
+	return writeAuditFindings(ctx, filterFindings(findings, audit.ParseSeverity(auditMinSeverity)))
+}
+
+// wporgReleaseDates looks up plugin release dates on wordpress.org,
+// querying each plugin once
+func wporgReleaseDates(client *wporg.Client) audit.ReleaseDateFunc {
+	infos := make(map[string]*wporg.ExtensionInfo)
+	return func(ctx context.Context, slug, version string) (time.Time, bool) {
+		info, ok := infos[slug]
+		if !ok {
+			var err error
+			info, err = client.PluginInfo(ctx, slug)
+			if err != nil {
+				logging.Debug("No wordpress.org release date for %s: %v", slug, err)
+			}
+			infos[slug] = info
+		}
+		if info == nil {
+			return time.Time{}, false
+		}
+		return info.ReleaseDate(version)
+	}
+}
+
+// filterFindings drops findings below min
+func filterFindings(findings []*audit.Finding, minSeverity audit.Severity) []*audit.Finding {
+	filtered := make([]*audit.Finding, 0, len(findings))
+	for _, f := range findings {
+		if f.Severity >= minSeverity {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
+// redactFindings masks secrets and email addresses in findings, which
+// can quote command lines, option values and administrator accounts.
+// Subjects that are paths are left alone, so they still name the file.
+func redactFindings(findings []*audit.Finding) {
+	for _, f := range findings {
+		if !filepath.IsAbs(f.Subject) {
+			f.Subject = scanner.RedactSecrets(f.Subject)
+		}
+		f.Message = scanner.RedactSecrets(f.Message)
+	}
+}
+
+// writeAuditFindings writes findings to the configured output
+func writeAuditFindings(ctx context.Context, findings []*audit.Finding) (err error) {
+	file, err := createOutput(auditOutput)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	output := file.File
+
+	switch auditOutputFormat {
+	case formatCSV, formatTSV:
+		w := csv.NewWriter(output)
+		if auditOutputFormat == formatTSV {
+			w.Comma = '\t'
+		}
+		_ = w.Write([]string{"severity", "check", "subject", "message"})
+		for _, f := range findings {
+			_ = w.Write([]string{f.Severity.String(), f.Check, f.Subject, f.Message})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("csv writer error: %w", err)
+		}
+	case formatJSON:
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return fmt.Errorf("json encode error: %w", err)
+		}
+	default:
+		writeHumanFindings(output, findings)
+	}
+
+	logging.Info("")
+	logging.Info("Audit complete: %d findings", len(findings))
+	return nil
+}
+
+// writeHumanFindings prints findings with colored severities
+func writeHumanFindings(output *os.File, findings []*audit.Finding) {
+	if len(findings) == 0 {
+		_, _ = fmt.Fprintln(output, color.GreenString("✓ No findings"))
+		return
+	}
+
+	for _, f := range findings {
+		label := severityColor(f.Severity).Sprintf("[%s]", f.Severity)
+		_, _ = fmt.Fprintf(output, "%s %s: %s\n", label, f.Subject, f.Message)
+	}
+}
+
+// severityColor returns the display color for a severity
+func severityColor(s audit.Severity) *color.Color {
+	switch s {
+	case audit.SeverityCritical:
+		return color.New(color.FgRed, color.Bold)
+	case audit.SeverityHigh:
+		return color.New(color.FgRed)
+	case audit.SeverityMedium:
+		return color.New(color.FgYellow)
+	case audit.SeverityLow:
+		return color.New(color.FgCyan)
+	default:
+		return color.New(color.Faint)
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	benchEngines         []string
+	benchRounds          int
+	benchMaxMB           int64
+	benchIncludeAllFiles bool
+	benchMatchTimeout    time.Duration
+	benchJSON            bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench <path>...",
+	Short: "Compare the regex engines on a corpus of files",
+	Long: `Match the malware signatures against a corpus of files with each regex
+engine and report throughput, how long the signatures took to compile, and
+how many signatures without common strings each engine gates with RE2 and
+how many fall back to regexp2 alone.
+
+Every signature is checked against every file, as with --match-all, and
+the corpus is read into memory first so disk speed doesn't skew results.`,
+	Example: `  # Compare all engines on a WordPress installation
+  wordfence bench /var/www/html
+
+  # Measure only the auto engine, five rounds, as JSON
+  wordfence bench --engine auto --rounds 5 --json /var/www/html`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBench(cmd.Context(), args)
+	},
+}
+
+func init() {
+	benchCmd.Flags().StringSliceVar(&benchEngines, "engine", nil, "engines to compare (default: all available)")
+	benchCmd.Flags().IntVar(&benchRounds, "rounds", 3, "times to match the corpus with each engine")
+	benchCmd.Flags().Int64Var(&benchMaxMB, "max-bytes", 256, "megabytes of files to read into the corpus (0 for no limit)")
+	benchCmd.Flags().BoolVar(&benchIncludeAllFiles, "include-all-files", false, "include all files, not only PHP, HTML and JS")
+	benchCmd.Flags().DurationVar(&benchMatchTimeout, "match-timeout", scanner.DefaultMatchTimeout, "time limit for one signature on one file")
+	benchCmd.Flags().BoolVar(&benchJSON, "json", false, "write results as JSON")
+
+	_ = benchCmd.RegisterFlagCompletionFunc("engine", cobra.FixedCompletions(scanner.RegexEngines(), cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(benchCmd)
+}
+
+func runBench(ctx context.Context, paths []string) error {
+	engines := benchEngines
+	if len(engines) == 0 {
+		engines = scanner.RegexEngines()
+	}
+	for _, engine := range engines {
+		if err := scanner.ValidateRegexEngine(engine); err != nil {
+			return err
+		}
+	}
+
+	sigSet, err := loadCachedSignatures(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := scanner.DefaultFilter()
+	if benchIncludeAllFiles {
+		filter = scanner.AllFilesFilter()
+	}
+	files, err := scanner.LoadBenchCorpus(paths, filter, benchMaxMB<<20)
+	if err != nil {
+		return fmt.Errorf("loading corpus: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files to benchmark in %v", paths)
+	}
+
+	results := make([]*scanner.BenchResult, 0, len(engines))
+	for _, engine := range engines {
+		logging.Debug("Benchmarking %s on %d files", engine, len(files))
+		result, err := scanner.Bench(ctx, sigSet, engine, files, benchRounds, scanner.WithMatchTimeout(benchMatchTimeout))
+		if err != nil {
+			return fmt.Errorf("benchmarking %s: %w", engine, err)
+		}
+		results = append(results, result)
+	}
+
+	if benchJSON {
+		return writeIndentedJSON(results)
+	}
+
+	first := results[0]
+	logging.Info("%d signatures, %d without common strings; %d files, %.1f MB, %d rounds",
+		first.Signatures, first.WithoutCommonStrings, first.Files, float64(first.Bytes)/(1<<20), first.Rounds)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ENGINE\tMB/S\tCOMPILE\tGATED\tFALLBACK\tFALLBACK %\tNATIVE SETS\tMATCHED\tTIMEOUTS")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(tw, "%s\t%.2f\t%s\t%d\t%d\t%.1f\t%d\t%d\t%d\n",
+			r.Engine,
+			r.MBPerSecond,
+			time.Duration(r.CompileSeconds*float64(time.Second)).Round(time.Millisecond),
+			r.Gated,
+			r.Fallback,
+			r.FallbackRate*100,
+			r.NativeSets,
+			r.FilesMatched,
+			r.Timeouts,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing results: %w", err)
+	}
+
+	for _, r := range results {
+		if len(r.FallbackReasons) == 0 {
+			continue
+		}
+		reasons := make([]string, 0, len(r.FallbackReasons))
+		for reason, count := range r.FallbackReasons {
+			reasons = append(reasons, fmt.Sprintf("%s %d", reason, count))
+		}
+		sort.Strings(reasons)
+		logging.Info("%s falls back for: %s", r.Engine, strings.Join(reasons, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	calibrateSave bool
+	calibrateName string
+	calibrateJSON bool
+)
+
+var calibrateCmd = &cobra.Command{
+	Use:   "calibrate [path]...",
+	Short: "Measure the host and recommend malware-scan settings",
+	Long: `Measure how fast this host can scan and recommend malware-scan settings
+for it, rather than guessing a worker count and memory limit.
+
+calibrate times one worker matching the cached signatures against a
+synthetic corpus of WordPress-like code with each regex engine, and reads
+the memory available to the process, including any cgroup limit. Given
+paths, it also reads a random sample of up to 32 MB of the files a scan
+of them would read, to time the disks, and estimates how long that scan
+would take with the recommended settings.
+
+It recommends the fastest engine (regexp2 when memory is short), enough
+workers to keep up with the disks without exceeding the CPUs, and a
+quarter of the available memory, at most 256 MiB, for --max-read-memory.
+The settings are printed as a [profile:NAME] section; --save writes the
+section to the config file, to be applied with --profile NAME.
+
+Files already in the page cache read faster than they would from disk, so
+calibrate on a cold cache, such as after a reboot, for the best estimate.`,
+	Example: `  # Measure the CPUs and memory only
+  wordfence calibrate
+
+  # Also time the disks holding the sites, and save the profile
+  wordfence calibrate --save /var/www
+
+  # Then scan with the calibrated settings
+  wordfence malware-scan --profile calibrated /var/www`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCalibrate(cmd.Context(), args)
+	},
+}
+
+func init() {
+	calibrateCmd.Flags().BoolVar(&calibrateSave, "save", false, "write the recommended settings to the config file as a profile")
+	calibrateCmd.Flags().StringVar(&calibrateName, "name", "calibrated", "name of the profile the settings are printed or saved as")
+	calibrateCmd.Flags().BoolVar(&calibrateJSON, "json", false, "write the measurements and recommendations as JSON")
+
+	rootCmd.AddCommand(calibrateCmd)
+}
+
+func runCalibrate(ctx context.Context, paths []string) error {
+	sigSet, err := loadCachedSignatures(ctx)
+	if err != nil {
+		return err
+	}
+	s := scanner.NewScanner(sigSet,
+		scanner.WithScanWorkers(runtime.NumCPU()),
+		scanner.WithScanRegexEngine(cfg.RegexEngine),
+		scanner.WithScanLogger(logging.GetDefaultLogger()),
+	)
+
+	var discovery *scanner.Discovery
+	if len(paths) > 0 {
+		logging.Info("Finding files in %v", paths)
+		if discovery, err = s.Discover(ctx, paths...); err != nil {
+			return fmt.Errorf("finding files: %w", err)
+		}
+	}
+	logging.Info("Timing the signatures on %d MB of synthetic code", scanner.DefaultCalibrationCorpusBytes>>20)
+	calibration, err := s.Calibrate(ctx, discovery, scanner.RegexEngines(), scanner.SyntheticCorpus(scanner.DefaultCalibrationCorpusBytes))
+	if err != nil {
+		return fmt.Errorf("calibrating: %w", err)
+	}
+
+	if calibrateSave {
+		path := cfg.ConfigFile
+		if path == "" {
+			path = config.DefaultConfigPath()
+		}
+		if err := config.SaveProfile(path, calibrateName, calibration.Recommended.Settings()); err != nil {
+			return err
+		}
+		logging.Info("Saved [%s%s] to %s; apply it with --profile %s", config.ProfilePrefix, calibrateName, path, calibrateName)
+	}
+	if calibrateJSON {
+		return writeIndentedJSON(calibration)
+	}
+	logCalibration(calibration)
+	settings := make(map[string]interface{})
+	for key, value := range calibration.Recommended.Settings() {
+		settings[key] = value
+	}
+	writeSection(config.ProfilePrefix+calibrateName, settings)
+	return nil
+}
+
+// logCalibration reports what calibrate measured
+func logCalibration(c *scanner.Calibration) {
+	logging.Info("CPUs: %d", c.CPUs)
+	if c.AvailableMemory > 0 {
+		logging.Info("Available memory: %d MiB", c.AvailableMemory>>20)
+	} else {
+		logging.Info("Available memory: unknown")
+	}
+	for _, engine := range scanner.RegexEngines() {
+		logging.Info("Matching with %s: %.2f MB/s per worker", engine, c.MatchMBPerSecond[engine])
+	}
+	if c.Reads != nil {
+		logging.Info("Reading: %.1f MB/s with %d readers (%d files, %.1f MB)",
+			c.Reads.BytesPerSecond/(1<<20), c.Reads.Readers, c.Reads.Files, float64(c.Reads.Bytes)/(1<<20))
+	}
+	if c.Estimate != nil && c.Estimate.BytesPerSecond > 0 {
+		logging.Info("Estimated scan duration: %v with %d workers", c.Estimate.Duration.Round(time.Second), c.Estimate.Workers)
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// outputFormats are the values of every --output-format flag
+var outputFormats = []string{formatHuman, formatCSV, formatTSV, formatJSON}
+
+// siemFormats are the further --output-format values of the scans
+var siemFormats = []string{formatCEF, formatLEEF}
+
+// registerCompletions completes flag values that cobra can't infer: the
+// profiles in the config file, regex engines, output formats, remediation
+// sources and modes, and the signature categories in the cached signature
+// set. It runs once every command has defined its flags.
+func registerCompletions(root *cobra.Command) {
+	_ = root.RegisterFlagCompletionFunc("profile", completeProfiles)
+	_ = root.RegisterFlagCompletionFunc("regex-engine", cobra.FixedCompletions(scanner.RegexEngines(), cobra.ShellCompDirectiveNoFileComp))
+
+	completions := map[string]cobra.CompletionFunc{
+		"output-format":      completeOutputFormats,
+		"remediation-source": cobra.FixedCompletions([]string{wordpress.RemediationSourceNOC1, wordpress.RemediationSourceWPOrg}, cobra.ShellCompDirectiveNoFileComp),
+		"remediate":          cobra.FixedCompletions([]string{remediateKnownFiles}, cobra.ShellCompDirectiveNoFileComp),
+		"order":              cobra.FixedCompletions(scanner.ScanOrders(), cobra.ShellCompDirectiveNoFileComp),
+		"categories":         completeCategories,
+		"category":           completeCategories,
+	}
+	var walk func(*cobra.Command)
+	walk = func(parent *cobra.Command) {
+		for _, cmd := range parent.Commands() {
+			for name, complete := range completions {
+				// remediate is also a bool on other commands
+				if flag := cmd.LocalFlags().Lookup(name); flag != nil && flag.Value.Type() != "bool" {
+					_ = cmd.RegisterFlagCompletionFunc(name, complete)
+				}
+			}
+			walk(cmd)
+		}
+	}
+	walk(root)
+}
+
+// completionConfig loads the config for completing a value, or returns
+// nil if it can't be loaded
+func completionConfig() *config.Config {
+	cfg, err := config.Load(cfgFile, "")
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// completeProfiles completes the profiles in the config file
+func completeProfiles(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	cfg := completionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cfg.Profiles(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOutputFormats completes --output-format, with the SIEM formats
+// on the scans that write them
+func completeOutputFormats(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if cmd == malwareScanCmd || cmd == vulnScanCmd {
+		return append(append([]string(nil), outputFormats...), siemFormats...), cobra.ShellCompDirectiveNoFileComp
+	}
+	return outputFormats, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCategories completes the categories of the cached signature set,
+// without fetching signatures. Categories already typed in a
+// comma-separated list are kept.
+func completeCategories(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := completionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	sigSet, err := intel.NewSignatureLoader(newSignatureCache(cfg)).Load(context.Background())
+	if err != nil || sigSet == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	typed := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		typed = toComplete[:i+1]
+	}
+	categories := sigSet.Categories()
+	for i, category := range categories {
+		categories[i] = typed + category
+	}
+	return categories, cobra.ShellCompDirectiveNoFileComp
+}
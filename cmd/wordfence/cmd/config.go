@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+var (
+	configValidateJSON bool
+	configShowRedact   bool
+	configShowJSON     bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check and show the configuration",
+	Long: `Check the configuration file for mistakes and show the settings in
+effect after the config file, WORDFENCE_CLI_* environment variables and
+flags are combined.`,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check a config file for unknown settings and invalid values",
+	Long: `Check a config file for unknown sections and settings, such as typos
+like cache_direcory, values of the wrong type or out of range, and settings
+that must be used together. Settings in command sections, such as
+[malware-scan], and profiles are checked against the commands' flags. It checks the file given, --config, or the
+file found in the default locations.
+
+Unknown settings are warnings, since they are ignored; invalid values are
+errors. The command fails if there are errors, or any problem at all with
+--strict-config.`,
+	Example: `  # Check the default config file
+  wordfence config validate
+
+  # Check a file before deploying it
+  wordfence config validate --strict-config ./wordfence-cli.ini`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := cfgFile
+		if len(args) > 0 {
+			path = args[0]
+		}
+		return runConfigValidate(cmd.Root(), path)
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the settings in effect",
+	Long: `Show the global settings in effect, the command settings from the
+config file's command sections and --profile, and the notification routes.`,
+	Example: `  # Show the settings without the license, e.g. to attach to a support request
+  wordfence config show --redact`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runConfigShow(cmd.Root())
+	},
+}
+
+func init() {
+	configValidateCmd.Flags().BoolVar(&configValidateJSON, "json", false, "write problems as JSON")
+	configShowCmd.Flags().BoolVar(&configShowRedact, "redact", false, "hide secrets such as the license and webhook URLs")
+	configShowCmd.Flags().BoolVar(&configShowJSON, "json", false, "write settings as JSON")
+
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigValidate(root *cobra.Command, path string) error {
+	if path == "" {
+		path = config.FindConfigFile()
+		if path == "" {
+			return fmt.Errorf("no config file found (default: %s)", config.DefaultConfigPath())
+		}
+	}
+
+	issues, err := config.ValidateFile(path, commandFlags(root))
+	if err != nil {
+		return err
+	}
+
+	var errs int
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errs++
+		}
+	}
+
+	if configValidateJSON {
+		if issues == nil {
+			issues = []*config.Issue{}
+		}
+		if err := writeIndentedJSON(struct {
+			File   string          `json:"file"`
+			Valid  bool            `json:"valid"`
+			Issues []*config.Issue `json:"issues"`
+		}{path, errs == 0 && (!strictConfig || len(issues) == 0), issues}); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			_, _ = fmt.Fprintf(os.Stdout, "%s: %s: %s\n", path, issue.Severity, issue)
+		}
+		if len(issues) == 0 {
+			logging.Info("%s: no problems found", path)
+		} else {
+			logging.Info("%d error(s), %d warning(s)", errs, len(issues)-errs)
+		}
+	}
+
+	if errs > 0 || (strictConfig && len(issues) > 0) {
+		return fmt.Errorf("%s is not valid", path)
+	}
+	return nil
+}
+
+func runConfigShow(root *cobra.Command) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	settings := cfg.Settings(configShowRedact)
+	commands := make(map[string]map[string]interface{})
+	for command, flags := range commandFlags(root) {
+		for name, value := range cfg.CommandSettings(command) {
+			if flags.Lookup(name) == nil {
+				continue
+			}
+			if commands[command] == nil {
+				commands[command] = make(map[string]interface{})
+			}
+			if configShowRedact && config.IsSecret(name) {
+				value = config.Redacted
+			}
+			commands[command][name] = value
+		}
+	}
+	notifications := make(map[string]map[string]interface{})
+	for route, values := range cfg.Notifications() {
+		notifications[route] = make(map[string]interface{}, len(values))
+		for name, value := range values {
+			if configShowRedact && config.IsSecret(name) {
+				value = config.Redacted
+			}
+			notifications[route][name] = value
+		}
+	}
+
+	if configShowJSON {
+		return writeIndentedJSON(struct {
+			File     string                            `json:"file,omitempty"`
+			Profile  string                            `json:"profile,omitempty"`
+			Settings map[string]interface{}            `json:"settings"`
+			Commands map[string]map[string]interface{} `json:"commands,omitempty"`
+			Notify   map[string]map[string]interface{} `json:"notifications,omitempty"`
+		}{cfg.ConfigFile, cfg.Profile, settings, commands, notifications})
+	}
+
+	source := cfg.ConfigFile
+	if source == "" {
+		source = "none"
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "# Config file: %s\n", source)
+	if cfg.Profile != "" {
+		_, _ = fmt.Fprintf(os.Stdout, "# Profile: %s\n", cfg.Profile)
+	}
+	writeSection("DEFAULT", settings)
+	names := make([]string, 0, len(commands))
+	for command := range commands {
+		names = append(names, command)
+	}
+	sort.Strings(names)
+	for _, command := range names {
+		writeSection(command, commands[command])
+	}
+	names = names[:0]
+	for route := range notifications {
+		names = append(names, route)
+	}
+	sort.Strings(names)
+	for _, route := range names {
+		writeSection(config.NotifyPrefix+route, notifications[route])
+	}
+	return nil
+}
+
+// writeSection writes settings as an INI section
+func writeSection(name string, settings map[string]interface{}) {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	_, _ = fmt.Fprintf(os.Stdout, "[%s]\n", name)
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(os.Stdout, "%s = %v\n", key, settings[key])
+	}
+}
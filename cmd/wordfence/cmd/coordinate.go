@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/daemon"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+// coordinateProgressInterval is how often worker progress is logged
+const coordinateProgressInterval = 30 * time.Second
+
+var (
+	coordinateWorkers      []string
+	coordinateShards       int
+	coordinatePollInterval time.Duration
+	coordinateTenant       string
+	coordinatePriority     string
+	coordinateJSON         bool
+)
+
+var coordinateCmd = &cobra.Command{
+	Use:   "coordinate [paths...]",
+	Short: "Split a malware scan across remote daemons",
+	Long: `Split a malware scan into shards and run them as jobs on remote scan
+daemons started with "wordfence daemon --listen".
+
+Each path is split into shards by a hash of the file paths, one per worker
+unless --shards is given. Workers must see the paths at the same locations,
+for example on shared storage. Progress is checked as the jobs run; shards
+from workers that stop responding, or whose jobs fail, are re-queued on
+the remaining workers. Matched files are printed as shards finish.
+
+Coordinator and workers authenticate each other with mutual TLS, using the
+certificate from --tls-cert and --tls-key and the CA bundle from --tls-ca.`,
+	Example: `  # Scan shared storage with three workers, in six shards
+  wordfence coordinate --workers scan1,scan2,scan3 --shards 6 /mnt/sites
+
+  # Workers listening on a non-default port
+  wordfence coordinate --workers 10.0.0.5:9000,10.0.0.6:9000 /mnt/sites`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCoordinate(cmd.Context(), args)
+	},
+}
+
+func init() {
+	coordinateCmd.Flags().StringSliceVar(&coordinateWorkers, "workers", nil, fmt.Sprintf("worker daemons as host or host:port (default port %d)", daemon.DefaultPort))
+	coordinateCmd.Flags().IntVar(&coordinateShards, "shards", 0, "shards to split each path into (default: one per worker)")
+	coordinateCmd.Flags().DurationVar(&coordinatePollInterval, "poll-interval", daemon.DefaultPollInterval, "how often to check on workers' jobs")
+	coordinateCmd.Flags().StringVar(&coordinateTenant, "tenant", "", "tenant the workers count the jobs against")
+	coordinateCmd.Flags().StringVar(&coordinatePriority, "priority", string(daemon.PriorityNormal), "job priority: low, normal or high")
+	coordinateCmd.Flags().BoolVar(&coordinateJSON, "json", false, "write matched files as JSON lines")
+	_ = coordinateCmd.MarkFlagRequired("workers")
+
+	rootCmd.AddCommand(coordinateCmd)
+}
+
+func runCoordinate(ctx context.Context, paths []string) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+	priority, err := daemon.ParsePriority(coordinatePriority)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := workerTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	var clients []*daemon.Client
+	for _, worker := range coordinateWorkers {
+		addr := daemon.WorkerAddress(worker)
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		clients = append(clients, daemon.NewRemoteClient(addr, tlsConfig))
+	}
+	if len(clients) == 0 {
+		return fmt.Errorf("no workers given")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := daemon.NewCoordinator(clients,
+		daemon.WithShards(coordinateShards),
+		daemon.WithPollInterval(coordinatePollInterval),
+		daemon.WithJobTenant(coordinateTenant),
+		daemon.WithJobPriority(priority),
+		daemon.WithCoordinatorLogger(logging.GetDefaultLogger()),
+	)
+
+	go logCoordinatorProgress(ctx, c)
+
+	matched := false
+	summary, runErr := c.Run(ctx, paths, func(resp *daemon.ScanResponse) {
+		matched = matched || resp.HasMatches()
+		if err := writeScanResponse(resp, coordinateJSON); err != nil {
+			logging.Warning("%v", err)
+		}
+	})
+	if summary != nil {
+		printCoordinatorSummary(summary)
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if matched {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// workerTLSConfig builds the mutual TLS settings shared by daemons
+// listening for coordinators and by coordinators: both present the
+// tls_cert certificate and verify the other side against tls_ca, so the
+// certificate must be valid for both client and server authentication
+func workerTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.TLSCert == "" || cfg.TLSKey == "" || cfg.TLSCA == "" {
+		return nil, fmt.Errorf("remote workers require --tls-cert, --tls-key and --tls-ca (or tls_cert, tls_key and tls_ca in the config)")
+	}
+	tlsConfig, err := api.LoadTLSConfig(config.ExpandPath(cfg.TLSCert), config.ExpandPath(cfg.TLSKey), config.ExpandPath(cfg.TLSCA))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings: %w", err)
+	}
+	tlsConfig.ClientCAs = tlsConfig.RootCAs
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
+
+// logCoordinatorProgress periodically logs each worker's progress
+func logCoordinatorProgress(ctx context.Context, c *daemon.Coordinator) {
+	ticker := time.NewTicker(coordinateProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, w := range c.Progress() {
+			switch {
+			case !w.Alive:
+				logging.Info("%s: failed", w.Address)
+			case w.Current != "":
+				logging.Info("%s: %d shards done, %d files scanned, scanning %s", w.Address, w.ShardsDone, w.FilesScanned, w.Current)
+			default:
+				logging.Info("%s: %d shards done, %d files scanned, idle", w.Address, w.ShardsDone, w.FilesScanned)
+			}
+		}
+	}
+}
+
+// printCoordinatorSummary logs the outcome of a coordinated scan
+func printCoordinatorSummary(summary *daemon.CoordinatorSummary) {
+	logging.Info("Scanned %d files in %s across %d workers: %d matched, %d errors",
+		summary.FilesScanned,
+		summary.Finished.Sub(summary.Started).Round(time.Second),
+		len(summary.Workers),
+		summary.FilesMatched,
+		summary.FilesErrored,
+	)
+	for _, w := range summary.Workers {
+		if w.Alive {
+			logging.Verbose("%s: %d shards, %d files scanned, %d matched", w.Address, w.ShardsDone, w.FilesScanned, w.FilesMatched)
+		} else {
+			logging.Warning("%s failed after %d shards: %s", w.Address, w.ShardsDone, w.Error)
+		}
+	}
+	for _, failed := range summary.Failed {
+		logging.Error("Not scanned: %s", failed)
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/custody"
+)
+
+var (
+	outputEncryptTo []string
+	outputSignWith  string
+)
+
+// addCustodyFlags adds --encrypt-to and --sign-with to a command writing
+// results to --output
+func addCustodyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&outputEncryptTo, "encrypt-to", nil,
+		"encrypt --output to an age recipient (age1... or ssh-...), a gpg key ID or email, or a file of recipients; repeatable")
+	cmd.Flags().StringVar(&outputSignWith, "sign-with", "",
+		"sign --output with an SSH private key file, or a gpg key ID")
+}
+
+// newSealer returns the sealer of --encrypt-to and --sign-with, or nil if
+// neither is set
+func newSealer() (*custody.Sealer, error) {
+	return custody.NewSealer(outputEncryptTo, outputSignWith)
+}
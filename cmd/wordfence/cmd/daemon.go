@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/cache"
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/daemon"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	daemonSocket      string
+	daemonSocketMode  uint32
+	daemonWorkers     int
+	daemonRefreshSigs time.Duration
+	daemonHashFeed    string
+	daemonIOCFeed     string
+	daemonRedact      bool
+	daemonJobsDir     string
+	daemonMaxJobs     int
+	daemonTenantJobs  int
+	daemonListen      string
+	daemonHealth      string
+	daemonMaxFeedAge  time.Duration
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run a long-lived scan daemon",
+	Long: `Run a long-lived scan daemon that keeps compiled malware signatures in
+memory and answers single-file scan requests on a unix socket.
+
+Clients such as "wordfence scan-file --fast" connect to the socket and
+get results without paying the cost of loading and compiling signatures.
+
+Directories can also be queued as scan jobs ("wordfence jobs submit").
+Jobs run in priority order and pause while single-file scans are answered,
+so upload checks are never slowed by background full scans. Queued jobs
+survive restarts.
+
+With --listen the daemon also accepts jobs over TCP from "wordfence
+coordinate", which splits large scans across several daemons. Connections
+use mutual TLS with the certificate from --tls-cert and --tls-key, and
+only clients with certificates signed by the --tls-ca bundle are served.
+
+With --health-listen the daemon answers Kubernetes probes and uptime
+monitors over plain HTTP: /healthz while it runs, and /readyz while its
+signatures are loaded and checked within --max-feed-age. Both report the
+age of the signature and vulnerability feeds, the last successful scan
+and the circuit breaker of each device read from, as JSON.`,
+	Example: `  # Start the daemon on the default socket
+  wordfence daemon
+
+  # Listen on a socket shared with the web server group
+  wordfence daemon --socket /run/wordfence/wordfence.sock --socket-mode 0660
+
+  # Take part in coordinated scans
+  wordfence daemon --listen :7390 --tls-cert node.pem --tls-key node.key --tls-ca fleet-ca.pem
+
+  # Answer health probes, unready once signatures go unchecked for two days
+  wordfence daemon --health-listen :8080 --max-feed-age 48h`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runDaemon(cmd.Context())
+	},
+}
+
+func init() {
+	daemonCmd.Flags().StringVar(&daemonSocket, "socket", daemon.DefaultSocketPath(), "unix socket path")
+	daemonCmd.Flags().Uint32Var(&daemonSocketMode, "socket-mode", 0660, "socket file permissions")
+	daemonCmd.Flags().IntVarP(&daemonWorkers, "workers", "w", 0, "maximum concurrent scans (default: NumCPU)")
+
+	daemonCmd.Flags().StringVar(&daemonHashFeed, "malware-hashes", "", "known-malware SHA256 blocklist file or http(s) feed URL")
+	daemonCmd.Flags().BoolVar(&daemonRedact, "redact", false, "mask passwords, API keys, tokens and email addresses in the matched text of results")
+	daemonCmd.Flags().StringVar(&daemonIOCFeed, "iocs", "", "report files referencing domains/IPs in this IOC list file or http(s) feed URL")
+	daemonCmd.Flags().DurationVar(&daemonRefreshSigs, "refresh-signatures", 6*time.Hour, "check for newer signatures at this interval (0 disables)")
+	daemonCmd.Flags().StringVar(&daemonJobsDir, "jobs-dir", config.DefaultJobsPath(), "directory of queued and finished scan jobs")
+	daemonCmd.Flags().IntVar(&daemonMaxJobs, "max-jobs", daemon.DefaultMaxJobs, "scan jobs run at once")
+	daemonCmd.Flags().IntVar(&daemonTenantJobs, "tenant-jobs", daemon.DefaultTenantJobs, "scan jobs run at once per tenant (0 is unlimited)")
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "", fmt.Sprintf("also accept jobs from coordinators on this TCP address, e.g. :%d (requires mutual TLS)", daemon.DefaultPort))
+	daemonCmd.Flags().StringVar(&daemonHealth, "health-listen", "", "answer /healthz and /readyz probes over HTTP on this TCP address, e.g. :8080")
+	daemonCmd.Flags().DurationVar(&daemonMaxFeedAge, "max-feed-age", 0, "fail readiness once signatures go unchecked this long, and report the vulnerability feed stale (0 never)")
+
+	addReportFlags(daemonCmd)
+
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(ctx context.Context) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+	if err := requireLicense(cfg); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The daemon reports heartbeats so the collector knows it is alive
+	reporter, err := newReporter(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeReporter(reporter)
+
+	sigSet, sigSource, err := loadScanSignatures(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	var hashes *intel.HashSet
+	if daemonHashFeed != "" {
+		hashes, err = loadMalwareHashes(ctx, cfg, daemonHashFeed)
+		if err != nil {
+			return err
+		}
+		logging.Info("Loaded %d known malware hashes", hashes.Count())
+	}
+
+	var iocs *intel.IOCSet
+	if daemonIOCFeed != "" {
+		iocs, err = loadIOCs(ctx, cfg, daemonIOCFeed)
+		if err != nil {
+			return err
+		}
+		logging.Info("Loaded %d indicators of compromise", iocs.Count())
+	}
+
+	workers := daemonWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	s := scanner.NewScanner(sigSet,
+		scanner.WithScanLogger(logging.GetDefaultLogger()),
+		scanner.WithMaxOpenFiles(workers),
+		scanner.WithHashSet(hashes),
+		scanner.WithIOCs(iocs),
+		scanner.WithRedaction(daemonRedact),
+		scanner.WithScanRegexEngine(cfg.RegexEngine),
+	)
+
+	if daemonRefreshSigs > 0 {
+		go s.RefreshSignatures(ctx, daemonRefreshSigs, sigSource.FetchNewer)
+	}
+
+	jobs, err := daemon.OpenJobQueue(daemonJobsDir,
+		daemon.WithMaxJobs(daemonMaxJobs),
+		daemon.WithTenantJobs(daemonTenantJobs),
+		daemon.WithJobLogger(logging.GetDefaultLogger()),
+	)
+	if err != nil {
+		return fmt.Errorf("opening job queue: %w", err)
+	}
+
+	opts := []daemon.ServerOption{
+		daemon.WithSocketMode(os.FileMode(daemonSocketMode)),
+		daemon.WithServerLogger(logging.GetDefaultLogger()),
+		daemon.WithJobQueue(jobs),
+	}
+	if daemonListen != "" {
+		tlsConfig, err := workerTLSConfig(cfg)
+		if err != nil {
+			return err
+		}
+		var lc net.ListenConfig
+		listener, err := lc.Listen(ctx, "tcp", daemonListen)
+		if err != nil {
+			return fmt.Errorf("listening on %s: %w", daemonListen, err)
+		}
+		opts = append(opts, daemon.WithListener(tls.NewListener(listener, tlsConfig)))
+	}
+	if daemonHealth != "" {
+		var lc net.ListenConfig
+		listener, err := lc.Listen(ctx, "tcp", daemonHealth)
+		if err != nil {
+			return fmt.Errorf("listening on %s: %w", daemonHealth, err)
+		}
+		feeds := newSignatureCache(cfg)
+		opts = append(opts,
+			daemon.WithHealthListener(listener),
+			daemon.WithMaxFeedAge(daemonMaxFeedAge),
+			daemon.WithVulnerabilityFeed(func() time.Time {
+				updated, _ := cache.StoredAt(feeds, vulnIndexCacheKey)
+				return updated
+			}),
+		)
+	}
+
+	srv := daemon.NewServer(s, daemonSocket, opts...)
+	if err := srv.Serve(ctx); err != nil {
+		return fmt.Errorf("daemon failed: %w", err)
+	}
+
+	logging.Info("Daemon stopped")
+	return nil
+}
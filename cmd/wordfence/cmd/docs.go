@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+)
+
+var docsManDir string
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate documentation",
+}
+
+var docsManCmd = &cobra.Command{
+	Use:   "man",
+	Short: "Generate man pages for every command",
+	Long: `Generate a man page in section 1 for wordfence and each of its commands,
+named like wordfence-malware-scan.1, from the commands' help.
+
+The pages are dated by SOURCE_DATE_EPOCH when it is set, so packages built
+from the same release have identical pages.`,
+	Example: `  # Install the man pages
+  wordfence docs man --dir /usr/local/share/man/man1`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runDocsMan(cmd.Root(), docsManDir)
+	},
+}
+
+func init() {
+	docsManCmd.Flags().StringVar(&docsManDir, "dir", "man", "directory to write the man pages to")
+
+	docsCmd.AddCommand(docsManCmd)
+	rootCmd.AddCommand(docsCmd)
+}
+
+func runDocsMan(root *cobra.Command, dir string) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating man page directory: %w", err)
+	}
+
+	date, err := manDate()
+	if err != nil {
+		return err
+	}
+
+	var count int
+	var walk func(*cobra.Command) error
+	walk = func(cmd *cobra.Command) error {
+		if !cmd.IsAvailableCommand() && cmd != root {
+			return nil
+		}
+		path := filepath.Join(dir, manName(cmd)+".1")
+		if err := os.WriteFile(path, manPage(cmd, date), 0o644); err != nil { // #nosec G306 -- man pages are world-readable
+			return fmt.Errorf("writing man page: %w", err)
+		}
+		count++
+		for _, child := range cmd.Commands() {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return err
+	}
+
+	logging.Info("Wrote %d man pages to %s", count, dir)
+	return nil
+}
+
+// manDate returns the date of the pages, from SOURCE_DATE_EPOCH for
+// reproducible builds
+func manDate() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// manName returns the page name of a command, such as
+// wordfence-remediate-rollback
+func manName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+// manPage renders a command's help as a roff man page
+func manPage(cmd *cobra.Command, date time.Time) []byte {
+	var buf bytes.Buffer
+	name := manName(cmd)
+
+	fmt.Fprintf(&buf, ".TH %q 1 %q %q %q\n", strings.ToUpper(name), date.Format("2006-01-02"),
+		"Wordfence CLI "+version.GetVersion(), "Wordfence CLI Manual")
+
+	buf.WriteString(".SH NAME\n")
+	fmt.Fprintf(&buf, "%s \\- %s\n", roffEscape(name), roffEscape(cmd.Short))
+
+	buf.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&buf, "\\fB%s\\fP\n", roffEscape(cmd.UseLine()))
+
+	buf.WriteString(".SH DESCRIPTION\n")
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	writeRoffText(&buf, description)
+
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		buf.WriteString(".SH OPTIONS\n")
+		writeRoffFlags(&buf, flags)
+	}
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		buf.WriteString(".SH OPTIONS INHERITED FROM PARENT COMMANDS\n")
+		writeRoffFlags(&buf, flags)
+	}
+
+	if cmd.Example != "" {
+		buf.WriteString(".SH EXAMPLE\n.PP\n.RS\n.nf\n")
+		for _, line := range strings.Split(strings.TrimRight(cmd.Example, "\n"), "\n") {
+			buf.WriteString(roffLine(line) + "\n")
+		}
+		buf.WriteString(".fi\n.RE\n")
+	}
+
+	var seeAlso []string
+	if cmd.HasParent() {
+		seeAlso = append(seeAlso, manName(cmd.Parent()))
+	}
+	for _, child := range cmd.Commands() {
+		if child.IsAvailableCommand() {
+			seeAlso = append(seeAlso, manName(child))
+		}
+	}
+	if len(seeAlso) > 0 {
+		sort.Strings(seeAlso)
+		buf.WriteString(".SH SEE ALSO\n")
+		for i, page := range seeAlso {
+			sep := ","
+			if i == len(seeAlso)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(&buf, ".BR %s (1)%s\n", roffEscape(page), sep)
+		}
+	}
+	return buf.Bytes()
+}
+
+// writeRoffText writes help text as paragraphs, keeping indented lines,
+// such as lists and examples, as they are
+func writeRoffText(buf *bytes.Buffer, text string) {
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		preformatted := strings.HasPrefix(paragraph, " ") || strings.Contains(paragraph, "\n ")
+		buf.WriteString(".PP\n")
+		if preformatted {
+			buf.WriteString(".nf\n")
+		}
+		for _, line := range strings.Split(paragraph, "\n") {
+			buf.WriteString(roffLine(line) + "\n")
+		}
+		if preformatted {
+			buf.WriteString(".fi\n")
+		}
+	}
+}
+
+func writeRoffFlags(buf *bytes.Buffer, flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Deprecated != "" {
+			return
+		}
+		buf.WriteString(".TP\n")
+		name := "\\fB\\-\\-" + roffEscape(flag.Name) + "\\fP"
+		if flag.Shorthand != "" && flag.ShorthandDeprecated == "" {
+			name = "\\fB\\-" + roffEscape(flag.Shorthand) + "\\fP, " + name
+		}
+		varname, usage := pflag.UnquoteUsage(flag)
+		if varname != "" {
+			name += " \\fI" + roffEscape(varname) + "\\fP"
+		}
+		buf.WriteString(name + "\n")
+		if flag.DefValue != "" && flag.DefValue != "false" && flag.DefValue != "[]" && flag.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %s)", flag.DefValue)
+		}
+		buf.WriteString(roffLine(usage) + "\n")
+	})
+}
+
+// roffLine escapes a line of text, protecting lines that roff would read
+// as requests
+func roffLine(line string) string {
+	line = roffEscape(line)
+	if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+		line = "\\&" + line
+	}
+	return line
+}
+
+func roffEscape(s string) string {
+	return strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/stix"
+	"github.com/greysquirr3l/wordfence-go/internal/triage"
+)
+
+var (
+	exportFrom   string
+	exportOutput string
+	exportNoHash bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Convert scan results for other tools",
+}
+
+var exportStixCmd = &cobra.Command{
+	Use:   "stix",
+	Short: "Convert malware-scan findings into a STIX 2.1 bundle",
+	Long: `Convert malware-scan JSON output (an array, or one result per line)
+into a STIX 2.1 bundle for a threat intelligence platform.
+
+Each infected file becomes an Indicator and each signature that matched
+it a Malware object, linked by an "indicates" relationship. Indicators
+match the file's SHA-256 hash, read from the file where it was found, so
+copies on other sites and hosts are recognized; files that are gone,
+remediated or skipped with --no-hash are matched by path instead.
+Persistence findings and remediation records are not exported.
+
+Object IDs are derived from the signature and the hash or path, so
+exporting the same findings again updates the objects a platform already
+has instead of duplicating them.`,
+	Example: `  # Scan, then export the findings for the TIP
+  wordfence malware-scan --output-format json --output results.ndjson /var/www
+  wordfence export stix --from results.ndjson --output indicators.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runExportStix(cmd.Context())
+	},
+}
+
+func init() {
+	exportStixCmd.Flags().StringVar(&exportFrom, "from", "", "malware-scan JSON results to convert (- for stdin)")
+	exportStixCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "output file or s3:// or gs:// location (default: stdout)")
+	addCustodyFlags(exportStixCmd)
+	exportStixCmd.Flags().BoolVar(&exportNoHash, "no-hash", false, "match files by path without reading them")
+	_ = exportStixCmd.MarkFlagRequired("from")
+
+	exportCmd.AddCommand(exportStixCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExportStix(ctx context.Context) (err error) {
+	findings, err := loadExportFindings(exportFrom)
+	if err != nil {
+		return err
+	}
+
+	builder := stix.NewBuilder(time.Now())
+	for _, f := range findings {
+		builder.Add(stixFile(f))
+	}
+	bundle, err := builder.Bundle()
+	if err != nil {
+		return err
+	}
+
+	output, err := createOutput(exportOutput)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := output.Close(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bundle); err != nil {
+		return fmt.Errorf("writing STIX bundle: %w", err)
+	}
+	logging.Info("Exported %d indicators from %d files", builder.Len(), len(findings))
+	return nil
+}
+
+// loadExportFindings reads malware-scan results from path, or stdin for -
+func loadExportFindings(path string) ([]*triage.Finding, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path) // #nosec G304 -- results file named by the user
+		if err != nil {
+			return nil, fmt.Errorf("opening results: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+		r = file
+	}
+	return triage.LoadFindings(r)
+}
+
+// stixFile is a finding's signature matches, with the file's hash unless
+// it has been remediated since or --no-hash is set
+func stixFile(f *triage.Finding) *stix.File {
+	file := &stix.File{Path: f.Path, Owner: f.Owner, Domain: f.Domain}
+	for _, m := range f.Matches {
+		if m.Audit {
+			continue
+		}
+		file.Signatures = append(file.Signatures, stix.Signature{
+			ID:          m.SignatureID,
+			Name:        m.Name,
+			Description: m.Description,
+			Category:    m.Category,
+		})
+	}
+	if len(file.Signatures) == 0 || exportNoHash || f.Remediation != "" {
+		return file
+	}
+	hash, err := scanner.HashFile(f.Path)
+	if err != nil {
+		logging.Debug("Matching %s by path: %v", f.Path, err)
+		return file
+	}
+	file.SHA256 = hash
+	return file
+}
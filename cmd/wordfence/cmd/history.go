@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	historyDir  string
+	historyJSON bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List and compare recorded scans",
+	Long: `List and compare the scans recorded by malware-scan and vuln-scan.
+
+Every completed scan is recorded with its statistics, the files that
+matched and the vulnerable components found, unless --no-history is
+given. Scans are named by ID, a unique ID prefix, "latest" or "previous".`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded scans",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return runHistoryList()
+	},
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff <scan-a> <scan-b>",
+	Short: "Show what changed between two scans",
+	Long: `Show files that became infected or were remediated, and components that
+became vulnerable or were fixed, between an earlier and a later scan.`,
+	Example: `  # Compare the last two scans
+  wordfence history diff previous latest
+
+  # Compare two scans by ID prefix
+  wordfence history diff 20250301-1000 20250308-1000`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return runHistoryDiff(args[0], args[1])
+	},
+}
+
+func init() {
+	historyCmd.PersistentFlags().StringVar(&historyDir, "history", config.DefaultHistoryPath(), "directory of recorded scans")
+	historyCmd.PersistentFlags().BoolVar(&historyJSON, "json", false, "write output as JSON")
+
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyDiffCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistoryList() error {
+	store := scanner.OpenHistory(historyDir)
+	records, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if historyJSON {
+		// Findings are left to "history diff"; list only the statistics
+		type listEntry struct {
+			ID              string    `json:"id"`
+			Kind            string    `json:"kind"`
+			Started         time.Time `json:"started"`
+			Finished        time.Time `json:"finished"`
+			Paths           []string  `json:"paths"`
+			FilesScanned    int64     `json:"files_scanned"`
+			FilesMatched    int64     `json:"files_matched"`
+			Matches         int       `json:"matches"`
+			Vulnerabilities int       `json:"vulnerabilities"`
+			Digest          string    `json:"digest"`
+		}
+		entries := make([]listEntry, 0, len(records))
+		for _, r := range records {
+			entries = append(entries, listEntry{
+				ID: r.ID, Kind: r.Kind, Paths: r.Paths, Digest: r.Digest,
+				Started: r.Started, Finished: r.Finished,
+				FilesScanned: r.FilesScanned, FilesMatched: r.FilesMatched,
+				Matches: r.Matches, Vulnerabilities: r.Vulnerabilities,
+			})
+		}
+		return writeIndentedJSON(entries)
+	}
+
+	if len(records) == 0 {
+		logging.Info("No scans recorded in %s", store.Dir())
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tKIND\tSTARTED\tDURATION\tFINDINGS\tDIGEST\tPATHS")
+	for _, r := range records {
+		findings := fmt.Sprintf("%d files", r.FilesMatched)
+		if r.Kind == scanner.ScanKindVuln {
+			findings = fmt.Sprintf("%d vulns", r.Vulnerabilities)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.ID,
+			r.Kind,
+			r.Started.Local().Format("2006-01-02 15:04"),
+			r.Finished.Sub(r.Started).Round(time.Second),
+			findings,
+			shortHash(r.Digest),
+			strings.Join(r.Paths, ", "),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return nil
+}
+
+func runHistoryDiff(fromID, toID string) error {
+	store := scanner.OpenHistory(historyDir)
+	from, err := store.Load(fromID)
+	if err != nil {
+		return err
+	}
+	to, err := store.Load(toID)
+	if err != nil {
+		return err
+	}
+
+	diff, err := scanner.DiffScans(from, to)
+	if err != nil {
+		return err
+	}
+
+	if historyJSON {
+		return writeIndentedJSON(struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+			*scanner.HistoryDiff
+		}{from.ID, to.ID, diff})
+	}
+
+	bold := color.New(color.Bold)
+	red := color.New(color.FgRed)
+	green := color.New(color.FgGreen)
+
+	_, _ = bold.Printf("Comparing %s (%s) with %s (%s)\n",
+		from.ID, from.Started.Local().Format("2006-01-02 15:04"),
+		to.ID, to.Started.Local().Format("2006-01-02 15:04"))
+	if diff.Unchanged() {
+		_, _ = green.Println("✓ No changes")
+		return nil
+	}
+
+	if len(diff.NewlyInfected) > 0 {
+		_, _ = bold.Printf("\n=== NEWLY INFECTED (%d) ===\n", len(diff.NewlyInfected))
+		for _, f := range diff.NewlyInfected {
+			_, _ = red.Print("+ ")
+			fmt.Printf("%s [%s]\n", f.Path, strings.Join(f.Signatures, ", "))
+		}
+	}
+	if len(diff.Remediated) > 0 {
+		_, _ = bold.Printf("\n=== REMEDIATED (%d) ===\n", len(diff.Remediated))
+		for _, f := range diff.Remediated {
+			_, _ = green.Print("- ")
+			fmt.Println(f.Path)
+		}
+	}
+	if len(diff.NewlyVulnerable) > 0 {
+		_, _ = bold.Printf("\n=== NEWLY VULNERABLE (%d) ===\n", len(diff.NewlyVulnerable))
+		for _, c := range diff.NewlyVulnerable {
+			_, _ = red.Print("+ ")
+			fmt.Printf("%s %s %s (%d vulnerabilities) %s\n", c.Type, c.Slug, c.Version, len(c.Vulnerabilities), c.Path)
+		}
+	}
+	if len(diff.Fixed) > 0 {
+		_, _ = bold.Printf("\n=== NO LONGER VULNERABLE (%d) ===\n", len(diff.Fixed))
+		for _, c := range diff.Fixed {
+			_, _ = green.Print("- ")
+			fmt.Printf("%s %s %s %s\n", c.Type, c.Slug, c.Version, c.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	ignoreSuppressions string
+	ignoreSignature    int
+	ignoreReason       string
+	ignoreJSON         bool
+)
+
+var ignoreCmd = &cobra.Command{
+	Use:   "ignore",
+	Short: "Manage suppressed malware matches",
+	Long: `Manage the suppression list used by malware-scan.
+
+A suppression hides matches for a file whose contents are known to be
+benign, such as a security plugin that contains signature-like strings.
+Each suppression records the file's SHA256, so it stops applying as soon
+as the file changes.`,
+}
+
+var ignoreAddCmd = &cobra.Command{
+	Use:   "add <file>...",
+	Short: "Suppress matches for the current contents of files",
+	Example: `  # Suppress signature 123 for a file
+  wordfence ignore add --signature 123 --reason "Wordfence plugin" wp-content/plugins/wordfence/lib/wfScan.php
+
+  # Suppress every signature for a file
+  wordfence ignore add wp-content/plugins/example/scanner.php`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return runIgnoreAdd(args)
+	},
+}
+
+var ignoreRemoveCmd = &cobra.Command{
+	Use:   "remove <file>...",
+	Short: "Remove suppressions for files",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return runIgnoreRemove(args)
+	},
+}
+
+var ignoreListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List suppressions",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return runIgnoreList()
+	},
+}
+
+func init() {
+	ignoreCmd.PersistentFlags().StringVar(&ignoreSuppressions, "suppressions", config.DefaultSuppressionsPath(), "suppression store path")
+	ignoreAddCmd.Flags().IntVar(&ignoreSignature, "signature", scanner.AnySignature, "signature ID to suppress (default: all)")
+	ignoreAddCmd.Flags().StringVar(&ignoreReason, "reason", "", "why the match is benign")
+	ignoreRemoveCmd.Flags().IntVar(&ignoreSignature, "signature", scanner.AnySignature, "signature ID to remove (default: all)")
+	ignoreListCmd.Flags().BoolVar(&ignoreJSON, "json", false, "write suppressions as JSON")
+
+	ignoreCmd.AddCommand(ignoreAddCmd)
+	ignoreCmd.AddCommand(ignoreRemoveCmd)
+	ignoreCmd.AddCommand(ignoreListCmd)
+	rootCmd.AddCommand(ignoreCmd)
+}
+
+func runIgnoreAdd(paths []string) error {
+	store, err := scanner.LoadSuppressions(ignoreSuppressions)
+	if err != nil {
+		return fmt.Errorf("loading suppressions: %w", err)
+	}
+
+	for _, path := range paths {
+		sup, err := store.Add(path, ignoreSignature, ignoreReason)
+		if err != nil {
+			return fmt.Errorf("suppressing %s: %w", path, err)
+		}
+		logging.Info("Suppressed %s for %s (sha256 %s)", describeSuppressedSignature(sup.SignatureID), sup.Path, shortHash(sup.SHA256))
+	}
+
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("saving suppressions: %w", err)
+	}
+	return nil
+}
+
+func runIgnoreRemove(paths []string) error {
+	store, err := scanner.LoadSuppressions(ignoreSuppressions)
+	if err != nil {
+		return fmt.Errorf("loading suppressions: %w", err)
+	}
+
+	total := 0
+	for _, path := range paths {
+		removed, err := store.Remove(path, ignoreSignature)
+		if err != nil {
+			return fmt.Errorf("removing suppression for %s: %w", path, err)
+		}
+		if removed == 0 {
+			logging.Warning("No suppression found for %s", path)
+		}
+		total += removed
+	}
+
+	if total == 0 {
+		return nil
+	}
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("saving suppressions: %w", err)
+	}
+	logging.Info("Removed %d suppression(s)", total)
+	return nil
+}
+
+func runIgnoreList() error {
+	store, err := scanner.LoadSuppressions(ignoreSuppressions)
+	if err != nil {
+		return fmt.Errorf("loading suppressions: %w", err)
+	}
+
+	list := store.List()
+	if ignoreJSON {
+		return writeIndentedJSON(list)
+	}
+
+	if len(list) == 0 {
+		logging.Info("No suppressions in %s", store.Path())
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PATH\tSIGNATURE\tSHA256\tADDED\tREASON")
+	for _, sup := range list {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			sup.Path,
+			describeSuppressedSignature(sup.SignatureID),
+			shortHash(sup.SHA256),
+			sup.Added.Format("2006-01-02"),
+			sup.Reason,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing suppressions: %w", err)
+	}
+	return nil
+}
+
+// describeSuppressedSignature formats a suppression's signature for display
+func describeSuppressedSignature(id int) string {
+	if id == scanner.AnySignature {
+		return "all signatures"
+	}
+	return fmt.Sprintf("signature %d", id)
+}
+
+// shortHash abbreviates a hex digest for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
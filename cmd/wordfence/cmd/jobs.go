@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/daemon"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+var (
+	jobsSocket   string
+	jobsJSON     bool
+	jobsTenant   string
+	jobsPriority string
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Queue scan jobs on a running daemon",
+	Long: `Queue scans of files or directories on a running "wordfence daemon" and
+check on their progress.
+
+Jobs run in priority order (high, normal, low) and pause while the daemon
+answers single-file scans, so on-demand upload checks preempt background
+full scans. The daemon limits how many jobs run at once in total and per
+tenant.`,
+}
+
+var jobsSubmitCmd = &cobra.Command{
+	Use:   "submit <path>",
+	Short: "Queue a scan job",
+	Example: `  # Nightly background scan of a customer's sites
+  wordfence jobs submit --tenant acme --priority low /home/acme/public_html`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runJobsSubmit(cmd.Context(), args[0])
+	},
+}
+
+var jobsStatusCmd = &cobra.Command{
+	Use:   "status <job-id>",
+	Short: "Show a job's state and matched files",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runJobsStatus(cmd.Context(), args[0])
+	},
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued, running and finished jobs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runJobsList(cmd.Context())
+	},
+}
+
+func init() {
+	jobsCmd.PersistentFlags().StringVar(&jobsSocket, "socket", daemon.DefaultSocketPath(), "daemon unix socket path")
+	jobsCmd.PersistentFlags().BoolVar(&jobsJSON, "json", false, "write output as JSON")
+
+	jobsSubmitCmd.Flags().StringVar(&jobsTenant, "tenant", "", "tenant the job is counted against")
+	jobsSubmitCmd.Flags().StringVar(&jobsPriority, "priority", string(daemon.PriorityNormal), "job priority: low, normal or high")
+	jobsListCmd.Flags().StringVar(&jobsTenant, "tenant", "", "only list this tenant's jobs")
+
+	jobsCmd.AddCommand(jobsSubmitCmd)
+	jobsCmd.AddCommand(jobsStatusCmd)
+	jobsCmd.AddCommand(jobsListCmd)
+	rootCmd.AddCommand(jobsCmd)
+}
+
+func runJobsSubmit(ctx context.Context, path string) error {
+	priority, err := daemon.ParsePriority(jobsPriority)
+	if err != nil {
+		return err
+	}
+	job, err := daemon.NewClient(jobsSocket).SubmitJob(ctx, path, jobsTenant, priority)
+	if err != nil {
+		return fmt.Errorf("failed to submit job: %w", err)
+	}
+
+	if jobsJSON {
+		return writeIndentedJSON(job)
+	}
+	_, _ = fmt.Fprintln(os.Stdout, job.ID)
+	return nil
+}
+
+func runJobsStatus(ctx context.Context, id string) error {
+	job, err := daemon.NewClient(jobsSocket).JobStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if jobsJSON {
+		return writeIndentedJSON(job)
+	}
+
+	_, _ = fmt.Fprintf(os.Stdout, "Job:       %s\n", job.ID)
+	_, _ = fmt.Fprintf(os.Stdout, "Path:      %s\n", job.Path)
+	if job.Tenant != "" {
+		_, _ = fmt.Fprintf(os.Stdout, "Tenant:    %s\n", job.Tenant)
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Priority:  %s\n", job.Priority)
+	_, _ = fmt.Fprintf(os.Stdout, "State:     %s\n", job.State)
+	if job.Error != "" {
+		_, _ = fmt.Fprintf(os.Stdout, "Error:     %s\n", job.Error)
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Scanned:   %d files (%d matched, %d errors)\n", job.FilesScanned, job.FilesMatched, job.FilesErrored)
+	if !job.Started.IsZero() {
+		end := job.Finished
+		if end.IsZero() {
+			end = time.Now()
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "Duration:  %s\n", end.Sub(job.Started).Round(time.Second))
+	}
+
+	for _, resp := range job.Results {
+		for _, m := range resp.Matches {
+			_, _ = fmt.Fprintf(os.Stdout, "%s: %s\n", resp.Path, m.SignatureName)
+		}
+	}
+	return nil
+}
+
+func runJobsList(ctx context.Context) error {
+	jobs, err := daemon.NewClient(jobsSocket).ListJobs(ctx, jobsTenant)
+	if err != nil {
+		return err
+	}
+
+	if jobsJSON {
+		if jobs == nil {
+			jobs = []*daemon.Job{}
+		}
+		return writeIndentedJSON(jobs)
+	}
+	if len(jobs) == 0 {
+		logging.Info("No jobs")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tTENANT\tPRIORITY\tSTATE\tSUBMITTED\tSCANNED\tMATCHED\tPATH")
+	for _, job := range jobs {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			job.ID,
+			job.Tenant,
+			job.Priority,
+			job.State,
+			job.Submitted.Local().Format("2006-01-02 15:04"),
+			job.FilesScanned,
+			job.FilesMatched,
+			job.Path,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing jobs: %w", err)
+	}
+	return nil
+}
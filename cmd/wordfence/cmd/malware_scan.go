@@ -2,255 +2,1543 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/audit"
 	"github.com/greysquirr3l/wordfence-go/internal/cache"
 	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/hosting"
 	"github.com/greysquirr3l/wordfence-go/internal/intel"
 	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/notify"
 	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
 )
 
 var (
 	malwareScanOutput         string
 	malwareScanOutputFormat   string
+	malwareScanOutputTemplate string
 	malwareScanWorkers        int
 	malwareScanIncludeAll     bool
+	malwareScanImages         bool
 	malwareScanReadStdin      bool
+	malwareScanFileList       string
+	malwareScanNullDelimited  bool
 	malwareScanIncludeFiles   []string
 	malwareScanIncludePattern []string
+	malwareScanIncludeFilesRe []string
+	malwareScanIncludeGlob    []string
 	malwareScanExcludeFiles   []string
 	malwareScanExcludePattern []string
+	malwareScanExcludeFilesRe []string
+	malwareScanExcludeGlob    []string
+	malwareScanExcludeFrom    []string
+	malwareScanExcludeDirs    []string
+	malwareScanRefreshSigs    time.Duration
+	malwareScanReadMemory     int64
+	malwareScanReadPool       int64
+	malwareScanMatchTimeout   time.Duration
+	malwareScanMatchSlack     int
+	malwareScanMaxDepth       int
+	malwareScanOrder          string
+	malwareScanMaxDirFiles    int
+	malwareScanSkipBinary     bool
+	malwareScanImagesWithPHP  bool
+	malwareScanFileTimeout    time.Duration
+	malwareScanMaxDuration    time.Duration
+	malwareScanCheckpoint     string
+	malwareScanResume         string
+	malwareScanErrorBudget    float64
+	malwareScanErrorsOutput   string
+	malwareScanMaxRetries     int
+	malwareScanCircuitLimit   int
+	malwareScanReadLatency    time.Duration
+	malwareScanReadRate       float64
+	malwareScanContentLimit   int64
+	malwareScanFileDelay      time.Duration
+	malwareScanBatchSize      int
+	malwareScanBatchPause     time.Duration
+	malwareScanTraceSlow      time.Duration
+	malwareScanMemoryLimit    int64
+	malwareScanAdaptive       bool
+	malwareScanReadWorkers    int
+	malwareScanMatchWorkers   int
+	malwareScanDryRun         bool
+	malwareScanMatchAll       bool
+	malwareScanFirstMatch     bool
+	malwareScanSuppressions   string
+	malwareScanNoSuppress     bool
+	malwareScanCategories     []string
+	malwareScanHashFeed       string
+	malwareScanIOCFeed        string
+	malwareScanPersistence    bool
+	malwareScanSkipNulled     bool
+	malwareScanSkipEmbedded   bool
+	malwareScanSitesManifest  string
+	malwareScanSummary        bool
+	malwareScanStats          bool
+	malwareScanSummaryOutput  string
+	malwareScanRedact         bool
+	malwareScanHistory        string
+	malwareScanNoHistory      bool
+	malwareScanShard          string
+	malwareScanShardStats     string
+	malwareScanRemediate      string
+	malwareScanQuarantine     bool
+	malwareScanQuarantineDir  string
+	malwareScanRemediateFrom  string
 )
 
+// remediateKnownFiles is the --remediate mode restoring matched core,
+// plugin and theme files
+const remediateKnownFiles = "known-files"
+
 var malwareScanCmd = &cobra.Command{
 	Use:   "malware-scan [paths...]",
 	Short: "Scan files for malware",
 	Long: `Scan files and directories for malware using Wordfence signatures.
 
-The scanner will recursively scan the specified paths for files matching
-known malware signatures. By default, only PHP, HTML, and JS files are
-scanned unless --include-all-files is specified.`,
-	Example: `  # Scan a single directory
-  wordfence malware-scan /var/www
+The scanner will recursively scan the specified paths for files matching
+known malware signatures. By default, only PHP, HTML, and JS files are
+scanned unless --include-all-files is specified.
+
+Use "-" as the only path to scan content read from stdin; matches are
+reported for the path "<stdin>".
+
+A .wordfenceignore file (gitignore syntax) at the root of a scanned
+directory excludes matching paths beneath it.
+
+With --remediate known-files, each matched file identified as a
+WordPress core, plugin or theme file is restored to its original content
+as soon as it is found, after backing it up, and the outcome is reported
+beside its matches. --quarantine-unknown also moves matched files that
+aren't known WordPress files into the quarantine directory. Every file
+changed is recorded in a manifest, and "wordfence remediate rollback"
+undoes the run.`,
+	Example: `  # Scan a single directory
+  wordfence malware-scan /var/www
+
+  # Scan multiple directories
+  wordfence malware-scan /var/www/site1 /var/www/site2
+
+  # Scan with CSV output
+  wordfence malware-scan --output-format csv --output results.csv /var/www
+
+  # Skip a cache directory and minified scripts
+  wordfence malware-scan --exclude 'wp-content/cache/**' --exclude '*.min.js' /var/www
+
+  # Don't walk dependency or VCS directories
+  wordfence malware-scan --exclude-dirs node_modules,.git /var/www
+
+  # Exclude paths listed in a gitignore-style file
+  wordfence malware-scan --exclude-from ~/.wordfenceignore /var/www
+
+  # Scan files from stdin
+  find /var/www -name "*.php" | wordfence malware-scan --read-stdin
+
+  # Scan content piped on stdin
+  curl -s https://example.com/upload.php | wordfence malware-scan -
+
+  # Scan NUL-separated paths (safe for names containing newlines)
+  find /var/www -type f -print0 | wordfence malware-scan --file-list - -0
+
+  # Scan paths listed in a file
+  wordfence malware-scan --file-list /tmp/changed-files.txt
+
+  # Also look for cron jobs, systemd units and php.ini settings that run
+  # files from the site
+  sudo wordfence malware-scan --check-persistence /var/www
+
+  # Scan every hosted site, attributing results to the owning account
+  wordfence malware-scan --sites-manifest sites.json --output-format csv
+
+  # Restore infected core, plugin and theme files, quarantining the rest
+  wordfence malware-scan --remediate known-files --quarantine-unknown /var/www
+
+  # See how many files a scan would read and how long it would take
+  wordfence malware-scan --dry-run /var/www
+
+  # Scan for at most two hours, then carry on in the next window
+  wordfence malware-scan --max-duration 2h /var/www
+  wordfence malware-scan --max-duration 2h --resume ~/.config/wordfence/scan-checkpoint.json
+
+  # Split one large scan across four processes, merging their statistics
+  for i in 1 2 3 4; do
+    wordfence malware-scan --shard $i/4 --shard-stats /tmp/scan-shards --output shard-$i.csv /var/www &
+  done`,
+	Args: func(_ *cobra.Command, args []string) error {
+		if !malwareScanReadStdin && malwareScanFileList == "" && malwareScanSitesManifest == "" && malwareScanResume == "" && len(args) == 0 {
+			return fmt.Errorf("at least one path is required (or use --read-stdin, --file-list, --sites-manifest or --resume)")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMalwareScan(cmd, args)
+	},
+}
+
+func init() {
+	malwareScanCmd.Flags().StringVarP(&malwareScanOutput, "output", "o", "", "output file or s3:// or gs:// location (default: stdout)")
+	addCustodyFlags(malwareScanCmd)
+	malwareScanCmd.Flags().StringVar(&malwareScanOutputFormat, "output-format", "human", "output format: csv, tsv, json, cef, leef, human")
+	malwareScanCmd.Flags().StringVar(&malwareScanOutputTemplate, "output-template", "", "write results through a Go text/template file instead of --output-format")
+	malwareScanCmd.Flags().BoolVar(&malwareScanRedact, "redact", false, "mask passwords, API keys, tokens and email addresses in matched text before it is written or sent anywhere")
+	malwareScanCmd.Flags().IntVarP(&malwareScanWorkers, "workers", "w", 0, "number of worker goroutines (default: NumCPU)")
+	malwareScanCmd.Flags().IntVar(&malwareScanReadWorkers, "read-workers", 0, "files read at once (default: twice the match workers, or half of them with --max-read-rate)")
+	malwareScanCmd.Flags().IntVar(&malwareScanMatchWorkers, "match-workers", 0, "files matched against the signatures at once (default: --workers)")
+	malwareScanCmd.Flags().BoolVar(&malwareScanIncludeAll, "include-all-files", false, "scan all files, not just PHP/HTML/JS")
+	malwareScanCmd.Flags().BoolVar(&malwareScanImages, "images", false, "also scan images, media, archives, SQL dumps and logs")
+	malwareScanCmd.Flags().BoolVar(&malwareScanSkipBinary, "skip-binary", false, "don't match files whose content is an image, media, archive or executable, unless it contains PHP")
+	malwareScanCmd.Flags().BoolVar(&malwareScanImagesWithPHP, "scan-images-with-php", false, "also scan image files that contain PHP, such as polyglot uploads")
+	malwareScanCmd.Flags().BoolVar(&malwareScanDryRun, "dry-run", false, "only find the files that would be scanned, and report their count, size, the largest of them and an estimate of how long the scan would take")
+	malwareScanCmd.Flags().BoolVar(&malwareScanReadStdin, "read-stdin", false, "read paths from stdin")
+	malwareScanCmd.Flags().StringVar(&malwareScanFileList, "file-list", "", "read paths to scan from file (- for stdin)")
+	malwareScanCmd.Flags().StringVar(&malwareScanFileList, "filenames-from", "", "alias for --file-list")
+	malwareScanCmd.Flags().BoolVarP(&malwareScanNullDelimited, "null-delimited", "0", false, "paths read from stdin or --file-list are NUL-separated (find -print0)")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanIncludeFiles, "include-files", nil, "additional filenames to include")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanIncludePattern, "include-pattern", nil, "regex patterns for files to include")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanIncludeFilesRe, "include-files-pattern", nil, "alias for --include-pattern")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanIncludeGlob, "include", nil, "shell glob patterns for files to include")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludeFiles, "exclude-files", nil, "filenames to exclude")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludePattern, "exclude-pattern", nil, "regex patterns to exclude")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludeFilesRe, "exclude-files-pattern", nil, "alias for --exclude-pattern")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludeGlob, "exclude", nil, "shell glob patterns to exclude (dir/** skips the directory)")
+	malwareScanCmd.Flags().StringVar(&malwareScanOrder, "order", scanner.OrderWalk, "order to scan files in: walk, newest-first (most recently modified first), oldest-first or largest-first")
+	malwareScanCmd.Flags().IntVar(&malwareScanMaxDepth, "max-depth", 0, "directory levels below each path to walk (0 is unlimited)")
+	malwareScanCmd.Flags().IntVar(&malwareScanMaxDirFiles, "max-files-per-dir", 0, "files to scan in any one directory; the rest are skipped with a warning (0 is unlimited)")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludeDirs, "exclude-dirs", nil, "directory names to skip entirely (e.g. node_modules,.git)")
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludeFrom, "exclude-from", nil, "read gitignore-style exclude patterns from file")
+
+	malwareScanCmd.Flags().StringSliceVar(&malwareScanCategories, "categories", nil, "only match signatures in these categories (e.g. backdoor)")
+	malwareScanCmd.Flags().StringVar(&malwareScanHashFeed, "malware-hashes", "", "known-malware SHA256 blocklist file or http(s) feed URL")
+	malwareScanCmd.Flags().StringVar(&malwareScanIOCFeed, "iocs", "", "report files referencing domains/IPs in this IOC list file or http(s) feed URL")
+	malwareScanCmd.Flags().StringVar(&malwareScanSuppressions, "suppressions", config.DefaultSuppressionsPath(), "suppression store managed by \"wordfence ignore\"")
+	malwareScanCmd.Flags().BoolVar(&malwareScanNoSuppress, "no-suppressions", false, "report suppressed matches too")
+	malwareScanCmd.Flags().StringVar(&malwareScanSitesManifest, "sites-manifest", "", "scan every docroot in this JSON manifest (cPanel/Plesk export or [{docroot, owner, domain}]) and attribute results to its account")
+	malwareScanCmd.Flags().BoolVar(&malwareScanSummary, "summary", false, "add per-site and fleet-level rollups to human and JSON output")
+	malwareScanCmd.Flags().BoolVar(&malwareScanStats, "stats", false, "add the scan's totals, errors by reason, signature set hash and duration to JSON and template output")
+	malwareScanCmd.Flags().StringVar(&malwareScanSummaryOutput, "summary-output", "", "also write the scan's totals as JSON to this file or s3:// or gs:// location")
+	malwareScanCmd.Flags().BoolVar(&malwareScanSkipNulled, "skip-nulled", false, "don't report pirated/nulled plugin and theme footprints")
+	malwareScanCmd.Flags().BoolVar(&malwareScanSkipEmbedded, "skip-embedded-php", false, "don't look for PHP code in images, stylesheets and fonts")
+	malwareScanCmd.Flags().StringVar(&malwareScanHistory, "history", config.DefaultHistoryPath(), "directory of recorded scans read by \"wordfence history\"")
+	malwareScanCmd.Flags().BoolVar(&malwareScanNoHistory, "no-history", false, "don't record this scan in the history")
+	addReportFlags(malwareScanCmd)
+	addNotifyFlags(malwareScanCmd)
+	addScanManifestFlags(malwareScanCmd)
+	malwareScanCmd.Flags().StringVar(&malwareScanShard, "shard", "", "scan only this part of the files, as index/count (e.g. 2/8), to split a scan across processes or hosts")
+	malwareScanCmd.Flags().StringVar(&malwareScanShardStats, "shard-stats", "", "save this shard's statistics in this directory and report merged totals once every shard has finished")
+	malwareScanCmd.Flags().BoolVar(&malwareScanPersistence, "check-persistence", false, "inspect crontabs, systemd units and php.ini for entries that run files in the scanned directories")
+	malwareScanCmd.Flags().Int64Var(&malwareScanReadMemory, "max-read-memory", scanner.DefaultMaxBytesInFlight>>20, "MiB of file content held in memory at once across workers (0 is unlimited)")
+	malwareScanCmd.Flags().Int64Var(&malwareScanReadPool, "read-pool-memory", scanner.DefaultReadPoolMemory>>20, "MiB of idle read buffers kept for reuse by the next files (0 disables reuse)")
+	malwareScanCmd.Flags().DurationVar(&malwareScanMatchTimeout, "match-timeout", scanner.DefaultMatchTimeout, "time limit for one signature on one file")
+	malwareScanCmd.Flags().IntVar(&malwareScanMatchSlack, "match-slack", scanner.DefaultHintSlack, "characters either side of a signature's common strings searched for a match (0 searches the whole file)")
+	malwareScanCmd.Flags().DurationVar(&malwareScanFileTimeout, "file-timeout", scanner.DefaultFileTimeout, "time limit for all signatures on one file (0 is unlimited)")
+	malwareScanCmd.Flags().DurationVar(&malwareScanMaxDuration, "max-duration", 0, "stop finding and starting files after this long (e.g. 2h), finish those being scanned and save a checkpoint to resume from (0 is unlimited)")
+	malwareScanCmd.Flags().StringVar(&malwareScanCheckpoint, "checkpoint", config.DefaultCheckpointPath(), "file the checkpoint of a scan stopped by --max-duration is saved to")
+	malwareScanCmd.Flags().StringVar(&malwareScanResume, "resume", "", "resume the scan checkpointed in this file, skipping the files it scanned (paths default to the checkpoint's)")
+	malwareScanCmd.Flags().Float64Var(&malwareScanErrorBudget, "error-budget", 0, "stop the scan and fail once more than this percent of files can't be read (0 is unlimited)")
+	malwareScanCmd.Flags().StringVar(&malwareScanErrorsOutput, "errors-output", "", "write the paths that failed or were skipped, grouped by reason, to this JSON file")
+	malwareScanCmd.Flags().IntVar(&malwareScanMaxRetries, "max-retries", scanner.DefaultMaxRetries, "times to retry files that failed for a while (EAGAIN, stale NFS handles, rate limiting) at the end of the scan, backing off between tries (0 disables)")
+	malwareScanCmd.Flags().IntVar(&malwareScanCircuitLimit, "circuit-threshold", scanner.DefaultCircuitThreshold, "skip a device's files once this many reads in a row from it fail, trying again every 30s, so one failing mount doesn't hold up the others (0 disables)")
+	malwareScanCmd.Flags().DurationVar(&malwareScanReadLatency, "read-latency-target", 0, "slow the reads of a device once its 95th percentile read latency passes this (e.g. 20ms), until it recovers (0 disables)")
+	malwareScanCmd.Flags().Float64Var(&malwareScanReadRate, "max-read-rate", 0, "MB/s the workers read files at, at most, in all (0 is unlimited)")
+	malwareScanCmd.Flags().Int64Var(&malwareScanContentLimit, "content-limit", 0, "MiB of each file read and matched (0 reads whole files)")
+	malwareScanCmd.Flags().DurationVar(&malwareScanFileDelay, "file-delay", 0, "time each worker waits after each file, leaving the host idle in between (e.g. 5ms)")
+	malwareScanCmd.Flags().IntVar(&malwareScanBatchSize, "batch-size", 0, "files read between pauses of --batch-pause (0 never pauses)")
+	malwareScanCmd.Flags().DurationVar(&malwareScanBatchPause, "batch-pause", 0, "time all reads stop for after each --batch-size files (e.g. 200ms)")
+	malwareScanCmd.Flags().DurationVar(&malwareScanTraceSlow, "trace-slow-files", scanner.DefaultSlowFileThreshold, "with tracing on, trace each file taking longer than this to read and match in a span of its own (0 traces none)")
+	malwareScanCmd.Flags().Int64Var(&malwareScanMemoryLimit, "memory-limit", 0, "MiB of memory the scan stays under: sets the Go memory limit, holds at most half of it as file content and less as the scan nears it, and throttles --adaptive scans (0 is none)")
+	malwareScanCmd.Flags().BoolVar(&malwareScanAdaptive, "adaptive", false, "halve the workers and wait longer between files whenever the load average passes the CPUs or memory runs short")
+	malwareScanCmd.Flags().BoolVar(&malwareScanMatchAll, "match-all", false, "check every signature against each file and report all matches")
+	malwareScanCmd.Flags().BoolVar(&malwareScanFirstMatch, "first-match-only", false, "stop checking a file at its first match (the default)")
+	malwareScanCmd.MarkFlagsMutuallyExclusive("match-all", "first-match-only")
+	malwareScanCmd.Flags().DurationVar(&malwareScanRefreshSigs, "refresh-signatures", 0, "check for newer signatures at this interval during the scan (e.g. 1h; 0 disables)")
+	malwareScanCmd.Flags().StringVar(&malwareScanRemediate, "remediate", "", "remediate matched files: known-files restores WordPress core, plugin and theme files")
+	malwareScanCmd.Flags().BoolVar(&malwareScanQuarantine, "quarantine-unknown", false, "with --remediate, quarantine matched files that aren't known WordPress files")
+	malwareScanCmd.Flags().StringVar(&malwareScanQuarantineDir, "quarantine-dir", config.DefaultQuarantinePath(), "directory quarantined files are moved to")
+	malwareScanCmd.Flags().StringVar(&malwareScanRemediateFrom, "remediation-source", "", "where to fetch original files: noc1 or wordpress.org (default: remediation_source from the config)")
+
+	for name, profile := range scanner.DefaultProfiles() {
+		config.RegisterProfile(name, profile.Flags())
+	}
+	rootCmd.AddCommand(malwareScanCmd)
+}
+
+func runMalwareScan(cmd *cobra.Command, paths []string) (err error) {
+	ctx := cmd.Context()
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	// Check for license
+	if err := requireLicense(cfg); err != nil {
+		return err
+	}
+
+	if err := scanner.ValidateScanOrder(malwareScanOrder); err != nil {
+		return err
+	}
+
+	tmpl, err := loadOutputTemplate(malwareScanOutputTemplate)
+	if err != nil {
+		return err
+	}
+
+	targets, err := resolveScanTargets(paths)
+	if err != nil {
+		return err
+	}
+	scanStdinContent, paths, roots, sites := targets.stdin, targets.paths, targets.roots, targets.sites
+
+	remediator, err := newScanRemediator(cfg, scanStdinContent)
+	if err != nil {
+		return err
+	}
+
+	reporter, err := newReporter(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeReporter(reporter)
+
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeNotifier(notifier)
+
+	logging.Info("Starting malware scan...")
+
+	s, sigSource, suppressions, err := newMalwareScanner(ctx, cfg, targets)
+	if err != nil {
+		return err
+	}
+
+	if malwareScanDryRun {
+		if scanStdinContent {
+			return fmt.Errorf("\"-\" cannot be combined with --dry-run")
+		}
+		return runDryRun(ctx, s, paths)
+	}
+
+	// Open output file
+	output, err := createOutput(malwareScanOutput)
+	if err != nil {
+		return err
+	}
+
+	// Create output writer; the output is closed, and uploaded if remote,
+	// once the writer has finished
+	var writer resultWriter
+	if tmpl != nil {
+		writer = newTemplateWriter(output.File, tmpl, sites)
+	} else {
+		writer = newResultWriter(output.File, malwareScanOutputFormat, sites, malwareScanSummary || malwareScanStats)
+	}
+	defer func() {
+		if closeErr := writer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if closeErr := output.Close(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	// Start scanning
+	results, stopRefresh, err := startMalwareScan(ctx, s, sigSource, paths, scanStdinContent)
+	if err != nil {
+		return err
+	}
+	defer stopRefresh()
+
+	// Record the scan so later runs can be compared with it, and so it can
+	// be described in the manifest
+	manifestDest := scanManifestDest(malwareScanOutput)
+	record := newMalwareScanRecord(roots, manifestDest, scanStdinContent)
+
+	// Process results, rolling them up per site: each manifest site, or
+	// each path given
+	tally := &scanTally{
+		scanner:      s,
+		writer:       writer,
+		suppressions: suppressions,
+		remediator:   remediator,
+		aggregator:   newScanAggregator(sites, roots, scanStdinContent),
+		record:       record,
+		reporting:    startScanReport(reporter, scanner.ScanKindMalware, roots, record),
+		notifier:     notifier,
+		sites:        sites,
+		roots:        roots,
+		matchedPaths: make(map[string]bool),
+	}
+	remediationStarted := time.Now()
+	for result := range results {
+		tally.add(ctx, result)
+	}
+
+	if malwareScanPersistence {
+		tally.persistence = checkPersistence(roots, tally.matchedPaths, writer)
+	}
+
+	writeScanSummary(writer, tally.aggregator)
+
+	stats := s.GetStats()
+	scanErr := s.Err()
+	if malwareScanErrorsOutput != "" {
+		writeScanErrors(malwareScanErrorsOutput, s.ScanErrors().Report())
+	}
+	stopped := ctx.Err() != nil || scanErr != nil
+	tally.finished(cmd, cfg, stats, manifestDest, stopped)
+	tally.writeStats(ctx, stats, stopped)
+
+	// Print summary
+	logging.Info("")
+	logging.Info("Scan complete:")
+	logScanStats(stats)
+	tally.log()
+	logging.Info("  Duration: %v", stats.TotalDuration.Round(time.Millisecond))
+
+	finishScanCheckpoint(ctx, s, targets.resume != nil)
+
+	if malwareScanShardStats != "" && ctx.Err() == nil && scanErr == nil {
+		reportShard(malwareScanShardStats, scanner.NewShardReport(targets.shard, roots, stats, tally.matches))
+	}
+
+	if remediator != nil {
+		if err := saveRemediationManifest(remediateManifests, roots, remediationStarted, tally.remediations); err != nil {
+			return err
+		}
+	}
+	if scanErr != nil {
+		return fmt.Errorf("scan stopped: %w", scanErr)
+	}
+	return nil
+}
+
+// scanTargets are the paths a malware scan scans, and where they came from
+type scanTargets struct {
+	paths []string
+	// roots are the paths given and the sites of the manifest, which are
+	// checked for ignore files, rather than those listed in files
+	roots  []string
+	sites  *hosting.Manifest
+	resume *scanner.Checkpoint
+	shard  scanner.Shard
+	// stdin is set to scan content read from stdin rather than paths
+	stdin bool
+}
+
+// resolveScanTargets collects the paths to scan: those given, the sites
+// of --sites-manifest, those listed on stdin or in --file-list, and the
+// --resume checkpoint's when none are given
+func resolveScanTargets(paths []string) (*scanTargets, error) {
+	stdin, err := stdinContentScan(paths)
+	if err != nil {
+		return nil, err
+	}
+	t := &scanTargets{stdin: stdin}
+
+	// Scan every site in a hosting panel manifest, attributing results to
+	// the owning account and domain
+	if t.sites, err = loadSitesManifest(stdin); err != nil {
+		return nil, err
+	}
+	if t.sites != nil {
+		paths = append(paths, t.sites.Roots()...)
+	}
+	t.roots = paths
+
+	listPaths, err := readScanPathLists()
+	if err != nil {
+		return nil, err
+	}
+	t.paths = append(paths, listPaths...)
+
+	// Resume a scan stopped at its deadline, of the same paths
+	if t.resume, err = loadResumeCheckpoint(t.paths, stdin); err != nil {
+		return nil, err
+	}
+	if t.resume != nil && len(t.paths) == 0 {
+		t.paths = t.resume.Paths
+		t.roots = t.paths
+	}
+	if len(t.paths) == 0 {
+		return nil, fmt.Errorf("no paths to scan")
+	}
+
+	if t.shard, err = parseScanShard(stdin); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// newMalwareScanner loads the signatures, feeds and suppressions, and
+// creates the scanner configured by the flags
+func newMalwareScanner(ctx context.Context, cfg *config.Config, targets *scanTargets) (*scanner.Scanner, *signatureSource, *scanner.SuppressionStore, error) {
+	workers := malwareScanWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	logScanTuning(cfg.Profile, workers)
+	applyMemoryLimit(malwareScanMemoryLimit << 20)
+	checkAdaptiveSupport()
+	logging.Debug("Paths: %v", targets.paths)
+
+	sigSet, sigSource, err := loadScanSignatures(ctx, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	filter, err := newMalwareScanFilter(targets.roots)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := checkScanCategories(sigSet); err != nil {
+		return nil, nil, nil, err
+	}
+	feedOpts, err := loadScanFeeds(ctx, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Load suppressions of known-benign matches
+	suppressions, err := loadScanSuppressions()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	s := scanner.NewScanner(sigSet, append(feedOpts,
+		scanner.WithScanWorkers(workers),
+		scanner.WithReadWorkers(malwareScanReadWorkers),
+		scanner.WithMatchWorkers(malwareScanMatchWorkers),
+		scanner.WithScanFilter(filter),
+		scanner.WithCategories(malwareScanCategories),
+		scanner.WithNulledDetection(!malwareScanSkipNulled),
+		scanner.WithEmbeddedPHPDetection(!malwareScanSkipEmbedded),
+		scanner.WithShard(targets.shard),
+		scanner.WithMaxBytesInFlight(malwareScanReadMemory<<20),
+		scanner.WithReadPoolMemory(malwareScanReadPool<<20),
+		scanner.WithSignatureTimeout(malwareScanMatchTimeout),
+		scanner.WithScanHintSlack(malwareScanMatchSlack),
+		scanner.WithMaxDepth(malwareScanMaxDepth),
+		scanner.WithScanOrder(malwareScanOrder),
+		scanner.WithMaxFilesPerDir(malwareScanMaxDirFiles),
+		scanner.WithSkipBinary(malwareScanSkipBinary),
+		scanner.WithScanImagesWithPHP(malwareScanImagesWithPHP),
+		scanner.WithFileTimeout(malwareScanFileTimeout),
+		scanner.WithMaxDuration(malwareScanMaxDuration),
+		scanner.WithResume(targets.resume),
+		scanner.WithErrorBudget(malwareScanErrorBudget),
+		scanner.WithMaxRetries(malwareScanMaxRetries),
+		scanner.WithCircuitBreaker(malwareScanCircuitLimit, scanner.DefaultCircuitCooldown),
+		scanner.WithReadLatencyTarget(malwareScanReadLatency),
+		scanner.WithReadRate(int64(malwareScanReadRate*(1<<20))),
+		scanner.WithContentLimit(malwareScanContentLimit<<20),
+		scanner.WithFileDelay(malwareScanFileDelay),
+		scanner.WithBatchPause(malwareScanBatchSize, malwareScanBatchPause),
+		scanner.WithSlowFileThreshold(malwareScanTraceSlow),
+		scanner.WithMemoryLimit(malwareScanMemoryLimit<<20),
+		scanner.WithAdaptive(malwareScanAdaptive),
+		scanner.WithScanMatchAll(malwareScanMatchAll),
+		scanner.WithRedaction(malwareScanRedact),
+		scanner.WithScanRegexEngine(cfg.RegexEngine),
+		scanner.WithScanLogger(logging.GetDefaultLogger()),
+	)...)
+	return s, sigSource, suppressions, nil
+}
+
+// stdinContentScan reports whether paths is "-", to scan content read
+// from stdin, which can't be combined with other paths
+func stdinContentScan(paths []string) (bool, error) {
+	if len(paths) == 1 && paths[0] == "-" {
+		if malwareScanReadStdin || malwareScanFileList == "-" {
+			return false, fmt.Errorf("\"-\" cannot be combined with reading paths from stdin")
+		}
+		return true, nil
+	}
+	if slices.Contains(paths, "-") {
+		return false, fmt.Errorf("\"-\" (scan stdin content) cannot be combined with other paths")
+	}
+	return false, nil
+}
+
+// readScanPathLists reads the paths listed on stdin with --read-stdin and
+// in the --file-list file
+func readScanPathLists() ([]string, error) {
+	var paths []string
+	if malwareScanReadStdin || malwareScanFileList == "-" {
+		stdinPaths, err := readPathList(os.Stdin, malwareScanNullDelimited)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read paths from stdin: %w", err)
+		}
+		paths = append(paths, stdinPaths...)
+	}
+	if malwareScanFileList != "" && malwareScanFileList != "-" {
+		listPaths, err := readPathListFile(malwareScanFileList, malwareScanNullDelimited)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, listPaths...)
+	}
+	return paths, nil
+}
+
+// newMalwareScanFilter creates the file filter from the include and
+// exclude flags; ignore files are read in the roots
+func newMalwareScanFilter(roots []string) (*scanner.FileFilter, error) {
+	filter, err := scanner.NewFilterFromConfig(&scanner.FilterConfig{
+		IncludeAll:      malwareScanIncludeAll,
+		IncludeImages:   malwareScanImages,
+		IncludeFiles:    malwareScanIncludeFiles,
+		IncludePatterns: append(malwareScanIncludePattern, malwareScanIncludeFilesRe...),
+		IncludeGlobs:    malwareScanIncludeGlob,
+		ExcludeFiles:    malwareScanExcludeFiles,
+		ExcludePatterns: append(malwareScanExcludePattern, malwareScanExcludeFilesRe...),
+		ExcludeGlobs:    malwareScanExcludeGlob,
+		ExcludeDirs:     malwareScanExcludeDirs,
+		ExcludeFrom:     malwareScanExcludeFrom,
+		IgnoreRoots:     roots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file filter: %w", err)
+	}
+	return filter, nil
+}
+
+// checkScanCategories fails if no signatures or built-in detections are in
+// the --categories given
+func checkScanCategories(sigSet *intel.SignatureSet) error {
+	if len(malwareScanCategories) == 0 || sigSet.FilterCategories(malwareScanCategories).Count() > 0 ||
+		slices.Contains(malwareScanCategories, scanner.NulledCategory) ||
+		slices.Contains(malwareScanCategories, scanner.EmbeddedPHPCategory) {
+		return nil
+	}
+	return fmt.Errorf("no signatures in categories %s (available: %s)",
+		strings.Join(malwareScanCategories, ", "), strings.Join(sigSet.Categories(), ", "))
+}
+
+// startMalwareScan starts scanning paths, or the content on stdin, and
+// refreshes the signatures of long scans every --refresh-signatures. The
+// function returned stops the refresh.
+func startMalwareScan(ctx context.Context, s *scanner.Scanner, sigSource *signatureSource, paths []string, scanStdinContent bool) (<-chan *scanner.ScanResult, func(), error) {
+	if scanStdinContent {
+		results := make(chan *scanner.ScanResult, 1)
+		results <- s.ScanReader(ctx, scanner.StdinPath, os.Stdin)
+		close(results)
+		return results, func() {}, nil
+	}
+
+	results, err := s.Scan(ctx, paths...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan failed: %w", err)
+	}
+	if malwareScanRefreshSigs <= 0 {
+		return results, func() {}, nil
+	}
+
+	// Swap in newer signatures while long scans are running
+	refreshCtx, cancelRefresh := context.WithCancel(ctx)
+	go s.RefreshSignatures(refreshCtx, malwareScanRefreshSigs, sigSource.FetchNewer)
+	return results, cancelRefresh, nil
+}
+
+// scanTally handles the results of a malware scan as they arrive: writing,
+// remediating, rolling up, recording, reporting and notifying them, and
+// counting matches
+type scanTally struct {
+	scanner      *scanner.Scanner
+	writer       resultWriter
+	suppressions *scanner.SuppressionStore
+	remediator   *wordpress.Remediator
+	aggregator   *scanner.Aggregator
+	record       *scanner.ScanRecord
+	reporting    *scanReport
+	notifier     *notify.Notifier
+	sites        *hosting.Manifest
+	roots        []string
+
+	matches      int
+	suppressed   int
+	persistence  int
+	matchedPaths map[string]bool
+	remediations []*wordpress.RemediationResult
+}
+
+// add handles one scan result
+func (t *scanTally) add(ctx context.Context, result *scanner.ScanResult) {
+	if result.Error != nil {
+		logging.Warning("Error scanning %s: %v", result.Path, result.Error)
+		return
+	}
+	if t.suppressions != nil {
+		t.suppressed += t.suppressions.Apply(result)
+	}
+	if !result.HasMatches() {
+		return
+	}
+
+	sigSet := t.scanner.SignatureSet()
+	t.matches += len(result.Matches)
+	if abs, err := filepath.Abs(result.Path); err == nil {
+		t.matchedPaths[abs] = true
+	}
+	if err := t.writer.WriteResult(result, sigSet); err != nil {
+		logging.Warning("Error writing result: %v", err)
+	}
+	if t.remediator != nil {
+		t.remediate(ctx, result.Path)
+	}
+	if t.aggregator != nil {
+		t.aggregator.AddScanResult(result, sigSet)
+	}
+	if t.record != nil {
+		t.record.AddScanResult(result, matchNames(result, sigSet))
+	}
+	t.reporting.result(result, sigSet, t.sites)
+	t.notifier.Notify(malwareNotification(result, sigSet, t.sites, t.roots))
+}
+
+// remediate restores or quarantines a matched file, writing the outcome
+func (t *scanTally) remediate(ctx context.Context, path string) {
+	remediation := t.remediator.RemediateFile(ctx, path)
+	t.remediations = append(t.remediations, remediation)
+	if remediation.Error != nil {
+		t.scanner.ScanErrors().RecordError(scanner.StageRemediation, path, remediation.Error)
+	}
+	if err := t.writer.WriteRemediation(remediation); err != nil {
+		logging.Warning("Error writing result: %v", err)
+	}
+}
+
+// finished reports the end of the scan, and records it in the history and
+// manifest unless it was cancelled or stopped
+func (t *scanTally) finished(cmd *cobra.Command, cfg *config.Config, stats scanner.ScanStats, manifestDest string, stopped bool) {
+	t.reporting.finished(scanFinished{
+		Kind:         scanner.ScanKindMalware,
+		Matches:      t.matches,
+		FilesScanned: stats.FilesScanned,
+		FilesMatched: stats.FilesMatched,
+		FilesSkipped: stats.FilesSkipped,
+		Duration:     stats.TotalDuration.Seconds(),
+		Cancelled:    stopped,
+	})
+	if t.record != nil && !stopped {
+		t.record.FilesScanned = stats.FilesScanned
+		saveMalwareScanRecord(cmd, cfg, t.record, manifestDest, t.scanner.SignatureSet())
+	}
+}
+
+// writeStats writes the scan's totals with --stats and --summary-output
+func (t *scanTally) writeStats(ctx context.Context, stats scanner.ScanStats, stopped bool) {
+	if !malwareScanStats && malwareScanSummaryOutput == "" {
+		return
+	}
+	report := scanner.NewScanStatsReport(scanner.ScanKindMalware, stats, t.scanner.SignatureSet())
+	report.Matches = t.matches
+	report.Suppressed = t.suppressed
+	report.Stopped = stopped
+
+	if malwareScanStats {
+		if err := t.writer.WriteStats(report); err != nil {
+			logging.Warning("Error writing stats: %v", err)
+		}
+	}
+	if malwareScanSummaryOutput != "" {
+		// Totals of a stopped scan are still uploaded
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), outputCloseTimeout)
+		defer cancel()
+		if err := writeStatsReport(ctx, malwareScanSummaryOutput, report); err != nil {
+			logging.Warning("Failed to write scan totals: %v", err)
+		}
+	}
+}
+
+// log lists the matches, suppressions, findings and remediations counted
+func (t *scanTally) log() {
+	logging.Info("  Total matches: %d", t.matches)
+	if t.suppressed > 0 {
+		logging.Info("  Suppressed matches: %d", t.suppressed)
+	}
+	if malwareScanPersistence {
+		logging.Info("  Persistence findings: %d", t.persistence)
+	}
+	if t.remediator != nil {
+		remediated, quarantined, failed := countRemediations(t.remediations)
+		logging.Info("  Files remediated: %d", remediated)
+		if malwareScanQuarantine {
+			logging.Info("  Files quarantined: %d", quarantined)
+		}
+		logging.Info("  Remediations failed: %d", failed)
+	}
+}
+
+// loadSitesManifest loads the --sites-manifest of sites to scan, if any
+func loadSitesManifest(scanStdinContent bool) (*hosting.Manifest, error) {
+	if malwareScanSitesManifest == "" {
+		return nil, nil //nolint:nilnil // no manifest to scan
+	}
+	if scanStdinContent {
+		return nil, fmt.Errorf("\"-\" cannot be combined with --sites-manifest")
+	}
+	sites, err := hosting.LoadManifest(malwareScanSitesManifest)
+	if err != nil {
+		return nil, err
+	}
+	logging.Info("Loaded %d sites from %s", len(sites.Sites), malwareScanSitesManifest)
+	return sites, nil
+}
+
+// loadResumeCheckpoint loads the --resume checkpoint, if any, checking it
+// is of a scan of paths when paths are given
+func loadResumeCheckpoint(paths []string, scanStdinContent bool) (*scanner.Checkpoint, error) {
+	if malwareScanResume == "" {
+		return nil, nil //nolint:nilnil // not resuming
+	}
+	if scanStdinContent {
+		return nil, fmt.Errorf("\"-\" cannot be combined with --resume")
+	}
+	resume, err := scanner.LoadCheckpoint(config.ExpandPath(malwareScanResume))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) > 0 && !resume.Matches(paths) {
+		return nil, fmt.Errorf("--resume checkpoint is of a scan of %s, not %s",
+			strings.Join(resume.Paths, ", "), strings.Join(paths, ", "))
+	}
+	logging.Info("Resuming scan at %.1f%%", resume.Progress()*100)
+	return resume, nil
+}
+
+// parseScanShard returns the --shard to scan, or the whole scan without one
+func parseScanShard(scanStdinContent bool) (scanner.Shard, error) {
+	if malwareScanShard == "" {
+		if malwareScanShardStats != "" {
+			return scanner.Shard{}, fmt.Errorf("--shard-stats requires --shard")
+		}
+		return scanner.Shard{}, nil
+	}
+	if scanStdinContent {
+		return scanner.Shard{}, fmt.Errorf("\"-\" cannot be combined with --shard")
+	}
+	return scanner.ParseShard(malwareScanShard)
+}
+
+// loadScanFeeds loads the --malware-hashes and --iocs feeds, returning the
+// scanner options that check files against them
+func loadScanFeeds(ctx context.Context, cfg *config.Config) ([]scanner.Option, error) {
+	var opts []scanner.Option
+	if malwareScanHashFeed != "" {
+		hashes, err := loadMalwareHashes(ctx, cfg, malwareScanHashFeed)
+		if err != nil {
+			return nil, err
+		}
+		logging.Info("Loaded %d known malware hashes", hashes.Count())
+		opts = append(opts, scanner.WithHashSet(hashes))
+	}
+	if malwareScanIOCFeed != "" {
+		iocs, err := loadIOCs(ctx, cfg, malwareScanIOCFeed)
+		if err != nil {
+			return nil, err
+		}
+		logging.Info("Loaded %d indicators of compromise", iocs.Count())
+		opts = append(opts, scanner.WithIOCs(iocs))
+	}
+	return opts, nil
+}
+
+// loadScanSuppressions loads the suppressions of known-benign matches, or
+// returns nil with --no-suppressions
+func loadScanSuppressions() (*scanner.SuppressionStore, error) {
+	if malwareScanNoSuppress {
+		return nil, nil //nolint:nilnil // suppressions are off
+	}
+	suppressions, err := scanner.LoadSuppressions(malwareScanSuppressions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load suppressions: %w", err)
+	}
+	if suppressions.Len() > 0 {
+		logging.Verbose("Loaded %d suppressions from %s", suppressions.Len(), malwareScanSuppressions)
+	}
+	return suppressions, nil
+}
+
+// newMalwareScanRecord starts the record of a scan kept in the history
+// and described by the manifest, or returns nil if neither wants it
+func newMalwareScanRecord(roots []string, manifestDest string, scanStdinContent bool) *scanner.ScanRecord {
+	if scanStdinContent || (malwareScanNoHistory && manifestDest == "") {
+		return nil
+	}
+	return scanner.NewScanRecord(scanner.ScanKindMalware, roots, time.Now())
+}
+
+// saveMalwareScanRecord finishes the record of a completed scan, saving it
+// in the history and describing it in the manifest
+func saveMalwareScanRecord(cmd *cobra.Command, cfg *config.Config, record *scanner.ScanRecord, manifestDest string, sigSet *intel.SignatureSet) {
+	record.Finish(time.Now())
+	if !malwareScanNoHistory {
+		if err := scanner.OpenHistory(malwareScanHistory).Save(record); err != nil {
+			logging.Warning("Failed to record scan history: %v", err)
+		} else {
+			logging.Verbose("Recorded scan %s", record.ID)
+		}
+	}
+	if manifestDest != "" {
+		manifest := newScanManifest(cmd, cfg, record, malwareScanOutput)
+		manifest.SetSignatures(sigSet)
+		if err := writeScanManifest(cmd.Context(), manifestDest, manifest); err != nil {
+			logging.Warning("Failed to write scan manifest: %v", err)
+		}
+	}
+}
+
+// newScanAggregator returns the --summary rollup of results per site:
+// each site of the manifest, or else each path given. It returns nil
+// without --summary.
+func newScanAggregator(sites *hosting.Manifest, roots []string, scanStdinContent bool) *scanner.Aggregator {
+	if !malwareScanSummary {
+		return nil
+	}
+	aggregator := scanner.NewAggregator()
+	switch {
+	case sites != nil:
+		for _, site := range sites.Sites {
+			aggregator.AddSite(site.Docroot, site.Owner, site.Domain)
+		}
+	case !scanStdinContent:
+		for _, root := range roots {
+			aggregator.AddSite(root, "", "")
+		}
+	}
+	return aggregator
+}
+
+// writeScanSummary writes the per-site and fleet rollups, if any
+func writeScanSummary(writer resultWriter, aggregator *scanner.Aggregator) {
+	if aggregator == nil {
+		return
+	}
+	if err := writer.WriteSummary(aggregator.Summary(scanner.DefaultSummaryTop)); err != nil {
+		logging.Warning("Error writing summary: %v", err)
+	}
+}
+
+// dryRunReport is the --dry-run report written with --output-format json
+type dryRunReport struct {
+	*scanner.Discovery
+	Estimate *scanner.ScanEstimate `json:"estimate"`
+}
+
+// runDryRun reports the files a scan of paths would read and estimates
+// how long it would take, from scanning a sample of them
+func runDryRun(ctx context.Context, s *scanner.Scanner, paths []string) error {
+	discovery, err := s.Discover(ctx, paths...)
+	if err != nil {
+		return fmt.Errorf("dry run failed: %w", err)
+	}
+	estimate, err := s.EstimateScan(ctx, discovery, scanner.DefaultCalibrationBytes)
+	if err != nil {
+		return fmt.Errorf("estimating scan duration: %w", err)
+	}
+
+	if malwareScanOutputFormat == "json" {
+		return writeIndentedJSON(dryRunReport{Discovery: discovery, Estimate: estimate})
+	}
+
+	logging.Info("Dry run: no files were scanned")
+	logging.Info("  Files to scan: %d", discovery.Files)
+	logging.Info("  Size to scan: %.1f MB", float64(discovery.Bytes)/(1<<20))
+	logging.Info("  Files skipped: %d", discovery.FilesSkipped)
+	if discovery.DirsSkipped > 0 {
+		logging.Info("  Directories skipped: %d", discovery.DirsSkipped)
+	}
+	logging.Info("  Walk took: %v", discovery.Duration.Round(time.Millisecond))
+	if estimate.BytesPerSecond > 0 {
+		logging.Info("  Estimated duration: %v (%.1f MB/s per worker on a %.1f MB sample, %d workers)",
+			estimate.Duration.Round(time.Second), estimate.BytesPerSecond/(1<<20),
+			float64(estimate.SampleBytes)/(1<<20), estimate.Workers)
+	}
+	logScanErrorCounts(s.GetStats().Errors)
+	if len(discovery.Largest) > 0 {
+		logging.Info("  Largest files:")
+		for _, file := range discovery.Largest {
+			logging.Info("    %10.1f MB  %s", float64(file.Size)/(1<<20), file.Path)
+		}
+	}
+	return nil
+}
+
+// logScanTuning reports the settings deciding how hard the scan works the
+// host, at info level when they come from a profile
+func logScanTuning(profile string, workers int) {
+	log := logging.Debug
+	name := "Scan settings"
+	if profile != "" {
+		log, name = logging.Info, "Profile "+profile
+	}
+	batch := "none"
+	if malwareScanBatchSize > 0 && malwareScanBatchPause > 0 {
+		batch = fmt.Sprintf("%v every %d files", malwareScanBatchPause, malwareScanBatchSize)
+	}
+	log("%s: %s, %d MiB read memory, read rate %s, content limit %s, file delay %v, batch pause %s, read latency target %v, adaptive %v",
+		name, workersText(workers), malwareScanReadMemory, limitText(malwareScanReadRate, "MB/s"), limitText(float64(malwareScanContentLimit), "MiB"),
+		malwareScanFileDelay, batch, malwareScanReadLatency, malwareScanAdaptive)
+}
+
+// workersText describes the workers, and the read and match workers when
+// they are set apart
+func workersText(workers int) string {
+	if malwareScanReadWorkers <= 0 && malwareScanMatchWorkers <= 0 {
+		return fmt.Sprintf("%d workers", workers)
+	}
+	read, match := malwareScanReadWorkers, malwareScanMatchWorkers
+	if match <= 0 {
+		match = workers
+	}
+	if read <= 0 {
+		return fmt.Sprintf("%d match workers", match)
+	}
+	return fmt.Sprintf("%d read and %d match workers", read, match)
+}
+
+// checkAdaptiveSupport warns when --adaptive can't sample the host's load
+// or free memory on this platform, so the scan isn't throttled for them
+func checkAdaptiveSupport() {
+	if !malwareScanAdaptive {
+		return
+	}
+	support := scanner.ProbeMonitor()
+	switch {
+	case !support.Load && !support.AvailableMemory:
+		logging.Warning("--adaptive can't read the load average or free memory on %s; only --memory-limit throttles the scan", runtime.GOOS)
+	case !support.Load:
+		logging.Warning("--adaptive can't read the load average on %s; the scan is throttled for memory only", runtime.GOOS)
+	case !support.AvailableMemory:
+		logging.Debug("--adaptive can't read the free memory on %s; the scan is throttled for load and --memory-limit", runtime.GOOS)
+	}
+}
+
+// applyMemoryLimit sets the Go memory limit to --memory-limit, so garbage
+// is collected sooner as the scan nears it, unless GOMEMLIMIT sets one
+func applyMemoryLimit(limit int64) {
+	if limit <= 0 {
+		return
+	}
+	if os.Getenv("GOMEMLIMIT") != "" {
+		logging.Debug("GOMEMLIMIT is set; --memory-limit only bounds the read memory")
+		return
+	}
+	debug.SetMemoryLimit(limit)
+	logging.Debug("Go memory limit: %d MiB", limit>>20)
+}
+
+// limitText describes a limit where 0 means none
+func limitText(limit float64, unit string) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return strconv.FormatFloat(limit, 'f', -1, 64) + " " + unit
+}
+
+// logScanStats lists the files a scan scanned, skipped and failed, with
+// the reasons for failures, the devices whose reads kept failing and those
+// whose reads were slowed, and how an adaptive scan was throttled
+func logScanStats(stats scanner.ScanStats) {
+	logging.Info("  Files scanned: %d", stats.FilesScanned)
+	logging.Info("  Files matched: %d", stats.FilesMatched)
+	logging.Info("  Files skipped: %d", stats.FilesSkipped)
+	if stats.DirsSkipped > 0 {
+		logging.Info("  Directories skipped: %d", stats.DirsSkipped)
+	}
+	logging.Info("  Files errored: %d", stats.FilesErrored)
+	if stats.FilesRetried > 0 {
+		logging.Info("  Files retried: %d", stats.FilesRetried)
+	}
+	logScanErrorCounts(stats.Errors)
+	for _, circuit := range stats.Circuits {
+		if circuit.Trips > 0 {
+			logging.Warning("Reads failed on the device of %s: its circuit opened %d times, skipping %d files (now %s)",
+				circuit.Path, circuit.Trips, circuit.Rejected, circuit.State)
+		}
+	}
+	for _, device := range stats.ReadLatency {
+		if device.MaxDelay > 0 {
+			logging.Info("  Reads slowed on the device of %s: p95 latency %v, waiting up to %v per file",
+				device.Path, device.P95.Round(time.Millisecond), device.MaxDelay)
+		}
+	}
+	if m := stats.Memory; m != nil {
+		logging.Info("  Memory: peak %d MiB of %d MiB limit", m.Peak>>20, m.Limit>>20)
+		if m.Shrinks > 0 {
+			logging.Info("  Read memory shrunk %d times near the limit, to %d MiB at least", m.Shrinks, m.MinReadMemory>>20)
+		}
+	}
+	logReadPool(stats.ReadPool)
+	if t := stats.Throttle; t != nil && t.Throttles > 0 {
+		logging.Info("  Throttled %d times for load or memory: %d of %d workers, waiting %v per file",
+			t.Throttles, t.Workers, t.MaxWorkers, t.Delay)
+	}
+	if t := stats.Throttle; t != nil && t.ScaleUps > 0 {
+		logging.Info("  Sped up %d times while the host was idle", t.ScaleUps)
+	}
+}
+
+// logReadPool lists how often read buffers were reused, by size
+func logReadPool(pool *scanner.ReadPoolState) {
+	if pool == nil {
+		return
+	}
+	var gets, hits int64
+	for _, tier := range pool.Tiers {
+		gets += tier.Gets
+		hits += tier.Hits
+		logging.Debug("  Read buffers of %d KiB: %d of %d reused, %d dropped, %d idle",
+			tier.Size>>10, tier.Hits, tier.Gets, tier.Dropped, tier.Idle)
+	}
+	if gets > 0 {
+		logging.Verbose("  Read buffers: %d of %d reused, at most %d MiB idle of %d MiB",
+			hits, gets, pool.PeakIdle>>20, pool.Limit>>20)
+	}
+}
+
+// logScanErrorCounts lists how many paths failed or were skipped, and how
+// many other failures there were, for each reason
+func logScanErrorCounts(counts map[scanner.ScanErrorCode]int64) {
+	if len(counts) == 0 {
+		return
+	}
+	codes := make([]scanner.ScanErrorCode, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	slices.SortFunc(codes, func(a, b scanner.ScanErrorCode) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	logging.Info("  Not scanned or failed, by reason:")
+	for _, code := range codes {
+		logging.Info("    %s: %d", code, counts[code])
+	}
+}
+
+// writeScanErrors writes the report of paths a scan didn't scan to path
+func writeScanErrors(path string, report *scanner.ScanErrorReport) {
+	file, err := os.Create(path) // #nosec G304 -- user-specified output file
+	if err != nil {
+		logging.Warning("Failed to write error report: %v", err)
+		return
+	}
+	err = report.WriteJSON(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logging.Warning("Failed to write error report: %v", err)
+		return
+	}
+	logging.Verbose("Wrote errored and skipped paths to %s", path)
+}
+
+// finishScanCheckpoint saves the checkpoint of a scan stopped at its
+// --max-duration, or removes the --resume checkpoint once the scan it
+// resumed is done
+func finishScanCheckpoint(ctx context.Context, s *scanner.Scanner, resumed bool) {
+	if checkpoint := s.Checkpoint(); checkpoint != nil {
+		saveScanCheckpoint(checkpoint, s.Unstarted())
+		return
+	}
+	if !resumed || ctx.Err() != nil {
+		return
+	}
+	// The resumed scan is done, so its checkpoint is spent
+	if err := os.Remove(config.ExpandPath(malwareScanResume)); err != nil && !os.IsNotExist(err) {
+		logging.Warning("Failed to remove checkpoint: %v", err)
+	}
+}
+
+// saveScanCheckpoint saves the checkpoint of a scan stopped by
+// --max-duration and says how to resume it
+func saveScanCheckpoint(checkpoint *scanner.Checkpoint, unstarted int64) {
+	path := config.ExpandPath(malwareScanCheckpoint)
+	logging.Warning("Scan stopped after %v, about %.1f%% complete (%d files found but not scanned)",
+		malwareScanMaxDuration, checkpoint.Progress()*100, unstarted)
+	if err := scanner.SaveCheckpoint(path, checkpoint); err != nil {
+		logging.Warning("Failed to save checkpoint: %v", err)
+		return
+	}
+	logging.Warning("Resume with: wordfence malware-scan --resume %s", path)
+}
+
+// newScanRemediator creates the remediator for --remediate, or nil when
+// matched files are only reported
+func newScanRemediator(cfg *config.Config, scanStdinContent bool) (*wordpress.Remediator, error) {
+	switch {
+	case malwareScanRemediate == "":
+		if malwareScanQuarantine {
+			return nil, fmt.Errorf("--quarantine-unknown requires --remediate")
+		}
+		return nil, nil
+	case malwareScanRemediate != remediateKnownFiles:
+		return nil, fmt.Errorf("invalid --remediate mode %q: use %s", malwareScanRemediate, remediateKnownFiles)
+	case scanStdinContent:
+		return nil, fmt.Errorf("\"-\" cannot be combined with --remediate")
+	}
+
+	source, err := newRemediationSource(cfg, malwareScanRemediateFrom, defaultRemediateAPIConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	remediatorCfg := &wordpress.RemediatorConfig{CreateBackup: true}
+	if malwareScanQuarantine {
+		remediatorCfg.QuarantineDir = config.ExpandPath(malwareScanQuarantineDir)
+	}
+	remediator := wordpress.NewRemediator(source, remediatorCfg)
+	remediator.SetLogger(logging.GetDefaultLogger())
+	return remediator, nil
+}
+
+// countRemediations counts the files remediated, quarantined and failed
+func countRemediations(results []*wordpress.RemediationResult) (int, int, int) {
+	var remediated, quarantined, failed int
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			failed++
+		case r.Quarantined:
+			quarantined++
+		case r.Remediated:
+			remediated++
+		}
+	}
+	return remediated, quarantined, failed
+}
+
+// remediationOutcome describes what remediation did to a matched file, and
+// the backup, quarantined copy or error behind it
+func remediationOutcome(r *wordpress.RemediationResult) (string, string) {
+	switch {
+	case r.Error != nil:
+		return "failed", r.Error.Error()
+	case r.Quarantined:
+		return "quarantined", r.BackupPath
+	case r.Remediated:
+		return "remediated", r.BackupPath
+	case !r.Known:
+		return "unknown", "not a known WordPress file"
+	default:
+		return "skipped", ""
+	}
+}
+
+// reportShard saves a finished shard's statistics and prints the merged
+// totals if it was the last shard to finish
+func reportShard(dir string, report *scanner.ShardReport) {
+	if err := scanner.SaveShardReport(dir, report); err != nil {
+		logging.Warning("Failed to save shard statistics: %v", err)
+		return
+	}
+	summary, err := loadShardSummary(dir)
+	if err != nil {
+		logging.Warning("%v", err)
+		return
+	}
+
+	logging.Info("")
+	if !summary.Done() {
+		logging.Info("Shard %d/%d complete; waiting for shards %s", report.Index, report.Count, joinInts(summary.Missing))
+		return
+	}
+	printShardSummary(summary)
+}
+
+// loadShardSummary merges the shard reports saved in dir
+func loadShardSummary(dir string) (*scanner.ShardSummary, error) {
+	reports, err := scanner.LoadShardReports(dir)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := scanner.MergeShardReports(reports)
+	if err != nil {
+		return nil, fmt.Errorf("merging shard statistics in %s: %w", dir, err)
+	}
+	return summary, nil
+}
 
-  # Scan multiple directories
-  wordfence malware-scan /var/www/site1 /var/www/site2
+// printShardSummary logs the merged statistics of a sharded scan
+func printShardSummary(summary *scanner.ShardSummary) {
+	if summary.Done() {
+		logging.Info("All %d shards complete:", summary.Count)
+	} else {
+		logging.Info("%d of %d shards complete (waiting for %s):", len(summary.Complete), summary.Count, joinInts(summary.Missing))
+	}
+	logging.Info("  Files scanned: %d", summary.FilesScanned)
+	logging.Info("  Files matched: %d", summary.FilesMatched)
+	logging.Info("  Files skipped: %d", summary.FilesSkipped)
+	if summary.DirsSkipped > 0 {
+		logging.Info("  Directories skipped: %d", summary.DirsSkipped)
+	}
+	logging.Info("  Files errored: %d", summary.FilesErrored)
+	logging.Info("  Total matches: %d", summary.Matches)
+	logging.Info("  Duration: %v", summary.Finished.Sub(summary.Started).Round(time.Millisecond))
+}
 
-  # Scan with CSV output
-  wordfence malware-scan --output-format csv --output results.csv /var/www
+// joinInts formats numbers as a comma-separated list
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
 
-  # Scan files from stdin
-  find /var/www -name "*.php" | wordfence malware-scan --read-stdin`,
-	Args: func(_ *cobra.Command, args []string) error {
-		if !malwareScanReadStdin && len(args) == 0 {
-			return fmt.Errorf("at least one path is required (or use --read-stdin)")
+// matchNames describes each match of a result
+func matchNames(result *scanner.ScanResult, sigSet *intel.SignatureSet) []string {
+	names := make([]string, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		name, _ := match.Describe(sigSet)
+		if name == "" {
+			name = fmt.Sprintf("Signature %d", match.SignatureID)
 		}
-		return nil
-	},
-	RunE: func(cmd *cobra.Command, args []string) error {
-		return runMalwareScan(cmd.Context(), args)
-	},
+		names = append(names, name)
+	}
+	return names
 }
 
-func init() {
-	malwareScanCmd.Flags().StringVarP(&malwareScanOutput, "output", "o", "", "output file (default: stdout)")
-	malwareScanCmd.Flags().StringVar(&malwareScanOutputFormat, "output-format", "human", "output format: csv, tsv, json, human")
-	malwareScanCmd.Flags().IntVarP(&malwareScanWorkers, "workers", "w", 0, "number of worker goroutines (default: NumCPU)")
-	malwareScanCmd.Flags().BoolVar(&malwareScanIncludeAll, "include-all-files", false, "scan all files, not just PHP/HTML/JS")
-	malwareScanCmd.Flags().BoolVar(&malwareScanReadStdin, "read-stdin", false, "read paths from stdin")
-	malwareScanCmd.Flags().StringSliceVar(&malwareScanIncludeFiles, "include-files", nil, "additional filenames to include")
-	malwareScanCmd.Flags().StringSliceVar(&malwareScanIncludePattern, "include-pattern", nil, "regex patterns for files to include")
-	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludeFiles, "exclude-files", nil, "filenames to exclude")
-	malwareScanCmd.Flags().StringSliceVar(&malwareScanExcludePattern, "exclude-pattern", nil, "regex patterns to exclude")
+// checkPersistence reports host persistence entries that run files in the
+// scanned directories and returns the number of findings
+func checkPersistence(roots []string, matchedPaths map[string]bool, writer resultWriter) int {
+	var docroots []string
+	for _, root := range roots {
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			docroots = append(docroots, root)
+		}
+	}
+	if len(docroots) == 0 {
+		logging.Warning("--check-persistence needs at least one directory to scan; only php.ini is checked")
+	}
 
-	rootCmd.AddCommand(malwareScanCmd)
+	logging.Info("Checking cron, systemd and php.ini for persistence...")
+	entries := audit.NewPersistenceScanner(docroots).Scan()
+	findings := audit.CorrelatePersistence(entries, matchedPaths)
+	if malwareScanRedact {
+		redactFindings(findings)
+	}
+	if err := writer.WriteFindings(findings); err != nil {
+		logging.Warning("Error writing persistence findings: %v", err)
+	}
+	return len(findings)
 }
 
-func runMalwareScan(ctx context.Context, paths []string) error {
-	cfg := GetConfig()
-	if cfg == nil {
-		return fmt.Errorf("configuration not loaded")
+// readPathListFile reads a list of paths from a file
+func readPathListFile(path string, nullDelimited bool) ([]string, error) {
+	file, err := os.Open(path) // #nosec G304 -- user-specified file list
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file list: %w", err)
 	}
+	defer func() { _ = file.Close() }()
 
-	// Check for license
-	if cfg.License == "" {
-		logging.Error("No license key configured.")
-		logging.Info("You can configure your license in one of the following ways:")
-		logging.Info("  1. Config file: %s", config.DefaultConfigPath())
-		logging.Info("     Add: license = YOUR_LICENSE_KEY")
-		logging.Info("  2. Environment: export WORDFENCE_CLI_LICENSE=YOUR_LICENSE_KEY")
-		logging.Info("  3. CLI flag: --license YOUR_LICENSE_KEY")
-		logging.Info("")
-		logging.Info("Visit https://www.wordfence.com/products/wordfence-cli/ to obtain a license.")
-		return fmt.Errorf("license required")
-	}
-
-	// Read paths from stdin if requested
-	if malwareScanReadStdin {
-		stdinPaths, err := readPathsFromStdin()
-		if err != nil {
-			return fmt.Errorf("failed to read paths from stdin: %w", err)
-		}
-		paths = append(paths, stdinPaths...)
+	paths, err := readPathList(file, nullDelimited)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list %s: %w", path, err)
 	}
+	return paths, nil
+}
 
-	if len(paths) == 0 {
-		return fmt.Errorf("no paths to scan")
+// readPathList reads newline- or NUL-separated paths. Newline-separated
+// entries are trimmed; NUL-separated entries are taken verbatim.
+func readPathList(r io.Reader, nullDelimited bool) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if nullDelimited {
+		scanner.Split(scanNullDelimited)
+	}
+	for scanner.Scan() {
+		path := scanner.Text()
+		if !nullDelimited {
+			path = strings.TrimSpace(path)
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading path list: %w", err)
 	}
+	return paths, nil
+}
 
-	logging.Info("Starting malware scan...")
+// scanNullDelimited is a bufio.SplitFunc for NUL-terminated records
+func scanNullDelimited(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
 
-	// Set up workers
-	workers := malwareScanWorkers
-	if workers <= 0 {
-		workers = runtime.NumCPU()
+// requireLicense reads the license from license_file, license_command or
+// the systemd credential if need be, and reports how to configure a
+// license if there is none
+func requireLicense(cfg *config.Config) error {
+	if err := cfg.ResolveLicense(); err != nil {
+		return err
+	}
+	if cfg.License != "" {
+		if cfg.LicenseSource != "" {
+			logging.Debug("Read license from %s", cfg.LicenseSource)
+		}
+		return nil
 	}
+	logging.Error("No license key configured.")
+	logging.Info("You can configure your license in one of the following ways:")
+	logging.Info("  1. Config file: %s", config.DefaultConfigPath())
+	logging.Info("     Add: license = YOUR_LICENSE_KEY")
+	logging.Info("     Or keep it elsewhere: license_file = /path/to/license")
+	logging.Info("     Or fetch it: license_command = vault kv get -field=license secret/wordfence")
+	logging.Info("  2. Environment: export WORDFENCE_CLI_LICENSE=YOUR_LICENSE_KEY")
+	logging.Info("  3. CLI flag: --license YOUR_LICENSE_KEY")
+	logging.Info("  4. systemd credential: LoadCredential=%s:/path/to/license", config.SystemdCredential)
+	logging.Info("")
+	logging.Info("Visit https://www.wordfence.com/products/wordfence-cli/ to obtain a license.")
+	return fmt.Errorf("license required")
+}
 
-	logging.Debug("Workers: %d", workers)
-	logging.Debug("Paths: %v", paths)
+// signatureSource loads malware signatures for scan commands and checks for updates
+type signatureSource struct {
+	noc1  *api.NOC1Client
+	cache cache.Cache
+}
 
+// newSignatureSource validates the license and sets up the signature cache
+func newSignatureSource(ctx context.Context, cfg *config.Config) (*signatureSource, error) {
 	// Create license
 	license := api.NewLicense(cfg.License)
 
 	// Create NOC1 client
-	noc1 := api.NewNOC1Client(api.WithNOC1License(license))
+	noc1 := api.NewNOC1Client(api.WithNOC1License(license), api.WithNOC1ClientOptions(clientOpts...))
 
 	// Validate license
 	logging.Verbose("Validating license...")
 	valid, err := noc1.PingAPIKey(ctx)
 	if err != nil {
-		return fmt.Errorf("license validation failed: %w", err)
+		return nil, fmt.Errorf("license validation failed: %w", err)
 	}
 	if !valid {
-		return fmt.Errorf("invalid license key")
+		return nil, fmt.Errorf("invalid license key")
 	}
 	logging.Verbose("License valid (paid: %v)", license.Paid)
 
-	// Set up cache
-	var fileCache cache.Cache
-	if cfg.CacheEnabled {
-		cacheDir := cfg.CacheDirectory
-		if cacheDir == "" {
-			cacheDir, err = cache.DefaultCacheDir()
-			if err != nil {
-				logging.Warning("Failed to get default cache directory: %v", err)
-				fileCache = &cache.NoOpCache{}
-			}
-		}
-		if fileCache == nil {
-			fileCache, err = cache.NewFileCache(cacheDir)
-			if err != nil {
-				logging.Warning("Failed to create file cache: %v", err)
-				fileCache = &cache.NoOpCache{}
-			}
-		}
-	} else {
-		fileCache = &cache.NoOpCache{}
-	}
+	return &signatureSource{noc1: noc1, cache: newSignatureCache(cfg)}, nil
+}
 
-	// Load signatures (from cache or API)
-	sigSet, err := loadSignatures(ctx, noc1, fileCache)
-	if err != nil {
-		return fmt.Errorf("failed to load signatures: %w", err)
-	}
-	logging.Info("Loaded %d signatures", sigSet.Count())
+// tieredCaches holds one two-tier cache per cache directory so every load
+// in the process, including concurrent daemon scans, shares decoded feeds
+var (
+	tieredCachesMu sync.Mutex
+	tieredCaches   = make(map[string]*cache.Tiered)
+)
 
-	// Create file filter
-	filterCfg := &scanner.FilterConfig{
-		IncludeAll:      malwareScanIncludeAll,
-		IncludeFiles:    malwareScanIncludeFiles,
-		IncludePatterns: malwareScanIncludePattern,
-		ExcludeFiles:    malwareScanExcludeFiles,
-		ExcludePatterns: malwareScanExcludePattern,
+// newSignatureCache returns the configured signature cache, falling back to
+// a no-op cache if caching is disabled or the cache directory is unusable.
+// Decoded signatures, hashes and vulnerabilities are kept in memory over
+// the file cache for the life of the process.
+func newSignatureCache(cfg *config.Config) cache.Cache {
+	if !cfg.CacheEnabled {
+		return cache.WithContext(cache.NewNoOpCache())
 	}
-	filter, err := scanner.NewFilterFromConfig(filterCfg)
-	if err != nil {
-		return fmt.Errorf("failed to create file filter: %w", err)
-	}
-
-	// Create scanner
-	s := scanner.NewScanner(sigSet,
-		scanner.WithScanWorkers(workers),
-		scanner.WithScanFilter(filter),
-	)
 
-	// Open output file
-	var output *os.File
-	if malwareScanOutput != "" {
-		output, err = os.Create(malwareScanOutput) // #nosec G304 -- user-specified output file
+	cacheDir := cfg.CacheDirectory
+	if cacheDir == "" {
+		var err error
+		cacheDir, err = cache.DefaultCacheDir()
 		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
+			logging.Warning("Failed to get default cache directory: %v", err)
+			return cache.WithContext(cache.NewNoOpCache())
 		}
-		defer func() { _ = output.Close() }()
-	} else {
-		output = os.Stdout
 	}
 
-	// Create output writer
-	writer := newResultWriter(output, malwareScanOutputFormat)
-	defer func() { _ = writer.Close() }()
+	tieredCachesMu.Lock()
+	defer tieredCachesMu.Unlock()
+	if c, ok := tieredCaches[cacheDir]; ok {
+		return c
+	}
 
-	// Start scanning
-	results, err := s.Scan(ctx, paths...)
+	fileCache, err := cache.NewFileCache(cacheDir)
 	if err != nil {
-		return fmt.Errorf("scan failed: %w", err)
+		logging.Warning("Failed to create file cache: %v", err)
+		return cache.WithContext(cache.NewNoOpCache())
 	}
+	c := cache.NewTiered(cache.WithContext(fileCache))
+	tieredCaches[cacheDir] = c
+	return c
+}
 
-	// Process results
-	matchCount := 0
-	for result := range results {
-		if result.Error != nil {
-			logging.Warning("Error scanning %s: %v", result.Path, result.Error)
-			continue
-		}
-
-		if result.HasMatches() {
-			matchCount += len(result.Matches)
-			if err := writer.WriteResult(result, sigSet); err != nil {
-				logging.Warning("Error writing result: %v", err)
-			}
-		}
+// Load loads signatures from cache, embedded rules or the API
+func (src *signatureSource) Load(ctx context.Context) (*intel.SignatureSet, error) {
+	sigSet, err := loadSignatures(ctx, src.noc1, src.cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signatures: %w", err)
 	}
+	logging.Info("Loaded %d signatures", sigSet.Count())
+	return sigSet, nil
+}
 
-	// Print summary
-	stats := s.GetStats()
-	logging.Info("")
-	logging.Info("Scan complete:")
-	logging.Info("  Files scanned: %d", stats.FilesScanned)
-	logging.Info("  Files matched: %d", stats.FilesMatched)
-	logging.Info("  Files skipped: %d", stats.FilesSkipped)
-	logging.Info("  Files errored: %d", stats.FilesErrored)
-	logging.Info("  Total matches: %d", matchCount)
-	logging.Info("  Duration: %v", stats.TotalDuration.Round(time.Millisecond))
+// FetchNewer fetches signatures from the API and returns them if they are
+// newer than current, or nil if current is up to date
+func (src *signatureSource) FetchNewer(ctx context.Context, current *intel.SignatureSet) (*intel.SignatureSet, error) {
+	logging.Debug("Checking for signature updates...")
+	sigSet, err := src.noc1.GetPatternsAsSignatureSet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching signatures: %w", err)
+	}
+	if current != nil && sigSet.UpdateTime <= current.UpdateTime {
+		return nil, nil //nolint:nilnil // nil signals no update
+	}
 
-	return nil
+	if err := intel.NewSignatureLoader(src.cache).Save(ctx, sigSet); err != nil {
+		logging.Warning("Failed to cache signatures: %v", err)
+	}
+	return sigSet, nil
 }
 
-func readPathsFromStdin() ([]string, error) {
-	var paths []string
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		path := strings.TrimSpace(scanner.Text())
-		if path != "" {
-			paths = append(paths, path)
-		}
+// loadScanSignatures validates the license and loads malware signatures
+func loadScanSignatures(ctx context.Context, cfg *config.Config) (*intel.SignatureSet, *signatureSource, error) {
+	src, err := newSignatureSource(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("reading stdin: %w", err)
+	sigSet, err := src.Load(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
-	return paths, nil
+	return sigSet, src, nil
 }
 
 func loadSignatures(ctx context.Context, noc1 *api.NOC1Client, c cache.Cache) (*intel.SignatureSet, error) {
@@ -275,67 +1563,205 @@ func loadSignatures(ctx context.Context, noc1 *api.NOC1Client, c cache.Cache) (*
 	return sigSet, nil
 }
 
+// loadMalwareHashes loads a known-malware hash blocklist from a file or an
+// http(s) feed. Feeds are cached and fetched at most once a day.
+func loadMalwareHashes(ctx context.Context, cfg *config.Config, source string) (*intel.HashSet, error) {
+	if !isFeedURL(source) {
+		data, err := os.ReadFile(source) // #nosec G304 -- user-specified hash list
+		if err != nil {
+			return nil, fmt.Errorf("failed to read malware hashes: %w", err)
+		}
+		hashes, err := intel.ParseHashSet(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse malware hashes %s: %w", source, err)
+		}
+		return hashes, nil
+	}
+
+	loader := intel.NewHashLoader(newSignatureCache(cfg), source)
+	if hashes, err := loader.Load(ctx); err == nil {
+		return hashes, nil
+	}
+
+	logging.Verbose("Fetching malware hashes from %s...", source)
+	hashes, err := api.GetMalwareHashes(ctx, source, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading malware hashes: %w", err)
+	}
+	if err := loader.Save(ctx, hashes); err != nil {
+		logging.Warning("Failed to cache malware hashes: %v", err)
+	}
+	return hashes, nil
+}
+
+// loadIOCs loads an indicator-of-compromise list from a file or an http(s)
+// feed. Feeds are cached and fetched at most once a day.
+func loadIOCs(ctx context.Context, cfg *config.Config, source string) (*intel.IOCSet, error) {
+	if !isFeedURL(source) {
+		data, err := os.ReadFile(source) // #nosec G304 -- user-specified IOC list
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IOCs: %w", err)
+		}
+		iocs, err := intel.ParseIOCSet(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IOCs %s: %w", source, err)
+		}
+		return iocs, nil
+	}
+
+	loader := intel.NewIOCLoader(newSignatureCache(cfg), source)
+	if iocs, err := loader.Load(ctx); err == nil {
+		return iocs, nil
+	}
+
+	logging.Verbose("Fetching IOCs from %s...", source)
+	iocs, err := api.GetIOCs(ctx, source, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading IOCs: %w", err)
+	}
+	if err := loader.Save(ctx, iocs); err != nil {
+		logging.Warning("Failed to cache IOCs: %v", err)
+	}
+	return iocs, nil
+}
+
+// isFeedURL reports whether source is an http(s) URL rather than a file
+func isFeedURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
 // Output format constants
 const (
 	formatCSV   = "csv"
 	formatTSV   = "tsv"
 	formatJSON  = "json"
 	formatHuman = "human"
+	formatCEF   = "cef"
+	formatLEEF  = "leef"
 )
 
 // resultWriter writes scan results in various formats
 type resultWriter interface {
 	WriteResult(result *scanner.ScanResult, sigSet *intel.SignatureSet) error
+	WriteFindings(findings []*audit.Finding) error
+	WriteRemediation(result *wordpress.RemediationResult) error
+	WriteSummary(summary *scanner.FleetSummary) error
+	WriteStats(stats *scanner.ScanStatsReport) error
 	Close() error
 }
 
-func newResultWriter(output *os.File, format string) resultWriter {
+// findingName labels an audit finding in scan result output
+func findingName(f *audit.Finding) string {
+	return fmt.Sprintf("%s (%s)", f.Check, f.Severity)
+}
+
+// newResultWriter creates a writer for format. With a sites manifest,
+// every result is attributed to the account and domain owning its path.
+// JSON output is wrapped in an object when a summary will be written.
+func newResultWriter(output *os.File, format string, sites *hosting.Manifest, summary bool) resultWriter {
 	switch format {
 	case formatCSV:
-		return newCSVWriter(output, ',')
+		return newCSVWriter(output, ',', sites)
 	case formatTSV:
-		return newCSVWriter(output, '\t')
+		return newCSVWriter(output, '\t', sites)
 	case formatJSON:
-		return newJSONWriter(output)
+		return newJSONWriter(output, sites, summary)
+	case formatCEF, formatLEEF:
+		return newSIEMWriter(output, format, sites)
 	default:
-		return newHumanWriter(output)
+		return newHumanWriter(output, sites)
+	}
+}
+
+// siteOwner returns the account and domain owning path, if known
+func siteOwner(sites *hosting.Manifest, path string) (string, string) {
+	if sites == nil {
+		return "", ""
 	}
+	site := sites.Lookup(path)
+	if site == nil {
+		return "", ""
+	}
+	return site.Owner, site.Domain
 }
 
+// Record types distinguishing signature matches from the other rows
+// malware-scan writes beside them
+const (
+	recordMatch       = "match"
+	recordFinding     = "finding"
+	recordRemediation = "remediation"
+)
+
 // csvWriter writes results in CSV format
 type csvWriter struct {
 	writer *csv.Writer
 	first  bool
+	sites  *hosting.Manifest
 }
 
-func newCSVWriter(output *os.File, delim rune) *csvWriter {
+func newCSVWriter(output *os.File, delim rune, sites *hosting.Manifest) *csvWriter {
 	w := csv.NewWriter(output)
 	w.Comma = delim
-	// Write header
-	_ = w.Write([]string{"filename", "signature_id", "signature_name", "signature_description", "matched_text"})
-	return &csvWriter{writer: w, first: true}
+	// Write header. New columns go at the end so existing consumers
+	// reading by position keep working.
+	header := []string{"filename", "signature_id", "signature_name", "signature_description", "matched_text", "signature_category", "record_type"}
+	if sites != nil {
+		header = append(header, "owner", "domain")
+	}
+	_ = w.Write(header)
+	return &csvWriter{writer: w, first: true, sites: sites}
+}
+
+// write writes a record, appending the owner and domain columns when
+// attributing results to sites
+func (w *csvWriter) write(path string, record ...string) {
+	if w.sites != nil {
+		owner, domain := siteOwner(w.sites, path)
+		record = append(record, owner, domain)
+	}
+	_ = w.writer.Write(record)
 }
 
 func (w *csvWriter) WriteResult(result *scanner.ScanResult, sigSet *intel.SignatureSet) error {
 	for _, match := range result.Matches {
-		sig, _ := sigSet.GetSignature(match.SignatureID)
-		name := ""
-		desc := ""
-		if sig != nil {
-			name = sig.Name
-			desc = sig.Description
-		}
-		_ = w.writer.Write([]string{
+		name, desc := match.Describe(sigSet)
+		w.write(result.Path,
 			result.Path,
 			fmt.Sprintf("%d", match.SignatureID),
 			name,
 			desc,
 			match.MatchedString,
-		})
+			match.Category,
+			recordMatch,
+		)
 	}
 	return nil
 }
 
+func (w *csvWriter) WriteFindings(findings []*audit.Finding) error {
+	for _, f := range findings {
+		w.write(f.Subject, f.Subject, "", findingName(f), f.Message, "", f.Check, recordFinding)
+	}
+	return nil
+}
+
+func (w *csvWriter) WriteRemediation(result *wordpress.RemediationResult) error {
+	outcome, detail := remediationOutcome(result)
+	w.write(result.Path, result.Path, "", outcome, detail, "", "", recordRemediation)
+	return nil
+}
+
+func (w *csvWriter) WriteSummary(_ *scanner.FleetSummary) error {
+	logging.Warning("--summary is only written in human and JSON output")
+	return nil
+}
+
+func (w *csvWriter) WriteStats(_ *scanner.ScanStatsReport) error {
+	logging.Warning("--stats is only written in JSON and template output; use --summary-output")
+	return nil
+}
+
 func (w *csvWriter) Close() error {
 	w.writer.Flush()
 	if err := w.writer.Error(); err != nil {
@@ -349,62 +1775,176 @@ type jsonWriter struct {
 	output  *os.File
 	encoder *json.Encoder
 	first   bool
+	sites   *hosting.Manifest
+	wrapped bool
+	summary *scanner.FleetSummary
+	stats   *scanner.ScanStatsReport
 }
 
-func newJSONWriter(output *os.File) *jsonWriter {
-	_, _ = output.WriteString("[\n")
-	return &jsonWriter{output: output, encoder: json.NewEncoder(output), first: true}
+func newJSONWriter(output *os.File, sites *hosting.Manifest, wrapped bool) *jsonWriter {
+	if wrapped {
+		_, _ = output.WriteString("{\n\"results\": [\n")
+	} else {
+		_, _ = output.WriteString("[\n")
+	}
+	return &jsonWriter{output: output, encoder: json.NewEncoder(output), first: true, sites: sites, wrapped: wrapped}
 }
 
 type jsonResult struct {
+	RecordType           string `json:"record_type"`
 	Filename             string `json:"filename"`
-	SignatureID          int    `json:"signature_id"`
+	SignatureID          int    `json:"signature_id,omitempty"`
 	SignatureName        string `json:"signature_name"`
 	SignatureDescription string `json:"signature_description"`
+	SignatureCategory    string `json:"signature_category"`
 	MatchedText          string `json:"matched_text"`
+	MatchingTruncated    bool   `json:"matching_truncated,omitempty"`
+	Remediation          string `json:"remediation,omitempty"`
+	BackupPath           string `json:"backup_path,omitempty"`
+	Owner                string `json:"owner,omitempty"`
+	Domain               string `json:"domain,omitempty"`
 }
 
-func (w *jsonWriter) WriteResult(result *scanner.ScanResult, sigSet *intel.SignatureSet) error {
+// matchRecords are the records of a file's matches
+func matchRecords(result *scanner.ScanResult, sigSet *intel.SignatureSet, sites *hosting.Manifest) []jsonResult {
+	records := make([]jsonResult, 0, len(result.Matches))
 	for _, match := range result.Matches {
-		sig, _ := sigSet.GetSignature(match.SignatureID)
-		name := ""
-		desc := ""
-		if sig != nil {
-			name = sig.Name
-			desc = sig.Description
-		}
-
-		if !w.first {
-			_, _ = w.output.WriteString(",\n")
-		}
-		w.first = false
-
+		name, desc := match.Describe(sigSet)
 		jr := jsonResult{
+			RecordType:           recordMatch,
 			Filename:             result.Path,
 			SignatureID:          match.SignatureID,
 			SignatureName:        name,
 			SignatureDescription: desc,
+			SignatureCategory:    match.Category,
 			MatchedText:          match.MatchedString,
+			MatchingTruncated:    result.Truncated,
+		}
+		jr.Owner, jr.Domain = siteOwner(sites, result.Path)
+		records = append(records, jr)
+	}
+	return records
+}
+
+// findingRecords are the records of audit findings
+func findingRecords(findings []*audit.Finding, sites *hosting.Manifest) []jsonResult {
+	records := make([]jsonResult, 0, len(findings))
+	for _, f := range findings {
+		jr := jsonResult{
+			RecordType:           recordFinding,
+			Filename:             f.Subject,
+			SignatureName:        findingName(f),
+			SignatureDescription: f.Message,
+			SignatureCategory:    f.Check,
 		}
-		data, _ := json.MarshalIndent(jr, "  ", "  ")
-		_, _ = w.output.WriteString("  ")
-		_, _ = w.output.Write(data)
+		jr.Owner, jr.Domain = siteOwner(sites, f.Subject)
+		records = append(records, jr)
+	}
+	return records
+}
+
+// remediationRecord is the record of a remediated file
+func remediationRecord(result *wordpress.RemediationResult, sites *hosting.Manifest) jsonResult {
+	outcome, detail := remediationOutcome(result)
+	jr := jsonResult{
+		RecordType:           recordRemediation,
+		Filename:             result.Path,
+		SignatureName:        outcome,
+		SignatureDescription: detail,
+		Remediation:          outcome,
+		BackupPath:           result.BackupPath,
+	}
+	jr.Owner, jr.Domain = siteOwner(sites, result.Path)
+	return jr
+}
+
+// write writes a record to the array
+func (w *jsonWriter) write(jr jsonResult) {
+	if !w.first {
+		_, _ = w.output.WriteString(",\n")
+	}
+	w.first = false
+	data, _ := json.MarshalIndent(jr, "  ", "  ")
+	_, _ = w.output.WriteString("  ")
+	_, _ = w.output.Write(data)
+}
+
+func (w *jsonWriter) WriteResult(result *scanner.ScanResult, sigSet *intel.SignatureSet) error {
+	for _, jr := range matchRecords(result, sigSet, w.sites) {
+		w.write(jr)
+	}
+	return nil
+}
+
+func (w *jsonWriter) WriteFindings(findings []*audit.Finding) error {
+	for _, jr := range findingRecords(findings, w.sites) {
+		w.write(jr)
 	}
 	return nil
 }
 
+func (w *jsonWriter) WriteRemediation(result *wordpress.RemediationResult) error {
+	w.write(remediationRecord(result, w.sites))
+	return nil
+}
+
+func (w *jsonWriter) WriteSummary(summary *scanner.FleetSummary) error {
+	w.summary = summary
+	return nil
+}
+
+func (w *jsonWriter) WriteStats(stats *scanner.ScanStatsReport) error {
+	w.stats = stats
+	return nil
+}
+
 func (w *jsonWriter) Close() error {
-	_, _ = w.output.WriteString("\n]\n")
+	if !w.wrapped {
+		_, _ = w.output.WriteString("\n]\n")
+		return nil
+	}
+
+	_, _ = w.output.WriteString("\n]")
+	if w.summary != nil {
+		if err := w.writeField("summary", w.summary); err != nil {
+			return err
+		}
+	}
+	if w.stats != nil {
+		if err := w.writeField("stats", w.stats); err != nil {
+			return err
+		}
+	}
+	_, _ = w.output.WriteString("\n}\n")
+	return nil
+}
+
+// writeField writes a field of the object wrapping the results
+func (w *jsonWriter) writeField(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json encode error: %w", err)
+	}
+	_, _ = fmt.Fprintf(w.output, ",\n%q: ", name)
+	_, _ = w.output.Write(data)
 	return nil
 }
 
 // humanWriter writes results in human-readable format
 type humanWriter struct {
 	output *os.File
+	sites  *hosting.Manifest
 }
 
-func newHumanWriter(output *os.File) *humanWriter {
-	return &humanWriter{output: output}
+func newHumanWriter(output *os.File, sites *hosting.Manifest) *humanWriter {
+	return &humanWriter{output: output, sites: sites}
+}
+
+// writeOwner prints the account and domain owning path, if known
+func (w *humanWriter) writeOwner(path string) {
+	if owner, domain := siteOwner(w.sites, path); owner != "" || domain != "" {
+		_, _ = fmt.Fprintf(w.output, "  Site: %s (%s)\n", domain, owner)
+	}
 }
 
 func (w *humanWriter) WriteResult(result *scanner.ScanResult, sigSet *intel.SignatureSet) error {
@@ -412,20 +1952,60 @@ func (w *humanWriter) WriteResult(result *scanner.ScanResult, sigSet *intel.Sign
 	yellow := color.New(color.FgYellow)
 
 	for _, match := range result.Matches {
-		sig, _ := sigSet.GetSignature(match.SignatureID)
-		name := fmt.Sprintf("Signature %d", match.SignatureID)
-		if sig != nil && sig.Name != "" {
-			name = sig.Name
+		name, desc := match.Describe(sigSet)
+		if name == "" {
+			name = fmt.Sprintf("Signature %d", match.SignatureID)
 		}
 
 		_, _ = red.Fprintf(w.output, "FOUND: ")
 		_, _ = fmt.Fprintf(w.output, "%s\n", result.Path)
+		w.writeOwner(result.Path)
 		_, _ = yellow.Fprintf(w.output, "  %s", name)
-		if sig != nil && sig.Description != "" {
-			_, _ = fmt.Fprintf(w.output, " - %s", sig.Description)
+		if match.Category != "" {
+			_, _ = fmt.Fprintf(w.output, " [%s]", match.Category)
+		}
+		if desc != "" {
+			_, _ = fmt.Fprintf(w.output, " - %s", desc)
 		}
 		_, _ = fmt.Fprintln(w.output)
 	}
+	if result.Truncated && result.HasMatches() {
+		_, _ = fmt.Fprintln(w.output, "  Stopped at the first match; use --match-all to list every match")
+	}
+	return nil
+}
+
+func (w *humanWriter) WriteFindings(findings []*audit.Finding) error {
+	for _, f := range findings {
+		_, _ = severityColor(f.Severity).Fprintf(w.output, "%s: ", strings.ToUpper(f.Check))
+		_, _ = fmt.Fprintf(w.output, "%s\n", f.Subject)
+		w.writeOwner(f.Subject)
+		_, _ = fmt.Fprintf(w.output, "  [%s] %s\n", f.Severity, f.Message)
+	}
+	return nil
+}
+
+func (w *humanWriter) WriteRemediation(result *wordpress.RemediationResult) error {
+	outcome, detail := remediationOutcome(result)
+	c := color.New(color.FgGreen)
+	if outcome != "remediated" && outcome != "quarantined" {
+		c = color.New(color.FgYellow)
+	}
+	_, _ = c.Fprintf(w.output, "  %s", strings.ToUpper(outcome[:1])+outcome[1:])
+	if detail != "" {
+		_, _ = fmt.Fprintf(w.output, ": %s", detail)
+	}
+	_, _ = fmt.Fprintln(w.output)
+	return nil
+}
+
+func (w *humanWriter) WriteSummary(summary *scanner.FleetSummary) error {
+	writeFleetSummary(w.output, summary)
+	return nil
+}
+
+func (w *humanWriter) WriteStats(_ *scanner.ScanStatsReport) error {
+	logging.Warning("--stats is only written in JSON and template output; use --summary-output")
 	return nil
 }
 
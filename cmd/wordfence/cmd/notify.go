@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/hosting"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/notify"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+// notifyCloseTimeout bounds how long a command waits to send its last
+// notifications
+const notifyCloseTimeout = 30 * time.Second
+
+var noNotify bool
+
+// addNotifyFlags registers the notification flags on a command
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&noNotify, "no-notify", false, "don't send findings to the [notify:NAME] routes of the config file")
+}
+
+// newNotifier starts a notifier for the [notify:NAME] routes of the config
+// file, or returns nil if there are none or --no-notify is set
+func newNotifier(cfg *config.Config) (*notify.Notifier, error) {
+	sections := cfg.Notifications()
+	if noNotify || len(sections) == 0 {
+		return nil, nil
+	}
+	opts, err := clientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := notify.ParseRoutes(sections, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification settings: %w", err)
+	}
+	for _, route := range routes {
+		logging.Verbose("Notifying %s", route)
+	}
+	return notify.New(routes, notify.WithLogger(logging.GetDefaultLogger())), nil
+}
+
+// closeNotifier sends the notifier's last findings
+func closeNotifier(n *notify.Notifier) {
+	if n == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyCloseTimeout)
+	defer cancel()
+	if err := n.Close(ctx); err != nil {
+		logging.Warning("Gave up sending notifications: %v", err)
+	}
+	if dropped := n.Dropped(); dropped > 0 {
+		logging.Warning("Dropped %d notifications", dropped)
+	}
+}
+
+// malwareSeverities rank the signature categories that aren't critical
+var malwareSeverities = map[string]notify.Severity{
+	"ioc":                  notify.SeverityHigh,
+	scanner.NulledCategory: notify.SeverityMedium,
+}
+
+// malwareSeverity ranks a match by its category
+func malwareSeverity(category string) notify.Severity {
+	if s, ok := malwareSeverities[category]; ok {
+		return s
+	}
+	return notify.SeverityCritical
+}
+
+// findingSite names the site holding path: its domain in the sites
+// manifest, or else the deepest scan root holding it
+func findingSite(sites *hosting.Manifest, roots []string, path string) string {
+	if _, domain := siteOwner(sites, path); domain != "" {
+		return domain
+	}
+	rootSites := &hosting.Manifest{}
+	for _, root := range roots {
+		if abs, err := filepath.Abs(root); err == nil {
+			rootSites.Sites = append(rootSites.Sites, &hosting.Site{Docroot: abs})
+		}
+	}
+	if site := rootSites.Lookup(path); site != nil {
+		return site.Docroot
+	}
+	return filepath.Dir(path)
+}
+
+// malwareNotification describes a file's matches as one finding, as
+// severe as its most severe match. Ticketing routes keep one issue per
+// infected site.
+func malwareNotification(result *scanner.ScanResult, sigSet *intel.SignatureSet, sites *hosting.Manifest, roots []string) *notify.Notification {
+	severity := notify.SeverityInfo
+	var names []string
+	for _, match := range result.Matches {
+		severity = max(severity, malwareSeverity(match.Category))
+		name, _ := match.Describe(sigSet)
+		names = append(names, name)
+	}
+
+	note := &notify.Notification{
+		Kind:     notify.KindMalware,
+		Severity: severity,
+		Title:    "Malware found: " + names[0],
+		Path:     result.Path,
+		Site:     findingSite(sites, roots, result.Path),
+		Key:      notify.KindMalware + ":" + result.Path,
+	}
+	note.Ticket = notify.KindMalware + ":" + note.Site
+	if len(names) > 1 {
+		note.Title = fmt.Sprintf("Malware found: %s and %d more", names[0], len(names)-1)
+		note.Text = "Matched " + strings.Join(names, ", ")
+	}
+	if owner, domain := siteOwner(sites, result.Path); domain != "" {
+		note.Text = strings.TrimSpace(fmt.Sprintf("Site %s (%s)\n%s", domain, owner, note.Text))
+	}
+	return note
+}
+
+// vulnSeverity ranks a vulnerability by its CVSS rating, informational
+// ones lowest
+func vulnSeverity(v *intel.Vulnerability) notify.Severity {
+	if v.Informational {
+		return notify.SeverityInfo
+	}
+	if v.CVSS != nil {
+		if s, err := notify.ParseSeverity(v.CVSS.Rating); err == nil {
+			return s
+		}
+		if strings.EqualFold(v.CVSS.Rating, "none") {
+			return notify.SeverityInfo
+		}
+	}
+	return notify.SeverityMedium
+}
+
+// vulnNotification describes a vulnerable component of the site at root as
+// a finding. Ticketing routes keep one issue per vulnerability and site.
+func vulnNotification(root string, m *scanner.VulnMatch) *notify.Notification {
+	v := m.Vulnerability
+	name := m.Name
+	if name == "" {
+		name = m.Slug
+	}
+	note := &notify.Notification{
+		Kind:     notify.KindVulnerability,
+		Severity: vulnSeverity(v),
+		Title:    fmt.Sprintf("Vulnerable %s %s %s", m.SoftwareType, name, m.Version),
+		Text:     v.Title,
+		Path:     m.Path,
+		Link:     v.GetWordfenceLink(),
+		Site:     root,
+		Key:      notify.KindVulnerability + ":" + m.Path + ":" + v.ID,
+		Ticket:   notify.KindVulnerability + ":" + root + ":" + v.ID,
+	}
+	if v.CVE != "" {
+		note.Text += " (" + v.CVE + ")"
+	}
+	return note
+}
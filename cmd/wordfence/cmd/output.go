@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api/objectstore"
+	"github.com/greysquirr3l/wordfence-go/internal/custody"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+// outputCloseTimeout bounds sealing and uploading the output. Both go on
+// after the command is cancelled, so results aren't left unsealed.
+const outputCloseTimeout = 5 * time.Minute
+
+// outputFile is where a command writes its results: stdout, a local file
+// or an s3:// or gs:// location. Remote outputs are spooled to a temporary
+// file and uploaded when closed. With --encrypt-to or --sign-with, the
+// output is sealed when closed, before it is uploaded.
+type outputFile struct {
+	*os.File
+	remote *objectstore.Location
+	sealer *custody.Sealer
+	closed bool
+}
+
+// createOutput opens dest for writing; "" and "-" are stdout
+func createOutput(dest string) (*outputFile, error) {
+	sealer, err := newSealer()
+	if err != nil {
+		return nil, err
+	}
+	if dest == "" || dest == "-" {
+		if sealer != nil {
+			return nil, fmt.Errorf("--encrypt-to and --sign-with need --output")
+		}
+		return &outputFile{File: os.Stdout}, nil
+	}
+
+	if !objectstore.IsRemote(dest) {
+		file, err := os.Create(dest) // #nosec G304 -- user-specified output file
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+		return &outputFile{File: file, sealer: sealer}, nil
+	}
+
+	loc, err := objectstore.ParseLocation(dest)
+	if err != nil {
+		return nil, err
+	}
+	// Fail before scanning rather than after
+	if _, err := objectstore.NewUploader(loc, clientOpts...); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp("", "wordfence-output-*"+filepath.Ext(loc.Key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output spool file: %w", err)
+	}
+	return &outputFile{File: file, remote: loc, sealer: sealer}, nil
+}
+
+// Close closes the output, uploading it first if it is remote. A failed
+// upload leaves the spooled results in place so they can be sent by hand.
+// Sealing and uploading aren't stopped by cancelling ctx. Results that
+// should have been encrypted are removed if encryption fails.
+func (o *outputFile) Close(ctx context.Context) error {
+	if o.closed || o.File == os.Stdout {
+		return nil
+	}
+	o.closed = true
+
+	if err := o.File.Close(); err != nil {
+		return fmt.Errorf("failed to close output file: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), outputCloseTimeout)
+	defer cancel()
+	sealed := &custody.Sealed{Path: o.Name()}
+	if o.sealer != nil {
+		var err error
+		if sealed, err = o.sealer.Seal(ctx, o.Name()); err != nil {
+			if o.sealer.Encrypts() {
+				if removeErr := os.Remove(o.Name()); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+					return fmt.Errorf("failed to seal output, unencrypted results left in %s: %w", o.Name(), err)
+				}
+				return fmt.Errorf("failed to seal output, unencrypted results removed: %w", err)
+			}
+			return fmt.Errorf("failed to seal output: %w", err)
+		}
+		logging.Info("Sealed results in %s (sha256 %s)", sealed.Path, sealed.SHA256)
+		if sealed.Signature != "" {
+			logging.Info("Signed results in %s", sealed.Signature)
+		}
+	}
+	if o.remote == nil {
+		return nil
+	}
+
+	loc := *o.remote
+	loc.Key += o.sealer.Extension()
+	if err := uploadOutput(ctx, &loc, sealed.Path); err != nil {
+		return err
+	}
+	if sealed.Signature != "" {
+		sigLoc := loc
+		sigLoc.Key += o.sealer.SignatureExtension()
+		if err := uploadOutput(ctx, &sigLoc, sealed.Signature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadOutput uploads a spooled output file to loc, removing it once it
+// is uploaded
+func uploadOutput(ctx context.Context, loc *objectstore.Location, path string) error {
+	contentType := mime.TypeByExtension(filepath.Ext(loc.Key))
+	switch {
+	case contentType != "":
+	case filepath.Ext(loc.Key) == ".age" || filepath.Ext(loc.Key) == ".gpg":
+		contentType = "application/octet-stream"
+	default:
+		contentType = "text/plain; charset=utf-8"
+	}
+	if err := objectstore.UploadFile(ctx, loc, path, contentType, clientOpts...); err != nil {
+		return fmt.Errorf("%w (results kept in %s)", err, path)
+	}
+	_ = os.Remove(path)
+	logging.Info("Uploaded results to %s", loc)
+	return nil
+}
@@ -0,0 +1,376 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/api/wporg"
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+var (
+	remediateOutput       string
+	remediateOutputFormat string
+	remediateBackup       bool
+	remediateBackupDir    string
+	remediateDryRun       bool
+	remediateReadStdin    bool
+	remediateSource       string
+	remediateWorkers      int
+	remediateAPILimit     int
+	remediateManifests    string
+	remediateQuarantine   bool
+	remediateQuarantineTo string
+	rollbackForce         bool
+)
+
+const (
+	// remediateProgressInterval is how often remediation progress is logged
+	remediateProgressInterval = 10 * time.Second
+	// defaultRemediateAPIConcurrency is the default number of requests in
+	// flight to each API host while remediating
+	defaultRemediateAPIConcurrency = 4
+)
+
+var remediateCmd = &cobra.Command{
+	Use:   "remediate [paths...]",
+	Short: "Restore infected WordPress files to their original versions",
+	Long: `Restore WordPress core, plugin and theme files to the content of their
+release, for example after malware-scan found them infected.
+
+Original files come from the source set by remediation_source in the
+config file, or --source:
+
+  noc1           the Wordfence API (requires a license)
+  wordpress.org  the release packages on wordpress.org, checked against
+                 the published checksums of core and plugin files
+
+Custom code and premium extensions that aren't on wordpress.org can't be
+remediated and are reported as such. --quarantine instead moves the given
+files, known or not, into the quarantine directory.
+
+Files in a directory are remediated --workers at a time, with at most
+--api-concurrency requests in flight to each API host. Requests that fail
+with a server error or rate limit are retried, waiting as long as the
+server asks.
+
+Each run that changes files is recorded in a manifest of the original
+and new hash and backup of every file, and can be undone with
+"wordfence remediate rollback <run-id>".`,
+	Example: `  # Remediate a single file
+  wordfence remediate /var/www/wordpress/wp-includes/infected.php
+
+  # Remediate an entire installation without a license
+  wordfence remediate --source wordpress.org /var/www/wordpress
+
+  # Preview changes without modifying files
+  wordfence remediate --dry-run /var/www/wordpress
+
+  # Move a backdoor out of the web root
+  wordfence remediate --quarantine /var/www/wordpress/wp-content/uploads/shell.php
+
+  # Undo the last run
+  wordfence remediate rollback latest`,
+	Args: func(_ *cobra.Command, args []string) error {
+		if !remediateReadStdin && len(args) == 0 {
+			return fmt.Errorf("at least one path is required (or use --read-stdin)")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRemediate(cmd.Context(), args)
+	},
+}
+
+var remediateRollbackCmd = &cobra.Command{
+	Use:   "rollback <run-id>",
+	Short: "Restore the files changed by a remediation run from their backups",
+	Long: `Restore every file changed by a remediation run from its backup. Runs are
+named by ID, a unique ID prefix or "latest".
+
+Every backup is checked against the run's manifest before any file is
+touched, and the rollback is refused if a backup is missing or a file has
+changed since it was remediated, unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return runRemediateRollback(args[0])
+	},
+}
+
+func init() {
+	remediateCmd.Flags().StringVarP(&remediateOutput, "output", "o", "", "output file or s3:// or gs:// location (default: stdout)")
+	addCustodyFlags(remediateCmd)
+	remediateCmd.Flags().StringVar(&remediateOutputFormat, "output-format", "human", "output format: csv, tsv, json, human")
+	remediateCmd.Flags().BoolVar(&remediateBackup, "backup", true, "back up files before remediating them")
+	remediateCmd.Flags().StringVar(&remediateBackupDir, "backup-dir", "", "directory for backups (default: next to each file)")
+	remediateCmd.Flags().BoolVar(&remediateDryRun, "dry-run", false, "report what would be remediated without modifying files")
+	remediateCmd.Flags().BoolVar(&remediateReadStdin, "read-stdin", false, "read paths from stdin")
+	remediateCmd.Flags().IntVarP(&remediateWorkers, "workers", "w", wordpress.DefaultRemediationWorkers, "number of files remediated at once")
+	remediateCmd.Flags().IntVar(&remediateAPILimit, "api-concurrency", defaultRemediateAPIConcurrency, "maximum requests in flight to each API host")
+	remediateCmd.Flags().StringVar(&remediateSource, "source", "", "where to fetch original files: noc1 or wordpress.org (default: remediation_source from the config)")
+	remediateCmd.Flags().BoolVar(&remediateQuarantine, "quarantine", false, "move the given files into the quarantine directory instead of restoring them")
+	remediateCmd.Flags().StringVar(&remediateQuarantineTo, "quarantine-dir", config.DefaultQuarantinePath(), "directory quarantined files are moved to")
+	remediateCmd.PersistentFlags().StringVar(&remediateManifests, "manifests", config.DefaultRemediationsPath(), "directory of remediation run manifests")
+
+	remediateRollbackCmd.Flags().BoolVar(&rollbackForce, "force", false, "restore files changed since the run, and runs already rolled back")
+	remediateCmd.AddCommand(remediateRollbackCmd)
+
+	rootCmd.AddCommand(remediateCmd)
+}
+
+func runRemediate(ctx context.Context, paths []string) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	if remediateReadStdin {
+		stdinPaths, err := readPathList(os.Stdin, false)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, stdinPaths...)
+	}
+
+	// Quarantining fetches nothing, so needs no source or license
+	var source wordpress.RemediationSource
+	if !remediateQuarantine {
+		var err error
+		source, err = newRemediationSource(cfg, remediateSource, remediateAPILimit)
+		if err != nil {
+			return err
+		}
+	}
+	remediator := wordpress.NewRemediator(source, &wordpress.RemediatorConfig{
+		CreateBackup:  remediateBackup,
+		BackupDir:     config.ExpandPath(remediateBackupDir),
+		DryRun:        remediateDryRun,
+		Workers:       remediateWorkers,
+		QuarantineDir: config.ExpandPath(remediateQuarantineTo),
+	})
+	remediator.SetLogger(logging.GetDefaultLogger())
+
+	started := time.Now()
+	results, stats := wordpress.CollectResults(logRemediationProgress(remediatePaths(ctx, remediator, paths)))
+	manifestErr := saveRemediationManifest(remediateManifests, paths, started, results)
+	if err := writeRemediationResults(ctx, results); err != nil {
+		return err
+	}
+	if manifestErr != nil {
+		return manifestErr
+	}
+
+	logging.Info("")
+	if remediateQuarantine {
+		logging.Info("Quarantine complete: %d quarantined, %d failed of %d files",
+			stats.Quarantined, stats.Failed, stats.Total)
+	} else {
+		logging.Info("Remediation complete: %d remediated, %d failed, %d unknown of %d files",
+			stats.Remediated, stats.Failed, stats.Unknown, stats.Total)
+	}
+	return nil
+}
+
+// saveRemediationManifest records the files a run changed in the manifest
+// directory dir so it can be rolled back
+func saveRemediationManifest(dir string, paths []string, started time.Time, results []*wordpress.RemediationResult) error {
+	manifest := wordpress.NewRemediationManifest(paths, started)
+	for _, result := range results {
+		manifest.AddResult(result)
+	}
+	if len(manifest.Entries) == 0 {
+		return nil
+	}
+	manifest.Finish(time.Now())
+
+	store := wordpress.OpenManifests(config.ExpandPath(dir))
+	if err := store.Save(manifest); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(manifest.Entries, func(e *wordpress.ManifestEntry) bool { return e.BackupPath == "" }) {
+		logging.Warning("Run %s made no backups and can't be rolled back", manifest.ID)
+	} else {
+		logging.Info("Run %s recorded; undo it with \"wordfence remediate rollback %s\"", manifest.ID, manifest.ID)
+	}
+	return nil
+}
+
+func runRemediateRollback(id string) error {
+	store := wordpress.OpenManifests(config.ExpandPath(remediateManifests))
+	manifest, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	if err := manifest.Rollback(rollbackForce); err != nil {
+		return fmt.Errorf("rolling back run %s: %w", manifest.ID, err)
+	}
+	if err := store.Save(manifest); err != nil {
+		return err
+	}
+	logging.Info("Restored %d files changed by run %s", len(manifest.Entries), manifest.ID)
+	return nil
+}
+
+// newRemediationSource creates the source of original files named by
+// source, or by the remediation_source setting if source is empty, with at
+// most apiLimit requests in flight to its API
+func newRemediationSource(cfg *config.Config, source string, apiLimit int) (wordpress.RemediationSource, error) {
+	name := cfg.RemediationSource
+	if source != "" {
+		name = source
+	}
+
+	opts := append(clientOpts[:len(clientOpts):len(clientOpts)], api.WithConcurrencyLimit(apiLimit))
+	switch name {
+	case wordpress.RemediationSourceNOC1, "":
+		if err := requireLicense(cfg); err != nil {
+			return nil, err
+		}
+		noc1 := api.NewNOC1Client(
+			api.WithNOC1License(&api.License{Key: cfg.License}),
+			api.WithNOC1ClientOptions(opts...),
+		)
+		return wordpress.NewNOC1RemediationSource(noc1), nil
+	case wordpress.RemediationSourceWPOrg:
+		client := wporg.NewClient(wporg.WithClientOptions(opts...))
+		return wordpress.NewWPOrgRemediationSource(client, wporg.NewPackageLoader(client, newSignatureCache(cfg))), nil
+	default:
+		return nil, fmt.Errorf("invalid remediation source %q: use %s or %s",
+			name, wordpress.RemediationSourceNOC1, wordpress.RemediationSourceWPOrg)
+	}
+}
+
+// remediatePaths remediates each file and every file in each directory
+func remediatePaths(ctx context.Context, remediator *wordpress.Remediator, paths []string) <-chan *wordpress.RemediationResult {
+	results := make(chan *wordpress.RemediationResult)
+	go func() {
+		defer close(results)
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			switch {
+			case err != nil:
+				results <- &wordpress.RemediationResult{Path: path, Error: err}
+			case remediateQuarantine && info.IsDir():
+				results <- &wordpress.RemediationResult{Path: path, Error: fmt.Errorf("--quarantine takes files, not directories")}
+			case remediateQuarantine:
+				results <- remediator.QuarantineFile(ctx, path)
+			case info.IsDir():
+				logging.Info("Remediating files in %s...", path)
+				for result := range remediator.RemediateDirectory(ctx, path) {
+					results <- result
+				}
+			default:
+				results <- remediator.RemediateFile(ctx, path)
+			}
+		}
+	}()
+	return results
+}
+
+// logRemediationProgress passes results through, logging how many files
+// have been processed every remediateProgressInterval
+func logRemediationProgress(results <-chan *wordpress.RemediationResult) <-chan *wordpress.RemediationResult {
+	out := make(chan *wordpress.RemediationResult)
+	go func() {
+		defer close(out)
+		var processed, remediated, failed int
+		last := time.Now()
+		for result := range results {
+			processed++
+			switch {
+			case result.Error != nil:
+				failed++
+			case result.Remediated:
+				remediated++
+			}
+			if time.Since(last) >= remediateProgressInterval {
+				last = time.Now()
+				logging.Info("Processed %d files: %d remediated, %d failed", processed, remediated, failed)
+			}
+			out <- result
+		}
+	}()
+	return out
+}
+
+// writeRemediationResults writes remediation results to the configured
+// output
+func writeRemediationResults(ctx context.Context, results []*wordpress.RemediationResult) (err error) {
+	file, err := createOutput(remediateOutput)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	output := file.File
+
+	type resultOutput struct {
+		Path        string `json:"path"`
+		Type        string `json:"type,omitempty"`
+		Known       bool   `json:"known"`
+		Remediated  bool   `json:"remediated"`
+		Quarantined bool   `json:"quarantined,omitempty"`
+		BackupPath  string `json:"backup_path,omitempty"`
+		Error       string `json:"error,omitempty"`
+	}
+	rows := make([]resultOutput, len(results))
+	for i, r := range results {
+		rows[i] = resultOutput{Path: r.Path, Known: r.Known, Remediated: r.Remediated, Quarantined: r.Quarantined, BackupPath: r.BackupPath}
+		if r.Identity != nil && r.Identity.IsKnown() {
+			rows[i].Type = string(r.Identity.Type)
+		}
+		if r.Error != nil {
+			rows[i].Error = r.Error.Error()
+		}
+	}
+
+	switch remediateOutputFormat {
+	case formatCSV, formatTSV:
+		w := csv.NewWriter(output)
+		if remediateOutputFormat == formatTSV {
+			w.Comma = '\t'
+		}
+		_ = w.Write([]string{"path", "type", "known", "remediated", "quarantined", "backup_path", "error"})
+		for _, r := range rows {
+			_ = w.Write([]string{r.Path, r.Type, fmt.Sprint(r.Known), fmt.Sprint(r.Remediated), fmt.Sprint(r.Quarantined), r.BackupPath, r.Error})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("csv writer error: %w", err)
+		}
+	case formatJSON:
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			return fmt.Errorf("json encode error: %w", err)
+		}
+	default:
+		for _, r := range rows {
+			switch {
+			case r.Error != "":
+				_, _ = fmt.Fprintf(output, "%s %s: %s\n", color.RedString("[failed]"), r.Path, r.Error)
+			case r.Quarantined:
+				_, _ = fmt.Fprintf(output, "%s %s -> %s\n", color.GreenString("[quarantined]"), r.Path, r.BackupPath)
+			case !r.Known:
+				_, _ = fmt.Fprintf(output, "%s %s\n", color.YellowString("[unknown]"), r.Path)
+			case r.Remediated:
+				_, _ = fmt.Fprintf(output, "%s %s\n", color.GreenString("[remediated]"), r.Path)
+			}
+		}
+	}
+	return nil
+}
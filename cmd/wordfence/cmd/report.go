@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/hosting"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/report"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+)
+
+// reportCloseTimeout bounds how long a command waits to deliver its last
+// events before leaving them in the queue
+const reportCloseTimeout = 30 * time.Second
+
+var (
+	reportTo        string
+	reportAgentID   string
+	reportCert      string
+	reportKey       string
+	reportCA        string
+	reportQueue     string
+	reportHeartbeat time.Duration
+)
+
+// addReportFlags registers the collector flags on a command
+func addReportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reportTo, "report-to", "", "stream results and heartbeats to this collector URL")
+	cmd.Flags().StringVar(&reportAgentID, "report-agent-id", "", "agent ID sent to the collector (default: hostname)")
+	cmd.Flags().StringVar(&reportCert, "report-cert", "", "client certificate (PEM) for mutual TLS with the collector (default: tls_cert)")
+	cmd.Flags().StringVar(&reportKey, "report-key", "", "client certificate key (PEM) for mutual TLS with the collector (default: tls_key)")
+	cmd.Flags().StringVar(&reportCA, "report-ca", "", "CA bundle (PEM) used to verify the collector (default: tls_ca or system roots)")
+	cmd.Flags().StringVar(&reportQueue, "report-queue", config.DefaultReportQueuePath(), "directory of reports waiting for the collector")
+	cmd.Flags().DurationVar(&reportHeartbeat, "report-heartbeat", report.DefaultHeartbeatInterval, "heartbeat interval (0 disables)")
+}
+
+// newReporter starts a reporter for --report-to, or returns nil if it
+// isn't set. The collector is sent the tls_cert and tls_key client
+// certificate unless --report-cert and --report-key give another.
+func newReporter(cfg *config.Config) (*report.Reporter, error) {
+	if reportTo == "" {
+		return nil, nil
+	}
+
+	certFile, keyFile, caFile := reportCert, reportKey, reportCA
+	if certFile == "" && keyFile == "" {
+		certFile, keyFile = config.ExpandPath(cfg.TLSCert), config.ExpandPath(cfg.TLSKey)
+	}
+	if caFile == "" {
+		caFile = config.ExpandPath(cfg.TLSCA)
+	}
+	tlsConfig, err := api.LoadTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collector TLS settings: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	agent := report.Agent{ID: reportAgentID, Hostname: hostname, Version: version.GetVersion()}
+	if agent.ID == "" {
+		agent.ID = hostname
+	}
+
+	r, err := report.NewReporter(reportTo,
+		report.WithAgent(agent),
+		report.WithQueueDir(reportQueue),
+		report.WithHeartbeatInterval(reportHeartbeat),
+		report.WithClientOptions(api.WithTLSConfig(tlsConfig)),
+		report.WithLogger(logging.GetDefaultLogger()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start reporting: %w", err)
+	}
+	logging.Verbose("Reporting to %s as agent %s", reportTo, agent.ID)
+	return r, nil
+}
+
+// closeReporter delivers the reporter's last events, queueing whatever
+// the collector doesn't take in time
+func closeReporter(r *report.Reporter) {
+	if r == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reportCloseTimeout)
+	defer cancel()
+	if err := r.Close(ctx); err != nil {
+		logging.Warning("Collector unreachable, reports left in %s", reportQueue)
+	}
+}
+
+// scanReport reports the progress of one scan to a collector. A nil
+// scanReport reports nothing.
+type scanReport struct {
+	reporter *report.Reporter
+	scanID   string
+}
+
+// scanStarted is the data of a scan_started event
+type scanStarted struct {
+	Kind  string   `json:"kind"`
+	Paths []string `json:"paths"`
+}
+
+// scanFinished is the data of a scan_finished event
+type scanFinished struct {
+	Kind         string  `json:"kind"`
+	Matches      int     `json:"matches"`
+	FilesScanned int64   `json:"files_scanned,omitempty"`
+	FilesMatched int64   `json:"files_matched,omitempty"`
+	FilesSkipped int64   `json:"files_skipped,omitempty"`
+	Duration     float64 `json:"duration_seconds"`
+	Cancelled    bool    `json:"cancelled,omitempty"`
+}
+
+// startScanReport reports the start of a scan under the history record's
+// ID, so the collector and local history agree, or a new one in the same
+// format when the scan isn't recorded
+func startScanReport(r *report.Reporter, kind string, paths []string, record *scanner.ScanRecord) *scanReport {
+	if r == nil {
+		return nil
+	}
+	if record == nil {
+		record = scanner.NewScanRecord(kind, paths, time.Now())
+	}
+	sr := &scanReport{reporter: r, scanID: record.ID}
+	r.Report(report.EventScanStarted, sr.scanID, scanStarted{Kind: kind, Paths: paths})
+	return sr
+}
+
+// result reports the matches in a malware scan result
+func (sr *scanReport) result(result *scanner.ScanResult, sigSet *intel.SignatureSet, sites *hosting.Manifest) {
+	if sr == nil {
+		return
+	}
+	for _, match := range result.Matches {
+		name, desc := match.Describe(sigSet)
+		jr := jsonResult{
+			Filename:             result.Path,
+			SignatureID:          match.SignatureID,
+			SignatureName:        name,
+			SignatureDescription: desc,
+			SignatureCategory:    match.Category,
+			MatchedText:          match.MatchedString,
+		}
+		jr.Owner, jr.Domain = siteOwner(sites, result.Path)
+		sr.reporter.Report(report.EventResult, sr.scanID, jr)
+	}
+}
+
+// vulnerabilities reports a site's vulnerable components
+func (sr *scanReport) vulnerabilities(matches []*scanner.VulnMatch) {
+	if sr == nil {
+		return
+	}
+	for _, m := range matches {
+		sr.reporter.Report(report.EventVulnerability, sr.scanID, vulnEvent{
+			SoftwareType: string(m.SoftwareType),
+			Slug:         m.Slug,
+			Name:         m.Name,
+			Version:      m.Version,
+			VulnID:       m.Vulnerability.ID,
+			Title:        m.Vulnerability.Title,
+			CVE:          m.Vulnerability.CVE,
+			Path:         m.Path,
+		})
+	}
+}
+
+// finished reports the end of the scan
+func (sr *scanReport) finished(data scanFinished) {
+	if sr == nil {
+		return
+	}
+	sr.reporter.Report(report.EventScanFinished, sr.scanID, data)
+}
+
+// vulnEvent is the data of a vulnerability event
+type vulnEvent struct {
+	SoftwareType string `json:"software_type"`
+	Slug         string `json:"slug"`
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	VulnID       string `json:"vulnerability_id"`
+	Title        string `json:"title,omitempty"`
+	CVE          string `json:"cve,omitempty"`
+	Path         string `json:"path"`
+}
@@ -4,15 +4,21 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
+	"github.com/greysquirr3l/wordfence-go/internal/api"
 	"github.com/greysquirr3l/wordfence-go/internal/config"
 	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
 	cfgFile      string
 	cfg          *config.Config
+	clientOpts   []api.ClientOption
 	debugFlag    bool
 	verboseFlag  bool
 	quietFlag    bool
@@ -20,8 +26,18 @@ var (
 	licenseFlag  string
 	cacheDirFlag string
 	noCacheFlag  bool
+	tlsCertFlag  string
+	tlsKeyFlag   string
+	tlsCAFlag    string
+	strictConfig bool
+	profileFlag  string
+	regexEngine  string
 )
 
+// mutuallyExclusiveAnnotation is where cobra records a flag's mutually
+// exclusive groups
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
 // rootCmd represents the base command.
 var rootCmd = &cobra.Command{
 	Use:   "wordfence",
@@ -32,14 +48,18 @@ PHP/other malware and WordPress vulnerabilities.
 It can scan filesystems for malware signatures and check WordPress
 installations for known vulnerabilities in core, plugins, and themes.`,
 	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
-		// Skip config loading for version, help, and configure commands
-		if cmd.Name() == "version" || cmd.Name() == "help" || cmd.Name() == "configure" {
+		startCommandSpan(cmd)
+		if skipsConfig(cmd) {
 			return nil
 		}
 
 		// Load configuration
 		var err error
-		cfg, err = config.Load(cfgFile)
+		profile := profileFlag
+		if profile == "" {
+			profile = os.Getenv("WORDFENCE_CLI_PROFILE")
+		}
+		cfg, err = config.Load(cfgFile, profile)
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
@@ -69,24 +89,57 @@ installations for known vulnerabilities in core, plugins, and themes.`,
 		if cmd.Flags().Changed("no-cache") {
 			cfg.CacheEnabled = !noCacheFlag
 		}
+		if cmd.Flags().Changed("tls-cert") {
+			cfg.TLSCert = tlsCertFlag
+		}
+		if cmd.Flags().Changed("tls-key") {
+			cfg.TLSKey = tlsKeyFlag
+		}
+		if cmd.Flags().Changed("tls-ca") {
+			cfg.TLSCA = tlsCAFlag
+		}
+		if cmd.Flags().Changed("regex-engine") {
+			cfg.RegexEngine = regexEngine
+		}
+		if err := scanner.ValidateRegexEngine(cfg.RegexEngine); err != nil {
+			return err
+		}
 
 		// Configure logging based on flags
 		configureLogging(cfg)
 
+		if err := reportConfigIssues(cmd, cfg); err != nil {
+			return err
+		}
+		if err := applyCommandSettings(cmd, cfg); err != nil {
+			return err
+		}
+
+		clientOpts, err = clientOptions(cfg)
+		if err != nil {
+			return err
+		}
+
 		return nil
 	},
 }
 
 // Execute runs the root command.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	registerCompletions(rootCmd)
+	stopTracing := startTracing()
+	cmd, err := rootCmd.ExecuteC()
+	endCommandSpan(err)
+	stopTracing()
+	if err != nil {
 		os.Exit(1)
 	}
+	notifyNewVersion(cmd)
 }
 
 func init() {
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ~/.config/wordfence/wordfence-cli.ini)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file in INI, YAML or TOML format (default: ~/.config/wordfence/wordfence-cli.ini, .yaml or .toml)")
 	rootCmd.PersistentFlags().StringVar(&licenseFlag, "license", "", "Wordfence CLI license key")
 	rootCmd.PersistentFlags().StringVar(&cacheDirFlag, "cache-dir", "", "cache directory (default: ~/.cache/wordfence)")
 	rootCmd.PersistentFlags().BoolVar(&noCacheFlag, "no-cache", false, "disable caching")
@@ -94,6 +147,12 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&verboseFlag, "verbose", false, "enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&quietFlag, "quiet", false, "suppress non-error output")
 	rootCmd.PersistentFlags().BoolVar(&noColorFlag, "no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().StringVar(&tlsCertFlag, "tls-cert", "", "client certificate (PEM) for servers and proxies requiring mutual TLS")
+	rootCmd.PersistentFlags().StringVar(&tlsKeyFlag, "tls-key", "", "client certificate key (PEM)")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "apply a built-in profile (gentle, balanced, aggressive, adaptive) or the [profile:NAME] section of the config file (default: $WORDFENCE_CLI_PROFILE)")
+	rootCmd.PersistentFlags().BoolVar(&strictConfig, "strict-config", false, "fail if the config file has unknown settings or invalid values")
+	rootCmd.PersistentFlags().StringVar(&regexEngine, "regex-engine", "", "engine malware signatures are matched with: auto, regexp2 to use less memory, or re2 in builds with -tags re2_cgo (default: auto)")
+	rootCmd.PersistentFlags().StringVar(&tlsCAFlag, "tls-ca", "", "CA bundle (PEM) used to verify servers (default: system roots)")
 }
 
 func configureLogging(cfg *config.Config) {
@@ -114,7 +173,133 @@ func configureLogging(cfg *config.Config) {
 	logging.SetDefaultColored(!cfg.NoColor)
 }
 
+// skipsConfig reports whether a command runs without loading the config:
+// version, help and configure; config validate, which reports a broken
+// config itself; and shell completion and documentation, which must work
+// whatever the config holds
+func skipsConfig(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case "version", "help", "configure", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	if cmd.HasParent() {
+		switch commandSection(cmd.Parent()) {
+		case "completion", "docs":
+			return true
+		}
+	}
+	return commandSection(cmd) == "config validate"
+}
+
+// reportConfigIssues warns about problems in the config file, or fails
+// with --strict-config
+func reportConfigIssues(cmd *cobra.Command, cfg *config.Config) error {
+	if cfg.ConfigFile == "" {
+		return nil
+	}
+	issues, err := config.ValidateFile(cfg.ConfigFile, commandFlags(cmd.Root()))
+	if err != nil || len(issues) == 0 {
+		return err
+	}
+	if strictConfig {
+		for _, issue := range issues {
+			logging.Error("%s: %s", cfg.ConfigFile, issue)
+		}
+		return fmt.Errorf("config file %s has %d problem(s) (see \"wordfence config validate\")", cfg.ConfigFile, len(issues))
+	}
+	for _, issue := range issues {
+		logging.Warning("%s: %s", cfg.ConfigFile, issue)
+	}
+	return nil
+}
+
+// commandFlags returns the flags each command under root takes in its
+// section of the config file. Global flags are set in [DEFAULT] or a
+// profile instead.
+func commandFlags(root *cobra.Command) config.CommandFlags {
+	commands := make(config.CommandFlags)
+	var walk func(*cobra.Command)
+	walk = func(parent *cobra.Command) {
+		for _, cmd := range parent.Commands() {
+			if !cmd.IsAvailableCommand() {
+				continue
+			}
+			flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+			add := func(f *pflag.Flag) {
+				if f.Name != "help" && root.PersistentFlags().Lookup(f.Name) == nil {
+					flags.AddFlag(f)
+				}
+			}
+			cmd.LocalFlags().VisitAll(add)
+			cmd.InheritedFlags().VisitAll(add)
+			commands[commandSection(cmd)] = flags
+			walk(cmd)
+		}
+	}
+	walk(root)
+	return commands
+}
+
+// commandSection names the config file section of a command, such as
+// malware-scan or "remediate rollback"
+func commandSection(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
+
+// applyCommandSettings sets the flags of cmd from its config file section
+// and the profile. Flags given on the command line win, including over
+// settings for flags they are mutually exclusive with.
+func applyCommandSettings(cmd *cobra.Command, cfg *config.Config) error {
+	settings := cfg.CommandSettings(commandSection(cmd))
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed || cmd.Root().PersistentFlags().Lookup(name) != nil || exclusiveFlagChanged(cmd, flag) {
+			continue
+		}
+		if err := config.CheckFlagSetting(name, settings[name]); err != nil {
+			return fmt.Errorf("config setting %s for %s: %w", name, commandSection(cmd), err)
+		}
+		if err := cmd.Flags().Set(name, settings[name]); err != nil {
+			return fmt.Errorf("config setting %s for %s: %w", name, commandSection(cmd), err)
+		}
+		logging.Debug("Set --%s=%s from %s", name, settings[name], cfg.CommandSettingSource(commandSection(cmd), name))
+	}
+	return nil
+}
+
+// exclusiveFlagChanged reports whether a flag mutually exclusive with flag
+// was given on the command line
+func exclusiveFlagChanged(cmd *cobra.Command, flag *pflag.Flag) bool {
+	for _, group := range flag.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Fields(group) {
+			if other := cmd.Flags().Lookup(name); other != nil && other != flag && other.Changed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetConfig returns the loaded configuration.
 func GetConfig() *config.Config {
 	return cfg
 }
+
+// clientOptions returns the options shared by every API client, such as
+// the client certificate from the tls_cert, tls_key and tls_ca settings
+func clientOptions(cfg *config.Config) ([]api.ClientOption, error) {
+	if cfg.TLSCert == "" && cfg.TLSKey == "" && cfg.TLSCA == "" {
+		return nil, nil
+	}
+	tlsConfig, err := api.LoadTLSConfig(config.ExpandPath(cfg.TLSCert), config.ExpandPath(cfg.TLSKey), config.ExpandPath(cfg.TLSCA))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings: %w", err)
+	}
+	return []api.ClientOption{api.WithTLSConfig(tlsConfig)}, nil
+}
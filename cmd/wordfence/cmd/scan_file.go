@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/daemon"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	scanFileFast   bool
+	scanFileSocket string
+	scanFileJSON   bool
+	scanFileRedact bool
+)
+
+var scanFileCmd = &cobra.Command{
+	Use:   "scan-file <file>",
+	Short: "Scan a single file (for upload hooks)",
+	Long: `Scan a single file and report whether it matches any malware signature.
+
+With --fast the scan is sent to a running "wordfence daemon", which keeps
+compiled signatures in memory and typically answers in a few milliseconds.
+If no daemon is reachable the file is scanned in-process instead.
+
+The exit status is 0 if the file is clean and 1 if malware was found or the
+scan failed, so the command can be called synchronously from upload handlers.`,
+	Example: `  # Scan an uploaded file via the daemon
+  wordfence scan-file --fast /tmp/phpA1b2C3
+
+  # JSON output for programmatic use
+  wordfence scan-file --fast --json /tmp/phpA1b2C3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runScanFile(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	scanFileCmd.Flags().BoolVar(&scanFileFast, "fast", false, "use a running daemon if available")
+	scanFileCmd.Flags().StringVar(&scanFileSocket, "socket", daemon.DefaultSocketPath(), "daemon unix socket path")
+	scanFileCmd.Flags().BoolVar(&scanFileJSON, "json", false, "write the result as JSON")
+	scanFileCmd.Flags().BoolVar(&scanFileRedact, "redact", false, "mask passwords, API keys, tokens and email addresses in matched text")
+
+	rootCmd.AddCommand(scanFileCmd)
+}
+
+func runScanFile(ctx context.Context, path string) error {
+
+	var resp *daemon.ScanResponse
+	if scanFileFast {
+		var err error
+		resp, err = daemon.NewClient(scanFileSocket).ScanFile(ctx, path)
+		if err != nil {
+			logging.Debug("Daemon unavailable, scanning in-process: %v", err)
+			resp = nil
+		}
+	}
+
+	if resp == nil {
+		var err error
+		resp, err = scanFileInProcess(ctx, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if scanFileRedact {
+		// The daemon may not have been started with --redact
+		for i := range resp.Matches {
+			resp.Matches[i].MatchedText = scanner.RedactSecrets(resp.Matches[i].MatchedText)
+		}
+	}
+	if err := writeScanResponse(resp, scanFileJSON); err != nil {
+		return err
+	}
+
+	if resp.Error != "" {
+		return fmt.Errorf("scan failed: %s", resp.Error)
+	}
+	if resp.HasMatches() {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// scanFileInProcess loads signatures and scans path without a daemon
+func scanFileInProcess(ctx context.Context, path string) (*daemon.ScanResponse, error) {
+	cfg := GetConfig()
+	if cfg == nil {
+		return nil, fmt.Errorf("configuration not loaded")
+	}
+	if err := requireLicense(cfg); err != nil {
+		return nil, err
+	}
+
+	sigSet, _, err := loadScanSignatures(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	s := scanner.NewScanner(sigSet, scanner.WithScanRegexEngine(cfg.RegexEngine))
+	result := s.ScanSingleFile(ctx, path)
+	logging.Debug("Scanned %s in %v", path, time.Since(start).Round(time.Millisecond))
+
+	return daemon.NewScanResponse(result, sigSet), nil
+}
+
+// writeScanResponse prints a daemon scan result to stdout
+func writeScanResponse(resp *daemon.ScanResponse, asJSON bool) error {
+	if asJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			return fmt.Errorf("json encode error: %w", err)
+		}
+		return nil
+	}
+
+	switch {
+	case resp.Error != "":
+		_, _ = fmt.Fprintf(os.Stdout, "%s: ERROR %s\n", resp.Path, resp.Error)
+	case resp.HasMatches():
+		for _, m := range resp.Matches {
+			name := m.SignatureName
+			if name == "" {
+				name = fmt.Sprintf("Signature %d", m.SignatureID)
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "%s: FOUND %s", resp.Path, name)
+			if m.SignatureCategory != "" {
+				_, _ = fmt.Fprintf(os.Stdout, " [%s]", m.SignatureCategory)
+			}
+			if m.SignatureDescription != "" {
+				_, _ = fmt.Fprintf(os.Stdout, " - %s", m.SignatureDescription)
+			}
+			_, _ = fmt.Fprintln(os.Stdout)
+		}
+	default:
+		_, _ = fmt.Fprintf(os.Stdout, "%s: OK\n", resp.Path)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+)
+
+// scanManifestSuffix names the default manifest after the results file
+const scanManifestSuffix = ".manifest.json"
+
+var (
+	scanManifestPath string
+	noScanManifest   bool
+)
+
+// addScanManifestFlags registers the scan manifest flags on a command
+func addScanManifestFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&scanManifestPath, "scan-manifest", "", "write the scan manifest (versions, intelligence, settings) to this file or s3:// or gs:// location (default: the output file with "+scanManifestSuffix+" appended)")
+	cmd.Flags().BoolVar(&noScanManifest, "no-scan-manifest", false, "don't write a scan manifest")
+	cmd.MarkFlagsMutuallyExclusive("scan-manifest", "no-scan-manifest")
+}
+
+// scanManifestDest returns where to write the manifest of a scan writing
+// its results to output, or "" for none. Results on stdout get a manifest
+// only when --scan-manifest asks for one.
+func scanManifestDest(output string) string {
+	switch {
+	case noScanManifest:
+		return ""
+	case scanManifestPath != "":
+		return scanManifestPath
+	case output == "" || output == "-":
+		return ""
+	default:
+		return output + scanManifestSuffix
+	}
+}
+
+// newScanManifest describes a finished scan run by cmd, with the config
+// snapshot redacted and the flags given on the command line
+func newScanManifest(cmd *cobra.Command, cfg *config.Config, record *scanner.ScanRecord, output string) *scanner.ScanManifest {
+	m := scanner.NewScanManifest(record, version.GetBuildInfo())
+	m.Config = cfg.Settings(true)
+	m.Output = output
+	m.Flags = make(map[string]string)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		value := f.Value.String()
+		if config.IsSecret(f.Name) {
+			value = config.Redacted
+		}
+		m.Flags[f.Name] = value
+	})
+	return m
+}
+
+// writeScanManifest writes a manifest to dest
+func writeScanManifest(ctx context.Context, dest string, m *scanner.ScanManifest) error {
+	out, err := createOutput(dest)
+	if err != nil {
+		return err
+	}
+	if err := m.Write(out.File); err != nil {
+		_ = out.Close(ctx)
+		return err
+	}
+	if err := out.Close(ctx); err != nil {
+		return fmt.Errorf("failed to save scan manifest: %w", err)
+	}
+	logging.Verbose("Wrote scan manifest to %s", dest)
+	return nil
+}
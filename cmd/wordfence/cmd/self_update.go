@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/cache"
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/update"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+)
+
+// updateCheckTimeout bounds the new version check, which runs after the
+// command the user asked for
+const updateCheckTimeout = 3 * time.Second
+
+var (
+	selfUpdateCheck bool
+	selfUpdateForce bool
+	noUpdateCheck   bool
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update to the latest release",
+	Long: `Download the latest release from GitHub for this platform and replace the
+running binary with it.
+
+The binary is checked against the release's SHA256SUMS, and SHA256SUMS
+against its ed25519 signature. Builds without the release signing key
+(RELEASE_PUBLIC_KEY) can check for a new release but won't install one.
+The new binary is written beside the old one and renamed over it, so an
+interrupted update leaves the old binary in place.
+
+Builds with embedded rules or the native RE2 library aren't updated, since
+releases have neither; rebuild them instead.
+
+Other commands print a notice when a newer release is available, checking
+at most once a day. Hide it with --no-update-check, update_check = false in
+the config file, or WORDFENCE_CLI_UPDATE_CHECK=false.`,
+	Example: `  # Check for a new release without installing it
+  wordfence self-update --check
+
+  # Update a binary installed system-wide
+  sudo wordfence self-update`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runSelfUpdate(cmd.Context())
+	},
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false, "only report whether a newer release is available")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "reinstall the latest release even if it isn't newer")
+
+	rootCmd.PersistentFlags().BoolVar(&noUpdateCheck, "no-update-check", false, "don't check for a newer release")
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+func runSelfUpdate(ctx context.Context) error {
+	client := update.NewClient(update.WithClientOptions(clientOpts...))
+	release, err := client.Latest(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := version.GetVersion()
+	newer := update.CompareVersions(current, release.Version()) < 0
+	if selfUpdateCheck {
+		if newer {
+			logging.Info("Wordfence CLI %s is available (installed: %s): %s", release.Version(), current, release.URL)
+		} else {
+			logging.Info("Wordfence CLI %s is up to date", current)
+		}
+		return nil
+	}
+	if !newer && !selfUpdateForce {
+		logging.Info("Wordfence CLI %s is up to date", current)
+		return nil
+	}
+	if intel.HasEmbedded() {
+		return errors.New("this build has embedded rules, which releases don't; rebuild it with make build-embedded instead")
+	}
+	if slices.Contains(scanner.RegexEngines(), scanner.RegexEngineRE2) {
+		return errors.New("this build links the native RE2 library, which releases don't; rebuild it with make build-re2 instead")
+	}
+
+	// A checksum fetched beside the binary only catches corrupt downloads,
+	// not a tampered release, so installing needs the signing key
+	if update.PublicKey == "" {
+		return fmt.Errorf("%w; download %s from %s and verify it by hand, or rebuild with make RELEASE_PUBLIC_KEY=<key>",
+			update.ErrNoPublicKey, release.Tag, release.URL)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating executable: %w", err)
+	}
+
+	data, err := downloadVerifiedRelease(ctx, client, release)
+	if err != nil {
+		return err
+	}
+	if err := update.Replace(exe, data); err != nil {
+		return fmt.Errorf("installing %s: %w", release.Tag, err)
+	}
+
+	logging.Info("Updated Wordfence CLI from %s to %s", current, release.Version())
+	return nil
+}
+
+// downloadVerifiedRelease downloads the release binary for this platform
+// and verifies it against the release's checksums, and the checksums
+// against their signature
+func downloadVerifiedRelease(ctx context.Context, client *update.Client, release *update.Release) ([]byte, error) {
+	asset, err := release.PlatformAsset()
+	if err != nil {
+		return nil, err
+	}
+	sumsAsset, ok := release.Asset(update.ChecksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s; refusing to install an unverified binary", release.Tag, update.ChecksumsAsset)
+	}
+	sumsData, err := client.Download(ctx, sumsAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	sigAsset, ok := release.Asset(update.SignatureAsset)
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s; refusing to install an unsigned release", release.Tag, update.SignatureAsset)
+	}
+	sig, err := client.Download(ctx, sigAsset)
+	if err != nil {
+		return nil, err
+	}
+	if err := update.VerifySignature(sumsData, sig); err != nil {
+		return nil, err
+	}
+	logging.Verbose("Verified the signature of %s", update.ChecksumsAsset)
+
+	checksums, err := update.ParseChecksums(sumsData)
+	if err != nil {
+		return nil, err
+	}
+	logging.Info("Downloading %s %s", asset.Name, release.Tag)
+	data, err := client.Download(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	if err := update.VerifyChecksum(checksums, asset.Name, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// notifyNewVersion warns, on a terminal, that a newer release is
+// available. GitHub is asked at most once per update.CheckInterval, and
+// failures are only logged for debugging.
+func notifyNewVersion(cmd *cobra.Command) {
+	if cfg == nil || !cfg.UpdateCheck || cfg.Quiet || noUpdateCheck || cmd == selfUpdateCmd {
+		return
+	}
+	if info, err := os.Stderr.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+
+	cacheDir := config.ExpandPath(cfg.CacheDirectory)
+	if cacheDir == "" {
+		var err error
+		if cacheDir, err = cache.DefaultCacheDir(); err != nil {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	defer cancel()
+	client := update.NewClient(update.WithClientOptions(append(clientOpts, api.WithRetries(0))...))
+	latest, url, err := update.LatestVersion(ctx, client, filepath.Join(cacheDir, "update-check.json"), time.Now())
+	if err != nil {
+		logging.Debug("Checking for a new release: %v", err)
+		return
+	}
+	if latest != "" && update.CompareVersions(version.GetVersion(), latest) < 0 {
+		logging.Warning("Wordfence CLI %s is available (installed: %s): %s", latest, version.GetVersion(), url)
+		logging.Warning("Run \"wordfence self-update\" to update, or set update_check = false to hide this notice")
+	}
+}
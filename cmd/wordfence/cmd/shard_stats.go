@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var shardStatsJSON bool
+
+var shardStatsCmd = &cobra.Command{
+	Use:   "shard-stats <dir>",
+	Short: "Merge the statistics of a sharded malware scan",
+	Long: `Merge the statistics saved by "malware-scan --shard --shard-stats" and
+report which shards have not finished.
+
+Each shard prints the merged totals itself when it finishes last. Use this
+command when shards ran on different hosts and their statistics files were
+collected afterwards, or to check on a scan in progress.`,
+	Example: `  # Totals of a scan split across hosts, after copying each host's
+  # shard-*.json into one directory
+  wordfence shard-stats /srv/scan-shards`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return runShardStats(args[0])
+	},
+}
+
+func init() {
+	shardStatsCmd.Flags().BoolVar(&shardStatsJSON, "json", false, "write the merged statistics as JSON")
+
+	rootCmd.AddCommand(shardStatsCmd)
+}
+
+func runShardStats(dir string) error {
+	summary, err := loadShardSummary(dir)
+	if err != nil {
+		return err
+	}
+	if shardStatsJSON {
+		return writeIndentedJSON(summary)
+	}
+	printShardSummary(summary)
+	return nil
+}
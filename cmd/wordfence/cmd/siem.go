@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/audit"
+	"github.com/greysquirr3l/wordfence-go/internal/hosting"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/notify"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// Header fields of CEF and LEEF events
+const (
+	siemVendor  = "Wordfence"
+	siemProduct = "wordfence-go"
+	// siemMaxValue bounds matched text, which can be a whole line of
+	// minified code
+	siemMaxValue = 1023
+)
+
+// siemSeverities are the 0-10 CEF and LEEF severities of finding severities
+var siemSeverities = map[notify.Severity]int{
+	notify.SeverityInfo:     1,
+	notify.SeverityLow:      3,
+	notify.SeverityMedium:   5,
+	notify.SeverityHigh:     8,
+	notify.SeverityCritical: 10,
+}
+
+// siemField is an extension field of an event. Fields with a label are
+// custom fields: labeled cs1 to cs6 in CEF, or cfp1 to cfp4 for numbers,
+// and keyed by the label in LEEF.
+type siemField struct {
+	key    string
+	label  string
+	value  string
+	number bool
+}
+
+// siemEvent is a finding written as a CEF or LEEF event
+type siemEvent struct {
+	id       string
+	name     string
+	severity int
+	category string
+	time     time.Time
+	fields   []siemField
+}
+
+// cefHeaderEscaper and cefValueEscaper escape CEF header fields and
+// extension values
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r", " ", "\n", " ")
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\r", `\r`, "\n", `\n`)
+	// leefHeaderEscaper and leefValueEscaper keep LEEF values from breaking
+	// the header or running into the next attribute
+	leefHeaderEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r", " ", "\n", " ")
+	leefValueEscaper  = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+)
+
+// cef formats the event as a Common Event Format line
+func (e *siemEvent) cef(host string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		siemVendor, siemProduct, cefHeaderEscaper.Replace(version.GetVersion()),
+		cefHeaderEscaper.Replace(e.id), cefHeaderEscaper.Replace(e.name), e.severity)
+	fmt.Fprintf(&b, "rt=%d dvchost=%s", e.time.UnixMilli(), cefValueEscaper.Replace(host))
+	if e.category != "" {
+		b.WriteString(" cat=" + cefValueEscaper.Replace(e.category))
+	}
+	texts, numbers := 0, 0
+	for _, f := range e.fields {
+		if f.value == "" {
+			continue
+		}
+		key := f.key
+		switch {
+		case f.label != "" && f.number:
+			numbers++
+			key = fmt.Sprintf("cfp%d", numbers)
+		case f.label != "":
+			texts++
+			key = fmt.Sprintf("cs%d", texts)
+		}
+		if f.label != "" {
+			fmt.Fprintf(&b, " %sLabel=%s", key, cefValueEscaper.Replace(f.label))
+		}
+		fmt.Fprintf(&b, " %s=%s", key, cefValueEscaper.Replace(f.value))
+	}
+	return b.String()
+}
+
+// leef formats the event as a LEEF 2.0 line, its attributes separated by
+// tabs
+func (e *siemEvent) leef(host string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "LEEF:2.0|%s|%s|%s|%s|x09|",
+		siemVendor, siemProduct, leefHeaderEscaper.Replace(version.GetVersion()), leefHeaderEscaper.Replace(e.id))
+	attrs := []string{
+		"devTime=" + e.time.UTC().Format("Jan 02 2006 15:04:05.000 UTC"),
+		"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z",
+		"sev=" + strconv.Itoa(max(e.severity, 1)),
+		"identHostName=" + leefValueEscaper.Replace(host),
+		"name=" + leefValueEscaper.Replace(e.name),
+	}
+	if e.category != "" {
+		attrs = append(attrs, "cat="+leefValueEscaper.Replace(e.category))
+	}
+	for _, f := range e.fields {
+		if f.value == "" {
+			continue
+		}
+		key := f.key
+		if f.label != "" {
+			key = f.label
+		}
+		attrs = append(attrs, key+"="+leefValueEscaper.Replace(f.value))
+	}
+	b.WriteString(strings.Join(attrs, "\t"))
+	return b.String()
+}
+
+// writeSIEMEvent writes an event as a line of CEF or LEEF
+func writeSIEMEvent(out *os.File, format, host string, e *siemEvent) error {
+	line := e.cef(host)
+	if format == formatLEEF {
+		line = e.leef(host)
+	}
+	if _, err := fmt.Fprintln(out, line); err != nil {
+		return fmt.Errorf("writing %s event: %w", format, err)
+	}
+	return nil
+}
+
+// siemWriter writes malware-scan results as CEF or LEEF events, one a line
+type siemWriter struct {
+	output *os.File
+	format string
+	host   string
+	sites  *hosting.Manifest
+}
+
+func newSIEMWriter(output *os.File, format string, sites *hosting.Manifest) *siemWriter {
+	hostname, _ := os.Hostname()
+	return &siemWriter{output: output, format: format, host: hostname, sites: sites}
+}
+
+// siteFields are the fields attributing path to a site
+func (w *siemWriter) siteFields(path string) []siemField {
+	owner, domain := siteOwner(w.sites, path)
+	return []siemField{
+		{key: "filePath", value: path},
+		{key: "fname", value: filepath.Base(path)},
+		{label: "owner", value: owner},
+		{label: "domain", value: domain},
+	}
+}
+
+func (w *siemWriter) WriteResult(result *scanner.ScanResult, sigSet *intel.SignatureSet) error {
+	now := time.Now()
+	for _, match := range result.Matches {
+		name, desc := match.Describe(sigSet)
+		if name == "" {
+			name = fmt.Sprintf("Signature %d", match.SignatureID)
+		}
+		id := match.Category
+		if match.SignatureID != 0 {
+			id = strconv.Itoa(match.SignatureID)
+		}
+		matched := match.MatchedString
+		if len(matched) > siemMaxValue {
+			matched = strings.ToValidUTF8(matched[:siemMaxValue], "")
+		}
+		e := &siemEvent{
+			id:       id,
+			name:     name,
+			severity: siemSeverities[malwareSeverity(match.Category)],
+			category: match.Category,
+			time:     now,
+			fields: append(w.siteFields(result.Path),
+				siemField{key: "msg", value: desc},
+				siemField{label: "matchedText", value: matched}),
+		}
+		if err := writeSIEMEvent(w.output, w.format, w.host, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *siemWriter) WriteFindings(findings []*audit.Finding) error {
+	now := time.Now()
+	for _, f := range findings {
+		severity, _ := notify.ParseSeverity(f.Severity.String())
+		e := &siemEvent{
+			id:       "audit:" + f.Check,
+			name:     findingName(f),
+			severity: siemSeverities[severity],
+			category: f.Check,
+			time:     now,
+			fields:   append(w.siteFields(f.Subject), siemField{key: "msg", value: f.Message}),
+		}
+		if err := writeSIEMEvent(w.output, w.format, w.host, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *siemWriter) WriteRemediation(result *wordpress.RemediationResult) error {
+	outcome, detail := remediationOutcome(result)
+	e := &siemEvent{
+		id:       "remediation",
+		name:     "File " + outcome,
+		severity: siemSeverities[notify.SeverityLow],
+		category: recordRemediation,
+		time:     time.Now(),
+		fields: append(w.siteFields(result.Path),
+			siemField{key: "act", value: outcome},
+			siemField{key: "msg", value: detail},
+			siemField{label: "backupPath", value: result.BackupPath}),
+	}
+	return writeSIEMEvent(w.output, w.format, w.host, e)
+}
+
+func (w *siemWriter) WriteSummary(_ *scanner.FleetSummary) error {
+	logging.Warning("--summary is only written in human and JSON output")
+	return nil
+}
+
+func (w *siemWriter) WriteStats(_ *scanner.ScanStatsReport) error {
+	logging.Warning("--stats is only written in JSON and template output; use --summary-output")
+	return nil
+}
+
+func (w *siemWriter) Close() error {
+	return nil
+}
+
+// cvssSeverity maps a CVSS score to a 0-10 severity: the score itself,
+// rounded. Vulnerabilities without a score are medium, and components only
+// flagged as outdated or abandoned are low.
+func cvssSeverity(vo *vulnOutput) int {
+	switch {
+	case vo.VulnID == "":
+		return siemSeverities[notify.SeverityLow]
+	case vo.CVSS > 0:
+		return int(math.Round(vo.CVSS))
+	default:
+		return siemSeverities[notify.SeverityMedium]
+	}
+}
+
+// outputVulnSIEM writes the vulnerable and flagged components as CEF or
+// LEEF events, one a line
+func outputVulnSIEM(out *os.File, format string, matches []*scanner.VulnMatch, statuses []*scanner.ExtensionStatus) error {
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	for _, vo := range vulnRecords(matches, statuses) {
+		e := &siemEvent{
+			id:       vo.VulnID,
+			name:     vo.Title,
+			severity: cvssSeverity(&vo),
+			category: "vulnerability",
+			time:     now,
+			fields: []siemField{
+				{key: "filePath", value: vo.Path},
+				{key: "request", value: vo.Link},
+				{label: "softwareType", value: vo.SoftwareType},
+				{label: "slug", value: vo.Slug},
+				{label: "version", value: vo.Version},
+				{label: "cve", value: vo.CVE},
+				{label: "flags", value: strings.Join(vo.Flags, ";")},
+				{label: "latestVersion", value: vo.LatestVersion},
+			},
+		}
+		if vo.CVSS > 0 {
+			e.fields = append(e.fields, siemField{label: "cvssScore", value: strconv.FormatFloat(vo.CVSS, 'f', 1, 64), number: true})
+		}
+		if vo.VulnID == "" {
+			e.id, e.category = "extension-status", "extension"
+			e.name = fmt.Sprintf("%s %s %s is %s", vo.SoftwareType, vo.Slug, vo.Version, strings.Join(vo.Flags, ", "))
+		}
+		if err := writeSIEMEvent(out, format, hostname, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/config"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	signaturesCategory  string
+	signaturesJSON      bool
+	signaturesPatternID int
+
+	selfTestCorpus            string
+	selfTestMatchTimeout      time.Duration
+	selfTestMinDetection      float64
+	selfTestMaxFalsePositives int
+)
+
+var signaturesCmd = &cobra.Command{
+	Use:   "signatures",
+	Short: "Inspect malware signatures",
+	Long: `Inspect the malware signatures used by malware-scan.
+
+Signatures are read from the local cache when available. If nothing is
+cached they are fetched from the Wordfence API, which requires a license.`,
+}
+
+var signaturesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all signatures",
+	Example: `  # List every signature
+  wordfence signatures list
+
+  # List signatures in one category
+  wordfence signatures list --category backdoor`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runSignaturesList(cmd.Context())
+	},
+}
+
+var signaturesShowCmd = &cobra.Command{
+	Use:     "show <id>",
+	Short:   "Show a signature's details and rule",
+	Example: `  wordfence signatures show 123`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSignaturesShow(cmd.Context(), args[0])
+	},
+}
+
+var signaturesSearchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Search signature names, descriptions and rules",
+	Example: `  # Find signatures mentioning webshells
+  wordfence signatures search webshell`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSignaturesSearch(cmd.Context(), args[0])
+	},
+}
+
+var signaturesTestCmd = &cobra.Command{
+	Use:   "test [--pattern-id id] <file>...",
+	Short: "Test files against one or all signatures",
+	Long: `Test files against a single signature, or all signatures when
+--pattern-id is not given, and report each match with its position and
+matched text. Useful for investigating false positives.`,
+	Example: `  # Why does this file match signature 123?
+  wordfence signatures test --pattern-id 123 file.php`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSignaturesTest(cmd.Context(), args)
+	},
+}
+
+var signaturesSelfTestCmd = &cobra.Command{
+	Use:   "self-test --corpus <dir>",
+	Short: "Measure detection and false positives on a sample corpus",
+	Long: `Match every signature against a corpus of known-malicious and
+known-clean samples and report the detection rate, the false positives, and
+how often each signature matched. Samples go in the malicious and clean
+subdirectories of the corpus; every file in them is checked, whatever its
+extension.
+
+Use it to validate signature or regex engine changes before a release:
+--min-detection and --max-false-positives make the command fail when the
+results fall short.`,
+	Example: `  # Report how the signatures do on a corpus
+  wordfence signatures self-test --corpus ./corpus
+
+  # Fail unless 99% of the malware is detected with no false positives
+  wordfence signatures self-test --corpus ./corpus --min-detection 99 --max-false-positives 0`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runSignaturesSelfTest(cmd.Context())
+	},
+}
+
+func init() {
+	signaturesListCmd.Flags().StringVar(&signaturesCategory, "category", "", "only list signatures in this category")
+	signaturesListCmd.Flags().BoolVar(&signaturesJSON, "json", false, "write signatures as JSON")
+	signaturesSearchCmd.Flags().BoolVar(&signaturesJSON, "json", false, "write signatures as JSON")
+	signaturesShowCmd.Flags().BoolVar(&signaturesJSON, "json", false, "write the signature as JSON")
+	signaturesTestCmd.Flags().IntVar(&signaturesPatternID, "pattern-id", 0, "signature ID to test (default: all)")
+	signaturesSelfTestCmd.Flags().StringVar(&selfTestCorpus, "corpus", "", "directory with malicious and clean subdirectories of samples")
+	signaturesSelfTestCmd.Flags().DurationVar(&selfTestMatchTimeout, "match-timeout", scanner.DefaultMatchTimeout, "time limit for one signature on one file")
+	signaturesSelfTestCmd.Flags().Float64Var(&selfTestMinDetection, "min-detection", 0, "fail if under this percentage of malicious samples is detected")
+	signaturesSelfTestCmd.Flags().IntVar(&selfTestMaxFalsePositives, "max-false-positives", -1, "fail if more clean samples than this match (-1 for no limit)")
+	signaturesSelfTestCmd.Flags().BoolVar(&signaturesJSON, "json", false, "write results as JSON")
+	_ = signaturesSelfTestCmd.MarkFlagRequired("corpus")
+
+	signaturesCmd.AddCommand(signaturesListCmd)
+	signaturesCmd.AddCommand(signaturesShowCmd)
+	signaturesCmd.AddCommand(signaturesSearchCmd)
+	signaturesCmd.AddCommand(signaturesTestCmd)
+	signaturesCmd.AddCommand(signaturesSelfTestCmd)
+	rootCmd.AddCommand(signaturesCmd)
+}
+
+func runSignaturesList(ctx context.Context) error {
+	sigSet, err := loadCachedSignatures(ctx)
+	if err != nil {
+		return err
+	}
+
+	var sigs []*intel.Signature
+	for _, id := range sigSet.IDs() {
+		sig := sigSet.Signatures[id]
+		if signaturesCategory != "" && !strings.EqualFold(sig.Category, signaturesCategory) {
+			continue
+		}
+		sigs = append(sigs, sig)
+	}
+
+	return writeSignatureList(sigs)
+}
+
+func runSignaturesShow(ctx context.Context, arg string) error {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return fmt.Errorf("invalid signature ID %q", arg)
+	}
+
+	sigSet, err := loadCachedSignatures(ctx)
+	if err != nil {
+		return err
+	}
+	sig, err := sigSet.GetSignature(id)
+	if err != nil {
+		return fmt.Errorf("getting signature: %w", err)
+	}
+
+	if signaturesJSON {
+		return writeIndentedJSON(sig)
+	}
+
+	out := os.Stdout
+	bold := color.New(color.Bold)
+	_, _ = bold.Fprintf(out, "Signature %d: %s\n", sig.ID, sig.Name)
+	if sig.Category != "" {
+		_, _ = fmt.Fprintf(out, "Category:       %s\n", sig.Category)
+	}
+	if sig.Description != "" {
+		_, _ = fmt.Fprintf(out, "Description:    %s\n", sig.Description)
+	}
+	if common := sigSet.GetCommonStringsForSignature(sig); len(common) > 0 {
+		_, _ = fmt.Fprintf(out, "Common strings: %s\n", strings.Join(common, ", "))
+	}
+	_, _ = fmt.Fprintf(out, "Rule:\n  %s\n", sig.Rule)
+	return nil
+}
+
+func runSignaturesSearch(ctx context.Context, text string) error {
+	sigSet, err := loadCachedSignatures(ctx)
+	if err != nil {
+		return err
+	}
+
+	needle := strings.ToLower(text)
+	var sigs []*intel.Signature
+	for _, id := range sigSet.IDs() {
+		sig := sigSet.Signatures[id]
+		if strings.Contains(strings.ToLower(sig.Name), needle) ||
+			strings.Contains(strings.ToLower(sig.Description), needle) ||
+			strings.Contains(strings.ToLower(sig.Category), needle) ||
+			strings.Contains(strings.ToLower(sig.Rule), needle) {
+			sigs = append(sigs, sig)
+		}
+	}
+
+	return writeSignatureList(sigs)
+}
+
+func runSignaturesTest(ctx context.Context, paths []string) error {
+	sigSet, err := loadCachedSignatures(ctx)
+	if err != nil {
+		return err
+	}
+
+	if signaturesPatternID != 0 {
+		if !sigSet.HasSignature(signaturesPatternID) {
+			return fmt.Errorf("signature %d not found", signaturesPatternID)
+		}
+		sigSet = sigSet.Subset(signaturesPatternID)
+	}
+
+	matcher := scanner.NewMatcher(sigSet, scanner.WithMatchAll(true), scanner.WithRegexEngine(cfg.RegexEngine))
+	red := color.New(color.FgRed, color.Bold)
+	out := os.Stdout
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path) // #nosec G304 -- user-specified file to test
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "%s: ERROR %v\n", path, err)
+			continue
+		}
+
+		mc := matcher.NewMatchContext()
+		if err := mc.Match(ctx, content); err != nil {
+			return fmt.Errorf("matching %s: %w", path, err)
+		}
+		for _, id := range mc.GetTimeouts() {
+			logging.Warning("%s: signature %d timed out", path, id)
+		}
+
+		matches := mc.GetMatches()
+		if len(matches) == 0 {
+			_, _ = fmt.Fprintf(out, "%s: no match\n", path)
+			continue
+		}
+		sort.Slice(matches, func(i, j int) bool { return matches[i].Position < matches[j].Position })
+		for _, match := range matches {
+			name := fmt.Sprintf("Signature %d", match.SignatureID)
+			if sig, err := sigSet.GetSignature(match.SignatureID); err == nil {
+				name = fmt.Sprintf("%s (%d)", sig.Name, sig.ID)
+			}
+			_, _ = red.Fprintf(out, "%s: %s\n", path, name)
+			_, _ = fmt.Fprintf(out, "  offset %d: %s\n", match.Position, truncateMatch(match.MatchedString, 200))
+		}
+	}
+
+	return nil
+}
+
+func runSignaturesSelfTest(ctx context.Context) error {
+	sigSet, err := loadCachedSignatures(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := scanner.SelfTest(ctx, sigSet, selfTestCorpus,
+		scanner.WithRegexEngine(cfg.RegexEngine), scanner.WithMatchTimeout(selfTestMatchTimeout))
+	if err != nil {
+		return fmt.Errorf("self-test: %w", err)
+	}
+
+	if signaturesJSON {
+		if err := writeIndentedJSON(result); err != nil {
+			return err
+		}
+	} else if err := writeSelfTest(result); err != nil {
+		return err
+	}
+
+	if result.Malicious > 0 && result.DetectionRate*100 < selfTestMinDetection {
+		return fmt.Errorf("detection rate %.1f%% is under %.1f%%", result.DetectionRate*100, selfTestMinDetection)
+	}
+	if selfTestMaxFalsePositives >= 0 && result.FalsePositives > selfTestMaxFalsePositives {
+		return fmt.Errorf("%d false positives, more than %d", result.FalsePositives, selfTestMaxFalsePositives)
+	}
+	return nil
+}
+
+// writeSelfTest prints a self-test summary, the signatures that matched,
+// and the samples they got wrong
+func writeSelfTest(r *scanner.SelfTestResult) error {
+	logging.Info("%d signatures (%s engine, %d failed to compile), %d timeouts",
+		r.Signatures, r.Engine, r.CompileErrors, r.Timeouts)
+	logging.Info("Detected %d of %d malicious samples (%.1f%%)", r.Detected, r.Malicious, r.DetectionRate*100)
+	logging.Info("%d of %d clean samples matched (%.1f%%)", r.FalsePositives, r.Clean, r.FalsePositiveRate*100)
+
+	out := os.Stdout
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tMALICIOUS\tCLEAN\tNAME")
+	for _, h := range r.Hits {
+		_, _ = fmt.Fprintf(tw, "%d\t%d\t%d\t%s\n", h.ID, h.Malicious, h.Clean, h.Name)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing results: %w", err)
+	}
+
+	red := color.New(color.FgRed, color.Bold)
+	for _, miss := range r.Missed {
+		_, _ = red.Fprintf(out, "Missed: %s\n", miss.Path)
+		if len(miss.Timeouts) > 0 {
+			_, _ = fmt.Fprintf(out, "  timed out: %s\n", joinIDs(miss.Timeouts))
+		}
+	}
+	for _, fp := range r.FalsePositiveFiles {
+		_, _ = red.Fprintf(out, "False positive: %s\n", fp.Path)
+		_, _ = fmt.Fprintf(out, "  matched: %s\n", joinIDs(fp.Signatures))
+	}
+	return nil
+}
+
+// joinIDs lists signature IDs
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ", ")
+}
+
+// loadCachedSignatures returns the cached signature set, falling back to
+// embedded rules or the API if nothing usable is cached
+func loadCachedSignatures(ctx context.Context) (*intel.SignatureSet, error) {
+	cfg := GetConfig()
+	if cfg == nil {
+		return nil, fmt.Errorf("configuration not loaded")
+	}
+
+	sigSet, err := intel.NewSignatureLoader(newSignatureCache(cfg)).Load(ctx)
+	if err == nil {
+		return sigSet, nil
+	}
+	logging.Debug("No cached signatures: %v", err)
+
+	return fetchSignaturesForInspection(ctx, cfg)
+}
+
+// fetchSignaturesForInspection loads signatures when none are cached
+func fetchSignaturesForInspection(ctx context.Context, cfg *config.Config) (*intel.SignatureSet, error) {
+	if intel.HasEmbedded() {
+		sigSet, err := intel.GetEmbedded()
+		if err != nil {
+			return nil, fmt.Errorf("loading embedded signatures: %w", err)
+		}
+		return sigSet, nil
+	}
+
+	if err := requireLicense(cfg); err != nil {
+		return nil, err
+	}
+	sigSet, _, err := loadScanSignatures(ctx, cfg)
+	return sigSet, err
+}
+
+// writeSignatureList prints signatures as a table or JSON
+func writeSignatureList(sigs []*intel.Signature) error {
+	if signaturesJSON {
+		if sigs == nil {
+			sigs = []*intel.Signature{}
+		}
+		return writeIndentedJSON(sigs)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tCATEGORY\tNAME")
+	for _, sig := range sigs {
+		category := sig.Category
+		if category == "" {
+			category = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", sig.ID, category, sig.Name)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing signatures: %w", err)
+	}
+
+	logging.Verbose("%d signatures", len(sigs))
+	return nil
+}
+
+// writeIndentedJSON writes v as indented JSON to stdout
+func writeIndentedJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("json encode error: %w", err)
+	}
+	return nil
+}
+
+// truncateMatch shortens matched text for display and keeps it on one line
+func truncateMatch(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > limit {
+		return s[:limit] + "..."
+	}
+	return s
+}
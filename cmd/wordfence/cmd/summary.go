@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+// writeStatsReport writes a scan's totals as JSON to dest, for
+// --summary-output
+func writeStatsReport(ctx context.Context, dest string, report *scanner.ScanStatsReport) error {
+	out, err := createOutput(dest)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out.File)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		_ = out.Close(ctx)
+		return fmt.Errorf("json encode error: %w", err)
+	}
+	if err := out.Close(ctx); err != nil {
+		return err
+	}
+	logging.Verbose("Wrote scan totals to %s", dest)
+	return nil
+}
+
+// writeFleetSummary prints per-site results and fleet-level rollups
+func writeFleetSummary(out *os.File, summary *scanner.FleetSummary) {
+	bold := color.New(color.Bold)
+	red := color.New(color.FgRed)
+	yellow := color.New(color.FgYellow)
+	green := color.New(color.FgGreen)
+
+	_, _ = fmt.Fprintln(out)
+	_, _ = bold.Fprintln(out, "=== FLEET SUMMARY ===")
+	_, _ = fmt.Fprintf(out, "  Sites scanned:    %d\n", summary.SitesScanned)
+	_, _ = green.Fprintf(out, "  Sites clean:      %d\n", summary.SitesClean)
+	_, _ = red.Fprintf(out, "  Sites infected:   %d\n", summary.SitesInfected)
+	_, _ = yellow.Fprintf(out, "  Sites vulnerable: %d\n", summary.SitesVulnerable)
+
+	if len(summary.TopSignatures) > 0 {
+		_, _ = fmt.Fprintln(out)
+		_, _ = bold.Fprintln(out, "Top signatures:")
+		for _, item := range summary.TopSignatures {
+			_, _ = fmt.Fprintf(out, "  %-50s %d sites, %d matches\n", item.Name, item.Sites, item.Count)
+		}
+	}
+
+	if len(summary.TopVulnerable) > 0 {
+		_, _ = fmt.Fprintln(out)
+		_, _ = bold.Fprintln(out, "Top vulnerable extensions:")
+		for _, item := range summary.TopVulnerable {
+			_, _ = fmt.Fprintf(out, "  %-50s %d sites, %d vulnerabilities\n", item.Type+" "+item.Name, item.Sites, item.Count)
+		}
+	}
+
+	_, _ = fmt.Fprintln(out)
+	_, _ = bold.Fprintln(out, "Sites:")
+	for _, site := range summary.Sites {
+		label := site.Root
+		if site.Domain != "" || site.Owner != "" {
+			label = fmt.Sprintf("%s (%s, %s)", site.Root, site.Domain, site.Owner)
+		}
+		switch {
+		case site.Infected():
+			_, _ = red.Fprintf(out, "  INFECTED   ")
+		case site.Vulnerable():
+			_, _ = yellow.Fprintf(out, "  VULNERABLE ")
+		default:
+			_, _ = green.Fprintf(out, "  CLEAN      ")
+		}
+		_, _ = fmt.Fprintf(out, "%s", label)
+		if site.Infected() {
+			_, _ = fmt.Fprintf(out, " - %d files, %d matches", site.FilesMatched, site.Matches)
+		}
+		if site.Vulnerable() {
+			_, _ = fmt.Fprintf(out, " - %d vulnerabilities", site.Vulnerabilities)
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/audit"
+	"github.com/greysquirr3l/wordfence-go/internal/hosting"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// templateFuncs are the functions output templates may call, beside the
+// text/template builtins
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join":    func(sep string, items []string) string { return strings.Join(items, sep) },
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": func(from, to, s string) string { return strings.ReplaceAll(s, from, to) },
+	"base":    filepath.Base,
+	"rfc3339": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+}
+
+// templateData is what an output template is executed with
+type templateData struct {
+	// Command is malware-scan or vuln-scan
+	Command string
+	// Host is the hostname and Time when the scan finished
+	Host string
+	Time time.Time
+	// Results are the records of --output-format json: []jsonResult for
+	// malware-scan, []vulnOutput for vuln-scan
+	Results interface{}
+	// Summary is the --summary rollup, or nil
+	Summary *scanner.FleetSummary
+	// Stats are malware-scan's --stats totals, or nil
+	Stats *scanner.ScanStatsReport
+}
+
+// loadOutputTemplate parses the --output-template file, so mistakes are
+// reported before the scan rather than after
+func loadOutputTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading output template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing output template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// executeOutputTemplate writes a command's results through tmpl
+func executeOutputTemplate(out *os.File, tmpl *template.Template, command string, results interface{}, summary *scanner.FleetSummary, stats *scanner.ScanStatsReport) error {
+	hostname, _ := os.Hostname()
+	data := templateData{Command: command, Host: hostname, Time: time.Now().UTC(), Results: results, Summary: summary, Stats: stats}
+	if err := tmpl.Execute(out, data); err != nil {
+		return fmt.Errorf("executing output template: %w", err)
+	}
+	return nil
+}
+
+// templateWriter collects malware-scan results and writes them through a
+// template once the scan is done
+type templateWriter struct {
+	output  *os.File
+	tmpl    *template.Template
+	sites   *hosting.Manifest
+	records []jsonResult
+	summary *scanner.FleetSummary
+	stats   *scanner.ScanStatsReport
+}
+
+func newTemplateWriter(output *os.File, tmpl *template.Template, sites *hosting.Manifest) *templateWriter {
+	return &templateWriter{output: output, tmpl: tmpl, sites: sites, records: []jsonResult{}}
+}
+
+func (w *templateWriter) WriteResult(result *scanner.ScanResult, sigSet *intel.SignatureSet) error {
+	w.records = append(w.records, matchRecords(result, sigSet, w.sites)...)
+	return nil
+}
+
+func (w *templateWriter) WriteFindings(findings []*audit.Finding) error {
+	w.records = append(w.records, findingRecords(findings, w.sites)...)
+	return nil
+}
+
+func (w *templateWriter) WriteRemediation(result *wordpress.RemediationResult) error {
+	w.records = append(w.records, remediationRecord(result, w.sites))
+	return nil
+}
+
+func (w *templateWriter) WriteSummary(summary *scanner.FleetSummary) error {
+	w.summary = summary
+	return nil
+}
+
+func (w *templateWriter) WriteStats(stats *scanner.ScanStatsReport) error {
+	w.stats = stats
+	return nil
+}
+
+func (w *templateWriter) Close() error {
+	return executeOutputTemplate(w.output, w.tmpl, "malware-scan", w.records, w.summary, w.stats)
+}
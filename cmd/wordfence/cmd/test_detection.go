@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/notify"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+var (
+	testDetectionDir          string
+	testDetectionOutput       string
+	testDetectionOutputFormat string
+)
+
+var testDetectionCmd = &cobra.Command{
+	Use:   "test-detection",
+	Short: "Check that detection and alerting work with a harmless test file",
+	Long: `Write a harmless test file, scan it, and send the finding through the
+same outputs and notification routes as malware-scan, then remove the file.
+
+Like the EICAR antivirus test file, the test file is detected by every scan
+whatever signatures are loaded, so this checks the whole pipeline: the
+license, signatures and --malware-hashes and --iocs feeds load, the file is
+detected, the result is written, and the [notify:NAME] routes of the config
+file deliver it. The command fails if any step does.
+
+The finding is critical, so it reaches the same routes real malware would.
+Write the file into a site's docroot with --dir to check that findings are
+attributed to the site.`,
+	Example: `  # Check detection and every notification route
+  wordfence test-detection
+
+  # Check a site's alerting, writing the result as JSON
+  wordfence test-detection --dir /var/www/example.com --output-format json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runTestDetection(cmd.Context())
+	},
+}
+
+func init() {
+	testDetectionCmd.Flags().StringVar(&testDetectionDir, "dir", "", "directory to write the test file in (default: a temporary directory)")
+	testDetectionCmd.Flags().StringVarP(&testDetectionOutput, "output", "o", "", "output file or s3:// or gs:// location (default: stdout)")
+	testDetectionCmd.Flags().StringVar(&testDetectionOutputFormat, "output-format", "human", "output format: csv, tsv, json, cef, leef, human")
+	testDetectionCmd.Flags().StringVar(&malwareScanHashFeed, "malware-hashes", "", "known-malware SHA256 blocklist file or http(s) feed URL to check loads")
+	testDetectionCmd.Flags().StringVar(&malwareScanIOCFeed, "iocs", "", "IOC list file or http(s) feed URL to check loads")
+
+	rootCmd.AddCommand(testDetectionCmd)
+}
+
+func runTestDetection(ctx context.Context) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+	if err := requireLicense(cfg); err != nil {
+		return err
+	}
+
+	sigSet, _, err := loadScanSignatures(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	feedOpts, err := loadScanFeeds(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	path, cleanup, err := writeTestFile(testDetectionDir)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	logging.Info("Wrote test file %s", path)
+
+	s := scanner.NewScanner(sigSet, append(feedOpts,
+		scanner.WithScanRegexEngine(cfg.RegexEngine),
+		scanner.WithScanLogger(logging.GetDefaultLogger()),
+	)...)
+	result := s.ScanSingleFile(ctx, path)
+	if result.Error != nil {
+		return fmt.Errorf("scanning test file: %w", result.Error)
+	}
+	if !detectedTestFile(result) {
+		return fmt.Errorf("the test file was not detected")
+	}
+	logging.Info("Test file detected")
+
+	if err := writeTestResult(ctx, result, s); err != nil {
+		return err
+	}
+
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		return err
+	}
+	if notifier == nil {
+		logging.Warning("No [notify:NAME] routes are configured, so alerting was not tested")
+		return nil
+	}
+	notifier.Notify(malwareNotification(result, sigSet, nil, []string{filepath.Dir(path)}))
+	return checkTestNotifications(notifier)
+}
+
+// writeTestFile writes a test file in dir, or a temporary directory if dir
+// is "", and returns its path and a function removing it. A file already
+// there is left alone.
+func writeTestFile(dir string) (string, func(), error) {
+	removeDir := func() {}
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "wordfence-test-*")
+		if err != nil {
+			return "", nil, fmt.Errorf("creating test directory: %w", err)
+		}
+		dir = tmp
+		removeDir = func() { _ = os.Remove(tmp) }
+	}
+
+	path := filepath.Join(dir, scanner.TestFileName)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) // #nosec G304 -- test file in the directory the user named
+	if err != nil {
+		removeDir()
+		return "", nil, fmt.Errorf("writing test file: %w", err)
+	}
+	_, err = file.Write(scanner.TestFileContent())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	cleanup := func() {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logging.Warning("Could not remove test file %s: %v", path, err)
+		}
+		removeDir()
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("writing test file: %w", err)
+	}
+	return path, cleanup, nil
+}
+
+// detectedTestFile reports whether result has the test file's match
+func detectedTestFile(result *scanner.ScanResult) bool {
+	for _, match := range result.Matches {
+		if match.Category == scanner.TestCategory {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTestResult writes the test file's result to the output
+func writeTestResult(ctx context.Context, result *scanner.ScanResult, s *scanner.Scanner) (err error) {
+	output, err := createOutput(testDetectionOutput)
+	if err != nil {
+		return err
+	}
+	writer := newResultWriter(output.File, testDetectionOutputFormat, nil, false)
+	defer func() {
+		if closeErr := writer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if closeErr := output.Close(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	if err := writer.WriteResult(result, s.SignatureSet()); err != nil {
+		return fmt.Errorf("writing result: %w", err)
+	}
+	return nil
+}
+
+// checkTestNotifications sends the test finding queued and fails unless
+// every route selecting it delivered it
+func checkTestNotifications(n *notify.Notifier) error {
+	closeNotifier(n)
+	switch {
+	case n.Failed() > 0 || n.Dropped() > 0:
+		return fmt.Errorf("%d notification routes failed to deliver the test finding", n.Failed()+n.Dropped())
+	case n.Sent() == 0:
+		return fmt.Errorf("no notification route selects critical malware findings")
+	}
+	logging.Info("Test finding sent to %d notification routes", n.Sent())
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/telemetry"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+	"github.com/spf13/cobra"
+)
+
+// tracingShutdownTimeout bounds the export of the last spans on exit
+const tracingShutdownTimeout = 5 * time.Second
+
+// commandSpan traces the command run, when tracing is on
+var commandSpan *telemetry.Span
+
+// startTracing turns tracing on as the OTEL_* environment variables set it
+// up, and returns a function exporting the last spans
+func startTracing() func() {
+	tracer, err := telemetry.FromEnv(version.GetVersion(), func(err error) {
+		logging.Debug("Tracing: %v", err)
+	})
+	if err != nil {
+		logging.Warning("Tracing is off: %v", err)
+		return func() {}
+	}
+	if tracer == nil {
+		return func() {}
+	}
+	telemetry.SetTracer(tracer)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := tracer.Shutdown(ctx); err != nil {
+			logging.Warning("Exporting traces: %v", err)
+		}
+	}
+}
+
+// startCommandSpan traces cmd in a span its context carries to the spans
+// of the scans it runs
+func startCommandSpan(cmd *cobra.Command) {
+	if !telemetry.Enabled() {
+		return
+	}
+	ctx, span := telemetry.Start(cmd.Context(), cmd.CommandPath())
+	cmd.SetContext(ctx)
+	commandSpan = span
+}
+
+// endCommandSpan ends the span of the command run, failed if err is set
+func endCommandSpan(err error) {
+	commandSpan.RecordError(err)
+	commandSpan.End()
+}
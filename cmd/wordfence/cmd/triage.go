@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/triage"
+)
+
+var (
+	triagePlan    string
+	triageContext int
+	triagePlain   bool
+)
+
+var triageCmd = &cobra.Command{
+	Use:   "triage <results>",
+	Short: "Review malware-scan findings and plan what to do with each file",
+	Long: `Step through the files in malware-scan JSON output (an array, or one
+result per line) one at a time, with the lines around each match, and
+decide whether to remediate, quarantine or ignore each one. On a terminal
+each finding fills the screen and commands are single keys, with the
+arrow keys moving between findings; --plain, or input that is not a
+terminal, reads one command per line instead.
+
+The decisions are written as a shell script of wordfence commands that
+carries them out, so the plan can be checked, shared or run later:
+"wordfence remediate" for files to restore or quarantine, and
+"wordfence ignore add" for matches reviewed as benign.`,
+	Example: `  # Scan, review the findings, then carry out the plan
+  wordfence malware-scan --output-format json --output results.json /var/www
+  wordfence triage results.json --plan plan.sh
+  sh plan.sh`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return runTriage(args[0])
+	},
+}
+
+func init() {
+	triageCmd.Flags().StringVar(&triagePlan, "plan", "wordfence-plan.sh", "file the action plan is written to")
+	triageCmd.Flags().IntVar(&triageContext, "context", triage.DefaultContextLines, "lines shown around each match")
+	triageCmd.Flags().BoolVar(&triagePlain, "plain", false, "read one command per line even on a terminal")
+
+	rootCmd.AddCommand(triageCmd)
+}
+
+func runTriage(resultsPath string) error {
+	file, err := os.Open(resultsPath) // #nosec G304 -- results file named by the user
+	if err != nil {
+		return fmt.Errorf("opening results: %w", err)
+	}
+	findings, err := triage.LoadFindings(file)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+
+	session := triage.NewSession(findings, triageContext)
+	var write bool
+	if triagePlain {
+		write, err = session.Run(os.Stdin, os.Stdout)
+	} else {
+		write, err = session.RunTerminal(os.Stdin, os.Stdout)
+		if errors.Is(err, triage.ErrNotTerminal) {
+			write, err = session.Run(os.Stdin, os.Stdout)
+		}
+	}
+	if err != nil || !write {
+		return err
+	}
+
+	plan, err := os.OpenFile(triagePlan, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) // #nosec G304 -- plan path named by the user
+	if err != nil {
+		return fmt.Errorf("creating plan: %w", err)
+	}
+	err = triage.WritePlan(plan, findings, resultsPath, time.Now())
+	if closeErr := plan.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	logging.Info("Wrote the action plan to %s; run it with: sh %s", triagePlan, triagePlan)
+	return nil
+}
@@ -6,13 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/api/wporg"
+	"github.com/greysquirr3l/wordfence-go/internal/audit"
 	"github.com/greysquirr3l/wordfence-go/internal/cache"
 	"github.com/greysquirr3l/wordfence-go/internal/config"
 	"github.com/greysquirr3l/wordfence-go/internal/intel"
@@ -22,12 +26,24 @@ import (
 )
 
 var (
-	vulnScanOutput        string
-	vulnScanOutputFormat  string
-	vulnScanCheckCore     bool
-	vulnScanCheckPlugins  bool
-	vulnScanCheckThemes   bool
-	vulnScanInformational bool
+	vulnScanOutput         string
+	vulnScanOutputFormat   string
+	vulnScanOutputTemplate string
+	vulnScanCheckCore      bool
+	vulnScanCheckPlugins   bool
+	vulnScanCheckThemes    bool
+	vulnScanInformational  bool
+	vulnScanDirectory      bool
+	vulnScanSummary        bool
+	vulnScanHistory        string
+	vulnScanNoHistory      bool
+	vulnScanPURLMap        string
+	vulnScanCheckActivity  bool
+	vulnScanAllActive      bool
+	vulnScanMySQLClient    string
+	vulnScanRecentlyMod    time.Duration
+	vulnScanMaxDepth       int
+	vulnScanLocateWorkers  int
 )
 
 var vulnScanCmd = &cobra.Command{
@@ -37,7 +53,30 @@ var vulnScanCmd = &cobra.Command{
 
 The scanner will detect WordPress core, plugins, and themes at the
 specified paths and check them against the Wordfence vulnerability
-database.`,
+database.
+
+With --check-directory, plugins and themes are also looked up on
+wordpress.org and flagged as outdated, abandoned (not updated in 2+ years)
+or removed from the directory, even when no vulnerability is known. Core
+is reported as current, outdated, insecure (a newer security release
+exists in its branch) or eol (its branch no longer receives security
+fixes), with the newest security release to upgrade to.
+
+Plugins and themes are matched to the database by directory name. Those
+installed under another name, such as "elementor-pro-nulled", are matched
+by the Text Domain and name in their headers, or by the directory name
+without words like "nulled" or a version. --purl-map names them
+explicitly, one directory and package URL per line:
+
+  wp-content/plugins/custom-forms pkg:wordpress-plugin/contact-form-7
+  renamed-theme                   pkg:wordpress-theme/astra
+
+With --check-activity, each site's active plugins and theme are read from
+its database, using the credentials in wp-config.php, and vulnerable
+extensions that aren't active are reported as inactive, a lower risk.
+--assume-all-active treats every extension as active instead. Extensions
+with files modified within --recently-modified are reported as recently
+modified.`,
 	Example: `  # Scan a single WordPress installation
   wordfence vuln-scan /var/www/wordpress
 
@@ -45,25 +84,48 @@ database.`,
   wordfence vuln-scan /var/www/site1 /var/www/site2
 
   # Scan with CSV output
-  wordfence vuln-scan --output-format csv --output vulns.csv /var/www`,
+  wordfence vuln-scan --output-format csv --output vulns.csv /var/www
+
+  # Also flag outdated, abandoned and removed plugins and themes
+  wordfence vuln-scan --check-directory /var/www/wordpress
+
+  # Tell active plugins from inactive ones using the site's database
+  wordfence vuln-scan --check-activity /var/www/wordpress`,
 	Args: cobra.MinimumNArgs(1),
-	RunE: func(_ *cobra.Command, args []string) error {
-		return runVulnScan(args)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runVulnScan(cmd, args)
 	},
 }
 
 func init() {
-	vulnScanCmd.Flags().StringVarP(&vulnScanOutput, "output", "o", "", "output file (default: stdout)")
-	vulnScanCmd.Flags().StringVar(&vulnScanOutputFormat, "output-format", "human", "output format: csv, tsv, json, human")
+	vulnScanCmd.Flags().StringVarP(&vulnScanOutput, "output", "o", "", "output file or s3:// or gs:// location (default: stdout)")
+	addCustodyFlags(vulnScanCmd)
+	vulnScanCmd.Flags().StringVar(&vulnScanOutputFormat, "output-format", "human", "output format: csv, tsv, json, cef, leef, human")
+	vulnScanCmd.Flags().StringVar(&vulnScanOutputTemplate, "output-template", "", "write results through a Go text/template file instead of --output-format")
 	vulnScanCmd.Flags().BoolVar(&vulnScanCheckCore, "check-core", true, "check WordPress core")
 	vulnScanCmd.Flags().BoolVar(&vulnScanCheckPlugins, "check-plugins", true, "check plugins")
 	vulnScanCmd.Flags().BoolVar(&vulnScanCheckThemes, "check-themes", true, "check themes")
 	vulnScanCmd.Flags().BoolVar(&vulnScanInformational, "informational", false, "include informational vulnerabilities")
+	vulnScanCmd.Flags().BoolVar(&vulnScanDirectory, "check-directory", false, "flag outdated, abandoned and removed extensions using wordpress.org")
+	vulnScanCmd.Flags().StringVar(&vulnScanHistory, "history", config.DefaultHistoryPath(), "directory of recorded scans read by \"wordfence history\"")
+	vulnScanCmd.Flags().BoolVar(&vulnScanNoHistory, "no-history", false, "don't record this scan in the history")
+	addReportFlags(vulnScanCmd)
+	addNotifyFlags(vulnScanCmd)
+	addScanManifestFlags(vulnScanCmd)
+	vulnScanCmd.Flags().StringVar(&vulnScanPURLMap, "purl-map", "", "file of plugin and theme directories and their package URLs, for extensions installed under other names")
+	vulnScanCmd.Flags().BoolVar(&vulnScanCheckActivity, "check-activity", false, "read active plugins and themes from each site's database")
+	vulnScanCmd.Flags().BoolVar(&vulnScanAllActive, "assume-all-active", false, "treat every plugin and theme as active")
+	vulnScanCmd.MarkFlagsMutuallyExclusive("check-activity", "assume-all-active")
+	vulnScanCmd.Flags().StringVar(&vulnScanMySQLClient, "mysql-client", audit.DefaultMySQLClient, "mysql command-line client binary used by --check-activity")
+	vulnScanCmd.Flags().DurationVar(&vulnScanRecentlyMod, "recently-modified", 7*24*time.Hour, "report extensions with files modified within this long (0 to disable)")
+	vulnScanCmd.Flags().IntVar(&vulnScanMaxDepth, "max-depth", 0, "how many directory levels below each path to search for installations (0 for no limit)")
+	vulnScanCmd.Flags().IntVar(&vulnScanLocateWorkers, "locate-workers", wordpress.DefaultLocatorWorkers, "directories read at once while searching for installations")
+	vulnScanCmd.Flags().BoolVar(&vulnScanSummary, "summary", false, "add per-site and fleet-level rollups to human and JSON output")
 
 	rootCmd.AddCommand(vulnScanCmd)
 }
 
-func runVulnScan(paths []string) error {
+func runVulnScan(cmd *cobra.Command, paths []string) error {
 	ctx := context.Background()
 	cfg := GetConfig()
 	if cfg == nil {
@@ -71,34 +133,31 @@ func runVulnScan(paths []string) error {
 	}
 
 	// Check for license
-	if cfg.License == "" {
-		logging.Error("No license key configured.")
-		logging.Info("You can configure your license in one of the following ways:")
-		logging.Info("  1. Config file: %s", config.DefaultConfigPath())
-		logging.Info("     Add: license = YOUR_LICENSE_KEY")
-		logging.Info("  2. Environment: export WORDFENCE_CLI_LICENSE=YOUR_LICENSE_KEY")
-		logging.Info("  3. CLI flag: --license YOUR_LICENSE_KEY")
-		logging.Info("")
-		logging.Info("Visit https://www.wordfence.com/products/wordfence-cli/ to obtain a license.")
-		os.Exit(1)
+	if err := requireLicense(cfg); err != nil {
+		return err
+	}
+
+	tmpl, err := loadOutputTemplate(vulnScanOutputTemplate)
+	if err != nil {
+		return err
+	}
+
+	reporter, err := newReporter(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeReporter(reporter)
+
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		return err
 	}
+	defer closeNotifier(notifier)
 
 	logging.Info("Starting vulnerability scan...")
 	startTime := time.Now()
 
-	// Set up cache
-	var c cache.Cache
-	if cfg.CacheEnabled && cfg.CacheDirectory != "" {
-		fileCache, err := cache.NewFileCache(cfg.CacheDirectory)
-		if err != nil {
-			logging.Warning("Failed to initialize cache: %v (continuing without cache)", err)
-			c = cache.NewNoOpCache()
-		} else {
-			c = fileCache
-		}
-	} else {
-		c = cache.NewNoOpCache()
-	}
+	c := newSignatureCache(cfg)
 
 	// Create license
 	license := &api.License{
@@ -108,6 +167,7 @@ func runVulnScan(paths []string) error {
 	// Create Intelligence API client
 	intelClient := api.NewIntelligenceClient(
 		api.WithIntelligenceLicense(license),
+		api.WithIntelligenceClientOptions(clientOpts...),
 	)
 
 	// Load vulnerability index
@@ -118,28 +178,33 @@ func runVulnScan(paths []string) error {
 	}
 	logging.Debug("Loaded %d vulnerabilities", vulnIndex.Count())
 
+	var purls map[string]string
+	if vulnScanPURLMap != "" {
+		purls, err = loadPackageURLMap(vulnScanPURLMap)
+		if err != nil {
+			return err
+		}
+		logging.Verbose("Loaded %d package URLs from %s", len(purls), vulnScanPURLMap)
+	}
+
 	// Create scanner
 	vulnScanner := scanner.NewVulnScanner(vulnIndex,
 		scanner.WithVulnCheckCore(vulnScanCheckCore),
 		scanner.WithVulnCheckPlugins(vulnScanCheckPlugins),
 		scanner.WithVulnCheckThemes(vulnScanCheckThemes),
 		scanner.WithVulnInformational(vulnScanInformational),
+		scanner.WithVulnPackageURLs(purls),
+		scanner.WithVulnLogger(logging.GetDefaultLogger()),
 	)
 
 	// Detect WordPress sites
 	logging.Verbose("Detecting WordPress installations...")
-	locator := wordpress.NewLocator()
-	var sites []*wordpress.Site
-
-	for _, path := range paths {
-		foundSites, err := locator.Locate(path)
-		if err != nil {
-			logging.Warning("Error scanning path %s: %v", path, err)
-			continue
-		}
-		sites = append(sites, foundSites...)
-	}
-
+	locator := wordpress.NewLocator(
+		wordpress.WithMaxDepth(vulnScanMaxDepth),
+		wordpress.WithLocatorWorkers(vulnScanLocateWorkers),
+		wordpress.WithLocateProgress(locateProgressLogger()),
+	)
+	sites := locateSites(locator, paths)
 	if len(sites) == 0 {
 		logging.Warning("No WordPress installations found")
 		return nil
@@ -147,11 +212,35 @@ func runVulnScan(paths []string) error {
 
 	logging.Info("Found %d WordPress installation(s)", len(sites))
 
+	var statusChecker *scanner.StatusChecker
+	if vulnScanDirectory {
+		statusChecker = scanner.NewStatusChecker(wporg.NewClient(wporg.WithClientOptions(clientOpts...)))
+	}
+
+	var aggregator *scanner.Aggregator
+	if vulnScanSummary {
+		aggregator = scanner.NewAggregator()
+	}
+
+	manifestDest := scanManifestDest(vulnScanOutput)
+	var record *scanner.ScanRecord
+	if !vulnScanNoHistory || manifestDest != "" {
+		record = scanner.NewScanRecord(scanner.ScanKindVuln, paths, startTime)
+	}
+	reporting := startScanReport(reporter, scanner.ScanKindVuln, paths, record)
+
 	// Scan each site
 	var allMatches []*scanner.VulnMatch
+	var allStatuses []*scanner.ExtensionStatus
 	for _, site := range sites {
 		logging.Verbose("Scanning %s (WordPress %s)", site.Path, site.Version)
 		logging.Debug("  Plugins: %d, Themes: %d", len(site.Plugins), len(site.Themes))
+		loadSiteActivity(ctx, site)
+		if vulnScanRecentlyMod > 0 {
+			if err := site.LoadModified(); err != nil {
+				logging.Warning("Failed to read modification times for %s: %v", site.Path, err)
+			}
+		}
 
 		result := vulnScanner.ScanSite(ctx, site)
 		if result.Error != nil {
@@ -160,138 +249,424 @@ func runVulnScan(paths []string) error {
 		}
 
 		allMatches = append(allMatches, result.Vulnerabilities...)
+		if aggregator != nil {
+			aggregator.AddVulnMatches(site.Path, result.Vulnerabilities)
+		}
+		if record != nil {
+			record.AddVulnMatches(result.Vulnerabilities)
+		}
+		reporting.vulnerabilities(result.Vulnerabilities)
+		for _, m := range result.Vulnerabilities {
+			notifier.Notify(vulnNotification(site.Path, m))
+		}
+
+		if statusChecker != nil {
+			allStatuses = append(allStatuses, checkSiteStatus(ctx, statusChecker, site)...)
+		}
 	}
 
 	// Output results
-	if err := outputVulnResults(allMatches, sites); err != nil {
+	var summary *scanner.FleetSummary
+	if aggregator != nil {
+		summary = aggregator.Summary(scanner.DefaultSummaryTop)
+	}
+	if err := outputVulnResults(ctx, tmpl, allMatches, allStatuses, sites, summary); err != nil {
 		return fmt.Errorf("failed to output results: %w", err)
 	}
 
+	reporting.finished(scanFinished{
+		Kind:      scanner.ScanKindVuln,
+		Matches:   len(allMatches),
+		Duration:  time.Since(startTime).Seconds(),
+		Cancelled: ctx.Err() != nil,
+	})
+	if record != nil {
+		saveVulnScanRecord(ctx, cmd, cfg, record, manifestDest, vulnIndex)
+	}
+
 	elapsed := time.Since(startTime)
 	logging.Info("Scan complete: %d vulnerabilities found in %s", len(allMatches), elapsed.Round(time.Millisecond))
+	if vulnScanDirectory {
+		logging.Info("  Extensions flagged by wordpress.org status: %d", len(allStatuses))
+	}
 
 	return nil
 }
 
-// loadVulnerabilityIndex loads vulnerability data from cache or API
-func loadVulnerabilityIndex(ctx context.Context, c cache.Cache, client *api.IntelligenceClient) (*intel.VulnerabilityIndex, error) {
-	cacheKey := "vulnerability_index_scanner"
-	cacheMaxAge := 24 * time.Hour
+// locateSites searches paths for WordPress installations
+func locateSites(locator *wordpress.Locator, paths []string) []*wordpress.Site {
+	var sites []*wordpress.Site
+	for _, path := range paths {
+		foundSites, err := locator.Locate(path)
+		if err != nil {
+			logging.Warning("Error scanning path %s: %v", path, err)
+			continue
+		}
+		sites = append(sites, foundSites...)
+	}
+	return sites
+}
 
-	// Try to load from cache
-	data, err := c.Get(cacheKey, cacheMaxAge)
-	if err == nil {
-		index, err := intel.ParseVulnerabilityIndex(data)
-		if err == nil {
-			logging.Debug("Loaded vulnerabilities from cache")
-			return index, nil
+// checkSiteStatus returns the wordpress.org directory status of a site's
+// core release and extensions that need flagging
+func checkSiteStatus(ctx context.Context, statusChecker *scanner.StatusChecker, site *wordpress.Site) []*scanner.ExtensionStatus {
+	logging.Verbose("Checking wordpress.org directory status for %s", site.Path)
+	var statuses []*scanner.ExtensionStatus
+	if vulnScanCheckCore {
+		coreStatus, err := statusChecker.CheckCore(ctx, site)
+		if err != nil {
+			logging.Warning("Failed to check core release status: %v", err)
+		} else if coreStatus != nil {
+			statuses = append(statuses, coreStatus)
 		}
-		logging.Debug("Failed to parse cached vulnerabilities: %v", err)
 	}
+	return append(statuses, statusChecker.CheckSite(ctx, site, vulnScanCheckPlugins, vulnScanCheckThemes)...)
+}
 
-	// Fetch from API
-	logging.Verbose("Fetching vulnerability database from Wordfence...")
-	index, err := client.GetScannerVulnerabilities(ctx)
+// saveVulnScanRecord finishes the record of a vulnerability scan, saving
+// it in the history and describing it in the manifest
+func saveVulnScanRecord(ctx context.Context, cmd *cobra.Command, cfg *config.Config, record *scanner.ScanRecord, manifestDest string, vulnIndex *intel.VulnerabilityIndex) {
+	record.Finish(time.Now())
+	if !vulnScanNoHistory {
+		if err := scanner.OpenHistory(vulnScanHistory).Save(record); err != nil {
+			logging.Warning("Failed to record scan history: %v", err)
+		} else {
+			logging.Verbose("Recorded scan %s", record.ID)
+		}
+	}
+	if manifestDest != "" {
+		manifest := newScanManifest(cmd, cfg, record, vulnScanOutput)
+		manifest.SetVulnerabilityFeed(vulnIndex)
+		if err := writeScanManifest(ctx, manifestDest, manifest); err != nil {
+			logging.Warning("Failed to write scan manifest: %v", err)
+		}
+	}
+}
+
+// locateProgressInterval is how often the search for installations is
+// logged
+const locateProgressInterval = 10 * time.Second
+
+// locateProgressLogger returns a progress function that logs how far the
+// search for installations has got, at most every locateProgressInterval
+func locateProgressLogger() func(wordpress.LocateProgress) {
+	last := time.Now()
+	return func(p wordpress.LocateProgress) {
+		if time.Since(last) < locateProgressInterval {
+			return
+		}
+		last = time.Now()
+		logging.Info("Searched %d directories, found %d installation(s) so far", p.Directories, p.Sites)
+	}
+}
+
+// loadSiteActivity marks a site's plugins and themes active or inactive,
+// as requested by --check-activity or --assume-all-active. Activity stays
+// unknown if the database can't be read.
+func loadSiteActivity(ctx context.Context, site *wordpress.Site) {
+	if vulnScanAllActive {
+		site.AssumeAllActive()
+		return
+	}
+	if !vulnScanCheckActivity {
+		return
+	}
+
+	configPath, err := wordpress.FindWPConfig(site.Path)
 	if err != nil {
-		return nil, fmt.Errorf("fetching vulnerabilities: %w", err)
+		logging.Warning("Can't check plugin activity for %s: %v", site.Path, err)
+		return
 	}
+	wpConfig, err := wordpress.ParseWPConfig(configPath)
+	if err != nil {
+		logging.Warning("Can't check plugin activity for %s: %v", site.Path, err)
+		return
+	}
+	db := audit.NewMySQLClient(wpConfig, audit.WithMySQLBinary(vulnScanMySQLClient))
+	active, err := audit.ReadActiveExtensions(ctx, db, wpConfig)
+	if err != nil {
+		logging.Warning("Can't check plugin activity for %s: %v", site.Path, err)
+		return
+	}
+	logging.Debug("  Active plugins: %d, theme: %s", len(active.Plugins), active.Stylesheet)
+	site.ApplyActivity(active)
+}
 
-	// Cache the data
-	indexData, err := json.Marshal(index)
+// loadPackageURLMap reads a --purl-map file. Directories given as paths
+// are made absolute, to compare with the paths of detected extensions.
+func loadPackageURLMap(path string) (map[string]string, error) {
+	data, err := os.ReadFile(config.ExpandPath(path))
 	if err != nil {
-		logging.Warning("Failed to marshal vulnerabilities for cache: %v", err)
-	} else {
-		if err := c.Put(cacheKey, indexData); err != nil {
-			logging.Warning("Failed to cache vulnerabilities: %v", err)
+		return nil, fmt.Errorf("failed to read package URLs: %w", err)
+	}
+	purls, err := intel.ParsePackageURLMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package URLs in %s: %w", path, err)
+	}
+
+	resolved := make(map[string]string, len(purls))
+	for dir, purl := range purls {
+		if strings.ContainsRune(dir, filepath.Separator) {
+			if abs, err := filepath.Abs(config.ExpandPath(dir)); err == nil {
+				dir = abs
+			}
 		}
+		resolved[dir] = purl
 	}
+	return resolved, nil
+}
 
+// vulnIndexCacheKey is the cache key of the vulnerability feed
+const vulnIndexCacheKey = "vulnerability_index_scanner"
+
+// loadVulnerabilityIndex loads vulnerability data from cache or API. The
+// decoded index is shared through the two-tier cache, so concurrent loads
+// parse the feed once.
+func loadVulnerabilityIndex(ctx context.Context, c cache.Cache, client *api.IntelligenceClient) (*intel.VulnerabilityIndex, error) {
+	index, err := cache.Load(ctx, c, vulnIndexCacheKey, 24*time.Hour, intel.ParseVulnerabilityIndex,
+		func(ctx context.Context) ([]byte, error) {
+			logging.Verbose("Fetching vulnerability database from Wordfence...")
+			index, err := client.GetScannerVulnerabilities(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("fetching vulnerabilities: %w", err)
+			}
+			data, err := json.Marshal(index)
+			if err != nil {
+				return nil, fmt.Errorf("encoding vulnerabilities: %w", err)
+			}
+			return data, nil
+		})
+	if err != nil {
+		return nil, err
+	}
 	return index, nil
 }
 
-// outputVulnResults outputs the vulnerability scan results
-func outputVulnResults(matches []*scanner.VulnMatch, sites []*wordpress.Site) error {
-	// Determine output writer
-	var out *os.File
-	var err error
+// statusKey identifies an installed extension
+func statusKey(softwareType intel.SoftwareType, slug, path string) string {
+	return string(softwareType) + ":" + slug + ":" + path
+}
 
-	if vulnScanOutput != "" && vulnScanOutput != "-" {
-		out, err = os.Create(vulnScanOutput) // #nosec G304 -- user-specified output file
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
+// statusIndex maps installed extensions to their directory status
+func statusIndex(statuses []*scanner.ExtensionStatus) map[string]*scanner.ExtensionStatus {
+	index := make(map[string]*scanner.ExtensionStatus, len(statuses))
+	for _, st := range statuses {
+		index[statusKey(st.SoftwareType, st.Slug, st.Path)] = st
+	}
+	return index
+}
+
+// unmatchedStatuses returns statuses for extensions without vulnerabilities
+func unmatchedStatuses(matches []*scanner.VulnMatch, statuses []*scanner.ExtensionStatus) []*scanner.ExtensionStatus {
+	vulnerable := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		vulnerable[statusKey(m.SoftwareType, m.Slug, m.Path)] = true
+	}
+	var rest []*scanner.ExtensionStatus
+	for _, st := range statuses {
+		if !vulnerable[statusKey(st.SoftwareType, st.Slug, st.Path)] {
+			rest = append(rest, st)
 		}
-		defer func() { _ = out.Close() }()
-	} else {
-		out = os.Stdout
 	}
+	return rest
+}
 
-	switch strings.ToLower(vulnScanOutputFormat) {
+// matchFlags returns the flags of a match: its activity and recent
+// modification, then its wordpress.org status flags
+func matchFlags(m *scanner.VulnMatch, st *scanner.ExtensionStatus) []string {
+	var flags []string
+	if m.Activity == wordpress.ActivityInactive {
+		flags = append(flags, string(wordpress.ActivityInactive))
+	}
+	if recentlyModified(m) {
+		flags = append(flags, "recently-modified")
+	}
+	return append(flags, statusFlags(st)...)
+}
+
+// recentlyModified reports whether a match's files changed within
+// --recently-modified
+func recentlyModified(m *scanner.VulnMatch) bool {
+	return vulnScanRecentlyMod > 0 && !m.Modified.IsZero() && time.Since(m.Modified) <= vulnScanRecentlyMod
+}
+
+// statusFlags returns the flags of a status as strings
+func statusFlags(st *scanner.ExtensionStatus) []string {
+	if st == nil {
+		return nil
+	}
+	flags := make([]string, len(st.Flags))
+	for i, f := range st.Flags {
+		flags[i] = string(f)
+	}
+	return flags
+}
+
+// latestVersion returns the directory version of a status, if any
+func latestVersion(st *scanner.ExtensionStatus) string {
+	if st == nil {
+		return ""
+	}
+	return st.LatestVersion
+}
+
+// securityRelease returns the core security release of a status, if any
+func securityRelease(st *scanner.ExtensionStatus) string {
+	if st == nil {
+		return ""
+	}
+	return st.SecurityRelease
+}
+
+// outputVulnResults outputs the vulnerability scan results, followed by the
+// fleet summary if there is one
+func outputVulnResults(ctx context.Context, tmpl *template.Template, matches []*scanner.VulnMatch, statuses []*scanner.ExtensionStatus, sites []*wordpress.Site, summary *scanner.FleetSummary) (err error) {
+	output, err := createOutput(vulnScanOutput)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := output.Close(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	out := output.File
+	if tmpl != nil {
+		return executeOutputTemplate(out, tmpl, "vuln-scan", vulnRecords(matches, statuses), summary, nil)
+	}
+
+	format := strings.ToLower(vulnScanOutputFormat)
+	if summary != nil && format != formatJSON && format != formatHuman {
+		logging.Warning("--summary is only written in human and JSON output")
+	}
+
+	switch format {
 	case formatJSON:
-		return outputVulnJSON(out, matches)
+		return outputVulnJSON(out, matches, statuses, summary)
 	case formatCSV:
-		return outputVulnCSV(out, matches, ',')
+		return outputVulnCSV(out, matches, statuses, ',')
 	case formatTSV:
-		return outputVulnCSV(out, matches, '\t')
+		return outputVulnCSV(out, matches, statuses, '\t')
+	case formatCEF, formatLEEF:
+		return outputVulnSIEM(out, format, matches, statuses)
 	default:
-		return outputVulnHuman(out, matches, sites)
+		if err := outputVulnHuman(out, matches, sites); err != nil {
+			return err
+		}
+		outputStatusHuman(out, statuses)
+		if summary != nil {
+			writeFleetSummary(out, summary)
+		}
+		return nil
 	}
 }
 
-// outputVulnJSON outputs results as JSON
-func outputVulnJSON(out *os.File, matches []*scanner.VulnMatch) error {
-	type vulnOutput struct {
-		SoftwareType string  `json:"software_type"`
-		Slug         string  `json:"slug"`
-		Name         string  `json:"name"`
-		Version      string  `json:"version"`
-		VulnID       string  `json:"vulnerability_id"`
-		Title        string  `json:"title"`
-		CVE          string  `json:"cve,omitempty"`
-		CVSS         float64 `json:"cvss_score,omitempty"`
-		Link         string  `json:"link"`
-		Path         string  `json:"path"`
-	}
-
-	results := make([]vulnOutput, 0, len(matches))
+// outputVulnJSON outputs results as JSON, wrapped in an object with the
+// fleet summary if there is one
+// vulnOutput is a vulnerable or flagged component in JSON and template
+// output
+type vulnOutput struct {
+	SoftwareType    string   `json:"software_type"`
+	Slug            string   `json:"slug"`
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	VulnID          string   `json:"vulnerability_id,omitempty"`
+	Title           string   `json:"title,omitempty"`
+	CVE             string   `json:"cve,omitempty"`
+	CVSS            float64  `json:"cvss_score,omitempty"`
+	Link            string   `json:"link,omitempty"`
+	Path            string   `json:"path"`
+	IdentifiedAs    string   `json:"identified_as,omitempty"`
+	IdentifiedBy    string   `json:"identified_by,omitempty"`
+	Flags           []string `json:"flags,omitempty"`
+	Modified        string   `json:"modified,omitempty"`
+	LatestVersion   string   `json:"latest_version,omitempty"`
+	SecurityRelease string   `json:"security_release,omitempty"`
+}
+
+// vulnRecords are the output records of the vulnerable components, then of
+// the flagged components without a vulnerability
+func vulnRecords(matches []*scanner.VulnMatch, statuses []*scanner.ExtensionStatus) []vulnOutput {
+	index := statusIndex(statuses)
+	results := make([]vulnOutput, 0, len(matches)+len(statuses))
 	for _, m := range matches {
+		st := index[statusKey(m.SoftwareType, m.Slug, m.Path)]
 		vo := vulnOutput{
-			SoftwareType: string(m.SoftwareType),
-			Slug:         m.Slug,
-			Name:         m.Name,
-			Version:      m.Version,
-			VulnID:       m.Vulnerability.ID,
-			Title:        m.Vulnerability.Title,
-			CVE:          m.Vulnerability.CVE,
-			Link:         fmt.Sprintf("https://www.wordfence.com/threat-intel/vulnerabilities/id/%s", m.Vulnerability.ID),
-			Path:         m.Path,
+			SoftwareType:  string(m.SoftwareType),
+			Slug:          m.Slug,
+			Name:          m.Name,
+			Version:       m.Version,
+			VulnID:        m.Vulnerability.ID,
+			Title:         m.Vulnerability.Title,
+			CVE:           m.Vulnerability.CVE,
+			Link:          fmt.Sprintf("https://www.wordfence.com/threat-intel/vulnerabilities/id/%s", m.Vulnerability.ID),
+			Path:          m.Path,
+			Flags:         matchFlags(m, st),
+			LatestVersion: latestVersion(st),
+		}
+		if !m.Modified.IsZero() {
+			vo.Modified = m.Modified.UTC().Format(time.RFC3339)
+		}
+		if st != nil {
+			vo.SecurityRelease = st.SecurityRelease
 		}
 		if m.Vulnerability.CVSS != nil {
 			vo.CVSS = m.Vulnerability.CVSS.Score
 		}
+		if feedSlug := identifiedAs(m); feedSlug != "" {
+			vo.IdentifiedAs, vo.IdentifiedBy = feedSlug, string(m.IdentifiedBy)
+		}
 		results = append(results, vo)
 	}
+	for _, st := range unmatchedStatuses(matches, statuses) {
+		results = append(results, vulnOutput{
+			SoftwareType:    string(st.SoftwareType),
+			Slug:            st.Slug,
+			Name:            st.Name,
+			Version:         st.Version,
+			Path:            st.Path,
+			Flags:           statusFlags(st),
+			LatestVersion:   st.LatestVersion,
+			SecurityRelease: st.SecurityRelease,
+		})
+	}
+	return results
+}
+
+func outputVulnJSON(out *os.File, matches []*scanner.VulnMatch, statuses []*scanner.ExtensionStatus, summary *scanner.FleetSummary) error {
+	results := vulnRecords(matches, statuses)
+
+	var doc interface{} = results
+	if summary != nil {
+		doc = struct {
+			Results []vulnOutput          `json:"results"`
+			Summary *scanner.FleetSummary `json:"summary"`
+		}{results, summary}
+	}
 
 	encoder := json.NewEncoder(out)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(results); err != nil {
+	if err := encoder.Encode(doc); err != nil {
 		return fmt.Errorf("json encode error: %w", err)
 	}
 	return nil
 }
 
 // outputVulnCSV outputs results as CSV/TSV
-func outputVulnCSV(out *os.File, matches []*scanner.VulnMatch, sep rune) error {
+func outputVulnCSV(out *os.File, matches []*scanner.VulnMatch, statuses []*scanner.ExtensionStatus, sep rune) error {
 	w := csv.NewWriter(out)
 	w.Comma = sep
 
 	// Write header
-	header := []string{"software_type", "slug", "name", "version", "vulnerability_id", "title", "cve", "cvss_score", "link", "path"}
+	header := []string{"software_type", "slug", "name", "version", "vulnerability_id", "title", "cve", "cvss_score", "link", "path", "flags", "latest_version", "security_release"}
 	if err := w.Write(header); err != nil {
 		return fmt.Errorf("csv write error: %w", err)
 	}
 
 	// Write rows
+	index := statusIndex(statuses)
 	for _, m := range matches {
+		st := index[statusKey(m.SoftwareType, m.Slug, m.Path)]
 		cvss := ""
 		if m.Vulnerability.CVSS != nil {
 			cvss = fmt.Sprintf("%.1f", m.Vulnerability.CVSS.Score)
@@ -308,6 +683,21 @@ func outputVulnCSV(out *os.File, matches []*scanner.VulnMatch, sep rune) error {
 			cvss,
 			fmt.Sprintf("https://www.wordfence.com/threat-intel/vulnerabilities/id/%s", m.Vulnerability.ID),
 			m.Path,
+			strings.Join(matchFlags(m, st), ";"),
+			latestVersion(st),
+			securityRelease(st),
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("csv write error: %w", err)
+		}
+	}
+	for _, st := range unmatchedStatuses(matches, statuses) {
+		row := []string{
+			string(st.SoftwareType), st.Slug, st.Name, st.Version,
+			"", "", "", "", "", st.Path,
+			strings.Join(statusFlags(st), ";"),
+			st.LatestVersion,
+			st.SecurityRelease,
 		}
 		if err := w.Write(row); err != nil {
 			return fmt.Errorf("csv write error: %w", err)
@@ -397,6 +787,15 @@ func outputVulnHuman(out *os.File, matches []*scanner.VulnMatch, _ []*wordpress.
 				_, _ = severityColor.Fprintf(out, "  CVSS: %.1f\n", m.Vulnerability.CVSS.Score)
 			}
 			_, _ = fmt.Fprintf(out, "  Path: %s\n", m.Path)
+			if feedSlug := identifiedAs(m); feedSlug != "" {
+				_, _ = fmt.Fprintf(out, "  Identified as %s by %s\n", feedSlug, m.IdentifiedBy)
+			}
+			if m.Activity == wordpress.ActivityInactive {
+				_, _ = fmt.Fprintln(out, "  Inactive – lower risk")
+			}
+			if recentlyModified(m) {
+				_, _ = yellow.Fprintf(out, "  Recently modified: %s\n", m.Modified.Format("2006-01-02 15:04"))
+			}
 			_, _ = fmt.Fprintf(out, "  Link: https://www.wordfence.com/threat-intel/vulnerabilities/id/%s\n", m.Vulnerability.ID)
 		}
 		_, _ = fmt.Fprintln(out)
@@ -409,3 +808,49 @@ func outputVulnHuman(out *os.File, matches []*scanner.VulnMatch, _ []*wordpress.
 
 	return nil
 }
+
+// identifiedAs returns the database slug a match was identified as, when
+// it differs from the installed directory name
+func identifiedAs(m *scanner.VulnMatch) string {
+	if m.Software == nil || m.Software.Slug == m.Slug || m.IdentifiedBy == intel.IdentifiedBySlug {
+		return ""
+	}
+	return m.Software.Slug
+}
+
+// outputStatusHuman prints wordpress.org directory status flags
+func outputStatusHuman(out *os.File, statuses []*scanner.ExtensionStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+
+	yellow := color.New(color.FgYellow)
+	bold := color.New(color.Bold)
+
+	_, _ = bold.Fprintf(out, "=== WORDPRESS.ORG STATUS ===\n")
+	for _, st := range statuses {
+		_, _ = yellow.Fprintf(out, "\n[%s] %s v%s\n", st.SoftwareType, st.Name, st.Version)
+		for _, flag := range st.Flags {
+			switch flag {
+			case scanner.StatusCurrent:
+				_, _ = fmt.Fprintf(out, "  Current: %s is the latest release\n", st.Version)
+			case scanner.StatusInsecure:
+				_, _ = fmt.Fprintf(out, "  Insecure: upgrade to security release %s (latest %s)\n", st.SecurityRelease, st.LatestVersion)
+			case scanner.StatusEndOfLife:
+				_, _ = fmt.Fprintf(out, "  End of life: this branch no longer receives security fixes; upgrade to %s\n", st.LatestVersion)
+			case scanner.StatusOutdated:
+				_, _ = fmt.Fprintf(out, "  Outdated: latest version is %s\n", st.LatestVersion)
+			case scanner.StatusAbandoned:
+				_, _ = fmt.Fprintf(out, "  Abandoned: not updated since %s\n", st.LastUpdated.Format(time.DateOnly))
+			case scanner.StatusRemoved:
+				reason := st.ClosedReason
+				if reason == "" {
+					reason = "no reason given"
+				}
+				_, _ = fmt.Fprintf(out, "  Removed from the directory (%s)\n", reason)
+			}
+		}
+		_, _ = fmt.Fprintf(out, "  Path: %s\n", st.Path)
+	}
+	_, _ = fmt.Fprintln(out)
+}
@@ -4,12 +4,14 @@ package api //nolint:revive // api is a well-understood package name for API cli
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +27,9 @@ const DefaultRetries = 3
 // DefaultRetryWait is the default wait time between retries
 const DefaultRetryWait = 1 * time.Second
 
+// MaxRetryAfter caps how long a server's Retry-After can delay a retry
+const MaxRetryAfter = 2 * time.Minute
+
 // Client is a base HTTP client for API requests
 type Client struct {
 	BaseURL    string
@@ -33,6 +38,9 @@ type Client struct {
 	UserAgent  string
 	Retries    int
 	RetryWait  time.Duration
+
+	// limit holds a slot for each request in flight, when limited
+	limit chan struct{}
 }
 
 // ClientOption is a function that configures a Client
@@ -59,6 +67,30 @@ func WithRetryWait(wait time.Duration) ClientOption {
 	}
 }
 
+// WithConcurrencyLimit limits how many requests the client has in flight
+// at once; each client talks to one host, so this is a per-host limit
+func WithConcurrencyLimit(n int) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.limit = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithTLSConfig sets the TLS configuration, such as client certificates
+// for mutual TLS. The transport is otherwise the default one, keeping its
+// proxy, dial and idle connection settings.
+func WithTLSConfig(tlsConfig *tls.Config) ClientOption {
+	return func(c *Client) {
+		transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+		if base, ok := http.DefaultTransport.(*http.Transport); ok {
+			transport = base.Clone()
+		}
+		transport.TLSClientConfig = tlsConfig
+		c.HTTPClient.Transport = transport
+	}
+}
+
 // WithLogger sets the logger for the client
 func WithLogger(logger *logging.Logger) ClientOption {
 	return func(c *Client) {
@@ -93,16 +125,30 @@ func (c *Client) Request(ctx context.Context, method, path string, body io.Reade
 	for attempt := 0; attempt <= c.Retries; attempt++ {
 		if attempt > 0 {
 			c.Logger.Debug("Retrying request (attempt %d/%d) after error: %v", attempt, c.Retries, lastErr)
+			wait := c.RetryWait * time.Duration(attempt)
+			if httpErr, ok := IsHTTPError(lastErr); ok && httpErr.RetryAfter > wait {
+				wait = min(httpErr.RetryAfter, MaxRetryAfter)
+			}
 			select {
 			case <-ctx.Done():
 				return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
-			case <-time.After(c.RetryWait * time.Duration(attempt)):
-				// Exponential backoff
+			case <-time.After(wait):
+				// Exponential backoff, or as long as the server asked
+			}
+			// Resend the whole body, not what the failed attempt left
+			if seeker, ok := body.(io.Seeker); ok {
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					return nil, fmt.Errorf("rewinding request body: %w", err)
+				}
 			}
 		}
 
 		resp, err := c.doRequest(ctx, method, path, body, headers)
 		if err != nil {
+			// Client errors won't succeed on retry
+			if httpErr, ok := IsHTTPError(err); ok && httpErr.StatusCode < 500 && httpErr.StatusCode != http.StatusTooManyRequests {
+				return nil, err
+			}
 			lastErr = err
 			continue
 		}
@@ -132,6 +178,15 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body io.Rea
 
 	c.Logger.Debug("HTTP %s %s", method, fullURL)
 
+	if c.limit != nil {
+		select {
+		case c.limit <- struct{}{}:
+			defer func() { <-c.limit }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
+		}
+	}
+
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -148,6 +203,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body io.Rea
 			StatusCode: resp.StatusCode,
 			Status:     resp.Status,
 			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 		}
 	}
 
@@ -191,6 +247,7 @@ type HTTPError struct {
 	StatusCode int
 	Status     string
 	Body       string
+	RetryAfter time.Duration // How long the server asked clients to wait, if it did
 }
 
 func (e *HTTPError) Error() string {
@@ -200,6 +257,21 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("HTTP %s", e.Status)
 }
 
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
 // IsHTTPError checks if an error is an HTTPError and returns it
 func IsHTTPError(err error) (*HTTPError, bool) {
 	var httpErr *HTTPError
@@ -37,6 +37,15 @@ func WithIntelligenceLogger(logger *logging.Logger) IntelligenceOption {
 	}
 }
 
+// WithIntelligenceClientOptions applies options to the underlying HTTP client
+func WithIntelligenceClientOptions(opts ...ClientOption) IntelligenceOption {
+	return func(c *IntelligenceClient) {
+		for _, opt := range opts {
+			opt(c.Client)
+		}
+	}
+}
+
 // NewIntelligenceClient creates a new Intelligence API client
 func NewIntelligenceClient(opts ...IntelligenceOption) *IntelligenceClient {
 	c := &IntelligenceClient{
@@ -106,3 +115,40 @@ func (c *IntelligenceClient) GetSoftwareVulnerabilities(ctx context.Context, sof
 
 	return vulns, nil
 }
+
+// GetMalwareHashes fetches a known-malware SHA256 feed from feedURL. The
+// feed is either a JSON-encoded intel.HashSet or one "<sha256> [name]"
+// entry per line.
+func GetMalwareHashes(ctx context.Context, feedURL string, opts ...ClientOption) (*intel.HashSet, error) {
+	client := NewClient(feedURL, opts...)
+
+	resp, err := client.Get(ctx, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch malware hashes: %w", err)
+	}
+
+	hashes, err := intel.ParseHashSet(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse malware hash feed: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// GetIOCs fetches an indicator-of-compromise feed of malicious domains, IP
+// addresses and networks from feedURL
+func GetIOCs(ctx context.Context, feedURL string, opts ...ClientOption) (*intel.IOCSet, error) {
+	client := NewClient(feedURL, opts...)
+
+	resp, err := client.Get(ctx, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IOCs: %w", err)
+	}
+
+	iocs, err := intel.ParseIOCSet(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse IOC feed: %w", err)
+	}
+
+	return iocs, nil
+}
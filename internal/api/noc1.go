@@ -39,6 +39,15 @@ func WithNOC1Logger(logger *logging.Logger) NOC1Option {
 	}
 }
 
+// WithNOC1ClientOptions applies options to the underlying HTTP client
+func WithNOC1ClientOptions(opts ...ClientOption) NOC1Option {
+	return func(c *NOC1Client) {
+		for _, opt := range opts {
+			opt(c.Client)
+		}
+	}
+}
+
 // NewNOC1Client creates a new NOC1 API client
 func NewNOC1Client(opts ...NOC1Option) *NOC1Client {
 	c := &NOC1Client{
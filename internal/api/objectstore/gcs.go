@@ -0,0 +1,77 @@
+// Package objectstore provides uploads to Google Cloud Storage
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// GCSBaseURL is the default Google Cloud Storage JSON API base URL
+const GCSBaseURL = "https://storage.googleapis.com"
+
+// GCSUploader uploads objects to Google Cloud Storage with an OAuth 2.0
+// access token
+type GCSUploader struct {
+	token      string
+	baseURL    string
+	clientOpts []api.ClientOption
+}
+
+// GCSOption configures a GCSUploader
+type GCSOption func(*GCSUploader)
+
+// WithGCSBaseURL sets the JSON API base URL
+func WithGCSBaseURL(baseURL string) GCSOption {
+	return func(u *GCSUploader) {
+		u.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithGCSClientOptions applies options to the underlying HTTP client
+func WithGCSClientOptions(opts ...api.ClientOption) GCSOption {
+	return func(u *GCSUploader) {
+		u.clientOpts = append(u.clientOpts, opts...)
+	}
+}
+
+// NewGCSUploader creates an uploader authenticating with token
+func NewGCSUploader(token string, opts ...GCSOption) *GCSUploader {
+	u := &GCSUploader{token: token, baseURL: GCSBaseURL}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// GCSFromEnv creates an uploader from GOOGLE_OAUTH_ACCESS_TOKEN, such as
+// the output of "gcloud auth print-access-token"
+func GCSFromEnv(opts ...GCSOption) (*GCSUploader, error) {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("%w: set GOOGLE_OAUTH_ACCESS_TOKEN", ErrMissingCredentials)
+	}
+	return NewGCSUploader(token, opts...), nil
+}
+
+// Upload stores body as the object at loc
+func (u *GCSUploader) Upload(ctx context.Context, loc *Location, body []byte, contentType string) error {
+	query := url.Values{"uploadType": {"media"}, "name": {loc.Key}}
+	path := "/upload/storage/v1/b/" + url.PathEscape(loc.Bucket) + "/o?" + query.Encode()
+	headers := map[string]string{
+		"Authorization": "Bearer " + u.token,
+		"Content-Type":  contentType,
+	}
+
+	client := api.NewClient(u.baseURL, append([]api.ClientOption{api.WithTimeout(UploadTimeout)}, u.clientOpts...)...)
+	if _, err := client.Request(ctx, http.MethodPost, path, bytes.NewReader(body), headers); err != nil {
+		return err
+	}
+	return nil
+}
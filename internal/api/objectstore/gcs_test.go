@@ -0,0 +1,62 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+func TestGCSUpload(t *testing.T) {
+	body := []byte("filename,signature_id\n")
+	var got *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"name": "scans/results.csv"}`))
+	}))
+	defer server.Close()
+
+	u := NewGCSUploader("ya29.token", WithGCSBaseURL(server.URL+"/"), WithGCSClientOptions(api.WithRetries(0)))
+	loc := &Location{Scheme: SchemeGCS, Bucket: "my-bucket", Key: "scans/results.csv"}
+	if err := u.Upload(context.Background(), loc, body, "text/csv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Method != http.MethodPost || got.URL.Path != "/upload/storage/v1/b/my-bucket/o" {
+		t.Fatalf("unexpected request %s %s", got.Method, got.URL.Path)
+	}
+	if q := got.URL.Query(); q.Get("uploadType") != "media" || q.Get("name") != "scans/results.csv" {
+		t.Errorf("unexpected query %v", q)
+	}
+	if got.Header.Get("Authorization") != "Bearer ya29.token" || got.Header.Get("Content-Type") != "text/csv" || string(gotBody) != string(body) {
+		t.Errorf("unexpected request headers %v and body %q", got.Header, gotBody)
+	}
+}
+
+func TestGCSUploadError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error": {"code": 401}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	u := NewGCSUploader("expired", WithGCSBaseURL(server.URL), WithGCSClientOptions(api.WithRetries(0)))
+	if err := u.Upload(context.Background(), &Location{Scheme: SchemeGCS, Bucket: "b", Key: "k"}, nil, "text/csv"); err == nil {
+		t.Fatal("expected an error for a refused upload")
+	}
+}
+
+func TestGCSFromEnv(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	if _, err := GCSFromEnv(); err == nil {
+		t.Error("expected an error without a token")
+	}
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.token")
+	if u, err := GCSFromEnv(); err != nil || u.token != "ya29.token" || u.baseURL != GCSBaseURL {
+		t.Errorf("unexpected uploader %+v (%v)", u, err)
+	}
+}
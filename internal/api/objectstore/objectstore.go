@@ -0,0 +1,92 @@
+// Package objectstore provides uploads of scan outputs to object storage
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// Location schemes
+const (
+	SchemeS3  = "s3"
+	SchemeGCS = "gs"
+)
+
+// UploadTimeout is the HTTP timeout for uploads, which can be far larger
+// than API requests
+const UploadTimeout = 5 * time.Minute
+
+// ErrMissingCredentials is returned when no credentials are configured for
+// a location's scheme
+var ErrMissingCredentials = errors.New("object storage credentials not configured")
+
+// Location is an object in a bucket, such as s3://bucket/path/key.json
+type Location struct {
+	Scheme string
+	Bucket string
+	Key    string
+}
+
+// String returns the location as a URL
+func (l *Location) String() string {
+	return l.Scheme + "://" + l.Bucket + "/" + l.Key
+}
+
+// IsRemote reports whether dest names an object storage location rather
+// than a local file
+func IsRemote(dest string) bool {
+	return strings.HasPrefix(dest, SchemeS3+"://") || strings.HasPrefix(dest, SchemeGCS+"://")
+}
+
+// ParseLocation parses an s3:// or gs:// URL
+func ParseLocation(dest string) (*Location, error) {
+	scheme, rest, ok := strings.Cut(dest, "://")
+	if !ok || (scheme != SchemeS3 && scheme != SchemeGCS) {
+		return nil, fmt.Errorf("%s: not an s3:// or gs:// location", dest)
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("%s: location must name a bucket and an object key", dest)
+	}
+	return &Location{Scheme: scheme, Bucket: bucket, Key: key}, nil
+}
+
+// Uploader stores objects in a bucket
+type Uploader interface {
+	Upload(ctx context.Context, loc *Location, body []byte, contentType string) error
+}
+
+// NewUploader returns an uploader for the location's scheme, configured
+// from the environment, with clientOpts applied to its HTTP client
+func NewUploader(loc *Location, clientOpts ...api.ClientOption) (Uploader, error) {
+	switch loc.Scheme {
+	case SchemeS3:
+		return S3FromEnv(WithS3ClientOptions(clientOpts...))
+	case SchemeGCS:
+		return GCSFromEnv(WithGCSClientOptions(clientOpts...))
+	default:
+		return nil, fmt.Errorf("unsupported object storage scheme %q", loc.Scheme)
+	}
+}
+
+// UploadFile uploads the contents of a local file to loc
+func UploadFile(ctx context.Context, loc *Location, path, contentType string, clientOpts ...api.ClientOption) error {
+	uploader, err := NewUploader(loc, clientOpts...)
+	if err != nil {
+		return err
+	}
+	body, err := os.ReadFile(path) // #nosec G304 -- spooled output file
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := uploader.Upload(ctx, loc, body, contentType); err != nil {
+		return fmt.Errorf("uploading to %s: %w", loc, err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package objectstore
+
+import (
+	"testing"
+)
+
+func TestParseLocation(t *testing.T) {
+	loc, err := ParseLocation("s3://scans/2024/host1.json")
+	if err != nil || loc.Scheme != SchemeS3 || loc.Bucket != "scans" || loc.Key != "2024/host1.json" {
+		t.Fatalf("unexpected location %+v (%v)", loc, err)
+	}
+	if loc.String() != "s3://scans/2024/host1.json" {
+		t.Errorf("unexpected string %s", loc)
+	}
+	for _, dest := range []string{"results.json", "ftp://host/a.json", "s3://bucket", "gs://bucket/", "gs://bucket/dir/", "s3:///key"} {
+		if _, err := ParseLocation(dest); err == nil {
+			t.Errorf("expected an error for %q", dest)
+		}
+	}
+}
+
+func TestIsRemote(t *testing.T) {
+	for dest, remote := range map[string]bool{
+		"s3://bucket/key": true,
+		"gs://bucket/key": true,
+		"/tmp/s3://x":     false,
+		"results.json":    false,
+	} {
+		if IsRemote(dest) != remote {
+			t.Errorf("IsRemote(%q) = %v", dest, !remote)
+		}
+	}
+}
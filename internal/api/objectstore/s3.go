@@ -0,0 +1,233 @@
+// Package objectstore provides uploads to S3 and S3-compatible storage
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// DefaultS3Region is used when no region is configured
+const DefaultS3Region = "us-east-1"
+
+// Credentials are an access key pair, with a session token for temporary
+// credentials
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Uploader uploads objects to S3, or to an S3-compatible service such as
+// MinIO or Google Cloud Storage with HMAC keys, signing requests with AWS
+// Signature Version 4
+type S3Uploader struct {
+	creds      Credentials
+	region     string
+	endpoint   string
+	clientOpts []api.ClientOption
+	now        func() time.Time
+}
+
+// S3Option configures an S3Uploader
+type S3Option func(*S3Uploader)
+
+// WithRegion sets the bucket region
+func WithRegion(region string) S3Option {
+	return func(u *S3Uploader) {
+		u.region = region
+	}
+}
+
+// WithEndpoint sends requests to an S3-compatible endpoint using path-style
+// addressing instead of AWS virtual-hosted buckets
+func WithEndpoint(endpoint string) S3Option {
+	return func(u *S3Uploader) {
+		u.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithS3ClientOptions applies options to the underlying HTTP client
+func WithS3ClientOptions(opts ...api.ClientOption) S3Option {
+	return func(u *S3Uploader) {
+		u.clientOpts = append(u.clientOpts, opts...)
+	}
+}
+
+// NewS3Uploader creates an uploader signing with creds
+func NewS3Uploader(creds Credentials, opts ...S3Option) *S3Uploader {
+	u := &S3Uploader{
+		creds:  creds,
+		region: DefaultS3Region,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// S3FromEnv creates an uploader from the standard AWS environment
+// variables: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN,
+// AWS_REGION (or AWS_DEFAULT_REGION) and AWS_ENDPOINT_URL_S3 (or
+// AWS_ENDPOINT_URL)
+func S3FromEnv(opts ...S3Option) (*S3Uploader, error) {
+	creds := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%w: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", ErrMissingCredentials)
+	}
+
+	var envOpts []S3Option
+	if region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"); region != "" {
+		envOpts = append(envOpts, WithRegion(region))
+	}
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		envOpts = append(envOpts, WithEndpoint(endpoint))
+	}
+	return NewS3Uploader(creds, append(envOpts, opts...)...), nil
+}
+
+// Upload stores body as the object at loc
+func (u *S3Uploader) Upload(ctx context.Context, loc *Location, body []byte, contentType string) error {
+	baseURL, path := u.objectURL(loc)
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	payloadHash := sha256.Sum256(body)
+	headers := map[string]string{
+		"Content-Type":         contentType,
+		"X-Amz-Content-Sha256": hex.EncodeToString(payloadHash[:]),
+		"X-Amz-Date":           u.now().UTC().Format(amzDateFormat),
+	}
+	if u.creds.SessionToken != "" {
+		headers["X-Amz-Security-Token"] = u.creds.SessionToken
+	}
+	headers["Authorization"] = signV4(http.MethodPut, base.Host, path, headers, u.creds, u.region, "s3")
+
+	client := api.NewClient(baseURL, append([]api.ClientOption{api.WithTimeout(UploadTimeout)}, u.clientOpts...)...)
+	if _, err := client.Request(ctx, http.MethodPut, path, bytes.NewReader(body), headers); err != nil {
+		return err
+	}
+	return nil
+}
+
+// objectURL returns the base URL and escaped path of the object at loc.
+// Buckets with dots cannot use virtual-hosted addressing over TLS.
+func (u *S3Uploader) objectURL(loc *Location) (string, string) {
+	key := escapePath(loc.Key)
+	if u.endpoint != "" || strings.Contains(loc.Bucket, ".") {
+		endpoint := u.endpoint
+		if endpoint == "" {
+			endpoint = "https://s3." + u.region + ".amazonaws.com"
+		}
+		return endpoint, "/" + escapePath(loc.Bucket) + "/" + key
+	}
+	return "https://" + loc.Bucket + ".s3." + u.region + ".amazonaws.com", "/" + key
+}
+
+// amzDateFormat is the ISO 8601 basic format used by Signature Version 4
+const amzDateFormat = "20060102T150405Z"
+
+// signV4 returns the Signature Version 4 Authorization header for a request
+// without a query string to the escaped path on host with the given headers, all of which are
+// signed along with the host. headers must include X-Amz-Date and
+// X-Amz-Content-Sha256.
+func signV4(method, host, path string, headers map[string]string, creds Credentials, region, service string) string {
+	canonical := map[string]string{"host": host}
+	for name, value := range headers {
+		canonical[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	names := make([]string, 0, len(canonical))
+	for name := range canonical {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + canonical[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		"", // no query string
+		canonicalHeaders.String(),
+		signedHeaders,
+		canonical["x-amz-content-sha256"],
+	}, "\n")
+
+	amzDate := canonical["x-amz-date"]
+	scope := amzDate[:8] + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), amzDate[:8])
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature)
+}
+
+// escapePath percent-encodes everything but unreserved characters and '/'
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escapeComponent(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escapeComponent percent-encodes everything but RFC 3986 unreserved
+// characters, as Signature Version 4 requires
+func escapeComponent(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// firstEnv returns the first non-empty environment variable of names
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
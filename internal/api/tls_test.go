@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadTLSConfig(t *testing.T) {
+	if _, err := LoadTLSConfig("cert.pem", "", ""); err == nil {
+		t.Error("expected error for certificate without key")
+	}
+	cfg, err := LoadTLSConfig("", "", "")
+	if err != nil || cfg.RootCAs != nil || len(cfg.Certificates) != 0 {
+		t.Errorf("expected default TLS config, got %+v, %v", cfg, err)
+	}
+}
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+	return path
+}
+
+func TestWithTLSConfigKeepsDefaults(t *testing.T) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	c := NewClient("https://example.com", WithTLSConfig(tlsConfig))
+	transport, ok := c.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected an *http.Transport, got %T", c.HTTPClient.Transport)
+	}
+	if transport.TLSClientConfig != tlsConfig {
+		t.Error("expected the TLS configuration to be used")
+	}
+	if transport.Proxy == nil || transport.TLSHandshakeTimeout == 0 || transport.IdleConnTimeout == 0 || !transport.ForceAttemptHTTP2 {
+		t.Errorf("expected the default transport's settings, got %+v", transport)
+	}
+	if transport == http.DefaultTransport {
+		t.Error("expected a copy of the default transport")
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	// A self-signed client certificate the server trusts
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := writePEM(t, dir, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	certFile := writePEM(t, dir, "agent.pem", "CERTIFICATE", certDER)
+	keyFile := writePEM(t, dir, "agent.key", "EC PRIVATE KEY", keyDER)
+
+	// Without a client certificate the handshake is refused
+	anonymous, err := LoadTLSConfig("", "", caFile)
+	if err != nil {
+		t.Fatalf("loading CA: %v", err)
+	}
+	client := NewClient(srv.URL, WithTLSConfig(anonymous), WithRetries(0))
+	if _, err := client.Get(context.Background(), "/", nil); err == nil {
+		t.Error("expected request without client certificate to fail")
+	}
+
+	tlsConfig, err := LoadTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("loading client certificate: %v", err)
+	}
+	client = NewClient(srv.URL, WithTLSConfig(tlsConfig), WithRetries(0))
+	body, err := client.Get(context.Background(), "/", nil)
+	if err != nil || string(body) != "agent" {
+		t.Errorf("expected server to see client certificate, got %q, %v", body, err)
+	}
+}
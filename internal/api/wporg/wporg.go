@@ -0,0 +1,357 @@
+// Package wporg provides a client for the WordPress.org API
+package wporg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/cache"
+)
+
+// BaseURL is the default WordPress.org API base URL
+const BaseURL = "https://api.wordpress.org"
+
+// DownloadsURL is the default WordPress.org package download base URL
+const DownloadsURL = "https://downloads.wordpress.org"
+
+// DownloadTimeout is the HTTP timeout for package downloads, which are far
+// larger than API responses
+const DownloadTimeout = 5 * time.Minute
+
+// Extension kinds hosted in the directory, and WordPress core releases
+const (
+	KindPlugin = "plugin"
+	KindTheme  = "theme"
+	KindCore   = "core"
+)
+
+// ErrNotInDirectory is returned for extensions that are not, and never
+// were, hosted on WordPress.org (premium or custom code)
+var ErrNotInDirectory = errors.New("not in the WordPress.org directory")
+
+// timeLayouts are the formats used by last_updated, added and closed_date
+var timeLayouts = []string{
+	"2006-01-02 3:04pm MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Client is a client for the WordPress.org API
+type Client struct {
+	*api.Client
+	downloads *api.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL sets the API base URL
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithDownloadsURL sets the package download base URL
+func WithDownloadsURL(downloadsURL string) Option {
+	return func(c *Client) {
+		c.downloads.BaseURL = strings.TrimSuffix(downloadsURL, "/")
+	}
+}
+
+// WithClientOptions applies options to the underlying HTTP clients
+func WithClientOptions(opts ...api.ClientOption) Option {
+	return func(c *Client) {
+		for _, opt := range opts {
+			opt(c.Client)
+			opt(c.downloads)
+		}
+	}
+}
+
+// NewClient creates a new WordPress.org API client
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		Client:    api.NewClient(BaseURL),
+		downloads: api.NewClient(DownloadsURL, api.WithTimeout(DownloadTimeout)),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// ExtensionInfo is directory metadata for a plugin or theme
+type ExtensionInfo struct {
+	Slug         string            `json:"slug"`
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	LastUpdated  time.Time         `json:"last_updated"`
+	Added        time.Time         `json:"added"`
+	Versions     map[string]string `json:"versions,omitempty"`
+	Closed       bool              `json:"closed,omitempty"`
+	ClosedDate   time.Time         `json:"closed_date,omitempty"`
+	ClosedReason string            `json:"closed_reason,omitempty"`
+}
+
+// infoResponse is the raw plugin_information/theme_information response
+type infoResponse struct {
+	Slug            string          `json:"slug"`
+	Name            string          `json:"name"`
+	Version         string          `json:"version"`
+	LastUpdated     string          `json:"last_updated"`
+	LastUpdatedTime string          `json:"last_updated_time"`
+	Added           string          `json:"added"`
+	Versions        json.RawMessage `json:"versions"`
+	Error           string          `json:"error"`
+	Closed          bool            `json:"closed"`
+	ClosedDate      string          `json:"closed_date"`
+	Reason          string          `json:"reason"`
+	ReasonText      string          `json:"reason_text"`
+}
+
+// PluginInfo fetches directory metadata for a plugin
+func (c *Client) PluginInfo(ctx context.Context, slug string) (*ExtensionInfo, error) {
+	return c.info(ctx, KindPlugin, slug)
+}
+
+// ThemeInfo fetches directory metadata for a theme
+func (c *Client) ThemeInfo(ctx context.Context, slug string) (*ExtensionInfo, error) {
+	return c.info(ctx, KindTheme, slug)
+}
+
+// info fetches metadata from the plugins or themes info endpoint
+func (c *Client) info(ctx context.Context, kind, slug string) (*ExtensionInfo, error) {
+	query := url.Values{}
+	query.Set("action", kind+"_information")
+	query.Set("request[slug]", slug)
+	query.Set("request[fields][versions]", "1")
+
+	resp, err := c.Get(ctx, "/"+kind+"s/info/1.2/?"+query.Encode(), nil)
+	if err != nil {
+		// Closed and unknown extensions are reported with a 404 and a
+		// JSON body describing why
+		httpErr, ok := api.IsHTTPError(err)
+		if !ok || !api.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to fetch %s %s: %w", kind, slug, err)
+		}
+		resp = []byte(httpErr.Body)
+	}
+
+	// The themes API answers unknown slugs with a bare false
+	if strings.TrimSpace(string(resp)) == "false" {
+		return nil, fmt.Errorf("%s %s: %w", kind, slug, ErrNotInDirectory)
+	}
+
+	var raw infoResponse
+	if err := json.Unmarshal(resp, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s %s: %w", kind, slug, err)
+	}
+
+	if raw.Error != "" && !raw.Closed && raw.Error != "closed" {
+		return nil, fmt.Errorf("%s %s: %w", kind, slug, ErrNotInDirectory)
+	}
+
+	info := &ExtensionInfo{
+		Slug:     raw.Slug,
+		Name:     raw.Name,
+		Version:  raw.Version,
+		Versions: parseVersions(raw.Versions),
+		Closed:   raw.Closed || raw.Error == "closed",
+	}
+	if info.Slug == "" {
+		info.Slug = slug
+	}
+	info.LastUpdated = parseTime(raw.LastUpdatedTime, raw.LastUpdated)
+	info.Added = parseTime(raw.Added)
+	if info.Closed {
+		info.ClosedDate = parseTime(raw.ClosedDate)
+		info.ClosedReason = raw.ReasonText
+		if info.ClosedReason == "" {
+			info.ClosedReason = raw.Reason
+		}
+	}
+	return info, nil
+}
+
+// parseTime parses the first non-empty value in any known layout
+func parseTime(values ...string) time.Time {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// parseVersions decodes the versions field, which is an empty array rather
+// than an object when an extension has no tagged releases
+func parseVersions(data json.RawMessage) map[string]string {
+	var versions map[string]string
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil
+	}
+	return versions
+}
+
+// Core release statuses reported by the stable-check API
+const (
+	CoreLatest   = "latest"
+	CoreOutdated = "outdated"
+	CoreInsecure = "insecure"
+)
+
+// CoreReleases fetches the status of every WordPress core release, keyed by
+// version: the latest release, outdated releases that still receive
+// security fixes, and insecure releases
+func (c *Client) CoreReleases(ctx context.Context) (map[string]string, error) {
+	resp, err := c.Get(ctx, "/core/stable-check/1.0/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch core releases: %w", err)
+	}
+
+	var releases map[string]string
+	if err := json.Unmarshal(resp, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse core releases: %w", err)
+	}
+	return releases, nil
+}
+
+// ReleaseDate returns when version of a plugin was released. The directory
+// only dates the current release, so older versions are reported unknown.
+func (info *ExtensionInfo) ReleaseDate(version string) (time.Time, bool) {
+	if info.LastUpdated.IsZero() || version == "" || version != info.Version {
+		return time.Time{}, false
+	}
+	return info.LastUpdated, true
+}
+
+// DownloadPackage downloads the official zip of a plugin or theme release,
+// or of a WordPress core release when kind is KindCore (slug is ignored)
+func (c *Client) DownloadPackage(ctx context.Context, kind, slug, version string) ([]byte, error) {
+	var path string
+	switch kind {
+	case KindPlugin, KindTheme:
+		path = fmt.Sprintf("/%s/%s.%s.zip", kind, url.PathEscape(slug), url.PathEscape(version))
+	case KindCore:
+		path = fmt.Sprintf("/release/wordpress-%s.zip", url.PathEscape(version))
+	default:
+		return nil, fmt.Errorf("unsupported extension kind: %s", kind)
+	}
+	data, err := c.downloads.Get(ctx, path, nil)
+	if err != nil {
+		if api.IsNotFound(err) {
+			return nil, fmt.Errorf("%s %s %s: %w", kind, slug, version, ErrNotInDirectory)
+		}
+		return nil, fmt.Errorf("failed to download %s %s %s: %w", kind, slug, version, err)
+	}
+	return data, nil
+}
+
+// CoreChecksums fetches the MD5 of every file in an en_US core release,
+// keyed by path relative to the installation
+func (c *Client) CoreChecksums(ctx context.Context, version string) (map[string]string, error) {
+	query := url.Values{}
+	query.Set("version", version)
+	query.Set("locale", "en_US")
+
+	resp, err := c.Get(ctx, "/core/checksums/1.0/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch core %s checksums: %w", version, err)
+	}
+
+	// Unknown releases have "checksums": false
+	var raw struct {
+		Checksums json.RawMessage `json:"checksums"`
+	}
+	if err := json.Unmarshal(resp, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse core %s checksums: %w", version, err)
+	}
+	var checksums map[string]string
+	if err := json.Unmarshal(raw.Checksums, &checksums); err != nil || len(checksums) == 0 {
+		return nil, fmt.Errorf("core %s: no checksums available", version)
+	}
+	return checksums, nil
+}
+
+// PluginChecksums fetches the SHA-256 of every file in a plugin release,
+// keyed by path relative to the plugin directory. A file has several
+// checksums when it changed without the version changing.
+func (c *Client) PluginChecksums(ctx context.Context, slug, version string) (map[string][]string, error) {
+	path := fmt.Sprintf("/plugin-checksums/%s/%s.json", url.PathEscape(slug), url.PathEscape(version))
+	resp, err := c.downloads.Get(ctx, path, nil)
+	if err != nil {
+		if api.IsNotFound(err) {
+			return nil, fmt.Errorf("plugin %s %s: %w", slug, version, ErrNotInDirectory)
+		}
+		return nil, fmt.Errorf("failed to fetch plugin %s %s checksums: %w", slug, version, err)
+	}
+
+	var raw struct {
+		Files map[string]struct {
+			SHA256 json.RawMessage `json:"sha256"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(resp, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin %s %s checksums: %w", slug, version, err)
+	}
+
+	checksums := make(map[string][]string, len(raw.Files))
+	for name, file := range raw.Files {
+		var sums []string
+		if err := json.Unmarshal(file.SHA256, &sums); err != nil {
+			var sum string
+			if err := json.Unmarshal(file.SHA256, &sum); err != nil {
+				continue
+			}
+			sums = []string{sum}
+		}
+		checksums[name] = sums
+	}
+	return checksums, nil
+}
+
+// PackageLoader downloads release packages through a cache. Released
+// packages never change, so cached copies do not expire.
+type PackageLoader struct {
+	client *Client
+	cache  cache.Cache
+}
+
+// NewPackageLoader creates a package loader
+func NewPackageLoader(client *Client, c cache.Cache) *PackageLoader {
+	return &PackageLoader{
+		client: client,
+		cache:  c,
+	}
+}
+
+// Load returns the zip of a plugin, theme or core release, downloading it
+// if it is not cached
+func (l *PackageLoader) Load(ctx context.Context, kind, slug, version string) ([]byte, error) {
+	key := fmt.Sprintf("wporg-package:%s:%s:%s", kind, slug, version)
+	if data, err := l.cache.Get(ctx, key, 0); err == nil {
+		return data, nil
+	}
+
+	data, err := l.client.DownloadPackage(ctx, kind, slug, version)
+	if err != nil {
+		return nil, err
+	}
+	// A failed cache write only costs another download next time
+	_ = l.cache.Put(ctx, key, data)
+	return data, nil
+}
@@ -0,0 +1,206 @@
+package wporg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// directoryResponses are the info API's answers, keyed by kind and slug,
+// modelled on what api.wordpress.org returns
+var directoryResponses = map[string]struct {
+	status int
+	body   string
+}{
+	"plugin:outdated": {http.StatusOK, `{
+		"slug": "outdated", "name": "Outdated", "version": "2.1.0",
+		"last_updated": "2024-11-05 9:41pm GMT", "added": "2015-03-02",
+		"versions": {"2.0.0": "https://downloads.wordpress.org/plugin/outdated.2.0.0.zip", "2.1.0": "https://downloads.wordpress.org/plugin/outdated.2.1.0.zip"}
+	}`},
+	"plugin:abandoned": {http.StatusOK, `{
+		"slug": "abandoned", "name": "Abandoned", "version": "1.0",
+		"last_updated": "2016-04-12 3:04pm GMT", "versions": []
+	}`},
+	"plugin:closed": {http.StatusNotFound, `{
+		"error": "closed", "name": "Closed", "slug": "closed",
+		"closed": true, "closed_date": "2023-06-01", "reason": "security-issue", "reason_text": "Security Issue"
+	}`},
+	"plugin:premium": {http.StatusNotFound, `{"error": "Plugin not found."}`},
+	"plugin:broken":  {http.StatusInternalServerError, `Internal Server Error`},
+	"plugin:garbled": {http.StatusOK, `<html>`},
+	"theme:current": {http.StatusOK, `{
+		"slug": "current", "name": "Current", "version": "1.9",
+		"last_updated_time": "2024-12-01 10:00:00", "last_updated": "2024-12-01"
+	}`},
+	"theme:custom": {http.StatusOK, `false`},
+}
+
+// newTestClient returns a client of a fake directory that doesn't retry
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	for _, kind := range []string{KindPlugin, KindTheme} {
+		mux.HandleFunc("/"+kind+"s/info/1.2/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("action") != kind+"_information" {
+				t.Errorf("unexpected action %q", r.URL.Query().Get("action"))
+			}
+			resp, ok := directoryResponses[kind+":"+r.URL.Query().Get("request[slug]")]
+			if !ok {
+				resp.status, resp.body = http.StatusNotFound, `{"error": "Plugin not found."}`
+			}
+			w.WriteHeader(resp.status)
+			_, _ = w.Write([]byte(resp.body))
+		})
+	}
+	mux.HandleFunc("/core/stable-check/1.0/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"6.6.2": "outdated", "6.7.1": "latest", "4.9.1": "insecure"}`))
+	})
+	mux.HandleFunc("/core/checksums/1.0/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("version") == "6.7.1" {
+			_, _ = w.Write([]byte(`{"checksums": {"wp-login.php": "4b8a2ed1a2ad4b6d4b2e2b4c6d9f9e3a"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"checksums": false}`))
+	})
+	mux.HandleFunc("/plugin/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plugin/outdated.2.1.0.zip" {
+			_, _ = w.Write([]byte("PK"))
+			return
+		}
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return NewClient(WithBaseURL(server.URL), WithDownloadsURL(server.URL), WithClientOptions(api.WithRetries(0)))
+}
+
+func TestPluginInfoOutdated(t *testing.T) {
+	info, err := newTestClient(t).PluginInfo(context.Background(), "outdated")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Slug != "outdated" || info.Name != "Outdated" || info.Version != "2.1.0" || info.Closed {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if len(info.Versions) != 2 {
+		t.Errorf("expected 2 versions, got %v", info.Versions)
+	}
+	if want := time.Date(2024, 11, 5, 21, 41, 0, 0, time.UTC); !info.LastUpdated.Equal(want) {
+		t.Errorf("last updated %v, want %v", info.LastUpdated, want)
+	}
+	if want := time.Date(2015, 3, 2, 0, 0, 0, 0, time.UTC); !info.Added.Equal(want) {
+		t.Errorf("added %v, want %v", info.Added, want)
+	}
+	if date, ok := info.ReleaseDate("2.1.0"); !ok || !date.Equal(info.LastUpdated) {
+		t.Errorf("expected the current release to be dated, got %v, %v", date, ok)
+	}
+	if _, ok := info.ReleaseDate("2.0.0"); ok {
+		t.Error("expected older releases to be undated")
+	}
+}
+
+func TestPluginInfoAbandoned(t *testing.T) {
+	info, err := newTestClient(t).PluginInfo(context.Background(), "abandoned")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2016, 4, 12, 15, 4, 0, 0, time.UTC); !info.LastUpdated.Equal(want) {
+		t.Errorf("last updated %v, want %v", info.LastUpdated, want)
+	}
+	// Extensions without tagged releases have an empty array of versions
+	if info.Versions != nil {
+		t.Errorf("expected no versions, got %v", info.Versions)
+	}
+}
+
+func TestPluginInfoClosed(t *testing.T) {
+	info, err := newTestClient(t).PluginInfo(context.Background(), "closed")
+	if err != nil {
+		t.Fatalf("expected closed plugins to be reported, got %v", err)
+	}
+	if !info.Closed || info.ClosedReason != "Security Issue" {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if want := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC); !info.ClosedDate.Equal(want) {
+		t.Errorf("closed date %v, want %v", info.ClosedDate, want)
+	}
+}
+
+func TestThemeInfo(t *testing.T) {
+	info, err := newTestClient(t).ThemeInfo(context.Background(), "current")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// last_updated_time is preferred for its time of day
+	if want := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC); info.Version != "1.9" || !info.LastUpdated.Equal(want) {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestInfoNotInDirectory(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	if _, err := c.PluginInfo(ctx, "premium"); !errors.Is(err, ErrNotInDirectory) {
+		t.Errorf("expected ErrNotInDirectory for an unknown plugin, got %v", err)
+	}
+	if _, err := c.ThemeInfo(ctx, "custom"); !errors.Is(err, ErrNotInDirectory) {
+		t.Errorf("expected ErrNotInDirectory for an unknown theme, got %v", err)
+	}
+}
+
+func TestInfoErrors(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := c.PluginInfo(ctx, "broken")
+	if err == nil || errors.Is(err, ErrNotInDirectory) {
+		t.Errorf("expected a server error, got %v", err)
+	}
+	if httpErr, ok := api.IsHTTPError(err); !ok || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected the HTTP error to be wrapped, got %v", err)
+	}
+	if _, err := c.PluginInfo(ctx, "garbled"); err == nil || errors.Is(err, ErrNotInDirectory) {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestCoreReleases(t *testing.T) {
+	releases, err := newTestClient(t).CoreReleases(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if releases["6.7.1"] != CoreLatest || releases["6.6.2"] != CoreOutdated || releases["4.9.1"] != CoreInsecure {
+		t.Errorf("unexpected releases %v", releases)
+	}
+}
+
+func TestCoreChecksums(t *testing.T) {
+	c := newTestClient(t)
+	checksums, err := c.CoreChecksums(context.Background(), "6.7.1")
+	if err != nil || len(checksums) != 1 {
+		t.Fatalf("unexpected checksums %v, %v", checksums, err)
+	}
+	if _, err := c.CoreChecksums(context.Background(), "0.1"); err == nil {
+		t.Error("expected an error for a release without checksums")
+	}
+}
+
+func TestDownloadPackage(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	if data, err := c.DownloadPackage(ctx, KindPlugin, "outdated", "2.1.0"); err != nil || string(data) != "PK" {
+		t.Errorf("unexpected package %q, %v", data, err)
+	}
+	if _, err := c.DownloadPackage(ctx, KindPlugin, "premium", "1.0"); !errors.Is(err, ErrNotInDirectory) {
+		t.Errorf("expected ErrNotInDirectory for a missing package, got %v", err)
+	}
+	if _, err := c.DownloadPackage(ctx, "mu-plugin", "x", "1.0"); err == nil {
+		t.Error("expected an error for an unsupported kind")
+	}
+}
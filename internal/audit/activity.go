@@ -0,0 +1,59 @@
+// Package audit provides reading of active plugins and themes from the database
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// ReadActiveExtensions reads the site's active plugins and theme from its
+// options. On multisite installations, network-activated plugins count as
+// active too.
+func ReadActiveExtensions(ctx context.Context, db Querier, cfg *wordpress.WPConfig) (*wordpress.ActiveExtensions, error) {
+	if !cfg.ValidTablePrefix() {
+		return nil, fmt.Errorf("unsafe table prefix %q in %s", cfg.TablePrefix, cfg.Path)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT option_name, option_value FROM %soptions WHERE option_name IN "+
+			"('active_plugins', 'template', 'stylesheet')",
+		cfg.TablePrefix)
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying active plugins: %w", err)
+	}
+
+	active := &wordpress.ActiveExtensions{}
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		switch row[0] {
+		case "active_plugins":
+			active.Plugins = append(active.Plugins, phpSerializedStrings(row[1])...)
+		case "template":
+			active.Template = row[1]
+		case "stylesheet":
+			active.Stylesheet = row[1]
+		}
+	}
+
+	if cfg.Bool("MULTISITE") {
+		// Network-activated plugins are the keys of a file => time array
+		query := fmt.Sprintf(
+			"SELECT meta_key, meta_value FROM %ssitemeta WHERE meta_key = 'active_sitewide_plugins'",
+			cfg.TablePrefix)
+		rows, err := db.Query(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("querying network active plugins: %w", err)
+		}
+		for _, row := range rows {
+			if len(row) >= 2 {
+				active.Plugins = append(active.Plugins, phpSerializedStrings(row[1])...)
+			}
+		}
+	}
+	return active, nil
+}
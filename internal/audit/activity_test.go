@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+func TestReadActiveExtensions(t *testing.T) {
+	db := fakeQuerier{
+		"options": {
+			{"active_plugins", `a:2:{i:0;s:19:"akismet/akismet.php";i:1;s:9:"hello.php";}`},
+			{"template", "astra"},
+			{"stylesheet", "astra-child"},
+		},
+		"sitemeta": {
+			{"active_sitewide_plugins", `a:1:{s:25:"wordfence/wordfence.php";i:1717200000;}`},
+		},
+	}
+
+	cfg := &wordpress.WPConfig{TablePrefix: "wp_", Constants: map[string]string{"MULTISITE": "true"}}
+	active, err := ReadActiveExtensions(context.Background(), db, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(active.Plugins) != 3 || active.Plugins[2] != "wordfence/wordfence.php" {
+		t.Errorf("unexpected active plugins: %v", active.Plugins)
+	}
+	if active.Template != "astra" || active.Stylesheet != "astra-child" {
+		t.Errorf("unexpected themes: %q, %q", active.Template, active.Stylesheet)
+	}
+
+	// Network-activated plugins are only read on multisite
+	active, err = ReadActiveExtensions(context.Background(), db, &wordpress.WPConfig{TablePrefix: "wp_"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(active.Plugins) != 2 {
+		t.Errorf("unexpected active plugins: %v", active.Plugins)
+	}
+
+	if _, err := ReadActiveExtensions(context.Background(), db, &wordpress.WPConfig{TablePrefix: "wp_; DROP"}); err == nil {
+		t.Error("expected error for an unsafe table prefix")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build darwin || freebsd || netbsd
+
+// Package audit provides inode change times on macOS and the BSDs
+package audit
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// changeTime returns a file's inode change time
+func changeTime(info fs.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Ctimespec.Unix()), true
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd
+
+// Package audit provides inode change times on platforms without them
+package audit
+
+import (
+	"io/fs"
+	"time"
+)
+
+// changeTime reports no change time on platforms without one in stat
+func changeTime(_ fs.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,368 @@
+// Package audit provides WordPress database checks
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// DefaultRecentAdminAge is how new an administrator account must be to be
+// reported as recently created
+const DefaultRecentAdminAge = 30 * 24 * time.Hour
+
+// Check names reported by the database auditor
+const (
+	CheckAdminUsers = "admin-users"
+	CheckOptions    = "options"
+	CheckCron       = "cron"
+)
+
+// mysqlTimeLayout is the format of DATETIME columns
+const mysqlTimeLayout = "2006-01-02 15:04:05"
+
+var (
+	// serializedString matches string values in PHP serialize() output
+	serializedString = regexp.MustCompile(`s:\d+:"([^"]*)";`)
+
+	// serializedCronHook matches cron hook names, which are keys of arrays
+	// keyed by 32-character argument hashes
+	serializedCronHook = regexp.MustCompile(`s:\d+:"([^"]+)";a:\d+:\{s:32:"[0-9a-f]{32}"`)
+)
+
+// suspiciousAdminLogins are account names commonly created by malware
+var suspiciousAdminLogins = map[string]bool{
+	"wpadmin":     true,
+	"wp-admin":    true,
+	"wp_admin":    true,
+	"wordpress":   true,
+	"wpsupport":   true,
+	"wp-support":  true,
+	"adminbackup": true,
+	"backupadmin": true,
+	"wpcron":      true,
+	"wp_update":   true,
+	"wp-update":   true,
+	"system":      true,
+	"sysadmin":    true,
+	"root":        true,
+}
+
+// coreCronHooks are cron events scheduled by WordPress core
+var coreCronHooks = map[string]bool{
+	"wp_version_check":                   true,
+	"wp_update_plugins":                  true,
+	"wp_update_themes":                   true,
+	"wp_maybe_auto_update":               true,
+	"wp_scheduled_delete":                true,
+	"wp_scheduled_auto_draft_delete":     true,
+	"delete_expired_transients":          true,
+	"wp_privacy_delete_old_export_files": true,
+	"recovery_mode_clean_expired_keys":   true,
+	"wp_site_health_scheduled_check":     true,
+	"wp_https_detection":                 true,
+	"wp_update_user_counts":              true,
+	"wp_delete_temp_updater_backups":     true,
+	"wp_batch_split_terms":               true,
+	"wp_split_shared_term_batch":         true,
+	"publish_future_post":                true,
+	"do_pings":                           true,
+	"importer_scheduled_cleanup":         true,
+	"upgrader_scheduled_cleanup":         true,
+	"wp_update_comment_type_batch":       true,
+}
+
+// DatabaseAuditor checks a WordPress database for signs of compromise
+type DatabaseAuditor struct {
+	db             Querier
+	prefix         string
+	pluginsDir     string
+	recentAdminAge time.Duration
+	now            func() time.Time
+}
+
+// DatabaseOption configures a DatabaseAuditor
+type DatabaseOption func(*DatabaseAuditor)
+
+// WithPluginsDir sets the plugins directory used to verify active_plugins
+func WithPluginsDir(dir string) DatabaseOption {
+	return func(a *DatabaseAuditor) {
+		a.pluginsDir = dir
+	}
+}
+
+// WithRecentAdminAge sets how new an administrator must be to be reported
+func WithRecentAdminAge(age time.Duration) DatabaseOption {
+	return func(a *DatabaseAuditor) {
+		a.recentAdminAge = age
+	}
+}
+
+// NewDatabaseAuditor creates an auditor for the database described by cfg
+func NewDatabaseAuditor(db Querier, cfg *wordpress.WPConfig, opts ...DatabaseOption) (*DatabaseAuditor, error) {
+	if !cfg.ValidTablePrefix() {
+		return nil, fmt.Errorf("unsafe table prefix %q in %s", cfg.TablePrefix, cfg.Path)
+	}
+
+	a := &DatabaseAuditor{
+		db:             db,
+		prefix:         cfg.TablePrefix,
+		recentAdminAge: DefaultRecentAdminAge,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
+}
+
+// Run runs all database checks
+func (a *DatabaseAuditor) Run(ctx context.Context) ([]*Finding, error) {
+	var findings []*Finding
+
+	admins, err := a.checkAdmins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, admins...)
+
+	options, err := a.checkOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, options...)
+
+	SortFindings(findings)
+	return findings, nil
+}
+
+// checkAdmins lists administrators and flags new or suspiciously named ones
+func (a *DatabaseAuditor) checkAdmins(ctx context.Context) ([]*Finding, error) {
+	query := fmt.Sprintf(
+		"SELECT u.user_login, u.user_email, u.user_registered FROM %[1]susers u "+
+			"JOIN %[1]susermeta m ON m.user_id = u.ID "+
+			"WHERE m.meta_key = '%[1]scapabilities' AND m.meta_value LIKE '%%\"administrator\"%%' "+
+			"ORDER BY u.user_registered",
+		a.prefix)
+
+	rows, err := a.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying administrators: %w", err)
+	}
+
+	var findings []*Finding
+	for _, row := range rows {
+		if len(row) < 3 {
+			continue
+		}
+		login, email, registered := row[0], row[1], row[2]
+		subject := fmt.Sprintf("user %s <%s>", login, email)
+
+		findings = append(findings, &Finding{
+			Check:    CheckAdminUsers,
+			Severity: SeverityInfo,
+			Subject:  subject,
+			Message:  "administrator account registered " + registered,
+		})
+
+		if suspiciousAdminLogins[strings.ToLower(login)] {
+			findings = append(findings, &Finding{
+				Check:    CheckAdminUsers,
+				Severity: SeverityHigh,
+				Subject:  subject,
+				Message:  "administrator name is commonly used by malware-created accounts",
+			})
+		}
+
+		created, err := time.Parse(mysqlTimeLayout, registered)
+		if err == nil && a.recentAdminAge > 0 && a.now().Sub(created) < a.recentAdminAge {
+			findings = append(findings, &Finding{
+				Check:    CheckAdminUsers,
+				Severity: SeverityHigh,
+				Subject:  subject,
+				Message:  "administrator account created recently (" + registered + ")",
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkOptions inspects options commonly tampered with by attackers
+func (a *DatabaseAuditor) checkOptions(ctx context.Context) ([]*Finding, error) {
+	query := fmt.Sprintf(
+		"SELECT option_name, option_value FROM %soptions WHERE option_name IN "+
+			"('siteurl', 'home', 'active_plugins', 'cron', 'users_can_register', 'default_role')",
+		a.prefix)
+
+	rows, err := a.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying options: %w", err)
+	}
+
+	options := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if len(row) >= 2 {
+			options[row[0]] = row[1]
+		}
+	}
+
+	var findings []*Finding
+	findings = append(findings, checkSiteURLs(options["siteurl"], options["home"])...)
+	findings = append(findings, checkRegistration(options["users_can_register"], options["default_role"])...)
+	findings = append(findings, a.checkActivePlugins(options["active_plugins"])...)
+	findings = append(findings, a.checkCronHooks(options["cron"], options["active_plugins"])...)
+	return findings, nil
+}
+
+// checkSiteURLs flags injected or mismatched siteurl/home values
+func checkSiteURLs(siteURL, home string) []*Finding {
+	var findings []*Finding
+	hosts := make(map[string]string)
+
+	for name, value := range map[string]string{"siteurl": siteURL, "home": home} {
+		if value == "" {
+			continue
+		}
+		lower := strings.ToLower(value)
+		if strings.ContainsAny(value, "<>\"'") || strings.Contains(lower, "javascript:") {
+			findings = append(findings, &Finding{
+				Check:    CheckOptions,
+				Severity: SeverityCritical,
+				Subject:  "option " + name,
+				Message:  "contains markup or script: " + value,
+			})
+			continue
+		}
+
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			findings = append(findings, &Finding{
+				Check:    CheckOptions,
+				Severity: SeverityHigh,
+				Subject:  "option " + name,
+				Message:  "is not a valid http(s) URL: " + value,
+			})
+			continue
+		}
+		hosts[name] = strings.ToLower(u.Hostname())
+	}
+
+	if hosts["siteurl"] != "" && hosts["home"] != "" &&
+		strings.TrimPrefix(hosts["siteurl"], "www.") != strings.TrimPrefix(hosts["home"], "www.") {
+		findings = append(findings, &Finding{
+			Check:    CheckOptions,
+			Severity: SeverityHigh,
+			Subject:  "option siteurl",
+			Message:  fmt.Sprintf("siteurl host %s differs from home host %s (possible redirect hijack)", hosts["siteurl"], hosts["home"]),
+		})
+	}
+	return findings
+}
+
+// checkRegistration flags open registration granting administrator
+func checkRegistration(usersCanRegister, defaultRole string) []*Finding {
+	if defaultRole != "administrator" {
+		return nil
+	}
+	severity := SeverityHigh
+	message := "new users are given the administrator role"
+	if usersCanRegister == "1" {
+		severity = SeverityCritical
+		message = "anyone can register and is given the administrator role"
+	}
+	return []*Finding{{
+		Check:    CheckOptions,
+		Severity: severity,
+		Subject:  "option default_role",
+		Message:  message,
+	}}
+}
+
+// checkActivePlugins flags active_plugins entries that are not plugin files
+func (a *DatabaseAuditor) checkActivePlugins(serialized string) []*Finding {
+	var findings []*Finding
+	for _, plugin := range phpSerializedStrings(serialized) {
+		subject := "active plugin " + plugin
+		if filepath.IsAbs(plugin) || strings.Contains(plugin, "..") || !strings.HasSuffix(plugin, ".php") {
+			findings = append(findings, &Finding{
+				Check:    CheckOptions,
+				Severity: SeverityCritical,
+				Subject:  subject,
+				Message:  "active_plugins entry does not point into the plugins directory (possible injection)",
+			})
+			continue
+		}
+
+		if a.pluginsDir == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(a.pluginsDir, filepath.FromSlash(plugin))); err != nil {
+			findings = append(findings, &Finding{
+				Check:    CheckOptions,
+				Severity: SeverityMedium,
+				Subject:  subject,
+				Message:  "active plugin file is missing from " + a.pluginsDir,
+			})
+		}
+	}
+	return findings
+}
+
+// checkCronHooks flags scheduled events not registered by core or an
+// active plugin
+func (a *DatabaseAuditor) checkCronHooks(serializedCron, serializedPlugins string) []*Finding {
+	var prefixes []string
+	for _, plugin := range phpSerializedStrings(serializedPlugins) {
+		slug := strings.SplitN(plugin, "/", 2)[0]
+		slug = strings.TrimSuffix(slug, ".php")
+		prefixes = append(prefixes, normaliseHook(slug))
+	}
+
+	var findings []*Finding
+	seen := make(map[string]bool)
+	for _, m := range serializedCronHook.FindAllStringSubmatch(serializedCron, -1) {
+		hook := m[1]
+		if seen[hook] || coreCronHooks[hook] || hasHookPrefix(normaliseHook(hook), prefixes) {
+			continue
+		}
+		seen[hook] = true
+		findings = append(findings, &Finding{
+			Check:    CheckCron,
+			Severity: SeverityLow,
+			Subject:  "cron event " + hook,
+			Message:  "scheduled event is not registered by WordPress core or an active plugin",
+		})
+	}
+	return findings
+}
+
+// phpSerializedStrings returns the string values in PHP serialize() output
+func phpSerializedStrings(serialized string) []string {
+	var values []string
+	for _, m := range serializedString.FindAllStringSubmatch(serialized, -1) {
+		values = append(values, m[1])
+	}
+	return values
+}
+
+// normaliseHook lowercases a name and unifies separators for comparison
+func normaliseHook(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(name))
+}
+
+// hasHookPrefix reports whether hook starts with any plugin prefix
+func hasHookPrefix(hook string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(hook, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// fakeQuerier returns canned rows for queries containing a key
+type fakeQuerier map[string][][]string
+
+func (f fakeQuerier) Query(_ context.Context, query string) ([][]string, error) {
+	for key, rows := range f {
+		if strings.Contains(query, key) {
+			return rows, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestDatabaseAuditor(t *testing.T) {
+	pluginsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(pluginsDir, "akismet"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginsDir, "akismet", "akismet.php"), []byte("<?php"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	db := fakeQuerier{
+		"usermeta": {
+			{"alice", "alice@example.com", "2019-01-01 00:00:00"},
+			{"wpadmin", "x@evil.example", "2024-05-30 12:00:00"},
+		},
+		"options": {
+			{"siteurl", "https://example.com"},
+			{"home", "https://evil.example"},
+			{"active_plugins", `a:3:{i:0;s:19:"akismet/akismet.php";i:1;s:15:"ghost/ghost.php";i:2;s:17:"../../../evil.php";}`},
+			{"cron", `a:2:{i:1717200000;a:1:{s:16:"wp_version_check";a:1:{s:32:"40cd750bba9870f18aada2478b24840a";a:0:{}}}i:1717200001;a:1:{s:11:"akismet_run";a:1:{s:32:"40cd750bba9870f18aada2478b24840a";a:0:{}}}i:1717200002;a:1:{s:10:"xq_updater";a:1:{s:32:"40cd750bba9870f18aada2478b24840a";a:0:{}}}}`},
+			{"default_role", "administrator"},
+			{"users_can_register", "1"},
+		},
+	}
+
+	auditor, err := NewDatabaseAuditor(db, &wordpress.WPConfig{TablePrefix: "wp_"}, WithPluginsDir(pluginsDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	auditor.now = func() time.Time { return now }
+
+	findings, err := auditor.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expect := []struct {
+		severity Severity
+		subject  string
+	}{
+		{SeverityCritical, "option default_role"},
+		{SeverityCritical, "active plugin ../../../evil.php"},
+		{SeverityHigh, "option siteurl"},
+		{SeverityHigh, "user wpadmin <x@evil.example>"},
+		{SeverityMedium, "active plugin ghost/ghost.php"},
+		{SeverityLow, "cron event xq_updater"},
+	}
+	for _, e := range expect {
+		found := false
+		for _, f := range findings {
+			if f.Severity == e.severity && f.Subject == e.subject {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected %s finding for %q", e.severity, e.subject)
+		}
+	}
+
+	for _, f := range findings {
+		if f.Severity > SeverityInfo && strings.Contains(f.Subject, "alice") {
+			t.Errorf("unexpected finding for established admin: %+v", f)
+		}
+		if strings.Contains(f.Subject, "akismet") {
+			t.Errorf("unexpected finding for installed plugin: %+v", f)
+		}
+	}
+
+	if findings[0].Severity != SeverityCritical {
+		t.Error("expected findings sorted by severity")
+	}
+}
+
+func TestNewDatabaseAuditorRejectsUnsafePrefix(t *testing.T) {
+	_, err := NewDatabaseAuditor(fakeQuerier{}, &wordpress.WPConfig{TablePrefix: "wp_; DROP TABLE x"})
+	if err == nil {
+		t.Error("expected error for unsafe table prefix")
+	}
+}
+
+func TestParseBatchOutput(t *testing.T) {
+	rows := parseBatchOutput("a\tb\\tc\nline\\nbreak\tback\\\\slash\n")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0][1] != "b\tc" || rows[1][0] != "line\nbreak" || rows[1][1] != `back\slash` {
+		t.Errorf("unexpected unescaping: %q", rows)
+	}
+}
+
+func TestParseDBHost(t *testing.T) {
+	tests := []struct {
+		in     string
+		host   string
+		port   int
+		socket string
+	}{
+		{"", "localhost", 0, ""},
+		{"db.internal", "db.internal", 0, ""},
+		{"127.0.0.1:3307", "127.0.0.1", 3307, ""},
+		{"localhost:/run/mysqld/mysqld.sock", "localhost", 0, "/run/mysqld/mysqld.sock"},
+	}
+	for _, tt := range tests {
+		host, port, socket := parseDBHost(tt.in)
+		if host != tt.host || port != tt.port || socket != tt.socket {
+			t.Errorf("parseDBHost(%q) = %q, %d, %q", tt.in, host, port, socket)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package audit provides WordPress configuration hardening checks
+package audit
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// Check names reported by the hardening checker
+const (
+	CheckConfig      = "config"
+	CheckPermissions = "permissions"
+	CheckExposure    = "exposed-files"
+)
+
+// backupFilePattern matches files in the web root that commonly leak
+// credentials or data when left behind
+var backupFilePattern = regexp.MustCompile(`(?i)^(?:\.?wp-config\.php[._~-].*|\.?wp-config\.php~|wp-config\.(?:old|bak|orig|txt|save)|.*\.sql(?:\.gz|\.zip|\.bz2)?|(?:backup|site|www|wordpress|db|database)[^/]*\.(?:zip|tar|tar\.gz|tgz|rar|7z))$`)
+
+// HardeningChecker inspects a site's configuration, permissions and web
+// root for weaknesses
+type HardeningChecker struct {
+	sitePath    string
+	contentPath string
+	config      *wordpress.WPConfig
+}
+
+// NewHardeningChecker creates a checker for the site at sitePath
+func NewHardeningChecker(sitePath, contentPath string, cfg *wordpress.WPConfig) *HardeningChecker {
+	if contentPath == "" {
+		contentPath = filepath.Join(sitePath, "wp-content")
+	}
+	return &HardeningChecker{
+		sitePath:    sitePath,
+		contentPath: contentPath,
+		config:      cfg,
+	}
+}
+
+// Run runs all hardening checks
+func (h *HardeningChecker) Run() []*Finding {
+	var findings []*Finding
+	findings = append(findings, h.checkConfig()...)
+	findings = append(findings, h.checkPermissions()...)
+	findings = append(findings, h.checkExposedFiles()...)
+	SortFindings(findings)
+	return findings
+}
+
+// checkConfig inspects security-relevant wp-config.php constants
+func (h *HardeningChecker) checkConfig() []*Finding {
+	if h.config == nil {
+		return nil
+	}
+
+	var findings []*Finding
+	subject := h.config.Path
+	add := func(severity Severity, message string) {
+		findings = append(findings, &Finding{Check: CheckConfig, Severity: severity, Subject: subject, Message: message})
+	}
+
+	if h.config.Bool("WP_DEBUG") {
+		// WP_DEBUG_DISPLAY defaults to true when WP_DEBUG is on
+		if _, ok := h.config.Constants["WP_DEBUG_DISPLAY"]; !ok || h.config.Bool("WP_DEBUG_DISPLAY") {
+			add(SeverityMedium, "WP_DEBUG is enabled and errors are displayed to visitors")
+		} else {
+			add(SeverityLow, "WP_DEBUG is enabled on a production site")
+		}
+		if h.config.Bool("WP_DEBUG_LOG") {
+			add(SeverityLow, "WP_DEBUG_LOG writes errors to wp-content/debug.log, which may be web accessible")
+		}
+	}
+
+	if !h.config.Bool("DISALLOW_FILE_EDIT") && !h.config.Bool("DISALLOW_FILE_MODS") {
+		add(SeverityLow, "DISALLOW_FILE_EDIT is not set; administrators can edit PHP files from the dashboard")
+	}
+
+	if strings.EqualFold(h.config.Constants["FS_METHOD"], "direct") {
+		add(SeverityLow, "FS_METHOD is 'direct'; the web server user can write plugin and core files")
+	}
+
+	if h.config.DBUser == "root" {
+		add(SeverityMedium, "the site connects to the database as root")
+	}
+
+	if h.config.TablePrefix == wordpress.DefaultTablePrefix {
+		add(SeverityInfo, "the default table prefix wp_ is in use")
+	}
+
+	return findings
+}
+
+// checkPermissions flags world-writable directories and exposed secrets
+func (h *HardeningChecker) checkPermissions() []*Finding {
+	var findings []*Finding
+
+	if h.config != nil {
+		if info, err := os.Stat(h.config.Path); err == nil {
+			mode := info.Mode().Perm()
+			switch {
+			case mode&0o002 != 0:
+				findings = append(findings, permissionFinding(SeverityCritical, h.config.Path, mode, "wp-config.php is world-writable"))
+			case mode&0o004 != 0:
+				findings = append(findings, permissionFinding(SeverityMedium, h.config.Path, mode, "wp-config.php is world-readable"))
+			}
+		}
+	}
+
+	dirs := []struct {
+		path     string
+		severity Severity
+	}{
+		{h.sitePath, SeverityHigh},
+		{h.contentPath, SeverityHigh},
+		{filepath.Join(h.contentPath, "plugins"), SeverityHigh},
+		{filepath.Join(h.contentPath, "themes"), SeverityHigh},
+		{filepath.Join(h.contentPath, "uploads"), SeverityMedium},
+	}
+	for _, dir := range dirs {
+		info, err := os.Stat(dir.path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		mode := info.Mode().Perm()
+		if mode&0o002 != 0 {
+			findings = append(findings, permissionFinding(dir.severity, dir.path, mode, "directory is world-writable"))
+		}
+	}
+
+	// World-writable PHP files anywhere in wp-content can be modified by
+	// any local user
+	_ = filepath.WalkDir(h.contentPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".php") {
+			return nil //nolint:nilerr // unreadable entries are skipped
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr // unreadable entries are skipped
+		}
+		if mode := info.Mode().Perm(); mode&0o002 != 0 {
+			findings = append(findings, permissionFinding(SeverityHigh, path, mode, "PHP file is world-writable"))
+		}
+		return nil
+	})
+
+	return findings
+}
+
+// permissionFinding builds a permissions finding that includes the mode
+func permissionFinding(severity Severity, path string, mode fs.FileMode, message string) *Finding {
+	return &Finding{
+		Check:    CheckPermissions,
+		Severity: severity,
+		Subject:  path,
+		Message:  fmt.Sprintf("%s (mode %04o)", message, mode),
+	}
+}
+
+// checkExposedFiles looks for backups, dumps and logs in public locations
+func (h *HardeningChecker) checkExposedFiles() []*Finding {
+	var findings []*Finding
+
+	entries, err := os.ReadDir(h.sitePath)
+	if err == nil {
+		for _, entry := range entries {
+			path := filepath.Join(h.sitePath, entry.Name())
+			switch {
+			case entry.IsDir() && entry.Name() == ".git":
+				findings = append(findings, &Finding{
+					Check:    CheckExposure,
+					Severity: SeverityHigh,
+					Subject:  path,
+					Message:  "git repository in the web root may expose source and history",
+				})
+			case !entry.IsDir() && backupFilePattern.MatchString(entry.Name()):
+				severity := SeverityHigh
+				if strings.Contains(strings.ToLower(entry.Name()), "wp-config") {
+					severity = SeverityCritical
+				}
+				findings = append(findings, &Finding{
+					Check:    CheckExposure,
+					Severity: severity,
+					Subject:  path,
+					Message:  "backup or database dump in the web root may be downloadable",
+				})
+			}
+		}
+	}
+
+	debugLog := filepath.Join(h.contentPath, "debug.log")
+	if info, err := os.Stat(debugLog); err == nil && info.Mode().IsRegular() {
+		findings = append(findings, &Finding{
+			Check:    CheckExposure,
+			Severity: SeverityMedium,
+			Subject:  debugLog,
+			Message:  fmt.Sprintf("debug log (%d bytes) may be web accessible", info.Size()),
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+func TestHardeningChecker(t *testing.T) {
+	site := t.TempDir()
+	content := filepath.Join(site, "wp-content")
+	for _, dir := range []string{"plugins/shop", "themes", "uploads"} {
+		if err := os.MkdirAll(filepath.Join(content, dir), 0o750); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]os.FileMode{
+		"wp-config.php":                  0o644,
+		".wp-config.php.bak":             0o600,
+		"dump.sql":                       0o600,
+		"index.php":                      0o600,
+		"wp-content/debug.log":           0o600,
+		"wp-content/plugins/shop/a.php":  0o666,
+		"wp-content/plugins/shop/ok.php": 0o640,
+	}
+	for name, mode := range files {
+		path := filepath.Join(site, filepath.FromSlash(name))
+		if err := os.WriteFile(path, []byte("<?php"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(content, "uploads"), 0o777); err != nil { // #nosec G302 -- test fixture
+		t.Fatal(err)
+	}
+
+	cfg := &wordpress.WPConfig{
+		Path:        filepath.Join(site, "wp-config.php"),
+		DBUser:      "wp",
+		TablePrefix: "wpx_",
+		Constants: map[string]string{
+			"WP_DEBUG":  "true",
+			"FS_METHOD": "direct",
+		},
+	}
+
+	findings := NewHardeningChecker(site, "", cfg).Run()
+
+	expect := []struct {
+		severity Severity
+		check    string
+		subject  string
+		message  string
+	}{
+		{SeverityCritical, CheckExposure, ".wp-config.php.bak", "backup"},
+		{SeverityHigh, CheckExposure, "dump.sql", "dump"},
+		{SeverityHigh, CheckPermissions, "a.php", "world-writable"},
+		{SeverityMedium, CheckPermissions, "uploads", "world-writable"},
+		{SeverityMedium, CheckPermissions, "wp-config.php", "world-readable"},
+		{SeverityMedium, CheckExposure, "debug.log", "debug log"},
+		{SeverityMedium, CheckConfig, "wp-config.php", "WP_DEBUG"},
+		{SeverityLow, CheckConfig, "wp-config.php", "DISALLOW_FILE_EDIT"},
+		{SeverityLow, CheckConfig, "wp-config.php", "FS_METHOD"},
+	}
+	for _, e := range expect {
+		found := false
+		for _, f := range findings {
+			if f.Severity == e.severity && f.Check == e.check &&
+				strings.HasSuffix(f.Subject, e.subject) && strings.Contains(f.Message, e.message) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected %s %s finding for %q", e.severity, e.check, e.subject)
+		}
+	}
+
+	for _, f := range findings {
+		if strings.HasSuffix(f.Subject, "index.php") || strings.HasSuffix(f.Subject, "ok.php") {
+			t.Errorf("unexpected finding: %+v", f)
+		}
+		if strings.Contains(f.Message, "table prefix") {
+			t.Errorf("unexpected table prefix finding: %+v", f)
+		}
+	}
+
+	if findings[0].Severity != SeverityCritical {
+		t.Error("expected findings sorted by severity")
+	}
+}
+
+func TestHardeningCheckerHardenedConfig(t *testing.T) {
+	site := t.TempDir()
+	cfg := &wordpress.WPConfig{
+		Path:        filepath.Join(site, "wp-config.php"),
+		TablePrefix: "site_",
+		Constants: map[string]string{
+			"WP_DEBUG":           "false",
+			"DISALLOW_FILE_EDIT": "true",
+		},
+	}
+	if err := os.WriteFile(cfg.Path, []byte("<?php"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if findings := NewHardeningChecker(site, "", cfg).Run(); len(findings) != 0 {
+		for _, f := range findings {
+			t.Errorf("unexpected finding: %+v", f)
+		}
+	}
+}
+
+func TestBackupFilePattern(t *testing.T) {
+	matches := []string{"wp-config.php.bak", ".wp-config.php.swp", "wp-config.php~", "wp-config.old", "db.sql", "backup.sql.gz", "backup-2024.zip", "site.tar.gz"}
+	for _, name := range matches {
+		if !backupFilePattern.MatchString(name) {
+			t.Errorf("expected %q to match", name)
+		}
+	}
+
+	for _, name := range []string{"wp-config.php", "wp-config-sample.php", "index.php", "readme.html", "plugin.zip"} {
+		if backupFilePattern.MatchString(name) {
+			t.Errorf("expected %q not to match", name)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package audit provides a MySQL query runner using the mysql client
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// DefaultMySQLClient is the mysql command-line client used for queries
+const DefaultMySQLClient = "mysql"
+
+// Querier runs SQL queries and returns rows of column values
+type Querier interface {
+	Query(ctx context.Context, query string) ([][]string, error)
+}
+
+// MySQLClient runs queries through the mysql command-line client, which
+// keeps the binary free of database drivers. Credentials are passed in a
+// private option file rather than on the command line.
+type MySQLClient struct {
+	binary   string
+	database string
+	user     string
+	password string
+	host     string
+	port     int
+	socket   string
+}
+
+// MySQLOption configures a MySQLClient
+type MySQLOption func(*MySQLClient)
+
+// WithMySQLBinary sets the mysql client binary
+func WithMySQLBinary(binary string) MySQLOption {
+	return func(c *MySQLClient) {
+		c.binary = binary
+	}
+}
+
+// NewMySQLClient creates a client for the database configured in wp-config.php
+func NewMySQLClient(cfg *wordpress.WPConfig, opts ...MySQLOption) *MySQLClient {
+	c := &MySQLClient{
+		binary:   DefaultMySQLClient,
+		database: cfg.DBName,
+		user:     cfg.DBUser,
+		password: cfg.DBPassword,
+	}
+	c.host, c.port, c.socket = parseDBHost(cfg.DBHost)
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// parseDBHost splits WordPress's DB_HOST ("host", "host:port" or
+// "host:/path/to/socket") into its parts
+func parseDBHost(dbHost string) (string, int, string) {
+	host, rest, found := strings.Cut(dbHost, ":")
+	if host == "" {
+		host = "localhost"
+	}
+	if !found {
+		return host, 0, ""
+	}
+	if strings.HasPrefix(rest, "/") {
+		return host, 0, rest
+	}
+	port, err := strconv.Atoi(rest)
+	if err != nil {
+		return host, 0, ""
+	}
+	return host, port, ""
+}
+
+// Query runs query in batch mode and returns its rows
+func (c *MySQLClient) Query(ctx context.Context, query string) ([][]string, error) {
+	optionFile, err := c.writeOptionFile()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(optionFile) }()
+
+	// --defaults-extra-file must be the first argument
+	args := []string{
+		"--defaults-extra-file=" + optionFile,
+		"--batch",
+		"--skip-column-names",
+		"--execute=" + query,
+		c.database,
+	}
+
+	cmd := exec.CommandContext(ctx, c.binary, args...) // #nosec G204 -- fixed client binary, query passed as a single argument
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("mysql query failed: %s", msg)
+	}
+
+	return parseBatchOutput(stdout.String()), nil
+}
+
+// writeOptionFile writes the connection settings to a private temp file
+func (c *MySQLClient) writeOptionFile() (string, error) {
+	file, err := os.CreateTemp("", "wordfence-mysql-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("creating mysql option file: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[client]\n")
+	fmt.Fprintf(&sb, "user=%s\n", quoteOption(c.user))
+	fmt.Fprintf(&sb, "password=%s\n", quoteOption(c.password))
+	fmt.Fprintf(&sb, "host=%s\n", quoteOption(c.host))
+	if c.port > 0 {
+		fmt.Fprintf(&sb, "port=%d\n", c.port)
+	}
+	if c.socket != "" {
+		fmt.Fprintf(&sb, "socket=%s\n", quoteOption(c.socket))
+	}
+
+	if _, err := file.WriteString(sb.String()); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("writing mysql option file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("writing mysql option file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// quoteOption quotes a value for a MySQL option file
+func quoteOption(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// parseBatchOutput splits mysql --batch output into rows, undoing its
+// escaping of tabs, newlines and backslashes
+func parseBatchOutput(output string) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		for i, field := range fields {
+			fields[i] = unescapeBatchField(field)
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
+
+// unescapeBatchField reverses mysql --batch escaping
+func unescapeBatchField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] != '\\' || i+1 >= len(field) {
+			sb.WriteByte(field[i])
+			continue
+		}
+		i++
+		switch field[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case '0':
+			sb.WriteByte(0)
+		default:
+			sb.WriteByte(field[i])
+		}
+	}
+	return sb.String()
+}
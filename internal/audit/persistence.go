@@ -0,0 +1,239 @@
+// Package audit provides host persistence detection
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CheckPersistence is the check name reported for persistence mechanisms
+const CheckPersistence = "persistence"
+
+// Persistence entry kinds
+const (
+	PersistenceCron    = "cron"
+	PersistenceSystemd = "systemd"
+	PersistencePHPIni  = "php-ini"
+)
+
+// cronSources are crontab files and directories, relative to the system root
+var cronSources = []string{
+	"etc/crontab",
+	"etc/anacrontab",
+	"etc/cron.d",
+	"etc/cron.hourly",
+	"etc/cron.daily",
+	"etc/cron.weekly",
+	"etc/cron.monthly",
+	"var/spool/cron",
+}
+
+// systemdSources are systemd unit directories, relative to the system root
+var systemdSources = []string{
+	"etc/systemd/system",
+	"run/systemd/system",
+	"lib/systemd/system",
+	"usr/lib/systemd/system",
+	"root/.config/systemd/user",
+	"home/*/.config/systemd/user",
+}
+
+// phpIniSources are php.ini files and directories, relative to the system root
+var phpIniSources = []string{
+	"etc/php.ini",
+	"etc/php.d",
+	"etc/php",
+	"usr/local/etc/php",
+	"usr/local/lib/php.ini",
+	"opt/*/etc/php.ini",
+	"opt/*/etc/php.d",
+}
+
+// PersistenceEntry is a scheduled job, service or PHP setting that runs a
+// file from a scanned docroot
+type PersistenceEntry struct {
+	Kind    string `json:"kind"`
+	Source  string `json:"source"`
+	Line    int    `json:"line"`
+	Command string `json:"command"`
+	Target  string `json:"target"`
+}
+
+// PersistenceScanner inspects host cron, systemd and PHP configuration
+type PersistenceScanner struct {
+	root     string
+	docroots []string
+}
+
+// PersistenceOption configures a PersistenceScanner
+type PersistenceOption func(*PersistenceScanner)
+
+// WithSystemRoot sets the directory system paths are resolved against
+func WithSystemRoot(root string) PersistenceOption {
+	return func(p *PersistenceScanner) {
+		p.root = root
+	}
+}
+
+// NewPersistenceScanner creates a scanner for references into docroots
+func NewPersistenceScanner(docroots []string, opts ...PersistenceOption) *PersistenceScanner {
+	p := &PersistenceScanner{root: "/"}
+	for _, docroot := range docroots {
+		if abs, err := filepath.Abs(docroot); err == nil {
+			p.docroots = append(p.docroots, abs)
+		}
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Scan returns every persistence entry referencing a docroot, plus any
+// PHP auto_prepend_file/auto_append_file setting. Unreadable sources, such
+// as other users' crontabs when not running as root, are skipped.
+func (p *PersistenceScanner) Scan() []*PersistenceEntry {
+	var entries []*PersistenceEntry
+	for _, file := range p.sourceFiles(cronSources, nil) {
+		entries = append(entries, p.scanFile(PersistenceCron, file)...)
+	}
+	for _, file := range p.sourceFiles(systemdSources, nil) {
+		entries = append(entries, p.scanFile(PersistenceSystemd, file)...)
+	}
+	for _, file := range p.sourceFiles(phpIniSources, isINIFile) {
+		entries = append(entries, p.scanFile(PersistencePHPIni, file)...)
+	}
+	return entries
+}
+
+// isINIFile reports whether path is a PHP ini file
+func isINIFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ini")
+}
+
+// sourceFiles expands source globs into regular files, walking directories
+func (p *PersistenceScanner) sourceFiles(sources []string, keep func(string) bool) []string {
+	var files []string
+	for _, source := range sources {
+		matches, _ := filepath.Glob(filepath.Join(p.root, filepath.FromSlash(source)))
+		for _, match := range matches {
+			_ = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil //nolint:nilerr // unreadable sources are skipped
+				}
+				if d.Type().IsRegular() && (keep == nil || keep(path)) {
+					files = append(files, path)
+				}
+				return nil
+			})
+		}
+	}
+	return files
+}
+
+// scanFile extracts persistence entries from one source file
+func (p *PersistenceScanner) scanFile(kind, path string) []*PersistenceEntry {
+	file, err := os.Open(path) // #nosec G304 -- fixed system configuration paths
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []*PersistenceEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if kind == PersistencePHPIni {
+			if m := prependPattern.FindStringSubmatch(line); m != nil && m[2] != "" && !strings.EqualFold(m[2], "none") {
+				entries = append(entries, &PersistenceEntry{Kind: kind, Source: path, Line: number, Command: line, Target: m[2]})
+			}
+			continue
+		}
+
+		for _, target := range p.docrootReferences(line) {
+			entries = append(entries, &PersistenceEntry{Kind: kind, Source: path, Line: number, Command: line, Target: target})
+		}
+	}
+	return entries
+}
+
+// docrootReferences returns the paths inside a docroot mentioned in line
+func (p *PersistenceScanner) docrootReferences(line string) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, docroot := range p.docroots {
+		rest := line
+		for {
+			i := strings.Index(rest, docroot)
+			if i < 0 {
+				break
+			}
+			end := strings.IndexAny(rest[i:], " \t\"'`;|&<>()")
+			if end < 0 {
+				end = len(rest) - i
+			}
+			target := filepath.Clean(rest[i : i+end])
+			rest = rest[i+end:]
+
+			// Require a path boundary so /var/www/site does not match /var/www/site2
+			if target != docroot && !strings.HasPrefix(target, docroot+string(filepath.Separator)) {
+				continue
+			}
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// CorrelatePersistence turns entries into findings, escalating entries
+// that run files with malware matches
+func CorrelatePersistence(entries []*PersistenceEntry, matched map[string]bool) []*Finding {
+	findings := make([]*Finding, 0, len(entries))
+	for _, e := range entries {
+		severity, reason := classifyPersistence(e, matched)
+		findings = append(findings, &Finding{
+			Check:    CheckPersistence,
+			Severity: severity,
+			Subject:  e.Target,
+			Message:  fmt.Sprintf("%s (%s entry at %s:%d: %s)", reason, e.Kind, e.Source, e.Line, summarizeDirective(e.Command)),
+		})
+	}
+	SortFindings(findings)
+	return findings
+}
+
+// classifyPersistence rates a single persistence entry
+func classifyPersistence(e *PersistenceEntry, matched map[string]bool) (Severity, string) {
+	base := strings.ToLower(filepath.Base(e.Target))
+	switch {
+	case matched[e.Target]:
+		return SeverityCritical, "file with malware matches is run by a persistence mechanism"
+	case e.Kind == PersistencePHPIni && base == "wordfence-waf.php":
+		return SeverityInfo, "PHP loads the Wordfence firewall on every request"
+	case e.Kind == PersistencePHPIni:
+		return SeverityHigh, "PHP runs this file before or after every request"
+	case base == "wp-cron.php" || strings.Contains(e.Command, "cron event run"):
+		return SeverityInfo, "WordPress cron is run by the system scheduler"
+	case strings.Contains(filepath.ToSlash(e.Target), "/uploads/"):
+		return SeverityHigh, "file in the uploads directory is run by a persistence mechanism"
+	}
+
+	if _, err := os.Stat(e.Target); err != nil {
+		return SeverityMedium, "persistence mechanism runs a file that no longer exists (it may be re-created)"
+	}
+	return SeverityMedium, "file in a scanned docroot is run by a persistence mechanism"
+}
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to path, creating parent directories
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPersistenceScanner(t *testing.T) {
+	root := t.TempDir()
+	docroot := filepath.Join(t.TempDir(), "site")
+	shell := filepath.Join(docroot, "wp-content", "uploads", "x.php")
+	loader := filepath.Join(docroot, "wp-includes", "load.php")
+	writeFile(t, shell, "<?php")
+	writeFile(t, loader, "<?php")
+
+	writeFile(t, filepath.Join(root, "var/spool/cron/crontabs/www-data"),
+		"# m h dom mon dow command\n*/5 * * * * php "+docroot+"/wp-cron.php >/dev/null\n@reboot php "+shell+"\n")
+	writeFile(t, filepath.Join(root, "etc/cron.d/other"), "0 * * * * root /usr/bin/true "+docroot+"2/x.php\n")
+	writeFile(t, filepath.Join(root, "etc/systemd/system/updater.service"),
+		"[Service]\nExecStart=/usr/bin/php "+loader+"\n")
+	writeFile(t, filepath.Join(root, "home/bob/.config/systemd/user/gone.service"),
+		"[Service]\nExecStart=/usr/bin/php "+docroot+"/.cache/gone.php\n")
+	writeFile(t, filepath.Join(root, "etc/php/8.2/fpm/conf.d/99-x.ini"),
+		"auto_prepend_file = /tmp/.sess.php\n;auto_append_file = /ignored.php\n")
+	writeFile(t, filepath.Join(root, "etc/php/8.2/fpm/php.ini"),
+		"auto_prepend_file = none\n")
+	writeFile(t, filepath.Join(root, "etc/php/8.2/fpm/README"),
+		"auto_prepend_file = /not/an/ini.php\n")
+
+	entries := NewPersistenceScanner([]string{docroot}, WithSystemRoot(root)).Scan()
+	if len(entries) != 5 {
+		for _, e := range entries {
+			t.Logf("%+v", e)
+		}
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	findings := CorrelatePersistence(entries, map[string]bool{loader: true})
+
+	expect := []struct {
+		target   string
+		severity Severity
+	}{
+		{loader, SeverityCritical},
+		{shell, SeverityHigh},
+		{"/tmp/.sess.php", SeverityHigh},
+		{filepath.Join(docroot, ".cache", "gone.php"), SeverityMedium},
+		{filepath.Join(docroot, "wp-cron.php"), SeverityInfo},
+	}
+	for _, e := range expect {
+		found := false
+		for _, f := range findings {
+			if f.Subject == e.target {
+				found = true
+				if f.Severity != e.severity {
+					t.Errorf("%s: expected severity %s, got %s (%s)", e.target, e.severity, f.Severity, f.Message)
+				}
+				if f.Check != CheckPersistence {
+					t.Errorf("%s: unexpected check %q", e.target, f.Check)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected finding for %s", e.target)
+		}
+	}
+
+	if !strings.Contains(findings[0].Message, "systemd entry") {
+		t.Errorf("expected the source kind in the message, got %q", findings[0].Message)
+	}
+}
+
+func TestDocrootReferencesBoundary(t *testing.T) {
+	p := NewPersistenceScanner([]string{"/var/www/site"})
+	refs := p.docrootReferences(`cd /var/www/site2 && php "/var/www/site/a.php";php /var/www/site/a.php|sh`)
+	if len(refs) != 1 || refs[0] != "/var/www/site/a.php" {
+		t.Errorf("unexpected references: %v", refs)
+	}
+}
@@ -0,0 +1,420 @@
+// Package audit provides web server configuration analysis
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CheckServerConfig is the check name reported by the server config analyzer
+const CheckServerConfig = "server-config"
+
+// hiddenDirectiveIndent is how much leading whitespace pushes a directive
+// far enough off-screen to be treated as hidden
+const hiddenDirectiveIndent = 80
+
+var (
+	// searchEnginePattern matches conditions that target search engine
+	// crawlers or visitors arriving from search results
+	searchEnginePattern = regexp.MustCompile(`(?i)google|bing|yahoo|yandex|baidu|duckduckgo|msn|aol|ask\.com|slurp|crawler|spider|bot`)
+
+	// mobilePattern matches conditions that target mobile visitors
+	mobilePattern = regexp.MustCompile(`(?i)android|iphone|ipad|ipod|mobile|blackberry|opera mini|windows phone`)
+
+	// externalURLPattern matches a literal absolute URL, excluding targets
+	// built from the request host
+	externalURLPattern = regexp.MustCompile(`(?i)^https?://([^/%${}\s"']+)`)
+
+	// nonPHPHandlerPattern matches handlers that execute non-PHP extensions
+	// as PHP
+	nonPHPHandlerPattern = regexp.MustCompile(`(?i)(?:x-httpd-php|php-script|php\d*-script|fcgid-script|FastCgiModule|php-cgi).*\.(?:jpe?g|png|gif|ico|txt|svg|bmp|webp|css|js|log)\b|\.(?:jpe?g|png|gif|ico|txt|svg|bmp|webp|css|js|log)\b.*(?:x-httpd-php|php-script|php\d*-script|php-cgi)`)
+
+	// targetingPattern matches conditions on the visitor's user agent or
+	// referrer in Apache, nginx and IIS syntax
+	targetingPattern = regexp.MustCompile(`(?i)HTTP_USER_AGENT|HTTP_REFERER|\$http_user_agent|\$http_referer`)
+
+	// prependPattern matches auto_prepend_file/auto_append_file settings
+	prependPattern = regexp.MustCompile(`(?i)\b(auto_(?:prepend|append)_file)\b\s*[= ]\s*["']?([^"'\s;]*)`)
+)
+
+// IsServerConfigFile reports whether name is a web server or PHP
+// configuration file the analyzer understands
+func IsServerConfigFile(name string) bool {
+	switch strings.ToLower(filepath.Base(name)) {
+	case ".htaccess", "web.config", ".user.ini", "php.ini", "nginx.conf":
+		return true
+	}
+	return strings.EqualFold(filepath.Ext(name), ".conf")
+}
+
+// ScanServerConfigs analyzes every server configuration file under root
+func ScanServerConfigs(root string) ([]*Finding, error) {
+	var findings []*Finding
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !IsServerConfigFile(path) {
+			return nil
+		}
+		fileFindings, err := AnalyzeServerConfigFile(path)
+		if err != nil {
+			return nil //nolint:nilerr // unreadable files are skipped
+		}
+		findings = append(findings, fileFindings...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning server config files: %w", err)
+	}
+	SortFindings(findings)
+	return findings, nil
+}
+
+// AnalyzeServerConfigFile analyzes a single configuration file
+func AnalyzeServerConfigFile(path string) ([]*Finding, error) {
+	file, err := os.Open(path) // #nosec G304 -- path is a config file found during a scan
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	findings, err := AnalyzeServerConfig(path, file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return findings, nil
+}
+
+// AnalyzeServerConfig analyzes configuration read from r. The file name
+// selects the syntax: web.config is IIS, .user.ini/php.ini is PHP, *.conf
+// is nginx and anything else is Apache.
+func AnalyzeServerConfig(name string, r io.Reader) ([]*Finding, error) {
+	lines, err := readConfigLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &configAnalyzer{name: name}
+	base := strings.ToLower(filepath.Base(name))
+	switch {
+	case base == "web.config":
+		a.analyzeIIS(lines)
+	case base == ".user.ini" || base == "php.ini":
+		a.analyzePHPIni(lines)
+	case base == "nginx.conf" || strings.HasSuffix(base, ".conf"):
+		a.analyzeNginx(lines)
+	default:
+		a.analyzeApache(lines)
+	}
+	a.checkHidden(lines)
+	return a.findings, nil
+}
+
+// configLine is a logical configuration line
+type configLine struct {
+	number int
+	raw    string
+	text   string
+}
+
+// readConfigLines reads lines, joining Apache-style continuations
+func readConfigLines(r io.Reader) ([]configLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var lines []configLine
+	var pending *configLine
+	number := 0
+	for scanner.Scan() {
+		number++
+		raw := scanner.Text()
+		if pending != nil {
+			pending.raw += "\n" + raw
+			pending.text += " " + strings.TrimSpace(raw)
+		} else {
+			pending = &configLine{number: number, raw: raw, text: strings.TrimSpace(raw)}
+		}
+		if strings.HasSuffix(pending.text, `\`) {
+			pending.text = strings.TrimSuffix(pending.text, `\`)
+			continue
+		}
+		lines = append(lines, *pending)
+		pending = nil
+	}
+	if pending != nil {
+		lines = append(lines, *pending)
+	}
+	return lines, scanner.Err()
+}
+
+// configAnalyzer accumulates findings for one file
+type configAnalyzer struct {
+	name     string
+	findings []*Finding
+}
+
+// add records a finding for the given line
+func (a *configAnalyzer) add(severity Severity, line int, message string) {
+	a.findings = append(a.findings, &Finding{
+		Check:    CheckServerConfig,
+		Severity: severity,
+		Subject:  fmt.Sprintf("%s:%d", a.name, line),
+		Message:  message,
+	})
+}
+
+// analyzeApache checks .htaccess and Apache configuration
+func (a *configAnalyzer) analyzeApache(lines []configLine) {
+	var conditions []string
+	for _, line := range lines {
+		if line.text == "" || strings.HasPrefix(line.text, "#") {
+			continue
+		}
+		fields := strings.Fields(line.text)
+		directive := strings.ToLower(fields[0])
+
+		switch directive {
+		case "rewritecond":
+			conditions = append(conditions, line.text)
+		case "rewriterule":
+			if len(fields) >= 3 {
+				a.checkRedirect(line.number, fields[2], conditions, line.text)
+			}
+			conditions = nil
+		case "redirect", "redirectmatch", "redirectpermanent", "redirecttemp":
+			if len(fields) >= 3 {
+				a.checkRedirect(line.number, fields[len(fields)-1], nil, line.text)
+			}
+		case "errordocument":
+			if len(fields) >= 3 && externalURLPattern.MatchString(fields[2]) {
+				a.add(SeverityMedium, line.number, "ErrorDocument sends visitors to an external site: "+summarizeDirective(line.text))
+			}
+		case "php_value", "php_admin_value", "php_flag":
+			a.checkPrepend(line.number, line.text)
+		case "addtype", "addhandler", "sethandler", "forcetype":
+			if nonPHPHandlerPattern.MatchString(line.text) {
+				a.add(SeverityHigh, line.number, "non-PHP file extensions are executed as PHP: "+summarizeDirective(line.text))
+			}
+		}
+
+		if directive == "sethandler" || directive == "forcetype" {
+			if strings.Contains(strings.ToLower(line.text), "php") && isUploadsPath(a.name) {
+				a.add(SeverityHigh, line.number, "PHP execution enabled in the uploads directory: "+summarizeDirective(line.text))
+			}
+		}
+	}
+}
+
+// nginxBlock is an open nginx block
+type nginxBlock struct {
+	condition     string
+	imageLocation bool
+}
+
+// imageLocationPattern matches nginx locations for static file extensions
+var imageLocationPattern = regexp.MustCompile(`(?i)\.\(?[a-z|]*(?:jpe?g|png|gif|ico|txt|svg|bmp|webp|css|js)\b`)
+
+// analyzeNginx checks nginx configuration and includes
+func (a *configAnalyzer) analyzeNginx(lines []configLine) {
+	var blocks []nginxBlock
+	for _, line := range lines {
+		text := line.text
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(text, ";"))
+		directive := strings.ToLower(fields[0])
+
+		conditions := make([]string, 0, len(blocks))
+		inImageLocation := false
+		for _, b := range blocks {
+			if b.condition != "" {
+				conditions = append(conditions, b.condition)
+			}
+			inImageLocation = inImageLocation || b.imageLocation
+		}
+
+		switch directive {
+		case "return", "rewrite":
+			if len(fields) >= 3 {
+				a.checkRedirect(line.number, strings.Trim(fields[2], `"'`), conditions, text)
+			}
+		case "fastcgi_param":
+			a.checkPrepend(line.number, text)
+		case "fastcgi_pass":
+			if inImageLocation {
+				a.add(SeverityHigh, line.number, "static file extensions are passed to PHP: "+summarizeDirective(text))
+			}
+		}
+
+		opens := strings.Count(text, "{")
+		for i := 0; i < opens; i++ {
+			b := nginxBlock{}
+			switch directive {
+			case "if":
+				b.condition = text
+			case "location":
+				b.imageLocation = imageLocationPattern.MatchString(text)
+			}
+			blocks = append(blocks, b)
+		}
+		for i := strings.Count(text, "}"); i > 0 && len(blocks) > 0; i-- {
+			blocks = blocks[:len(blocks)-1]
+		}
+	}
+}
+
+// iisRulePattern matches a rewrite rule element in web.config
+var iisRulePattern = regexp.MustCompile(`(?is)<rule\b.*?</rule>`)
+
+// iisActionURLPattern extracts a rewrite action's type and target
+var iisActionURLPattern = regexp.MustCompile(`(?is)<action\b[^>]*\btype\s*=\s*"(Redirect|Rewrite)"[^>]*\burl\s*=\s*"([^"]*)"|<action\b[^>]*\burl\s*=\s*"([^"]*)"[^>]*\btype\s*=\s*"(Redirect|Rewrite)"`)
+
+// iisConditionPattern extracts rule conditions
+var iisConditionPattern = regexp.MustCompile(`(?is)<add\b[^>]*\binput\s*=\s*"[^"]*"[^>]*>`)
+
+// analyzeIIS checks IIS web.config rewrite rules and handlers
+func (a *configAnalyzer) analyzeIIS(lines []configLine) {
+	var sb strings.Builder
+	offsets := make([]int, 0, len(lines))
+	for _, line := range lines {
+		offsets = append(offsets, sb.Len())
+		sb.WriteString(line.raw)
+		sb.WriteByte('\n')
+	}
+	content := sb.String()
+	lineAt := func(offset int) int {
+		n := 1
+		for i, start := range offsets {
+			if start > offset {
+				break
+			}
+			n = lines[i].number
+		}
+		return n
+	}
+
+	for _, loc := range iisRulePattern.FindAllStringIndex(content, -1) {
+		rule := content[loc[0]:loc[1]]
+		m := iisActionURLPattern.FindStringSubmatch(rule)
+		if m == nil {
+			continue
+		}
+		target := m[2]
+		if target == "" {
+			target = m[3]
+		}
+		conditions := iisConditionPattern.FindAllString(rule, -1)
+		a.checkRedirect(lineAt(loc[0]), target, conditions, strings.Join(strings.Fields(rule), " "))
+	}
+
+	for _, line := range lines {
+		lower := strings.ToLower(line.text)
+		if strings.Contains(lower, "<add") && strings.Contains(lower, "path=") && nonPHPHandlerPattern.MatchString(line.text) {
+			a.add(SeverityHigh, line.number, "non-PHP file extensions are executed as PHP: "+summarizeDirective(line.text))
+		}
+	}
+}
+
+// analyzePHPIni checks .user.ini and php.ini settings
+func (a *configAnalyzer) analyzePHPIni(lines []configLine) {
+	for _, line := range lines {
+		if line.text == "" || strings.HasPrefix(line.text, ";") || strings.HasPrefix(line.text, "#") {
+			continue
+		}
+		a.checkPrepend(line.number, line.text)
+	}
+}
+
+// checkPrepend reports auto_prepend_file/auto_append_file settings, which
+// run a PHP file before or after every request
+func (a *configAnalyzer) checkPrepend(line int, text string) {
+	m := prependPattern.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	setting, value := strings.ToLower(m[1]), m[2]
+	if value == "" || strings.EqualFold(value, "none") {
+		return
+	}
+
+	// The Wordfence web application firewall loads itself this way
+	if strings.EqualFold(filepath.Base(value), "wordfence-waf.php") {
+		a.add(SeverityInfo, line, fmt.Sprintf("%s loads the Wordfence firewall (%s)", setting, value))
+		return
+	}
+	a.add(SeverityCritical, line, fmt.Sprintf("%s runs %s on every request", setting, value))
+}
+
+// checkRedirect reports rules that send visitors to an external site,
+// escalating when the rule only applies to crawlers, search referrals or
+// mobile visitors, which is how SEO spam and redirect malware hides
+func (a *configAnalyzer) checkRedirect(line int, target string, conditions []string, text string) {
+	m := externalURLPattern.FindStringSubmatch(target)
+	if m == nil {
+		return
+	}
+	host := strings.ToLower(m[1])
+	directive := summarizeDirective(text)
+
+	// Only conditions on the visitor decide who is redirected
+	var targeted []string
+	for _, condition := range conditions {
+		if targetingPattern.MatchString(condition) {
+			targeted = append(targeted, condition)
+		}
+	}
+	joined := strings.Join(targeted, " ")
+
+	switch {
+	case searchEnginePattern.MatchString(joined):
+		a.add(SeverityCritical, line, fmt.Sprintf("redirect to %s only for search engine crawlers or referrals (cloaking): %s", host, directive))
+	case mobilePattern.MatchString(joined):
+		a.add(SeverityHigh, line, fmt.Sprintf("redirect to %s only for mobile visitors: %s", host, directive))
+	case len(targeted) > 0:
+		a.add(SeverityMedium, line, fmt.Sprintf("conditional redirect to %s based on user agent or referrer: %s", host, directive))
+	default:
+		a.add(SeverityInfo, line, fmt.Sprintf("redirect to %s: %s", host, directive))
+	}
+}
+
+// checkHidden reports directives pushed off-screen with whitespace
+func (a *configAnalyzer) checkHidden(lines []configLine) {
+	for _, line := range lines {
+		indent := len(line.raw) - len(strings.TrimLeft(line.raw, " \t"))
+		if indent >= hiddenDirectiveIndent && line.text != "" {
+			a.add(SeverityHigh, line.number, fmt.Sprintf("directive hidden after %d whitespace characters: %s", indent, summarizeDirective(line.text)))
+		}
+	}
+}
+
+// isUploadsPath reports whether path is inside a WordPress uploads directory
+func isUploadsPath(path string) bool {
+	return strings.Contains(filepath.ToSlash(path), "/uploads/")
+}
+
+// summarizeDirective shortens a directive for display
+func summarizeDirective(text string) string {
+	const maxLen = 200
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxLen {
+		return text[:maxLen] + "..."
+	}
+	return text
+}
@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// findingFor returns the first finding whose message contains substr
+func findingFor(findings []*Finding, substr string) *Finding {
+	for _, f := range findings {
+		if strings.Contains(f.Message, substr) {
+			return f
+		}
+	}
+	return nil
+}
+
+func TestAnalyzeServerConfigApache(t *testing.T) {
+	htaccess := `# BEGIN WordPress
+RewriteEngine On
+RewriteCond %{HTTPS} off
+RewriteRule ^(.*)$ https://%{HTTP_HOST}/$1 [R=301,L]
+RewriteCond %{HTTP_REFERER} (google|bing|yahoo) [NC]
+RewriteCond %{REQUEST_URI} !robots\.txt
+RewriteRule ^(.*)$ http://spam.example/in.php [R=302,L]
+RewriteCond %{HTTP_USER_AGENT} (android|iphone) [NC]
+RewriteRule .* https://mobile.example/ [R,L]
+Redirect 301 /old https://partner.example/new
+php_value auto_prepend_file /tmp/.cache.php
+AddHandler application/x-httpd-php .jpg
+` + strings.Repeat(" ", 120) + `ErrorDocument 404 http://evil.example/404
+# END WordPress
+`
+
+	findings, err := AnalyzeServerConfig("/site/.htaccess", strings.NewReader(htaccess))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		message  string
+		severity Severity
+		line     string
+	}{
+		{"spam.example only for search engine", SeverityCritical, ":7"},
+		{"mobile.example only for mobile", SeverityHigh, ":9"},
+		{"redirect to partner.example", SeverityInfo, ":10"},
+		{"auto_prepend_file runs /tmp/.cache.php", SeverityCritical, ":11"},
+		{"executed as PHP", SeverityHigh, ":12"},
+		{"hidden after 120", SeverityHigh, ":13"},
+		{"ErrorDocument", SeverityMedium, ":13"},
+	}
+	for _, tt := range tests {
+		f := findingFor(findings, tt.message)
+		if f == nil {
+			t.Errorf("expected finding containing %q", tt.message)
+			continue
+		}
+		if f.Severity != tt.severity {
+			t.Errorf("%q: expected severity %s, got %s", tt.message, tt.severity, f.Severity)
+		}
+		if !strings.HasSuffix(f.Subject, tt.line) || f.Check != CheckServerConfig {
+			t.Errorf("%q: unexpected subject %q", tt.message, f.Subject)
+		}
+	}
+
+	if f := findingFor(findings, "HTTP_HOST"); f != nil {
+		t.Errorf("unexpected finding for same-host redirect: %+v", f)
+	}
+}
+
+func TestAnalyzeServerConfigContinuation(t *testing.T) {
+	findings, err := AnalyzeServerConfig(".htaccess", strings.NewReader("RewriteCond %{HTTP_USER_AGENT} \\\n  googlebot\nRewriteRule ^ http://cloak.example/ [R,L]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f := findingFor(findings, "cloak.example"); f == nil || f.Severity != SeverityCritical {
+		t.Errorf("expected critical cloaking finding, got %+v", findings)
+	}
+}
+
+func TestAnalyzeServerConfigNginx(t *testing.T) {
+	conf := `server {
+    if ($http_user_agent ~* "googlebot") {
+        return 302 https://seo-spam.example/;
+    }
+    return 301 https://$host$request_uri;
+    location ~* \.(jpg|png)$ {
+        fastcgi_pass unix:/run/php-fpm.sock;
+    }
+    location ~ \.php$ {
+        fastcgi_pass unix:/run/php-fpm.sock;
+        fastcgi_param PHP_VALUE "auto_prepend_file=/var/www/x.php";
+    }
+}
+`
+	findings, err := AnalyzeServerConfig("/etc/nginx/sites-enabled/site.conf", strings.NewReader(conf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if f := findingFor(findings, "seo-spam.example"); f == nil || f.Severity != SeverityCritical {
+		t.Errorf("expected critical cloaking finding, got %+v", f)
+	}
+	if f := findingFor(findings, "static file extensions"); f == nil || !strings.HasSuffix(f.Subject, ":7") {
+		t.Errorf("expected static files passed to PHP finding on line 7, got %+v", f)
+	}
+	if f := findingFor(findings, "auto_prepend_file runs /var/www/x.php"); f == nil || f.Severity != SeverityCritical {
+		t.Errorf("expected critical prepend finding, got %+v", f)
+	}
+	if len(findings) != 3 {
+		t.Errorf("expected 3 findings, got %d: %+v", len(findings), findings)
+	}
+}
+
+func TestAnalyzeServerConfigIIS(t *testing.T) {
+	webConfig := `<configuration>
+  <system.webServer>
+    <rewrite>
+      <rules>
+        <rule name="r1" stopProcessing="true">
+          <match url=".*" />
+          <conditions>
+            <add input="{HTTP_REFERER}" pattern="google" />
+          </conditions>
+          <action type="Redirect" url="http://iis-spam.example/" />
+        </rule>
+      </rules>
+    </rewrite>
+  </system.webServer>
+</configuration>
+`
+	findings, err := AnalyzeServerConfig("web.config", strings.NewReader(webConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f := findingFor(findings, "iis-spam.example")
+	if f == nil || f.Severity != SeverityCritical {
+		t.Fatalf("expected critical cloaking finding, got %+v", findings)
+	}
+	if !strings.HasSuffix(f.Subject, ":5") {
+		t.Errorf("expected finding on the rule's line, got %q", f.Subject)
+	}
+}
+
+func TestAnalyzeServerConfigUserIni(t *testing.T) {
+	ini := "; Wordfence WAF\nauto_prepend_file = '/var/www/wordfence-waf.php'\nauto_append_file = /tmp/sess_x\n"
+	findings, err := AnalyzeServerConfig(".user.ini", strings.NewReader(ini))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f := findingFor(findings, "Wordfence firewall"); f == nil || f.Severity != SeverityInfo {
+		t.Errorf("expected info finding for the Wordfence firewall, got %+v", f)
+	}
+	if f := findingFor(findings, "auto_append_file runs /tmp/sess_x"); f == nil || f.Severity != SeverityCritical {
+		t.Errorf("expected critical append finding, got %+v", f)
+	}
+}
+
+func TestScanServerConfigs(t *testing.T) {
+	root := t.TempDir()
+	uploads := filepath.Join(root, "wp-content", "uploads")
+	if err := os.MkdirAll(uploads, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(uploads, ".htaccess"), []byte("SetHandler application/x-httpd-php\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "index.php"), []byte("auto_prepend_file=/tmp/x.php"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := ScanServerConfigs(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 1 || !strings.Contains(findings[0].Message, "uploads directory") {
+		t.Errorf("expected one uploads finding, got %+v", findings)
+	}
+}
@@ -0,0 +1,227 @@
+// Package audit provides timestomping detection
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// CheckTimestamps is the check name reported for suspicious timestamps
+const CheckTimestamps = "timestamps"
+
+// DefaultTimestampSlack is how far timestamps may drift before they are
+// considered inconsistent
+const DefaultTimestampSlack = 24 * time.Hour
+
+// minTimestampPeers is how many files a directory or plugin needs before
+// its timestamps are used as a baseline
+const minTimestampPeers = 3
+
+// ReleaseDateFunc returns when a plugin version was released, if known
+type ReleaseDateFunc func(ctx context.Context, slug, version string) (time.Time, bool)
+
+// TimestampChecker finds files whose timestamps were back-dated to blend in
+// with the files around them
+type TimestampChecker struct {
+	sitePath    string
+	plugins     []*wordpress.Plugin
+	releaseDate ReleaseDateFunc
+	slack       time.Duration
+}
+
+// TimestampOption configures a TimestampChecker
+type TimestampOption func(*TimestampChecker)
+
+// WithReleaseDates sets the source of plugin release dates
+func WithReleaseDates(fn ReleaseDateFunc) TimestampOption {
+	return func(c *TimestampChecker) {
+		c.releaseDate = fn
+	}
+}
+
+// WithTimestampSlack sets the tolerance for timestamp comparisons
+func WithTimestampSlack(slack time.Duration) TimestampOption {
+	return func(c *TimestampChecker) {
+		c.slack = slack
+	}
+}
+
+// NewTimestampChecker creates a checker for the site at sitePath
+func NewTimestampChecker(sitePath string, plugins []*wordpress.Plugin, opts ...TimestampOption) *TimestampChecker {
+	c := &TimestampChecker{
+		sitePath: sitePath,
+		plugins:  plugins,
+		slack:    DefaultTimestampSlack,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// fileTimes holds the timestamps of one file
+type fileTimes struct {
+	path     string
+	modTime  time.Time
+	ctime    time.Time
+	hasCtime bool
+}
+
+// Run checks every executable file in the site
+func (c *TimestampChecker) Run(ctx context.Context) ([]*Finding, error) {
+	byDir := make(map[string][]*fileTimes)
+	err := filepath.WalkDir(c.sitePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == c.sitePath {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && path != c.sitePath {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !isExecutableFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr // unreadable files are skipped
+		}
+		ft := &fileTimes{path: path, modTime: info.ModTime()}
+		ft.ctime, ft.hasCtime = changeTime(info)
+		dir := filepath.Dir(path)
+		byDir[dir] = append(byDir[dir], ft)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", c.sitePath, err)
+	}
+
+	reasons := make(map[string][]string)
+	for _, files := range byDir {
+		c.checkChangeTimes(files, reasons)
+	}
+	c.checkReleaseDates(ctx, byDir, reasons)
+
+	findings := make([]*Finding, 0, len(reasons))
+	for path, why := range reasons {
+		severity := SeverityMedium
+		if len(why) > 1 {
+			severity = SeverityHigh
+		}
+		findings = append(findings, &Finding{
+			Check:    CheckTimestamps,
+			Severity: severity,
+			Subject:  path,
+			Message:  "suspicious timestamp: " + strings.Join(why, "; "),
+		})
+	}
+	SortFindings(findings)
+	return findings, nil
+}
+
+// checkChangeTimes flags files whose modification time predates their
+// change time while their change time is newer than their neighbours',
+// which is what back-dating a recently written file with touch looks like
+func (c *TimestampChecker) checkChangeTimes(files []*fileTimes, reasons map[string][]string) {
+	var ctimes []time.Time
+	for _, f := range files {
+		if f.hasCtime {
+			ctimes = append(ctimes, f.ctime)
+		}
+	}
+	if len(ctimes) < minTimestampPeers {
+		return
+	}
+	baseline := medianTime(ctimes)
+
+	for _, f := range files {
+		if !f.hasCtime {
+			continue
+		}
+		if f.modTime.Add(c.slack).Before(f.ctime) && f.ctime.After(baseline.Add(c.slack)) {
+			reasons[f.path] = append(reasons[f.path], fmt.Sprintf(
+				"modified %s but its inode changed %s, later than the rest of its directory (%s)",
+				formatTime(f.modTime), formatTime(f.ctime), formatTime(baseline)))
+		}
+	}
+}
+
+// checkReleaseDates flags plugin files dated before their plugin version
+// was released while the rest of the plugin is not
+func (c *TimestampChecker) checkReleaseDates(ctx context.Context, byDir map[string][]*fileTimes, reasons map[string][]string) {
+	if c.releaseDate == nil {
+		return
+	}
+
+	for _, plugin := range c.plugins {
+		if plugin.Path == "" || plugin.Version == "" {
+			continue
+		}
+		released, ok := c.releaseDate(ctx, plugin.Slug, plugin.Version)
+		if !ok {
+			continue
+		}
+
+		var files []*fileTimes
+		prefix := plugin.Path + string(filepath.Separator)
+		for dir, dirFiles := range byDir {
+			if dir == plugin.Path || strings.HasPrefix(dir, prefix) {
+				files = append(files, dirFiles...)
+			}
+		}
+		if len(files) < minTimestampPeers {
+			continue
+		}
+
+		mtimes := make([]time.Time, len(files))
+		for i, f := range files {
+			mtimes[i] = f.modTime
+		}
+		baseline := medianTime(mtimes)
+		cutoff := released.Add(-c.slack)
+
+		for _, f := range files {
+			if f.modTime.Before(cutoff) && f.modTime.Before(baseline.Add(-c.slack)) {
+				reasons[f.path] = append(reasons[f.path], fmt.Sprintf(
+					"modified %s, before %s %s was released (%s)",
+					formatTime(f.modTime), plugin.Slug, plugin.Version, formatTime(released)))
+			}
+		}
+	}
+}
+
+// isExecutableFile reports whether name is a file type attackers plant
+func isExecutableFile(name string) bool {
+	lower := strings.ToLower(name)
+	if lower == ".htaccess" || lower == ".user.ini" {
+		return true
+	}
+	switch filepath.Ext(lower) {
+	case ".php", ".php3", ".php4", ".php5", ".php7", ".phtml", ".phar", ".inc":
+		return true
+	}
+	return false
+}
+
+// medianTime returns the median of times
+func medianTime(times []time.Time) time.Time {
+	sorted := append([]time.Time(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	return sorted[len(sorted)/2]
+}
+
+// formatTime formats a timestamp for findings
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.DateTime)
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+func TestCheckChangeTimes(t *testing.T) {
+	installed := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	files := []*fileTimes{
+		{path: "a.php", modTime: installed, ctime: installed, hasCtime: true},
+		{path: "b.php", modTime: installed, ctime: installed, hasCtime: true},
+		{path: "c.php", modTime: installed, ctime: installed.Add(time.Hour), hasCtime: true},
+		// Written months later, then back-dated to match its neighbours
+		{path: "shell.php", modTime: installed, ctime: installed.AddDate(0, 6, 0), hasCtime: true},
+		// Legitimately edited: mtime and ctime agree
+		{path: "edited.php", modTime: installed.AddDate(0, 3, 0), ctime: installed.AddDate(0, 3, 0), hasCtime: true},
+	}
+
+	c := NewTimestampChecker("", nil)
+	reasons := make(map[string][]string)
+	c.checkChangeTimes(files, reasons)
+
+	if len(reasons) != 1 || reasons["shell.php"] == nil {
+		t.Errorf("expected only shell.php flagged, got %v", reasons)
+	}
+}
+
+func TestCheckChangeTimesNeedsPeers(t *testing.T) {
+	now := time.Now()
+	files := []*fileTimes{
+		{path: "a.php", modTime: now.AddDate(-1, 0, 0), ctime: now, hasCtime: true},
+		{path: "b.php", modTime: now, ctime: now.AddDate(-1, 0, 0), hasCtime: true},
+	}
+	reasons := make(map[string][]string)
+	NewTimestampChecker("", nil).checkChangeTimes(files, reasons)
+	if len(reasons) != 0 {
+		t.Errorf("expected no findings without enough peers, got %v", reasons)
+	}
+}
+
+func TestTimestampCheckerReleaseDates(t *testing.T) {
+	site := t.TempDir()
+	pluginDir := filepath.Join(site, "wp-content", "plugins", "shop")
+	if err := os.MkdirAll(filepath.Join(pluginDir, "inc"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	released := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	installed := released.AddDate(0, 0, 5)
+	files := map[string]time.Time{
+		"shop.php":        installed,
+		"inc/cart.php":    installed,
+		"inc/checkout.js": installed,
+		"inc/order.php":   installed,
+		"inc/cache.php":   time.Date(2016, 5, 4, 0, 0, 0, 0, time.UTC),
+	}
+	for name, mtime := range files {
+		path := filepath.Join(pluginDir, filepath.FromSlash(name))
+		if err := os.WriteFile(path, []byte("<?php"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plugins := []*wordpress.Plugin{{Extension: wordpress.Extension{Slug: "shop", Version: "2.0", Path: pluginDir}}}
+	releaseDates := func(_ context.Context, slug, version string) (time.Time, bool) {
+		return released, slug == "shop" && version == "2.0"
+	}
+
+	findings, err := NewTimestampChecker(site, plugins, WithReleaseDates(releaseDates)).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d: %+v", len(findings), findings)
+	}
+	f := findings[0]
+	if !strings.HasSuffix(f.Subject, "cache.php") || f.Check != CheckTimestamps {
+		t.Errorf("unexpected finding: %+v", f)
+	}
+	if !strings.Contains(f.Message, "suspicious timestamp") || !strings.Contains(f.Message, "shop 2.0") {
+		t.Errorf("unexpected message: %q", f.Message)
+	}
+}
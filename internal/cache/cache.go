@@ -2,9 +2,12 @@
 package cache
 
 import (
+	"container/list"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -18,17 +21,19 @@ var (
 	ErrCacheDisabled      = errors.New("cache is disabled")
 )
 
-// Cache is the interface for cache implementations
+// Cache is the interface for cache implementations. Operations that touch
+// storage take a context so callers are not held up by slow disks once
+// the context is done.
 type Cache interface {
 	// Get retrieves a value from the cache
 	// Returns ErrNoCachedValue if the key doesn't exist or has expired
-	Get(key string, maxAge time.Duration) ([]byte, error)
+	Get(ctx context.Context, key string, maxAge time.Duration) ([]byte, error)
 
 	// Put stores a value in the cache
-	Put(key string, value []byte) error
+	Put(ctx context.Context, key string, value []byte) error
 
 	// Remove removes a value from the cache
-	Remove(key string) error
+	Remove(ctx context.Context, key string) error
 
 	// Purge clears all cached values
 	Purge() error
@@ -37,6 +42,26 @@ type Cache interface {
 	Exists(key string, maxAge time.Duration) bool
 }
 
+// Store is a cache without context support. FileCache, MemoryCache and
+// NoOpCache are stores; WithContext adapts a store to Cache.
+type Store interface {
+	// Get retrieves a value from the store
+	// Returns ErrNoCachedValue if the key doesn't exist or has expired
+	Get(key string, maxAge time.Duration) ([]byte, error)
+
+	// Put stores a value in the store
+	Put(key string, value []byte) error
+
+	// Remove removes a value from the store
+	Remove(key string) error
+
+	// Purge clears all stored values
+	Purge() error
+
+	// Exists checks if a key exists and is not expired
+	Exists(key string, maxAge time.Duration) bool
+}
+
 // Entry represents a cached item with metadata
 type Entry struct {
 	Key       string
@@ -99,60 +124,222 @@ func (c *NoOpCache) Exists(_ string, _ time.Duration) bool {
 	return false
 }
 
-// MemoryCache is an in-memory cache implementation
+// MemoryCache is a goroutine-safe in-memory cache. Entries can expire after
+// a TTL, the least recently used entries are evicted beyond a maximum
+// count, and a backing cache can be layered behind it as a read-through,
+// write-through store.
 type MemoryCache struct {
-	items map[string]*Entry
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List // front is most recently used
+	ttl        time.Duration
+	maxEntries int
+	backing    Store
+	now        func() time.Time
+}
+
+// memoryEntry is a cached value and when it expires (zero = never)
+type memoryEntry struct {
+	Entry
+	expiresAt time.Time
+}
+
+// MemoryCacheOption configures a MemoryCache
+type MemoryCacheOption func(*MemoryCache)
+
+// WithTTL sets how long entries live unless put with their own TTL
+func WithTTL(ttl time.Duration) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxEntries caps the number of entries, evicting the least recently
+// used first (0 = unbounded)
+func WithMaxEntries(n int) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.maxEntries = n
+	}
+}
+
+// WithReadThrough layers the memory cache in front of backing: misses are
+// loaded from backing and kept in memory, and writes go to both
+func WithReadThrough(backing Store) MemoryCacheOption {
+	return func(c *MemoryCache) {
+		c.backing = backing
+	}
+}
+
+// createdAter is implemented by caches that know when an entry was stored
+type createdAter interface {
+	CreatedAt(key string) (time.Time, error)
+}
+
+// StoredAt returns when c stored the value for key, or false if it holds
+// none or cannot tell
+func StoredAt(c Cache, key string) (time.Time, bool) {
+	ca, ok := c.(createdAter)
+	if !ok {
+		return time.Time{}, false
+	}
+	created, err := ca.CreatedAt(key)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created, true
 }
 
 // NewMemoryCache creates a new in-memory cache
-func NewMemoryCache() *MemoryCache {
-	return &MemoryCache{
-		items: make(map[string]*Entry),
+func NewMemoryCache(opts ...MemoryCacheOption) *MemoryCache {
+	c := &MemoryCache{
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
-// Get retrieves a value from the memory cache
+// Get retrieves a value from the memory cache, falling back to the backing
+// cache if there is one
 func (c *MemoryCache) Get(key string, maxAge time.Duration) ([]byte, error) {
-	entry, ok := c.items[key]
-	if !ok {
-		return nil, ErrNoCachedValue
+	c.mu.Lock()
+	if entry := c.lookup(key, maxAge); entry != nil {
+		c.mu.Unlock()
+		return entry.Data, nil
 	}
+	c.mu.Unlock()
 
-	if entry.IsExpired(maxAge) {
-		delete(c.items, key)
+	if c.backing == nil {
 		return nil, ErrNoCachedValue
 	}
+	data, err := c.backing.Get(key, maxAge)
+	if err != nil {
+		return nil, err
+	}
 
-	return entry.Data, nil
+	// Keep the backing entry's age so maxAge still applies in memory
+	created := c.now()
+	if ca, ok := c.backing.(createdAter); ok {
+		if t, err := ca.CreatedAt(key); err == nil {
+			created = t
+		}
+	}
+	c.mu.Lock()
+	c.store(key, data, created, c.ttl)
+	c.mu.Unlock()
+	return data, nil
 }
 
-// Put stores a value in the memory cache
+// Put stores a value in the memory cache and any backing cache
 func (c *MemoryCache) Put(key string, value []byte) error {
-	c.items[key] = &Entry{
-		Key:       key,
-		Data:      value,
-		CreatedAt: time.Now(),
+	return c.PutWithTTL(key, value, c.ttl)
+}
+
+// PutWithTTL stores a value that expires from memory after ttl (0 = never)
+func (c *MemoryCache) PutWithTTL(key string, value []byte, ttl time.Duration) error {
+	if c.backing != nil {
+		if err := c.backing.Put(key, value); err != nil {
+			return err
+		}
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(key, value, c.now(), ttl)
 	return nil
 }
 
-// Remove removes a value from the memory cache
+// Remove removes a value from the memory cache and any backing cache
 func (c *MemoryCache) Remove(key string) error {
-	delete(c.items, key)
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+	c.mu.Unlock()
+
+	if c.backing != nil {
+		return c.backing.Remove(key)
+	}
 	return nil
 }
 
-// Purge clears all values from the memory cache
+// Purge clears all values from the memory cache and any backing cache
 func (c *MemoryCache) Purge() error {
-	c.items = make(map[string]*Entry)
+	c.mu.Lock()
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.mu.Unlock()
+
+	if c.backing != nil {
+		return c.backing.Purge()
+	}
 	return nil
 }
 
-// Exists checks if a key exists in the memory cache
+// Exists checks if a key exists in the memory cache or any backing cache
 func (c *MemoryCache) Exists(key string, maxAge time.Duration) bool {
-	entry, ok := c.items[key]
+	c.mu.Lock()
+	found := c.lookup(key, maxAge) != nil
+	c.mu.Unlock()
+
+	if !found && c.backing != nil {
+		return c.backing.Exists(key, maxAge)
+	}
+	return found
+}
+
+// Len returns the number of entries held in memory
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// lookup returns a live entry, marking it recently used and dropping it if
+// expired. c.mu must be held.
+func (c *MemoryCache) lookup(key string, maxAge time.Duration) *memoryEntry {
+	elem, ok := c.items[key]
 	if !ok {
-		return false
+		return nil
 	}
-	return !entry.IsExpired(maxAge)
+
+	entry := elem.Value.(*memoryEntry)
+	now := c.now()
+	if (!entry.expiresAt.IsZero() && !now.Before(entry.expiresAt)) ||
+		(maxAge > 0 && now.Sub(entry.CreatedAt) > maxAge) {
+		c.removeElement(elem)
+		return nil
+	}
+
+	c.order.MoveToFront(elem)
+	return entry
+}
+
+// store adds or replaces an entry and evicts beyond the maximum count.
+// c.mu must be held.
+func (c *MemoryCache) store(key string, value []byte, created time.Time, ttl time.Duration) {
+	entry := &memoryEntry{Entry: Entry{Key: key, Data: value, CreatedAt: created}}
+	if ttl > 0 {
+		entry.expiresAt = c.now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+	} else {
+		c.items[key] = c.order.PushFront(entry)
+	}
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// removeElement drops an entry. c.mu must be held.
+func (c *MemoryCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*memoryEntry).Key)
 }
@@ -247,3 +247,116 @@ func TestFileCacheConcurrency(t *testing.T) {
 		<-done
 	}
 }
+
+func TestMemoryCacheTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewMemoryCache(WithTTL(time.Minute))
+	cache.now = func() time.Time { return now }
+
+	_ = cache.Put("default", []byte("a"))
+	_ = cache.PutWithTTL("short", []byte("b"), time.Second)
+	_ = cache.PutWithTTL("forever", []byte("c"), 0)
+
+	now = now.Add(2 * time.Second)
+	if _, err := cache.Get("short", 0); err != ErrNoCachedValue {
+		t.Errorf("expected short-lived entry to expire, got %v", err)
+	}
+	if !cache.Exists("default", 0) {
+		t.Error("expected default entry to still exist")
+	}
+	if cache.Exists("default", time.Second) {
+		t.Error("expected maxAge to apply on top of the TTL")
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := cache.Get("default", 0); err != ErrNoCachedValue {
+		t.Errorf("expected default entry to expire, got %v", err)
+	}
+	if data, err := cache.Get("forever", 0); err != nil || string(data) != "c" {
+		t.Errorf("expected entry without TTL to remain, got %q, %v", data, err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expected expired entries to be dropped, got %d", cache.Len())
+	}
+}
+
+func TestMemoryCacheLRUEviction(t *testing.T) {
+	cache := NewMemoryCache(WithMaxEntries(2))
+
+	_ = cache.Put("a", []byte("1"))
+	_ = cache.Put("b", []byte("2"))
+	// Touch a so b is the least recently used
+	_, _ = cache.Get("a", 0)
+	_ = cache.Put("c", []byte("3"))
+
+	if cache.Exists("b", 0) {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if !cache.Exists("a", 0) || !cache.Exists("c", 0) || cache.Len() != 2 {
+		t.Errorf("expected a and c to remain, len %d", cache.Len())
+	}
+}
+
+func TestMemoryCacheReadThrough(t *testing.T) {
+	backing, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if err := backing.Put("on-disk", []byte("disk")); err != nil {
+		t.Fatalf("failed to put data: %v", err)
+	}
+
+	cache := NewMemoryCache(WithReadThrough(backing))
+	data, err := cache.Get("on-disk", time.Hour)
+	if err != nil || string(data) != "disk" {
+		t.Fatalf("expected read-through from file cache, got %q, %v", data, err)
+	}
+	if cache.Len() != 1 {
+		t.Error("expected value to be kept in memory")
+	}
+
+	if err := cache.Put("new", []byte("both")); err != nil {
+		t.Fatalf("failed to put data: %v", err)
+	}
+	if data, err := backing.Get("new", 0); err != nil || string(data) != "both" {
+		t.Errorf("expected write-through to file cache, got %q, %v", data, err)
+	}
+
+	if err := cache.Remove("new"); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	if cache.Exists("new", 0) || backing.Exists("new", 0) {
+		t.Error("expected removal from both layers")
+	}
+
+	if _, err := cache.Get("missing", 0); err != ErrNoCachedValue {
+		t.Errorf("expected ErrNoCachedValue, got %v", err)
+	}
+}
+
+func TestMemoryCacheConcurrency(t *testing.T) {
+	cache := NewMemoryCache(WithMaxEntries(8), WithTTL(time.Minute))
+
+	done := make(chan bool)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			for j := 0; j < 100; j++ {
+				key := string(rune('a' + (i+j)%16))
+				_ = cache.Put(key, []byte("data"))
+				_, _ = cache.Get(key, time.Hour)
+				_ = cache.Exists(key, 0)
+				if j%25 == 0 {
+					_ = cache.Remove(key)
+				}
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+
+	if cache.Len() > 8 {
+		t.Errorf("expected at most 8 entries, got %d", cache.Len())
+	}
+}
@@ -0,0 +1,92 @@
+// Package cache provides the context adapter for blocking stores
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// contextStore adapts a Store to the Cache interface
+type contextStore struct {
+	store Store
+}
+
+// WithContext adapts a Store to the Cache interface. Get, Put and Remove
+// return as soon as ctx is done; an operation already running on the store
+// is left to finish in the background, which is safe because stores write
+// atomically.
+func WithContext(s Store) Cache {
+	return &contextStore{store: s}
+}
+
+// Get retrieves a value from the store unless ctx is done first
+func (c *contextStore) Get(ctx context.Context, key string, maxAge time.Duration) ([]byte, error) {
+	return runWithContext(ctx, func() ([]byte, error) {
+		return c.store.Get(key, maxAge)
+	})
+}
+
+// Put stores a value unless ctx is done first
+func (c *contextStore) Put(ctx context.Context, key string, value []byte) error {
+	_, err := runWithContext(ctx, func() (struct{}, error) {
+		return struct{}{}, c.store.Put(key, value)
+	})
+	return err
+}
+
+// Remove removes a value unless ctx is done first
+func (c *contextStore) Remove(ctx context.Context, key string) error {
+	_, err := runWithContext(ctx, func() (struct{}, error) {
+		return struct{}{}, c.store.Remove(key)
+	})
+	return err
+}
+
+// Purge clears the store
+func (c *contextStore) Purge() error {
+	return c.store.Purge()
+}
+
+// Exists checks if a key exists in the store
+func (c *contextStore) Exists(key string, maxAge time.Duration) bool {
+	return c.store.Exists(key, maxAge)
+}
+
+// CreatedAt returns when the store saved key, if it can tell
+func (c *contextStore) CreatedAt(key string) (time.Time, error) {
+	if ca, ok := c.store.(createdAter); ok {
+		return ca.CreatedAt(key)
+	}
+	return time.Time{}, ErrNoCachedValue
+}
+
+// runWithContext runs op, returning early with the context's error if ctx
+// is done before op completes
+func runWithContext[T any](ctx context.Context, op func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, fmt.Errorf("cache: %w", err)
+	}
+	if ctx.Done() == nil {
+		// Never cancelled, so there is nothing to wait on
+		return op()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := op()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, fmt.Errorf("cache: %w", ctx.Err())
+	}
+}
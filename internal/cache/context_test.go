@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingStore is a store whose operations wait until released
+type blockingStore struct {
+	NoOpCache
+	release chan struct{}
+}
+
+func (s *blockingStore) Get(_ string, _ time.Duration) ([]byte, error) {
+	<-s.release
+	return []byte("late"), nil
+}
+
+func (s *blockingStore) Put(_ string, _ []byte) error {
+	<-s.release
+	return nil
+}
+
+func TestWithContextPassesThrough(t *testing.T) {
+	store, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	c := WithContext(store)
+	ctx := context.Background()
+
+	if err := c.Put(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("failed to put data: %v", err)
+	}
+	if data, err := c.Get(ctx, "key", time.Hour); err != nil || string(data) != "value" {
+		t.Errorf("expected stored value, got %q, %v", data, err)
+	}
+	if !c.Exists("key", 0) {
+		t.Error("expected key to exist")
+	}
+	if err := c.Remove(ctx, "key"); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	if _, err := c.Get(ctx, "key", 0); !errors.Is(err, ErrNoCachedValue) {
+		t.Errorf("expected ErrNoCachedValue, got %v", err)
+	}
+}
+
+func TestWithContextCancellation(t *testing.T) {
+	store := &blockingStore{release: make(chan struct{})}
+	defer close(store.release)
+	c := WithContext(store)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(cancelled, "key", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled before starting, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.Put(ctx, "key", []byte("value")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected blocked operation to be abandoned promptly")
+	}
+}
@@ -98,6 +98,21 @@ func (c *FileCache) Get(key string, maxAge time.Duration) ([]byte, error) {
 	return data, nil
 }
 
+// CreatedAt returns when the value for key was stored
+func (c *FileCache) CreatedAt(key string) (time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	info, err := os.Stat(c.path(key))
+	if os.IsNotExist(err) {
+		return time.Time{}, ErrNoCachedValue
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to stat cache file: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
 // Put stores a value in the file cache
 func (c *FileCache) Put(key string, value []byte) error {
 	c.mu.Lock()
@@ -0,0 +1,265 @@
+// Package cache provides a two-tier cache of decoded values
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultHotEntries is the default number of decoded values a Tiered cache
+// keeps in memory
+const DefaultHotEntries = 16
+
+// Tiered is a two-tier cache: decoded values are kept in memory over the
+// raw bytes held by a backing cache, so large feeds are read and parsed
+// once per process rather than once per use. Concurrent loads of the same
+// key share a single read, fetch and decode.
+//
+// Tiered is itself a Cache of raw bytes; writes through it invalidate the
+// decoded value. Decoded values are shared between callers and must be
+// treated as read-only.
+type Tiered struct {
+	backing    Cache
+	mu         sync.Mutex
+	hot        map[string]*list.Element
+	order      *list.List // front is most recently used
+	maxEntries int
+	flight     Group
+	now        func() time.Time
+}
+
+// hotEntry is a decoded value and when its raw bytes were stored
+type hotEntry struct {
+	key       string
+	value     any
+	createdAt time.Time
+}
+
+// TieredOption configures a Tiered cache
+type TieredOption func(*Tiered)
+
+// WithHotEntries caps the number of decoded values held in memory,
+// evicting the least recently used first (0 = unbounded)
+func WithHotEntries(n int) TieredOption {
+	return func(t *Tiered) {
+		t.maxEntries = n
+	}
+}
+
+// NewTiered creates a two-tier cache over backing
+func NewTiered(backing Cache, opts ...TieredOption) *Tiered {
+	t := &Tiered{
+		backing:    backing,
+		hot:        make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: DefaultHotEntries,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Get retrieves raw bytes from the backing cache
+func (t *Tiered) Get(ctx context.Context, key string, maxAge time.Duration) ([]byte, error) {
+	return t.backing.Get(ctx, key, maxAge)
+}
+
+// Put stores raw bytes in the backing cache and drops the decoded value
+func (t *Tiered) Put(ctx context.Context, key string, value []byte) error {
+	t.forget(key)
+	return t.backing.Put(ctx, key, value)
+}
+
+// Remove removes a value from both tiers
+func (t *Tiered) Remove(ctx context.Context, key string) error {
+	t.forget(key)
+	return t.backing.Remove(ctx, key)
+}
+
+// Purge clears both tiers
+func (t *Tiered) Purge() error {
+	t.mu.Lock()
+	t.hot = make(map[string]*list.Element)
+	t.order.Init()
+	t.mu.Unlock()
+	return t.backing.Purge()
+}
+
+// Exists checks if a key exists in either tier
+func (t *Tiered) Exists(key string, maxAge time.Duration) bool {
+	t.mu.Lock()
+	_, found := t.lookup(key, maxAge)
+	t.mu.Unlock()
+	return found || t.backing.Exists(key, maxAge)
+}
+
+// CreatedAt returns when the backing cache stored key, if it can tell
+func (t *Tiered) CreatedAt(key string) (time.Time, error) {
+	if ca, ok := t.backing.(createdAter); ok {
+		return ca.CreatedAt(key)
+	}
+	return time.Time{}, ErrNoCachedValue
+}
+
+// Len returns the number of decoded values held in memory
+func (t *Tiered) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
+
+// Load returns the value cached under key, decoded with decode. With a
+// Tiered cache the decoded value is served from memory while younger than
+// maxAge, and concurrent loads of the same key are performed once. Other
+// caches are read and decoded on every call.
+//
+// When the raw bytes are missing, stale or undecodable, fetch is called
+// and its result stored; a nil fetch returns the cache error instead.
+// Failing to store a fetched value is not an error.
+func Load[T any](ctx context.Context, c Cache, key string, maxAge time.Duration, decode func([]byte) (T, error), fetch func(context.Context) ([]byte, error)) (T, error) {
+	t, ok := c.(*Tiered)
+	if !ok {
+		value, _, err := loadRaw(ctx, c, key, maxAge, decode, fetch)
+		return value, err
+	}
+
+	if value, ok := hotValue[T](t, key, maxAge); ok {
+		return value, nil
+	}
+
+	v, err := t.flight.Do(key, func() (any, error) {
+		// A load that finished between the check above and this one
+		// starting may have already filled the hot tier
+		if value, ok := hotValue[T](t, key, maxAge); ok {
+			return value, nil
+		}
+		value, created, err := loadRaw(ctx, t.backing, key, maxAge, decode, fetch)
+		if err != nil {
+			return nil, err
+		}
+		t.store(key, value, created)
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	value, ok := v.(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%s: %w", key, ErrInvalidCachedValue)
+	}
+	return value, nil
+}
+
+// loadRaw reads and decodes the raw bytes for key, fetching them when
+// needed, and returns when they were stored
+func loadRaw[T any](ctx context.Context, c Cache, key string, maxAge time.Duration, decode func([]byte) (T, error), fetch func(context.Context) ([]byte, error)) (T, time.Time, error) {
+	var zero T
+
+	data, err := c.Get(ctx, key, maxAge)
+	if err == nil {
+		value, decodeErr := decode(data)
+		if decodeErr == nil {
+			return value, storedAt(c, key), nil
+		}
+		err = fmt.Errorf("decoding cached %s: %w", key, decodeErr)
+	}
+	if fetch == nil {
+		return zero, time.Time{}, err
+	}
+
+	data, err = fetch(ctx)
+	if err != nil {
+		return zero, time.Time{}, err
+	}
+	value, err := decode(data)
+	if err != nil {
+		return zero, time.Time{}, fmt.Errorf("decoding %s: %w", key, err)
+	}
+	// Storing is best-effort; the fetched value is good either way
+	_ = c.Put(ctx, key, data)
+	return value, time.Now(), nil
+}
+
+// storedAt returns when c stored key, or now if c cannot tell
+func storedAt(c Cache, key string) time.Time {
+	if ca, ok := c.(createdAter); ok {
+		if t, err := ca.CreatedAt(key); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// hotValue returns the decoded value for key if it is held in memory, is
+// younger than maxAge and has type T
+func hotValue[T any](t *Tiered, key string, maxAge time.Duration) (T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v, ok := t.lookup(key, maxAge)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	value, ok := v.(T)
+	return value, ok
+}
+
+// lookup returns a live decoded value, marking it recently used and
+// dropping it if older than maxAge. t.mu must be held.
+func (t *Tiered) lookup(key string, maxAge time.Duration) (any, bool) {
+	elem, ok := t.hot[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*hotEntry)
+	if maxAge > 0 && t.now().Sub(entry.createdAt) > maxAge {
+		t.removeElement(elem)
+		return nil, false
+	}
+
+	t.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// store keeps a decoded value in memory, evicting beyond the maximum count
+func (t *Tiered) store(key string, value any, created time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := &hotEntry{key: key, value: value, createdAt: created}
+	if elem, ok := t.hot[key]; ok {
+		elem.Value = entry
+		t.order.MoveToFront(elem)
+	} else {
+		t.hot[key] = t.order.PushFront(entry)
+	}
+
+	for t.maxEntries > 0 && t.order.Len() > t.maxEntries {
+		t.removeElement(t.order.Back())
+	}
+}
+
+// forget drops the decoded value for key
+func (t *Tiered) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if elem, ok := t.hot[key]; ok {
+		t.removeElement(elem)
+	}
+}
+
+// removeElement drops a decoded value. t.mu must be held.
+func (t *Tiered) removeElement(elem *list.Element) {
+	t.order.Remove(elem)
+	delete(t.hot, elem.Value.(*hotEntry).key)
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// atoi decodes a cached decimal number
+func atoi(data []byte) (int, error) {
+	return strconv.Atoi(string(data))
+}
+
+func TestTieredLoadDecodesOnce(t *testing.T) {
+	backing, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if err := backing.Put("number", []byte("42")); err != nil {
+		t.Fatalf("failed to put data: %v", err)
+	}
+
+	var decodes atomic.Int32
+	decode := func(data []byte) (int, error) {
+		decodes.Add(1)
+		return atoi(data)
+	}
+
+	tiered := NewTiered(WithContext(backing))
+	for i := 0; i < 3; i++ {
+		n, err := Load(context.Background(), tiered, "number", time.Hour, decode, nil)
+		if err != nil || n != 42 {
+			t.Fatalf("expected 42, got %d, %v", n, err)
+		}
+	}
+	if decodes.Load() != 1 {
+		t.Errorf("expected one decode, got %d", decodes.Load())
+	}
+
+	// Writing through the tiered cache invalidates the decoded value
+	if err := tiered.Put(context.Background(), "number", []byte("7")); err != nil {
+		t.Fatalf("failed to put data: %v", err)
+	}
+	if n, err := Load(context.Background(), tiered, "number", time.Hour, decode, nil); err != nil || n != 7 {
+		t.Errorf("expected 7 after put, got %d, %v", n, err)
+	}
+
+	// Decoded values expire with maxAge like the bytes they came from
+	tiered.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_ = tiered.Exists("number", time.Hour)
+	if tiered.Len() != 0 {
+		t.Error("expected stale decoded value to be dropped")
+	}
+}
+
+func TestTieredLoadFetchesOnMiss(t *testing.T) {
+	tiered := NewTiered(WithContext(NewMemoryCache()))
+	fetch := func(context.Context) ([]byte, error) { return []byte("5"), nil }
+
+	n, err := Load(context.Background(), tiered, "n", 0, atoi, fetch)
+	if err != nil || n != 5 {
+		t.Fatalf("expected fetched value, got %d, %v", n, err)
+	}
+	if data, err := tiered.Get(context.Background(), "n", 0); err != nil || string(data) != "5" {
+		t.Errorf("expected fetched bytes to be stored, got %q, %v", data, err)
+	}
+
+	// Undecodable cached bytes are refetched
+	_ = tiered.Put(context.Background(), "n", []byte("garbage"))
+	if n, err := Load(context.Background(), tiered, "n", 0, atoi, fetch); err != nil || n != 5 {
+		t.Errorf("expected refetch of corrupt value, got %d, %v", n, err)
+	}
+
+	if _, err := Load(context.Background(), tiered, "missing", 0, atoi, nil); !errors.Is(err, ErrNoCachedValue) {
+		t.Errorf("expected ErrNoCachedValue without fetch, got %v", err)
+	}
+
+	failed := errors.New("offline")
+	_, err = Load(context.Background(), tiered, "missing", 0, atoi, func(context.Context) ([]byte, error) {
+		return nil, failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("expected fetch error, got %v", err)
+	}
+}
+
+func TestTieredLoadSingleflight(t *testing.T) {
+	tiered := NewTiered(WithContext(NewNoOpCache()))
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func(context.Context) ([]byte, error) {
+		fetches.Add(1)
+		<-release
+		return []byte("1"), nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if n, err := Load(context.Background(), tiered, "index", time.Hour, atoi, fetch); err != nil || n != 1 {
+				t.Errorf("expected 1, got %d, %v", n, err)
+			}
+		}()
+	}
+	// Release the fetch once every other caller is waiting for it
+	for tiered.flight.waiting("index") < callers-1 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if fetches.Load() != 1 {
+		t.Errorf("expected one fetch, got %d", fetches.Load())
+	}
+}
+
+func TestLoadWithoutTiered(t *testing.T) {
+	store := NewMemoryCache()
+	_ = store.Put("n", []byte("3"))
+	c := WithContext(store)
+
+	n, err := Load(context.Background(), c, "n", 0, atoi, nil)
+	if err != nil || n != 3 {
+		t.Errorf("expected plain caches to be read and decoded, got %d, %v", n, err)
+	}
+}
+
+func TestStoredAt(t *testing.T) {
+	backing, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	tiered := NewTiered(WithContext(backing))
+	if _, ok := StoredAt(tiered, "number"); ok {
+		t.Error("expected no time for a missing key")
+	}
+	if err := tiered.Put(context.Background(), "number", []byte("42")); err != nil {
+		t.Fatalf("failed to put data: %v", err)
+	}
+	if created, ok := StoredAt(tiered, "number"); !ok || time.Since(created) > time.Minute {
+		t.Errorf("expected the time the key was stored, got %v, %v", created, ok)
+	}
+	if _, ok := StoredAt(WithContext(NewNoOpCache()), "number"); ok {
+		t.Error("expected no time from a cache that can't tell")
+	}
+}
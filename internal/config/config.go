@@ -3,10 +3,11 @@ package config
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/go-viper/encoding/ini"
@@ -18,6 +19,19 @@ type Config struct {
 	// License is the Wordfence CLI license key.
 	License string `mapstructure:"license"`
 
+	// LicenseFile is a file holding the license key, read when License
+	// isn't set.
+	LicenseFile string `mapstructure:"license_file"`
+
+	// LicenseCommand is a shell command printing the license key, such as
+	// a secrets manager lookup, run when neither License nor LicenseFile
+	// is set.
+	LicenseCommand string `mapstructure:"license_command"`
+
+	// LicenseSource describes where ResolveLicense found the license (set
+	// at runtime).
+	LicenseSource string `mapstructure:"-"`
+
 	// CacheDirectory is the path to the cache directory.
 	CacheDirectory string `mapstructure:"cache_directory"`
 
@@ -36,8 +50,39 @@ type Config struct {
 	// NoColor disables colored output.
 	NoColor bool `mapstructure:"no_color"`
 
+	// TLSCert is the client certificate (PEM) presented to API servers,
+	// proxies and collectors that require mutual TLS.
+	TLSCert string `mapstructure:"tls_cert"`
+
+	// TLSKey is the private key (PEM) for TLSCert.
+	TLSKey string `mapstructure:"tls_key"`
+
+	// TLSCA is a CA bundle (PEM) that replaces the system roots when
+	// verifying servers.
+	TLSCA string `mapstructure:"tls_ca"`
+
+	// RemediationSource is where remediate fetches original files from:
+	// "noc1" (requires a license) or "wordpress.org".
+	RemediationSource string `mapstructure:"remediation_source"`
+
+	// RegexEngine is the engine malware signatures are matched with:
+	// "auto", "regexp2" to use less memory on small hosts, or "re2" in
+	// builds linking the native RE2 library.
+	RegexEngine string `mapstructure:"regex_engine"`
+
+	// UpdateCheck enables the notice that a newer release is available.
+	UpdateCheck bool `mapstructure:"update_check"`
+
 	// ConfigFile is the path to the configuration file (set at runtime).
 	ConfigFile string `mapstructure:"-"`
+
+	// Profile is the [profile:name] section applied on top of the others
+	// (set at runtime).
+	Profile string `mapstructure:"-"`
+
+	// sections are the settings of each section of the configuration
+	// file, keyed by section and then by setting
+	sections map[string]map[string]string
 }
 
 // DefaultConfig returns the default configuration.
@@ -52,6 +97,10 @@ func DefaultConfig() *Config {
 		Verbose:        false,
 		Quiet:          false,
 		NoColor:        false,
+
+		RemediationSource: "noc1",
+		RegexEngine:       "auto",
+		UpdateCheck:       true,
 	}
 }
 
@@ -61,12 +110,74 @@ func DefaultConfigPath() string {
 	return filepath.Join(homeDir, ".config", "wordfence", "wordfence-cli.ini")
 }
 
+// FindConfigFile returns the configuration file Load reads when none is
+// given, or "" if there is none: wordfence-cli.ini, .yaml, .yml or .toml
+// in ~/.config/wordfence, then in the current directory.
+func FindConfigFile() string {
+	for _, dir := range []string{filepath.Dir(DefaultConfigPath()), "."} {
+		for _, ext := range configExtensions {
+			path := filepath.Join(dir, "wordfence-cli"+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// DefaultSuppressionsPath returns the default suppression store path.
+func DefaultSuppressionsPath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath()), "suppressions.json")
+}
+
+// DefaultHistoryPath returns the default directory of recorded scans.
+func DefaultHistoryPath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath()), "history")
+}
+
+// DefaultJobsPath returns the default directory of daemon scan jobs.
+func DefaultJobsPath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath()), "jobs")
+}
+
+// DefaultReportQueuePath returns the default directory of undelivered
+// collector reports.
+func DefaultReportQueuePath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath()), "report-queue")
+}
+
+// DefaultRemediationsPath returns the default directory of remediation
+// manifests.
+func DefaultRemediationsPath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath()), "remediations")
+}
+
+// DefaultCheckpointPath returns the default file a malware scan stopped at
+// its --max-duration is checkpointed in.
+func DefaultCheckpointPath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath()), "scan-checkpoint.json")
+}
+
+// DefaultQuarantinePath returns the default directory of quarantined
+// files.
+func DefaultQuarantinePath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath()), "quarantine")
+}
+
+// ProfilePrefix starts the name of a profile's section, as in
+// [profile:nightly].
+const ProfilePrefix = "profile:"
+
 // Load loads configuration from all sources in priority order:
 // 1. Command-line flags (handled by cobra)
 // 2. Environment variables (WORDFENCE_CLI_*)
-// 3. Config file
-// 4. Defaults
-func Load(configFile string) (*Config, error) {
+// 3. The profile's section of the config file, if profile is set
+// 4. The [DEFAULT] section of the config file
+// 5. Defaults
+//
+// Settings in the command and profile sections are read by
+// CommandSettings.
+func Load(configFile, profile string) (*Config, error) {
 	// Create codec registry and register INI support
 	codecRegistry := viper.NewCodecRegistry()
 	if err := codecRegistry.RegisterCodec("ini", ini.Codec{}); err != nil {
@@ -80,12 +191,20 @@ func Load(configFile string) (*Config, error) {
 	// Set defaults
 	defaults := DefaultConfig()
 	v.SetDefault("license", defaults.License)
+	v.SetDefault("license_file", defaults.LicenseFile)
+	v.SetDefault("license_command", defaults.LicenseCommand)
 	v.SetDefault("cache_directory", defaults.CacheDirectory)
 	v.SetDefault("cache", defaults.CacheEnabled)
 	v.SetDefault("debug", defaults.Debug)
 	v.SetDefault("verbose", defaults.Verbose)
 	v.SetDefault("quiet", defaults.Quiet)
 	v.SetDefault("no_color", defaults.NoColor)
+	v.SetDefault("tls_cert", defaults.TLSCert)
+	v.SetDefault("tls_key", defaults.TLSKey)
+	v.SetDefault("tls_ca", defaults.TLSCA)
+	v.SetDefault("remediation_source", defaults.RemediationSource)
+	v.SetDefault("regex_engine", defaults.RegexEngine)
+	v.SetDefault("update_check", defaults.UpdateCheck)
 
 	// Environment variables
 	v.SetEnvPrefix("WORDFENCE_CLI")
@@ -97,47 +216,80 @@ func Load(configFile string) (*Config, error) {
 		v.Set("no_color", true)
 	}
 
-	// Config file
-	if configFile != "" {
-		v.SetConfigFile(configFile)
-	} else {
-		// Try default locations
-		homeDir, _ := os.UserHomeDir()
-		v.AddConfigPath(filepath.Join(homeDir, ".config", "wordfence"))
-		v.AddConfigPath(".")
-		v.SetConfigName("wordfence-cli")
-		v.SetConfigType("ini")
-	}
-
-	// Read config file (ignore if not found)
-	if err := v.ReadInConfig(); err != nil {
-		var configFileNotFoundError viper.ConfigFileNotFoundError
-		if !errors.As(err, &configFileNotFoundError) {
-			// Only return error if it's not a "file not found" error
-			if configFile != "" {
-				return nil, fmt.Errorf("reading config: %w", err)
-			}
+	if err := readConfig(v, configFile); err != nil {
+		return nil, err
+	}
+	debugConfig(v)
+	applyDefaultSection(v)
+
+	var sections map[string]map[string]string
+	if v.ConfigFileUsed() != "" {
+		entries, _, err := readConfigFile(v.ConfigFileUsed())
+		if err != nil {
+			return nil, err
 		}
+		sections = groupSections(entries)
+	}
+	if err := applyProfile(v, sections, profile); err != nil {
+		return nil, err
 	}
 
-	// Debug: print config file info (without sensitive values)
-	if os.Getenv("WORDFENCE_DEBUG_CONFIG") != "" {
-		fmt.Fprintf(os.Stderr, "[DEBUG] Config file used: %s\n", v.ConfigFileUsed())
-		fmt.Fprintf(os.Stderr, "[DEBUG] All keys: %v\n", v.AllKeys())
-		// Don't print license values for security
-		settings := v.AllSettings()
-		if _, exists := settings["license"]; exists {
-			settings["license"] = "[REDACTED]"
-		}
-		if _, exists := settings["DEFAULT.license"]; exists {
-			settings["DEFAULT.license"] = "[REDACTED]"
-		}
-		fmt.Fprintf(os.Stderr, "[DEBUG] All settings: %v\n", settings)
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("unmarshaling config: %w", err)
+	}
+
+	cfg.ConfigFile = v.ConfigFileUsed()
+	cfg.Profile = profile
+	cfg.sections = sections
+
+	return &cfg, nil
+}
+
+// readConfig reads the config file, in the format given by its extension,
+// from the default locations if none is given. Errors reading files found
+// in the default locations are ignored.
+func readConfig(v *viper.Viper, configFile string) error {
+	path := configFile
+	if path == "" {
+		path = FindConfigFile()
+	}
+	if path == "" {
+		return nil
+	}
+	v.SetConfigFile(path)
+	v.SetConfigType(Format(path))
+	if err := v.ReadInConfig(); err != nil && configFile != "" {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	return nil
+}
 
-		hasLicense := v.GetString("license") != ""
-		fmt.Fprintf(os.Stderr, "[DEBUG] license configured: %v\n", hasLicense)
+// debugConfig prints the config file and settings read, without the
+// license, when WORDFENCE_DEBUG_CONFIG is set
+func debugConfig(v *viper.Viper) {
+	if os.Getenv("WORDFENCE_DEBUG_CONFIG") == "" {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[DEBUG] Config file used: %s\n", v.ConfigFileUsed())
+	fmt.Fprintf(os.Stderr, "[DEBUG] All keys: %v\n", v.AllKeys())
+	// Don't print license values for security
+	settings := v.AllSettings()
+	if _, exists := settings["license"]; exists {
+		settings["license"] = Redacted
+	}
+	if _, exists := settings["DEFAULT.license"]; exists {
+		settings["DEFAULT.license"] = Redacted
 	}
+	fmt.Fprintf(os.Stderr, "[DEBUG] All settings: %v\n", settings)
 
+	hasLicense := v.GetString("license") != ""
+	fmt.Fprintf(os.Stderr, "[DEBUG] license configured: %v\n", hasLicense)
+}
+
+// applyDefaultSection sets the global settings from the INI [DEFAULT]
+// section, which viper reads as "DEFAULT.key"
+func applyDefaultSection(v *viper.Viper) {
 	// Handle INI section prefixes - Viper reads [DEFAULT] section as "DEFAULT.key"
 	// Check for DEFAULT.license if license is not set directly
 	if v.GetString("license") == "" && v.GetString("DEFAULT.license") != "" {
@@ -148,7 +300,7 @@ func Load(configFile string) (*Config, error) {
 	}
 
 	// Fallback: manually parse INI file if Viper failed to read license
-	if v.GetString("license") == "" && v.ConfigFileUsed() != "" {
+	if v.GetString("license") == "" && v.ConfigFileUsed() != "" && Format(v.ConfigFileUsed()) == FormatINI {
 		if manualLicense, err := parseINILicense(v.ConfigFileUsed()); err == nil && manualLicense != "" {
 			v.Set("license", manualLicense)
 			if os.Getenv("WORDFENCE_DEBUG_CONFIG") != "" {
@@ -156,18 +308,148 @@ func Load(configFile string) (*Config, error) {
 			}
 		}
 	}
-	if v.GetString("cache_directory") == "" && v.GetString("DEFAULT.cache_directory") != "" {
-		v.Set("cache_directory", v.GetString("DEFAULT.cache_directory"))
+	for _, key := range []string{"license_file", "license_command", "cache_directory", "tls_cert", "tls_key", "tls_ca", "remediation_source", "regex_engine"} {
+		if v.GetString(key) == "" && v.GetString("DEFAULT."+key) != "" {
+			v.Set(key, v.GetString("DEFAULT."+key))
+		}
 	}
+	if value := v.GetString("DEFAULT.update_check"); value != "" && os.Getenv("WORDFENCE_CLI_UPDATE_CHECK") == "" {
+		v.Set("update_check", value)
+	}
+}
 
-	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("unmarshaling config: %w", err)
+// applyProfile sets the global settings of the profile, built in or from
+// its section, which override the config file but not the environment
+func applyProfile(v *viper.Viper, sections map[string]map[string]string, profile string) error {
+	if profile == "" {
+		return nil
 	}
+	settings, ok := profileSettings(sections, profile)
+	if !ok {
+		if v.ConfigFileUsed() == "" {
+			return fmt.Errorf("profile %q: no config file found", profile)
+		}
+		return fmt.Errorf("profile %q not found in %s", profile, v.ConfigFileUsed())
+	}
+	for key, value := range settings {
+		if _, global := schema[key]; global && os.Getenv("WORDFENCE_CLI_"+strings.ToUpper(key)) == "" {
+			v.Set(key, value)
+		}
+	}
+	return nil
+}
 
-	cfg.ConfigFile = v.ConfigFileUsed()
+// CommandSettings returns the settings for a command's flags from its
+// section of the config file, such as [malware-scan], overridden by those
+// of the profile, built in or from its section. Keys are flag names.
+func (c *Config) CommandSettings(command string) map[string]string {
+	settings := make(map[string]string)
+	for key, value := range c.sections[command] {
+		settings[flagName(key)] = value
+	}
+	if c.Profile != "" {
+		profile, _ := profileSettings(c.sections, c.Profile)
+		for key, value := range profile {
+			if _, global := schema[key]; !global {
+				settings[flagName(key)] = value
+			}
+		}
+	}
+	return settings
+}
 
-	return &cfg, nil
+// CommandSettingSource describes where CommandSettings got the setting for
+// a command's flag: its profile, built in or from its section, or the
+// command's section of the config file.
+func (c *Config) CommandSettingSource(command, name string) string {
+	if c.Profile != "" {
+		inProfile := func(settings map[string]string) bool {
+			for key := range settings {
+				if _, global := schema[key]; !global && flagName(key) == name {
+					return true
+				}
+			}
+			return false
+		}
+		if inProfile(c.sections[ProfilePrefix+c.Profile]) {
+			return fmt.Sprintf("profile %s in the config file", c.Profile)
+		}
+		if inProfile(builtinProfiles[c.Profile]) {
+			return "profile " + c.Profile
+		}
+	}
+	return fmt.Sprintf("[%s] in the config file", command)
+}
+
+// Profiles returns the names of the built-in profiles and those in the
+// configuration file.
+func (c *Config) Profiles() []string {
+	profiles := make([]string, 0, len(builtinProfiles))
+	for name := range builtinProfiles {
+		profiles = append(profiles, name)
+	}
+	for section := range c.sections {
+		if name, ok := strings.CutPrefix(section, ProfilePrefix); ok && builtinProfiles[name] == nil {
+			profiles = append(profiles, name)
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// groupSections collects INI entries by section; settings before any
+// section header belong to [DEFAULT]
+func groupSections(entries []*fileEntry) map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+	for _, e := range entries {
+		section := e.section
+		if section == "" {
+			section = defaultSection
+		}
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+		sections[section][e.key] = e.value
+	}
+	return sections
+}
+
+// flagName converts a config file key to the flag it sets, so both
+// match_all and match-all set --match-all
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// Redacted replaces secret values in settings and output.
+const Redacted = "[REDACTED]"
+
+// secretKeys are the settings hidden by Settings when redacting.
+var secretKeys = map[string]bool{"license": true, "license_command": true, "webhook_url": true, "routing_key": true, "token": true}
+
+// Settings returns the configuration keyed by config file setting, with
+// secrets such as the license replaced by Redacted when redact is set.
+func (c *Config) Settings(redact bool) map[string]interface{} {
+	settings := make(map[string]interface{})
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		value := v.Field(i).Interface()
+		if redact && IsSecret(key) && !v.Field(i).IsZero() {
+			value = Redacted
+		}
+		settings[key] = value
+	}
+	return settings
+}
+
+// IsSecret reports whether a setting, or the flag of the same name, holds
+// a secret.
+func IsSecret(key string) bool {
+	return secretKeys[strings.ReplaceAll(key, "-", "_")]
 }
 
 // ExpandPath expands ~ in paths to the user's home directory.
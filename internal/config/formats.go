@@ -0,0 +1,230 @@
+// Package config provides YAML and TOML configuration files.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// Configuration file formats, named by their viper config types
+const (
+	FormatINI  = "ini"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// profilesKey holds the profiles of a YAML or TOML file, each a mapping
+// like an INI [profile:name] section.
+const profilesKey = "profiles"
+
+// nestedSections are the keys of a YAML or TOML file holding mappings of
+// named sections, and the prefix of the INI sections they stand for.
+var nestedSections = map[string]string{profilesKey: ProfilePrefix, notificationsKey: NotifyPrefix}
+
+// configExtensions are the file extensions searched for, in order.
+var configExtensions = []string{".ini", ".yaml", ".yml", ".toml"}
+
+// Format returns the format of a configuration file from its extension.
+// Files without a YAML or TOML extension are INI.
+func Format(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatINI
+	}
+}
+
+// readConfigFile reads the entries of a configuration file in any format,
+// reporting malformed content as issues.
+func readConfigFile(path string) ([]*fileEntry, []*Issue, error) {
+	switch Format(path) {
+	case FormatYAML:
+		return readYAML(path)
+	case FormatTOML:
+		return readTOML(path)
+	default:
+		return readINI(path)
+	}
+}
+
+// readYAML reads the entries of a YAML file: settings at the top level,
+// mappings of command settings, and mappings of profiles and notification
+// routes.
+func readYAML(path string) ([]*fileEntry, []*Issue, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- config file named by the user
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, []*Issue{yamlIssue(err)}, nil
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, []*Issue{{Line: root.Line, Severity: SeverityError, Message: "expected a mapping of settings"}}, nil
+	}
+
+	var entries []*fileEntry
+	var issues []*Issue
+	add := func(section string, sectionLine int, key, value *yaml.Node) {
+		v, ok := yamlValue(value)
+		if !ok {
+			issues = append(issues, &Issue{Line: value.Line, Key: key.Value, Severity: SeverityError, Message: "expected a value or a list of values"})
+			return
+		}
+		entries = append(entries, &fileEntry{section: section, sectionLine: sectionLine, key: strings.ToLower(key.Value), value: v, line: key.Line})
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch {
+		case value.Kind != yaml.MappingNode:
+			add("", 0, key, value)
+		case nestedSections[key.Value] != "":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name, profile := value.Content[j], value.Content[j+1]
+				if profile.Kind != yaml.MappingNode {
+					issues = append(issues, &Issue{Line: profile.Line, Key: name.Value, Severity: SeverityError, Message: "expected a mapping of settings"})
+					continue
+				}
+				for k := 0; k+1 < len(profile.Content); k += 2 {
+					add(nestedSections[key.Value]+name.Value, name.Line, profile.Content[k], profile.Content[k+1])
+				}
+			}
+		default:
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				add(key.Value, key.Line, value.Content[j], value.Content[j+1])
+			}
+		}
+	}
+	return entries, issues, nil
+}
+
+// yamlIssue reports a YAML syntax error, at the line given in its message
+func yamlIssue(err error) *Issue {
+	issue := &Issue{Severity: SeverityError, Message: err.Error()}
+	if rest, ok := strings.CutPrefix(err.Error(), "yaml: line "); ok {
+		if n, msg, ok := strings.Cut(rest, ": "); ok {
+			if line, err := strconv.Atoi(n); err == nil {
+				issue.Line, issue.Message = line, msg
+			}
+		}
+	}
+	return issue
+}
+
+// yamlValue returns a scalar as a flag value, and a list as a
+// comma-separated one.
+func yamlValue(node *yaml.Node) (string, bool) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.ScalarNode:
+		return node.Value, true
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", false
+			}
+			values = append(values, item.Value)
+		}
+		return strings.Join(values, ","), true
+	}
+	return "", false
+}
+
+// readTOML reads the entries of a TOML file, laid out as a YAML file is.
+// TOML doesn't give the lines of decoded values, so entries have none.
+func readTOML(path string) ([]*fileEntry, []*Issue, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- config file named by the user
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		issue := &Issue{Severity: SeverityError, Message: err.Error()}
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			issue.Line, _ = decodeErr.Position()
+		}
+		return nil, []*Issue{issue}, nil
+	}
+
+	var entries []*fileEntry
+	var issues []*Issue
+	add := func(section, key string, value interface{}) {
+		v, ok := tomlValue(value)
+		if !ok {
+			issues = append(issues, &Issue{Key: key, Severity: SeverityError, Message: "expected a value or a list of values"})
+			return
+		}
+		entries = append(entries, &fileEntry{section: section, key: strings.ToLower(key), value: v})
+	}
+	for _, key := range sortedKeys(doc) {
+		table, isTable := doc[key].(map[string]interface{})
+		switch {
+		case !isTable:
+			add("", key, doc[key])
+		case nestedSections[key] != "":
+			for _, name := range sortedKeys(table) {
+				profile, ok := table[name].(map[string]interface{})
+				if !ok {
+					issues = append(issues, &Issue{Key: name, Severity: SeverityError, Message: "expected a table of settings"})
+					continue
+				}
+				for _, k := range sortedKeys(profile) {
+					add(nestedSections[key]+name, k, profile[k])
+				}
+			}
+		default:
+			for _, k := range sortedKeys(table) {
+				add(key, k, table[k])
+			}
+		}
+	}
+	return entries, issues, nil
+}
+
+// tomlValue returns a decoded TOML value as a flag value, and an array as
+// a comma-separated one.
+func tomlValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "", false
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := tomlValue(item)
+			if !ok {
+				return "", false
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, ","), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+const yamlConfig = `license: abc
+verbose: true
+cache_direcory: /tmp
+malware-scan:
+  workers: 2
+  include-files:
+    - a.php
+    - b.php
+profiles:
+  nightly:
+    quiet: true
+    workers: 8
+notifications:
+  oncall:
+    type: pagerduty
+    routing_key: abc123
+`
+
+const tomlConfig = `license = "abc"
+verbose = true
+cache_direcory = "/tmp"
+
+[malware-scan]
+workers = 2
+include-files = ["a.php", "b.php"]
+
+[profiles.nightly]
+quiet = true
+workers = 8
+
+[notifications.oncall]
+type = "pagerduty"
+routing_key = "abc123"
+`
+
+func TestLoadFormats(t *testing.T) {
+	for name, content := range map[string]string{"wordfence-cli.yaml": yamlConfig, "wordfence-cli.toml": tomlConfig} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path, "nightly")
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+			if cfg.License != "abc" || !cfg.Verbose || !cfg.Quiet {
+				t.Errorf("unexpected config %+v", cfg)
+			}
+			settings := cfg.CommandSettings("malware-scan")
+			if settings["workers"] != "8" || settings["include-files"] != "a.php,b.php" {
+				t.Errorf("unexpected command settings %v", settings)
+			}
+			if route := cfg.Notifications()["oncall"]; route["type"] != "pagerduty" || route["routing_key"] != "abc123" {
+				t.Errorf("unexpected notification routes %v", cfg.Notifications())
+			}
+
+			flags := pflag.NewFlagSet("malware-scan", pflag.ContinueOnError)
+			flags.Int("workers", 0, "")
+			flags.StringSlice("include-files", nil, "")
+			issues, err := ValidateFile(path, CommandFlags{"malware-scan": flags})
+			if err != nil {
+				t.Fatalf("validate failed: %v", err)
+			}
+			if len(issues) != 1 || issues[0].Key != "cache_direcory" {
+				t.Fatalf("expected only the typo to be reported, got %v", issues)
+			}
+		})
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	for name, content := range map[string]string{
+		"bad.yaml": "license: abc\n  verbose: [true\n",
+		"bad.toml": "license = \"abc\"\nverbose = \n",
+	} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		issues, err := ValidateFile(path, nil)
+		if err != nil {
+			t.Fatalf("%s: validate failed: %v", name, err)
+		}
+		if len(issues) != 1 || issues[0].Severity != SeverityError || issues[0].Line != 2 {
+			t.Errorf("%s: expected a syntax error on line 2, got %v", name, issues)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for path, want := range map[string]string{
+		"wordfence-cli.ini":  FormatINI,
+		"wordfence.conf":     FormatINI,
+		"wordfence-cli.YML":  FormatYAML,
+		"wordfence-cli.yaml": FormatYAML,
+		"wordfence-cli.toml": FormatTOML,
+	} {
+		if got := Format(path); got != want {
+			t.Errorf("Format(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package config provides license keys from files, commands and systemd
+// credentials.
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SystemdCredential is the name of the systemd credential holding the
+// license, as in LoadCredential=wordfence-license:/etc/wordfence/license.
+const SystemdCredential = "wordfence-license"
+
+// LicenseCommandTimeout bounds how long license_command may run.
+const LicenseCommandTimeout = 30 * time.Second
+
+// ResolveLicense sets the license, if it isn't set, from the first of
+// license_file, license_command and the systemd credential that is
+// configured. It is called only by commands that need a license, so a
+// license command runs only when it is used.
+func (c *Config) ResolveLicense() error {
+	if c.License != "" {
+		return nil
+	}
+
+	switch {
+	case c.LicenseFile != "":
+		data, err := os.ReadFile(ExpandPath(c.LicenseFile)) // #nosec G304 -- license file named in the config
+		if err != nil {
+			return fmt.Errorf("reading license_file: %w", err)
+		}
+		c.License, c.LicenseSource = strings.TrimSpace(string(data)), "license_file "+c.LicenseFile
+		if c.License == "" {
+			return fmt.Errorf("license_file %s is empty", c.LicenseFile)
+		}
+
+	case c.LicenseCommand != "":
+		license, err := runLicenseCommand(c.LicenseCommand)
+		if err != nil {
+			return err
+		}
+		c.License, c.LicenseSource = license, "license_command"
+
+	default:
+		dir := os.Getenv("CREDENTIALS_DIRECTORY")
+		if dir == "" {
+			return nil
+		}
+		data, err := os.ReadFile(filepath.Join(dir, SystemdCredential)) // #nosec G304 -- credential passed by systemd
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading systemd credential %s: %w", SystemdCredential, err)
+		}
+		c.License, c.LicenseSource = strings.TrimSpace(string(data)), "systemd credential "+SystemdCredential
+	}
+	return nil
+}
+
+// runLicenseCommand runs a license command with the shell and returns the
+// first line it prints
+func runLicenseCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), LicenseCommandTimeout)
+	defer cancel()
+
+	shell, flag := "/bin/sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, command) // #nosec G204 -- command from the user's own config
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("running license_command: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("running license_command: %w", err)
+	}
+
+	license, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	license = strings.TrimSpace(license)
+	if license == "" {
+		return "", errors.New("license_command printed no license")
+	}
+	return license, nil
+}
@@ -0,0 +1,69 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveLicense(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "license")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	creds := t.TempDir()
+	if err := os.WriteFile(filepath.Join(creds, SystemdCredential), []byte("from-systemd\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CREDENTIALS_DIRECTORY", creds)
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"license wins", Config{License: "direct", LicenseFile: file, LicenseCommand: "echo from-command"}, "direct"},
+		{"file", Config{LicenseFile: file, LicenseCommand: "echo from-command"}, "from-file"},
+		{"command", Config{LicenseCommand: "printf 'from-command\\nignored\\n'"}, "from-command"},
+		{"systemd", Config{}, "from-systemd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if err := cfg.ResolveLicense(); err != nil {
+				t.Fatalf("resolve failed: %v", err)
+			}
+			if cfg.License != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, cfg.License)
+			}
+		})
+	}
+}
+
+func TestResolveLicenseErrors(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+
+	cfg := Config{LicenseCommand: "echo denied >&2; exit 3"}
+	if err := cfg.ResolveLicense(); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("expected the command's error, got %v", err)
+	}
+
+	cfg = Config{LicenseCommand: "true"}
+	if err := cfg.ResolveLicense(); err == nil {
+		t.Error("expected a command printing nothing to fail")
+	}
+
+	cfg = Config{LicenseFile: filepath.Join(t.TempDir(), "missing")}
+	if err := cfg.ResolveLicense(); err == nil {
+		t.Error("expected a missing license file to fail")
+	}
+
+	cfg = Config{}
+	if err := cfg.ResolveLicense(); err != nil || cfg.License != "" {
+		t.Errorf("expected no license and no error, got %q, %v", cfg.License, err)
+	}
+}
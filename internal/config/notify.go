@@ -0,0 +1,134 @@
+// Package config provides the notification routes of configuration files.
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NotifyPrefix starts the name of a notification route's section, as in
+// [notify:oncall].
+const NotifyPrefix = "notify:"
+
+// notificationsKey holds the notification routes of a YAML or TOML file,
+// each a mapping like an INI [notify:name] section.
+const notificationsKey = "notifications"
+
+// severityNames are the severities notification routes select by.
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+// notifySchema is every setting a [notify:name] section may contain.
+var notifySchema = map[string]setting{
+	"type":         {kind: kindString, values: []string{"slack", "discord", "pagerduty", "jira", "github"}},
+	"webhook_url":  {kind: kindString},
+	"routing_key":  {kind: kindString},
+	"url":          {kind: kindString},
+	"project":      {kind: kindString},
+	"issue_type":   {kind: kindString},
+	"repository":   {kind: kindString},
+	"user":         {kind: kindString},
+	"token":        {kind: kindString},
+	"labels":       {kind: kindString},
+	"min_severity": {kind: kindString, values: severityNames},
+	"max_severity": {kind: kindString, values: severityNames},
+	"kinds":        {kind: kindString, values: []string{"malware", "vulnerability"}, list: true},
+	"timeout":      {kind: kindDuration, maxDelay: 5 * time.Minute},
+}
+
+// Notifications returns the settings of each [notify:name] section, keyed
+// by route name and then by setting.
+func (c *Config) Notifications() map[string]map[string]string {
+	routes := make(map[string]map[string]string)
+	for section, settings := range c.sections {
+		name, ok := strings.CutPrefix(section, NotifyPrefix)
+		if !ok {
+			continue
+		}
+		route := make(map[string]string, len(settings))
+		for key, value := range settings {
+			route[strings.ReplaceAll(key, "-", "_")] = value
+		}
+		routes[name] = route
+	}
+	return routes
+}
+
+// checkNotifyEntry returns what is wrong with a setting of a [notify:name]
+// section, if anything, and how serious it is.
+func checkNotifyEntry(e *fileEntry) (string, Severity) {
+	s, ok := notifySchema[strings.ReplaceAll(e.key, "-", "_")]
+	if !ok {
+		keys := make([]string, 0, len(notifySchema))
+		for key := range notifySchema {
+			keys = append(keys, key)
+		}
+		return unknownKeyMessage(e.key, keys), SeverityWarning
+	}
+	return s.check(e.value), SeverityError
+}
+
+// checkNotifySections reports [notify:name] sections missing the settings
+// their type needs.
+func checkNotifySections(seen map[string]*fileEntry, sectionLines map[string]int) []*Issue {
+	names := make([]string, 0, len(sectionLines))
+	for section := range sectionLines {
+		names = append(names, section)
+	}
+	sort.Strings(names)
+
+	var issues []*Issue
+	value := func(section, key string) string {
+		for _, k := range []string{key, strings.ReplaceAll(key, "_", "-")} {
+			if e := seen[section+"\x00"+k]; e != nil {
+				return e.value
+			}
+		}
+		return ""
+	}
+	for _, section := range names {
+		var msg string
+		kind := value(section, "type")
+		if kind == "" {
+			msg = "type is not set: use slack, discord, pagerduty, jira or github"
+		}
+		var missing []string
+		for _, key := range notifyRequired[kind] {
+			if value(section, key) == "" {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			msg = strings.Join(missing, ", ") + " not set"
+		}
+		min, max := value(section, "min_severity"), value(section, "max_severity")
+		if msg == "" && min != "" && max != "" && severityRank(min) > severityRank(max) {
+			msg = fmt.Sprintf("min_severity %s is above max_severity %s", min, max)
+		}
+		if msg != "" {
+			issues = append(issues, &Issue{Line: sectionLines[section], Severity: SeverityError,
+				Message: fmt.Sprintf("[%s]: %s", section, msg)})
+		}
+	}
+	return issues
+}
+
+// notifyRequired are the settings each type of notification route needs.
+var notifyRequired = map[string][]string{
+	"slack":     {"webhook_url"},
+	"discord":   {"webhook_url"},
+	"pagerduty": {"routing_key"},
+	"jira":      {"url", "project", "token"},
+	"github":    {"repository", "token"},
+}
+
+// severityRank orders severity names, least severe first.
+func severityRank(name string) int {
+	for i, n := range severityNames {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
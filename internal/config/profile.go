@@ -0,0 +1,109 @@
+// Package config provides scan profiles: named sets of flag defaults.
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// builtinProfiles are the profiles available without a config file, by
+// name, each holding settings like a [profile:name] section.
+var builtinProfiles = make(map[string]map[string]string)
+
+// RegisterProfile makes a built-in profile available to --profile. A
+// [profile:name] section in the config file overrides its settings one by
+// one.
+func RegisterProfile(name string, settings map[string]string) {
+	builtinProfiles[name] = settings
+}
+
+// profileSettings returns the settings of a profile: those of the built-in
+// profile, if any, overridden by those of its section. It reports false if
+// there is neither.
+func profileSettings(sections map[string]map[string]string, profile string) (map[string]string, bool) {
+	builtin, isBuiltin := builtinProfiles[profile]
+	section, inFile := sections[ProfilePrefix+profile]
+	if !inFile {
+		return builtin, isBuiltin
+	}
+	settings := make(map[string]string, len(builtin)+len(section))
+	for key, value := range builtin {
+		settings[key] = value
+	}
+	for key, value := range section {
+		settings[key] = value
+	}
+	return settings, true
+}
+
+// SaveProfile writes settings to the [profile:name] section of an INI
+// config file, replacing the section if it exists and creating the file
+// if it doesn't. Other sections and comments are kept as they are.
+func SaveProfile(path, name string, settings map[string]string) error {
+	if format := Format(path); format != FormatINI {
+		return fmt.Errorf("saving profiles to %s files isn't supported; add the profile to %s by hand", strings.ToUpper(format), path)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- config file named by the user
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	// Comments at the end of the section replaced belong to the next one,
+	// so they are held until it's clear whether a setting follows
+	var out, held bytes.Buffer
+	header := "[" + ProfilePrefix + name + "]"
+	skipping := false
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		switch {
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			skipping = line == header
+			if !skipping && bytes.HasSuffix(out.Bytes(), []byte("\n\n")) {
+				out.Write(bytes.TrimLeft(held.Bytes(), "\n"))
+			} else if !skipping {
+				out.Write(held.Bytes())
+			}
+			held.Reset()
+		case skipping && (line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";")):
+			held.WriteString(lines.Text() + "\n")
+			continue
+		case skipping:
+			held.Reset()
+		}
+		if !skipping {
+			out.WriteString(lines.Text() + "\n")
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if trimmed := bytes.TrimRight(out.Bytes(), "\n"); len(trimmed) > 0 {
+		out.Truncate(len(trimmed))
+		out.WriteString("\n\n")
+	}
+	out.WriteString(header + "\n")
+	for _, key := range keys {
+		fmt.Fprintf(&out, "%s = %s\n", key, settings[key])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+	return nil
+}
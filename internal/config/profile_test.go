@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordfence", "wordfence-cli.ini")
+	if err := SaveProfile(path, "calibrated", map[string]string{"workers": "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "[DEFAULT]\nlicense = abc\n\n[profile:calibrated]\nworkers = 2\n\n# scans at night\n[profile:nightly]\nquiet = true\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveProfile(path, "calibrated", map[string]string{"workers": "4", "regex_engine": "regexp2"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[DEFAULT]\nlicense = abc\n\n# scans at night\n[profile:nightly]\nquiet = true\n\n[profile:calibrated]\nregex_engine = regexp2\nworkers = 4\n"
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+
+	cfg, err := Load(path, "calibrated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RegexEngine != "regexp2" || cfg.CommandSettings("malware-scan")["workers"] != "4" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	if err := SaveProfile(filepath.Join(t.TempDir(), "wordfence-cli.yaml"), "calibrated", nil); err == nil {
+		t.Error("expected saving to YAML to fail")
+	}
+}
+
+func TestBuiltinProfile(t *testing.T) {
+	RegisterProfile("gentle", map[string]string{"workers": "1", "file-delay": "5ms"})
+	t.Cleanup(func() { delete(builtinProfiles, "gentle") })
+
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.ini"), "gentle")
+	if err == nil {
+		t.Fatal("expected a missing config file to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "wordfence-cli.ini")
+	if err := os.WriteFile(path, []byte("[profile:gentle]\nworkers = 2\n\n[profile:nightly]\nquiet = true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = Load(path, "gentle"); err != nil {
+		t.Fatal(err)
+	}
+	settings := cfg.CommandSettings("malware-scan")
+	if settings["workers"] != "2" || settings["file-delay"] != "5ms" {
+		t.Errorf("expected the section to override the built-in profile, got %v", settings)
+	}
+	if source := cfg.CommandSettingSource("malware-scan", "workers"); source != "profile gentle in the config file" {
+		t.Errorf("unexpected source of workers %q", source)
+	}
+	if source := cfg.CommandSettingSource("malware-scan", "file-delay"); source != "profile gentle" {
+		t.Errorf("unexpected source of file-delay %q", source)
+	}
+	if source := cfg.CommandSettingSource("malware-scan", "output"); source != "[malware-scan] in the config file" {
+		t.Errorf("unexpected source of output %q", source)
+	}
+	if profiles := cfg.Profiles(); len(profiles) != 2 || profiles[0] != "gentle" || profiles[1] != "nightly" {
+		t.Errorf("unexpected profiles %v", profiles)
+	}
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = Load(path, "gentle"); err != nil {
+		t.Fatal(err)
+	}
+	if settings := cfg.CommandSettings("malware-scan"); settings["workers"] != "1" {
+		t.Errorf("expected the built-in profile without a section, got %v", settings)
+	}
+}
@@ -0,0 +1,427 @@
+// Package config provides validation of configuration files.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Severity is how serious a validation issue is.
+type Severity string
+
+const (
+	// SeverityWarning marks settings that are ignored, such as unknown keys.
+	SeverityWarning Severity = "warning"
+	// SeverityError marks settings that are invalid.
+	SeverityError Severity = "error"
+)
+
+// Issue is a problem found in a configuration file.
+type Issue struct {
+	Line     int      `json:"line,omitempty"`
+	Key      string   `json:"key,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (i *Issue) String() string {
+	var sb strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&sb, "line %d: ", i.Line)
+	}
+	if i.Key != "" {
+		sb.WriteString(i.Key + ": ")
+	}
+	sb.WriteString(i.Message)
+	return sb.String()
+}
+
+// kind is the type of a setting's value.
+type kind int
+
+const (
+	kindString kind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindDuration
+	kindDir  // Directory, created if missing
+	kindFile // Existing file
+)
+
+// setting describes a key allowed in the configuration file.
+type setting struct {
+	kind     kind
+	min, max int           // Range of a kindInt or kindFloat value
+	maxDelay time.Duration // Upper bound of a kindDuration value
+	values   []string      // Allowed values, if limited
+	list     bool          // Comma-separated values, each allowed
+}
+
+// schema is every setting the configuration file may contain.
+var schema = map[string]setting{
+	"license":            {kind: kindString},
+	"license_file":       {kind: kindFile},
+	"license_command":    {kind: kindString},
+	"cache_directory":    {kind: kindDir},
+	"cache":              {kind: kindBool},
+	"debug":              {kind: kindBool},
+	"verbose":            {kind: kindBool},
+	"quiet":              {kind: kindBool},
+	"no_color":           {kind: kindBool},
+	"tls_cert":           {kind: kindFile},
+	"tls_key":            {kind: kindFile},
+	"tls_ca":             {kind: kindFile},
+	"remediation_source": {kind: kindString, values: []string{"noc1", "wordpress.org"}},
+	"regex_engine":       {kind: kindString, values: []string{"auto", "regexp2", "re2"}},
+	"update_check":       {kind: kindBool},
+}
+
+// flagRanges limits the values of numeric command settings, by flag name,
+// including the scan tuning settings of profiles.
+var flagRanges = map[string]setting{
+	"workers":             {kind: kindInt, min: 0, max: 1024},
+	"locate-workers":      {kind: kindInt, min: 0, max: 1024},
+	"read-workers":        {kind: kindInt, min: 0, max: 1024},
+	"match-workers":       {kind: kindInt, min: 0, max: 1024},
+	"api-concurrency":     {kind: kindInt, min: 0, max: 256},
+	"max-jobs":            {kind: kindInt, min: 0, max: 256},
+	"tenant-jobs":         {kind: kindInt, min: 0, max: 256},
+	"max-depth":           {kind: kindInt, min: 0, max: 1000},
+	"context":             {kind: kindInt, min: 0, max: 100},
+	"max-read-memory":     {kind: kindInt, min: 0, max: 64 << 10},
+	"read-pool-memory":    {kind: kindInt, min: 0, max: 64 << 10},
+	"max-read-rate":       {kind: kindFloat, min: 0, max: 100 << 10},
+	"content-limit":       {kind: kindInt, min: 0, max: 64 << 10},
+	"memory-limit":        {kind: kindInt, min: 0, max: 1 << 20},
+	"file-delay":          {kind: kindDuration, maxDelay: 10 * time.Second},
+	"batch-size":          {kind: kindInt, min: 0, max: 1 << 20},
+	"batch-pause":         {kind: kindDuration, maxDelay: time.Minute},
+	"read-latency-target": {kind: kindDuration, maxDelay: 10 * time.Second},
+}
+
+// CommandFlags are the flags each command takes in its section of the
+// configuration file, keyed by the command's name, such as malware-scan,
+// or path, such as "remediate rollback".
+type CommandFlags map[string]*pflag.FlagSet
+
+// defaultSection is the INI section holding the global settings, which may
+// also be written before any section header.
+const defaultSection = "DEFAULT"
+
+// fileEntry is a setting read from a configuration file.
+type fileEntry struct {
+	section     string
+	sectionLine int
+	key         string
+	value       string
+	line        int
+}
+
+// ValidateFile checks a configuration file for unknown sections and keys,
+// values of the wrong type or out of range, and inconsistent settings.
+// Command and profile sections are checked against the flags in commands.
+// It returns an error only if the file can't be read.
+func ValidateFile(path string, commands CommandFlags) ([]*Issue, error) {
+	entries, issues, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]*fileEntry)
+	reported := make(map[string]bool)
+	routes := make(map[string]int)
+	for _, e := range entries {
+		section := e.section
+		if section == "" {
+			section = defaultSection
+		}
+		_, isCommand := commands[section]
+		isProfile := strings.HasPrefix(section, ProfilePrefix)
+		isNotify := strings.HasPrefix(section, NotifyPrefix)
+		if isNotify {
+			routes[section] = e.sectionLine
+		}
+		if section != defaultSection && !isCommand && !isProfile && !isNotify {
+			if !reported[section] {
+				reported[section] = true
+				issues = append(issues, &Issue{Line: e.sectionLine, Severity: SeverityWarning,
+					Message: fmt.Sprintf("unknown section [%s]; its settings are ignored", section)})
+			}
+			continue
+		}
+
+		if prev, ok := seen[section+"\x00"+e.key]; ok {
+			issues = append(issues, &Issue{Line: e.line, Key: e.key, Severity: SeverityWarning,
+				Message: fmt.Sprintf("also set on line %d; this value is used", prev.line)})
+		}
+		seen[section+"\x00"+e.key] = e
+
+		var msg string
+		severity := SeverityError
+		s, global := schema[e.key]
+		switch {
+		case isNotify:
+			msg, severity = checkNotifyEntry(e)
+		case global && isCommand:
+			severity, msg = SeverityWarning, fmt.Sprintf("global setting ignored in [%s]; set it in [DEFAULT] or a profile", section)
+		case global:
+			msg = s.check(e.value)
+		case isCommand:
+			msg, severity = checkFlag(commands, []string{section}, e)
+		case isProfile:
+			msg, severity = checkFlag(commands, nil, e)
+		default:
+			severity, msg = SeverityWarning, unknownKeyMessage(e.key, schemaKeys())
+		}
+		if msg != "" {
+			issues = append(issues, &Issue{Line: e.line, Key: e.key, Severity: severity, Message: msg})
+		}
+	}
+
+	cert, key := seen[defaultSection+"\x00tls_cert"], seen[defaultSection+"\x00tls_key"]
+	if (cert == nil || cert.value == "") != (key == nil || key.value == "") {
+		e := cert
+		if e == nil || e.value == "" {
+			e = key
+		}
+		issues = append(issues, &Issue{Line: e.line, Key: e.key, Severity: SeverityError,
+			Message: "tls_cert and tls_key must be set together"})
+	}
+
+	issues = append(issues, checkNotifySections(seen, routes)...)
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues, nil
+}
+
+// check returns what is wrong with a value for the setting, if anything.
+func (s setting) check(value string) string {
+	switch s.kind {
+	case kindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Sprintf("%q is not true or false", value)
+		}
+	case kindInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Sprintf("%q is not a whole number", value)
+		}
+		if n < s.min || n > s.max {
+			return fmt.Sprintf("%d is out of range (%d to %d)", n, s.min, s.max)
+		}
+	case kindFloat:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Sprintf("%q is not a number", value)
+		}
+		if n < float64(s.min) || n > float64(s.max) {
+			return fmt.Sprintf("%s is out of range (%d to %d)", value, s.min, s.max)
+		}
+	case kindDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Sprintf("%q is not a duration", value)
+		}
+		if d < 0 || d > s.maxDelay {
+			return fmt.Sprintf("%s is out of range (0s to %v)", value, s.maxDelay)
+		}
+	case kindDir:
+		if info, err := os.Stat(ExpandPath(value)); err == nil && !info.IsDir() {
+			return fmt.Sprintf("%s is not a directory", value)
+		}
+	case kindFile:
+		if value == "" {
+			return ""
+		}
+		if _, err := os.Stat(ExpandPath(value)); err != nil {
+			return fmt.Sprintf("cannot use %s: %v", value, err)
+		}
+	}
+	if len(s.values) == 0 {
+		return ""
+	}
+	values := []string{value}
+	if s.list {
+		values = strings.Split(value, ",")
+	}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); !slices.Contains(s.values, value) && (!s.list || value != "") {
+			return fmt.Sprintf("%q is not one of %s", value, strings.Join(s.values, ", "))
+		}
+	}
+	return ""
+}
+
+// CheckFlagSetting returns an error if a command or profile setting for a
+// flag is out of the range allowed for it.
+func CheckFlagSetting(name, value string) error {
+	if r, ok := flagRanges[flagName(name)]; ok {
+		if msg := r.check(value); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+	}
+	return nil
+}
+
+// checkFlag checks a command setting against the flag of that name in the
+// named commands, or in any command when none are named, as for profiles
+func checkFlag(commands CommandFlags, names []string, e *fileEntry) (string, Severity) {
+	if names == nil {
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	name := flagName(e.key)
+	var known []string
+	for _, command := range names {
+		flags := commands[command]
+		flag := flags.Lookup(name)
+		if flag == nil {
+			flags.VisitAll(func(f *pflag.Flag) { known = append(known, f.Name) })
+			continue
+		}
+		if msg := checkFlagValue(flag, e.value); msg != "" {
+			return msg, SeverityError
+		}
+		if r, ok := flagRanges[name]; ok {
+			return r.check(e.value), SeverityError
+		}
+		return "", SeverityError
+	}
+	if len(names) > 1 {
+		known = append(known, schemaKeys()...)
+	}
+	return unknownKeyMessage(name, known), SeverityWarning
+}
+
+// checkFlagValue returns what is wrong with a value for a flag, if
+// anything, by the flag's type
+func checkFlagValue(flag *pflag.Flag, value string) string {
+	var err error
+	switch flag.Value.Type() {
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "int", "int8", "int16", "int32", "int64":
+		_, err = strconv.ParseInt(value, 0, 64)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		_, err = strconv.ParseUint(value, 0, 64)
+	case "float32", "float64":
+		_, err = strconv.ParseFloat(value, 64)
+	case "duration":
+		_, err = time.ParseDuration(value)
+	default:
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("%q is not a valid %s", value, flag.Value.Type())
+	}
+	return ""
+}
+
+// schemaKeys returns the global settings
+func schemaKeys() []string {
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// unknownKeyMessage describes an unknown key, suggesting the closest of
+// the known keys for likely typos.
+func unknownKeyMessage(key string, known []string) string {
+	best, bestDistance := "", 3
+	for _, known := range known {
+		if d := editDistance(key, known); d < bestDistance || (d == bestDistance && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("unknown setting (did you mean %s?)", best)
+	}
+	return "unknown setting"
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// readINI reads the entries of an INI file with their sections and line
+// numbers, reporting malformed lines as issues.
+func readINI(path string) ([]*fileEntry, []*Issue, error) {
+	file, err := os.Open(path) // #nosec G304 -- config file named by the user
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening config file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []*fileEntry
+	var issues []*Issue
+	section, sectionLine := "", 0
+	lines := bufio.NewScanner(file)
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, sectionLine = strings.TrimSpace(line[1:len(line)-1]), n
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, ok = strings.Cut(line, ":")
+		}
+		if !ok {
+			issues = append(issues, &Issue{Line: n, Severity: SeverityError, Message: "expected key = value"})
+			continue
+		}
+		entries = append(entries, &fileEntry{
+			section:     section,
+			sectionLine: sectionLine,
+			key:         strings.ToLower(strings.TrimSpace(key)),
+			value:       unquote(strings.TrimSpace(value)),
+			line:        n,
+		})
+	}
+	if err := lines.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+	return entries, issues, nil
+}
+
+// unquote removes matching quotes around a value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
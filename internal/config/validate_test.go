@@ -0,0 +1,271 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestValidateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordfence-cli.ini")
+	content := `[DEFAULT]
+license = abc
+cache_direcory = /tmp
+cache = maybe
+remediation_source = "wordpress.org"
+quiet = true
+quiet = false
+[profile]
+anything = goes
+not a setting
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := ValidateFile(path, nil)
+	if err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	want := []struct {
+		line     int
+		severity Severity
+		message  string
+	}{
+		{3, SeverityWarning, "did you mean cache_directory?"},
+		{4, SeverityError, `"maybe" is not true or false`},
+		{7, SeverityWarning, "also set on line 6"},
+		{8, SeverityWarning, "unknown section [profile]"},
+		{10, SeverityError, "expected key = value"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), issues)
+	}
+	for i, w := range want {
+		got := issues[i]
+		if got.Line != w.line || got.Severity != w.severity || !strings.Contains(got.Message, w.message) {
+			t.Errorf("issue %d: expected line %d %s %q, got %s %s", i, w.line, w.severity, w.message, got.Severity, got)
+		}
+	}
+}
+
+func TestSettingCheck(t *testing.T) {
+	workers := setting{kind: kindInt, min: 1, max: 64}
+	for value, ok := range map[string]bool{"1": true, "64": true, "0": false, "65": false, "four": false} {
+		if msg := workers.check(value); (msg == "") != ok {
+			t.Errorf("check(%q): unexpected result %q", value, msg)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if msg := (setting{kind: kindFile}).check(file); msg != "" {
+		t.Errorf("expected existing file to be valid, got %q", msg)
+	}
+	if msg := (setting{kind: kindDir}).check(file); msg == "" {
+		t.Error("expected a file to be rejected as a directory")
+	}
+	if msg := (setting{kind: kindDir}).check(filepath.Join(t.TempDir(), "new")); msg != "" {
+		t.Errorf("expected a missing directory to be valid, got %q", msg)
+	}
+}
+
+func TestSettingsRedact(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.License = "secret"
+	if got := cfg.Settings(true)["license"]; got != Redacted {
+		t.Errorf("expected license redacted, got %v", got)
+	}
+	if got := cfg.Settings(false)["license"]; got != "secret" {
+		t.Errorf("expected license shown, got %v", got)
+	}
+	if _, ok := cfg.Settings(true)["-"]; ok {
+		t.Error("expected runtime fields to be left out")
+	}
+}
+
+func TestValidateCommandSections(t *testing.T) {
+	flags := pflag.NewFlagSet("malware-scan", pflag.ContinueOnError)
+	flags.Int("workers", 0, "")
+	flags.Bool("match-all", false, "")
+	flags.Duration("match-timeout", time.Second, "")
+	commands := CommandFlags{"malware-scan": flags}
+
+	path := filepath.Join(t.TempDir(), "wordfence-cli.ini")
+	content := `[malware-scan]
+workers = 2000
+match_all = yes
+match-timeout = 5s
+verbose = true
+match-al = true
+[profile:nightly]
+workers = 8
+quiet = true
+output = /tmp/results.json
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := ValidateFile(path, commands)
+	if err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	want := []struct {
+		line    int
+		message string
+	}{
+		{2, "out of range"},
+		{3, `"yes" is not a valid bool`},
+		{5, "global setting ignored in [malware-scan]"},
+		{6, "did you mean match-all?"},
+		{10, "unknown setting"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].Line != w.line || !strings.Contains(issues[i].Message, w.message) {
+			t.Errorf("issue %d: expected line %d %q, got %s", i, w.line, w.message, issues[i])
+		}
+	}
+}
+
+func TestValidateNotifySections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordfence-cli.ini")
+	content := `[notify:oncall]
+type = pagerduty
+min_severity = critical
+kinds = malware, spam
+[notify:channel]
+type = slack
+min_severity = high
+max_severity = low
+channel = #security
+[notify:chat]
+type = discord
+webhook-url = https://discord.com/api/webhooks/1/abc
+kinds = malware,vulnerability
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := ValidateFile(path, nil)
+	if err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	want := []struct {
+		line    int
+		message string
+	}{
+		{1, "routing_key not set"},
+		{4, `"spam" is not one of malware, vulnerability`},
+		{5, "webhook_url not set"},
+		{9, "unknown setting"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].Line != w.line || !strings.Contains(issues[i].Message, w.message) {
+			t.Errorf("issue %d: expected line %d %q, got %s", i, w.line, w.message, issues[i])
+		}
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordfence-cli.ini")
+	content := `[DEFAULT]
+license = abc
+[malware-scan]
+workers = 2
+match_all = true
+[profile:nightly]
+quiet = true
+verbose = true
+workers = 8
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WORDFENCE_CLI_VERBOSE", "false")
+
+	cfg, err := Load(path, "nightly")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if !cfg.Quiet || cfg.Verbose || cfg.License != "abc" {
+		t.Errorf("expected the profile to override the file but not the environment, got %+v", cfg)
+	}
+	settings := cfg.CommandSettings("malware-scan")
+	if settings["workers"] != "8" || settings["match-all"] != "true" || settings["quiet"] != "" {
+		t.Errorf("unexpected command settings %v", settings)
+	}
+
+	cfg, err = Load(path, "")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.Quiet || cfg.CommandSettings("malware-scan")["workers"] != "2" {
+		t.Errorf("expected no profile to apply, got %+v", cfg)
+	}
+	if profiles := cfg.Profiles(); len(profiles) != 1 || profiles[0] != "nightly" {
+		t.Errorf("expected profiles [nightly], got %v", profiles)
+	}
+
+	if _, err := Load(path, "weekly"); err == nil {
+		t.Error("expected an unknown profile to fail")
+	}
+}
+
+func TestValidateProfileTuning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordfence-cli.ini")
+	content := `[profile:my-nas]
+workers = 2
+max-read-rate = 40.5
+memory_limit = 256
+file-delay = 1m
+read-latency-target = 50ms
+max-read-memory = 100000
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flags := pflag.NewFlagSet("malware-scan", pflag.ContinueOnError)
+	flags.Int("workers", 0, "")
+	flags.Float64("max-read-rate", 0, "")
+	flags.Int64("memory-limit", 0, "")
+	flags.Duration("file-delay", 0, "")
+	flags.Duration("read-latency-target", 0, "")
+	flags.Int64("max-read-memory", 0, "")
+
+	issues, err := ValidateFile(path, CommandFlags{"malware-scan": flags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Line != 5 || issues[1].Line != 7 {
+		t.Fatalf("expected file-delay and max-read-memory out of range, got %v", issues)
+	}
+
+	for value, ok := range map[string]bool{"0": true, "20": true, "102400": true, "-1": false, "1e9": false} {
+		if err := CheckFlagSetting("max_read_rate", value); (err == nil) != ok {
+			t.Errorf("max-read-rate %s: got %v", value, err)
+		}
+	}
+	if err := CheckFlagSetting("file-delay", "-5ms"); err == nil {
+		t.Error("expected a negative delay to be out of range")
+	}
+	if err := CheckFlagSetting("output", "anything"); err != nil {
+		t.Errorf("expected settings without a range to pass, got %v", err)
+	}
+}
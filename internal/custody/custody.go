@@ -0,0 +1,299 @@
+// Package custody seals result files for chain of custody: encrypting them
+// to recipients' age or PGP keys and signing them with the operator's SSH
+// or GnuPG key, through the age, gpg and ssh-keygen command-line tools
+package custody
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Command-line tools used to encrypt and sign, which keep the binary free
+// of age and OpenPGP implementations
+const (
+	DefaultAge       = "age"
+	DefaultGPG       = "gpg"
+	DefaultSSHKeygen = "ssh-keygen"
+)
+
+// SignatureNamespace is the ssh-keygen -Y namespace of SSH signatures,
+// which keeps them from being replayed as signatures of anything else
+const SignatureNamespace = "wordfence-evidence"
+
+// pgpKeyHeader starts an armored PGP public key
+const pgpKeyHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// Sealer encrypts and signs result files
+type Sealer struct {
+	recipients []string
+	// pgp is set when the recipients are PGP keys rather than age ones
+	pgp        bool
+	signingKey string
+	age        string
+	gpg        string
+	sshKeygen  string
+}
+
+// Option configures a Sealer
+type Option func(*Sealer)
+
+// WithAgeBinary sets the age binary
+func WithAgeBinary(binary string) Option {
+	return func(s *Sealer) {
+		s.age = binary
+	}
+}
+
+// WithGPGBinary sets the gpg binary
+func WithGPGBinary(binary string) Option {
+	return func(s *Sealer) {
+		s.gpg = binary
+	}
+}
+
+// WithSSHKeygenBinary sets the ssh-keygen binary
+func WithSSHKeygenBinary(binary string) Option {
+	return func(s *Sealer) {
+		s.sshKeygen = binary
+	}
+}
+
+// Sealed is a sealed result file
+type Sealed struct {
+	// Path is the encrypted file, or the original if nothing was encrypted
+	Path string
+	// Signature is the detached signature of Path, or "" if unsigned
+	Signature string
+	// SHA256 is the hex digest of Path, for custody records
+	SHA256 string
+}
+
+// NewSealer returns a sealer encrypting to recipients and signing with
+// signingKey, or nil if there are neither.
+//
+// Recipients are age recipients (age1... or ssh-...), PGP key IDs,
+// fingerprints or email addresses in the gpg keyring, or files of
+// recipients: an armored PGP public key, or age recipients one a line.
+// age and PGP recipients cannot be mixed. The signing key is an SSH
+// private key file, or otherwise a gpg key ID.
+func NewSealer(recipients []string, signingKey string, opts ...Option) (*Sealer, error) {
+	if len(recipients) == 0 && signingKey == "" {
+		return nil, nil
+	}
+	s := &Sealer{
+		recipients: recipients,
+		signingKey: signingKey,
+		age:        DefaultAge,
+		gpg:        DefaultGPG,
+		sshKeygen:  DefaultSSHKeygen,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var ages, pgps int
+	for _, r := range recipients {
+		if r == "" {
+			return nil, errors.New("empty encryption recipient")
+		}
+		if isPGPRecipient(r) {
+			pgps++
+		} else {
+			ages++
+		}
+	}
+	if ages > 0 && pgps > 0 {
+		return nil, errors.New("cannot encrypt to both age and PGP recipients")
+	}
+	s.pgp = pgps > 0
+
+	// Fail before the results are written rather than after
+	for _, tool := range s.tools() {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, fmt.Errorf("%s is needed to seal results: %w", tool, err)
+		}
+	}
+	return s, nil
+}
+
+// tools are the binaries the sealer runs
+func (s *Sealer) tools() []string {
+	var tools []string
+	switch {
+	case s.pgp:
+		tools = append(tools, s.gpg)
+	case len(s.recipients) > 0:
+		tools = append(tools, s.age)
+	}
+	switch {
+	case s.signingKey == "":
+	case isFile(s.signingKey):
+		tools = append(tools, s.sshKeygen)
+	case !s.pgp:
+		tools = append(tools, s.gpg)
+	}
+	return tools
+}
+
+// isPGPRecipient reports whether r is a PGP key rather than an age one
+func isPGPRecipient(r string) bool {
+	if strings.HasPrefix(r, "age1") || strings.HasPrefix(r, "ssh-") {
+		return false
+	}
+	if isFile(r) {
+		return fileStartsWith(r, pgpKeyHeader)
+	}
+	return true
+}
+
+// Encrypts reports whether the sealer encrypts
+func (s *Sealer) Encrypts() bool {
+	return s != nil && len(s.recipients) > 0
+}
+
+// Extension is the extension added to encrypted files
+func (s *Sealer) Extension() string {
+	switch {
+	case !s.Encrypts():
+		return ""
+	case s.pgp:
+		return ".gpg"
+	default:
+		return ".age"
+	}
+}
+
+// SignatureExtension is the extension of signature files
+func (s *Sealer) SignatureExtension() string {
+	switch {
+	case s == nil || s.signingKey == "":
+		return ""
+	case isFile(s.signingKey):
+		return ".sig"
+	default:
+		return ".asc"
+	}
+}
+
+// Seal encrypts the file at path, removing the plaintext, and signs what
+// is left. A file that fails to encrypt is left as it was.
+func (s *Sealer) Seal(ctx context.Context, path string) (*Sealed, error) {
+	sealed := &Sealed{Path: path}
+	if s.Encrypts() {
+		sealed.Path = path + s.Extension()
+		if err := s.encrypt(ctx, path, sealed.Path); err != nil {
+			_ = os.Remove(sealed.Path)
+			return nil, err
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing unencrypted results: %w", err)
+		}
+	}
+	if s.signingKey != "" {
+		sealed.Signature = sealed.Path + s.SignatureExtension()
+		if err := s.sign(ctx, sealed.Path, sealed.Signature); err != nil {
+			return nil, err
+		}
+	}
+	sum, err := hashFile(sealed.Path)
+	if err != nil {
+		return nil, err
+	}
+	sealed.SHA256 = sum
+	return sealed, nil
+}
+
+// encrypt encrypts in to out
+func (s *Sealer) encrypt(ctx context.Context, in, out string) error {
+	if s.pgp {
+		// The recipients were named by the operator, so keys that are not
+		// certified in the keyring are used rather than refused
+		args := []string{"--batch", "--yes", "--trust-model", "always", "--encrypt"}
+		for _, r := range s.recipients {
+			if isFile(r) {
+				args = append(args, "--recipient-file", r)
+			} else {
+				args = append(args, "--recipient", r)
+			}
+		}
+		return run(ctx, s.gpg, append(args, "--output", out, in)...)
+	}
+
+	args := []string{"--encrypt"}
+	for _, r := range s.recipients {
+		if isFile(r) {
+			args = append(args, "--recipients-file", r)
+		} else {
+			args = append(args, "--recipient", r)
+		}
+	}
+	return run(ctx, s.age, append(args, "--output", out, in)...)
+}
+
+// sign writes the detached signature of path to sig
+func (s *Sealer) sign(ctx context.Context, path, sig string) error {
+	if isFile(s.signingKey) {
+		// ssh-keygen always writes the signature beside the file, and asks
+		// before replacing one
+		if err := os.Remove(sig); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing old signature: %w", err)
+		}
+		return run(ctx, s.sshKeygen, "-Y", "sign", "-f", s.signingKey, "-n", SignatureNamespace, path)
+	}
+	return run(ctx, s.gpg, "--batch", "--yes", "--armor", "--detach-sign",
+		"--local-user", s.signingKey, "--output", sig, path)
+}
+
+// run runs a tool, returning what it printed to stderr if it fails
+func run(ctx context.Context, binary string, args ...string) error {
+	cmd := exec.CommandContext(ctx, binary, args...) // #nosec G204 -- fixed tool binary, arguments passed separately
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("%s failed: %s", binary, msg)
+	}
+	return nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// fileStartsWith reports whether the file at path starts with prefix,
+// ignoring leading whitespace
+func fileStartsWith(path, prefix string) bool {
+	file, err := os.Open(path) // #nosec G304 -- recipient file named by the operator
+	if err != nil {
+		return false
+	}
+	defer func() { _ = file.Close() }()
+	head := make([]byte, 1024)
+	n, _ := io.ReadFull(file, head)
+	return bytes.HasPrefix(bytes.TrimSpace(head[:n]), []byte(prefix))
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path) // #nosec G304 -- sealed results file
+	if err != nil {
+		return "", fmt.Errorf("opening sealed results: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("hashing sealed results: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
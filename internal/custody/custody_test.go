@@ -0,0 +1,168 @@
+package custody
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeTool writes a script standing in for a tool: it logs its arguments
+// and writes "sealed" to its --output file, or beside its last argument
+// as ssh-keygen does
+func fakeTool(t *testing.T, dir, name string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	script := `#!/bin/sh
+echo "` + name + ` $*" >> "` + filepath.Join(dir, "calls") + `"
+out=""
+prev=""
+for a in "$@"; do
+  [ "$prev" = "--output" ] && out="$a"
+  prev="$a"
+done
+[ -n "$out" ] || out="$prev.sig"
+echo sealed > "$out"
+`
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil { // #nosec G306 -- test executable
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSealAge(t *testing.T) {
+	dir := t.TempDir()
+	recipients := filepath.Join(dir, "recipients.txt")
+	key := filepath.Join(dir, "id_ed25519")
+	results := filepath.Join(dir, "results.json")
+	for path, content := range map[string]string{recipients: "age1other\n", key: "key", results: "[]"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewSealer([]string{"age1abc", recipients}, key,
+		WithAgeBinary(fakeTool(t, dir, "age")), WithSSHKeygenBinary(fakeTool(t, dir, "ssh-keygen")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Extension() != ".age" || s.SignatureExtension() != ".sig" {
+		t.Errorf("unexpected extensions %q and %q", s.Extension(), s.SignatureExtension())
+	}
+	sealed, err := s.Seal(context.Background(), results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.Path != results+".age" || sealed.Signature != results+".age.sig" || len(sealed.SHA256) != 64 {
+		t.Errorf("unexpected sealed file %+v", sealed)
+	}
+	if _, err := os.Stat(results); !os.IsNotExist(err) {
+		t.Error("expected the unencrypted results to be removed")
+	}
+
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	want := "age --encrypt --recipient age1abc --recipients-file " + recipients + " --output " + sealed.Path + " " + results + "\n" +
+		"ssh-keygen -Y sign -f " + key + " -n " + SignatureNamespace + " " + sealed.Path + "\n"
+	if string(calls) != want {
+		t.Errorf("unexpected calls:\n%s\nwant:\n%s", calls, want)
+	}
+}
+
+func TestSealPGP(t *testing.T) {
+	dir := t.TempDir()
+	pubkey := filepath.Join(dir, "ir-team.asc")
+	results := filepath.Join(dir, "results.csv")
+	for path, content := range map[string]string{pubkey: "\n" + pgpKeyHeader + "\n...", results: "a,b"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewSealer([]string{"soc@example.com", pubkey}, "ABCD1234", WithGPGBinary(fakeTool(t, dir, "gpg")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.Seal(context.Background(), results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.Path != results+".gpg" || sealed.Signature != results+".gpg.asc" {
+		t.Errorf("unexpected sealed file %+v", sealed)
+	}
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	for _, want := range []string{
+		"--encrypt --recipient soc@example.com --recipient-file " + pubkey,
+		"--detach-sign --local-user ABCD1234 --output " + sealed.Signature,
+	} {
+		if !strings.Contains(string(calls), want) {
+			t.Errorf("expected %q in calls:\n%s", want, calls)
+		}
+	}
+}
+
+func TestSealSignOnly(t *testing.T) {
+	sshKeygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	dir := t.TempDir()
+	key := filepath.Join(dir, "id_ed25519")
+	if out, err := exec.Command(sshKeygen, "-q", "-t", "ed25519", "-N", "", "-C", "operator", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("generating key: %v: %s", err, out)
+	}
+	results := filepath.Join(dir, "results.json")
+	if err := os.WriteFile(results, []byte(`{"results": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSealer(nil, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.Seal(context.Background(), results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.Path != results || sealed.Signature != results+".sig" {
+		t.Fatalf("unexpected sealed file %+v", sealed)
+	}
+
+	pub, err := os.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := filepath.Join(dir, "allowed_signers")
+	if err := os.WriteFile(signers, append([]byte("operator "), pub...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	verify := exec.Command(sshKeygen, "-Y", "verify", "-f", signers, "-I", "operator", "-n", SignatureNamespace, "-s", sealed.Signature) // #nosec G204 -- test
+	verify.Stdin, _ = os.Open(results)
+	if out, err := verify.CombinedOutput(); err != nil {
+		t.Errorf("signature did not verify: %v: %s", err, out)
+	}
+}
+
+func TestNewSealer(t *testing.T) {
+	if s, err := NewSealer(nil, ""); s != nil || err != nil {
+		t.Errorf("expected no sealer, got %v (%v)", s, err)
+	}
+	if s, _ := NewSealer(nil, ""); s.Encrypts() || s.Extension() != "" || s.SignatureExtension() != "" {
+		t.Error("expected a nil sealer to do nothing")
+	}
+	if _, err := NewSealer([]string{"age1abc", "soc@example.com"}, ""); err == nil {
+		t.Error("expected an error mixing age and PGP recipients")
+	}
+	if _, err := NewSealer([]string{""}, ""); err == nil {
+		t.Error("expected an error for an empty recipient")
+	}
+	_, err := NewSealer([]string{"age1abc"}, "", WithAgeBinary(filepath.Join(t.TempDir(), "age")))
+	if err == nil || !strings.Contains(err.Error(), "needed to seal results") {
+		t.Errorf("expected an error for a missing age binary, got %v", err)
+	}
+}
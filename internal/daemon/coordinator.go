@@ -0,0 +1,412 @@
+// Package daemon provides a coordinator that splits a scan into shards and
+// runs them as jobs on remote daemons
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+// Defaults for the coordinator
+const (
+	DefaultPollInterval    = 2 * time.Second
+	DefaultMaxPollFailures = 3
+)
+
+// errWorkerLost marks failures that take a worker out of the scan
+var errWorkerLost = errors.New("worker unreachable")
+
+// WorkerAddress adds the default port to a worker given as a bare host
+func WorkerAddress(worker string) string {
+	if _, _, err := net.SplitHostPort(worker); err == nil {
+		return worker
+	}
+	return net.JoinHostPort(worker, strconv.Itoa(DefaultPort))
+}
+
+// WorkerStatus is a worker's progress through a coordinated scan
+type WorkerStatus struct {
+	Address      string `json:"address"`
+	Alive        bool   `json:"alive"`
+	Current      string `json:"current,omitempty"`
+	ShardsDone   int    `json:"shards_done"`
+	FilesScanned int64  `json:"files_scanned"`
+	FilesMatched int64  `json:"files_matched"`
+	FilesErrored int64  `json:"files_errored"`
+	Error        string `json:"error,omitempty"`
+}
+
+// CoordinatorSummary is the outcome of a coordinated scan
+type CoordinatorSummary struct {
+	Started      time.Time      `json:"started"`
+	Finished     time.Time      `json:"finished"`
+	Shards       int            `json:"shards"`
+	FilesScanned int64          `json:"files_scanned"`
+	FilesMatched int64          `json:"files_matched"`
+	FilesErrored int64          `json:"files_errored"`
+	Failed       []string       `json:"failed,omitempty"`
+	Workers      []WorkerStatus `json:"workers"`
+}
+
+// shardTask is one shard of one path, run as a job on a worker
+type shardTask struct {
+	path     string
+	shard    scanner.Shard
+	failedOn map[string]bool
+	err      error
+}
+
+func (t *shardTask) String() string {
+	if !t.shard.Enabled() {
+		return t.path
+	}
+	return fmt.Sprintf("%s (shard %s)", t.path, t.shard)
+}
+
+// worker is a remote daemon taking part in a coordinated scan
+type worker struct {
+	client *Client
+	status WorkerStatus
+	// current counts the running job's files, added to status when it
+	// finishes
+	current WorkerStatus
+}
+
+// Coordinator splits a scan into shards and runs each as a job on one of
+// several remote daemons. Shards of a worker that stops responding, or
+// whose job fails, are queued again for the remaining workers. Workers
+// must see the scanned paths at the same locations, such as on shared
+// storage, for the shards to cover every file.
+type Coordinator struct {
+	workers         []*worker
+	shards          int
+	pollInterval    time.Duration
+	maxPollFailures int
+	tenant          string
+	priority        Priority
+	logger          *logging.Logger
+
+	mu      sync.Mutex
+	changed *sync.Cond
+	pending []*shardTask
+	running int
+	failed  []*shardTask
+
+	resultMu sync.Mutex
+}
+
+// CoordinatorOption configures a Coordinator
+type CoordinatorOption func(*Coordinator)
+
+// WithShards sets how many shards each path is split into (default: one
+// per worker). More shards than workers balances uneven workers and makes
+// re-running a failed worker's shard cheaper.
+func WithShards(n int) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.shards = n
+	}
+}
+
+// WithPollInterval sets how often workers are asked for their progress
+func WithPollInterval(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.pollInterval = d
+	}
+}
+
+// WithMaxPollFailures sets how many progress checks in a row may fail
+// before a worker is given up on
+func WithMaxPollFailures(n int) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.maxPollFailures = n
+	}
+}
+
+// WithJobTenant sets the tenant the workers count the jobs against
+func WithJobTenant(tenant string) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.tenant = tenant
+	}
+}
+
+// WithJobPriority sets the priority of the workers' jobs
+func WithJobPriority(priority Priority) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.priority = priority
+	}
+}
+
+// WithCoordinatorLogger sets the logger
+func WithCoordinatorLogger(logger *logging.Logger) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.logger = logger
+	}
+}
+
+// NewCoordinator creates a coordinator for the daemons reached by clients
+func NewCoordinator(clients []*Client, opts ...CoordinatorOption) *Coordinator {
+	c := &Coordinator{
+		pollInterval:    DefaultPollInterval,
+		maxPollFailures: DefaultMaxPollFailures,
+		priority:        PriorityNormal,
+		logger:          logging.New(logging.LevelInfo),
+	}
+	c.changed = sync.NewCond(&c.mu)
+	for _, client := range clients {
+		c.workers = append(c.workers, &worker{
+			client: client,
+			status: WorkerStatus{Address: client.address, Alive: true},
+		})
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.shards <= 0 {
+		c.shards = len(c.workers)
+	}
+	if c.maxPollFailures <= 0 {
+		c.maxPollFailures = DefaultMaxPollFailures
+	}
+	return c
+}
+
+// Run scans paths across the workers, calling onResult with each matched
+// or failed file as the shards finish. onResult is not called
+// concurrently. Run returns an error if a shard failed on every worker
+// or ctx was cancelled; jobs already running on workers are not stopped.
+func (c *Coordinator) Run(ctx context.Context, paths []string, onResult func(*ScanResponse)) (*CoordinatorSummary, error) {
+	if len(c.workers) == 0 {
+		return nil, fmt.Errorf("no workers")
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths to scan")
+	}
+
+	c.mu.Lock()
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("resolving path: %w", err)
+		}
+		for i := 1; i <= c.shards; i++ {
+			c.pending = append(c.pending, &shardTask{
+				path:     absPath,
+				shard:    scanner.Shard{Index: i, Count: c.shards},
+				failedOn: make(map[string]bool),
+			})
+		}
+	}
+	c.mu.Unlock()
+
+	// Wake idle workers so they notice the cancellation
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.changed.Broadcast()
+		c.mu.Unlock()
+	})
+	defer stop()
+
+	summary := &CoordinatorSummary{Started: time.Now().UTC(), Shards: c.shards}
+	var wg sync.WaitGroup
+	for _, w := range c.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.work(ctx, w, onResult)
+		}()
+	}
+	wg.Wait()
+	summary.Finished = time.Now().UTC()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range c.workers {
+		summary.FilesScanned += w.status.FilesScanned
+		summary.FilesMatched += w.status.FilesMatched
+		summary.FilesErrored += w.status.FilesErrored
+		summary.Workers = append(summary.Workers, w.status)
+	}
+	for _, t := range c.failed {
+		summary.Failed = append(summary.Failed, t.String())
+	}
+
+	if ctx.Err() != nil {
+		return summary, fmt.Errorf("coordinated scan interrupted: %w", ctx.Err())
+	}
+	if len(c.failed) > 0 {
+		return summary, fmt.Errorf("%d shards failed on every worker, the first with: %w", len(c.failed), c.failed[0].err)
+	}
+	return summary, nil
+}
+
+// Progress returns the workers' progress, including running jobs
+func (c *Coordinator) Progress() []WorkerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]WorkerStatus, 0, len(c.workers))
+	for _, w := range c.workers {
+		status := w.status
+		status.Current = w.current.Current
+		status.FilesScanned += w.current.FilesScanned
+		status.FilesMatched += w.current.FilesMatched
+		status.FilesErrored += w.current.FilesErrored
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// work runs shards on w until none are left that it can take
+func (c *Coordinator) work(ctx context.Context, w *worker, onResult func(*ScanResponse)) {
+	for {
+		task := c.take(ctx, w)
+		if task == nil {
+			return
+		}
+
+		job, err := c.runTask(ctx, w, task)
+
+		c.mu.Lock()
+		c.running--
+		w.current = WorkerStatus{}
+		if err == nil {
+			w.status.ShardsDone++
+			w.status.FilesScanned += job.FilesScanned
+			w.status.FilesMatched += job.FilesMatched
+			w.status.FilesErrored += job.FilesErrored
+			c.changed.Broadcast()
+			c.mu.Unlock()
+
+			c.resultMu.Lock()
+			for _, resp := range job.Results {
+				onResult(resp)
+			}
+			c.resultMu.Unlock()
+			continue
+		}
+		if ctx.Err() != nil {
+			c.changed.Broadcast()
+			c.mu.Unlock()
+			return
+		}
+
+		lost := errors.Is(err, errWorkerLost)
+		if lost {
+			w.status.Alive = false
+			w.status.Error = err.Error()
+			c.logger.Warning("Worker %s failed, re-queueing %s: %v", w.status.Address, task, err)
+		} else {
+			c.logger.Warning("Shard %s failed on %s, re-queueing: %v", task, w.status.Address, err)
+		}
+		task.failedOn[w.status.Address] = true
+		task.err = err
+		c.pending = append(c.pending, task)
+		c.sweep()
+		c.changed.Broadcast()
+		c.mu.Unlock()
+
+		if lost {
+			return
+		}
+	}
+}
+
+// take waits for a pending shard that w has not failed. It returns nil
+// once there are none and no running shard can be re-queued.
+func (c *Coordinator) take(ctx context.Context, w *worker) *shardTask {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for ctx.Err() == nil {
+		for i, task := range c.pending {
+			if !task.failedOn[w.status.Address] {
+				c.pending = append(c.pending[:i], c.pending[i+1:]...)
+				c.running++
+				w.current = WorkerStatus{Current: task.String()}
+				return task
+			}
+		}
+		if c.running == 0 {
+			return nil
+		}
+		c.changed.Wait()
+	}
+	return nil
+}
+
+// sweep moves pending shards that every live worker has failed to the
+// failed list. Callers hold c.mu.
+func (c *Coordinator) sweep() {
+	pending := c.pending[:0]
+	for _, task := range c.pending {
+		runnable := false
+		for _, w := range c.workers {
+			if w.status.Alive && !task.failedOn[w.status.Address] {
+				runnable = true
+				break
+			}
+		}
+		if runnable {
+			pending = append(pending, task)
+		} else {
+			c.failed = append(c.failed, task)
+		}
+	}
+	c.pending = pending
+}
+
+// runTask submits a shard to w and polls until its job finishes
+func (c *Coordinator) runTask(ctx context.Context, w *worker, task *shardTask) (*Job, error) {
+	job, err := w.client.SubmitShardJob(ctx, task.path, c.tenant, c.priority, task.shard)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errWorkerLost, err)
+	}
+	c.logger.Verbose("Started %s on %s as job %s", task, w.status.Address, job.ID)
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for job %s: %w", job.ID, ctx.Err())
+		case <-ticker.C:
+		}
+
+		status, err := w.client.JobStatus(ctx, job.ID)
+		if err != nil {
+			failures++
+			if failures >= c.maxPollFailures {
+				return nil, fmt.Errorf("%w: %w", errWorkerLost, err)
+			}
+			c.logger.Debug("Checking job %s on %s failed: %v", job.ID, w.status.Address, err)
+			continue
+		}
+		failures = 0
+
+		c.mu.Lock()
+		w.current.FilesScanned = status.FilesScanned
+		w.current.FilesMatched = status.FilesMatched
+		w.current.FilesErrored = status.FilesErrored
+		c.mu.Unlock()
+
+		switch status.State {
+		case JobDone:
+			c.logger.Verbose("Finished %s on %s: %d files scanned, %d matched", task, w.status.Address, status.FilesScanned, status.FilesMatched)
+			return status, nil
+		case JobFailed:
+			return nil, fmt.Errorf("job %s failed: %s", job.ID, status.Error)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+// startTestWorker starts a daemon accepting jobs on a local TCP port
+func startTestWorker(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	startTestServer(t, WithListener(listener), WithJobQueue(openTestQueue(t, t.TempDir())))
+	return addr
+}
+
+func newTestCoordinator(addrs []string, opts ...CoordinatorOption) *Coordinator {
+	clients := make([]*Client, 0, len(addrs))
+	for _, addr := range addrs {
+		clients = append(clients, NewRemoteClient(addr, nil, WithDialTimeout(time.Second)))
+	}
+	opts = append([]CoordinatorOption{
+		WithPollInterval(10 * time.Millisecond),
+		WithMaxPollFailures(1),
+		WithCoordinatorLogger(logging.New(logging.LevelCritical)),
+	}, opts...)
+	return NewCoordinator(clients, opts...)
+}
+
+func writeTestSite(t *testing.T, files int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i < files; i++ {
+		content := "<?php echo 'hello';"
+		if i%5 == 0 {
+			content = "<?php eval($_POST['x']);"
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.php", i)), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestWorkerAddress(t *testing.T) {
+	if addr := WorkerAddress("scan1"); addr != fmt.Sprintf("scan1:%d", DefaultPort) {
+		t.Errorf("expected default port, got %s", addr)
+	}
+	if addr := WorkerAddress("10.0.0.2:9000"); addr != "10.0.0.2:9000" {
+		t.Errorf("expected address kept, got %s", addr)
+	}
+}
+
+func TestCoordinatorMergesShards(t *testing.T) {
+	site := writeTestSite(t, 20)
+	c := newTestCoordinator([]string{startTestWorker(t), startTestWorker(t)}, WithShards(4))
+
+	matched := make(map[string]int)
+	summary, err := c.Run(context.Background(), []string{site}, func(resp *ScanResponse) {
+		matched[resp.Path]++
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.FilesScanned != 20 || summary.FilesMatched != 4 || len(matched) != 4 {
+		t.Errorf("expected 20 files scanned and 4 matched, got %+v and %v", summary, matched)
+	}
+	for path, n := range matched {
+		if n != 1 {
+			t.Errorf("expected %s reported once, got %d", path, n)
+		}
+	}
+	shards := 0
+	for _, w := range summary.Workers {
+		shards += w.ShardsDone
+	}
+	if shards != 4 {
+		t.Errorf("expected 4 shards done, got %d", shards)
+	}
+}
+
+func TestCoordinatorRequeuesFailedWorker(t *testing.T) {
+	// Nothing listens on a closed listener's port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	dead := listener.Addr().String()
+	_ = listener.Close()
+
+	site := writeTestSite(t, 10)
+	c := newTestCoordinator([]string{dead, startTestWorker(t)})
+
+	summary, err := c.Run(context.Background(), []string{site}, func(*ScanResponse) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.FilesScanned != 10 {
+		t.Errorf("expected every file scanned by the live worker, got %d", summary.FilesScanned)
+	}
+	if w := summary.Workers[0]; w.Alive || w.Error == "" || w.ShardsDone != 0 {
+		t.Errorf("expected dead worker to be dropped, got %+v", w)
+	}
+	if w := summary.Workers[1]; w.ShardsDone != 2 {
+		t.Errorf("expected live worker to run both shards, got %+v", w)
+	}
+}
+
+func TestCoordinatorFailsWithoutWorkers(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	dead := listener.Addr().String()
+	_ = listener.Close()
+
+	summary, err := newTestCoordinator([]string{dead}).Run(context.Background(), []string{t.TempDir()}, func(*ScanResponse) {})
+	if err == nil || len(summary.Failed) != 1 {
+		t.Errorf("expected the shard to fail, got %+v, %v", summary, err)
+	}
+}
@@ -0,0 +1,568 @@
+// Package daemon provides a long-lived scan server that keeps compiled
+// signatures in memory and answers single-file scan requests over a unix
+// socket. Callers can also queue prioritized scan jobs and query their
+// status, locally or, from a coordinator, over TLS.
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+// DefaultDialTimeout is how long a client waits to connect to the daemon
+const DefaultDialTimeout = 100 * time.Millisecond
+
+// DefaultRemoteDialTimeout is how long a client waits to connect to a
+// daemon over the network
+const DefaultRemoteDialTimeout = 5 * time.Second
+
+// DefaultPort is the TCP port daemons listen on for remote coordinators
+const DefaultPort = 7390
+
+// DefaultRequestTimeout bounds a single scan request
+const DefaultRequestTimeout = 30 * time.Second
+
+// maxRequestSize bounds a request line to keep misbehaving clients cheap
+const maxRequestSize = 64 * 1024
+
+// DefaultSocketPath returns the default daemon socket path
+func DefaultSocketPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "wordfence", "wordfence.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("wordfence-%d", os.Getuid()), "wordfence.sock")
+}
+
+// Request operations
+const (
+	// OpScan scans a single file and waits for the result (the default)
+	OpScan = "scan"
+	// OpSubmit queues a scan job for a file or directory
+	OpSubmit = "submit"
+	// OpStatus reports a job's state and results
+	OpStatus = "status"
+	// OpList lists a tenant's jobs, or all jobs
+	OpList = "list"
+)
+
+// ScanRequest asks the daemon to scan a file or manage scan jobs
+type ScanRequest struct {
+	Op       string   `json:"op,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	Priority Priority `json:"priority,omitempty"`
+	Tenant   string   `json:"tenant,omitempty"`
+	JobID    string   `json:"job_id,omitempty"`
+	Shard    string   `json:"shard,omitempty"`
+}
+
+// ScanMatch is a single signature match in a ScanResponse
+type ScanMatch struct {
+	SignatureID          int    `json:"signature_id"`
+	SignatureName        string `json:"signature_name"`
+	SignatureDescription string `json:"signature_description"`
+	SignatureCategory    string `json:"signature_category,omitempty"`
+	MatchedText          string `json:"matched_text"`
+}
+
+// ScanResponse is the daemon's answer to a ScanRequest
+type ScanResponse struct {
+	Path    string      `json:"path"`
+	Matches []ScanMatch `json:"matches"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// HasMatches returns true if the scanned file matched any signature
+func (r *ScanResponse) HasMatches() bool {
+	return len(r.Matches) > 0
+}
+
+// JobResponse is the daemon's answer to a job request
+type JobResponse struct {
+	Job   *Job   `json:"job,omitempty"`
+	Jobs  []*Job `json:"jobs,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewScanResponse converts a scan result into a ScanResponse
+func NewScanResponse(result *scanner.ScanResult, sigSet *intel.SignatureSet) *ScanResponse {
+	resp := &ScanResponse{
+		Path:    result.Path,
+		Matches: make([]ScanMatch, 0, len(result.Matches)),
+	}
+	if result.Error != nil {
+		resp.Error = result.Error.Error()
+	}
+	for _, match := range result.Matches {
+		m := ScanMatch{
+			SignatureID:       match.SignatureID,
+			SignatureCategory: match.Category,
+			MatchedText:       match.MatchedString,
+		}
+		m.SignatureName, m.SignatureDescription = match.Describe(sigSet)
+		resp.Matches = append(resp.Matches, m)
+	}
+	return resp
+}
+
+// Server answers scan requests using a shared, pre-compiled scanner
+type Server struct {
+	scanner    *scanner.Scanner
+	socketPath string
+	socketMode os.FileMode
+	logger     *logging.Logger
+	jobs       *JobQueue
+	listeners  []net.Listener
+	wg         sync.WaitGroup
+
+	// health answers health probes over HTTP (nil = none)
+	health net.Listener
+	// vulnFeed returns when the vulnerability feed was cached (nil = not
+	// reported)
+	vulnFeed func() time.Time
+	// maxFeedAge is how old feeds get before they are stale (0 = never)
+	maxFeedAge time.Duration
+	started    time.Time
+	// lastScan is when a single-file scan last succeeded, in Unix
+	// nanoseconds
+	lastScan atomic.Int64
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithSocketMode sets the permissions of the socket file
+func WithSocketMode(mode os.FileMode) ServerOption {
+	return func(s *Server) {
+		s.socketMode = mode
+	}
+}
+
+// WithJobQueue enables scan jobs, run from q while the server is serving
+func WithJobQueue(q *JobQueue) ServerOption {
+	return func(s *Server) {
+		s.jobs = q
+	}
+}
+
+// WithListener also serves connections accepted by l, such as a TLS
+// listener for remote coordinators. Serve closes it when it returns.
+func WithListener(l net.Listener) ServerOption {
+	return func(s *Server) {
+		s.listeners = append(s.listeners, l)
+	}
+}
+
+// WithHealthListener answers /healthz and /readyz probes over HTTP on l.
+// Serve closes it when it returns.
+func WithHealthListener(l net.Listener) ServerOption {
+	return func(s *Server) {
+		s.health = l
+	}
+}
+
+// WithVulnerabilityFeed reports the age of the vulnerability feed, from
+// when updated returns it was cached (the zero time if it isn't)
+func WithVulnerabilityFeed(updated func() time.Time) ServerOption {
+	return func(s *Server) {
+		s.vulnFeed = updated
+	}
+}
+
+// WithMaxFeedAge sets how long signatures may go unchecked before the
+// daemon is unavailable, and the vulnerability feed uncached before it is
+// degraded (0 never)
+func WithMaxFeedAge(age time.Duration) ServerOption {
+	return func(s *Server) {
+		s.maxFeedAge = age
+	}
+}
+
+// WithServerLogger sets the logger
+func WithServerLogger(logger *logging.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// NewServer creates a new scan server. Signature names are taken from the
+// scanner's current signature set, so reloads are picked up automatically.
+func NewServer(s *scanner.Scanner, socketPath string, opts ...ServerOption) *Server {
+	srv := &Server{
+		scanner:    s,
+		socketPath: socketPath,
+		socketMode: 0660,
+		logger:     logging.New(logging.LevelInfo),
+		started:    time.Now(),
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
+
+// Serve listens on the socket and handles requests until ctx is cancelled
+func (srv *Server) Serve(ctx context.Context) error {
+	defer func() {
+		for _, l := range srv.listeners {
+			_ = l.Close()
+		}
+		if srv.health != nil {
+			_ = srv.health.Close()
+		}
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(srv.socketPath), 0750); err != nil {
+		return fmt.Errorf("creating socket directory: %w", err)
+	}
+
+	// Remove a stale socket left behind by a previous daemon
+	if err := os.Remove(srv.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale socket: %w", err)
+	}
+
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "unix", srv.socketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", srv.socketPath, err)
+	}
+	defer func() { _ = os.Remove(srv.socketPath) }()
+
+	if err := os.Chmod(srv.socketPath, srv.socketMode); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("setting socket permissions: %w", err)
+	}
+
+	var health *http.Server
+	if srv.health != nil {
+		health = srv.serveHealth()
+	}
+
+	listeners := append([]net.Listener{listener}, srv.listeners...)
+	go func() {
+		<-ctx.Done()
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+		if health != nil {
+			_ = health.Close()
+		}
+	}()
+
+	if srv.jobs != nil {
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			srv.jobs.Run(ctx, srv.scanner)
+		}()
+	}
+
+	var accepting sync.WaitGroup
+	for _, l := range listeners {
+		srv.logger.Info("Listening on %s", l.Addr())
+		accepting.Add(1)
+		go func() {
+			defer accepting.Done()
+			srv.accept(ctx, l)
+		}()
+	}
+	accepting.Wait()
+	srv.wg.Wait()
+	return nil
+}
+
+// accept handles connections on l until it is closed
+func (srv *Server) accept(ctx context.Context, l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			srv.logger.Warning("Accept failed: %v", err)
+			continue
+		}
+
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			srv.handle(ctx, conn)
+		}()
+	}
+}
+
+// handle serves a single connection: one request line, one response line
+func (srv *Server) handle(ctx context.Context, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(DefaultRequestTimeout))
+
+	reader := bufio.NewReader(&limitedConn{conn: conn, remaining: maxRequestSize})
+	line, err := reader.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		srv.logger.Debug("Reading request failed: %v", err)
+		return
+	}
+
+	var resp any
+	var req ScanRequest
+	switch {
+	case json.Unmarshal(line, &req) != nil:
+		resp = &ScanResponse{Error: "malformed request"}
+	case req.Op == OpSubmit || req.Op == OpStatus || req.Op == OpList:
+		resp = srv.handleJob(&req)
+	case req.Op != "" && req.Op != OpScan:
+		resp = &ScanResponse{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	case !filepath.IsAbs(req.Path):
+		resp = &ScanResponse{Path: req.Path, Error: "path must be absolute"}
+	default:
+		resp = srv.scanFile(ctx, req.Path)
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		srv.logger.Warning("Encoding response failed: %v", err)
+		return
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		srv.logger.Debug("Writing response failed: %v", err)
+	}
+}
+
+// scanFile answers a single-file scan, pausing lower-priority jobs so
+// upload checks are not slowed by background scans
+func (srv *Server) scanFile(ctx context.Context, path string) *ScanResponse {
+	if srv.jobs != nil {
+		release := srv.jobs.preempt(PriorityHigh)
+		defer release()
+	}
+
+	scanCtx, cancel := context.WithTimeout(ctx, DefaultRequestTimeout)
+	defer cancel()
+	result := srv.scanner.ScanSingleFile(scanCtx, path)
+	if result.Error == nil {
+		srv.lastScan.Store(time.Now().UnixNano())
+	}
+	resp := NewScanResponse(result, srv.scanner.SignatureSet())
+	srv.logger.Debug("Scanned %s: %d matches", path, len(resp.Matches))
+	return resp
+}
+
+// handleJob answers a job request
+func (srv *Server) handleJob(req *ScanRequest) *JobResponse {
+	if srv.jobs == nil {
+		return &JobResponse{Error: "scan jobs are not enabled"}
+	}
+
+	switch req.Op {
+	case OpSubmit:
+		var shard scanner.Shard
+		if req.Shard != "" {
+			parsed, err := scanner.ParseShard(req.Shard)
+			if err != nil {
+				return &JobResponse{Error: err.Error()}
+			}
+			shard = parsed
+		}
+		job, err := srv.jobs.SubmitShard(req.Path, req.Tenant, req.Priority, shard)
+		if err != nil {
+			return &JobResponse{Error: err.Error()}
+		}
+		return &JobResponse{Job: job}
+	case OpStatus:
+		job, err := srv.jobs.Status(req.JobID)
+		if err != nil {
+			return &JobResponse{Error: err.Error()}
+		}
+		return &JobResponse{Job: job}
+	default:
+		return &JobResponse{Jobs: srv.jobs.List(req.Tenant)}
+	}
+}
+
+// limitedConn stops reading from a connection after a fixed number of bytes
+type limitedConn struct {
+	conn      net.Conn
+	remaining int
+}
+
+func (l *limitedConn) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, fmt.Errorf("request exceeds %d bytes", maxRequestSize)
+	}
+	if len(p) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.conn.Read(p)
+	l.remaining -= n
+	if err != nil {
+		return n, fmt.Errorf("reading request: %w", err)
+	}
+	return n, nil
+}
+
+// Client sends scan requests to a running daemon
+type Client struct {
+	network        string
+	address        string
+	tlsConfig      *tls.Config
+	dialTimeout    time.Duration
+	requestTimeout time.Duration
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithDialTimeout sets how long to wait when connecting
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.dialTimeout = timeout
+	}
+}
+
+// WithRequestTimeout sets how long to wait for a response
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.requestTimeout = timeout
+	}
+}
+
+// NewClient creates a client for the daemon listening on socketPath
+func NewClient(socketPath string, opts ...ClientOption) *Client {
+	c := &Client{
+		network:        "unix",
+		address:        socketPath,
+		dialTimeout:    DefaultDialTimeout,
+		requestTimeout: DefaultRequestTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewRemoteClient creates a client for the daemon listening on the TCP
+// address addr. With a nil tlsConfig the connection is not encrypted.
+func NewRemoteClient(addr string, tlsConfig *tls.Config, opts ...ClientOption) *Client {
+	c := &Client{
+		network:        "tcp",
+		address:        addr,
+		tlsConfig:      tlsConfig,
+		dialTimeout:    DefaultRemoteDialTimeout,
+		requestTimeout: DefaultRequestTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ScanFile asks the daemon to scan path
+func (c *Client) ScanFile(ctx context.Context, path string) (*ScanResponse, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving path: %w", err)
+	}
+
+	var resp ScanResponse
+	if err := c.roundTrip(ctx, &ScanRequest{Path: absPath}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SubmitJob queues a scan of path, a file or directory, for tenant
+func (c *Client) SubmitJob(ctx context.Context, path, tenant string, priority Priority) (*Job, error) {
+	return c.SubmitShardJob(ctx, path, tenant, priority, scanner.Shard{})
+}
+
+// SubmitShardJob queues a scan of the files of path that belong to shard
+func (c *Client) SubmitShardJob(ctx context.Context, path, tenant string, priority Priority, shard scanner.Shard) (*Job, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving path: %w", err)
+	}
+	req := &ScanRequest{Op: OpSubmit, Path: absPath, Tenant: tenant, Priority: priority}
+	if shard.Enabled() {
+		req.Shard = shard.String()
+	}
+	resp, err := c.jobRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Job, nil
+}
+
+// JobStatus returns a job's state and the results of its matched files
+func (c *Client) JobStatus(ctx context.Context, id string) (*Job, error) {
+	resp, err := c.jobRequest(ctx, &ScanRequest{Op: OpStatus, JobID: id})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Job, nil
+}
+
+// ListJobs returns tenant's jobs, or all jobs if tenant is ""
+func (c *Client) ListJobs(ctx context.Context, tenant string) ([]*Job, error) {
+	resp, err := c.jobRequest(ctx, &ScanRequest{Op: OpList, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Jobs, nil
+}
+
+// jobRequest sends a job request, returning the daemon's error as an error
+func (c *Client) jobRequest(ctx context.Context, req *ScanRequest) (*JobResponse, error) {
+	var resp JobResponse
+	if err := c.roundTrip(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// roundTrip sends one request line and decodes the response line
+func (c *Client) roundTrip(ctx context.Context, req *ScanRequest, resp any) error {
+	dialer := &net.Dialer{Timeout: c.dialTimeout}
+	var conn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: c.tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, c.network, c.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, c.network, c.address)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to daemon: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(c.requestTimeout))
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+
+	if err := json.NewDecoder(conn).Decode(resp); err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+func createTestSignatureSet() *intel.SignatureSet {
+	ss := intel.NewSignatureSet()
+	ss.Signatures[1] = intel.NewSignature(1, `eval\s*\(`, "Eval Pattern", "Detects eval() calls", nil)
+	return ss
+}
+
+// shortTempDir returns a temp dir short enough for a unix socket path
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "wfd")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func startTestServer(t *testing.T, opts ...ServerOption) string {
+	t.Helper()
+
+	sigSet := createTestSignatureSet()
+	socketPath := filepath.Join(shortTempDir(t), "wf.sock")
+	logger := logging.New(logging.LevelCritical)
+	opts = append([]ServerOption{WithServerLogger(logger)}, opts...)
+	srv := NewServer(scanner.NewScanner(sigSet), socketPath, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serve returned error: %v", err)
+		}
+	})
+
+	// Wait for the socket to appear
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(socketPath); err == nil {
+			return socketPath
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("daemon socket did not appear")
+	return ""
+}
+
+func TestClientServerScan(t *testing.T) {
+	socketPath := startTestServer(t)
+
+	dir := t.TempDir()
+	infected := filepath.Join(dir, "infected.php")
+	clean := filepath.Join(dir, "clean.php")
+	if err := os.WriteFile(infected, []byte("<?php eval($_POST['x']);"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(clean, []byte("<?php echo 'hello';"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	client := NewClient(socketPath)
+
+	resp, err := client.ScanFile(context.Background(), infected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.HasMatches() {
+		t.Fatal("expected infected file to match")
+	}
+	if resp.Matches[0].SignatureName != "Eval Pattern" {
+		t.Errorf("expected signature name, got %q", resp.Matches[0].SignatureName)
+	}
+
+	resp, err = client.ScanFile(context.Background(), clean)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.HasMatches() || resp.Error != "" {
+		t.Errorf("expected clean result, got %+v", resp)
+	}
+
+	resp, err = client.ScanFile(context.Background(), filepath.Join(dir, "missing.php"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Error == "" {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestClientNoDaemon(t *testing.T) {
+	client := NewClient(filepath.Join(shortTempDir(t), "none.sock"))
+	if _, err := client.ScanFile(context.Background(), "/tmp/x.php"); err == nil {
+		t.Error("expected error when daemon is not running")
+	}
+}
+
+func TestClientJobs(t *testing.T) {
+	socketPath := startTestServer(t, WithJobQueue(openTestQueue(t, t.TempDir())))
+	client := NewClient(socketPath)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "infected.php"), []byte("<?php eval($_POST['x']);"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	job, err := client.SubmitJob(ctx, dir, "acme", PriorityLow)
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if job.State != JobQueued || job.Tenant != "acme" {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.Done() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = client.JobStatus(ctx, job.ID); err != nil {
+			t.Fatalf("status failed: %v", err)
+		}
+	}
+	if job.State != JobDone || len(job.Results) != 1 || !job.Results[0].HasMatches() {
+		t.Fatalf("expected finished job with one match, got %+v", job)
+	}
+
+	jobs, err := client.ListJobs(ctx, "acme")
+	if err != nil || len(jobs) != 1 {
+		t.Errorf("expected one job for tenant, got %d, %v", len(jobs), err)
+	}
+	if _, err := client.SubmitJob(ctx, dir, "", "urgent"); err == nil {
+		t.Error("expected error for unknown priority")
+	}
+	if _, err := client.JobStatus(ctx, "missing"); err == nil {
+		t.Error("expected error for unknown job")
+	}
+}
+
+func TestClientJobsDisabled(t *testing.T) {
+	client := NewClient(startTestServer(t))
+	if _, err := client.ListJobs(context.Background(), ""); err == nil {
+		t.Error("expected error when jobs are not enabled")
+	}
+}
@@ -0,0 +1,174 @@
+// Package daemon provides the health report answering liveness and
+// readiness probes
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+// Health statuses. A degraded daemon still serves scans; an unavailable
+// one fails readiness probes.
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+// healthTimeout bounds reading a probe request and writing its answer
+const healthTimeout = 5 * time.Second
+
+// FeedHealth describes how fresh an intelligence feed is
+type FeedHealth struct {
+	// Updated is when the feed's content was published or cached
+	Updated time.Time `json:"updated,omitzero"`
+	// Checked is when the daemon last loaded the feed or found it up to date
+	Checked    time.Time `json:"checked,omitzero"`
+	AgeSeconds int64     `json:"age_seconds"`
+	Count      int       `json:"count,omitempty"`
+	Stale      bool      `json:"stale,omitempty"`
+}
+
+// HealthReport is the daemon's answer to /healthz and /readyz
+type HealthReport struct {
+	Status        string                 `json:"status"`
+	Ready         bool                   `json:"ready"`
+	UptimeSeconds int64                  `json:"uptime_seconds"`
+	Signatures    FeedHealth             `json:"signature_feed"`
+	Vulns         *FeedHealth            `json:"vulnerability_feed,omitempty"`
+	LastScan      time.Time              `json:"last_scan,omitzero"`
+	Circuits      []scanner.CircuitState `json:"circuits"`
+	Problems      []string               `json:"problems,omitempty"`
+}
+
+// Health reports the freshness of the feeds, the latest successful scan
+// and the circuits of the devices read from. The daemon is unavailable
+// without signatures, or once they haven't been checked for longer than
+// the maximum feed age; a stale vulnerability feed or a failing device
+// only degrades it.
+func (srv *Server) Health() *HealthReport {
+	now := time.Now()
+	report := &HealthReport{
+		Status:        HealthOK,
+		Ready:         true,
+		UptimeSeconds: int64(now.Sub(srv.started).Seconds()),
+		Circuits:      srv.scanner.CircuitStates(),
+	}
+
+	sigSet := srv.scanner.SignatureSet()
+	checked := srv.scanner.SignaturesChecked()
+	report.Signatures = FeedHealth{Checked: checked, AgeSeconds: int64(now.Sub(checked).Seconds())}
+	if sigSet != nil {
+		report.Signatures.Count = sigSet.Count()
+		if sigSet.UpdateTime > 0 {
+			report.Signatures.Updated = time.Unix(sigSet.UpdateTime, 0).UTC()
+		}
+	}
+	switch {
+	case report.Signatures.Count == 0:
+		report.unavailable("no signatures loaded")
+	case srv.stale(now, checked):
+		report.Signatures.Stale = true
+		report.unavailable(fmt.Sprintf("signatures last checked %s ago", now.Sub(checked).Round(time.Second)))
+	}
+
+	if srv.vulnFeed != nil {
+		if updated := srv.vulnFeed(); !updated.IsZero() {
+			report.Vulns = &FeedHealth{Updated: updated.UTC(), AgeSeconds: int64(now.Sub(updated).Seconds())}
+			if srv.stale(now, updated) {
+				report.Vulns.Stale = true
+				report.degraded(fmt.Sprintf("vulnerability feed cached %s ago", now.Sub(updated).Round(time.Second)))
+			}
+		}
+	}
+
+	if last := srv.lastScan.Load(); last != 0 {
+		report.LastScan = time.Unix(0, last).UTC()
+	}
+	if srv.jobs != nil {
+		if done := srv.jobs.LastDone(); done.After(report.LastScan) {
+			report.LastScan = done
+		}
+		report.Circuits = append(report.Circuits, srv.jobs.CircuitStates()...)
+	}
+
+	for _, c := range report.Circuits {
+		if c.State != scanner.CircuitClosed {
+			report.degraded(fmt.Sprintf("circuit %s for the device of %s", c.State, c.Path))
+		}
+	}
+	return report
+}
+
+// stale reports whether a feed checked at checked is past the maximum age
+func (srv *Server) stale(now, checked time.Time) bool {
+	return srv.maxFeedAge > 0 && now.Sub(checked) > srv.maxFeedAge
+}
+
+func (r *HealthReport) unavailable(problem string) {
+	r.Status = HealthUnavailable
+	r.Ready = false
+	r.Problems = append(r.Problems, problem)
+}
+
+func (r *HealthReport) degraded(problem string) {
+	if r.Status == HealthOK {
+		r.Status = HealthDegraded
+	}
+	r.Problems = append(r.Problems, problem)
+}
+
+// HealthHandler serves /healthz, which answers 200 while the daemon is
+// running, and /readyz, which answers 503 while it is unavailable. Both
+// return the health report as JSON.
+func (srv *Server) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		srv.writeHealth(w, srv.Health(), false)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		srv.writeHealth(w, srv.Health(), true)
+	})
+	return mux
+}
+
+// writeHealth writes report, failing the request if readiness was probed
+// and the daemon isn't ready
+func (srv *Server) writeHealth(w http.ResponseWriter, report *HealthReport, readiness bool) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		srv.logger.Warning("Encoding health report failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if readiness && !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// serveHealth answers health probes on the health listener until it is
+// closed
+func (srv *Server) serveHealth() *http.Server {
+	hs := &http.Server{
+		Handler:           srv.HealthHandler(),
+		ReadHeaderTimeout: healthTimeout,
+		WriteTimeout:      healthTimeout,
+	}
+	srv.logger.Info("Serving health checks on %s", srv.health.Addr())
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		if err := hs.Serve(srv.health); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			srv.logger.Warning("Health checks stopped: %v", err)
+		}
+	}()
+	return hs
+}
@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// getHealth probes path on the health listener at addr
+func getHealth(t *testing.T, addr, path string) (int, *HealthReport) {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + path)
+	if err != nil {
+		t.Fatalf("probing %s: %v", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var report HealthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decoding %s: %v", path, err)
+	}
+	return resp.StatusCode, &report
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	var cached atomic.Int64
+	cached.Store(time.Now().Add(-72 * time.Hour).UnixNano())
+	socketPath := startTestServer(t,
+		WithHealthListener(listener),
+		WithVulnerabilityFeed(func() time.Time { return time.Unix(0, cached.Load()) }),
+		WithMaxFeedAge(48*time.Hour),
+	)
+
+	status, report := getHealth(t, addr, "/readyz")
+	if status != http.StatusOK || !report.Ready || report.Status != HealthDegraded {
+		t.Fatalf("expected ready and degraded by the vulnerability feed, got %d %+v", status, report)
+	}
+	if report.Signatures.Count != 1 || report.Signatures.Stale || report.Vulns == nil || !report.Vulns.Stale {
+		t.Errorf("unexpected feeds %+v, %+v", report.Signatures, report.Vulns)
+	}
+	if !report.LastScan.IsZero() {
+		t.Errorf("expected no scan yet, got %v", report.LastScan)
+	}
+
+	file := filepath.Join(t.TempDir(), "clean.php")
+	if err := os.WriteFile(file, []byte("<?php echo 'hello';"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(socketPath).ScanFile(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+	if _, report := getHealth(t, addr, "/healthz"); report.LastScan.IsZero() {
+		t.Error("expected the scan recorded")
+	}
+
+	// A freshly cached vulnerability feed is healthy again
+	cached.Store(time.Now().UnixNano())
+	status, report = getHealth(t, addr, "/readyz")
+	if status != http.StatusOK || report.Status != HealthOK {
+		t.Fatalf("expected ok, got %d %+v", status, report)
+	}
+}
+
+func TestHealthStaleSignatures(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	startTestServer(t, WithHealthListener(listener), WithMaxFeedAge(time.Nanosecond))
+	time.Sleep(time.Millisecond)
+
+	status, report := getHealth(t, addr, "/readyz")
+	if status != http.StatusServiceUnavailable || report.Ready || report.Status != HealthUnavailable || !report.Signatures.Stale {
+		t.Errorf("expected unavailable with stale signatures, got %d %+v", status, report)
+	}
+	if status, _ := getHealth(t, addr, "/healthz"); status != http.StatusOK {
+		t.Errorf("expected liveness to pass, got %d", status)
+	}
+}
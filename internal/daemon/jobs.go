@@ -0,0 +1,582 @@
+// Package daemon provides a persistent, prioritized queue of scan jobs
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+// Defaults for the job queue
+const (
+	DefaultMaxJobs      = 2
+	DefaultTenantJobs   = 1
+	DefaultJobRetention = 24 * time.Hour
+)
+
+// ErrJobNotFound is returned when no job has the requested ID
+var ErrJobNotFound = errors.New("job not found")
+
+// Priority orders scan work. Work of a higher priority pauses running jobs
+// of a lower one until it is done.
+type Priority string
+
+// Priorities, lowest first. Single-file scans are always PriorityHigh so
+// upload checks preempt background full scans.
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+)
+
+// ParsePriority validates a priority name; "" is PriorityNormal
+func ParsePriority(name string) (Priority, error) {
+	switch p := Priority(strings.ToLower(name)); p {
+	case "":
+		return PriorityNormal, nil
+	case PriorityLow, PriorityNormal, PriorityHigh:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown priority %q (use low, normal or high)", name)
+	}
+}
+
+// rank orders priorities, lowest first
+func (p Priority) rank() int {
+	switch p {
+	case PriorityLow:
+		return 0
+	case PriorityHigh:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// JobState is the lifecycle state of a job
+type JobState string
+
+// Job states
+const (
+	JobQueued  JobState = "queued"
+	JobRunning JobState = "running"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+)
+
+// Job is a queued scan of a file or directory
+type Job struct {
+	ID           string          `json:"id"`
+	Path         string          `json:"path"`
+	Shard        string          `json:"shard,omitempty"`
+	Tenant       string          `json:"tenant,omitempty"`
+	Priority     Priority        `json:"priority"`
+	State        JobState        `json:"state"`
+	Submitted    time.Time       `json:"submitted"`
+	Started      time.Time       `json:"started,omitzero"`
+	Finished     time.Time       `json:"finished,omitzero"`
+	FilesScanned int64           `json:"files_scanned"`
+	FilesMatched int64           `json:"files_matched"`
+	FilesErrored int64           `json:"files_errored,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Results      []*ScanResponse `json:"results,omitempty"`
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j *Job) Done() bool {
+	return j.State == JobDone || j.State == JobFailed
+}
+
+// snapshot copies the job so it can be read without the queue's lock
+func (j *Job) snapshot(withResults bool) *Job {
+	c := *j
+	c.Results = nil
+	if withResults {
+		c.Results = append([]*ScanResponse(nil), j.Results...)
+	}
+	return &c
+}
+
+// JobQueue runs scan jobs in priority order, limiting how many run at once
+// in total and per tenant. Jobs are persisted to disk, one JSON file per
+// job, so queued and interrupted jobs resume when the daemon restarts and
+// finished jobs can be queried until they expire.
+type JobQueue struct {
+	dir         string
+	maxRunning  int
+	tenantLimit int
+	retention   time.Duration
+	logger      *logging.Logger
+	gate        *gate
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	running map[string]*Job
+	wake    chan struct{}
+	// lastDone is when the latest successful job finished, kept after the
+	// job itself is pruned
+	lastDone time.Time
+	// circuits are those of the latest job to finish
+	circuits []scanner.CircuitState
+}
+
+// JobQueueOption configures a JobQueue
+type JobQueueOption func(*JobQueue)
+
+// WithMaxJobs sets how many jobs run at once. A job may exceed the limit
+// when every running job has a lower priority, as those are paused.
+func WithMaxJobs(n int) JobQueueOption {
+	return func(q *JobQueue) {
+		q.maxRunning = n
+	}
+}
+
+// WithTenantJobs sets how many of a tenant's jobs run at once (0 is unlimited)
+func WithTenantJobs(n int) JobQueueOption {
+	return func(q *JobQueue) {
+		q.tenantLimit = n
+	}
+}
+
+// WithJobRetention sets how long finished jobs can be queried
+func WithJobRetention(d time.Duration) JobQueueOption {
+	return func(q *JobQueue) {
+		q.retention = d
+	}
+}
+
+// WithJobLogger sets the logger
+func WithJobLogger(logger *logging.Logger) JobQueueOption {
+	return func(q *JobQueue) {
+		q.logger = logger
+	}
+}
+
+// OpenJobQueue opens the queue persisted in dir. Jobs that were running
+// when the daemon stopped are queued again.
+func OpenJobQueue(dir string, opts ...JobQueueOption) (*JobQueue, error) {
+	q := &JobQueue{
+		dir:         dir,
+		maxRunning:  DefaultMaxJobs,
+		tenantLimit: DefaultTenantJobs,
+		retention:   DefaultJobRetention,
+		logger:      logging.New(logging.LevelInfo),
+		gate:        newGate(),
+		jobs:        make(map[string]*Job),
+		running:     make(map[string]*Job),
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.maxRunning <= 0 {
+		q.maxRunning = DefaultMaxJobs
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	q.prune(time.Now())
+	q.mu.Unlock()
+	return q, nil
+}
+
+// Submit queues a scan of path, which must be absolute
+func (q *JobQueue) Submit(path, tenant string, priority Priority) (*Job, error) {
+	return q.SubmitShard(path, tenant, priority, scanner.Shard{})
+}
+
+// SubmitShard queues a scan of the files of path that belong to shard
+func (q *JobQueue) SubmitShard(path, tenant string, priority Priority, shard scanner.Shard) (*Job, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("path must be absolute")
+	}
+	priority, err := ParsePriority(string(priority))
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:        newJobID(),
+		Path:      filepath.Clean(path),
+		Tenant:    tenant,
+		Priority:  priority,
+		State:     JobQueued,
+		Submitted: time.Now().UTC(),
+	}
+	if shard.Enabled() {
+		job.Shard = shard.String()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.save(job); err != nil {
+		return nil, err
+	}
+	q.jobs[job.ID] = job
+	q.signal()
+	q.logger.Debug("Queued job %s: %s (%s priority)", job.ID, job.Path, job.Priority)
+	return job.snapshot(false), nil
+}
+
+// Status returns a job, including the results of its matched files
+func (q *JobQueue) Status(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job.snapshot(true), nil
+}
+
+// LastDone returns when the latest successful job finished, or the zero
+// time if none has
+func (q *JobQueue) LastDone() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.lastDone
+}
+
+// CircuitStates returns the circuits of the devices the latest job to
+// finish read from
+func (q *JobQueue) CircuitStates() []scanner.CircuitState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.circuits
+}
+
+// List returns the jobs of tenant, or of every tenant if tenant is "",
+// oldest first and without their results
+func (q *JobQueue) List(tenant string) []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		if tenant == "" || job.Tenant == tenant {
+			jobs = append(jobs, job.snapshot(false))
+		}
+	}
+	// Job IDs start with their submission time
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].ID < jobs[k].ID })
+	return jobs
+}
+
+// Run starts queued jobs on forks of s until ctx is cancelled. Jobs interrupted by
+// the cancellation are queued again.
+func (q *JobQueue) Run(ctx context.Context, s *scanner.Scanner) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		q.mu.Lock()
+		for job := q.next(); job != nil; job = q.next() {
+			job.State = JobRunning
+			job.Started = time.Now().UTC()
+			q.running[job.ID] = job
+			if err := q.save(job); err != nil {
+				q.logger.Warning("%v", err)
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				q.execute(ctx, s, job)
+			}()
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		}
+	}
+}
+
+// next picks the queued job to start: the highest priority, then the
+// oldest, among those within the concurrency limits. Callers hold q.mu.
+func (q *JobQueue) next() *Job {
+	tenants := make(map[string]int)
+	lowest := PriorityHigh.rank() + 1
+	for _, job := range q.running {
+		tenants[job.Tenant]++
+		lowest = min(lowest, job.Priority.rank())
+	}
+
+	var best *Job
+	for _, job := range q.jobs {
+		if job.State != JobQueued {
+			continue
+		}
+		if q.tenantLimit > 0 && tenants[job.Tenant] >= q.tenantLimit {
+			continue
+		}
+		// Running lower-priority jobs pause for this one, so it may
+		// exceed the limit rather than wait for them
+		if len(q.running) >= q.maxRunning && job.Priority.rank() <= lowest {
+			continue
+		}
+		if best == nil || job.Priority.rank() > best.Priority.rank() ||
+			(job.Priority == best.Priority && job.ID < best.ID) {
+			best = job
+		}
+	}
+	return best
+}
+
+// execute runs a job and records its outcome
+func (q *JobQueue) execute(ctx context.Context, s *scanner.Scanner, job *Job) {
+	defer q.signal()
+
+	release := q.gate.enter(job.Priority)
+	defer release()
+
+	// Jobs run at the same time, so each scans with state of its own
+	fork := s.Fork()
+	q.logger.Verbose("Starting job %s: %s", job.ID, job.Path)
+	err := q.scan(ctx, fork, job)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, job.ID)
+	q.circuits = fork.CircuitStates()
+
+	switch {
+	case ctx.Err() != nil:
+		// Start over when the daemon next runs
+		job.State = JobQueued
+		job.Started = time.Time{}
+		job.FilesScanned, job.FilesMatched, job.FilesErrored = 0, 0, 0
+		job.Results = nil
+	case err != nil:
+		job.State = JobFailed
+		job.Error = err.Error()
+		job.Finished = time.Now().UTC()
+		q.logger.Warning("Job %s failed: %v", job.ID, err)
+	default:
+		job.State = JobDone
+		job.Finished = time.Now().UTC()
+		q.lastDone = job.Finished
+		q.logger.Verbose("Finished job %s: %d files scanned, %d matched", job.ID, job.FilesScanned, job.FilesMatched)
+	}
+	if err := q.save(job); err != nil {
+		q.logger.Warning("%v", err)
+	}
+	q.prune(time.Now())
+}
+
+// scan scans a job's path, pausing between files while higher-priority
+// work is running
+func (q *JobQueue) scan(ctx context.Context, s *scanner.Scanner, job *Job) error {
+	if _, err := os.Stat(job.Path); err != nil {
+		return fmt.Errorf("cannot access path: %w", err)
+	}
+	var shard scanner.Shard
+	if job.Shard != "" {
+		parsed, err := scanner.ParseShard(job.Shard)
+		if err != nil {
+			return err
+		}
+		shard = parsed
+	}
+	results, err := s.ScanShard(ctx, shard, job.Path)
+	if err != nil {
+		return fmt.Errorf("starting scan: %w", err)
+	}
+
+	for result := range results {
+		// Leaving results unread stalls the scan's workers
+		_ = q.gate.wait(ctx, job.Priority)
+
+		q.mu.Lock()
+		switch {
+		case result.Error != nil:
+			job.FilesErrored++
+		case result.HasMatches():
+			job.FilesScanned++
+			job.FilesMatched++
+			job.Results = append(job.Results, NewScanResponse(result, s.SignatureSet()))
+		default:
+			job.FilesScanned++
+		}
+		q.mu.Unlock()
+	}
+	return nil
+}
+
+// preempt pauses jobs below priority until the returned function is called
+func (q *JobQueue) preempt(priority Priority) func() {
+	return q.gate.enter(priority)
+}
+
+// signal wakes Run to start more jobs
+func (q *JobQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// prune forgets finished jobs older than the retention period. Callers
+// hold q.mu.
+func (q *JobQueue) prune(now time.Time) {
+	if q.retention <= 0 {
+		return
+	}
+	for id, job := range q.jobs {
+		if job.Done() && now.Sub(job.Finished) > q.retention {
+			delete(q.jobs, id)
+			if err := os.Remove(q.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				q.logger.Warning("Removing expired job: %v", err)
+			}
+		}
+	}
+}
+
+// load reads the persisted jobs
+func (q *JobQueue) load() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading job queue: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(q.dir, name)) // #nosec G304 -- file in the job directory
+		if err != nil {
+			return fmt.Errorf("reading job: %w", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			q.logger.Warning("Skipping unreadable job %s: %v", name, err)
+			continue
+		}
+		if job.State == JobRunning {
+			job.State = JobQueued
+			job.Started = time.Time{}
+			job.FilesScanned, job.FilesMatched, job.FilesErrored = 0, 0, 0
+			job.Results = nil
+		}
+		if job.State == JobDone && job.Finished.After(q.lastDone) {
+			q.lastDone = job.Finished
+		}
+		q.jobs[job.ID] = &job
+	}
+	return nil
+}
+
+// save persists a job. Callers hold q.mu.
+func (q *JobQueue) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("encoding job: %w", err)
+	}
+	if err := os.MkdirAll(q.dir, 0o700); err != nil {
+		return fmt.Errorf("creating job directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(q.dir, ".job-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing job: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing job: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.path(job.ID)); err != nil {
+		return fmt.Errorf("saving job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// path returns the file holding a job
+func (q *JobQueue) path(id string) string {
+	return filepath.Join(q.dir, filepath.Base(id)+".json")
+}
+
+// newJobID returns a unique ID that sorts by submission time
+func newJobID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102-150405.000000") + "-" + hex.EncodeToString(suffix)
+}
+
+// gate pauses work while work of a higher priority is active
+type gate struct {
+	mu      sync.Mutex
+	active  [3]int
+	changed chan struct{}
+}
+
+func newGate() *gate {
+	return &gate{changed: make(chan struct{})}
+}
+
+// enter marks work of priority as active until the returned function is
+// called
+func (g *gate) enter(priority Priority) func() {
+	g.mu.Lock()
+	g.active[priority.rank()]++
+	g.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			g.active[priority.rank()]--
+			close(g.changed)
+			g.changed = make(chan struct{})
+			g.mu.Unlock()
+		})
+	}
+}
+
+// wait blocks while work of a higher priority than priority is active
+func (g *gate) wait(ctx context.Context, priority Priority) error {
+	for {
+		g.mu.Lock()
+		blocked := false
+		for rank := priority.rank() + 1; rank < len(g.active); rank++ {
+			if g.active[rank] > 0 {
+				blocked = true
+			}
+		}
+		changed := g.changed
+		g.mu.Unlock()
+
+		if !blocked {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for higher-priority work: %w", ctx.Err())
+		case <-changed:
+		}
+	}
+}
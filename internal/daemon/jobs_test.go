@@ -0,0 +1,255 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/scanner"
+)
+
+func openTestQueue(t *testing.T, dir string, opts ...JobQueueOption) *JobQueue {
+	t.Helper()
+	opts = append([]JobQueueOption{WithJobLogger(logging.New(logging.LevelCritical))}, opts...)
+	q, err := OpenJobQueue(dir, opts...)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	return q
+}
+
+// waitForJob polls until a job has finished
+func waitForJob(t *testing.T, q *JobQueue, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Status(id)
+		if err != nil {
+			t.Fatalf("status failed: %v", err)
+		}
+		if job.Done() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestParsePriority(t *testing.T) {
+	if p, err := ParsePriority(""); err != nil || p != PriorityNormal {
+		t.Errorf("expected normal priority by default, got %q, %v", p, err)
+	}
+	if p, err := ParsePriority("HIGH"); err != nil || p != PriorityHigh {
+		t.Errorf("expected high priority, got %q, %v", p, err)
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("expected error for unknown priority")
+	}
+}
+
+func TestJobQueueNext(t *testing.T) {
+	q := openTestQueue(t, t.TempDir(), WithMaxJobs(1), WithTenantJobs(1))
+
+	low, _ := q.Submit("/srv/a", "acme", PriorityLow)
+	normal, _ := q.Submit("/srv/b", "acme", PriorityNormal)
+	other, _ := q.Submit("/srv/c", "globex", PriorityNormal)
+	high, _ := q.Submit("/srv/d", "acme", PriorityHigh)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	start := func() *Job {
+		job := q.next()
+		if job != nil {
+			job.State = JobRunning
+			q.running[job.ID] = job
+		}
+		return job
+	}
+
+	if job := start(); job == nil || job.ID != high.ID {
+		t.Fatalf("expected high-priority job first, got %+v", job)
+	}
+	// The limit is reached and acme is at its tenant limit
+	if job := q.next(); job != nil {
+		t.Fatalf("expected no job to start, got %+v", job)
+	}
+
+	// A finished job frees a slot for the oldest of the best priority
+	delete(q.running, high.ID)
+	if job := start(); job == nil || job.ID != normal.ID {
+		t.Fatalf("expected oldest normal job, got %+v", job)
+	}
+	delete(q.running, normal.ID)
+
+	// Running low-priority work doesn't hold back a normal job
+	q.jobs[low.ID].State = JobRunning
+	q.running[low.ID] = q.jobs[low.ID]
+	if job := q.next(); job == nil || job.ID != other.ID {
+		t.Fatalf("expected normal job to exceed the limit over low, got %+v", job)
+	}
+}
+
+func TestJobQueueRunsAndPersists(t *testing.T) {
+	scanDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(scanDir, "infected.php"), []byte("<?php eval($_POST['x']);"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(scanDir, "clean.php"), []byte("<?php echo 'hello';"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	queueDir := t.TempDir()
+	q := openTestQueue(t, queueDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx, scanner.NewScanner(createTestSignatureSet()))
+		close(done)
+	}()
+
+	job, err := q.Submit(scanDir, "acme", PriorityLow)
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	missing, err := q.Submit(filepath.Join(scanDir, "missing"), "acme", PriorityLow)
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+
+	finished := waitForJob(t, q, job.ID)
+	if finished.State != JobDone || finished.FilesScanned != 2 || finished.FilesMatched != 1 || len(finished.Results) != 1 {
+		t.Errorf("unexpected job %+v", finished)
+	}
+	if failed := waitForJob(t, q, missing.ID); failed.State != JobFailed || failed.Error == "" {
+		t.Errorf("expected missing path to fail, got %+v", failed)
+	}
+	cancel()
+	<-done
+
+	// Finished jobs can still be queried after a restart
+	reopened := openTestQueue(t, queueDir)
+	loaded, err := reopened.Status(job.ID)
+	if err != nil || loaded.State != JobDone || len(loaded.Results) != 1 {
+		t.Errorf("expected persisted job, got %+v, %v", loaded, err)
+	}
+	if jobs := reopened.List("globex"); len(jobs) != 0 {
+		t.Errorf("expected no jobs for another tenant, got %d", len(jobs))
+	}
+	if _, err := reopened.Status("nope"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobQueueRunsJobsConcurrently(t *testing.T) {
+	// Two jobs at once share the queue's scanner; run with -race
+	var dirs []string
+	for _, files := range []int{20, 30} {
+		dir := t.TempDir()
+		for i := range files {
+			content := "<?php echo 'hello';"
+			if i == 0 {
+				content = "<?php eval($_POST['x']);"
+			}
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.php", i)), []byte(content), 0600); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+
+	q := openTestQueue(t, t.TempDir(), WithMaxJobs(2))
+	var jobs []*Job
+	for i, dir := range dirs {
+		job, err := q.Submit(dir, fmt.Sprintf("tenant%d", i), PriorityNormal)
+		if err != nil {
+			t.Fatalf("submit failed: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		// The jobs share the read rate
+		q.Run(ctx, scanner.NewScanner(createTestSignatureSet(), scanner.WithReadRate(1<<30)))
+		close(done)
+	}()
+	for i, job := range jobs {
+		finished := waitForJob(t, q, job.ID)
+		if want := int64(20 + 10*i); finished.State != JobDone || finished.FilesScanned != want || finished.FilesMatched != 1 {
+			t.Errorf("expected %d files scanned and 1 matched, got %+v", want, finished)
+		}
+	}
+	cancel()
+	<-done
+}
+
+func TestJobQueueRequeuesInterruptedJobs(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	job, _ := q.Submit("/srv/site", "", PriorityNormal)
+
+	q.mu.Lock()
+	q.jobs[job.ID].State = JobRunning
+	q.jobs[job.ID].FilesScanned = 10
+	if err := q.save(q.jobs[job.ID]); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	q.mu.Unlock()
+
+	loaded, err := openTestQueue(t, dir).Status(job.ID)
+	if err != nil || loaded.State != JobQueued || loaded.FilesScanned != 0 {
+		t.Errorf("expected interrupted job to be queued again, got %+v, %v", loaded, err)
+	}
+}
+
+func TestJobQueuePrunesExpiredJobs(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir, WithJobRetention(time.Hour))
+	job, _ := q.Submit("/srv/site", "", PriorityNormal)
+
+	q.mu.Lock()
+	q.jobs[job.ID].State = JobDone
+	q.jobs[job.ID].Finished = time.Now().Add(-2 * time.Hour)
+	_ = q.save(q.jobs[job.ID])
+	q.mu.Unlock()
+
+	if _, err := openTestQueue(t, dir, WithJobRetention(time.Hour)).Status(job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected expired job to be pruned, got %v", err)
+	}
+}
+
+func TestGatePreemptsLowerPriority(t *testing.T) {
+	g := newGate()
+	release := g.enter(PriorityHigh)
+
+	// Work at the same priority is not paused
+	if err := g.wait(context.Background(), PriorityHigh); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resumed := make(chan error, 1)
+	go func() { resumed <- g.wait(context.Background(), PriorityLow) }()
+	select {
+	case <-resumed:
+		t.Fatal("expected low-priority work to pause")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case err := <-resumed:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected low-priority work to resume")
+	}
+}
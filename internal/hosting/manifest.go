@@ -0,0 +1,157 @@
+// Package hosting provides site manifests exported from hosting control panels
+package hosting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Field names used for the document root, owning account and domain by the
+// simple manifest format and by cPanel (whmapi1 get_domain_info, uapi
+// DomainInfo domains_data) and Plesk exports
+var (
+	docrootKeys = []string{"docroot", "documentroot", "document_root", "www_root", "wwwroot"}
+	ownerKeys   = []string{"owner", "user", "username", "login", "owner_login", "customer"}
+	domainKeys  = []string{"domain", "domain_name", "servername", "name"}
+)
+
+// Site is a document root and the hosting account and domain it belongs to
+type Site struct {
+	Docroot string `json:"docroot"`
+	Owner   string `json:"owner"`
+	Domain  string `json:"domain"`
+}
+
+// Manifest is the list of sites hosted on a server
+type Manifest struct {
+	Sites []*Site
+}
+
+// LoadManifest reads a site manifest file
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- user-specified manifest
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sites manifest: %w", err)
+	}
+	m, err := ParseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sites manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseManifest parses a JSON array of {docroot, owner, domain} objects or a
+// cPanel/Plesk domain export. Every object with a document root is a site,
+// wherever it is nested; the first entry for a document root wins.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	seen := make(map[string]bool)
+	collectSites(doc, func(site *Site) {
+		if !seen[site.Docroot] {
+			seen[site.Docroot] = true
+			m.Sites = append(m.Sites, site)
+		}
+	})
+	if len(m.Sites) == 0 {
+		return nil, fmt.Errorf("no sites with a document root found")
+	}
+	return m, nil
+}
+
+// collectSites walks a decoded JSON document reporting every object that
+// has a document root
+func collectSites(value interface{}, report func(*Site)) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			collectSites(item, report)
+		}
+	case map[string]interface{}:
+		fields := make(map[string]string, len(v))
+		for key, field := range v {
+			if s, ok := field.(string); ok {
+				fields[strings.ToLower(key)] = s
+			}
+		}
+		if docroot := firstField(fields, docrootKeys); docroot != "" {
+			report(&Site{
+				Docroot: filepath.Clean(docroot),
+				Owner:   firstField(fields, ownerKeys),
+				Domain:  firstField(fields, domainKeys),
+			})
+			return
+		}
+		// Visit nested values in a stable order
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectSites(v[key], report)
+		}
+	}
+}
+
+// firstField returns the first non-empty field among keys
+func firstField(fields map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(fields[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Roots returns the document roots to scan, leaving out those nested inside
+// another site's document root (such as cPanel addon domains under
+// public_html) so no file is scanned twice
+func (m *Manifest) Roots() []string {
+	docroots := make([]string, 0, len(m.Sites))
+	for _, site := range m.Sites {
+		docroots = append(docroots, site.Docroot)
+	}
+	sort.Strings(docroots)
+
+	var roots []string
+	for _, docroot := range docroots {
+		if len(roots) > 0 && isWithin(docroot, roots[len(roots)-1]) {
+			continue
+		}
+		roots = append(roots, docroot)
+	}
+	return roots
+}
+
+// Lookup returns the site whose document root most closely contains path,
+// or nil if path is outside every site
+func (m *Manifest) Lookup(path string) *Site {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	var best *Site
+	for _, site := range m.Sites {
+		if isWithin(path, site.Docroot) && (best == nil || len(site.Docroot) > len(best.Docroot)) {
+			best = site
+		}
+	}
+	return best
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
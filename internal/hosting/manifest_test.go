@@ -0,0 +1,73 @@
+package hosting
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseManifestSimple(t *testing.T) {
+	data := []byte(`[
+		{"docroot": "/home/alice/public_html", "owner": "alice", "domain": "alice.example"},
+		{"docroot": "/home/bob/public_html/", "owner": "bob", "domain": "bob.example"}
+	]`)
+	m, err := ParseManifest(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Sites) != 2 {
+		t.Fatalf("expected 2 sites, got %d", len(m.Sites))
+	}
+	if got := m.Sites[1]; got.Docroot != "/home/bob/public_html" || got.Owner != "bob" || got.Domain != "bob.example" {
+		t.Errorf("unexpected site %+v", got)
+	}
+}
+
+func TestParseManifestCPanel(t *testing.T) {
+	// uapi DomainInfo domains_data
+	data := []byte(`{"result": {"data": {
+		"main_domain": {"domain": "alice.example", "documentroot": "/home/alice/public_html", "user": "alice"},
+		"addon_domains": [{"domain": "shop.example", "documentroot": "/home/alice/public_html/shop", "user": "alice"}],
+		"sub_domains": [{"domain": "www.alice.example", "documentroot": "/home/alice/public_html", "user": "alice"}]
+	}, "status": 1}}`)
+	m, err := ParseManifest(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Sites) != 2 {
+		t.Fatalf("expected duplicate docroots to be merged, got %+v", m.Sites)
+	}
+
+	if roots := m.Roots(); !reflect.DeepEqual(roots, []string{"/home/alice/public_html"}) {
+		t.Errorf("expected nested docroots to be dropped, got %v", roots)
+	}
+
+	if site := m.Lookup(filepath.FromSlash("/home/alice/public_html/shop/wp-config.php")); site == nil || site.Domain != "shop.example" {
+		t.Errorf("expected addon domain attribution, got %+v", site)
+	}
+	if site := m.Lookup("/home/alice/public_html/index.php"); site == nil || site.Domain != "alice.example" {
+		t.Errorf("expected main domain attribution, got %+v", site)
+	}
+	if site := m.Lookup("/home/alice/public_html_old/index.php"); site != nil {
+		t.Errorf("expected no attribution outside docroots, got %+v", site)
+	}
+}
+
+func TestParseManifestPlesk(t *testing.T) {
+	data := []byte(`[{"name": "carol.example", "www_root": "/var/www/vhosts/carol.example/httpdocs", "owner_login": "carol"}]`)
+	m, err := ParseManifest(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if site := m.Sites[0]; site.Owner != "carol" || site.Domain != "carol.example" {
+		t.Errorf("unexpected site %+v", site)
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	for _, data := range []string{`not json`, `[]`, `[{"domain": "no-docroot.example"}]`} {
+		if _, err := ParseManifest([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package intel provides known-malware file hash sets
+package intel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/cache"
+)
+
+// KnownHash is the SHA256 of a file known to be malware
+type KnownHash struct {
+	SHA256   string `json:"sha256"`
+	Name     string `json:"name,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// HashSet is a blocklist of known-malicious file hashes
+type HashSet struct {
+	Hashes     map[string]*KnownHash `json:"hashes"`
+	UpdateTime int64                 `json:"update_time"`
+}
+
+// NewHashSet creates an empty hash set
+func NewHashSet() *HashSet {
+	return &HashSet{
+		Hashes: make(map[string]*KnownHash),
+	}
+}
+
+// Add adds a hash to the set, normalising it to lowercase hex
+func (hs *HashSet) Add(h *KnownHash) error {
+	sum := strings.ToLower(strings.TrimSpace(h.SHA256))
+	if !isSHA256Hex(sum) {
+		return fmt.Errorf("invalid sha256 %q", h.SHA256)
+	}
+	h.SHA256 = sum
+	hs.Hashes[sum] = h
+	return nil
+}
+
+// Lookup returns the known hash entry for a raw SHA256 digest, or nil
+func (hs *HashSet) Lookup(sum [32]byte) *KnownHash {
+	if hs == nil {
+		return nil
+	}
+	return hs.Hashes[hex.EncodeToString(sum[:])]
+}
+
+// Count returns the number of hashes
+func (hs *HashSet) Count() int {
+	return len(hs.Hashes)
+}
+
+// ParseHashSet parses a hash feed. JSON feeds use the HashSet encoding; any
+// other input is read as lines of "<sha256> [name]", with # comments.
+func ParseHashSet(data []byte) (*HashSet, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		hs := NewHashSet()
+		if err := json.Unmarshal(trimmed, hs); err != nil {
+			return nil, fmt.Errorf("parsing hash feed: %w", err)
+		}
+		normalised := NewHashSet()
+		normalised.UpdateTime = hs.UpdateTime
+		for key, h := range hs.Hashes {
+			if h.SHA256 == "" {
+				h.SHA256 = key
+			}
+			if err := normalised.Add(h); err != nil {
+				return nil, err
+			}
+		}
+		return normalised, nil
+	}
+
+	return parseHashList(bytes.NewReader(data))
+}
+
+// parseHashList reads one "<sha256> [name]" entry per line
+func parseHashList(r io.Reader) (*HashSet, error) {
+	hs := NewHashSet()
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		h := &KnownHash{SHA256: fields[0]}
+		if len(fields) > 1 {
+			h.Name = strings.Join(fields[1:], " ")
+		}
+		if err := hs.Add(h); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading hash feed: %w", err)
+	}
+	return hs, nil
+}
+
+// isSHA256Hex reports whether s is 64 lowercase hex characters
+func isSHA256Hex(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// HashLoader loads and saves hash sets in the cache
+type HashLoader struct {
+	cache    cache.Cache
+	cacheKey string
+	maxAge   time.Duration
+}
+
+// NewHashLoader creates a hash loader caching under a key derived from source
+func NewHashLoader(c cache.Cache, source string) *HashLoader {
+	return &HashLoader{
+		cache:    c,
+		cacheKey: "malware-hashes:" + source,
+		maxAge:   24 * time.Hour,
+	}
+}
+
+// Load loads the hash set from cache
+func (l *HashLoader) Load(ctx context.Context) (*HashSet, error) {
+	hs, err := cache.Load(ctx, l.cache, l.cacheKey, l.maxAge, decodeHashSet, nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading cached hashes: %w", err)
+	}
+	return hs, nil
+}
+
+// decodeHashSet decodes a cached hash set
+func decodeHashSet(data []byte) (*HashSet, error) {
+	hs := NewHashSet()
+	if err := json.Unmarshal(data, hs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached hashes: %w", err)
+	}
+	return hs, nil
+}
+
+// Save saves the hash set to cache
+func (l *HashLoader) Save(ctx context.Context, hs *HashSet) error {
+	data, err := json.Marshal(hs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal hashes: %w", err)
+	}
+	if err := l.cache.Put(ctx, l.cacheKey, data); err != nil {
+		return fmt.Errorf("putting to cache: %w", err)
+	}
+	return nil
+}
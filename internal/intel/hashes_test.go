@@ -0,0 +1,89 @@
+package intel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/cache"
+)
+
+func TestParseHashSetList(t *testing.T) {
+	sum := sha256.Sum256([]byte("malware"))
+	data := []byte("# known bad files\n\n" +
+		"E7A6EAEB5EA1DBD1B5B2A4ECD6D4CE79D3E2CB0D0D8A58F7FCD3EAB0A0A1D7C9 dropper\n" +
+		hexSum(sum) + " WP-VCD loader\n")
+
+	hs, err := ParseHashSet(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hs.Count() != 2 {
+		t.Fatalf("expected 2 hashes, got %d", hs.Count())
+	}
+
+	known := hs.Lookup(sum)
+	if known == nil {
+		t.Fatal("expected hash to be found")
+	}
+	if known.Name != "WP-VCD loader" {
+		t.Errorf("expected name 'WP-VCD loader', got %q", known.Name)
+	}
+	if hs.Lookup(sha256.Sum256([]byte("clean"))) != nil {
+		t.Error("expected unknown hash not to be found")
+	}
+}
+
+func TestParseHashSetJSON(t *testing.T) {
+	sum := sha256.Sum256([]byte("malware"))
+	data := []byte(`{"update_time": 42, "hashes": {"` + hexSum(sum) + `": {"name": "shell"}}}`)
+
+	hs, err := ParseHashSet(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hs.UpdateTime != 42 {
+		t.Errorf("expected update time 42, got %d", hs.UpdateTime)
+	}
+	if known := hs.Lookup(sum); known == nil || known.Name != "shell" {
+		t.Errorf("expected hash 'shell' to be found, got %+v", known)
+	}
+}
+
+func TestParseHashSetInvalid(t *testing.T) {
+	if _, err := ParseHashSet([]byte("not-a-hash\n")); err == nil {
+		t.Error("expected error for invalid hash")
+	}
+}
+
+func TestHashLoaderCache(t *testing.T) {
+	ctx := context.Background()
+	c := cache.WithContext(cache.NewMemoryCache())
+	loader := NewHashLoader(c, "https://example.com/hashes.txt")
+
+	if _, err := loader.Load(ctx); err == nil {
+		t.Fatal("expected error for empty cache")
+	}
+
+	hs := NewHashSet()
+	sum := sha256.Sum256([]byte("malware"))
+	if err := hs.Add(&KnownHash{SHA256: hexSum(sum)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := loader.Save(ctx, hs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := loader.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Lookup(sum) == nil {
+		t.Error("expected cached hash to be found")
+	}
+}
+
+func hexSum(sum [32]byte) string {
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,241 @@
+// Package intel provides indicator-of-compromise (IOC) lists
+package intel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/cache"
+)
+
+// IOCKind is the type of an indicator
+type IOCKind string
+
+const (
+	// IOCDomain matches a domain and all of its subdomains
+	IOCDomain IOCKind = "domain"
+	// IOCIP matches a single IP address
+	IOCIP IOCKind = "ip"
+	// IOCNetwork matches every IP address in a CIDR range
+	IOCNetwork IOCKind = "network"
+)
+
+// IOC is a known-malicious domain, IP address or network
+type IOC struct {
+	Value       string  `json:"value"`
+	Kind        IOCKind `json:"kind"`
+	Description string  `json:"description,omitempty"`
+}
+
+// IOCSet is a list of indicators indexed for lookup
+type IOCSet struct {
+	Indicators []*IOC `json:"indicators"`
+	UpdateTime int64  `json:"update_time"`
+
+	domains  map[string]*IOC
+	ips      map[string]*IOC
+	networks []*iocNetwork
+}
+
+type iocNetwork struct {
+	network *net.IPNet
+	ioc     *IOC
+}
+
+// NewIOCSet creates an empty IOC set
+func NewIOCSet() *IOCSet {
+	return &IOCSet{
+		domains: make(map[string]*IOC),
+		ips:     make(map[string]*IOC),
+	}
+}
+
+// Add adds an indicator, detecting whether value is a URL, domain, IP
+// address or CIDR network
+func (s *IOCSet) Add(value, description string) error {
+	ioc, err := newIOC(value, description)
+	if err != nil {
+		return err
+	}
+	s.index(ioc)
+	s.Indicators = append(s.Indicators, ioc)
+	return nil
+}
+
+// newIOC classifies and normalises an indicator
+func newIOC(value, description string) (*IOC, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil || u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid indicator URL %q", value)
+		}
+		value = u.Hostname()
+	}
+
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return &IOC{Value: network.String(), Kind: IOCNetwork, Description: description}, nil
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		return &IOC{Value: ip.String(), Kind: IOCIP, Description: description}, nil
+	}
+
+	domain := strings.TrimSuffix(strings.ToLower(value), ".")
+	if domain == "" || !strings.Contains(domain, ".") || strings.ContainsAny(domain, " /") {
+		return nil, fmt.Errorf("invalid indicator %q", value)
+	}
+	return &IOC{Value: domain, Kind: IOCDomain, Description: description}, nil
+}
+
+// index adds ioc to the lookup tables
+func (s *IOCSet) index(ioc *IOC) {
+	switch ioc.Kind {
+	case IOCDomain:
+		s.domains[ioc.Value] = ioc
+	case IOCIP:
+		s.ips[ioc.Value] = ioc
+	case IOCNetwork:
+		if _, network, err := net.ParseCIDR(ioc.Value); err == nil {
+			s.networks = append(s.networks, &iocNetwork{network: network, ioc: ioc})
+		}
+	}
+}
+
+// Count returns the number of indicators
+func (s *IOCSet) Count() int {
+	return len(s.Indicators)
+}
+
+// LookupDomain returns the indicator listing host or one of its parent domains
+func (s *IOCSet) LookupDomain(host string) *IOC {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for host != "" {
+		if ioc, ok := s.domains[host]; ok {
+			return ioc
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			break
+		}
+		host = host[dot+1:]
+	}
+	return nil
+}
+
+// LookupIP returns the indicator listing ip, either directly or by network
+func (s *IOCSet) LookupIP(ip net.IP) *IOC {
+	if ioc, ok := s.ips[ip.String()]; ok {
+		return ioc
+	}
+	for _, n := range s.networks {
+		if n.network.Contains(ip) {
+			return n.ioc
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes an IOC set and rebuilds its lookup tables
+func (s *IOCSet) UnmarshalJSON(data []byte) error {
+	type iocSetJSON IOCSet
+	var decoded iocSetJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("decoding IOC set: %w", err)
+	}
+
+	*s = *NewIOCSet()
+	s.UpdateTime = decoded.UpdateTime
+	for _, ioc := range decoded.Indicators {
+		if err := s.Add(ioc.Value, ioc.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseIOCSet parses an IOC feed. JSON feeds use the IOCSet encoding; any
+// other input is read as lines of "<indicator> [description]", with #
+// comments. Indicators may be domains, URLs, IP addresses or CIDR ranges.
+func ParseIOCSet(data []byte) (*IOCSet, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		s := NewIOCSet()
+		if err := json.Unmarshal(trimmed, s); err != nil {
+			return nil, fmt.Errorf("parsing IOC feed: %w", err)
+		}
+		return s, nil
+	}
+
+	s := NewIOCSet()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if err := s.Add(fields[0], strings.Join(fields[1:], " ")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading IOC feed: %w", err)
+	}
+	return s, nil
+}
+
+// IOCLoader loads and saves IOC sets in the cache
+type IOCLoader struct {
+	cache    cache.Cache
+	cacheKey string
+	maxAge   time.Duration
+}
+
+// NewIOCLoader creates an IOC loader caching under a key derived from source
+func NewIOCLoader(c cache.Cache, source string) *IOCLoader {
+	return &IOCLoader{
+		cache:    c,
+		cacheKey: "iocs:" + source,
+		maxAge:   24 * time.Hour,
+	}
+}
+
+// Load loads the IOC set from cache
+func (l *IOCLoader) Load(ctx context.Context) (*IOCSet, error) {
+	s, err := cache.Load(ctx, l.cache, l.cacheKey, l.maxAge, decodeIOCSet, nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading cached IOCs: %w", err)
+	}
+	return s, nil
+}
+
+// decodeIOCSet decodes a cached IOC set
+func decodeIOCSet(data []byte) (*IOCSet, error) {
+	s := NewIOCSet()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached IOCs: %w", err)
+	}
+	return s, nil
+}
+
+// Save saves the IOC set to cache
+func (l *IOCLoader) Save(ctx context.Context, s *IOCSet) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal IOCs: %w", err)
+	}
+	if err := l.cache.Put(ctx, l.cacheKey, data); err != nil {
+		return fmt.Errorf("putting to cache: %w", err)
+	}
+	return nil
+}
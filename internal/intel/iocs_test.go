@@ -0,0 +1,76 @@
+package intel
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+func TestParseIOCSet(t *testing.T) {
+	data := []byte(`# C2 infrastructure
+evil.example      WP-VCD C2
+https://drop.example.net/payload.php
+203.0.113.7
+198.51.100.0/24   bulletproof hosting
+`)
+
+	iocs, err := ParseIOCSet(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if iocs.Count() != 4 {
+		t.Fatalf("expected 4 indicators, got %d", iocs.Count())
+	}
+
+	tests := []struct {
+		name  string
+		ioc   *IOC
+		found bool
+	}{
+		{"exact domain", iocs.LookupDomain("evil.example"), true},
+		{"subdomain", iocs.LookupDomain("cdn.EVIL.example"), true},
+		{"url host", iocs.LookupDomain("drop.example.net"), true},
+		{"parent of listed host", iocs.LookupDomain("example.net"), false},
+		{"unrelated domain", iocs.LookupDomain("wordpress.org"), false},
+		{"ip", iocs.LookupIP(net.ParseIP("203.0.113.7")), true},
+		{"network", iocs.LookupIP(net.ParseIP("198.51.100.42")), true},
+		{"other ip", iocs.LookupIP(net.ParseIP("192.0.2.1")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.ioc != nil) != tt.found {
+				t.Errorf("expected found=%v, got %+v", tt.found, tt.ioc)
+			}
+		})
+	}
+
+	if ioc := iocs.LookupDomain("evil.example"); ioc.Description != "WP-VCD C2" {
+		t.Errorf("expected description 'WP-VCD C2', got %q", ioc.Description)
+	}
+}
+
+func TestIOCSetJSONRoundTrip(t *testing.T) {
+	iocs := NewIOCSet()
+	if err := iocs.Add("evil.example", "c2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(iocs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, err := ParseIOCSet(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.LookupDomain("a.evil.example") == nil {
+		t.Error("expected decoded set to be indexed")
+	}
+}
+
+func TestParseIOCSetInvalid(t *testing.T) {
+	if _, err := ParseIOCSet([]byte("localhost\n")); err == nil {
+		t.Error("expected error for indicator without a dot")
+	}
+}
@@ -33,28 +33,22 @@ func NewSignatureLoader(c cache.Cache) *SignatureLoader {
 }
 
 // Load loads signatures from cache or returns nil if not cached
-func (l *SignatureLoader) Load() (*SignatureSet, error) {
-	data, err := l.cache.Get(l.cacheKey, l.maxAge)
+func (l *SignatureLoader) Load(ctx context.Context) (*SignatureSet, error) {
+	sigSet, err := cache.Load(ctx, l.cache, l.cacheKey, l.maxAge, decodeSignatureSet, nil)
 	if err != nil {
 		return nil, err
 	}
-
-	var sigSet SignatureSet
-	if err := json.Unmarshal(data, &sigSet); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal cached signatures: %w", err)
-	}
-
-	return &sigSet, nil
+	return sigSet, nil
 }
 
 // Save saves signatures to cache
-func (l *SignatureLoader) Save(sigSet *SignatureSet) error {
+func (l *SignatureLoader) Save(ctx context.Context, sigSet *SignatureSet) error {
 	data, err := json.Marshal(sigSet)
 	if err != nil {
 		return fmt.Errorf("failed to marshal signatures: %w", err)
 	}
 
-	return l.cache.Put(l.cacheKey, data)
+	return l.cache.Put(ctx, l.cacheKey, data)
 }
 
 // HasEmbedded returns true if rules are embedded in the binary
@@ -76,7 +70,7 @@ func GetEmbedded() (*SignatureSet, error) {
 // LoadOrFetch loads from embedded/cache, or calls fetchFn to get fresh data
 func (l *SignatureLoader) LoadOrFetch(ctx context.Context, fetchFn func(context.Context) (*SignatureSet, error)) (*SignatureSet, error) {
 	// Try cache first (may have fresher rules)
-	sigSet, err := l.Load()
+	sigSet, err := l.Load(ctx)
 	if err == nil {
 		return sigSet, nil
 	}
@@ -94,7 +88,7 @@ func (l *SignatureLoader) LoadOrFetch(ctx context.Context, fetchFn func(context.
 	}
 
 	// Cache for next time
-	_ = l.Save(sigSet)
+	_ = l.Save(ctx, sigSet)
 
 	return sigSet, nil
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -28,6 +29,8 @@ type Signature struct {
 	Rule          string `json:"rule"` // PCRE pattern
 	Name          string `json:"name"`
 	Description   string `json:"description"`
+	Category      string `json:"category,omitempty"`
+	Type          int    `json:"type,omitempty"` // 0 = malware, non-zero = other
 	CommonStrings []int  `json:"common_strings"` // Indices into SignatureSet.CommonStrings
 }
 
@@ -113,12 +116,88 @@ func (ss *SignatureSet) Count() int {
 	return len(ss.Signatures)
 }
 
-// GetHash returns a hash of the signature set for cache invalidation
+// IDs returns all signature IDs in ascending order
+func (ss *SignatureSet) IDs() []int {
+	ids := make([]int, 0, len(ss.Signatures))
+	for id := range ss.Signatures {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Subset returns a new set containing only the given signatures and the
+// common strings they reference. Unknown IDs are ignored.
+func (ss *SignatureSet) Subset(ids ...int) *SignatureSet {
+	subset := NewSignatureSet()
+	subset.UpdateTime = ss.UpdateTime
+	remap := make(map[int]int)
+
+	for _, id := range ids {
+		sig, ok := ss.Signatures[id]
+		if !ok {
+			continue
+		}
+
+		copied := *sig
+		copied.CommonStrings = make([]int, 0, len(sig.CommonStrings))
+		for _, idx := range sig.CommonStrings {
+			if idx < 0 || idx >= len(ss.CommonStrings) {
+				continue
+			}
+			newIdx, ok := remap[idx]
+			if !ok {
+				newIdx = len(subset.CommonStrings)
+				remap[idx] = newIdx
+				subset.CommonStrings = append(subset.CommonStrings, NewCommonString(ss.CommonStrings[idx].String))
+			}
+			copied.CommonStrings = append(copied.CommonStrings, newIdx)
+			subset.CommonStrings[newIdx].SignatureIDs = append(subset.CommonStrings[newIdx].SignatureIDs, id)
+		}
+
+		subset.Signatures[id] = &copied
+	}
+
+	return subset
+}
+
+// FilterCategories returns a subset containing only signatures whose
+// category matches one of categories, compared case-insensitively
+func (ss *SignatureSet) FilterCategories(categories []string) *SignatureSet {
+	var ids []int
+	for _, id := range ss.IDs() {
+		for _, category := range categories {
+			if strings.EqualFold(ss.Signatures[id].Category, strings.TrimSpace(category)) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ss.Subset(ids...)
+}
+
+// Categories returns the distinct signature categories in sorted order
+func (ss *SignatureSet) Categories() []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, sig := range ss.Signatures {
+		if sig.Category != "" && !seen[sig.Category] {
+			seen[sig.Category] = true
+			categories = append(categories, sig.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// GetHash returns a hash of the signature set for cache invalidation.
+// Signatures are hashed in ID order, so equal sets always hash the same.
 func (ss *SignatureSet) GetHash() []byte {
 	h := sha256.New()
 	delimiter := ";"
 
-	for _, sig := range ss.Signatures {
+	for _, id := range ss.IDs() {
+		sig := ss.Signatures[id]
 		// Build common strings portion
 		commonStrs := make([]string, 0, len(sig.CommonStrings))
 		for _, idx := range sig.CommonStrings {
@@ -189,6 +268,8 @@ func ParseSignatureSet(commonStrings []string, rules []*RawSignatureRule, update
 			rule.Description,
 			rule.CommonStrings,
 		)
+		sig.Category = rule.Category
+		sig.Type = rule.Type
 
 		ss.Signatures[sig.ID] = sig
 
@@ -239,6 +320,15 @@ func (ss *SignatureSet) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// decodeSignatureSet decodes a cached signature set
+func decodeSignatureSet(data []byte) (*SignatureSet, error) {
+	var sigSet SignatureSet
+	if err := json.Unmarshal(data, &sigSet); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached signatures: %w", err)
+	}
+	return &sigSet, nil
+}
+
 // ParseRawAPIResponse parses the raw API response format from get_patterns
 // The format is: rules: array of [id, timestamp, rule, description, scope, enabled, category, name, commonStrings[]]
 func ParseRawAPIResponse(data []byte) (*SignatureSet, error) {
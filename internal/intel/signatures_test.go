@@ -1,7 +1,9 @@
 package intel
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -129,6 +131,61 @@ func TestSignatureSetGetCommonStringsForSignature(t *testing.T) {
 	}
 }
 
+func TestSignatureSetIDs(t *testing.T) {
+	ss := NewSignatureSet()
+	for _, id := range []int{30, 10, 20} {
+		ss.Signatures[id] = NewSignature(id, "test", "Test", "", nil)
+	}
+
+	ids := ss.IDs()
+	if len(ids) != 3 || ids[0] != 10 || ids[1] != 20 || ids[2] != 30 {
+		t.Errorf("expected sorted IDs [10 20 30], got %v", ids)
+	}
+}
+
+func TestSignatureSetSubset(t *testing.T) {
+	ss := NewSignatureSet()
+	ss.CommonStrings = append(ss.CommonStrings, NewCommonString("eval"))
+	ss.CommonStrings = append(ss.CommonStrings, NewCommonString("base64"))
+	ss.Signatures[1] = NewSignature(1, "eval", "Eval", "", []int{0})
+	ss.Signatures[2] = NewSignature(2, "base64", "Base64", "", []int{1})
+	ss.CommonStrings[0].SignatureIDs = []int{1}
+	ss.CommonStrings[1].SignatureIDs = []int{2}
+
+	subset := ss.Subset(2, 99)
+	if subset.Count() != 1 || !subset.HasSignature(2) {
+		t.Fatalf("expected subset with only signature 2, got %v", subset.IDs())
+	}
+	if len(subset.CommonStrings) != 1 || subset.CommonStrings[0].String != "base64" {
+		t.Fatalf("expected only the base64 common string, got %+v", subset.CommonStrings)
+	}
+	if got := subset.Signatures[2].CommonStrings; len(got) != 1 || got[0] != 0 {
+		t.Errorf("expected common string index remapped to 0, got %v", got)
+	}
+	if ss.Signatures[2].CommonStrings[0] != 1 {
+		t.Error("expected original signature to be unchanged")
+	}
+}
+
+func TestSignatureSetFilterCategories(t *testing.T) {
+	ss := NewSignatureSet()
+	ss.Signatures[1] = NewSignature(1, "a", "A", "", nil)
+	ss.Signatures[1].Category = "backdoor"
+	ss.Signatures[2] = NewSignature(2, "b", "B", "", nil)
+	ss.Signatures[2].Category = "spam"
+	ss.Signatures[3] = NewSignature(3, "c", "C", "", nil)
+
+	filtered := ss.FilterCategories([]string{"BACKDOOR"})
+	if filtered.Count() != 1 || !filtered.HasSignature(1) {
+		t.Errorf("expected only signature 1, got %v", filtered.IDs())
+	}
+
+	categories := ss.Categories()
+	if len(categories) != 2 || categories[0] != "backdoor" || categories[1] != "spam" {
+		t.Errorf("expected [backdoor spam], got %v", categories)
+	}
+}
+
 func TestParseSignatureSet(t *testing.T) {
 	commonStrings := []string{"eval", "base64_decode"}
 	rules := []*RawSignatureRule{
@@ -187,6 +244,11 @@ func TestParseSignatureSet(t *testing.T) {
 		t.Error("disabled rule should not be in signature set")
 	}
 
+	// Check category is preserved
+	if sig, _ := ss.GetSignature(1); sig == nil || sig.Category != "malware" {
+		t.Error("expected signature 1 to keep its category")
+	}
+
 	// Check update time
 	if ss.UpdateTime != 12345 {
 		t.Errorf("expected update time 12345, got %d", ss.UpdateTime)
@@ -252,3 +314,23 @@ func TestSignatureSetGetHash(t *testing.T) {
 		t.Error("different signature sets should have different hashes")
 	}
 }
+
+func TestSignatureSetGetHashStable(t *testing.T) {
+	build := func(ids ...int) *SignatureSet {
+		ss := NewSignatureSet()
+		for _, id := range ids {
+			ss.Signatures[id] = NewSignature(id, fmt.Sprintf("rule%d", id), "Test", "", nil)
+		}
+		return ss
+	}
+
+	want := build(1, 2, 3, 4, 5).GetHash()
+	for i := 0; i < 20; i++ {
+		if got := build(5, 3, 1, 4, 2).GetHash(); !bytes.Equal(got, want) {
+			t.Fatalf("expected equal sets to hash the same, got %x and %x", got, want)
+		}
+	}
+	if bytes.Equal(build(1, 2).GetHash(), want) {
+		t.Error("expected different sets to hash differently")
+	}
+}
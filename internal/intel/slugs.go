@@ -0,0 +1,207 @@
+// Package intel provides identification of installed software in the vulnerability feed
+package intel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Identification records how installed software was matched to the feed
+type Identification string
+
+const (
+	// IdentifiedBySlug matched the directory name exactly
+	IdentifiedBySlug Identification = "slug"
+	// IdentifiedByPackageURL matched a package URL given for the software
+	IdentifiedByPackageURL Identification = "package URL"
+	// IdentifiedByTextDomain matched the header's Text Domain
+	IdentifiedByTextDomain Identification = "text domain"
+	// IdentifiedByName matched the header's name
+	IdentifiedByName Identification = "name"
+	// IdentifiedByDirectory matched the directory name once decorations
+	// such as "-nulled" or a version were removed
+	IdentifiedByDirectory Identification = "directory name"
+)
+
+// Package URL types for WordPress plugins and themes
+const (
+	PackageURLTypePlugin = "wordpress-plugin"
+	PackageURLTypeTheme  = "wordpress-theme"
+)
+
+// slugDecorations are words added to the directory names of copied,
+// pirated or archived extensions
+var slugDecorations = map[string]bool{
+	"nulled":   true,
+	"null":     true,
+	"cracked":  true,
+	"gpl":      true,
+	"master":   true,
+	"main":     true,
+	"trunk":    true,
+	"latest":   true,
+	"old":      true,
+	"backup":   true,
+	"bak":      true,
+	"copy":     true,
+	"orig":     true,
+	"original": true,
+	"disabled": true,
+}
+
+// SoftwareIdentity is what identifies an installed plugin or theme
+type SoftwareIdentity struct {
+	Slug       string // Directory or file name
+	Name       string // Header name
+	TextDomain string // Header text domain
+	PackageURL string // Optional package URL, e.g. pkg:wordpress-plugin/elementor-pro
+}
+
+// Identify returns the feed slug of installed software and how it was
+// identified. An explicit package URL wins; otherwise the directory name
+// is tried, then the header's text domain and name, then the directory
+// name without decorations. The slug is "" when nothing matches a slug
+// with known vulnerabilities.
+func (vi *VulnerabilityIndex) Identify(softwareType SoftwareType, id SoftwareIdentity) (string, Identification) {
+	if id.PackageURL != "" {
+		if purlType, slug, _, err := ParsePackageURL(id.PackageURL); err == nil && purlType == softwareType {
+			return slug, IdentifiedByPackageURL
+		}
+	}
+
+	typeIndex := vi.byType[softwareType]
+	if typeIndex[id.Slug] != nil {
+		return id.Slug, IdentifiedBySlug
+	}
+	if slug := normalizeSlug(id.TextDomain); typeIndex[slug] != nil {
+		return slug, IdentifiedByTextDomain
+	}
+	if slug := vi.names[softwareType][normalizeName(id.Name)]; slug != "" {
+		return slug, IdentifiedByName
+	}
+
+	words := strings.Split(normalizeSlug(id.Slug), "-")
+	for len(words) > 1 {
+		if n := versionWords(words); n > 0 && n < len(words) {
+			words = words[:len(words)-n]
+		} else if slugDecorations[words[len(words)-1]] {
+			words = words[:len(words)-1]
+		} else if slugDecorations[words[0]] {
+			words = words[1:]
+		} else {
+			return "", ""
+		}
+		if slug := strings.Join(words, "-"); typeIndex[slug] != nil {
+			return slug, IdentifiedByDirectory
+		}
+	}
+	return "", ""
+}
+
+// versionWords returns how many words at the end of a directory name are
+// a dotted version, such as "5", "3", "1" from "akismet-5.3.1". A single
+// number is part of slugs like contact-form-7, so it is not a version.
+func versionWords(words []string) int {
+	n := 0
+	for n < len(words) {
+		// A version may start with "v"
+		word := strings.TrimPrefix(words[len(words)-1-n], "v")
+		if word == "" || strings.Trim(word, "0123456789") != "" {
+			break
+		}
+		n++
+	}
+	if n < 2 {
+		return 0
+	}
+	return n
+}
+
+// ParsePackageURL parses a package URL for a WordPress plugin or theme,
+// such as pkg:wordpress-plugin/elementor-pro@3.18.0. The version is ""
+// when the URL has none.
+func ParsePackageURL(purl string) (SoftwareType, string, string, error) {
+	rest, ok := strings.CutPrefix(purl, "pkg:")
+	if !ok {
+		return "", "", "", fmt.Errorf("package URL %q does not start with pkg:", purl)
+	}
+	// Qualifiers and subpath don't identify the package
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	rest = strings.TrimLeft(rest, "/")
+
+	purlType, name, ok := strings.Cut(rest, "/")
+	if !ok || name == "" {
+		return "", "", "", fmt.Errorf("package URL %q has no name", purl)
+	}
+	var softwareType SoftwareType
+	switch strings.ToLower(purlType) {
+	case PackageURLTypePlugin:
+		softwareType = SoftwareTypePlugin
+	case PackageURLTypeTheme:
+		softwareType = SoftwareTypeTheme
+	default:
+		return "", "", "", fmt.Errorf("package URL %q is not a WordPress plugin or theme", purl)
+	}
+
+	name, version, _ := strings.Cut(name, "@")
+	slug, err := url.PathUnescape(name)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid package URL %q: %w", purl, err)
+	}
+	if version, err = url.PathUnescape(version); err != nil {
+		return "", "", "", fmt.Errorf("invalid package URL %q: %w", purl, err)
+	}
+	return softwareType, strings.ToLower(slug), version, nil
+}
+
+// ParsePackageURLMap parses lines of a directory and the package URL of
+// the plugin or theme installed there, such as
+//
+//	wp-content/plugins/elementor-pro-nulled pkg:wordpress-plugin/elementor-pro
+//
+// The directory may be a path or just the directory name. Blank lines and
+// lines starting with # are ignored.
+func ParsePackageURLMap(data []byte) (map[string]string, error) {
+	purls := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Directories may contain spaces; package URLs can't
+		i := strings.LastIndexAny(line, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected a directory and a package URL", lineNo)
+		}
+		dir, purl := strings.TrimSpace(line[:i]), line[i+1:]
+		if _, _, _, err := ParsePackageURL(purl); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		purls[dir] = purl
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading package URLs: %w", err)
+	}
+	return purls, nil
+}
+
+// normalizeSlug lowercases s and joins its words with hyphens
+func normalizeSlug(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	}), "-")
+}
+
+// normalizeName reduces a software name to lowercase words, so header
+// and feed names compare regardless of case and punctuation
+func normalizeName(name string) string {
+	return strings.ReplaceAll(normalizeSlug(name), "-", " ")
+}
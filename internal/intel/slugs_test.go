@@ -0,0 +1,112 @@
+package intel
+
+import "testing"
+
+// testIdentifyIndex indexes one vulnerability in each of a few plugins
+func testIdentifyIndex() *VulnerabilityIndex {
+	index := NewVulnerabilityIndex()
+	for i, sw := range []struct{ slug, name string }{
+		{"elementor-pro", "Elementor Pro"},
+		{"contact-form-7", "Contact Form 7"},
+		{"akismet", "Akismet Anti-spam: Spam Protection"},
+		{"copy-a", "Duplicate Name"},
+		{"copy-b", "Duplicate Name"},
+	} {
+		index.Add(&Vulnerability{
+			ID: string(rune('a' + i)),
+			Software: []*Software{{
+				Type: SoftwareTypePlugin,
+				Name: sw.name,
+				Slug: sw.slug,
+				AffectedVersions: map[string]*VersionRange{
+					"*": {FromVersion: VersionAny, ToVersion: VersionAny},
+				},
+			}},
+		})
+	}
+	return index
+}
+
+func TestIdentify(t *testing.T) {
+	index := testIdentifyIndex()
+
+	tests := []struct {
+		name     string
+		id       SoftwareIdentity
+		wantSlug string
+		wantHow  Identification
+	}{
+		{"exact slug", SoftwareIdentity{Slug: "akismet"}, "akismet", IdentifiedBySlug},
+		{"package URL", SoftwareIdentity{Slug: "forms", PackageURL: "pkg:wordpress-plugin/contact-form-7@5.8"}, "contact-form-7", IdentifiedByPackageURL},
+		{"package URL for a theme", SoftwareIdentity{Slug: "akismet", PackageURL: "pkg:wordpress-theme/astra"}, "akismet", IdentifiedBySlug},
+		{"text domain", SoftwareIdentity{Slug: "ep", TextDomain: "elementor-pro"}, "elementor-pro", IdentifiedByTextDomain},
+		{"name", SoftwareIdentity{Slug: "ep", Name: "elementor  PRO"}, "elementor-pro", IdentifiedByName},
+		{"ambiguous name", SoftwareIdentity{Slug: "dup", Name: "Duplicate Name"}, "", ""},
+		{"nulled", SoftwareIdentity{Slug: "elementor-pro-nulled"}, "elementor-pro", IdentifiedByDirectory},
+		{"leading decoration", SoftwareIdentity{Slug: "nulled_Elementor-Pro"}, "elementor-pro", IdentifiedByDirectory},
+		{"version", SoftwareIdentity{Slug: "akismet-5.3.1"}, "akismet", IdentifiedByDirectory},
+		{"decoration and version", SoftwareIdentity{Slug: "akismet-v5.3-master"}, "akismet", IdentifiedByDirectory},
+		{"number kept", SoftwareIdentity{Slug: "contact-form-7-nulled"}, "contact-form-7", IdentifiedByDirectory},
+		{"single number not a version", SoftwareIdentity{Slug: "akismet-2"}, "", ""},
+		{"unknown words kept", SoftwareIdentity{Slug: "elementor-pro-addons"}, "", ""},
+		{"unknown", SoftwareIdentity{Slug: "custom-plugin", Name: "Custom", TextDomain: "custom"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slug, how := index.Identify(SoftwareTypePlugin, tt.id)
+			if slug != tt.wantSlug || how != tt.wantHow {
+				t.Errorf("expected %q by %q, got %q by %q", tt.wantSlug, tt.wantHow, slug, how)
+			}
+		})
+	}
+}
+
+func TestParsePackageURL(t *testing.T) {
+	tests := []struct {
+		purl     string
+		wantType SoftwareType
+		wantSlug string
+		wantVer  string
+		wantErr  bool
+	}{
+		{"pkg:wordpress-plugin/elementor-pro@3.18.0", SoftwareTypePlugin, "elementor-pro", "3.18.0", false},
+		{"pkg:WordPress-Theme/Astra", SoftwareTypeTheme, "astra", "", false},
+		{"pkg:wordpress-plugin/my%20plugin@1.0-beta?repository_url=x#sub", SoftwareTypePlugin, "my plugin", "1.0-beta", false},
+		{"pkg:npm/left-pad@1.0", "", "", "", true},
+		{"pkg:wordpress-plugin", "", "", "", true},
+		{"wordpress-plugin/akismet", "", "", "", true},
+	}
+	for _, tt := range tests {
+		softwareType, slug, version, err := ParsePackageURL(tt.purl)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.purl, err)
+			continue
+		}
+		if softwareType != tt.wantType || slug != tt.wantSlug || version != tt.wantVer {
+			t.Errorf("%s: got %q %q %q", tt.purl, softwareType, slug, version)
+		}
+	}
+}
+
+func TestParsePackageURLMap(t *testing.T) {
+	data := []byte(`# Renamed extensions
+wp-content/plugins/custom forms  pkg:wordpress-plugin/contact-form-7
+renamed-theme	pkg:wordpress-theme/astra
+`)
+	purls, err := ParsePackageURLMap(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(purls) != 2 ||
+		purls["wp-content/plugins/custom forms"] != "pkg:wordpress-plugin/contact-form-7" ||
+		purls["renamed-theme"] != "pkg:wordpress-theme/astra" {
+		t.Errorf("unexpected package URLs: %v", purls)
+	}
+
+	if _, err := ParsePackageURLMap([]byte("plugin pkg:npm/left-pad\n")); err == nil {
+		t.Error("expected error for a package URL that isn't a plugin or theme")
+	}
+	if _, err := ParsePackageURLMap([]byte("just-a-directory\n")); err == nil {
+		t.Error("expected error for a line without a package URL")
+	}
+}
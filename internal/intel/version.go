@@ -0,0 +1,204 @@
+// Package intel provides version comparison compatible with PHP's version_compare
+package intel
+
+import "strings"
+
+// versionForms orders the special forms of version_compare. Forms match
+// by prefix, in this order, and unknown forms sort before all of them.
+var versionForms = []struct {
+	name  string
+	order int
+}{
+	{"dev", 0},
+	{"alpha", 1},
+	{"a", 1},
+	{"beta", 2},
+	{"b", 2},
+	{"RC", 3},
+	{"rc", 3},
+	{"#", 4},
+	{"pl", 5},
+	{"p", 5},
+}
+
+// numberForm stands in for a numeric part when comparing it to a form, so
+// "1.0" sorts after "1.0RC1" but before "1.0pl1"
+const numberForm = "#N#"
+
+// CompareVersions compares two version strings the way PHP's
+// version_compare does, which is how WordPress and the vulnerability feed
+// order versions. Returns -1 if v1 < v2, 0 if v1 == v2, 1 if v1 > v2.
+//
+// Versions are split into numeric and named parts, so "6.4.2-beta1" is
+// 6, 4, 2, "beta", 1 and sorts before "6.4.2". Named parts order as
+// dev < alpha = a < beta = b < RC = rc < numbers < pl = p; any other name
+// sorts before dev. A version with extra numeric parts is newer, so
+// "1.0.0" sorts after "1.0".
+func CompareVersions(v1, v2 string) int {
+	if v1 == "" || v2 == "" {
+		switch {
+		case v1 == v2:
+			return 0
+		case v1 != "":
+			return 1
+		default:
+			return -1
+		}
+	}
+
+	ver1, ver2 := v1, v2
+	if ver1[0] != '#' {
+		ver1 = canonicalizeVersion(ver1)
+	}
+	if ver2[0] != '#' {
+		ver2 = canonicalizeVersion(ver2)
+	}
+
+	// more1 and more2 record whether the last part compared was followed
+	// by another
+	compare := 0
+	more1, more2 := true, true
+	for ver1 != "" && ver2 != "" && more1 && more2 {
+		var part1, part2, rest1, rest2 string
+		part1, rest1, more1 = strings.Cut(ver1, ".")
+		part2, rest2, more2 = strings.Cut(ver2, ".")
+
+		switch digit1, digit2 := startsWithDigit(part1), startsWithDigit(part2); {
+		case digit1 && digit2:
+			compare = compareNumbers(part1, part2)
+		case !digit1 && !digit2:
+			compare = compareVersionForms(part1, part2)
+		case digit1:
+			compare = compareVersionForms(numberForm, part2)
+		default:
+			compare = compareVersionForms(part1, numberForm)
+		}
+		if compare != 0 {
+			return compare
+		}
+
+		if more1 {
+			ver1 = rest1
+		}
+		if more2 {
+			ver2 = rest2
+		}
+	}
+
+	switch {
+	case more1 && startsWithDigit(ver1):
+		return 1
+	case more1:
+		return CompareVersions(ver1, numberForm)
+	case more2 && startsWithDigit(ver2):
+		return -1
+	case more2:
+		return CompareVersions(numberForm, ver2)
+	}
+	return 0
+}
+
+// canonicalizeVersion separates a version's parts with dots: "-", "_",
+// "+" and other punctuation become dots, and dots are inserted between
+// digits and letters, so "1.0-RC1" becomes "1.0.RC.1"
+func canonicalizeVersion(version string) string {
+	var b strings.Builder
+	b.Grow(len(version) * 2)
+
+	// Written as b's last byte, since the builder can't be read back
+	last := version[0]
+	b.WriteByte(last)
+	dot := func() {
+		if last != '.' {
+			b.WriteByte('.')
+			last = '.'
+		}
+	}
+
+	prev := version[0]
+	for i := 1; i < len(version); i++ {
+		c := version[i]
+		switch {
+		case c == '-' || c == '_' || c == '+':
+			dot()
+		case isVersionTransition(prev, c):
+			dot()
+			b.WriteByte(c)
+			last = c
+		case !isAlnum(c):
+			dot()
+		default:
+			b.WriteByte(c)
+			last = c
+		}
+		prev = c
+	}
+	return b.String()
+}
+
+// isVersionTransition reports whether a version switches between digits
+// and other characters, dots aside, from a to b
+func isVersionTransition(a, b byte) bool {
+	if a == '.' || b == '.' {
+		return false
+	}
+	return isDigit(a) != isDigit(b)
+}
+
+// compareNumbers compares the numbers two parts start with, without
+// overflowing
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(leadingDigits(a), "0")
+	b = strings.TrimLeft(leadingDigits(b), "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func leadingDigits(s string) string {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// compareVersionForms compares two named version parts
+func compareVersionForms(form1, form2 string) int {
+	order1, order2 := versionFormOrder(form1), versionFormOrder(form2)
+	switch {
+	case order1 < order2:
+		return -1
+	case order1 > order2:
+		return 1
+	}
+	return 0
+}
+
+// versionFormOrder returns where a named part sorts, or -1 if it is not a
+// special form
+func versionFormOrder(form string) int {
+	for _, f := range versionForms {
+		if strings.HasPrefix(form, f.name) {
+			return f.order
+		}
+	}
+	return -1
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && isDigit(s[0])
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,94 @@
+package intel
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	// Expected results are those of PHP's version_compare
+	tests := []struct {
+		v1, v2 string
+		want   int
+	}{
+		// Numeric parts
+		{"1.0", "1.0", 0},
+		{"1.10", "1.9", 1},
+		{"1.01", "1.1", 0},
+		{"6.4.2", "6.4.10", -1},
+		{"1-0", "1.0", 0},
+		{"1_0", "1.0", 0},
+		{"1.0", "1.0.1", -1},
+		{"5.2", "5.2.0", -1},
+		{"1.0.0", "1.0", 1},
+		{"99999999999999999999", "99999999999999999998", 1},
+
+		// Pre-releases sort before the release
+		{"6.4.2-beta1", "6.4.2", -1},
+		{"1.0-RC1", "1.0", -1},
+		{"1.0RC1", "1.0", -1},
+		{"1.0-dev", "1.0", -1},
+		{"1.0-beta1", "1.0-beta2", -1},
+		{"1.0rc1", "1.0RC2", -1},
+		{"6.4-RC1", "6.4.0", -1},
+
+		// But a pre-release of a longer version sorts after a shorter one
+		{"1.0.0-RC1", "1.0", 1},
+
+		// Special forms
+		{"1.0-dev", "1.0alpha", -1},
+		{"1.0alpha", "1.0beta", -1},
+		{"1.0beta", "1.0RC", -1},
+		{"1.0a1", "1.0alpha1", 0},
+		{"1.0b1", "1.0beta1", 0},
+		{"1.0RC1", "1.0rc1", 0},
+		{"1.0-p1", "1.0pl1", 0},
+		{"1.0", "1.0pl1", -1},
+		{"1.0.1", "1.0-pl1", -1},
+		{"1.0-foo", "1.0-dev", -1},
+		{"1.0", "1.0-foo", 1},
+		{"1.0-Beta1", "1.0-dev", -1},
+		{"1.0+build5", "1.0", -1},
+
+		// Empty versions
+		{"", "", 0},
+		{"", "1.0", -1},
+		{"1.0.", "1.0.0", -1},
+		{".5", "0.5", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.v1, tt.v2); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+		}
+		if got := CompareVersions(tt.v2, tt.v1); got != -tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.v2, tt.v1, got, -tt.want)
+		}
+	}
+}
+
+func TestCanonicalizeVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.0-RC1":    "1.0.RC.1",
+		"6.4.2beta1": "6.4.2.beta.1",
+		"1.0__2":     "1.0.2",
+		"1..0":       "1.0",
+		"1.0+b/5":    "1.0.b.5",
+	}
+	for version, want := range tests {
+		if got := canonicalizeVersion(version); got != want {
+			t.Errorf("canonicalizeVersion(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestVersionRangeIncludesPreRelease(t *testing.T) {
+	fixedIn := &VersionRange{FromVersion: VersionAny, ToVersion: "6.4.2", ToInclusive: false}
+	if !fixedIn.Includes("6.4.2-beta1") {
+		t.Error("expected a pre-release of the fixed version to be affected")
+	}
+	if fixedIn.Includes("6.4.2") {
+		t.Error("expected the fixed version not to be affected")
+	}
+
+	introducedIn := &VersionRange{FromVersion: "2.0", FromInclusive: true, ToVersion: VersionAny}
+	if introducedIn.Includes("2.0-RC1") {
+		t.Error("expected a release candidate before the affected release not to be affected")
+	}
+}
@@ -4,8 +4,6 @@ package intel
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 )
 
@@ -130,6 +128,9 @@ func (v *Vulnerability) IsAffected(softwareType SoftwareType, slug, version stri
 type VulnerabilityIndex struct {
 	vulnerabilities map[string]*Vulnerability
 	byType          map[SoftwareType]map[string][]*indexEntry
+	// names maps normalized software names to slugs, or to "" when a
+	// name is shared by several slugs
+	names map[SoftwareType]map[string]string
 }
 
 type indexEntry struct {
@@ -146,6 +147,7 @@ func NewVulnerabilityIndex() *VulnerabilityIndex {
 			SoftwareTypePlugin: make(map[string][]*indexEntry),
 			SoftwareTypeTheme:  make(map[string][]*indexEntry),
 		},
+		names: make(map[SoftwareType]map[string]string),
 	}
 }
 
@@ -160,6 +162,8 @@ func (vi *VulnerabilityIndex) Add(vuln *Vulnerability) {
 			vi.byType[sw.Type] = typeIndex
 		}
 
+		vi.addName(sw)
+
 		for _, vr := range sw.AffectedVersions {
 			entry := &indexEntry{
 				versionRange: vr,
@@ -170,11 +174,43 @@ func (vi *VulnerabilityIndex) Add(vuln *Vulnerability) {
 	}
 }
 
+// addName indexes the name of affected software
+func (vi *VulnerabilityIndex) addName(sw *Software) {
+	name := normalizeName(sw.Name)
+	if name == "" {
+		return
+	}
+	typeNames := vi.names[sw.Type]
+	if typeNames == nil {
+		typeNames = make(map[string]string)
+		vi.names[sw.Type] = typeNames
+	}
+	if slug, ok := typeNames[name]; ok && slug != sw.Slug {
+		typeNames[name] = ""
+		return
+	}
+	typeNames[name] = sw.Slug
+}
+
 // Get retrieves a vulnerability by ID
 func (vi *VulnerabilityIndex) Get(id string) *Vulnerability {
 	return vi.vulnerabilities[id]
 }
 
+// LatestUpdate returns the most recent published or updated time of any
+// vulnerability, as given in the feed, which dates the feed itself
+func (vi *VulnerabilityIndex) LatestUpdate() string {
+	var latest string
+	for _, v := range vi.vulnerabilities {
+		for _, t := range []string{v.Published, v.Updated} {
+			if t > latest {
+				latest = t
+			}
+		}
+	}
+	return latest
+}
+
 // Count returns the total number of vulnerabilities
 func (vi *VulnerabilityIndex) Count() int {
 	return len(vi.vulnerabilities)
@@ -307,68 +343,6 @@ func parseVulnerability(id string, data json.RawMessage) (*Vulnerability, error)
 	return vuln, nil
 }
 
-// CompareVersions compares two version strings
-// Returns -1 if v1 < v2, 0 if v1 == v2, 1 if v1 > v2
-func CompareVersions(v1, v2 string) int {
-	// Normalize versions
-	parts1 := normalizeVersion(v1)
-	parts2 := normalizeVersion(v2)
-
-	// Compare each part
-	maxLen := len(parts1)
-	if len(parts2) > maxLen {
-		maxLen = len(parts2)
-	}
-
-	for i := 0; i < maxLen; i++ {
-		var p1, p2 int
-		if i < len(parts1) {
-			p1 = parts1[i]
-		}
-		if i < len(parts2) {
-			p2 = parts2[i]
-		}
-
-		if p1 < p2 {
-			return -1
-		}
-		if p1 > p2 {
-			return 1
-		}
-	}
-
-	return 0
-}
-
-// normalizeVersion splits a version string into comparable parts
-func normalizeVersion(version string) []int {
-	// Remove common suffixes
-	version = strings.TrimPrefix(version, "v")
-
-	// Split by common delimiters
-	re := regexp.MustCompile(`[.\-_]`)
-	parts := re.Split(version, -1)
-
-	result := make([]int, 0, len(parts))
-	for _, part := range parts {
-		// Extract numeric portion
-		numStr := ""
-		for _, c := range part {
-			if c >= '0' && c <= '9' {
-				numStr += string(c)
-			} else {
-				break
-			}
-		}
-		if numStr != "" {
-			num, _ := strconv.Atoi(numStr)
-			result = append(result, num)
-		}
-	}
-
-	return result
-}
-
 // MarshalJSON implements json.Marshaler for VulnerabilityIndex
 func (vi *VulnerabilityIndex) MarshalJSON() ([]byte, error) {
 	// Marshal as a map of vulnerability ID -> vulnerability
@@ -388,5 +362,6 @@ func (vi *VulnerabilityIndex) UnmarshalJSON(data []byte) error {
 	}
 	vi.vulnerabilities = index.vulnerabilities
 	vi.byType = index.byType
+	vi.names = index.names
 	return nil
 }
@@ -0,0 +1,299 @@
+// Package notify sends scan findings to chat and paging services, such as
+// Slack, Discord and PagerDuty. Routes pick the findings each service gets
+// by kind and severity, so critical malware can page the on-call while
+// low-severity vulnerabilities only post to a channel.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+// Severity ranks how urgent a finding is
+type Severity int
+
+// Severity levels, from least to most urgent
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// severityNames are the names of the severity levels, in order
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+// SeverityNames returns the names of the severity levels, least urgent
+// first
+func SeverityNames() []string {
+	return append([]string(nil), severityNames...)
+}
+
+func (s Severity) String() string {
+	if s < SeverityInfo || s > SeverityCritical {
+		return "unknown"
+	}
+	return severityNames[s]
+}
+
+// MarshalText encodes the severity by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity parses a severity name
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(strings.TrimSpace(name), n) {
+			return Severity(i), nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity %q: use %s", name, strings.Join(severityNames, ", "))
+}
+
+// Kinds of findings
+const (
+	KindMalware       = "malware"
+	KindVulnerability = "vulnerability"
+)
+
+// Kinds returns the kinds of findings routes can select
+func Kinds() []string {
+	return []string{KindMalware, KindVulnerability}
+}
+
+// Notification is a finding sent to the routes that select it
+type Notification struct {
+	Kind     string   `json:"kind"`
+	Severity Severity `json:"severity"`
+	Title    string   `json:"title"`
+	Text     string   `json:"text,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	Link     string   `json:"link,omitempty"`
+	// Site is the domain or directory of the site the finding is on
+	Site string    `json:"site,omitempty"`
+	Host string    `json:"host"`
+	Time time.Time `json:"time"`
+	// Key identifies the finding; a finding is sent once per run
+	Key string `json:"-"`
+	// Ticket groups findings into one issue on ticketing routes, which is
+	// commented on by later findings and later runs (default: Key)
+	Ticket string `json:"-"`
+}
+
+// Provider delivers notifications to a service
+type Provider interface {
+	// Send delivers a batch of notifications, as one message where the
+	// service allows
+	Send(ctx context.Context, batch []*Notification) error
+}
+
+// Defaults for batching notifications
+const (
+	// DefaultBatchWindow is how long the first finding of a batch waits
+	// for others to be sent with it
+	DefaultBatchWindow = 2 * time.Second
+	// DefaultBatchSize is the most findings sent in one message
+	DefaultBatchSize = 20
+	// queueSize is how many findings a route holds before dropping more
+	queueSize = 1000
+)
+
+// Notifier sends findings to the routes that select them, in the
+// background. Close must be called to send the last of them.
+type Notifier struct {
+	routes []*queuedRoute
+	host   string
+	window time.Duration
+	logger *logging.Logger
+
+	mu      sync.Mutex
+	seen    map[string]bool
+	dropped int64
+	sent    int64
+	failed  int64
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// queuedRoute is a route and the findings waiting for it
+type queuedRoute struct {
+	*Route
+	queue chan *Notification
+}
+
+// Option configures a Notifier
+type Option func(*Notifier)
+
+// WithHost sets the host name sent with each finding (default: the
+// hostname)
+func WithHost(host string) Option {
+	return func(n *Notifier) {
+		n.host = host
+	}
+}
+
+// WithBatchWindow sets how long findings wait to be sent together
+func WithBatchWindow(window time.Duration) Option {
+	return func(n *Notifier) {
+		n.window = window
+	}
+}
+
+// WithLogger sets the logger for delivery problems
+func WithLogger(logger *logging.Logger) Option {
+	return func(n *Notifier) {
+		n.logger = logger
+	}
+}
+
+// New starts a notifier sending to routes
+func New(routes []*Route, opts ...Option) *Notifier {
+	hostname, _ := os.Hostname()
+	n := &Notifier{
+		host:   hostname,
+		window: DefaultBatchWindow,
+		logger: logging.GetDefaultLogger(),
+		seen:   make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	for _, route := range routes {
+		q := &queuedRoute{Route: route, queue: make(chan *Notification, queueSize)}
+		n.routes = append(n.routes, q)
+		n.wg.Add(1)
+		go n.run(q)
+	}
+	return n
+}
+
+// Notify queues a finding for the routes that select it. Findings already
+// sent in this run are skipped, and findings are dropped when a route
+// falls far behind.
+func (n *Notifier) Notify(note *Notification) {
+	if n == nil {
+		return
+	}
+	if note.Host == "" {
+		note.Host = n.host
+	}
+	if note.Time.IsZero() {
+		note.Time = time.Now().UTC()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed || (note.Key != "" && n.seen[note.Key]) {
+		return
+	}
+	if note.Key != "" {
+		n.seen[note.Key] = true
+	}
+	for _, route := range n.routes {
+		if !route.Selects(note) {
+			continue
+		}
+		select {
+		case route.queue <- note:
+		default:
+			n.dropped++
+		}
+	}
+}
+
+// Dropped returns how many findings were dropped for routes falling behind
+func (n *Notifier) Dropped() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.dropped
+}
+
+// Sent returns how many findings were delivered, counting each route they
+// were sent to
+func (n *Notifier) Sent() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.sent
+}
+
+// Failed returns how many findings routes failed to deliver
+func (n *Notifier) Failed() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.failed
+}
+
+// Close sends the findings queued and stops the notifier, giving up on
+// those left once ctx is done
+func (n *Notifier) Close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		for _, route := range n.routes {
+			close(route.queue)
+		}
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("closing notifier: %w", ctx.Err())
+	}
+}
+
+// run sends a route's findings in batches until its queue is closed. The
+// first finding of a batch waits for others for the batch window.
+func (n *Notifier) run(route *queuedRoute) {
+	defer n.wg.Done()
+	for first := range route.queue {
+		batch := []*Notification{first}
+		timer := time.NewTimer(n.window)
+	collect:
+		for len(batch) < DefaultBatchSize {
+			select {
+			case note, ok := <-route.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, note)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), route.timeout)
+		err := route.provider.Send(ctx, batch)
+		cancel()
+		n.mu.Lock()
+		if err != nil {
+			n.failed += int64(len(batch))
+		} else {
+			n.sent += int64(len(batch))
+		}
+		n.mu.Unlock()
+		if err != nil {
+			n.logger.Warning("Notifying %s failed: %v", route.Name, err)
+		} else {
+			n.logger.Debug("Sent %d findings to %s", len(batch), route.Name)
+		}
+	}
+}
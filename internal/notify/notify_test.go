@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhook records the JSON bodies posted to it
+type webhook struct {
+	mu     sync.Mutex
+	bodies []map[string]any
+}
+
+func (h *webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.mu.Lock()
+	h.bodies = append(h.bodies, body)
+	h.mu.Unlock()
+}
+
+func (h *webhook) received() []map[string]any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]map[string]any(nil), h.bodies...)
+}
+
+func newWebhook(t *testing.T) (*webhook, string) {
+	t.Helper()
+	h := &webhook{}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return h, srv.URL
+}
+
+func closeNotifier(t *testing.T, n *Notifier) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := n.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	if s, err := ParseSeverity(" High "); err != nil || s != SeverityHigh {
+		t.Errorf("expected high, got %v, %v", s, err)
+	}
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+}
+
+func TestParseRouteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		want     string
+	}{
+		{"no type", map[string]string{}, "type is not set"},
+		{"unknown type", map[string]string{"type": "email"}, "unknown type"},
+		{"no webhook", map[string]string{"type": "slack"}, "needs a webhook_url"},
+		{"bad webhook", map[string]string{"type": "discord", "webhook_url": "ftp://example.com"}, "invalid webhook_url"},
+		{"no routing key", map[string]string{"type": "pagerduty"}, "needs a routing_key"},
+		{"bad severity", map[string]string{"type": "slack", "webhook_url": "https://example.com", "min_severity": "urgent"}, "min_severity"},
+		{"inverted range", map[string]string{"type": "slack", "webhook_url": "https://example.com", "min_severity": "high", "max_severity": "low"}, "above max_severity"},
+		{"bad kind", map[string]string{"type": "slack", "webhook_url": "https://example.com", "kinds": "malware,spam"}, "unknown kind"},
+		{"bad timeout", map[string]string{"type": "slack", "webhook_url": "https://example.com", "timeout": "-1s"}, "invalid timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRoute("test", tt.settings)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRouteSelects(t *testing.T) {
+	route, err := ParseRoute("oncall", map[string]string{
+		"type": "pagerduty", "routing_key": "key", "min_severity": "high", "kinds": "malware",
+	})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	tests := []struct {
+		note *Notification
+		want bool
+	}{
+		{&Notification{Kind: KindMalware, Severity: SeverityCritical}, true},
+		{&Notification{Kind: KindMalware, Severity: SeverityHigh}, true},
+		{&Notification{Kind: KindMalware, Severity: SeverityMedium}, false},
+		{&Notification{Kind: KindVulnerability, Severity: SeverityCritical}, false},
+	}
+	for _, tt := range tests {
+		if got := route.Selects(tt.note); got != tt.want {
+			t.Errorf("Selects(%s %s) = %v, want %v", tt.note.Kind, tt.note.Severity, got, tt.want)
+		}
+	}
+}
+
+func TestNotifierRoutesBySeverity(t *testing.T) {
+	oncall, oncallURL := newWebhook(t)
+	channel, channelURL := newWebhook(t)
+	routes, err := ParseRoutes(map[string]map[string]string{
+		"oncall": {
+			"type": "pagerduty", "webhook_url": oncallURL, "routing_key": "key",
+			"min_severity": "critical", "kinds": "malware",
+		},
+		"channel": {"type": "slack", "webhook_url": channelURL, "max_severity": "medium"},
+	})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if routes[0].Name != "channel" || routes[1].Name != "oncall" {
+		t.Fatalf("expected routes in order of name, got %v", routes)
+	}
+
+	n := New(routes, WithHost("web-01"), WithBatchWindow(time.Hour))
+	n.Notify(&Notification{Kind: KindMalware, Severity: SeverityCritical, Title: "Backdoor", Path: "/a.php", Key: "a"})
+	n.Notify(&Notification{Kind: KindMalware, Severity: SeverityCritical, Title: "Backdoor", Path: "/a.php", Key: "a"})
+	n.Notify(&Notification{Kind: KindVulnerability, Severity: SeverityLow, Title: "Old plugin <b>", Key: "b"})
+	closeNotifier(t, n)
+	if n.Sent() != 2 || n.Failed() != 0 {
+		t.Errorf("expected 2 findings sent and none failed, got %d and %d", n.Sent(), n.Failed())
+	}
+
+	pages := oncall.received()
+	if len(pages) != 1 {
+		t.Fatalf("expected one PagerDuty event, got %d", len(pages))
+	}
+	event := pages[0]
+	payload := event["payload"].(map[string]any)
+	if event["routing_key"] != "key" || event["dedup_key"] != "wordfence/web-01/malware" || payload["severity"] != "critical" {
+		t.Errorf("unexpected PagerDuty event %v", event)
+	}
+	if findings := payload["custom_details"].(map[string]any)["findings"].([]any); len(findings) != 1 {
+		t.Errorf("expected the repeated finding to be sent once, got %d", len(findings))
+	}
+
+	posts := channel.received()
+	if len(posts) != 1 {
+		t.Fatalf("expected one Slack message, got %d", len(posts))
+	}
+	attachments := posts[0]["attachments"].([]any)
+	if len(attachments) != 1 || attachments[0].(map[string]any)["title"] != "Old plugin &lt;b&gt;" {
+		t.Errorf("unexpected Slack attachments %v", attachments)
+	}
+}
+
+func TestNotifierCountsFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	t.Cleanup(srv.Close)
+	routes, err := ParseRoutes(map[string]map[string]string{
+		"channel": {"type": "slack", "webhook_url": srv.URL},
+	})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	n := New(routes, WithBatchWindow(time.Millisecond))
+	n.Notify(&Notification{Kind: KindMalware, Severity: SeverityCritical, Title: "Backdoor", Key: "a"})
+	closeNotifier(t, n)
+	if n.Sent() != 0 || n.Failed() != 1 {
+		t.Errorf("expected 1 finding failed and none sent, got %d and %d", n.Failed(), n.Sent())
+	}
+}
+
+func TestDiscordChunksEmbeds(t *testing.T) {
+	h, url := newWebhook(t)
+	route, err := ParseRoute("discord", map[string]string{"type": "discord", "webhook_url": url})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	batch := make([]*Notification, 12)
+	for i := range batch {
+		batch[i] = &Notification{Kind: KindMalware, Severity: SeverityHigh, Title: "@everyone", Host: "web-01"}
+	}
+	if err := route.provider.Send(context.Background(), batch); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	posts := h.received()
+	if len(posts) != 2 {
+		t.Fatalf("expected two messages, got %d", len(posts))
+	}
+	if embeds := posts[0]["embeds"].([]any); len(embeds) != maxDiscordEmbed {
+		t.Errorf("expected %d embeds, got %d", maxDiscordEmbed, len(embeds))
+	}
+	if mentions := posts[0]["allowed_mentions"].(map[string]any)["parse"].([]any); len(mentions) != 0 {
+		t.Errorf("expected mentions to be disabled, got %v", mentions)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("expected short strings unchanged, got %q", got)
+	}
+	got := truncate(strings.Repeat("é", 10), 8)
+	if len(got) > 8 || !strings.HasSuffix(got, "…") || !strings.HasPrefix(got, "é") {
+		t.Errorf("unexpected truncation %q", got)
+	}
+}
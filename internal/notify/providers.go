@@ -0,0 +1,246 @@
+// Package notify provides the Slack, Discord and PagerDuty services findings
+// are sent to
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Limits of the services on the length of message fields
+const (
+	maxTitle        = 256
+	maxText         = 2000
+	maxSummary      = 1024
+	maxDiscordEmbed = 10
+)
+
+// severityColors are the colors findings are shown in, by severity
+var severityColors = [...]int{0x6c757d, 0x2b7bb9, 0xf0ad4e, 0xe8590c, 0xd00000}
+
+func (s Severity) color() int {
+	if s < SeverityInfo || s > SeverityCritical {
+		return severityColors[SeverityInfo]
+	}
+	return severityColors[s]
+}
+
+// summarize describes a batch in a line
+func summarize(batch []*Notification) string {
+	if len(batch) == 1 {
+		return batch[0].Title + " on " + batch[0].Host
+	}
+	highest := SeverityInfo
+	for _, note := range batch {
+		highest = max(highest, note.Severity)
+	}
+	return fmt.Sprintf("%d findings on %s, the most severe %s", len(batch), batch[0].Host, highest)
+}
+
+// details describes a finding's text and path in a few lines
+func details(note *Notification) string {
+	var lines []string
+	if note.Text != "" {
+		lines = append(lines, note.Text)
+	}
+	if note.Path != "" {
+		lines = append(lines, "Path: "+note.Path)
+	}
+	lines = append(lines, fmt.Sprintf("Severity: %s", note.Severity))
+	return truncate(strings.Join(lines, "\n"), maxText)
+}
+
+// truncate cuts s to at most n bytes, marking the cut
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// slackProvider posts to a Slack incoming webhook
+type slackProvider struct {
+	client *api.Client
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color     string `json:"color"`
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Footer    string `json:"footer,omitempty"`
+	Timestamp int64  `json:"ts,omitempty"`
+}
+
+// slackEscaper escapes the characters Slack treats as markup
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (p *slackProvider) Send(ctx context.Context, batch []*Notification) error {
+	msg := slackMessage{Text: slackEscaper.Replace(summarize(batch))}
+	for _, note := range batch {
+		msg.Attachments = append(msg.Attachments, slackAttachment{
+			Color:     fmt.Sprintf("#%06x", note.Severity.color()),
+			Title:     slackEscaper.Replace(truncate(note.Title, maxTitle)),
+			TitleLink: note.Link,
+			Text:      slackEscaper.Replace(details(note)),
+			Footer:    "Wordfence CLI on " + note.Host,
+			Timestamp: note.Time.Unix(),
+		})
+	}
+	if _, err := p.client.PostJSON(ctx, "", msg, nil); err != nil {
+		return fmt.Errorf("posting to Slack: %w", err)
+	}
+	return nil
+}
+
+// discordProvider posts to a Discord webhook
+type discordProvider struct {
+	client *api.Client
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+	// AllowedMentions keeps text from findings, such as @everyone in a
+	// matched file, from pinging anyone
+	AllowedMentions discordMentions `json:"allowed_mentions"`
+}
+
+type discordEmbed struct {
+	Title       string        `json:"title"`
+	URL         string        `json:"url,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Color       int           `json:"color"`
+	Timestamp   string        `json:"timestamp,omitempty"`
+	Footer      discordFooter `json:"footer"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+type discordMentions struct {
+	Parse []string `json:"parse"`
+}
+
+func (p *discordProvider) Send(ctx context.Context, batch []*Notification) error {
+	// Discord takes at most 10 embeds in a message
+	for start := 0; start < len(batch); start += maxDiscordEmbed {
+		part := batch[start:min(start+maxDiscordEmbed, len(batch))]
+		msg := discordMessage{Content: truncate(summarize(batch), maxText), AllowedMentions: discordMentions{Parse: []string{}}}
+		if start > 0 {
+			msg.Content = ""
+		}
+		for _, note := range part {
+			msg.Embeds = append(msg.Embeds, discordEmbed{
+				Title:       truncate(note.Title, maxTitle),
+				URL:         note.Link,
+				Description: details(note),
+				Color:       note.Severity.color(),
+				Timestamp:   note.Time.UTC().Format(time.RFC3339),
+				Footer:      discordFooter{Text: "Wordfence CLI on " + note.Host},
+			})
+		}
+		if _, err := p.client.PostJSON(ctx, "", msg, nil); err != nil {
+			return fmt.Errorf("posting to Discord: %w", err)
+		}
+	}
+	return nil
+}
+
+// pagerDutyProvider triggers PagerDuty alerts through the Events API v2.
+// Findings of a kind on a host share a dedup key, so repeated scans add to
+// the open alert rather than paging again.
+type pagerDutyProvider struct {
+	client     *api.Client
+	routingKey string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string          `json:"summary"`
+	Source        string          `json:"source"`
+	Severity      string          `json:"severity"`
+	Timestamp     string          `json:"timestamp"`
+	Component     string          `json:"component,omitempty"`
+	Class         string          `json:"class,omitempty"`
+	CustomDetails pagerDutyDetail `json:"custom_details"`
+}
+
+type pagerDutyDetail struct {
+	Findings []*Notification `json:"findings"`
+}
+
+// pagerDutySeverity maps a severity to one PagerDuty accepts
+func pagerDutySeverity(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "critical"
+	case SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func (p *pagerDutyProvider) Send(ctx context.Context, batch []*Notification) error {
+	byKind := make(map[string][]*Notification)
+	var kinds []string
+	for _, note := range batch {
+		if byKind[note.Kind] == nil {
+			kinds = append(kinds, note.Kind)
+		}
+		byKind[note.Kind] = append(byKind[note.Kind], note)
+	}
+
+	for _, kind := range kinds {
+		notes := byKind[kind]
+		highest := SeverityInfo
+		for _, note := range notes {
+			highest = max(highest, note.Severity)
+		}
+		event := pagerDutyEvent{
+			RoutingKey:  p.routingKey,
+			EventAction: "trigger",
+			DedupKey:    "wordfence/" + notes[0].Host + "/" + kind,
+			Payload: pagerDutyPayload{
+				Summary:       truncate(summarize(notes), maxSummary),
+				Source:        notes[0].Host,
+				Severity:      pagerDutySeverity(highest),
+				Timestamp:     notes[0].Time.UTC().Format(time.RFC3339),
+				Component:     "wordfence-cli",
+				Class:         kind,
+				CustomDetails: pagerDutyDetail{Findings: notes},
+			},
+		}
+		if _, err := p.client.PostJSON(ctx, "", event, nil); err != nil {
+			return fmt.Errorf("triggering PagerDuty alert: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package notify provides notification routes parsed from [notify:NAME]
+// sections
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// Providers a route's type may name
+const (
+	ProviderSlack     = "slack"
+	ProviderDiscord   = "discord"
+	ProviderPagerDuty = "pagerduty"
+	ProviderJira      = "jira"
+	ProviderGitHub    = "github"
+)
+
+// Providers returns the provider types routes may use
+func Providers() []string {
+	return []string{ProviderSlack, ProviderDiscord, ProviderPagerDuty, ProviderJira, ProviderGitHub}
+}
+
+// DefaultJiraIssueType is the type of the Jira issues opened
+const DefaultJiraIssueType = "Task"
+
+// DefaultTimeout bounds sending one batch, retries included
+const DefaultTimeout = 30 * time.Second
+
+// Route sends the findings of some kinds, within a range of severities, to
+// one provider
+type Route struct {
+	Name     string
+	Type     string
+	provider Provider
+	min, max Severity
+	// kinds are the kinds selected (empty is every kind)
+	kinds   []string
+	timeout time.Duration
+}
+
+// Selects reports whether the route sends note
+func (r *Route) Selects(note *Notification) bool {
+	if note.Severity < r.min || note.Severity > r.max {
+		return false
+	}
+	return len(r.kinds) == 0 || slices.Contains(r.kinds, note.Kind)
+}
+
+func (r *Route) String() string {
+	kinds := "all findings"
+	if len(r.kinds) > 0 {
+		kinds = strings.Join(r.kinds, " and ") + " findings"
+	}
+	return fmt.Sprintf("%s (%s: %s, %s to %s)", r.Name, r.Type, kinds, r.min, r.max)
+}
+
+// ParseRoute creates the route named name from the settings of its
+// [notify:name] configuration section:
+//
+//	type = slack, discord, pagerduty, jira or github
+//	webhook_url = the Slack or Discord incoming webhook (for PagerDuty,
+//	              an Events API v2 URL replacing the default)
+//	routing_key = the PagerDuty integration key
+//	url = the Jira site, or a GitHub Enterprise API URL
+//	project, issue_type = the Jira project key and issue type (default Task)
+//	repository = the GitHub repository, as owner/name
+//	user, token = the Jira user and API token (a personal access token
+//	              without a user), or a GitHub token
+//	labels = the GitHub labels of issues opened (default wordfence)
+//	min_severity, max_severity = the severities sent (default all)
+//	kinds = malware, vulnerability or both (default both)
+//	timeout = how long sending a batch may take (default 30s)
+//
+// clientOpts apply to the HTTP client, such as api.WithTLSConfig.
+func ParseRoute(name string, settings map[string]string, clientOpts ...api.ClientOption) (*Route, error) {
+	r := &Route{Name: name, Type: strings.ToLower(settings["type"]), min: SeverityInfo, max: SeverityCritical, timeout: DefaultTimeout}
+	var err error
+	if value := settings["min_severity"]; value != "" {
+		if r.min, err = ParseSeverity(value); err != nil {
+			return nil, fmt.Errorf("notification %s: min_severity: %w", name, err)
+		}
+	}
+	if value := settings["max_severity"]; value != "" {
+		if r.max, err = ParseSeverity(value); err != nil {
+			return nil, fmt.Errorf("notification %s: max_severity: %w", name, err)
+		}
+	}
+	if r.min > r.max {
+		return nil, fmt.Errorf("notification %s: min_severity %s is above max_severity %s", name, r.min, r.max)
+	}
+	for _, kind := range strings.Split(settings["kinds"], ",") {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" {
+			continue
+		}
+		if !slices.Contains(Kinds(), kind) {
+			return nil, fmt.Errorf("notification %s: unknown kind %q: use %s", name, kind, strings.Join(Kinds(), ", "))
+		}
+		r.kinds = append(r.kinds, kind)
+	}
+	if value := settings["timeout"]; value != "" {
+		if r.timeout, err = time.ParseDuration(value); err != nil || r.timeout <= 0 {
+			return nil, fmt.Errorf("notification %s: invalid timeout %q", name, value)
+		}
+	}
+
+	webhook := settings["webhook_url"]
+	if webhook != "" {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("notification %s: invalid webhook_url", name)
+		}
+	}
+	clientOpts = append([]api.ClientOption{api.WithTimeout(r.timeout), api.WithRetries(2)}, clientOpts...)
+	switch r.Type {
+	case ProviderSlack, ProviderDiscord:
+		if webhook == "" {
+			return nil, fmt.Errorf("notification %s: %s needs a webhook_url", name, r.Type)
+		}
+		if r.Type == ProviderSlack {
+			r.provider = &slackProvider{client: api.NewClient(webhook, clientOpts...)}
+		} else {
+			r.provider = &discordProvider{client: api.NewClient(webhook, clientOpts...)}
+		}
+	case ProviderPagerDuty:
+		if settings["routing_key"] == "" {
+			return nil, fmt.Errorf("notification %s: pagerduty needs a routing_key", name)
+		}
+		if webhook == "" {
+			webhook = PagerDutyEventsURL
+		}
+		r.provider = &pagerDutyProvider{client: api.NewClient(webhook, clientOpts...), routingKey: settings["routing_key"]}
+	case ProviderJira, ProviderGitHub:
+		if r.provider, err = parseTracker(name, r.Type, settings, clientOpts); err != nil {
+			return nil, err
+		}
+	case "":
+		return nil, fmt.Errorf("notification %s: type is not set: use %s", name, strings.Join(Providers(), ", "))
+	default:
+		return nil, fmt.Errorf("notification %s: unknown type %q: use %s", name, r.Type, strings.Join(Providers(), ", "))
+	}
+	return r, nil
+}
+
+// parseTracker creates the provider of a jira or github route
+func parseTracker(name, kind string, settings map[string]string, clientOpts []api.ClientOption) (Provider, error) {
+	base := settings["url"]
+	if kind == ProviderGitHub && base == "" {
+		base = GitHubAPIURL
+	}
+	if u, err := url.Parse(base); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("notification %s: %s needs a valid url", name, kind)
+	}
+	token := settings["token"]
+	if token == "" {
+		return nil, fmt.Errorf("notification %s: %s needs a token", name, kind)
+	}
+	client := api.NewClient(base, clientOpts...)
+
+	if kind == ProviderJira {
+		project := settings["project"]
+		if project == "" {
+			return nil, fmt.Errorf("notification %s: jira needs a project", name)
+		}
+		issueType := settings["issue_type"]
+		if issueType == "" {
+			issueType = DefaultJiraIssueType
+		}
+		return newTicketProvider("Jira", newJiraTracker(client, project, issueType, settings["user"], token)), nil
+	}
+
+	repo := settings["repository"]
+	if owner, repoName, ok := strings.Cut(repo, "/"); !ok || owner == "" || repoName == "" || strings.Contains(repoName, "/") {
+		return nil, fmt.Errorf("notification %s: github needs a repository as owner/name", name)
+	}
+	labels := []string{DefaultTicketLabel}
+	if value, ok := settings["labels"]; ok {
+		labels = nil
+		for _, label := range strings.Split(value, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				labels = append(labels, label)
+			}
+		}
+	}
+	return newTicketProvider("GitHub", newGitHubTracker(client, repo, token, labels)), nil
+}
+
+// ParseRoutes creates the routes of the [notify:name] sections, keyed by
+// name, in order of name
+func ParseRoutes(sections map[string]map[string]string, clientOpts ...api.ClientOption) ([]*Route, error) {
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	routes := make([]*Route, 0, len(names))
+	for _, name := range names {
+		route, err := ParseRoute(name, sections[name], clientOpts...)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
@@ -0,0 +1,306 @@
+// Package notify provides the ticketing systems that track findings as
+// issues
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// DefaultTicketLabel marks the issues opened for findings
+const DefaultTicketLabel = "wordfence"
+
+// GitHubAPIURL is the GitHub REST API endpoint
+const GitHubAPIURL = "https://api.github.com"
+
+// githubPageSize is how many issues are listed per request
+const githubPageSize = 100
+
+// tracker opens and comments on issues in a ticketing system
+type tracker interface {
+	// find returns the open issue marked with id, or "" if there is none
+	find(ctx context.Context, id string) (string, error)
+	// create opens an issue marked with id and returns it
+	create(ctx context.Context, id, title, text string) (string, error)
+	// comment adds text to an open issue
+	comment(ctx context.Context, issue, text string) error
+	// bullet starts a list item in the system's markup
+	bullet() string
+}
+
+// ticketProvider keeps one issue open per ticket: the first findings of a
+// ticket open an issue, and later ones, in this run or later runs, are
+// added to it as comments. Send is only called from the route's goroutine,
+// so the issues found are cached without locking.
+type ticketProvider struct {
+	name    string
+	tracker tracker
+	issues  map[string]string
+}
+
+func newTicketProvider(name string, t tracker) *ticketProvider {
+	return &ticketProvider{name: name, tracker: t, issues: make(map[string]string)}
+}
+
+// ticketID identifies a ticket across runs. Findings on different hosts
+// never share an issue, since the same path may be another site elsewhere.
+func ticketID(note *Notification) string {
+	ticket := note.Ticket
+	if ticket == "" {
+		ticket = note.Key
+	}
+	sum := sha256.Sum256([]byte(note.Host + "\x00" + ticket))
+	return DefaultTicketLabel + "-" + hex.EncodeToString(sum[:8])
+}
+
+// ticketTitle names the issue opened for a ticket's first findings
+func ticketTitle(notes []*Notification) string {
+	site := notes[0].Site
+	if site == "" {
+		site = notes[0].Host
+	}
+	if len(notes) == 1 {
+		return truncate(notes[0].Title+" on "+site, maxTitle)
+	}
+	return truncate(fmt.Sprintf("%d %s findings on %s", len(notes), notes[0].Kind, site), maxTitle)
+}
+
+// ticketText lists findings in an issue or comment
+func ticketText(notes []*Notification, bullet string) string {
+	var b strings.Builder
+	for _, note := range notes {
+		b.WriteString(bullet + note.Title + "\n")
+		if note.Link != "" {
+			b.WriteString(note.Link + "\n")
+		}
+		b.WriteString(details(note) + "\n")
+		if note.Site != "" {
+			b.WriteString("Site: " + note.Site + "\n")
+		}
+		fmt.Fprintf(&b, "Found on %s at %s\n\n", note.Host, note.Time.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (p *ticketProvider) Send(ctx context.Context, batch []*Notification) error {
+	byTicket := make(map[string][]*Notification)
+	var ids []string
+	for _, note := range batch {
+		id := ticketID(note)
+		if byTicket[id] == nil {
+			ids = append(ids, id)
+		}
+		byTicket[id] = append(byTicket[id], note)
+	}
+
+	for _, id := range ids {
+		if err := p.update(ctx, id, byTicket[id]); err != nil {
+			return fmt.Errorf("updating %s issue: %w", p.name, err)
+		}
+	}
+	return nil
+}
+
+// update opens the ticket's issue, or comments on it if it is open
+func (p *ticketProvider) update(ctx context.Context, id string, notes []*Notification) error {
+	text := ticketText(notes, p.tracker.bullet())
+	issue, ok := p.issues[id]
+	if !ok {
+		var err error
+		if issue, err = p.tracker.find(ctx, id); err != nil {
+			return err
+		}
+	}
+	if issue == "" {
+		issue, err := p.tracker.create(ctx, id, ticketTitle(notes), text)
+		if err != nil {
+			return err
+		}
+		p.issues[id] = issue
+		return nil
+	}
+	p.issues[id] = issue
+	return p.tracker.comment(ctx, issue, text)
+}
+
+// jiraTracker opens Jira issues through the REST API v2, which Jira Cloud
+// and Data Center both serve. Issues are found by a label holding the
+// ticket's ID.
+type jiraTracker struct {
+	client *api.Client
+	// headers are cloned for each request, since PostJSON adds to them
+	headers   map[string]string
+	project   string
+	issueType string
+	// searchPath is the search endpoint; Jira Cloud replaced /search with
+	// /search/jql, which Data Center doesn't have
+	searchPath string
+}
+
+func newJiraTracker(client *api.Client, project, issueType, user, token string) *jiraTracker {
+	auth := "Bearer " + token
+	if user != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+token))
+	}
+	return &jiraTracker{
+		client:     client,
+		headers:    map[string]string{"Authorization": auth, "Accept": "application/json"},
+		project:    project,
+		issueType:  issueType,
+		searchPath: "/rest/api/2/search/jql",
+	}
+}
+
+func (j *jiraTracker) bullet() string {
+	return "* "
+}
+
+func (j *jiraTracker) find(ctx context.Context, id string) (string, error) {
+	jql := fmt.Sprintf(`project = %q AND labels = %q AND statusCategory != Done ORDER BY created DESC`, j.project, id)
+	query := "?" + url.Values{"jql": {jql}, "fields": {"key"}, "maxResults": {"1"}}.Encode()
+	body, err := j.client.Get(ctx, j.searchPath+query, maps.Clone(j.headers))
+	if api.IsNotFound(err) && j.searchPath != "/rest/api/2/search" {
+		j.searchPath = "/rest/api/2/search"
+		body, err = j.client.Get(ctx, j.searchPath+query, maps.Clone(j.headers))
+	}
+	if err != nil {
+		return "", fmt.Errorf("searching Jira: %w", err)
+	}
+	var result struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("parsing Jira search: %w", err)
+	}
+	if len(result.Issues) == 0 {
+		return "", nil
+	}
+	return result.Issues[0].Key, nil
+}
+
+func (j *jiraTracker) create(ctx context.Context, id, title, text string) (string, error) {
+	issue := map[string]any{"fields": map[string]any{
+		"project":     map[string]string{"key": j.project},
+		"issuetype":   map[string]string{"name": j.issueType},
+		"summary":     title,
+		"description": text,
+		"labels":      []string{DefaultTicketLabel, id},
+	}}
+	body, err := j.client.PostJSON(ctx, "/rest/api/2/issue", issue, maps.Clone(j.headers))
+	if err != nil {
+		return "", fmt.Errorf("creating Jira issue: %w", err)
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil || created.Key == "" {
+		return "", fmt.Errorf("parsing created Jira issue: %q", truncate(string(body), 200))
+	}
+	return created.Key, nil
+}
+
+func (j *jiraTracker) comment(ctx context.Context, issue, text string) error {
+	path := "/rest/api/2/issue/" + url.PathEscape(issue) + "/comment"
+	if _, err := j.client.PostJSON(ctx, path, map[string]string{"body": text}, maps.Clone(j.headers)); err != nil {
+		return fmt.Errorf("commenting on %s: %w", issue, err)
+	}
+	return nil
+}
+
+// githubTracker opens GitHub issues. Issues are found among the open ones
+// carrying the route's labels by a hidden marker holding the ticket's ID.
+type githubTracker struct {
+	client  *api.Client
+	headers map[string]string
+	repo    string
+	labels  []string
+}
+
+func newGitHubTracker(client *api.Client, repo, token string, labels []string) *githubTracker {
+	return &githubTracker{
+		client: client,
+		headers: map[string]string{
+			"Authorization":        "Bearer " + token,
+			"Accept":               "application/vnd.github+json",
+			"X-GitHub-Api-Version": "2022-11-28",
+		},
+		repo:   repo,
+		labels: labels,
+	}
+}
+
+func (g *githubTracker) bullet() string {
+	return "- "
+}
+
+// marker is the hidden comment identifying a ticket's issue
+func (g *githubTracker) marker(id string) string {
+	return "<!-- " + id + " -->"
+}
+
+func (g *githubTracker) find(ctx context.Context, id string) (string, error) {
+	marker := g.marker(id)
+	for page := 1; ; page++ {
+		query := url.Values{"state": {"open"}, "per_page": {fmt.Sprint(githubPageSize)}, "page": {fmt.Sprint(page)}}
+		if len(g.labels) > 0 {
+			query.Set("labels", strings.Join(g.labels, ","))
+		}
+		body, err := g.client.Get(ctx, "/repos/"+g.repo+"/issues?"+query.Encode(), maps.Clone(g.headers))
+		if err != nil {
+			return "", fmt.Errorf("listing GitHub issues: %w", err)
+		}
+		var issues []struct {
+			Number      int             `json:"number"`
+			Body        string          `json:"body"`
+			PullRequest json.RawMessage `json:"pull_request"`
+		}
+		if err := json.Unmarshal(body, &issues); err != nil {
+			return "", fmt.Errorf("parsing GitHub issues: %w", err)
+		}
+		for _, issue := range issues {
+			if issue.PullRequest == nil && strings.Contains(issue.Body, marker) {
+				return fmt.Sprint(issue.Number), nil
+			}
+		}
+		if len(issues) < githubPageSize {
+			return "", nil
+		}
+	}
+}
+
+func (g *githubTracker) create(ctx context.Context, id, title, text string) (string, error) {
+	issue := map[string]any{"title": title, "body": text + "\n\n" + g.marker(id)}
+	if len(g.labels) > 0 {
+		issue["labels"] = g.labels
+	}
+	body, err := g.client.PostJSON(ctx, "/repos/"+g.repo+"/issues", issue, maps.Clone(g.headers))
+	if err != nil {
+		return "", fmt.Errorf("creating GitHub issue: %w", err)
+	}
+	var created struct {
+		Number int `json:"number"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil || created.Number == 0 {
+		return "", fmt.Errorf("parsing created GitHub issue: %q", truncate(string(body), 200))
+	}
+	return fmt.Sprint(created.Number), nil
+}
+
+func (g *githubTracker) comment(ctx context.Context, issue, text string) error {
+	path := "/repos/" + g.repo + "/issues/" + issue + "/comments"
+	if _, err := g.client.PostJSON(ctx, path, map[string]string{"body": text}, maps.Clone(g.headers)); err != nil {
+		return fmt.Errorf("commenting on #%s: %w", issue, err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitHub serves the issues API of one repository
+type fakeGitHub struct {
+	mu       sync.Mutex
+	issues   []map[string]any
+	comments map[string][]string
+}
+
+func (g *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var body map[string]any
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/sites/issues":
+		if r.URL.Query().Get("labels") != "security,wordfence" {
+			_ = json.NewEncoder(w).Encode([]any{})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(g.issues)
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/sites/issues":
+		body["number"] = len(g.issues) + 1
+		g.issues = append(g.issues, body)
+		_ = json.NewEncoder(w).Encode(body)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comments"):
+		number := strings.Split(r.URL.Path, "/")[5]
+		g.comments[number] = append(g.comments[number], body["body"].(string))
+		_, _ = w.Write([]byte("{}"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGitHubTickets(t *testing.T) {
+	gh := &fakeGitHub{comments: make(map[string][]string)}
+	srv := httptest.NewServer(gh)
+	defer srv.Close()
+
+	settings := map[string]string{
+		"type": "github", "url": srv.URL, "repository": "acme/sites", "token": "secret", "labels": "security, wordfence",
+	}
+	infected := func(path string) *Notification {
+		return &Notification{
+			Kind: KindMalware, Severity: SeverityCritical, Title: "Malware found: Backdoor", Path: path,
+			Site: "example.com", Host: "web-01", Key: "malware:" + path, Ticket: "malware:example.com",
+		}
+	}
+
+	// Each run starts afresh, finding the issue opened by the first
+	for run := 0; run < 2; run++ {
+		route, err := ParseRoute("tickets", settings)
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		batch := []*Notification{infected("/var/www/a.php"), infected("/var/www/b.php")}
+		if err := route.provider.Send(context.Background(), batch); err != nil {
+			t.Fatalf("run %d: send failed: %v", run, err)
+		}
+	}
+
+	if len(gh.issues) != 1 {
+		t.Fatalf("expected one issue for the site, got %d", len(gh.issues))
+	}
+	issue := gh.issues[0]
+	if issue["title"] != "2 malware findings on example.com" {
+		t.Errorf("unexpected title %q", issue["title"])
+	}
+	if body := issue["body"].(string); !strings.Contains(body, "- Malware found: Backdoor") || !strings.Contains(body, "<!-- wordfence-") {
+		t.Errorf("unexpected body %q", body)
+	}
+	if labels := fmt.Sprint(issue["labels"]); labels != "[security wordfence]" {
+		t.Errorf("unexpected labels %s", labels)
+	}
+	if comments := gh.comments["1"]; len(comments) != 1 || !strings.Contains(comments[0], "/var/www/b.php") {
+		t.Errorf("expected the second run to comment on the issue, got %q", comments)
+	}
+}
+
+// fakeJira serves the search, issue and comment APIs of a Data Center
+// site, which has no /search/jql
+type fakeJira struct {
+	mu       sync.Mutex
+	labels   map[string][]string
+	comments map[string]int
+}
+
+func (j *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if user, token, ok := r.BasicAuth(); !ok || user != "bot@example.com" || token != "secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == "/rest/api/2/search":
+		var found []map[string]string
+		for key, labels := range j.labels {
+			for _, label := range labels {
+				if strings.Contains(r.URL.Query().Get("jql"), fmt.Sprintf("labels = %q", label)) && label != DefaultTicketLabel {
+					found = append(found, map[string]string{"key": key})
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"issues": found})
+	case r.URL.Path == "/rest/api/2/issue":
+		var body struct {
+			Fields struct {
+				Project struct{ Key string } `json:"project"`
+				Labels  []string             `json:"labels"`
+			} `json:"fields"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		key := fmt.Sprintf("%s-%d", body.Fields.Project.Key, len(j.labels)+1)
+		j.labels[key] = body.Fields.Labels
+		_ = json.NewEncoder(w).Encode(map[string]string{"key": key})
+	case strings.HasSuffix(r.URL.Path, "/comment"):
+		j.comments[strings.Split(r.URL.Path, "/")[5]]++
+		_, _ = w.Write([]byte("{}"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestJiraTickets(t *testing.T) {
+	jira := &fakeJira{labels: make(map[string][]string), comments: make(map[string]int)}
+	srv := httptest.NewServer(jira)
+	defer srv.Close()
+
+	route, err := ParseRoute("jira", map[string]string{
+		"type": "jira", "url": srv.URL, "project": "SEC", "user": "bot@example.com", "token": "secret",
+	})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	vuln := func(id string) *Notification {
+		return &Notification{
+			Kind: KindVulnerability, Severity: SeverityCritical, Title: "Vulnerable plugin", Site: "/var/www",
+			Host: "web-01", Key: id, Ticket: "vulnerability:/var/www:" + id,
+		}
+	}
+	batch := []*Notification{vuln("a"), vuln("b")}
+	if err := route.provider.Send(context.Background(), batch); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if err := route.provider.Send(context.Background(), []*Notification{vuln("a")}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	if len(jira.labels) != 2 {
+		t.Fatalf("expected an issue per vulnerability, got %v", jira.labels)
+	}
+	if jira.comments["SEC-1"] != 1 || jira.comments["SEC-2"] != 0 {
+		t.Errorf("expected the repeated vulnerability to comment on its issue, got %v", jira.comments)
+	}
+}
+
+func TestParseTrackerErrors(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		want     string
+	}{
+		{map[string]string{"type": "jira", "project": "SEC", "token": "x"}, "needs a valid url"},
+		{map[string]string{"type": "jira", "url": "https://example.atlassian.net", "token": "x"}, "needs a project"},
+		{map[string]string{"type": "github", "repository": "acme/sites"}, "needs a token"},
+		{map[string]string{"type": "github", "repository": "acme", "token": "x"}, "owner/name"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRoute("tickets", tt.settings)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected an error containing %q, got %v", tt.settings, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package re2 provides bindings to the native RE2 library for matching
+// signature gates. It is only built with -tags re2_cgo, with cgo enabled
+// and libre2 installed where pkg-config can find it.
+package re2
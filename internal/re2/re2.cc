@@ -0,0 +1,30 @@
+//go:build re2_cgo && cgo
+
+#include <stdlib.h>
+#include <string.h>
+
+#include <re2/re2.h>
+
+extern "C" {
+
+void *wf_re2_new(const char *pattern, size_t len, char **err) {
+	RE2::Options options;
+	options.set_log_errors(false);
+	RE2 *re = new RE2(re2::StringPiece(pattern, len), options);
+	if (!re->ok()) {
+		*err = strdup(re->error().c_str());
+		delete re;
+		return NULL;
+	}
+	return re;
+}
+
+int wf_re2_match(void *re, const char *text, size_t len) {
+	return RE2::PartialMatch(re2::StringPiece(text, len), *static_cast<RE2 *>(re));
+}
+
+void wf_re2_delete(void *re) {
+	delete static_cast<RE2 *>(re);
+}
+
+}
@@ -0,0 +1,59 @@
+//go:build re2_cgo && cgo
+
+// Package re2 provides bindings to the native RE2 library
+package re2
+
+/*
+#cgo pkg-config: re2
+#cgo CXXFLAGS: -std=c++17
+#include <stdlib.h>
+
+void *wf_re2_new(const char *pattern, size_t len, char **err);
+int wf_re2_match(void *re, const char *text, size_t len);
+void wf_re2_delete(void *re);
+*/
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+// Regexp is a pattern compiled by RE2. It is safe for concurrent use, and
+// freed when it is no longer referenced.
+type Regexp struct {
+	re unsafe.Pointer
+}
+
+// Compile compiles a pattern in RE2 syntax
+func Compile(pattern string) (*Regexp, error) {
+	cPattern := C.CString(pattern)
+	defer C.free(unsafe.Pointer(cPattern))
+
+	var cErr *C.char
+	re := C.wf_re2_new(cPattern, C.size_t(len(pattern)), &cErr)
+	if re == nil {
+		msg := "invalid pattern"
+		if cErr != nil {
+			msg = C.GoString(cErr)
+			C.free(unsafe.Pointer(cErr))
+		}
+		return nil, errors.New("re2: " + msg)
+	}
+
+	r := &Regexp{re: re}
+	runtime.AddCleanup(r, func(re unsafe.Pointer) { C.wf_re2_delete(re) }, re)
+	return r, nil
+}
+
+// Match reports whether content contains a match of the pattern
+func (r *Regexp) Match(content []byte) bool {
+	var text *C.char
+	if len(content) > 0 {
+		text = (*C.char)(unsafe.Pointer(&content[0]))
+	}
+	matched := C.wf_re2_match(r.re, text, C.size_t(len(content))) != 0
+	runtime.KeepAlive(r)
+	return matched
+}
@@ -0,0 +1,68 @@
+//go:build re2_cgo && cgo
+
+package re2
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	for _, pattern := range []string{
+		`eval\s*\(\s*base64_decode`,
+		`(?i)FilesMan`,
+		`(?ms)^<\?php.+?\?>$`,
+		`\\x[0-9a-f]{2}`,
+		``,
+	} {
+		if _, err := Compile(pattern); err != nil {
+			t.Errorf("%q: unexpected error: %v", pattern, err)
+		}
+	}
+
+	// Unbalanced, and constructs RE2 doesn't support
+	for _, pattern := range []string{`(eval\(`, `[a-z`, `(?<=\$)x`, `(a)\1`} {
+		re, err := Compile(pattern)
+		if err == nil || re != nil {
+			t.Errorf("%q: expected an error", pattern)
+			continue
+		}
+		if len(err.Error()) <= len("re2: ") {
+			t.Errorf("%q: expected RE2's reason, got %q", pattern, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	patterns := []string{
+		`eval\s*\(\s*base64_decode`,
+		`(?i)assert\s*\(\s*\$_(?:GET|POST|REQUEST)`,
+		`(?m)^<\?php\s+@?error_reporting\(0\)`,
+		`(?i)FilesMan`,
+		`x*`,
+	}
+	contents := []string{
+		"",
+		"<?php @error_reporting(0); eval(base64_decode('ZWNobyAx'));",
+		"<?php ASSERT ( $_POST['x'] );",
+		"<?php echo 'filesman';",
+		"<?php echo 'hello world';",
+		"\x00\x01binary\xff",
+	}
+	for _, pattern := range patterns {
+		re, err := Compile(pattern)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", pattern, err)
+		}
+		want := regexp.MustCompile(pattern)
+		for _, content := range contents {
+			if got := re.Match([]byte(content)); got != want.MatchString(content) {
+				t.Errorf("%q on %q: got %v, want %v", pattern, content, got, !got)
+			}
+		}
+		// Nil content is empty content
+		if got := re.Match(nil); got != want.MatchString("") {
+			t.Errorf("%q on nil: got %v, want %v", pattern, got, !got)
+		}
+	}
+}
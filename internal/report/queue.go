@@ -0,0 +1,107 @@
+// Package report provides a disk-backed queue of undelivered batches
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Queue persists batches that could not be delivered, one JSON file per
+// batch, so they survive restarts and are sent once the collector is back
+type Queue struct {
+	dir string
+}
+
+// OpenQueue opens the queue in dir. The directory is created when the
+// first batch is pushed.
+func OpenQueue(dir string) *Queue {
+	return &Queue{dir: dir}
+}
+
+// Dir returns the queue's directory
+func (q *Queue) Dir() string {
+	return q.dir
+}
+
+// Push adds a batch to the queue
+func (q *Queue) Push(batch *Batch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("encoding batch: %w", err)
+	}
+	if err := os.MkdirAll(q.dir, 0o700); err != nil {
+		return fmt.Errorf("creating queue directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(q.dir, ".batch-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing batch: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing batch: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.path(batch.ID)); err != nil {
+		return fmt.Errorf("queueing batch: %w", err)
+	}
+	return nil
+}
+
+// Pending returns the IDs of queued batches, oldest first
+func (q *Queue) Pending() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading queue: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	// Batch IDs start with their creation time
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Load reads a queued batch
+func (q *Queue) Load(id string) (*Batch, error) {
+	data, err := os.ReadFile(q.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("reading queued batch: %w", err)
+	}
+	var batch Batch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("parsing queued batch %s: %w", id, err)
+	}
+	return &batch, nil
+}
+
+// Remove drops a delivered batch
+func (q *Queue) Remove(id string) error {
+	if err := os.Remove(q.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing queued batch: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding a batch
+func (q *Queue) path(id string) string {
+	return filepath.Join(q.dir, filepath.Base(id)+".json")
+}
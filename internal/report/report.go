@@ -0,0 +1,379 @@
+// Package report provides streaming of scan results and heartbeats to a
+// central collector.
+//
+// Agents POST batches of events as JSON to the collector URL:
+//
+//	{
+//	  "id": "20250301-100000.000000000-a1b2c3",
+//	  "agent": {"id": "web-01", "hostname": "web-01", "version": "1.2.0"},
+//	  "sent": "2025-03-01T10:00:05Z",
+//	  "events": [
+//	    {"type": "result", "time": "...", "scan_id": "...", "data": {...}}
+//	  ]
+//	}
+//
+// Any 2xx response acknowledges the batch. Batches that cannot be
+// delivered are queued on disk and resent, oldest first, with their
+// original ID so the collector can discard duplicates.
+package report
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+// Event types
+const (
+	EventScanStarted   = "scan_started"
+	EventResult        = "result"
+	EventVulnerability = "vulnerability"
+	EventScanFinished  = "scan_finished"
+	EventHeartbeat     = "heartbeat"
+)
+
+// Defaults for batching and heartbeats
+const (
+	DefaultBatchSize         = 100
+	DefaultFlushInterval     = 10 * time.Second
+	DefaultHeartbeatInterval = time.Minute
+)
+
+// Agent identifies the reporting host
+type Agent struct {
+	ID       string `json:"id"`
+	Hostname string `json:"hostname"`
+	Version  string `json:"version"`
+}
+
+// Event is a single report sent to the collector
+type Event struct {
+	Type   string          `json:"type"`
+	Time   time.Time       `json:"time"`
+	ScanID string          `json:"scan_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// Batch is the unit of delivery to the collector
+type Batch struct {
+	ID     string    `json:"id"`
+	Agent  Agent     `json:"agent"`
+	Sent   time.Time `json:"sent"`
+	Events []*Event  `json:"events"`
+}
+
+// Heartbeat is the data of a heartbeat event
+type Heartbeat struct {
+	Uptime  float64 `json:"uptime_seconds"`
+	Queued  int     `json:"queued_batches"`
+	Dropped int64   `json:"dropped_events,omitempty"`
+}
+
+// Reporter batches events and delivers them to a collector in the
+// background, queueing batches on disk while the collector is unreachable
+type Reporter struct {
+	client            *api.Client
+	agent             Agent
+	queue             *Queue
+	batchSize         int
+	flushInterval     time.Duration
+	heartbeatInterval time.Duration
+	clientOpts        []api.ClientOption
+	logger            *logging.Logger
+
+	events   chan *Event
+	done     chan struct{}
+	closeCtx context.Context
+	started  time.Time
+
+	mu      sync.Mutex
+	dropped int64
+}
+
+// Option configures a Reporter
+type Option func(*Reporter)
+
+// WithAgent sets the agent identity sent with every batch
+func WithAgent(agent Agent) Option {
+	return func(r *Reporter) {
+		r.agent = agent
+	}
+}
+
+// WithQueueDir sets the directory of undelivered batches
+func WithQueueDir(dir string) Option {
+	return func(r *Reporter) {
+		r.queue = OpenQueue(dir)
+	}
+}
+
+// WithBatchSize sets the number of events sent per batch
+func WithBatchSize(n int) Option {
+	return func(r *Reporter) {
+		r.batchSize = n
+	}
+}
+
+// WithFlushInterval sets how long events wait for a full batch
+func WithFlushInterval(d time.Duration) Option {
+	return func(r *Reporter) {
+		r.flushInterval = d
+	}
+}
+
+// WithHeartbeatInterval sets how often heartbeats are sent (0 disables)
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(r *Reporter) {
+		r.heartbeatInterval = d
+	}
+}
+
+// WithClientOptions applies options to the underlying HTTP client, such as
+// api.WithTLSConfig for mutual TLS
+func WithClientOptions(opts ...api.ClientOption) Option {
+	return func(r *Reporter) {
+		r.clientOpts = append(r.clientOpts, opts...)
+	}
+}
+
+// WithLogger sets the logger for delivery problems
+func WithLogger(logger *logging.Logger) Option {
+	return func(r *Reporter) {
+		r.logger = logger
+	}
+}
+
+// NewReporter starts a reporter delivering to the collector at endpoint.
+// Close must be called to flush pending events.
+func NewReporter(endpoint string, opts ...Option) (*Reporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid collector URL %q", endpoint)
+	}
+
+	hostname, _ := os.Hostname()
+	r := &Reporter{
+		agent:             Agent{ID: hostname, Hostname: hostname},
+		batchSize:         DefaultBatchSize,
+		flushInterval:     DefaultFlushInterval,
+		heartbeatInterval: DefaultHeartbeatInterval,
+		logger:            logging.GetDefaultLogger(),
+		events:            make(chan *Event, DefaultBatchSize),
+		done:              make(chan struct{}),
+		started:           time.Now(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = DefaultBatchSize
+	}
+	if r.flushInterval <= 0 {
+		r.flushInterval = DefaultFlushInterval
+	}
+
+	// Undelivered batches go to the queue rather than being retried inline
+	clientOpts := append([]api.ClientOption{api.WithRetries(0), api.WithLogger(r.logger)}, r.clientOpts...)
+	r.client = api.NewClient(endpoint, clientOpts...)
+
+	go r.run()
+	return r, nil
+}
+
+// Report sends an event with data encoded as JSON. It blocks while the
+// reporter's buffer is full and must not be called after Close.
+func (r *Reporter) Report(eventType, scanID string, data any) {
+	event := &Event{Type: eventType, Time: time.Now().UTC(), ScanID: scanID}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			r.drop(1, "encoding %s event: %v", eventType, err)
+			return
+		}
+		event.Data = encoded
+	}
+	r.events <- event
+}
+
+// Close delivers or queues pending events and stops the reporter. Delivery
+// stops early, queueing what is left, once ctx is done.
+func (r *Reporter) Close(ctx context.Context) error {
+	r.closeCtx = ctx
+	close(r.events)
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		// The final flush falls back to the queue when ctx ends
+		<-r.done
+		return fmt.Errorf("closing reporter: %w", ctx.Err())
+	}
+}
+
+// run batches events until the reporter is closed
+func (r *Reporter) run() {
+	defer close(r.done)
+
+	flush := time.NewTicker(r.flushInterval)
+	defer flush.Stop()
+
+	var heartbeat <-chan time.Time
+	if r.heartbeatInterval > 0 {
+		ticker := time.NewTicker(r.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	var pending []*Event
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				r.deliver(r.closeCtx, pending)
+				return
+			}
+			pending = append(pending, event)
+			if len(pending) >= r.batchSize {
+				r.deliver(context.Background(), pending)
+				pending = nil
+			}
+		case <-flush.C:
+			r.deliver(context.Background(), pending)
+			pending = nil
+		case <-heartbeat:
+			pending = append(pending, r.heartbeat())
+			r.deliver(context.Background(), pending)
+			pending = nil
+		}
+	}
+}
+
+// heartbeat builds a heartbeat event
+func (r *Reporter) heartbeat() *Event {
+	hb := Heartbeat{Uptime: time.Since(r.started).Seconds()}
+	if r.queue != nil {
+		if ids, err := r.queue.Pending(); err == nil {
+			hb.Queued = len(ids)
+		}
+	}
+	r.mu.Lock()
+	hb.Dropped = r.dropped
+	r.mu.Unlock()
+
+	data, _ := json.Marshal(hb)
+	return &Event{Type: EventHeartbeat, Time: time.Now().UTC(), Data: data}
+}
+
+// deliver sends queued batches, oldest first, then events as a new batch.
+// Whatever cannot be sent is queued, or dropped if there is no queue.
+func (r *Reporter) deliver(ctx context.Context, events []*Event) {
+	delivered := r.drainQueue(ctx)
+	if len(events) == 0 {
+		return
+	}
+
+	batch := &Batch{ID: newBatchID(), Agent: r.agent, Events: events}
+	if delivered {
+		err := r.send(ctx, batch)
+		if err == nil {
+			return
+		}
+		if rejected(err) {
+			r.drop(len(events), "collector rejected batch: %v", err)
+			return
+		}
+		r.logger.Debug("Collector unavailable: %v", err)
+	}
+
+	if r.queue == nil {
+		r.drop(len(events), "collector unavailable and no queue configured")
+		return
+	}
+	if err := r.queue.Push(batch); err != nil {
+		r.drop(len(events), "%v", err)
+	}
+}
+
+// drainQueue sends queued batches in order, stopping at the first failure.
+// It reports whether the queue is now empty.
+func (r *Reporter) drainQueue(ctx context.Context) bool {
+	if r.queue == nil {
+		return true
+	}
+	ids, err := r.queue.Pending()
+	if err != nil {
+		r.logger.Warning("Reading report queue: %v", err)
+		return false
+	}
+
+	for _, id := range ids {
+		batch, err := r.queue.Load(id)
+		if err != nil {
+			// An unreadable batch would block the queue forever
+			r.logger.Warning("Discarding queued batch: %v", err)
+			_ = r.queue.Remove(id)
+			continue
+		}
+		if err := r.send(ctx, batch); err != nil {
+			if !rejected(err) {
+				r.logger.Debug("Collector unavailable: %v", err)
+				return false
+			}
+			r.drop(len(batch.Events), "collector rejected batch: %v", err)
+		}
+		if err := r.queue.Remove(id); err != nil {
+			r.logger.Warning("%v", err)
+		}
+	}
+	return true
+}
+
+// send posts a batch to the collector
+func (r *Reporter) send(ctx context.Context, batch *Batch) error {
+	batch.Sent = time.Now().UTC()
+	if _, err := r.client.PostJSON(ctx, "", batch, nil); err != nil {
+		return fmt.Errorf("sending batch %s: %w", batch.ID, err)
+	}
+	return nil
+}
+
+// rejected reports whether the collector refused a batch in a way that
+// resending will not fix. Authentication failures are kept for retry, as
+// they are usually fixed by configuration rather than by the batch.
+func rejected(err error) bool {
+	httpErr, ok := api.IsHTTPError(err)
+	if !ok || httpErr.StatusCode < 400 || httpErr.StatusCode >= 500 {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+// drop counts events that will never be delivered
+func (r *Reporter) drop(n int, format string, args ...any) {
+	r.mu.Lock()
+	r.dropped += int64(n)
+	r.mu.Unlock()
+	r.logger.Warning("Dropped %d events: "+format, append([]any{n}, args...)...)
+}
+
+// newBatchID returns a unique ID that sorts by creation time
+func newBatchID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102-150405.000000000") + "-" + hex.EncodeToString(suffix)
+}
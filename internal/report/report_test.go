@@ -0,0 +1,191 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collector records the batches it receives
+type collector struct {
+	mu      sync.Mutex
+	batches []*Batch
+	down    atomic.Bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.down.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var batch Batch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.batches = append(c.batches, &batch)
+	c.mu.Unlock()
+}
+
+func (c *collector) received() []*Batch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Batch(nil), c.batches...)
+}
+
+func TestQueueRoundTrip(t *testing.T) {
+	q := OpenQueue(t.TempDir())
+	if ids, err := q.Pending(); err != nil || len(ids) != 0 {
+		t.Fatalf("expected empty queue, got %v, %v", ids, err)
+	}
+
+	first := &Batch{ID: newBatchID(), Events: []*Event{{Type: EventResult}}}
+	second := &Batch{ID: newBatchID(), Events: []*Event{{Type: EventHeartbeat}}}
+	for _, b := range []*Batch{second, first} {
+		if err := q.Push(b); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+
+	ids, err := q.Pending()
+	if err != nil || len(ids) != 2 || ids[0] != first.ID {
+		t.Fatalf("expected batches oldest first, got %v, %v", ids, err)
+	}
+	loaded, err := q.Load(ids[0])
+	if err != nil || loaded.Events[0].Type != EventResult {
+		t.Errorf("unexpected batch %+v, %v", loaded, err)
+	}
+	if err := q.Remove(ids[0]); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if ids, _ := q.Pending(); len(ids) != 1 {
+		t.Errorf("expected one batch left, got %v", ids)
+	}
+}
+
+func TestReporterBatches(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	r, err := NewReporter(srv.URL,
+		WithAgent(Agent{ID: "web-01"}),
+		WithBatchSize(2),
+		WithFlushInterval(time.Hour),
+		WithHeartbeatInterval(0),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		r.Report(EventResult, "scan-1", map[string]int{"n": i})
+	}
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	batches := c.received()
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+	if batches[0].Agent.ID != "web-01" || len(batches[0].Events) != 2 || batches[0].Events[0].ScanID != "scan-1" {
+		t.Errorf("unexpected first batch %+v", batches[0])
+	}
+	if len(batches[2].Events) != 1 {
+		t.Errorf("expected remaining event flushed on close, got %d", len(batches[2].Events))
+	}
+}
+
+func TestReporterQueuesWhileCollectorDown(t *testing.T) {
+	c := &collector{}
+	c.down.Store(true)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	dir := t.TempDir()
+
+	r, err := NewReporter(srv.URL, WithQueueDir(dir), WithHeartbeatInterval(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Report(EventResult, "scan-1", nil)
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if ids, _ := OpenQueue(dir).Pending(); len(ids) != 1 {
+		t.Fatalf("expected undelivered batch to be queued, got %v", ids)
+	}
+
+	// A later run delivers the queued batch before its own events
+	c.down.Store(false)
+	r, err = NewReporter(srv.URL, WithQueueDir(dir), WithHeartbeatInterval(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Report(EventResult, "scan-2", nil)
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	batches := c.received()
+	if len(batches) != 2 || batches[0].Events[0].ScanID != "scan-1" || batches[1].Events[0].ScanID != "scan-2" {
+		t.Fatalf("expected queued batch first, got %+v", batches)
+	}
+	if ids, _ := OpenQueue(dir).Pending(); len(ids) != 0 {
+		t.Errorf("expected queue to be drained, got %v", ids)
+	}
+}
+
+func TestReporterDropsRejectedBatches(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "malformed", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	r, err := NewReporter(srv.URL, WithQueueDir(dir), WithHeartbeatInterval(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Report(EventResult, "scan-1", nil)
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if ids, _ := OpenQueue(dir).Pending(); len(ids) != 0 {
+		t.Errorf("expected rejected batch not to be queued, got %v", ids)
+	}
+}
+
+func TestReporterHeartbeat(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	r, err := NewReporter(srv.URL, WithHeartbeatInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(c.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	_ = r.Close(context.Background())
+
+	batches := c.received()
+	if len(batches) == 0 || batches[0].Events[0].Type != EventHeartbeat {
+		t.Fatalf("expected a heartbeat, got %+v", batches)
+	}
+}
+
+func TestNewReporterRejectsInvalidURL(t *testing.T) {
+	for _, endpoint := range []string{"", "collector.example", "ftp://collector.example"} {
+		if _, err := NewReporter(endpoint); err == nil {
+			t.Errorf("expected error for %q", endpoint)
+		}
+	}
+}
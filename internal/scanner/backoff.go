@@ -0,0 +1,165 @@
+// Package scanner provides read backoff for devices slowing down under a
+// scan
+package scanner
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultReadLatencyTarget is a 95th percentile read latency target suited
+// to spinning disks and network mounts
+const DefaultReadLatencyTarget = 20 * time.Millisecond
+
+const (
+	// latencyWindow is how many recent reads of a device its latency is
+	// measured over
+	latencyWindow = 128
+	// latencyAdjustEvery is how many reads pass between changes to a
+	// device's delay
+	latencyAdjustEvery = 16
+	// minReadDelay and maxReadDelay bound the wait before each file on a
+	// device that is backing off
+	minReadDelay = 5 * time.Millisecond
+	maxReadDelay = time.Second
+)
+
+// DeviceLatency describes the reads from one device
+type DeviceLatency struct {
+	Device uint64 `json:"device"`
+	// Path is the first file read from the device, to tell which it is
+	Path string        `json:"path"`
+	P95  time.Duration `json:"p95"`
+	// Delay is the current wait before each file on the device, and
+	// MaxDelay the longest it reached
+	Delay    time.Duration `json:"delay"`
+	MaxDelay time.Duration `json:"max_delay"`
+}
+
+// deviceReads holds the latest read latencies of one device
+type deviceReads struct {
+	state   DeviceLatency
+	samples []time.Duration
+	next    int
+	pending int
+}
+
+// readBackoff slows the reads of each device whose read latency rises
+// above a target, doubling a wait before each of its files while the 95th
+// percentile is over target and halving it once it falls below half
+type readBackoff struct {
+	mu      sync.Mutex
+	target  time.Duration
+	devices map[uint64]*deviceReads
+}
+
+func newReadBackoff(target time.Duration) *readBackoff {
+	return &readBackoff{target: target, devices: make(map[uint64]*deviceReads)}
+}
+
+// wait sleeps for the current delay of device before a file at path on it
+// is read
+func (b *readBackoff) wait(ctx context.Context, device uint64, path string) error {
+	if b.target <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	d := b.devices[device]
+	if d == nil {
+		d = &deviceReads{state: DeviceLatency{Device: device, Path: path}, samples: make([]time.Duration, 0, latencyWindow)}
+		b.devices[device] = d
+	}
+	delay := d.state.Delay
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record notes how long one read from device took
+func (b *readBackoff) record(device uint64, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.devices[device]
+	if d == nil {
+		return
+	}
+	if len(d.samples) < latencyWindow {
+		d.samples = append(d.samples, latency)
+	} else {
+		d.samples[d.next] = latency
+		d.next = (d.next + 1) % latencyWindow
+	}
+	d.pending++
+	if d.pending < latencyAdjustEvery {
+		return
+	}
+	d.pending = 0
+
+	sorted := slices.Clone(d.samples)
+	slices.Sort(sorted)
+	d.state.P95 = sorted[len(sorted)*95/100]
+	switch {
+	case d.state.P95 > b.target:
+		d.state.Delay = min(max(d.state.Delay*2, minReadDelay), maxReadDelay)
+		d.state.MaxDelay = max(d.state.MaxDelay, d.state.Delay)
+	case d.state.P95 < b.target/2:
+		if d.state.Delay /= 2; d.state.Delay < minReadDelay {
+			d.state.Delay = 0
+		}
+	}
+}
+
+// reader times each read from r as a read from device
+func (b *readBackoff) reader(device uint64, r io.Reader) io.Reader {
+	if b.target <= 0 {
+		return r
+	}
+	return &timedReader{r: r, backoff: b, device: device}
+}
+
+// states returns the latency of every device read from, by device
+func (b *readBackoff) states() []DeviceLatency {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make([]DeviceLatency, 0, len(b.devices))
+	for _, d := range b.devices {
+		states = append(states, d.state)
+	}
+	sort.Slice(states, func(i, k int) bool { return states[i].Device < states[k].Device })
+	return states
+}
+
+// reset forgets every device
+func (b *readBackoff) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.devices = make(map[uint64]*deviceReads)
+}
+
+// timedReader records the latency of each read to a readBackoff
+type timedReader struct {
+	r       io.Reader
+	backoff *readBackoff
+	device  uint64
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(p)
+	t.backoff.record(t.device, time.Since(start))
+	return n, err
+}
@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReadBackoff(t *testing.T) {
+	b := newReadBackoff(20 * time.Millisecond)
+	ctx := context.Background()
+	if err := b.wait(ctx, 1, "/mnt/nfs/a.php"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Slow reads double the wait each time latency is measured
+	for range 3 * latencyAdjustEvery {
+		b.record(1, 50*time.Millisecond)
+	}
+	states := b.states()
+	if len(states) != 1 || states[0].Path != "/mnt/nfs/a.php" || states[0].Delay != 4*minReadDelay || states[0].P95 != 50*time.Millisecond {
+		t.Fatalf("expected the delay to double twice from %v, got %+v", minReadDelay, states)
+	}
+
+	// Fast reads, once they are 95% of the window, halve it back to nothing
+	for range latencyWindow * 2 {
+		b.record(1, time.Millisecond)
+	}
+	if states := b.states(); states[0].Delay != 0 || states[0].MaxDelay < 4*minReadDelay {
+		t.Errorf("expected the delay to recover, got %+v", states)
+	}
+
+	// Devices never waited on, and disabled backoff, aren't tracked
+	b.record(2, time.Second)
+	if len(b.states()) != 1 {
+		t.Errorf("expected reads from an unknown device to be ignored")
+	}
+	disabled := newReadBackoff(0)
+	_ = disabled.wait(ctx, 1, "/a.php")
+	if len(disabled.states()) != 0 {
+		t.Errorf("expected no tracking with backoff disabled")
+	}
+
+	// Waiting gives up when the scan is cancelled
+	for range latencyAdjustEvery {
+		b.record(1, time.Second)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := b.wait(cancelled, 1, "/mnt/nfs/b.php"); err == nil {
+		t.Error("expected a cancelled wait to fail")
+	}
+}
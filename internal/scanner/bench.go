@@ -0,0 +1,188 @@
+// Package scanner provides benchmarking of the regex engines on a corpus
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+// MatcherStats describes how a matcher prepared its signatures
+type MatcherStats struct {
+	Engine        string `json:"engine"`
+	Signatures    int    `json:"signatures"`
+	CompileErrors int    `json:"compile_errors"`
+
+	// Signatures without common strings are tried on every file: Gated
+	// of them in Sets, and Fallback one by one with regexp2
+	WithoutCommonStrings int `json:"without_common_strings"`
+	Gated                int `json:"gated"`
+	Fallback             int `json:"fallback"`
+	Sets                 int `json:"sets"`
+
+	// NativeSets are the sets gated by the native RE2 library; the rest
+	// use Go's engine
+	NativeSets int `json:"native_sets,omitempty"`
+
+	// FallbackReasons counts the constructs that kept signatures from
+	// being gated, such as backreferences
+	FallbackReasons map[string]int `json:"fallback_reasons,omitempty"`
+}
+
+// FallbackRate returns the share of signatures without common strings that
+// are matched one by one rather than gated
+func (s MatcherStats) FallbackRate() float64 {
+	if s.WithoutCommonStrings == 0 {
+		return 0
+	}
+	return float64(s.Fallback) / float64(s.WithoutCommonStrings)
+}
+
+// Stats describes how the matcher prepared its signatures
+func (m *Matcher) Stats() MatcherStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := MatcherStats{
+		Engine:               m.engine,
+		WithoutCommonStrings: len(m.noCommonStrSigs),
+		Fallback:             len(m.ungrouped),
+		Sets:                 len(m.sets),
+	}
+	for _, sig := range m.signatures {
+		if sig.CompileError != nil {
+			stats.CompileErrors++
+		} else {
+			stats.Signatures++
+		}
+	}
+	for _, sig := range m.ungrouped {
+		if sig.gateError == nil {
+			continue
+		}
+		if stats.FallbackReasons == nil {
+			stats.FallbackReasons = make(map[string]int)
+		}
+		stats.FallbackReasons[gateReason(sig.gateError)]++
+	}
+	for _, set := range m.sets {
+		stats.Gated += len(set.signatures)
+		if _, ok := set.re.(*regexp.Regexp); !ok {
+			stats.NativeSets++
+		}
+	}
+	return stats
+}
+
+// BenchFile is a file of a benchmark corpus
+type BenchFile struct {
+	Path    string
+	Content []byte
+}
+
+// LoadBenchCorpus reads the files under paths that pass filter into
+// memory, stopping at maxBytes in all (0 is unlimited)
+func LoadBenchCorpus(paths []string, filter *FileFilter, maxBytes int64) ([]BenchFile, error) {
+	var files []BenchFile
+	var total int64
+	errFull := errors.New("corpus full")
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && filter != nil && !filter.FilterDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || (filter != nil && !filter.Filter(path)) {
+				return nil
+			}
+			content, err := os.ReadFile(path) // #nosec G304 -- files the user asked to benchmark
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", path, err)
+			}
+			if maxBytes > 0 && total+int64(len(content)) > maxBytes {
+				return errFull
+			}
+			total += int64(len(content))
+			files = append(files, BenchFile{Path: path, Content: content})
+			return nil
+		})
+		if errors.Is(err, errFull) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// BenchResult is how one engine performed on a corpus
+type BenchResult struct {
+	MatcherStats
+	FallbackRate   float64 `json:"fallback_rate"`
+	CompileSeconds float64 `json:"compile_seconds"`
+
+	Files        int     `json:"files"`
+	Bytes        int64   `json:"bytes"`
+	Rounds       int     `json:"rounds"`
+	MatchSeconds float64 `json:"match_seconds"`
+	MBPerSecond  float64 `json:"mb_per_second"`
+
+	// FilesMatched and Timeouts are from the first round
+	FilesMatched int `json:"files_matched"`
+	Timeouts     int `json:"timeouts"`
+}
+
+// Bench compiles sigSet with an engine and matches every file of the
+// corpus against it, checking every signature, rounds times
+func Bench(ctx context.Context, sigSet *intel.SignatureSet, engine string, files []BenchFile, rounds int, opts ...MatcherOption) (*BenchResult, error) {
+	if err := ValidateRegexEngine(engine); err != nil {
+		return nil, err
+	}
+	rounds = max(rounds, 1)
+
+	start := time.Now()
+	m := NewMatcher(sigSet, append(opts, WithMatchAll(true), WithRegexEngine(engine))...)
+	result := &BenchResult{
+		MatcherStats:   m.Stats(),
+		CompileSeconds: time.Since(start).Seconds(),
+		Files:          len(files),
+		Rounds:         rounds,
+	}
+	result.FallbackRate = result.MatcherStats.FallbackRate()
+
+	start = time.Now()
+	for round := 0; round < rounds; round++ {
+		for _, file := range files {
+			mc := m.NewMatchContext()
+			if err := mc.Match(ctx, file.Content); err != nil {
+				return nil, fmt.Errorf("matching %s: %w", file.Path, err)
+			}
+			if round == 0 {
+				result.Bytes += int64(len(file.Content))
+				if mc.HasMatches() {
+					result.FilesMatched++
+				}
+				result.Timeouts += len(mc.GetTimeouts())
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	result.MatchSeconds = elapsed.Seconds()
+	if elapsed > 0 {
+		result.MBPerSecond = float64(result.Bytes*int64(rounds)) / (1 << 20) / elapsed.Seconds()
+	}
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+// corpusDir holds representative clean, webshell, obfuscated and injected
+// samples. They are defanged: nothing in them does harm if run.
+const corpusDir = "testdata/corpus"
+
+// corpusFixture is testdata/signatures.json: signatures in the styles of
+// the real feed, whether each should be gated with RE2, and the
+// signatures each corpus file should match
+type corpusFixture struct {
+	Signatures []struct {
+		ID       int    `json:"id"`
+		Rule     string `json:"rule"`
+		Category string `json:"category"`
+		Gated    bool   `json:"gated"`
+	} `json:"signatures"`
+	Matches map[string][]int `json:"matches"`
+}
+
+func loadCorpusFixture(t testing.TB) (*corpusFixture, *intel.SignatureSet, []BenchFile) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/signatures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture corpusFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	ss := intel.NewSignatureSet()
+	for _, s := range fixture.Signatures {
+		ss.Signatures[s.ID] = intel.NewSignature(s.ID, s.Rule, fmt.Sprintf("Signature %d", s.ID), "", nil)
+		ss.Signatures[s.ID].Category = s.Category
+	}
+
+	files, err := LoadBenchCorpus([]string{corpusDir}, DefaultFilter(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(fixture.Matches) {
+		t.Fatalf("expected %d corpus files, found %d", len(fixture.Matches), len(files))
+	}
+	return &fixture, ss, files
+}
+
+// TestCorpusGating catches regressions in which signatures can be gated
+// with RE2: gating one that RE2 can't match exactly as regexp2 does risks
+// missed detections, and falling back needlessly costs speed
+func TestCorpusGating(t *testing.T) {
+	fixture, ss, _ := loadCorpusFixture(t)
+	m := NewMatcher(ss, WithRegexEngine(RegexEngineAuto))
+
+	gated := make(map[int]bool)
+	for _, set := range m.sets {
+		for _, sig := range set.signatures {
+			gated[sig.Signature.ID] = true
+		}
+	}
+	for _, s := range fixture.Signatures {
+		if gated[s.ID] != s.Gated {
+			t.Errorf("signature %d (%s): gated %v, want %v", s.ID, s.Rule, gated[s.ID], s.Gated)
+		}
+	}
+
+	stats := m.Stats()
+	if stats.CompileErrors != 0 || stats.Gated+stats.Fallback != len(fixture.Signatures) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.FallbackReasons[reasonBackreference] != stats.Fallback {
+		t.Errorf("expected only backreferences to fall back, got %v", stats.FallbackReasons)
+	}
+}
+
+func TestCorpusMatches(t *testing.T) {
+	fixture, ss, files := loadCorpusFixture(t)
+	for _, engine := range RegexEngines() {
+		t.Run(engine, func(t *testing.T) {
+			m := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(engine))
+			for _, file := range files {
+				name, err := filepath.Rel(corpusDir, file.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				name = filepath.ToSlash(name)
+				got := matchedIDs(t, m, string(file.Content))
+				if want := fixture.Matches[name]; fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("%s: got matches %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBench(t *testing.T) {
+	_, ss, files := loadCorpusFixture(t)
+	result, err := Bench(context.Background(), ss, RegexEngineAuto, files, 2)
+	if err != nil {
+		t.Fatalf("Bench failed: %v", err)
+	}
+	if result.Files != len(files) || result.Rounds != 2 || result.Bytes == 0 || result.MBPerSecond <= 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.FilesMatched == 0 || result.FallbackRate <= 0 || result.FallbackRate >= 1 {
+		t.Errorf("expected matches and a partial fallback rate, got %+v", result)
+	}
+
+	if _, err := Bench(context.Background(), ss, "pcre", files, 1); err == nil {
+		t.Error("expected an unknown engine to fail")
+	}
+
+	limited, err := LoadBenchCorpus([]string{corpusDir}, DefaultFilter(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) == 0 || len(limited) >= len(files) {
+		t.Errorf("expected the byte limit to stop loading early, got %d of %d files", len(limited), len(files))
+	}
+}
+
+// BenchmarkCorpus measures throughput on the corpus with each engine.
+// Run it with
+//
+//	go test -run '^$' -bench Corpus ./internal/scanner
+func BenchmarkCorpus(b *testing.B) {
+	_, ss, files := loadCorpusFixture(b)
+	var size int64
+	for _, file := range files {
+		size += int64(len(file.Content))
+	}
+	for _, engine := range RegexEngines() {
+		b.Run(engine, func(b *testing.B) {
+			m := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(engine))
+			b.SetBytes(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, file := range files {
+					if err := m.NewMatchContext().Match(context.Background(), file.Content); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package scanner provides a memory budget for file content held by workers
+package scanner
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultMaxBytesInFlight bounds the file content held in memory at once
+// by all workers
+const DefaultMaxBytesInFlight = 256 << 20
+
+// byteBudget is a weighted semaphore of bytes. Reservations are granted in
+// order, so a large file is not starved by a stream of small ones.
+type byteBudget struct {
+	mu      sync.Mutex
+	size    int64
+	used    int64
+	waiters list.List
+}
+
+// budgetWaiter is a reservation waiting for bytes to be released
+type budgetWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func newByteBudget(size int64) *byteBudget {
+	return &byteBudget{size: size}
+}
+
+// acquire reserves n bytes, waiting until they are free, and returns the
+// bytes reserved. Reservations larger than the budget are clamped to it,
+// so a file bigger than the budget is read while nothing else is.
+func (b *byteBudget) acquire(ctx context.Context, n int64) (int64, error) {
+	b.mu.Lock()
+	n = min(n, b.size)
+	if n <= 0 {
+		b.mu.Unlock()
+		return 0, nil
+	}
+	if b.waiters.Len() == 0 && b.fits(n) {
+		b.used += n
+		b.mu.Unlock()
+		return n, nil
+	}
+	w := &budgetWaiter{n: n, ready: make(chan struct{})}
+	elem := b.waiters.PushBack(w)
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return n, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		select {
+		case <-w.ready:
+			// Granted while giving up; hand the bytes on
+			b.used -= n
+		default:
+			b.waiters.Remove(elem)
+		}
+		b.grant()
+		b.mu.Unlock()
+		return 0, fmt.Errorf("waiting for read memory: %w", ctx.Err())
+	}
+}
+
+// release returns n reserved bytes
+func (b *byteBudget) release(n int64) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.grant()
+	b.mu.Unlock()
+}
+
+// resize changes the size of the budget. Reservations already granted are
+// kept, and those waiting for more than the new size are granted once
+// nothing else is reserved.
+func (b *byteBudget) resize(size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.size = size
+	b.grant()
+}
+
+// fits reports whether n more bytes can be reserved: within the size, or
+// alone if the budget shrank below n. Callers hold b.mu.
+func (b *byteBudget) fits(n int64) bool {
+	return b.used+n <= b.size || b.used == 0
+}
+
+// grant wakes waiting reservations, in order, while they fit. Callers
+// hold b.mu.
+func (b *byteBudget) grant() {
+	for elem := b.waiters.Front(); elem != nil; elem = b.waiters.Front() {
+		w, _ := elem.Value.(*budgetWaiter)
+		if !b.fits(w.n) {
+			return
+		}
+		b.used += w.n
+		b.waiters.Remove(elem)
+		close(w.ready)
+	}
+}
+
+// WithMaxBytesInFlight bounds the file content held in memory at once by
+// all workers (0 is unlimited). Workers wait for room before reading a
+// file, so memory use does not depend on how large the scanned files are.
+func WithMaxBytesInFlight(n int64) Option {
+	return func(s *Scanner) {
+		s.options.MaxBytesInFlight = n
+	}
+}
@@ -0,0 +1,141 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestByteBudgetWaitsForRoom(t *testing.T) {
+	b := newByteBudget(100)
+	ctx := context.Background()
+
+	first, err := b.acquire(ctx, 60)
+	if err != nil || first != 60 {
+		t.Fatalf("expected 60 bytes, got %d, %v", first, err)
+	}
+
+	granted := make(chan int64, 1)
+	go func() {
+		n, _ := b.acquire(ctx, 60)
+		granted <- n
+	}()
+	select {
+	case <-granted:
+		t.Fatal("expected reservation to wait for room")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	b.release(first)
+	select {
+	case n := <-granted:
+		b.release(n)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected reservation once bytes were released")
+	}
+}
+
+func TestByteBudgetClampsLargeReservations(t *testing.T) {
+	b := newByteBudget(100)
+	n, err := b.acquire(context.Background(), 1<<30)
+	if err != nil || n != 100 {
+		t.Fatalf("expected reservation clamped to the budget, got %d, %v", n, err)
+	}
+	b.release(n)
+	if b.used != 0 {
+		t.Errorf("expected budget to be free, %d bytes used", b.used)
+	}
+}
+
+func TestByteBudgetResize(t *testing.T) {
+	b := newByteBudget(100)
+	ctx := context.Background()
+	held, _ := b.acquire(ctx, 80)
+
+	// A reservation bigger than the shrunk budget waits for the rest to be
+	// released, then goes alone
+	b.resize(50)
+	granted := make(chan int64, 1)
+	go func() {
+		n, _ := b.acquire(ctx, 70)
+		granted <- n
+	}()
+	select {
+	case <-granted:
+		t.Fatal("expected reservation to wait while the budget is over its size")
+	case <-time.After(20 * time.Millisecond):
+	}
+	b.release(held)
+	select {
+	case n := <-granted:
+		if n != 50 {
+			t.Errorf("expected reservation clamped to the shrunk budget, got %d", n)
+		}
+		b.release(n)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected reservation once nothing else was reserved")
+	}
+
+	b.resize(100)
+	if n, _ := b.acquire(ctx, 100); n != 100 {
+		t.Errorf("expected the grown budget to grant 100 bytes, got %d", n)
+	}
+}
+
+func TestByteBudgetCancel(t *testing.T) {
+	b := newByteBudget(100)
+	held, _ := b.acquire(context.Background(), 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := b.acquire(ctx, 10); err == nil {
+		t.Fatal("expected error when cancelled while waiting")
+	}
+
+	b.release(held)
+	n, err := b.acquire(context.Background(), 100)
+	if err != nil || n != 100 {
+		t.Errorf("expected the whole budget after cancellation, got %d, %v", n, err)
+	}
+}
+
+func TestScanWithSmallReadBudget(t *testing.T) {
+	dir := t.TempDir()
+	const files = 20
+	for i := 0; i < files; i++ {
+		content := "<?php " + strings.Repeat("echo 'x';", 500)
+		if i == 0 {
+			content += " eval($_POST['x']);"
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.php", i)), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	// Smaller than a single file, so files are read one at a time
+	s := NewScanner(createTestSignatureSet(), WithScanWorkers(8), WithMaxBytesInFlight(1024))
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	matched := 0
+	for result := range results {
+		if result.Error != nil {
+			t.Errorf("unexpected error for %s: %v", result.Path, result.Error)
+		}
+		if result.HasMatches() {
+			matched++
+		}
+	}
+
+	if stats := s.GetStats(); stats.FilesScanned != files || matched != 1 {
+		t.Errorf("expected %d files scanned and 1 matched, got %d and %d", files, stats.FilesScanned, matched)
+	}
+	if s.readBudget.used != 0 {
+		t.Errorf("expected budget released, %d bytes used", s.readBudget.used)
+	}
+}
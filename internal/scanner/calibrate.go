@@ -0,0 +1,175 @@
+// Package scanner provides calibration of scan settings to the host
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// DefaultCalibrationCorpusBytes is how much synthetic content match speed
+// is measured on
+const DefaultCalibrationCorpusBytes = 8 << 20
+
+// calibrationFileSize is the size of each synthetic file, about that of a
+// large plugin file
+const calibrationFileSize = 32 << 10
+
+// minCalibratedReadMemory is the least read memory recommended, so files
+// of a typical size still fit alongside each other
+const minCalibratedReadMemory = 16 << 20
+
+// lowMemory is the available memory below which regexp2 is recommended, as
+// it compiles the signatures into less memory than the other engines
+const lowMemory = 512 << 20
+
+// syntheticSnippets are the PHP, HTML and JavaScript fragments the
+// synthetic corpus is assembled from, in the proportions of a typical
+// WordPress site. None of them match a signature
+var syntheticSnippets = []string{
+	"<?php\n/**\n * Plugin helpers for %s\n *\n * @package %s\n */\n\nif ( ! defined( 'ABSPATH' ) ) {\n\texit;\n}\n",
+	"function %s_register_settings( $args = array() ) {\n\t$defaults = array( 'label' => __( 'Settings', '%s' ), 'capability' => 'manage_options' );\n\treturn wp_parse_args( $args, $defaults );\n}\n",
+	"add_action( 'init', '%s_init' );\nadd_filter( 'the_content', array( $this, 'filter_%s' ), 10, 1 );\n",
+	"$query = $wpdb->prepare( \"SELECT ID, post_title FROM {$wpdb->posts} WHERE post_type = %%s AND post_status = 'publish' LIMIT %%d\", '%s', 20 );\n$rows = $wpdb->get_results( $query ); // %s\n",
+	"<div class=\"%s-wrapper\">\n\t<h2><?php echo esc_html( get_the_title() ); ?></h2>\n\t<p class=\"%s-excerpt\"><?php the_excerpt(); ?></p>\n</div>\n",
+	"jQuery( function ( $ ) {\n\t$( '.%s-toggle' ).on( 'click', function ( e ) {\n\t\te.preventDefault();\n\t\t$( this ).closest( '.%s' ).toggleClass( 'open' );\n\t} );\n} );\n",
+	"$options = get_option( '%s_options', array() );\nforeach ( $options as $key => $value ) {\n\tupdate_post_meta( $post_id, '_%s_' . sanitize_key( $key ), $value );\n}\n",
+	"/* translators: %%s: %s name */\n$message = sprintf( __( 'Saved %%s.', '%s' ), esc_html( $name ) );\n",
+}
+
+// SyntheticCorpus returns about size bytes of PHP, HTML and JavaScript
+// resembling WordPress code, the same on every call, to time matching on
+// without reading files
+func SyntheticCorpus(size int64) []BenchFile {
+	rng := rand.New(rand.NewPCG(1, 2)) // #nosec G404 -- fixed content, not security
+	var files []BenchFile
+	for total := int64(0); total < size; {
+		var b strings.Builder
+		for b.Len() < calibrationFileSize {
+			name := fmt.Sprintf("plugin_%x", rng.Uint32())
+			_, _ = fmt.Fprintf(&b, syntheticSnippets[rng.IntN(len(syntheticSnippets))], name, name)
+		}
+		files = append(files, BenchFile{
+			Path:    fmt.Sprintf("synthetic/%d.php", len(files)),
+			Content: []byte(b.String()),
+		})
+		total += int64(b.Len())
+	}
+	return files
+}
+
+// Calibration is what was measured of the host and the scan settings
+// recommended for it
+type Calibration struct {
+	CPUs int `json:"cpus"`
+
+	// Reads is how fast a sample of the files to scan was read, nil when
+	// no paths were given
+	Reads *ReadSpeed `json:"reads,omitempty"`
+
+	// MatchMBPerSecond is how fast one worker matched the synthetic
+	// corpus with each engine
+	MatchMBPerSecond map[string]float64 `json:"match_mb_per_second"`
+
+	// AvailableMemory is the memory free for the scan in bytes, 0 if it
+	// couldn't be read
+	AvailableMemory int64 `json:"available_memory"`
+
+	Recommended CalibratedSettings `json:"recommended"`
+
+	// Estimate is how long scanning the files would take with the
+	// recommended workers, nil when no paths were given
+	Estimate *ScanEstimate `json:"estimate,omitempty"`
+}
+
+// CalibratedSettings are the malware-scan settings recommended for a host
+type CalibratedSettings struct {
+	Workers         int    `json:"workers"`
+	MaxReadMemoryMB int64  `json:"max_read_memory_mb"`
+	RegexEngine     string `json:"regex_engine"`
+}
+
+// Calibrate times reading a sample of the discovered files, which may be
+// nil, and one worker matching corpus with each engine, reads the memory
+// available, and recommends settings from those. With files discovered,
+// it then estimates how long scanning them would take with the
+// recommended workers
+func (s *Scanner) Calibrate(ctx context.Context, d *Discovery, engines []string, corpus []BenchFile) (*Calibration, error) {
+	c := &Calibration{
+		CPUs:             runtime.NumCPU(),
+		MatchMBPerSecond: make(map[string]float64, len(engines)),
+		AvailableMemory:  availableMemory(),
+	}
+	if d != nil {
+		reads, err := s.measureReads(ctx, d, DefaultCalibrationBytes)
+		if err != nil {
+			return nil, err
+		}
+		c.Reads = reads
+	}
+
+	sigSet := s.SignatureSet()
+	for _, engine := range engines {
+		result, err := Bench(ctx, sigSet, engine, corpus, 1, WithMatchTimeout(s.options.MatchTimeout), WithHintSlack(s.options.HintSlack))
+		if err != nil {
+			return nil, fmt.Errorf("timing %s: %w", engine, err)
+		}
+		c.MatchMBPerSecond[engine] = result.MBPerSecond
+	}
+	c.Recommended = c.recommend(engines)
+
+	if d != nil {
+		estimate, err := s.estimateScan(ctx, d, DefaultCalibrationBytes, c.Recommended.Workers)
+		if err != nil {
+			return nil, err
+		}
+		c.Estimate = estimate
+	}
+	return c, nil
+}
+
+// recommend picks the fastest engine, or regexp2 when memory is short;
+// enough workers to keep up with the disk, within the CPUs; and a quarter
+// of the available memory for file content, within the default
+func (c *Calibration) recommend(engines []string) CalibratedSettings {
+	settings := CalibratedSettings{
+		Workers:         c.CPUs,
+		MaxReadMemoryMB: DefaultMaxBytesInFlight >> 20,
+		RegexEngine:     RegexEngineAuto,
+	}
+	lowOnMemory := c.AvailableMemory > 0 && c.AvailableMemory < lowMemory
+	for _, engine := range engines {
+		if lowOnMemory {
+			if engine == RegexEngineRegexp2 {
+				settings.RegexEngine = engine
+			}
+			continue
+		}
+		if c.MatchMBPerSecond[engine] > c.MatchMBPerSecond[settings.RegexEngine] {
+			settings.RegexEngine = engine
+		}
+	}
+
+	if perWorker := c.MatchMBPerSecond[settings.RegexEngine]; perWorker > 0 && c.Reads != nil && c.Reads.BytesPerSecond > 0 {
+		disk := c.Reads.BytesPerSecond / (1 << 20)
+		settings.Workers = min(max(int(math.Ceil(disk/perWorker)), 1), c.CPUs)
+	}
+	if c.AvailableMemory > 0 {
+		settings.MaxReadMemoryMB = max(min(c.AvailableMemory/4, DefaultMaxBytesInFlight), minCalibratedReadMemory) >> 20
+	}
+	return settings
+}
+
+// Settings returns the recommended settings as config file settings, keyed
+// like those of a [profile:name] section
+func (s CalibratedSettings) Settings() map[string]string {
+	return map[string]string{
+		"workers":         strconv.Itoa(s.Workers),
+		"max-read-memory": strconv.FormatInt(s.MaxReadMemoryMB, 10),
+		"regex_engine":    s.RegexEngine,
+	}
+}
@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestSyntheticCorpus(t *testing.T) {
+	a, b := SyntheticCorpus(100<<10), SyntheticCorpus(100<<10)
+	if len(a) != 4 || len(a) != len(b) {
+		t.Fatalf("expected 4 files each time, got %d and %d", len(a), len(b))
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].Content, b[i].Content) {
+			t.Fatalf("file %d differs between calls", i)
+		}
+		if len(a[i].Content) < calibrationFileSize {
+			t.Errorf("file %d is %d bytes", i, len(a[i].Content))
+		}
+	}
+}
+
+func TestCalibrationRecommend(t *testing.T) {
+	engines := []string{RegexEngineAuto, RegexEngineRegexp2}
+	tests := []struct {
+		name        string
+		c           Calibration
+		workers     int
+		readMemory  int64
+		regexEngine string
+	}{
+		{
+			name:        "nothing known",
+			c:           Calibration{CPUs: 8, MatchMBPerSecond: map[string]float64{RegexEngineAuto: 10, RegexEngineRegexp2: 5}},
+			workers:     8,
+			readMemory:  DefaultMaxBytesInFlight >> 20,
+			regexEngine: RegexEngineAuto,
+		},
+		{
+			name: "slow disk",
+			c: Calibration{
+				CPUs:             8,
+				Reads:            &ReadSpeed{BytesPerSecond: 25 << 20},
+				MatchMBPerSecond: map[string]float64{RegexEngineAuto: 10, RegexEngineRegexp2: 12},
+				AvailableMemory:  4 << 30,
+			},
+			workers:     3,
+			readMemory:  DefaultMaxBytesInFlight >> 20,
+			regexEngine: RegexEngineRegexp2,
+		},
+		{
+			name: "small host",
+			c: Calibration{
+				CPUs:             2,
+				Reads:            &ReadSpeed{BytesPerSecond: 1 << 30},
+				MatchMBPerSecond: map[string]float64{RegexEngineAuto: 10, RegexEngineRegexp2: 5},
+				AvailableMemory:  40 << 20,
+			},
+			workers:     2,
+			readMemory:  minCalibratedReadMemory >> 20,
+			regexEngine: RegexEngineRegexp2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.c.recommend(engines)
+			if got.Workers != tt.workers || got.MaxReadMemoryMB != tt.readMemory || got.RegexEngine != tt.regexEngine {
+				t.Errorf("got %+v, want %d workers, %d MiB, %s", got, tt.workers, tt.readMemory, tt.regexEngine)
+			}
+		})
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	_, ss, _ := loadCorpusFixture(t)
+	s := NewScanner(ss, WithScanWorkers(2), WithReadWorkers(3))
+	ctx := context.Background()
+
+	d, err := s.Discover(ctx, corpusDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Calibrate(ctx, d, []string{RegexEngineAuto, RegexEngineRegexp2}, SyntheticCorpus(64<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Reads == nil || c.Reads.Files != int(d.Files) || c.Reads.Readers != 3 {
+		t.Errorf("unexpected reads %+v of %d files", c.Reads, d.Files)
+	}
+	if c.MatchMBPerSecond[RegexEngineAuto] <= 0 || c.MatchMBPerSecond[RegexEngineRegexp2] <= 0 {
+		t.Errorf("unexpected match speeds %v", c.MatchMBPerSecond)
+	}
+	if c.Estimate == nil || c.Estimate.Workers != c.Recommended.Workers {
+		t.Errorf("expected an estimate with the recommended workers, got %+v", c.Estimate)
+	}
+	if settings := c.Recommended.Settings(); settings["workers"] == "" || settings["regex_engine"] == "" {
+		t.Errorf("unexpected settings %v", settings)
+	}
+}
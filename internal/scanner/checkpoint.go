@@ -0,0 +1,279 @@
+// Package scanner provides checkpoints of scans stopped at a deadline
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errScanDeadline stops a walk once the scan's MaxDuration has passed
+var errScanDeadline = errors.New("scan deadline reached")
+
+// Checkpoint records how far a scan stopped at its deadline got, so a later
+// scan can resume from it. Files are found in walk order (by name, depth
+// first), and every file up to and including After was scanned.
+type Checkpoint struct {
+	Paths []string `json:"paths"`
+	// Root is the index in Paths of the path the scan stopped in; the
+	// paths before it were scanned
+	Root int `json:"root"`
+	// After is the last file of Root scanned, relative to it: "" if none
+	// was, "." if Root is a file that was
+	After        string    `json:"after,omitempty"`
+	FilesScanned int64     `json:"files_scanned"`
+	Created      time.Time `json:"created"`
+}
+
+// Matches reports whether the checkpoint was made scanning paths
+func (c *Checkpoint) Matches(paths []string) bool {
+	return slices.Equal(c.Paths, paths)
+}
+
+// Progress estimates the fraction of the scan done, from how far into the
+// walk of its paths the checkpoint is
+func (c *Checkpoint) Progress() float64 {
+	if len(c.Paths) == 0 {
+		return 0
+	}
+	done := float64(c.Root)
+	switch c.After {
+	case "":
+	case ".":
+		done++
+	default:
+		done += walkFraction(c.Paths[c.Root], c.After)
+	}
+	return done / float64(len(c.Paths))
+}
+
+// walkFraction estimates the fraction of the walk of root up to and
+// including the file rel, weighting the entries of each directory equally
+func walkFraction(root, rel string) float64 {
+	done, span := 0.0, 1.0
+	dir := root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) == 0 {
+			return done
+		}
+		i := sort.Search(len(entries), func(i int) bool { return entries[i].Name() >= name })
+		span /= float64(len(entries))
+		done += float64(i) * span
+		dir = filepath.Join(dir, name)
+	}
+	return done + span
+}
+
+// LoadCheckpoint reads a checkpoint saved by SaveCheckpoint
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- user-specified checkpoint
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint %s: %w", path, err)
+	}
+	if len(cp.Paths) == 0 || cp.Root < 0 || cp.Root >= len(cp.Paths) {
+		return nil, fmt.Errorf("invalid checkpoint %s", path)
+	}
+	return &cp, nil
+}
+
+// SaveCheckpoint writes a checkpoint to path, replacing it atomically
+func SaveCheckpoint(path string, cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating checkpoint directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".checkpoint-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("saving checkpoint: %w", err)
+	}
+	return nil
+}
+
+// comparePaths compares two paths relative to the same directory in the
+// order filepath.WalkDir visits them: name by name, a directory before the
+// files in it
+func comparePaths(a, b string) int {
+	as := strings.Split(a, string(filepath.Separator))
+	bs := strings.Split(b, string(filepath.Separator))
+	return slices.Compare(as, bs)
+}
+
+// walkPos is the position of a found file in the walk of the scan paths.
+// Files found through followed symlinked directories have no position.
+type walkPos struct {
+	root int
+	rel  string
+	ok   bool
+}
+
+// scanProgress tracks how far a scan has got, to stop it at its deadline
+// and make a checkpoint it can be resumed from
+type scanProgress struct {
+	paths    []string
+	deadline time.Time
+	resume   *Checkpoint
+
+	// root and sent are only used by the file locator
+	root int
+	sent int
+
+	stopped atomic.Bool
+	dropped atomic.Int64
+
+	// abort stops the scan, with err saying why
+	abort context.CancelCauseFunc
+	err   error
+
+	mu sync.Mutex
+	// next is the first file sent that hasn't been scanned; done holds
+	// the positions of the files after it that have
+	next int
+	done map[int]walkPos
+	mark walkPos
+}
+
+func newScanProgress(paths []string, maxDuration time.Duration, resume *Checkpoint) *scanProgress {
+	p := &scanProgress{
+		paths:  paths,
+		resume: resume,
+		done:   make(map[int]walkPos),
+	}
+	if maxDuration > 0 {
+		p.deadline = time.Now().Add(maxDuration)
+	}
+	if resume != nil {
+		p.mark = walkPos{root: resume.Root, rel: resume.After, ok: true}
+	}
+	return p
+}
+
+// pastDeadline reports whether the scan should stop, noting that it did
+func (p *scanProgress) pastDeadline() bool {
+	if p.deadline.IsZero() || time.Now().Before(p.deadline) {
+		return false
+	}
+	p.stopped.Store(true)
+	return true
+}
+
+// fail stops the scan with err, unless it has already failed
+func (p *scanProgress) fail(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.abort(err)
+}
+
+// failure returns why the scan failed, or nil
+func (p *scanProgress) failure() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// skipsRoot reports whether the scan path at index i was scanned before
+// the scan being resumed stopped
+func (p *scanProgress) skipsRoot(i int) bool {
+	if p.resume == nil {
+		return false
+	}
+	return i < p.resume.Root || (i == p.resume.Root && p.resume.After == ".")
+}
+
+// resumeSkips reports whether path, found walking the scan path root, was
+// scanned before the scan being resumed stopped. For a directory, it
+// reports whether everything in it was.
+func (p *scanProgress) resumeSkips(root, path string, isDir bool) bool {
+	if p.resume == nil || p.root != p.resume.Root || p.resume.After == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	after := p.resume.After
+	c := comparePaths(rel, after)
+	if isDir {
+		return c < 0 && !strings.HasPrefix(after, rel+string(filepath.Separator))
+	}
+	return c <= 0
+}
+
+// position returns the walk position of a file found at path walking the
+// scan path root
+func (p *scanProgress) position(root, path string) walkPos {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return walkPos{}
+	}
+	return walkPos{root: p.root, rel: rel, ok: true}
+}
+
+// scanned records that the file sent as seq has been scanned, moving the
+// mark past every file sent before it once they all have been
+func (p *scanProgress) scanned(seq int, pos walkPos) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done[seq] = pos
+	for {
+		pos, ok := p.done[p.next]
+		if !ok {
+			return
+		}
+		delete(p.done, p.next)
+		p.next++
+		if pos.ok {
+			p.mark = pos
+		}
+	}
+}
+
+// checkpoint returns where a resumed scan would start. Files scanned count
+// those of the scans resumed.
+func (p *scanProgress) checkpoint(filesScanned int64) *Checkpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume != nil {
+		filesScanned += p.resume.FilesScanned
+	}
+	return &Checkpoint{
+		Paths:        p.paths,
+		Root:         p.mark.root,
+		After:        p.mark.rel,
+		FilesScanned: filesScanned,
+		Created:      time.Now(),
+	}
+}
@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestComparePaths(t *testing.T) {
+	// In the order filepath.WalkDir visits them
+	walk := []string{"a", "a/b", "a/b/c.php", "a/d.php", "a.php", "b.php", "z"}
+	for i := range walk {
+		for k := range walk {
+			want := 0
+			if i < k {
+				want = -1
+			} else if i > k {
+				want = 1
+			}
+			if got := comparePaths(filepath.FromSlash(walk[i]), filepath.FromSlash(walk[k])); got != want {
+				t.Errorf("comparePaths(%q, %q) = %d, want %d", walk[i], walk[k], got, want)
+			}
+		}
+	}
+}
+
+func TestScanProgressMark(t *testing.T) {
+	p := newScanProgress([]string{"/a", "/b"}, 0, nil)
+	pos := func(root int, rel string) walkPos { return walkPos{root: root, rel: rel, ok: true} }
+
+	p.scanned(1, pos(0, "y.php"))
+	if cp := p.checkpoint(1); cp.After != "" {
+		t.Errorf("expected no mark until the first file is scanned, got %+v", cp)
+	}
+	p.scanned(0, pos(0, "x.php"))
+	p.scanned(2, walkPos{})
+	if cp := p.checkpoint(3); cp.Root != 0 || cp.After != "y.php" {
+		t.Errorf("expected a mark after y.php, got %+v", cp)
+	}
+	p.scanned(3, pos(1, "."))
+	if cp := p.checkpoint(4); cp.Root != 1 || cp.After != "." || cp.Progress() != 1 {
+		t.Errorf("expected a mark after the file /b, got %+v", cp)
+	}
+}
+
+func TestCheckpointSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "checkpoint.json")
+	cp := &Checkpoint{Paths: []string{"/var/www"}, After: "wp-admin/index.php", FilesScanned: 42, Created: time.Now().UTC()}
+	if err := SaveCheckpoint(path, cp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !loaded.Matches(cp.Paths) || loaded.After != cp.After || loaded.FilesScanned != 42 || !loaded.Created.Equal(cp.Created) {
+		t.Errorf("expected %+v, got %+v", cp, loaded)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"paths": [], "root": 0}`), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := LoadCheckpoint(path); err == nil {
+		t.Error("expected a checkpoint without paths to be rejected")
+	}
+}
+
+func TestScanMaxDurationResume(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"a.php", "b/c.php", "b/d/e.php", "b/f.php", "g.php"}
+	for _, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("<?php eval($x);"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	scan := func(s *Scanner, paths ...string) []string {
+		t.Helper()
+		results, err := s.Scan(context.Background(), paths...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var scanned []string
+		for result := range results {
+			rel, _ := filepath.Rel(dir, result.Path)
+			scanned = append(scanned, filepath.ToSlash(rel))
+		}
+		sort.Strings(scanned)
+		return scanned
+	}
+
+	// A scan that finishes in time has no checkpoint, though it tracks one
+	s := NewScanner(createTestSignatureSet(), WithMaxDuration(time.Hour))
+	if got := scan(s, dir); !slices.Equal(got, files) {
+		t.Errorf("expected every file to be scanned, got %v", got)
+	}
+	if cp := s.Checkpoint(); cp != nil {
+		t.Errorf("expected no checkpoint, got %+v", cp)
+	}
+	if cp := s.progress.checkpoint(0); cp.After != "g.php" {
+		t.Errorf("expected the mark after the last file, got %+v", cp)
+	}
+
+	// A scan out of time stops without scanning anything more
+	s = NewScanner(createTestSignatureSet(), WithMaxDuration(time.Nanosecond))
+	if got := scan(s, dir); len(got) != 0 {
+		t.Errorf("expected no files to be scanned, got %v", got)
+	}
+	cp := s.Checkpoint()
+	if cp == nil || cp.Root != 0 || cp.After != "" || cp.Progress() != 0 {
+		t.Fatalf("expected a checkpoint at the start, got %+v", cp)
+	}
+
+	// Resuming skips the files scanned, pruning directories done
+	cp.After = filepath.FromSlash("b/d/e.php")
+	s = NewScanner(createTestSignatureSet(), WithResume(cp))
+	if got, want := scan(s, dir), []string{"b/f.php", "g.php"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v to be scanned, got %v", want, got)
+	}
+	if progress := cp.Progress(); progress <= 0.5 || progress >= 1 {
+		t.Errorf("expected progress past half way, got %v", progress)
+	}
+
+	// Paths before the checkpoint's are skipped whole
+	other := filepath.Join(dir, "a.php")
+	s = NewScanner(createTestSignatureSet(), WithResume(&Checkpoint{Paths: []string{other, dir}, Root: 1, After: "g.php"}))
+	if got := scan(s, other, dir); len(got) != 0 {
+		t.Errorf("expected no files to be scanned, got %v", got)
+	}
+	if _, err := s.Scan(context.Background(), dir); err == nil {
+		t.Error("expected resuming a scan of other paths to fail")
+	}
+}
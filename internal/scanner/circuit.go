@@ -0,0 +1,156 @@
+// Package scanner provides circuit breakers isolating failing devices
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen indicates a file was not read because reads from its
+// device keep failing
+var ErrCircuitOpen = errors.New("circuit open")
+
+// DefaultCircuitThreshold is how many reads in a row must fail on a device
+// before its files are skipped
+const DefaultCircuitThreshold = 10
+
+// DefaultCircuitCooldown is how long a device's files are skipped before
+// one is read again to see whether it has recovered
+const DefaultCircuitCooldown = 30 * time.Second
+
+// States of a device's circuit
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitState describes the circuit of one device
+type CircuitState struct {
+	Device uint64 `json:"device"`
+	// Path is the first file read from the device, to tell which it is
+	Path  string `json:"path"`
+	State string `json:"state"`
+	// Trips counts the times the circuit opened, and Rejected the files
+	// skipped while it was
+	Trips    int64 `json:"trips"`
+	Rejected int64 `json:"rejected"`
+}
+
+// circuit tracks reads from one device. After threshold failures in a row
+// it opens, rejecting reads; once the cooldown has passed it lets one read
+// through, closing again if that succeeds.
+type circuit struct {
+	state    CircuitState
+	failures int
+	opened   time.Time
+	probing  bool
+}
+
+// circuitBreakers keeps a circuit for each device files are read from, so
+// one failing mount doesn't hold up the others
+type circuitBreakers struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[uint64]*circuit
+	now       func() time.Time
+}
+
+func newCircuitBreakers(threshold int, cooldown time.Duration) *circuitBreakers {
+	return &circuitBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[uint64]*circuit),
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a file at path on device may be read, returning
+// an ErrCircuitOpen error if not
+func (b *circuitBreakers) allow(device uint64, path string) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[device]
+	if c == nil {
+		c = &circuit{state: CircuitState{Device: device, Path: path, State: CircuitClosed}}
+		b.circuits[device] = c
+	}
+	if c.state.State == CircuitClosed {
+		return nil
+	}
+	if !c.probing && b.now().Sub(c.opened) >= b.cooldown {
+		c.probing = true
+		c.state.State = CircuitHalfOpen
+		return nil
+	}
+	c.state.Rejected++
+	return fmt.Errorf("%w: reads from the device of %s keep failing", ErrCircuitOpen, c.state.Path)
+}
+
+// record notes the outcome of reading a file from device
+func (b *circuitBreakers) record(device uint64, err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[device]
+	if c == nil {
+		return
+	}
+	if !deviceFailure(err) {
+		c.failures = 0
+		c.probing = false
+		c.state.State = CircuitClosed
+		return
+	}
+	c.failures++
+	if c.probing || (c.state.State == CircuitClosed && c.failures >= b.threshold) {
+		c.probing = false
+		c.opened = b.now()
+		if c.state.State == CircuitClosed {
+			c.state.Trips++
+		}
+		c.state.State = CircuitOpen
+	}
+}
+
+// deviceFailure reports whether err suggests the device itself is failing,
+// rather than the file
+func deviceFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch ClassifyError(err) {
+	case CodeIOError, CodeTimeout, CodeUnavailable:
+		return true
+	}
+	return false
+}
+
+// states returns the state of every device's circuit, by device
+func (b *circuitBreakers) states() []CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make([]CircuitState, 0, len(b.circuits))
+	for _, c := range b.circuits {
+		states = append(states, c.state)
+	}
+	sort.Slice(states, func(i, k int) bool { return states[i].Device < states[k].Device })
+	return states
+}
+
+// reset forgets every circuit
+func (b *circuitBreakers) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.circuits = make(map[uint64]*circuit)
+}
@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreakers(3, time.Minute)
+	b.now = func() time.Time { return now }
+	ioErr := fmt.Errorf("failed to read file: %w", syscall.EIO)
+
+	// Only failures of the device itself count
+	for range 5 {
+		if err := b.allow(1, "/mnt/nfs/a.php"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b.record(1, fs.ErrPermission)
+	}
+	for range 3 {
+		if err := b.allow(1, "/mnt/nfs/a.php"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b.record(1, ioErr)
+	}
+
+	// The failing device is cut off while others carry on
+	if err := b.allow(1, "/mnt/nfs/b.php"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the circuit to be open, got %v", err)
+	}
+	if err := b.allow(2, "/var/www/index.php"); err != nil {
+		t.Errorf("expected another device to be read, got %v", err)
+	}
+	b.record(2, nil)
+
+	// After the cooldown one read tries the device again
+	now = now.Add(time.Minute)
+	if err := b.allow(1, "/mnt/nfs/c.php"); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %v", err)
+	}
+	if err := b.allow(1, "/mnt/nfs/d.php"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected only one probe at a time, got %v", err)
+	}
+	b.record(1, ioErr)
+	if err := b.allow(1, "/mnt/nfs/e.php"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected a failed probe to reopen the circuit, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := b.allow(1, "/mnt/nfs/f.php"); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %v", err)
+	}
+	b.record(1, nil)
+
+	states := b.states()
+	want := []CircuitState{
+		{Device: 1, Path: "/mnt/nfs/a.php", State: CircuitClosed, Trips: 1, Rejected: 3},
+		{Device: 2, Path: "/var/www/index.php", State: CircuitClosed},
+	}
+	if fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Errorf("expected circuits %+v, got %+v", want, states)
+	}
+}
+
+func TestScanCircuitStats(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.php"), []byte("<?php echo 1;"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	s := NewScanner(createTestSignatureSet())
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range results {
+	}
+	circuits := s.GetStats().Circuits
+	if len(circuits) != 1 || circuits[0].State != CircuitClosed || circuits[0].Path != filepath.Join(dir, "index.php") {
+		t.Errorf("expected one closed circuit, got %+v", circuits)
+	}
+
+	if code := ClassifyError(fmt.Errorf("%w: device", ErrCircuitOpen)); !(ScanError{Code: code}).IsRetryable() {
+		t.Errorf("expected files skipped by an open circuit to be retried, got %s", code)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+// Package scanner provides the devices files are on, on Unix
+package scanner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileDevice returns the ID of the device a file is on
+func fileDevice(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev) // #nosec G115 -- device IDs are never negative
+	}
+	return 0
+}
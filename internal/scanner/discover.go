@@ -0,0 +1,276 @@
+// Package scanner provides discovery of the files a scan would read
+package scanner
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDiscoveryTop is how many of the largest files a discovery lists
+const DefaultDiscoveryTop = 20
+
+// DefaultCalibrationBytes is how much of the files found is scanned to
+// estimate how long the whole scan takes
+const DefaultCalibrationBytes = 32 << 20
+
+// calibrationSampleSize is how many files found are picked at random to
+// calibrate the estimate with
+const calibrationSampleSize = 500
+
+// DiscoveredFile is a file a scan would read
+type DiscoveredFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Discovery describes the files a scan would read, found without reading
+// them
+type Discovery struct {
+	Files int64 `json:"files"`
+	// Bytes is how much would be read, within the content limit
+	Bytes        int64            `json:"bytes"`
+	FilesSkipped int64            `json:"files_skipped"`
+	DirsSkipped  int64            `json:"dirs_skipped"`
+	Largest      []DiscoveredFile `json:"largest"`
+	Duration     time.Duration    `json:"duration"`
+
+	// sample is a random pick of the files, to calibrate estimates with
+	sample []string
+}
+
+// sizeHeap is a min-heap of files by size, holding the largest found
+type sizeHeap []DiscoveredFile
+
+func (h sizeHeap) Len() int           { return len(h) }
+func (h sizeHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h sizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *sizeHeap) Push(x any)        { *h = append(*h, x.(DiscoveredFile)) }
+func (h *sizeHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// Discover walks paths as a scan of them would, applying the filter, shard
+// and limits, and describes the files it would read without reading them
+func (s *Scanner) Discover(ctx context.Context, paths ...string) (*Discovery, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths to scan")
+	}
+	resume := s.options.Resume
+	if resume != nil && !resume.Matches(paths) {
+		return nil, fmt.Errorf("checkpoint is of a scan of %s", strings.Join(resume.Paths, ", "))
+	}
+
+	s.mu.Lock()
+	s.stats = ScanStats{StartTime: time.Now()}
+	s.mu.Unlock()
+	s.errs.Reset()
+
+	files := make(chan fileTask, 1000)
+	go s.locateFiles(ctx, newScanProgress(paths, 0, resume), s.options.Shard, files)
+
+	start := time.Now()
+	d := &Discovery{}
+	largest := &sizeHeap{}
+	for task := range files {
+		info, err := os.Stat(task.path)
+		if err != nil {
+			s.errs.RecordError(StageWalk, task.path, err)
+			continue
+		}
+		d.Files++
+		d.Bytes += s.readSize(info.Size())
+
+		heap.Push(largest, DiscoveredFile{Path: task.path, Size: info.Size()})
+		if largest.Len() > DefaultDiscoveryTop {
+			heap.Pop(largest)
+		}
+
+		// Reservoir sampling keeps each file equally likely to be picked
+		if len(d.sample) < calibrationSampleSize {
+			d.sample = append(d.sample, task.path)
+		} else if i := rand.Int64N(d.Files); i < calibrationSampleSize { // #nosec G404 -- sampling, not security
+			d.sample[i] = task.path
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.Duration = time.Since(start)
+	d.FilesSkipped = atomic.LoadInt64(&s.stats.FilesSkipped)
+	d.DirsSkipped = atomic.LoadInt64(&s.stats.DirsSkipped)
+	d.Largest = append([]DiscoveredFile{}, *largest...)
+	sort.Slice(d.Largest, func(i, k int) bool { return d.Largest[i].Size > d.Largest[k].Size })
+	return d, nil
+}
+
+// ScanEstimate is how long a scan of discovered files is expected to take
+type ScanEstimate struct {
+	// BytesPerSecond is how fast one worker read and matched the sample
+	BytesPerSecond float64       `json:"bytes_per_second"`
+	SampleFiles    int           `json:"sample_files"`
+	SampleBytes    int64         `json:"sample_bytes"`
+	Workers        int           `json:"workers"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// EstimateScan times one worker reading and matching a random sample of
+// the discovered files, up to maxBytes of them, and extrapolates how long
+// the scanner's match workers would take over all of them
+func (s *Scanner) EstimateScan(ctx context.Context, d *Discovery, maxBytes int64) (*ScanEstimate, error) {
+	return s.estimateScan(ctx, d, maxBytes, s.options.MatchWorkers)
+}
+
+// estimateScan is EstimateScan for a number of workers
+func (s *Scanner) estimateScan(ctx context.Context, d *Discovery, maxBytes int64, workers int) (*ScanEstimate, error) {
+	estimate := &ScanEstimate{Workers: max(workers, 1)}
+	var elapsed time.Duration
+	for _, path := range d.sample {
+		if estimate.SampleBytes >= maxBytes {
+			break
+		}
+		start := time.Now()
+		n, err := s.calibrateFile(ctx, path, maxBytes-estimate.SampleBytes)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		elapsed += time.Since(start)
+		estimate.SampleFiles++
+		estimate.SampleBytes += n
+	}
+	if estimate.SampleBytes == 0 || elapsed <= 0 {
+		return estimate, nil
+	}
+
+	estimate.BytesPerSecond = float64(estimate.SampleBytes) / elapsed.Seconds()
+	seconds := float64(d.Bytes) / (estimate.BytesPerSecond * float64(estimate.Workers))
+	estimate.Duration = time.Duration(seconds * float64(time.Second))
+	return estimate, nil
+}
+
+// calibrateFile reads and matches up to limit bytes of a file as a scan
+// would, within the read budget and file time limit, returning how many
+// bytes it matched
+func (s *Scanner) calibrateFile(ctx context.Context, path string, limit int64) (int64, error) {
+	file, err := os.Open(path) // #nosec G304 -- file found walking user-specified paths
+	if err != nil {
+		return 0, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("reading %s: %w", path, err)
+	}
+	limit = min(limit, s.readSize(info.Size()))
+	if s.readBudget != nil {
+		reserved, err := s.readBudget.acquire(ctx, limit)
+		if err != nil {
+			return 0, err
+		}
+		defer s.readBudget.release(reserved)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(file, limit))
+	if err != nil {
+		return 0, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	mc := s.matcher.Load().NewMatchContext()
+	fileCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.options.FileTimeout > 0 {
+		fileCtx, cancel = context.WithTimeout(ctx, s.options.FileTimeout)
+	}
+	defer cancel()
+	if err := mc.Match(fileCtx, content); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return 0, fmt.Errorf("matching %s: %w", path, err)
+	}
+	return int64(len(content)), nil
+}
+
+// ReadSpeed is how fast a sample of discovered files was read
+type ReadSpeed struct {
+	Files          int           `json:"files"`
+	Bytes          int64         `json:"bytes"`
+	Readers        int           `json:"readers"`
+	Duration       time.Duration `json:"duration"`
+	BytesPerSecond float64       `json:"bytes_per_second"`
+}
+
+// measureReads reads a random sample of the discovered files, up to
+// maxBytes of them, with as many readers as the scanner has read workers and
+// without matching, to time how fast their disks deliver content. Files
+// already in the page cache make it optimistic
+func (s *Scanner) measureReads(ctx context.Context, d *Discovery, maxBytes int64) (*ReadSpeed, error) {
+	speed := &ReadSpeed{Readers: max(s.options.ReadWorkers, 1)}
+	paths := make(chan string)
+	var files, total atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < speed.Readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if n, err := s.readSample(path); err == nil {
+					files.Add(1)
+					total.Add(n)
+				}
+			}
+		}()
+	}
+	for _, path := range d.sample {
+		if total.Load() >= maxBytes || ctx.Err() != nil {
+			break
+		}
+		paths <- path
+	}
+	close(paths)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	speed.Duration = time.Since(start)
+	speed.Files = int(files.Load())
+	speed.Bytes = total.Load()
+	if speed.Bytes > 0 && speed.Duration > 0 {
+		speed.BytesPerSecond = float64(speed.Bytes) / speed.Duration.Seconds()
+	}
+	return speed, nil
+}
+
+// readSample reads as much of a file as a scan would, discarding it
+func (s *Scanner) readSample(path string) (int64, error) {
+	file, err := os.Open(path) // #nosec G304 -- file found walking user-specified paths
+	if err != nil {
+		return 0, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("reading %s: %w", path, err)
+	}
+	n, err := io.Copy(io.Discard, io.LimitReader(file, s.readSize(info.Size())))
+	if err != nil {
+		return n, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"index.php":          100,
+		"wp-admin/admin.php": 300,
+		"wp-admin/big.js":    5000,
+		"readme.txt":         1000,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("<?php "+strings.Repeat("x", size-6)), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithContentLimit(1000), WithEmbeddedPHPDetection(false))
+	d, err := s.Discover(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Files != 3 || d.Bytes != 100+300+1000 || d.FilesSkipped != 1 {
+		t.Errorf("expected 3 files of 1400 bytes and 1 skipped, got %+v", d)
+	}
+	if len(d.Largest) != 3 || filepath.Base(d.Largest[0].Path) != "big.js" || d.Largest[0].Size != 5000 {
+		t.Errorf("expected the largest file first, got %+v", d.Largest)
+	}
+	if stats := s.GetStats(); stats.FilesScanned != 0 {
+		t.Errorf("expected no files to be scanned, got %d", stats.FilesScanned)
+	}
+
+	estimate, err := s.EstimateScan(context.Background(), d, DefaultCalibrationBytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if estimate.SampleFiles != 3 || estimate.SampleBytes != d.Bytes || estimate.BytesPerSecond <= 0 || estimate.Duration <= 0 {
+		t.Errorf("expected an estimate from every file, got %+v", estimate)
+	}
+
+	// Calibration reads no more than it was allowed
+	estimate, err = s.EstimateScan(context.Background(), d, 150)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if estimate.SampleBytes == 0 || estimate.SampleBytes > 150 {
+		t.Errorf("expected at most 150 bytes sampled, got %+v", estimate)
+	}
+}
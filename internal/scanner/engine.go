@@ -0,0 +1,54 @@
+// Package scanner provides the choice of regular expression engine
+package scanner
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Regular expression engines signatures can be matched with
+const (
+	// RegexEngineAuto gates signatures without common strings in sets
+	// with Go's linear-time RE2 engine, running regexp2 only when their
+	// set can match, so backtracking signatures are skipped on files they
+	// can't match instead of running to their timeout.
+	RegexEngineAuto = "auto"
+
+	// RegexEngineRegexp2 matches every signature with regexp2 alone. It
+	// skips compiling the sets, which takes the least memory and startup
+	// time on small hosts, but pathological files reach match timeouts
+	// more often. Which is faster per file depends on the signatures and
+	// content; BenchmarkRegexEngines compares them.
+	RegexEngineRegexp2 = "regexp2"
+
+	// RegexEngineRE2 gates as RegexEngineAuto does, but with the native
+	// RE2 library linked with cgo instead of Go's engine. Matches are
+	// still confirmed by regexp2, so results are the same. It is only
+	// available in builds with -tags re2_cgo.
+	RegexEngineRE2 = "re2"
+)
+
+// compileNativeGate compiles a combined pattern with the native RE2
+// library, in builds that link it
+var compileNativeGate func(pattern string) (gate, error)
+
+// RegexEngines returns the engines available in this build
+func RegexEngines() []string {
+	engines := []string{RegexEngineAuto, RegexEngineRegexp2}
+	if compileNativeGate != nil {
+		engines = append(engines, RegexEngineRE2)
+	}
+	return engines
+}
+
+// ValidateRegexEngine checks that an engine is available in this build
+func ValidateRegexEngine(engine string) error {
+	if engine == RegexEngineRE2 && compileNativeGate == nil {
+		return fmt.Errorf("regex engine %s needs a build with -tags re2_cgo and libre2", engine)
+	}
+	if !slices.Contains(RegexEngines(), engine) {
+		return fmt.Errorf("unknown regex engine %q (available: %s)", engine, strings.Join(RegexEngines(), ", "))
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build re2_cgo && cgo
+
+// Package scanner provides the native RE2 engine, in builds linking libre2
+package scanner
+
+import "github.com/greysquirr3l/wordfence-go/internal/re2"
+
+func init() {
+	compileNativeGate = func(pattern string) (gate, error) {
+		re, err := re2.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re, nil
+	}
+}
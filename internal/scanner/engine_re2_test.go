@@ -0,0 +1,76 @@
+//go:build re2_cgo && cgo
+
+package scanner
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/re2"
+)
+
+// TestNativeGatesAgree checks that the native library matches each gated
+// signature's pattern exactly as Go's engine does on the corpus
+func TestNativeGatesAgree(t *testing.T) {
+	fixture, _, files := loadCorpusFixture(t)
+	contents := [][]byte{nil, []byte("<?php echo 'hello world';")}
+	for _, file := range files {
+		contents = append(contents, file.Content)
+	}
+
+	for _, s := range fixture.Signatures {
+		if !s.Gated {
+			continue
+		}
+		pattern, err := gatePattern(s.Rule)
+		if err != nil {
+			t.Fatalf("signature %d: %v", s.ID, err)
+		}
+		pattern = "(?ms)" + pattern
+		native, err := compileNativeGate(pattern)
+		if err != nil {
+			t.Fatalf("signature %d: native compile failed: %v", s.ID, err)
+		}
+		want := regexp.MustCompile(pattern)
+		for i, content := range contents {
+			if got := native.Match(content); got != want.Match(content) {
+				t.Errorf("signature %d on content %d: native %v, Go %v", s.ID, i, got, !got)
+			}
+		}
+	}
+}
+
+func TestRegexEngineRE2(t *testing.T) {
+	fixture, ss, files := loadCorpusFixture(t)
+	m := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(RegexEngineRE2))
+	reference := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(RegexEngineRegexp2))
+
+	if len(m.sets) == 0 {
+		t.Fatal("expected signatures to be gated in sets")
+	}
+	for _, set := range m.sets {
+		if _, ok := set.re.(*re2.Regexp); !ok {
+			t.Errorf("set %s: expected a native gate, got %T", set.category, set.re)
+		}
+	}
+
+	for _, file := range files {
+		name, err := filepath.Rel(corpusDir, file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name = filepath.ToSlash(name)
+		got := matchedIDs(t, m, string(file.Content))
+		if want := matchedIDs(t, reference, string(file.Content)); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got matches %v, regexp2 matched %v", name, got, want)
+		}
+		if want := fixture.Matches[name]; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got matches %v, want %v", name, got, want)
+		}
+	}
+	if got := matchedIDs(t, m, ""); len(got) != 0 {
+		t.Errorf("expected no matches on empty content, got %v", got)
+	}
+}
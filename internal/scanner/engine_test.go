@@ -0,0 +1,147 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+// engineTestPatterns cover the constructs signatures use: classes,
+// shorthands, alternation, anchors, lazy repetition and case-insensitivity
+var engineTestPatterns = []string{
+	`eval\s*\(\s*base64_decode`,
+	`(?i)assert\s*\(\s*\$_(?:GET|POST|REQUEST)`,
+	`^<\?php\s+@?error_reporting\(0\)`,
+	`\$[a-z_]+\s*=\s*str_rot13\(`,
+	`preg_replace\s*\(\s*['"]/.+?/e['"]`,
+	`(?i)FilesMan`,
+	`\\x[0-9a-f]{2}\\x[0-9a-f]{2}\\x[0-9a-f]{2}`,
+	`gzinflate\(\s*str_rev\(`,
+	`(?:shell_exec|passthru|proc_open)\s*\(\s*\$`,
+	`\bmove_uploaded_file\b.+?\$_FILES`,
+}
+
+var engineTestContents = []string{
+	"<?php @error_reporting(0); eval(base64_decode('ZWNobyAx'));",
+	"<?php ASSERT ( $_POST['x'] );",
+	"<?php $a = str_rot13('riny'); echo 'FilesMan';",
+	"<?php preg_replace('/.*/e', $_GET['c'], '');",
+	"<?php echo \"\\x65\\x76\\x61\\x6c\";",
+	"<?php passthru( $cmd ); move_uploaded_file($tmp, $_FILES['f']['name']);",
+	"<?php echo 'hello world';",
+	"<?php // İstanbul gzinflate( str_rev($x));",
+}
+
+// newEngineTestSignatureSet returns signatures without common strings, so
+// every one is tried on every file and gated when the engine gates
+func newEngineTestSignatureSet(copies int) *intel.SignatureSet {
+	ss := intel.NewSignatureSet()
+	id := 1
+	for c := 0; c < copies; c++ {
+		for _, pattern := range engineTestPatterns {
+			if c > 0 {
+				// Distinct patterns that never match, as most signatures don't
+				pattern = fmt.Sprintf("%s(?:marker%d)", pattern, c)
+			}
+			ss.Signatures[id] = intel.NewSignature(id, pattern, fmt.Sprintf("Signature %d", id), "", nil)
+			ss.Signatures[id].Category = fmt.Sprintf("category%d", id%4)
+			id++
+		}
+	}
+	return ss
+}
+
+func matchedIDs(t testing.TB, m *Matcher, content string) []int {
+	t.Helper()
+	mc := m.NewMatchContext()
+	if err := mc.Match(context.Background(), []byte(content)); err != nil {
+		t.Fatalf("match failed: %v", err)
+	}
+	var ids []int
+	for _, match := range mc.GetMatches() {
+		ids = append(ids, match.SignatureID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestRegexEnginesAgree(t *testing.T) {
+	ss := newEngineTestSignatureSet(3)
+	reference := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(RegexEngineRegexp2))
+
+	for _, engine := range RegexEngines() {
+		t.Run(engine, func(t *testing.T) {
+			m := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(engine))
+			for _, content := range engineTestContents {
+				want := matchedIDs(t, reference, content)
+				got := matchedIDs(t, m, content)
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("%q: got matches %v, want %v", content, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRegexEngineRegexp2SkipsSets(t *testing.T) {
+	ss := newEngineTestSignatureSet(1)
+	if m := NewMatcher(ss, WithRegexEngine(RegexEngineAuto)); len(m.sets) == 0 {
+		t.Error("expected the auto engine to gate signatures in sets")
+	}
+	m := NewMatcher(ss, WithRegexEngine(RegexEngineRegexp2))
+	if len(m.sets) != 0 || len(m.ungrouped) != len(engineTestPatterns) {
+		t.Errorf("expected regexp2 to match every signature alone, got %d sets and %d ungrouped", len(m.sets), len(m.ungrouped))
+	}
+}
+
+func TestValidateRegexEngine(t *testing.T) {
+	for _, engine := range RegexEngines() {
+		if err := ValidateRegexEngine(engine); err != nil {
+			t.Errorf("expected %s to be available: %v", engine, err)
+		}
+	}
+	if err := ValidateRegexEngine("pcre"); err == nil {
+		t.Error("expected an unknown engine to fail")
+	}
+}
+
+// BenchmarkRegexEngines compares the engines on a clean file, the common
+// case, against a few hundred signatures. Run it with
+//
+//	go test -run '^$' -bench RegexEngines -benchmem ./internal/scanner
+func BenchmarkRegexEngines(b *testing.B) {
+	ss := newEngineTestSignatureSet(30)
+	content := []byte(strings.Repeat("<?php echo htmlspecialchars($title); ?>\n<div class=\"entry\">Lorem ipsum dolor sit amet.</div>\n", 200))
+
+	for _, engine := range RegexEngines() {
+		b.Run(engine, func(b *testing.B) {
+			m := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(engine))
+			b.SetBytes(int64(len(content)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mc := m.NewMatchContext()
+				if err := mc.Match(context.Background(), content); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRegexEngineCompile compares the time and memory each engine
+// takes to prepare the signatures
+func BenchmarkRegexEngineCompile(b *testing.B) {
+	ss := newEngineTestSignatureSet(30)
+	for _, engine := range RegexEngines() {
+		b.Run(engine, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				NewMatcher(ss, WithRegexEngine(engine))
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package scanner provides progress events for user interfaces following
+// a scan
+package scanner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressEventType is the kind of a progress event
+type ProgressEventType string
+
+// Progress event types
+const (
+	ProgressScanStarted  ProgressEventType = "scan_started"
+	ProgressStage        ProgressEventType = "stage"
+	ProgressFileStarted  ProgressEventType = "file_started"
+	ProgressFileFinished ProgressEventType = "file_finished"
+	ProgressThrottle     ProgressEventType = "throttle"
+	ProgressError        ProgressEventType = "error"
+	ProgressScanFinished ProgressEventType = "scan_finished"
+)
+
+// StageRetry is the stage of a scan retrying the files that failed for a
+// while, after the others are scanned
+const StageRetry = "retry"
+
+// DefaultProgressBuffer is how many events a subscriber can fall behind by
+const DefaultProgressBuffer = 1024
+
+// ProgressEvent is something that happened during a scan. Stage events
+// mark StageWalk, StageScan and StageRetry starting, and ending with Done
+// set; the walk and the scan of the files found run alongside each other.
+type ProgressEvent struct {
+	Type  ProgressEventType `json:"type"`
+	Time  time.Time         `json:"time"`
+	Stage string            `json:"stage,omitempty"`
+	Done  bool              `json:"done,omitempty"`
+	Path  string            `json:"path,omitempty"`
+	// Result is the result of a finished file
+	Result *ScanResult `json:"-"`
+	// Throttle is how an adaptive scan is throttled after a change
+	Throttle *ThrottleState `json:"throttle,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	// Stats are the statistics of a finished scan
+	Stats *ScanStats `json:"stats,omitempty"`
+}
+
+// progressEvents hands the events of a scan to its subscribers. A
+// subscriber that falls behind misses file events rather than slowing the
+// scan, and has its oldest events pushed out by the others.
+type progressEvents struct {
+	mu      sync.Mutex
+	subs    []chan ProgressEvent
+	active  atomic.Bool
+	dropped atomic.Int64
+}
+
+// subscribe returns a channel of the events of the scan running, or else
+// of the next one, that is closed once it ends
+func (e *progressEvents) subscribe() <-chan ProgressEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	sub := make(chan ProgressEvent, DefaultProgressBuffer)
+	e.subs = append(e.subs, sub)
+	e.active.Store(true)
+	return sub
+}
+
+// emit hands event to the subscribers
+func (e *progressEvents) emit(event ProgressEvent) {
+	if !e.active.Load() {
+		return
+	}
+	event.Time = time.Now()
+	droppable := event.Type == ProgressFileStarted || event.Type == ProgressFileFinished
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, sub := range e.subs {
+		for sent := false; !sent; {
+			select {
+			case sub <- event:
+				sent = true
+				continue
+			default:
+			}
+			e.dropped.Add(1)
+			if droppable {
+				break
+			}
+			select {
+			case <-sub:
+			default:
+			}
+		}
+	}
+}
+
+// finish closes the channels of the subscribers of the scan ended
+func (e *progressEvents) finish() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, sub := range e.subs {
+		close(sub)
+	}
+	e.subs = nil
+	e.active.Store(false)
+}
+
+// Subscribe returns a channel of the progress events of the scan running,
+// or else of the next scan, closed once that scan ends. Subscribe again
+// for each scan. Receive promptly: file events are dropped when more than
+// DefaultProgressBuffer are waiting, and the oldest events make way for
+// stage, throttle and error events.
+func (s *Scanner) Subscribe() <-chan ProgressEvent {
+	return s.events.subscribe()
+}
+
+// DroppedEvents returns how many progress events subscribers missed for
+// falling behind
+func (s *Scanner) DroppedEvents() int64 {
+	return s.events.dropped.Load()
+}
@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 5 {
+		content := "<?php echo 'hello';"
+		if i == 0 {
+			content = "<?php eval($_POST['x']);"
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.php", i)), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	s := NewScanner(createTestSignatureSet())
+	events := s.Subscribe()
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range results {
+	}
+
+	counts := make(map[ProgressEventType]int)
+	var stages []string
+	var last ProgressEvent
+	for event := range events {
+		counts[event.Type]++
+		if event.Type == ProgressStage {
+			stages = append(stages, fmt.Sprintf("%s:%v", event.Stage, event.Done))
+		}
+		if event.Type == ProgressFileFinished && event.Result == nil {
+			t.Errorf("expected the result of %s", event.Path)
+		}
+		last = event
+	}
+	if counts[ProgressScanStarted] != 1 || counts[ProgressFileStarted] != 5 || counts[ProgressFileFinished] != 5 || counts[ProgressError] != 0 {
+		t.Errorf("unexpected events %v", counts)
+	}
+	if len(stages) != 4 || stages[0] != "walk:false" || stages[1] != "scan:false" || stages[3] != "scan:true" {
+		t.Errorf("unexpected stages %v", stages)
+	}
+	if last.Type != ProgressScanFinished || last.Stats == nil || last.Stats.FilesScanned != 5 || last.Stats.FilesMatched != 1 {
+		t.Errorf("expected the scan to finish with its statistics, got %+v", last)
+	}
+}
+
+func TestProgressEventsFallingBehind(t *testing.T) {
+	var e progressEvents
+	sub := e.subscribe()
+	for range DefaultProgressBuffer + 10 {
+		e.emit(ProgressEvent{Type: ProgressFileStarted})
+	}
+	e.emit(ProgressEvent{Type: ProgressScanFinished})
+	e.finish()
+
+	var received int
+	var last ProgressEvent
+	for event := range sub {
+		received++
+		last = event
+	}
+	if received != DefaultProgressBuffer || last.Type != ProgressScanFinished {
+		t.Errorf("expected a full buffer ending with the scan finishing, got %d ending with %s", received, last.Type)
+	}
+	if dropped := e.dropped.Load(); dropped != 11 {
+		t.Errorf("expected 11 events dropped, got %d", dropped)
+	}
+
+	// Without subscribers nothing is held
+	e.emit(ProgressEvent{Type: ProgressFileStarted})
+	if dropped := e.dropped.Load(); dropped != 11 {
+		t.Errorf("expected no events held without subscribers, got %d dropped", dropped)
+	}
+}
@@ -3,6 +3,7 @@ package scanner
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -16,7 +17,8 @@ type FilterCondition struct {
 
 // FileFilter filters files based on conditions
 type FileFilter struct {
-	conditions []*FilterCondition
+	conditions    []*FilterCondition
+	dirConditions []func(path string) bool
 }
 
 // NewFileFilter creates a new FileFilter
@@ -49,6 +51,21 @@ func (f *FileFilter) Deny(test func(path string) bool) {
 	f.Add(test, false)
 }
 
+// DenyDir adds a directory condition; matching directories are not traversed
+func (f *FileFilter) DenyDir(test func(path string) bool) {
+	f.dirConditions = append(f.dirConditions, test)
+}
+
+// FilterDir returns true if the directory should be traversed
+func (f *FileFilter) FilterDir(path string) bool {
+	for _, test := range f.dirConditions {
+		if test(path) {
+			return false
+		}
+	}
+	return true
+}
+
 // Filter returns true if the path should be included (not filtered out)
 func (f *FileFilter) Filter(path string) bool {
 	allowed := false
@@ -71,6 +88,17 @@ func (f *FileFilter) Filter(path string) bool {
 	return allowed
 }
 
+// Excluded returns true if a deny condition matches the path, so it is
+// left out whatever its type
+func (f *FileFilter) Excluded(path string) bool {
+	for _, cond := range f.conditions {
+		if !cond.Allow && cond.Test(path) {
+			return true
+		}
+	}
+	return false
+}
+
 // Default file extension patterns (case-insensitive)
 var (
 	PatternPHP    = regexp.MustCompile(`(?i)\.(?:php(?:\d+)?|phtml)(\.|$)`)
@@ -111,6 +139,21 @@ func FilterFilename(filename string) func(string) bool {
 	}
 }
 
+// FilterUnderDirname creates a filter that matches paths below a directory
+// with the given name at any depth
+func FilterUnderDirname(name string) func(string) bool {
+	return func(path string) bool {
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			if filepath.Base(dir) == name {
+				return true
+			}
+			if parent := filepath.Dir(dir); parent == dir {
+				return false
+			}
+		}
+	}
+}
+
 // FilterPattern creates a filter from a regex pattern
 func FilterPattern(pattern string) (func(string) bool, error) {
 	re, err := regexp.Compile(pattern)
@@ -122,6 +165,58 @@ func FilterPattern(pattern string) (func(string) bool, error) {
 	}, nil
 }
 
+// FilterGlob creates a filter from a shell glob. Globs without a slash match
+// the file name; globs with a slash match the trailing path components.
+// "*" and "?" do not cross directories, "**" does.
+func FilterGlob(pattern string) (func(string) bool, error) {
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(path string) bool {
+		return re.MatchString(filepath.ToSlash(path))
+	}, nil
+}
+
+// FilterGlobDir creates a directory filter for globs that exclude a whole
+// subtree ("dir/**" or "dir/"). It returns nil for other globs, which can
+// only be decided per file.
+func FilterGlobDir(pattern string) (func(string) bool, error) {
+	var dirPattern string
+	switch {
+	case strings.HasSuffix(pattern, "/**"):
+		dirPattern = strings.TrimSuffix(pattern, "/**")
+	case strings.HasSuffix(pattern, "/"):
+		dirPattern = strings.TrimRight(pattern, "/")
+	default:
+		return nil, nil //nolint:nilnil // not a subtree glob
+	}
+	if dirPattern == "" || dirPattern == "**" {
+		return nil, nil //nolint:nilnil // would prune every directory
+	}
+	return FilterGlob(dirPattern)
+}
+
+// compileGlob compiles a glob into a regex matched against slash-separated paths
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	glob := filepath.ToSlash(pattern)
+	// "dir/" means everything under dir
+	if strings.HasSuffix(glob, "/") {
+		glob += "**"
+	}
+
+	prefix := "(?:^|/)"
+	if strings.HasPrefix(glob, "/") {
+		prefix = "^"
+	}
+
+	re, err := regexp.Compile(prefix + globToRegexp(strings.TrimPrefix(glob, "/")) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("compiling glob %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
 // FilterExtension creates a filter for a specific file extension
 func FilterExtension(ext string) func(string) bool {
 	if !strings.HasPrefix(ext, ".") {
@@ -170,9 +265,15 @@ func AllFilesFilter() *FileFilter {
 type FilterConfig struct {
 	IncludeFiles    []string // Specific filenames to include
 	IncludePatterns []string // Regex patterns to include
+	IncludeGlobs    []string // Shell globs to include
 	ExcludeFiles    []string // Specific filenames to exclude
 	ExcludePatterns []string // Regex patterns to exclude
+	ExcludeGlobs    []string // Shell globs to exclude; "dir/**" prunes the subtree
+	ExcludeDirs     []string // Directory names whose subtrees are never walked
+	ExcludeFrom     []string // Files of gitignore-style patterns applied at each root
+	IgnoreRoots     []string // Scan roots checked for an IgnoreFileName file
 	IncludeAll      bool     // Include all files
+	IncludeImages   bool     // Include images, media, archives and logs (FilterImages)
 }
 
 // NewFilterFromConfig creates a filter from a configuration
@@ -187,6 +288,9 @@ func NewFilterFromConfig(cfg *FilterConfig) (*FileFilter, error) {
 		f.Allow(FilterPHP)
 		f.Allow(FilterHTML)
 		f.Allow(FilterJS)
+		if cfg.IncludeImages {
+			f.Allow(FilterImages)
+		}
 
 		// Additional include files
 		for _, filename := range cfg.IncludeFiles {
@@ -201,6 +305,15 @@ func NewFilterFromConfig(cfg *FilterConfig) (*FileFilter, error) {
 			}
 			f.Allow(fn)
 		}
+
+		// Additional include globs
+		for _, pattern := range cfg.IncludeGlobs {
+			fn, err := FilterGlob(pattern)
+			if err != nil {
+				return nil, err
+			}
+			f.Allow(fn)
+		}
 	}
 
 	// Exclude files
@@ -217,5 +330,79 @@ func NewFilterFromConfig(cfg *FilterConfig) (*FileFilter, error) {
 		f.Deny(fn)
 	}
 
+	// Exclude globs
+	for _, pattern := range cfg.ExcludeGlobs {
+		fn, err := FilterGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.Deny(fn)
+
+		dirFn, err := FilterGlobDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if dirFn != nil {
+			f.DenyDir(dirFn)
+		}
+	}
+
+	// Excluded directory names
+	for _, name := range cfg.ExcludeDirs {
+		test := FilterFilename(name)
+		f.DenyDir(test)
+		f.Deny(FilterUnderDirname(name))
+	}
+
+	// Gitignore-style exclusion files
+	rules, err := loadIgnoreRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		f.Deny(r.Filter())
+		f.DenyDir(r.DirFilter())
+	}
+
 	return f, nil
 }
+
+// loadIgnoreRules loads per-root ignore files and the ExcludeFrom files
+func loadIgnoreRules(cfg *FilterConfig) ([]*IgnoreRules, error) {
+	var rules []*IgnoreRules
+
+	var roots []string
+	for _, root := range cfg.IgnoreRoots {
+		info, err := os.Stat(root)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		roots = append(roots, root)
+
+		ignoreFile := filepath.Join(root, IgnoreFileName)
+		if _, err := os.Stat(ignoreFile); err != nil {
+			continue
+		}
+		r, err := LoadIgnoreFile(ignoreFile, root)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	// ExcludeFrom patterns apply relative to every root, or the working directory
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	for _, path := range cfg.ExcludeFrom {
+		for _, root := range roots {
+			r, err := LoadIgnoreFile(path, root)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
@@ -85,6 +85,34 @@ func TestFilterConfigIncludeFiles(t *testing.T) {
 	}
 }
 
+func TestFilterConfigIncludeImages(t *testing.T) {
+	for _, images := range []bool{false, true} {
+		filter, err := NewFilterFromConfig(&FilterConfig{
+			IncludeImages: images,
+			ExcludeFiles:  []string{"skip.png"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for path, want := range map[string]bool{
+			"photo.jpg":    images,
+			"backup.sql":   images,
+			"archive.zip":  images,
+			"skip.png":     false,
+			"index.php":    true,
+			"notes.txt":    false,
+			"photo.jpg.gz": images,
+		} {
+			if got := filter.Filter(path); got != want {
+				t.Errorf("images %v: Filter(%q) = %v, want %v", images, path, got, want)
+			}
+		}
+		if !filter.Excluded("skip.png") || filter.Excluded("notes.txt") {
+			t.Error("expected only skip.png to be excluded")
+		}
+	}
+}
+
 func TestFilterConfigIncludePatterns(t *testing.T) {
 	cfg := &FilterConfig{
 		IncludePatterns: []string{`\.config$`, `^important_`},
@@ -260,3 +288,55 @@ func TestFileFilterAllowAndDeny(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterConfigGlobs(t *testing.T) {
+	cfg := &FilterConfig{
+		IncludeGlobs: []string{"*.inc"},
+		ExcludeGlobs: []string{"wp-content/cache/**", "*.min.js"},
+	}
+
+	filter, err := NewFilterFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/var/www/index.php", true},
+		{"/var/www/lib/config.inc", true},
+		{"/var/www/readme.txt", false},
+		{"/var/www/js/app.js", true},
+		{"/var/www/js/app.min.js", false},
+		{"/var/www/wp-content/cache/page.php", false},
+		{"/var/www/wp-content/cache/a/b/page.php", false},
+		{"/var/www/wp-content/plugins/cache/page.php", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := filter.Filter(tt.path); got != tt.expected {
+				t.Errorf("Filter(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	dirTests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/var/www/wp-content", true},
+		{"/var/www/wp-content/cache", false},
+		{"/var/www/wp-content/plugins", true},
+		{"/var/www/js", true},
+	}
+
+	for _, tt := range dirTests {
+		t.Run("dir:"+tt.path, func(t *testing.T) {
+			if got := filter.FilterDir(tt.path); got != tt.expected {
+				t.Errorf("FilterDir(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
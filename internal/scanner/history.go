@@ -0,0 +1,353 @@
+// Package scanner provides a local history of completed scans
+package scanner
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of scan recorded in the history
+const (
+	ScanKindMalware = "malware"
+	ScanKindVuln    = "vuln"
+)
+
+// ErrScanNotFound is returned when no recorded scan matches an ID
+var ErrScanNotFound = errors.New("scan not found")
+
+// InfectedFile is a file with malware matches in a recorded scan
+type InfectedFile struct {
+	Path       string   `json:"path"`
+	Signatures []string `json:"signatures"`
+}
+
+// VulnerableComponent is an installed core, plugin or theme with known
+// vulnerabilities in a recorded scan
+type VulnerableComponent struct {
+	Type            string   `json:"type"`
+	Slug            string   `json:"slug"`
+	Version         string   `json:"version"`
+	Path            string   `json:"path"`
+	Vulnerabilities []string `json:"vulnerabilities"`
+}
+
+// key identifies the component across scans
+func (c *VulnerableComponent) key() string {
+	return c.Type + ":" + c.Slug + ":" + c.Path
+}
+
+// ScanRecord is the summary of a completed scan kept in the history
+type ScanRecord struct {
+	ID              string                 `json:"id"`
+	Kind            string                 `json:"kind"`
+	Started         time.Time              `json:"started"`
+	Finished        time.Time              `json:"finished"`
+	Paths           []string               `json:"paths"`
+	FilesScanned    int64                  `json:"files_scanned,omitempty"`
+	FilesMatched    int64                  `json:"files_matched,omitempty"`
+	Matches         int                    `json:"matches,omitempty"`
+	Vulnerabilities int                    `json:"vulnerabilities,omitempty"`
+	Digest          string                 `json:"digest"`
+	Nonce           string                 `json:"nonce,omitempty"`
+	Infected        []*InfectedFile        `json:"infected,omitempty"`
+	Vulnerable      []*VulnerableComponent `json:"vulnerable,omitempty"`
+}
+
+// NewScanNonce returns the random part of a new scan ID
+func NewScanNonce() string {
+	nonce := make([]byte, 3)
+	_, _ = rand.Read(nonce)
+	return hex.EncodeToString(nonce)
+}
+
+// GenerateScanID builds a scan ID from the scan's start time and a nonce,
+// so IDs sort by start time and scans started together don't collide
+func GenerateScanID(started time.Time, nonce string) string {
+	return started.UTC().Format("20060102-150405") + "-" + nonce
+}
+
+// NewScanRecord starts a record for a scan of kind over paths
+func NewScanRecord(kind string, paths []string, started time.Time) *ScanRecord {
+	nonce := NewScanNonce()
+
+	absPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		absPaths = append(absPaths, path)
+	}
+
+	return &ScanRecord{
+		ID:      GenerateScanID(started, nonce),
+		Kind:    kind,
+		Started: started,
+		Paths:   absPaths,
+		Nonce:   nonce,
+	}
+}
+
+// AddScanResult records the matches of a scanned file
+func (r *ScanRecord) AddScanResult(result *ScanResult, names []string) {
+	if !result.HasMatches() {
+		return
+	}
+	path := result.Path
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	r.FilesMatched++
+	r.Matches += len(result.Matches)
+	r.Infected = append(r.Infected, &InfectedFile{Path: path, Signatures: names})
+}
+
+// AddVulnMatches records vulnerable components, grouping vulnerabilities
+// of the same installed component
+func (r *ScanRecord) AddVulnMatches(matches []*VulnMatch) {
+	index := make(map[string]*VulnerableComponent, len(r.Vulnerable))
+	for _, c := range r.Vulnerable {
+		index[c.key()] = c
+	}
+	for _, m := range matches {
+		c := &VulnerableComponent{Type: string(m.SoftwareType), Slug: m.Slug, Version: m.Version, Path: m.Path}
+		if existing, ok := index[c.key()]; ok {
+			c = existing
+		} else {
+			index[c.key()] = c
+			r.Vulnerable = append(r.Vulnerable, c)
+		}
+		c.Vulnerabilities = append(c.Vulnerabilities, m.Vulnerability.ID)
+		r.Vulnerabilities++
+	}
+}
+
+// Finish marks the record complete, sorting its findings and computing
+// their digest. Scans with the same digest found exactly the same things.
+func (r *ScanRecord) Finish(finished time.Time) {
+	r.Finished = finished
+
+	sort.Slice(r.Infected, func(i, j int) bool { return r.Infected[i].Path < r.Infected[j].Path })
+	sort.Slice(r.Vulnerable, func(i, j int) bool { return r.Vulnerable[i].key() < r.Vulnerable[j].key() })
+
+	h := sha256.New()
+	for _, f := range r.Infected {
+		sigs := append([]string(nil), f.Signatures...)
+		sort.Strings(sigs)
+		_, _ = fmt.Fprintf(h, "file\x00%s\x00%s\n", f.Path, strings.Join(sigs, "\x00"))
+	}
+	for _, c := range r.Vulnerable {
+		vulns := append([]string(nil), c.Vulnerabilities...)
+		sort.Strings(vulns)
+		_, _ = fmt.Fprintf(h, "component\x00%s\x00%s\x00%s\n", c.key(), c.Version, strings.Join(vulns, "\x00"))
+	}
+	r.Digest = hex.EncodeToString(h.Sum(nil))
+}
+
+// HistoryStore is a directory of scan records, one JSON file per scan
+type HistoryStore struct {
+	dir string
+}
+
+// OpenHistory opens the history store in dir. The directory is created
+// when the first record is saved.
+func OpenHistory(dir string) *HistoryStore {
+	return &HistoryStore{dir: dir}
+}
+
+// Dir returns the store's directory
+func (s *HistoryStore) Dir() string {
+	return s.dir
+}
+
+// Save writes a record to the store
+func (s *HistoryStore) Save(record *ScanRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding scan record: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".scan-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing scan record: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing scan record: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, record.ID+".json")); err != nil {
+		return fmt.Errorf("saving scan record: %w", err)
+	}
+	return nil
+}
+
+// List returns every recorded scan, oldest first
+func (s *HistoryStore) List() ([]*ScanRecord, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	var records []*ScanRecord
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		record, err := s.read(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Started.Before(records[j].Started)
+	})
+	return records, nil
+}
+
+// Load returns the scan with the given ID or unique ID prefix. "latest"
+// and "previous" name the most recent scan and the one before it.
+func (s *HistoryStore) Load(id string) (*ScanRecord, error) {
+	records, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	switch id {
+	case "latest":
+		if len(records) > 0 {
+			return records[len(records)-1], nil
+		}
+	case "previous":
+		if len(records) > 1 {
+			return records[len(records)-2], nil
+		}
+	default:
+		var found *ScanRecord
+		for _, record := range records {
+			if record.ID == id {
+				return record, nil
+			}
+			if strings.HasPrefix(record.ID, id) {
+				if found != nil {
+					return nil, fmt.Errorf("scan ID %s is ambiguous", id)
+				}
+				found = record
+			}
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", id, ErrScanNotFound)
+}
+
+// read decodes a record file
+func (s *HistoryStore) read(path string) (*ScanRecord, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- file in the history directory
+	if err != nil {
+		return nil, fmt.Errorf("reading scan record: %w", err)
+	}
+	var record ScanRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parsing scan record %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// HistoryDiff is what changed between two recorded scans
+type HistoryDiff struct {
+	From            *ScanRecord            `json:"-"`
+	To              *ScanRecord            `json:"-"`
+	NewlyInfected   []*InfectedFile        `json:"newly_infected"`
+	Remediated      []*InfectedFile        `json:"remediated"`
+	NewlyVulnerable []*VulnerableComponent `json:"newly_vulnerable"`
+	Fixed           []*VulnerableComponent `json:"fixed"`
+}
+
+// Unchanged reports whether both scans found the same things
+func (d *HistoryDiff) Unchanged() bool {
+	return len(d.NewlyInfected) == 0 && len(d.Remediated) == 0 &&
+		len(d.NewlyVulnerable) == 0 && len(d.Fixed) == 0
+}
+
+// DiffScans compares an earlier scan with a later one. A component is newly
+// vulnerable when the later scan reports a vulnerability the earlier did not.
+func DiffScans(from, to *ScanRecord) (*HistoryDiff, error) {
+	if from.Kind != to.Kind {
+		return nil, fmt.Errorf("cannot compare a %s scan with a %s scan", from.Kind, to.Kind)
+	}
+
+	diff := &HistoryDiff{From: from, To: to}
+
+	fromFiles := make(map[string]bool, len(from.Infected))
+	for _, f := range from.Infected {
+		fromFiles[f.Path] = true
+	}
+	toFiles := make(map[string]bool, len(to.Infected))
+	for _, f := range to.Infected {
+		toFiles[f.Path] = true
+		if !fromFiles[f.Path] {
+			diff.NewlyInfected = append(diff.NewlyInfected, f)
+		}
+	}
+	for _, f := range from.Infected {
+		if !toFiles[f.Path] {
+			diff.Remediated = append(diff.Remediated, f)
+		}
+	}
+
+	fromComponents := make(map[string]*VulnerableComponent, len(from.Vulnerable))
+	for _, c := range from.Vulnerable {
+		fromComponents[c.key()] = c
+	}
+	toComponents := make(map[string]bool, len(to.Vulnerable))
+	for _, c := range to.Vulnerable {
+		toComponents[c.key()] = true
+		if prev, ok := fromComponents[c.key()]; !ok || hasNewVulnerability(prev, c) {
+			diff.NewlyVulnerable = append(diff.NewlyVulnerable, c)
+		}
+	}
+	for _, c := range from.Vulnerable {
+		if !toComponents[c.key()] {
+			diff.Fixed = append(diff.Fixed, c)
+		}
+	}
+
+	return diff, nil
+}
+
+// hasNewVulnerability reports whether to lists a vulnerability from lacks
+func hasNewVulnerability(from, to *VulnerableComponent) bool {
+	known := make(map[string]bool, len(from.Vulnerabilities))
+	for _, id := range from.Vulnerabilities {
+		known[id] = true
+	}
+	for _, id := range to.Vulnerabilities {
+		if !known[id] {
+			return true
+		}
+	}
+	return false
+}
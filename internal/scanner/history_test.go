@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+func TestHistoryStoreRoundTrip(t *testing.T) {
+	store := OpenHistory(t.TempDir())
+
+	if records, err := store.List(); err != nil || len(records) != 0 {
+		t.Fatalf("expected empty history, got %v, %v", records, err)
+	}
+
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	first := NewScanRecord(ScanKindMalware, []string{"/var/www"}, start)
+	first.AddScanResult(&ScanResult{Path: "/var/www/a.php", Matches: []*MatchResult{{SignatureID: 1}}}, []string{"Eval Pattern"})
+	first.AddScanResult(&ScanResult{Path: "/var/www/clean.php"}, nil)
+	first.Finish(start.Add(time.Minute))
+
+	second := NewScanRecord(ScanKindMalware, []string{"/var/www"}, start.Add(time.Hour))
+	second.Finish(start.Add(time.Hour + time.Minute))
+
+	for _, record := range []*ScanRecord{second, first} {
+		if err := store.Save(record); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+
+	records, err := store.List()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != first.ID {
+		t.Fatalf("expected records oldest first, got %+v", records)
+	}
+	if records[0].FilesMatched != 1 || records[0].Digest != first.Digest || records[0].Digest == second.Digest {
+		t.Errorf("unexpected record %+v", records[0])
+	}
+
+	latest, err := store.Load("latest")
+	if err != nil || latest.ID != second.ID {
+		t.Errorf("expected latest to be %s, got %+v, %v", second.ID, latest, err)
+	}
+	previous, err := store.Load("previous")
+	if err != nil || previous.ID != first.ID {
+		t.Errorf("expected previous to be %s, got %+v, %v", first.ID, previous, err)
+	}
+	if _, err := store.Load("20250301-1"); err == nil {
+		t.Error("expected ambiguous prefix to fail")
+	}
+	if _, err := store.Load("nope"); !errors.Is(err, ErrScanNotFound) {
+		t.Errorf("expected ErrScanNotFound, got %v", err)
+	}
+}
+
+func TestDiffScans(t *testing.T) {
+	start := time.Now()
+	vuln := func(id string) *intel.Vulnerability { return &intel.Vulnerability{ID: id} }
+
+	from := NewScanRecord(ScanKindMalware, nil, start)
+	from.AddScanResult(&ScanResult{Path: "/www/a.php", Matches: []*MatchResult{{}}}, []string{"Backdoor"})
+	from.AddScanResult(&ScanResult{Path: "/www/b.php", Matches: []*MatchResult{{}}}, []string{"Backdoor"})
+	from.AddVulnMatches([]*VulnMatch{
+		{Vulnerability: vuln("v1"), SoftwareType: intel.SoftwareTypePlugin, Slug: "forms", Path: "/www/p/forms"},
+		{Vulnerability: vuln("v2"), SoftwareType: intel.SoftwareTypePlugin, Slug: "old", Path: "/www/p/old"},
+	})
+	from.Finish(start)
+
+	to := NewScanRecord(ScanKindMalware, nil, start.Add(time.Hour))
+	to.AddScanResult(&ScanResult{Path: "/www/b.php", Matches: []*MatchResult{{}}}, []string{"Backdoor"})
+	to.AddScanResult(&ScanResult{Path: "/www/c.php", Matches: []*MatchResult{{}}}, []string{"Webshell"})
+	to.AddVulnMatches([]*VulnMatch{
+		{Vulnerability: vuln("v1"), SoftwareType: intel.SoftwareTypePlugin, Slug: "forms", Path: "/www/p/forms"},
+		{Vulnerability: vuln("v3"), SoftwareType: intel.SoftwareTypePlugin, Slug: "forms", Path: "/www/p/forms"},
+	})
+	to.Finish(start.Add(time.Hour))
+
+	diff, err := DiffScans(from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diff.NewlyInfected) != 1 || diff.NewlyInfected[0].Path != "/www/c.php" {
+		t.Errorf("unexpected newly infected: %+v", diff.NewlyInfected)
+	}
+	if len(diff.Remediated) != 1 || diff.Remediated[0].Path != "/www/a.php" {
+		t.Errorf("unexpected remediated: %+v", diff.Remediated)
+	}
+	if len(diff.NewlyVulnerable) != 1 || diff.NewlyVulnerable[0].Slug != "forms" {
+		t.Errorf("unexpected newly vulnerable: %+v", diff.NewlyVulnerable)
+	}
+	if len(diff.Fixed) != 1 || diff.Fixed[0].Slug != "old" {
+		t.Errorf("unexpected fixed: %+v", diff.Fixed)
+	}
+
+	same, err := DiffScans(to, to)
+	if err != nil || !same.Unchanged() {
+		t.Errorf("expected no changes against itself, got %+v, %v", same, err)
+	}
+
+	if _, err := DiffScans(from, NewScanRecord(ScanKindVuln, nil, start)); err == nil {
+		t.Error("expected error comparing different scan kinds")
+	}
+}
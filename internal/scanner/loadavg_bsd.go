@@ -0,0 +1,32 @@
+//go:build darwin || freebsd
+
+// Package scanner provides the load average on macOS and FreeBSD
+package scanner
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/sys/unix"
+)
+
+// loadAverage returns the 1-minute load average from the vm.loadavg
+// sysctl, a struct loadavg of three fixed-point loads and their scale
+func loadAverage() (float64, bool) {
+	raw, err := unix.SysctlRaw("vm.loadavg")
+	if err != nil || len(raw) < 16 {
+		return 0, false
+	}
+	load := binary.NativeEndian.Uint32(raw[0:4])
+
+	// fscale is a long, after padding where longs are 64-bit
+	var scale int64
+	if len(raw) >= 24 {
+		scale = int64(binary.NativeEndian.Uint64(raw[16:24])) // #nosec G115 -- a small power of two
+	} else {
+		scale = int64(int32(binary.NativeEndian.Uint32(raw[12:16]))) // #nosec G115 -- a small power of two
+	}
+	if scale <= 0 {
+		return 0, false
+	}
+	return float64(load) / float64(scale), true
+}
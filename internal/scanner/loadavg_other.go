@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+// Package scanner provides the load average on platforms where it isn't
+// read
+package scanner
+
+// loadAverage reports no load average on platforms where it isn't read
+func loadAverage() (float64, bool) {
+	return 0, false
+}
@@ -0,0 +1,110 @@
+//go:build windows
+
+// Package scanner provides a load average on Windows, from the processor
+// queue length
+package scanner
+
+import (
+	"math"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// queueLengthCounter is the threads ready to run but waiting for a CPU
+	queueLengthCounter = `\System\Processor Queue Length`
+	// pdhFmtDouble asks PDH for a counter's value as a float64
+	pdhFmtDouble = 0x00000200
+	// loadWindow is the period the load average is taken over, as the
+	// 1-minute load average on Unix
+	loadWindow = time.Minute
+)
+
+var (
+	pdh                             = windows.NewLazySystemDLL("pdh.dll")
+	procPdhOpenQueryW               = pdh.NewProc("PdhOpenQueryW")
+	procPdhAddEnglishCounterW       = pdh.NewProc("PdhAddEnglishCounterW")
+	procPdhCollectQueryData         = pdh.NewProc("PdhCollectQueryData")
+	procPdhGetFormattedCounterValue = pdh.NewProc("PdhGetFormattedCounterValue")
+)
+
+// pdhCounterValue is PDH_FMT_COUNTERVALUE holding a double
+type pdhCounterValue struct {
+	CStatus uint32
+	_       uint32
+	Value   float64
+}
+
+// processorQueue averages the processor queue length over time, since
+// Windows has no load average: the threads waiting for a CPU, decayed
+// over a minute like the Unix load average
+type processorQueue struct {
+	once    sync.Once
+	query   uintptr
+	counter uintptr
+	ok      bool
+
+	mu      sync.Mutex
+	load    float64
+	sampled time.Time
+}
+
+var queue processorQueue
+
+// loadAverage returns the processor queue length averaged over the last
+// minute, from the performance counters
+func loadAverage() (float64, bool) {
+	queue.once.Do(queue.open)
+	if !queue.ok {
+		return 0, false
+	}
+	length, ok := queue.length()
+	if !ok {
+		return 0, false
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	now := time.Now()
+	if queue.sampled.IsZero() {
+		queue.load = length
+	} else {
+		decay := math.Exp(-float64(now.Sub(queue.sampled)) / float64(loadWindow))
+		queue.load = queue.load*decay + length*(1-decay)
+	}
+	queue.sampled = now
+	return queue.load, true
+}
+
+// open opens the query of the processor queue length
+func (q *processorQueue) open() {
+	if pdh.Load() != nil {
+		return
+	}
+	if status, _, _ := procPdhOpenQueryW.Call(0, 0, uintptr(unsafe.Pointer(&q.query))); status != 0 { // #nosec G103 -- Win32 call filling in the query handle
+		return
+	}
+	path, err := windows.UTF16PtrFromString(queueLengthCounter)
+	if err != nil {
+		return
+	}
+	if status, _, _ := procPdhAddEnglishCounterW.Call(q.query, uintptr(unsafe.Pointer(path)), 0, uintptr(unsafe.Pointer(&q.counter))); status != 0 { // #nosec G103 -- Win32 call filling in the counter handle
+		return
+	}
+	q.ok = true
+}
+
+// length samples the processor queue length
+func (q *processorQueue) length() (float64, bool) {
+	if status, _, _ := procPdhCollectQueryData.Call(q.query); status != 0 {
+		return 0, false
+	}
+	var value pdhCounterValue
+	if status, _, _ := procPdhGetFormattedCounterValue.Call(q.counter, pdhFmtDouble, 0, uintptr(unsafe.Pointer(&value))); status != 0 { // #nosec G103 -- Win32 call filling in value
+		return 0, false
+	}
+	return value.Value, true
+}
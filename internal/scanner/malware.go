@@ -3,31 +3,67 @@ package scanner
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/greysquirr3l/wordfence-go/internal/intel"
 	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/telemetry"
 )
 
+// ErrSpecialFile indicates a path is not a regular file (FIFO, socket, device)
+var ErrSpecialFile = errors.New("not a regular file")
+
+// StdinPath is the synthetic path reported for content scanned from stdin
+const StdinPath = "<stdin>"
+
 // DefaultChunkSize is the default size for reading file chunks
 const DefaultChunkSize = 1024 * 1024 // 1MB
 
 // DefaultWorkers is the default number of worker goroutines
 const DefaultWorkers = 4
 
+// DefaultMaxPathLength is the longest path the scanner will attempt to open
+const DefaultMaxPathLength = 4096
+
+// DefaultMaxSymlinkDepth bounds how many symlinked directories are followed in a chain
+const DefaultMaxSymlinkDepth = 40
+
+// DefaultFileTimeout bounds the time spent matching signatures against a
+// single file
+const DefaultFileTimeout = time.Minute
+
+// DefaultSlowFileThreshold is how long a file takes to read and match
+// before it is traced in a span of its own
+const DefaultSlowFileThreshold = time.Second
+
+// reservedFileDescriptors are kept free for stdio, API connections and output files
+const reservedFileDescriptors = 32
+
 // ScanResult represents the result of scanning a single file
 type ScanResult struct {
-	Path         string
-	Matches      []*MatchResult
-	Timeouts     []int
+	Path     string
+	Matches  []*MatchResult
+	Timeouts []int
+	// Incomplete is set when matching stopped at the per-file time limit,
+	// so some signatures were not checked
+	Incomplete bool
+	// Truncated is set when matching stopped at the first match, so other
+	// signatures that would match may not be listed
+	Truncated bool
+	// Skipped says why the content wasn't matched, if it wasn't
+	Skipped      string
 	Error        error
 	ScannedBytes int64
 	ScanDuration time.Duration
@@ -42,22 +78,75 @@ func (r *ScanResult) HasMatches() bool {
 type ScanOptions struct {
 	Paths             []string
 	Workers           int
+	ReadWorkers       int
+	MatchWorkers      int
 	ChunkSize         int
 	Filter            *FileFilter
 	ContentLimit      int64
 	AllowIOErrors     bool
 	FollowSymlinks    bool
+	MaxOpenFiles      int
+	MaxPathLength     int
+	MaxSymlinkDepth   int
+	MaxDepth          int
+	MaxFilesPerDir    int
+	Order             string
+	OrderBuffer       int
 	IncludeSignatures []int
 	ExcludeSignatures []int
+	Categories        []string
+	DetectNulled      bool
+	DetectEmbeddedPHP bool
+	Shard             Shard
+	MaxBytesInFlight  int64
+	MatchTimeout      time.Duration
+	HintSlack         int
+	FileTimeout       time.Duration
+	MatchAll          bool
+	RegexEngine       string
+	SkipBinary        bool
+	ScanImagesWithPHP bool
+	MaxDuration       time.Duration
+	Resume            *Checkpoint
+	ErrorBudget       float64
+	MaxRetries        int
+	RetryWait         time.Duration
+	CircuitThreshold  int
+	CircuitCooldown   time.Duration
+	ReadLatencyTarget time.Duration
+	FileDelay         time.Duration
+	BatchSize         int
+	BatchPause        time.Duration
+	ReadRate          int64
+	Adaptive          bool
+	MemoryLimit       int64
+	SlowFileThreshold time.Duration
+	Redact            bool
+	ReadPoolMemory    int64
 }
 
 // ScanStats holds scanning statistics
 type ScanStats struct {
-	FilesScanned  int64
-	FilesMatched  int64
-	FilesSkipped  int64
-	FilesErrored  int64
-	BytesScanned  int64
+	FilesScanned int64
+	FilesMatched int64
+	FilesSkipped int64
+	FilesErrored int64
+	DirsSkipped  int64
+	FilesRetried int64
+	BytesScanned int64
+	// Errors counts the paths not scanned, and other failures, by code
+	Errors map[ScanErrorCode]int64
+	// Circuits has the circuit breaker of each device files were read from
+	Circuits []CircuitState
+	// ReadLatency has the read latency of each device, when reads back off
+	ReadLatency []DeviceLatency
+	// Throttle describes how an adaptive scan was throttled
+	Throttle *ThrottleState
+	// Memory describes how a scan with a memory limit kept under it
+	Memory *MemoryState
+	// ReadPool describes how read buffers were reused, since the scanner
+	// was created
+	ReadPool      *ReadPoolState
 	TotalDuration time.Duration
 	StartTime     time.Time
 	EndTime       time.Time
@@ -65,12 +154,42 @@ type ScanStats struct {
 
 // Scanner is the malware scanner
 type Scanner struct {
-	matcher *Matcher
-	sigSet  *intel.SignatureSet
+	matcher atomic.Pointer[Matcher]
+	sigSet  atomic.Pointer[intel.SignatureSet]
+	hashes  atomic.Pointer[intel.HashSet]
+	iocs    atomic.Pointer[intel.IOCSet]
+	// checked is when the signatures were last loaded or found up to date,
+	// in Unix nanoseconds
+	checked atomic.Int64
 	options *ScanOptions
 	logger  *logging.Logger
 	stats   ScanStats
 	mu      sync.Mutex
+
+	// openFiles bounds the number of concurrently open files (nil = unbounded)
+	openFiles chan struct{}
+	// readBudget bounds the file content held in memory (nil = unbounded)
+	readBudget *byteBudget
+	// pool keeps the buffers of files matched for reuse (nil = none)
+	pool *readPool
+	// progress tracks the latest scan of paths, for its checkpoint
+	progress *scanProgress
+	// errs collects the paths the latest scan didn't scan
+	errs *ScanErrorStats
+	// circuits skips the files of devices whose reads keep failing
+	circuits *circuitBreakers
+	// backoff slows the reads of devices whose read latency rises
+	backoff *readBackoff
+	// rate bounds how fast the workers read together
+	rate *readRate
+	// batch pauses the reads after each batch of files
+	batch *batchPause
+	// monitor throttles adaptive scans while the host is under pressure
+	monitor *resourceMonitor
+	// memory shrinks the read budget near the memory limit (nil = no limit)
+	memory *memoryGuard
+	// events hands progress events to subscribers
+	events progressEvents
 }
 
 // Option configures a Scanner
@@ -83,6 +202,22 @@ func WithScanWorkers(workers int) Option {
 	}
 }
 
+// WithReadWorkers sets how many workers read files (0 derives it from the
+// match workers and the read rate)
+func WithReadWorkers(workers int) Option {
+	return func(s *Scanner) {
+		s.options.ReadWorkers = workers
+	}
+}
+
+// WithMatchWorkers sets how many workers match the files read against the
+// signatures (0 is the number of workers)
+func WithMatchWorkers(workers int) Option {
+	return func(s *Scanner) {
+		s.options.MatchWorkers = workers
+	}
+}
+
 // WithScanFilter sets the file filter
 func WithScanFilter(filter *FileFilter) Option {
 	return func(s *Scanner) {
@@ -104,6 +239,105 @@ func WithAllowIOErrors(allow bool) Option {
 	}
 }
 
+// WithErrorBudget stops scans once more than percent of the files checked
+// have failed (0 is unlimited). Small scans are never stopped early, but
+// Err reports an exceeded budget once they end.
+func WithErrorBudget(percent float64) Option {
+	return func(s *Scanner) {
+		s.options.ErrorBudget = percent
+	}
+}
+
+// WithMaxRetries sets how many times files that fail with a retryable
+// error, such as EAGAIN, are scanned again once the rest of the scan is
+// done (0 reports them as failed straight away)
+func WithMaxRetries(retries int) Option {
+	return func(s *Scanner) {
+		s.options.MaxRetries = retries
+	}
+}
+
+// WithRetryWait sets how long to wait before the first retry of failed
+// files; the wait doubles on each retry after it
+func WithRetryWait(wait time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.RetryWait = wait
+	}
+}
+
+// WithCircuitBreaker skips the files of a device once threshold reads in a
+// row from it have failed (0 never does), trying one again after cooldown.
+// Each device has its own circuit, so a failing mount doesn't hold up the
+// rest.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.CircuitThreshold = threshold
+		s.options.CircuitCooldown = cooldown
+	}
+}
+
+// WithReadLatencyTarget slows the reads of a device once the 95th
+// percentile latency of its reads passes target (0 never does), waiting
+// longer before each of its files until the latency falls again
+func WithReadLatencyTarget(target time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.ReadLatencyTarget = target
+	}
+}
+
+// WithFileDelay makes each worker wait after each file it scans, leaving
+// the CPUs and disks idle for the sites in between
+func WithFileDelay(delay time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.FileDelay = delay
+	}
+}
+
+// WithBatchPause stops reading files for pause after every size files
+// read, letting the sites catch up in bursts rather than after each file
+// (0 never does)
+func WithBatchPause(size int, pause time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.BatchSize = size
+		s.options.BatchPause = pause
+	}
+}
+
+// WithReadRate bounds how many bytes a second the workers read in all (0
+// is unbounded)
+func WithReadRate(bytesPerSecond int64) Option {
+	return func(s *Scanner) {
+		s.options.ReadRate = bytesPerSecond
+	}
+}
+
+// WithAdaptive samples the host's load and memory during scans and
+// throttles the workers while it is under pressure
+func WithAdaptive(adaptive bool) Option {
+	return func(s *Scanner) {
+		s.options.Adaptive = adaptive
+	}
+}
+
+// WithMemoryLimit is the memory a scan keeps under, in bytes (0 is none):
+// the read budget starts at no more than half of it and shrinks as the
+// process nears it, and adaptive scans are throttled too. Set the Go
+// memory limit to match with debug.SetMemoryLimit
+func WithMemoryLimit(limit int64) Option {
+	return func(s *Scanner) {
+		s.options.MemoryLimit = limit
+	}
+}
+
+// WithSlowFileThreshold traces each file that takes longer than threshold
+// to read and match in a span of its own, when tracing is on (0 traces
+// none)
+func WithSlowFileThreshold(threshold time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.SlowFileThreshold = threshold
+	}
+}
+
 // WithContentLimit sets the maximum content size to scan per file
 func WithContentLimit(limit int64) Option {
 	return func(s *Scanner) {
@@ -118,16 +352,201 @@ func WithFollowSymlinks(follow bool) Option {
 	}
 }
 
+// WithHashSet sets a blocklist of known-malware file hashes that are
+// checked before signature matching
+func WithHashSet(hashes *intel.HashSet) Option {
+	return func(s *Scanner) {
+		s.hashes.Store(hashes)
+	}
+}
+
+// WithIOCs enables extraction of domains and IP addresses from scanned
+// content, reporting those listed in iocs
+func WithIOCs(iocs *intel.IOCSet) Option {
+	return func(s *Scanner) {
+		s.iocs.Store(iocs)
+	}
+}
+
+// WithCategories limits matching to signatures in the given categories
+func WithCategories(categories []string) Option {
+	return func(s *Scanner) {
+		s.options.Categories = categories
+	}
+}
+
+// WithNulledDetection sets whether to report footprints of pirated/nulled
+// plugins and themes
+func WithNulledDetection(enabled bool) Option {
+	return func(s *Scanner) {
+		s.options.DetectNulled = enabled
+	}
+}
+
+// WithEmbeddedPHPDetection enables or disables reporting PHP code in
+// images, stylesheets and fonts, which are looked at even when the filter
+// leaves them out
+func WithEmbeddedPHPDetection(enabled bool) Option {
+	return func(s *Scanner) {
+		s.options.DetectEmbeddedPHP = enabled
+	}
+}
+
+// WithMaxOpenFiles caps the number of files open at once (0 = derive from RLIMIT_NOFILE)
+func WithMaxOpenFiles(limit int) Option {
+	return func(s *Scanner) {
+		s.options.MaxOpenFiles = limit
+	}
+}
+
+// WithSignatureTimeout bounds each signature's match against a file
+func WithSignatureTimeout(timeout time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.MatchTimeout = timeout
+	}
+}
+
+// WithScanHintSlack sets how many characters either side of a signature's
+// common strings are searched for a match (0 searches the whole file)
+func WithScanHintSlack(slack int) Option {
+	return func(s *Scanner) {
+		s.options.HintSlack = slack
+	}
+}
+
+// WithFileTimeout bounds the time spent matching all signatures against a
+// single file (0 is unlimited). Files that reach it are reported with
+// Incomplete set.
+func WithFileTimeout(timeout time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.FileTimeout = timeout
+	}
+}
+
+// WithScanMatchAll checks every signature against each file rather than
+// stopping at the first match. Results of a scan that stops early are
+// reported with Truncated set.
+func WithScanMatchAll(all bool) Option {
+	return func(s *Scanner) {
+		s.options.MatchAll = all
+	}
+}
+
+// WithScanRegexEngine sets the engine signatures are matched with, one of
+// RegexEngines
+func WithScanRegexEngine(engine string) Option {
+	return func(s *Scanner) {
+		s.options.RegexEngine = engine
+	}
+}
+
+// WithSkipBinary skips matching files whose magic bytes show they are
+// images, media, archives or executables, unless they contain PHP
+func WithSkipBinary(skip bool) Option {
+	return func(s *Scanner) {
+		s.options.SkipBinary = skip
+	}
+}
+
+// WithScanImagesWithPHP also walks images the filter excludes, matching
+// those that contain PHP to catch polyglot uploads
+func WithScanImagesWithPHP(scan bool) Option {
+	return func(s *Scanner) {
+		s.options.ScanImagesWithPHP = scan
+	}
+}
+
+// WithMaxPathLength sets the longest path that will be scanned
+func WithMaxPathLength(length int) Option {
+	return func(s *Scanner) {
+		s.options.MaxPathLength = length
+	}
+}
+
+// WithMaxDepth sets how many directory levels below each scan path are
+// walked (0 is unlimited). Deeper directories are skipped with a warning.
+func WithMaxDepth(depth int) Option {
+	return func(s *Scanner) {
+		s.options.MaxDepth = depth
+	}
+}
+
+// WithMaxFilesPerDir sets how many files are scanned in any one directory
+// (0 is unlimited). The rest are skipped with a warning, so directories of
+// millions of spam doorway pages don't hold up the scan.
+func WithMaxFilesPerDir(limit int) Option {
+	return func(s *Scanner) {
+		s.options.MaxFilesPerDir = limit
+	}
+}
+
+// WithScanOrder sets the order files are scanned in, one of ScanOrders
+func WithScanOrder(order string) Option {
+	return func(s *Scanner) {
+		if order != "" {
+			s.options.Order = order
+		}
+	}
+}
+
+// WithOrderBuffer sets how many files are held to be ordered by the scan
+// order
+func WithOrderBuffer(size int) Option {
+	return func(s *Scanner) {
+		s.options.OrderBuffer = size
+	}
+}
+
+// WithMaxDuration stops scans after d (0 is unlimited): no more files are
+// found or started, while those being scanned are finished. Checkpoint then
+// says where to resume.
+func WithMaxDuration(d time.Duration) Option {
+	return func(s *Scanner) {
+		s.options.MaxDuration = d
+	}
+}
+
+// WithResume resumes the scan a checkpoint was made of, skipping the files
+// it had scanned. The scan must be of the checkpoint's paths.
+func WithResume(cp *Checkpoint) Option {
+	return func(s *Scanner) {
+		s.options.Resume = cp
+	}
+}
+
+// WithMaxSymlinkDepth sets how many symlinked directories may be followed in a chain
+func WithMaxSymlinkDepth(depth int) Option {
+	return func(s *Scanner) {
+		s.options.MaxSymlinkDepth = depth
+	}
+}
+
 // NewScanner creates a new malware scanner
 func NewScanner(sigSet *intel.SignatureSet, opts ...Option) *Scanner {
 	s := &Scanner{
-		sigSet: sigSet,
 		options: &ScanOptions{
-			Workers:   DefaultWorkers,
-			ChunkSize: DefaultChunkSize,
-			Filter:    DefaultFilter(),
+			Workers:           DefaultWorkers,
+			ChunkSize:         DefaultChunkSize,
+			Filter:            DefaultFilter(),
+			MaxPathLength:     DefaultMaxPathLength,
+			MaxSymlinkDepth:   DefaultMaxSymlinkDepth,
+			Order:             OrderWalk,
+			OrderBuffer:       DefaultOrderBuffer,
+			DetectNulled:      true,
+			DetectEmbeddedPHP: true,
+			MaxBytesInFlight:  DefaultMaxBytesInFlight,
+			MatchTimeout:      DefaultMatchTimeout,
+			HintSlack:         DefaultHintSlack,
+			FileTimeout:       DefaultFileTimeout,
+			MaxRetries:        DefaultMaxRetries,
+			RetryWait:         DefaultRetryWait,
+			CircuitThreshold:  DefaultCircuitThreshold,
+			CircuitCooldown:   DefaultCircuitCooldown,
+			SlowFileThreshold: DefaultSlowFileThreshold,
+			ReadPoolMemory:    DefaultReadPoolMemory,
 		},
 		logger: logging.New(logging.LevelInfo),
+		errs:   NewScanErrorStats(DefaultErrorPathLimit),
 	}
 
 	for _, opt := range opts {
@@ -135,61 +554,358 @@ func NewScanner(sigSet *intel.SignatureSet, opts ...Option) *Scanner {
 	}
 
 	// Create the matcher
-	s.matcher = NewMatcher(sigSet, WithMatcherLogger(s.logger))
+	s.SetSignatures(sigSet)
 
+	// Bound open files so high worker counts don't run into EMFILE
+	maxOpen := s.options.MaxOpenFiles
+	if maxOpen <= 0 {
+		maxOpen = defaultMaxOpenFiles()
+	}
+	if maxOpen > 0 {
+		s.openFiles = make(chan struct{}, maxOpen)
+	}
+	s.pool = newReadPool(s.options.ReadPoolMemory)
+	s.initScanState()
 	return s
 }
 
+// Fork returns a scanner with the signatures, feeds and options of s, and
+// sharing its bounds on open files and the read rate and its read buffers,
+// but with statistics, error counts, circuits and throttling of its own. A scan of paths resets
+// that state, so scans running at the same time each need their own fork.
+// Signatures set on s later are not seen by the fork.
+func (s *Scanner) Fork() *Scanner {
+	options := *s.options
+	f := &Scanner{
+		options:   &options,
+		logger:    s.logger,
+		errs:      NewScanErrorStats(DefaultErrorPathLimit),
+		openFiles: s.openFiles,
+		rate:      s.rate,
+		pool:      s.pool,
+	}
+	s.mu.Lock()
+	f.matcher.Store(s.matcher.Load())
+	f.sigSet.Store(s.sigSet.Load())
+	f.checked.Store(s.checked.Load())
+	s.mu.Unlock()
+	f.hashes.Store(s.hashes.Load())
+	f.iocs.Store(s.iocs.Load())
+	f.initScanState()
+	return f
+}
+
+// initScanState creates the state a scan of paths resets, once the options
+// are set
+func (s *Scanner) initScanState() {
+	if s.options.MaxBytesInFlight > 0 {
+		s.readBudget = newByteBudget(s.options.MaxBytesInFlight)
+	}
+	if limit := s.options.MemoryLimit; limit > 0 {
+		size := s.options.MaxBytesInFlight
+		if s.readBudget == nil {
+			size = limit / 2
+			s.readBudget = newByteBudget(size)
+		}
+		s.memory = newMemoryGuard(limit, s.readBudget, size)
+		s.memory.pool = s.pool
+	}
+	s.circuits = newCircuitBreakers(s.options.CircuitThreshold, s.options.CircuitCooldown)
+	s.backoff = newReadBackoff(s.options.ReadLatencyTarget)
+	if s.rate == nil {
+		s.rate = newReadRate(s.options.ReadRate)
+	}
+	s.batch = newBatchPause(s.options.BatchSize, s.options.BatchPause)
+	s.options.ReadWorkers, s.options.MatchWorkers = s.options.stageWorkers()
+	s.monitor = newResourceMonitor(s.options.MatchWorkers, s.options.MemoryLimit)
+	s.monitor.changed = func(state ThrottleState) {
+		s.events.emit(ProgressEvent{Type: ProgressThrottle, Throttle: &state})
+	}
+}
+
+// SetSignatures compiles sigSet and atomically replaces the active matcher.
+// Scans already in progress finish with the previous signatures. Only
+// signatures in the configured categories are compiled, but the full set is
+// kept for result lookups.
+func (s *Scanner) SetSignatures(sigSet *intel.SignatureSet) {
+	active := sigSet
+	if len(s.options.Categories) > 0 {
+		active = sigSet.FilterCategories(s.options.Categories)
+		s.logger.Debug("Matching %d of %d signatures in categories %v", active.Count(), sigSet.Count(), s.options.Categories)
+	}
+
+	matcher := NewMatcher(active,
+		WithMatcherLogger(s.logger),
+		WithMatchTimeout(s.options.MatchTimeout),
+		WithHintSlack(s.options.HintSlack),
+		WithMatchAll(s.options.MatchAll),
+		WithRegexEngine(s.options.RegexEngine),
+	)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matcher.Store(matcher)
+	s.sigSet.Store(sigSet)
+	s.checked.Store(time.Now().UnixNano())
+}
+
+// SetHashSet atomically replaces the known-malware hash blocklist
+func (s *Scanner) SetHashSet(hashes *intel.HashSet) {
+	s.hashes.Store(hashes)
+}
+
+// SignatureSet returns the signatures currently used for matching
+func (s *Scanner) SignatureSet() *intel.SignatureSet {
+	return s.sigSet.Load()
+}
+
+// SignaturesChecked returns when the signatures were last loaded, or found
+// up to date by RefreshSignatures
+func (s *Scanner) SignaturesChecked() time.Time {
+	return time.Unix(0, s.checked.Load())
+}
+
+// CircuitStates returns the circuits of the devices the latest scan of
+// paths read from
+func (s *Scanner) CircuitStates() []CircuitState {
+	return s.circuits.states()
+}
+
+// RefreshSignatures calls fetch every interval until ctx is done and swaps
+// in any newer signature set it returns. fetch returns nil when the current
+// set is up to date.
+func (s *Scanner) RefreshSignatures(ctx context.Context, interval time.Duration, fetch func(context.Context, *intel.SignatureSet) (*intel.SignatureSet, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := s.SignatureSet()
+		newer, err := fetch(ctx, current)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warning("Signature refresh failed: %v", err)
+				s.errs.RecordError(StageSignatures, "", err)
+			}
+			continue
+		}
+		if newer == nil {
+			s.checked.Store(time.Now().UnixNano())
+			s.logger.Debug("Signatures are up to date")
+			continue
+		}
+
+		s.SetSignatures(newer)
+		s.logger.Info("Reloaded %d signatures (updated %s)", newer.Count(), time.Unix(newer.UpdateTime, 0).UTC().Format(time.RFC3339))
+	}
+}
+
+// defaultMaxOpenFiles derives an open file cap from the process descriptor limit
+func defaultMaxOpenFiles() int {
+	limit := openFileLimit()
+	if limit == 0 || limit > math.MaxInt32 {
+		return 0 // Unknown or effectively unlimited
+	}
+	if limit <= reservedFileDescriptors*2 {
+		return max(1, int(limit/2))
+	}
+	return int(limit - reservedFileDescriptors)
+}
+
+// specialFileReason returns why a file mode cannot be scanned, or "" for regular files
+func specialFileReason(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "device node"
+	case mode&fs.ModeIrregular != 0:
+		return "irregular file"
+	default:
+		return ""
+	}
+}
+
 // Scan scans the given paths for malware
 func (s *Scanner) Scan(ctx context.Context, paths ...string) (<-chan *ScanResult, error) {
+	return s.ScanShard(ctx, s.options.Shard, paths...)
+}
+
+// ScanShard scans the files of the given paths that belong to shard,
+// overriding the scanner's own shard
+func (s *Scanner) ScanShard(ctx context.Context, shard Shard, paths ...string) (<-chan *ScanResult, error) {
 	if len(paths) == 0 {
 		return nil, fmt.Errorf("no paths to scan")
 	}
 
+	resume := s.options.Resume
+	if resume != nil && !resume.Matches(paths) {
+		return nil, fmt.Errorf("checkpoint is of a scan of %s", strings.Join(resume.Paths, ", "))
+	}
+	ctx, span := telemetry.Start(ctx, "malware_scan",
+		telemetry.String("scan.paths", strings.Join(paths, ",")),
+		telemetry.Int("scan.read_workers", s.options.ReadWorkers),
+		telemetry.Int("scan.match_workers", s.options.MatchWorkers))
+	ctx, abort := context.WithCancelCause(ctx)
+	progress := newScanProgress(paths, s.options.MaxDuration, resume)
+	progress.abort = abort
+	s.errs.Reset()
+	s.circuits.reset()
+	s.backoff.reset()
+	s.monitor.reset()
+	s.batch.reset()
+	if s.options.Adaptive {
+		go s.monitor.run(ctx)
+	}
+	if s.memory != nil {
+		s.memory.reset()
+		go s.memory.run(ctx)
+	}
+
 	s.mu.Lock()
 	s.stats = ScanStats{
 		StartTime: time.Now(),
 	}
+	s.progress = progress
 	s.mu.Unlock()
+	s.events.emit(ProgressEvent{Type: ProgressScanStarted})
+	s.events.emit(ProgressEvent{Type: ProgressStage, Stage: StageWalk})
+	s.events.emit(ProgressEvent{Type: ProgressStage, Stage: StageScan})
 
 	results := make(chan *ScanResult, 100)
-	files := make(chan string, 1000)
+	files := make(chan fileTask, 1000)
 
-	// Start file locator
-	go s.locateFiles(ctx, paths, files)
+	// Start file locator, ordering the files it finds if asked to
+	walkCtx, walkSpan := telemetry.Start(ctx, StageWalk)
+	locate := func(found chan<- fileTask) {
+		s.locateFiles(walkCtx, progress, shard, found)
+		walkSpan.End()
+	}
+	if s.options.Order != OrderWalk && s.options.OrderBuffer > 0 {
+		found := files
+		files = make(chan fileTask)
+		go s.orderFiles(ctx, found, files)
+		go locate(found)
+	} else {
+		go locate(files)
+	}
 
-	// Start workers
-	var wg sync.WaitGroup
-	for i := 0; i < s.options.Workers; i++ {
-		wg.Add(1)
-		go s.worker(ctx, files, results, &wg)
+	// Start the workers reading files, and those matching what they read
+	stageCtx, stageSpan := telemetry.Start(ctx, StageScan)
+	var readers, matchers sync.WaitGroup
+	read := make(chan *readFile, s.options.MatchWorkers)
+	retries := &retryQueue{}
+	for i := 0; i < s.options.ReadWorkers; i++ {
+		readers.Add(1)
+		go s.readWorker(stageCtx, progress, files, read, &readers)
 	}
+	for i := 0; i < s.options.MatchWorkers; i++ {
+		matchers.Add(1)
+		go s.matchWorker(stageCtx, progress, read, retries, results, &matchers)
+	}
+	go func() {
+		readers.Wait()
+		close(read)
+	}()
 
-	// Close results when all workers are done
+	// Retry files that failed for a while, then close results
 	go func() {
-		wg.Wait()
+		matchers.Wait()
+		stageSpan.End()
+		s.events.emit(ProgressEvent{Type: ProgressStage, Stage: StageScan, Done: true})
+		s.retryFailed(ctx, progress, retries, results)
+		s.checkErrorBudget(progress, 0)
+		abort(nil)
 		s.mu.Lock()
 		s.stats.EndTime = time.Now()
 		s.stats.TotalDuration = s.stats.EndTime.Sub(s.stats.StartTime)
 		s.mu.Unlock()
+		stats := s.GetStats()
+		span.SetAttributes(
+			telemetry.Int64("scan.files_scanned", stats.FilesScanned),
+			telemetry.Int64("scan.files_matched", stats.FilesMatched),
+			telemetry.Int64("scan.files_errored", stats.FilesErrored),
+			telemetry.Int64("scan.bytes_scanned", stats.BytesScanned))
+		span.RecordError(s.Err())
+		span.End()
+		s.events.emit(ProgressEvent{Type: ProgressScanFinished, Stats: &stats})
+		s.events.finish()
 		close(results)
 	}()
 
 	return results, nil
 }
 
+// Checkpoint returns where to resume the latest scan of paths if it was
+// stopped at its MaxDuration, or nil if it wasn't. Call it once the scan's
+// results have all been received.
+func (s *Scanner) Checkpoint() *Checkpoint {
+	s.mu.Lock()
+	progress := s.progress
+	s.mu.Unlock()
+	if progress == nil || !progress.stopped.Load() {
+		return nil
+	}
+	return progress.checkpoint(atomic.LoadInt64(&s.stats.FilesScanned))
+}
+
+// Err returns why the latest scan of paths failed, such as its error
+// budget being exceeded, or nil. Call it once the scan's results have all
+// been received.
+func (s *Scanner) Err() error {
+	s.mu.Lock()
+	progress := s.progress
+	s.mu.Unlock()
+	if progress == nil {
+		return nil
+	}
+	return progress.failure()
+}
+
+// ScanErrors returns the paths the latest scan didn't scan
+func (s *Scanner) ScanErrors() *ScanErrorStats {
+	return s.errs
+}
+
+// Unstarted returns how many files found by the latest scan of paths were
+// left unscanned because it was stopped at its MaxDuration
+func (s *Scanner) Unstarted() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.progress == nil {
+		return 0
+	}
+	return s.progress.dropped.Load()
+}
+
 // locateFiles walks the file system and sends file paths to the files channel
-func (s *Scanner) locateFiles(ctx context.Context, paths []string, files chan<- string) {
+func (s *Scanner) locateFiles(ctx context.Context, progress *scanProgress, shard Shard, files chan<- fileTask) {
 	defer close(files)
+	defer s.events.emit(ProgressEvent{Type: ProgressStage, Stage: StageWalk, Done: true})
 
 	visited := make(map[string]bool)
 
-	for _, path := range paths {
+	for i, path := range progress.paths {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
+		if progress.pastDeadline() {
+			return
+		}
+		if progress.skipsRoot(i) {
+			continue
+		}
+		progress.root = i
 
 		info, err := os.Stat(path)
 		if err != nil {
@@ -198,78 +914,242 @@ func (s *Scanner) locateFiles(ctx context.Context, paths []string, files chan<-
 		}
 
 		if info.IsDir() {
-			s.walkDirectory(ctx, path, files, visited)
+			s.walkDirectory(ctx, progress, path, shard, files, visited, 0, 0)
+		} else if reason := specialFileReason(info.Mode()); reason != "" {
+			s.skipFile(StageWalk, path, CodeSpecialFile, reason)
 		} else {
-			s.sendFile(ctx, path, files, visited)
+			s.sendFile(ctx, progress, path, progress.position(path, path), shard, files, visited)
 		}
 	}
 }
 
-// walkDirectory recursively walks a directory
-func (s *Scanner) walkDirectory(ctx context.Context, dir string, files chan<- string, visited map[string]bool) {
+// allowDir reports whether a directory should be walked, recording pruned ones
+func (s *Scanner) allowDir(path string) bool {
+	if s.options.Filter == nil || s.options.Filter.FilterDir(path) {
+		return true
+	}
+	s.logger.Debug("Skipping excluded directory %s", path)
+	atomic.AddInt64(&s.stats.DirsSkipped, 1)
+	return false
+}
+
+// allowDepth reports whether a directory level directories below a scan
+// path should be walked, recording skipped ones
+func (s *Scanner) allowDepth(path string, level int) bool {
+	if s.options.MaxDepth <= 0 || level <= s.options.MaxDepth {
+		return true
+	}
+	s.logger.Warning("Not descending into %s: depth limit (%d) reached", path, s.options.MaxDepth)
+	atomic.AddInt64(&s.stats.DirsSkipped, 1)
+	s.errs.Record(ScanError{Stage: StageWalk, Path: path, Code: CodeDepthLimit, Message: fmt.Sprintf("more than %d levels deep", s.options.MaxDepth)})
+	return false
+}
+
+// allowFileInDir counts a file in its directory, reporting whether it is
+// within MaxFilesPerDir. A truncated directory is warned about once.
+func (s *Scanner) allowFileInDir(dirFiles map[string]int, path string) bool {
+	if s.options.MaxFilesPerDir <= 0 {
+		return true
+	}
+	dir := filepath.Dir(path)
+	dirFiles[dir]++
+	switch count := dirFiles[dir]; {
+	case count <= s.options.MaxFilesPerDir:
+		return true
+	case count == s.options.MaxFilesPerDir+1:
+		s.logger.Warning("Truncated directory %s: only the first %d files are scanned", dir, s.options.MaxFilesPerDir)
+	}
+	s.skipFile(StageWalk, path, CodeDirectoryTruncated, fmt.Sprintf("more than %d files in the directory", s.options.MaxFilesPerDir))
+	return false
+}
+
+// dirLevel returns how many directories below root path is
+func dirLevel(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// skipFile records a file that was not scanned at stage and why. Files
+// skipped as a matter of course have no code and are left out of the error
+// report.
+func (s *Scanner) skipFile(stage, path string, code ScanErrorCode, reason string) {
+	s.logger.Debug("Skipping %s: %s", path, reason)
+	atomic.AddInt64(&s.stats.FilesSkipped, 1)
+	if code != "" {
+		s.errs.Record(ScanError{Stage: stage, Path: path, Code: code, Message: reason})
+	}
+}
+
+// walkDirectory recursively walks a directory. depth counts the symlinked
+// directories followed to reach dir and bounds symlink loops; level is how
+// many directories below a scan path dir is.
+func (s *Scanner) walkDirectory(ctx context.Context, progress *scanProgress, dir string, shard Shard, files chan<- fileTask, visited map[string]bool, depth, level int) {
+	// Mark the real directory as visited so symlinks back into it are not re-walked
+	if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+		if abs, err := filepath.Abs(realDir); err == nil {
+			visited[abs] = true
+		}
+	}
+
+	// Files seen in each directory, for MaxFilesPerDir
+	dirFiles := make(map[string]int)
+
 	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		if err := walkStopped(ctx, progress); err != nil {
+			return err
 		}
 
 		if err != nil {
-			if s.options.AllowIOErrors {
-				s.logger.Warning("Error accessing %s: %v", path, err)
-				return nil
-			}
-			return err
+			return s.walkError(progress, path, err)
+		}
+
+		// Only the walk of a scan path itself is resumed part way; the
+		// directories it follows symlinks to are walked again
+		if depth == 0 && progress.resumeSkips(dir, path, d.IsDir()) {
+			return skipEntry(d)
 		}
 
-		// Skip directories
+		// Don't descend into excluded directories, or too deep
 		if d.IsDir() {
+			if path != dir && (!s.allowDir(path) || !s.allowDepth(path, level+dirLevel(dir, path))) {
+				return fs.SkipDir
+			}
 			return nil
 		}
 
-		// Handle symlinks
-		if d.Type()&fs.ModeSymlink != 0 {
-			if !s.options.FollowSymlinks {
-				atomic.AddInt64(&s.stats.FilesSkipped, 1)
-				return nil
-			}
+		if !s.allowFileInDir(dirFiles, path) {
+			return nil
+		}
 
-			// Resolve symlink
-			resolved, err := filepath.EvalSymlinks(path)
-			if err != nil {
-				s.logger.Debug("Cannot resolve symlink %s: %v", path, err)
-				return nil
-			}
+		// A file's place in the walk is where it, or the symlink to it, is
+		var pos walkPos
+		if depth == 0 {
+			pos = progress.position(dir, path)
+		}
 
-			// Check for loops
-			if visited[resolved] {
+		// Handle symlinks
+		if d.Type()&fs.ModeSymlink != 0 {
+			resolved, info := s.resolveSymlink(path, visited)
+			if info == nil {
 				return nil
 			}
 
-			info, err := os.Stat(resolved)
-			if err != nil {
+			if info.IsDir() {
+				linkLevel := level + dirLevel(dir, path)
+				if !s.allowDir(path) || !s.allowDepth(path, linkLevel) {
+					return nil
+				}
+				if depth >= s.options.MaxSymlinkDepth {
+					s.logger.Warning("Not following %s: symlink depth limit (%d) reached", path, s.options.MaxSymlinkDepth)
+					return nil
+				}
+				s.walkDirectory(ctx, progress, resolved, shard, files, visited, depth+1, linkLevel)
 				return nil
 			}
 
-			if info.IsDir() {
-				s.walkDirectory(ctx, resolved, files, visited)
+			if reason := specialFileReason(info.Mode()); reason != "" {
+				s.skipFile(StageWalk, path, CodeSpecialFile, reason)
 				return nil
 			}
 
 			path = resolved
+		} else if reason := specialFileReason(d.Type()); reason != "" {
+			s.skipFile(StageWalk, path, CodeSpecialFile, reason)
+			return nil
 		}
 
-		s.sendFile(ctx, path, files, visited)
+		s.sendFile(ctx, progress, path, pos, shard, files, visited)
 		return nil
 	})
 
-	if err != nil && !errors.Is(err, context.Canceled) {
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, errScanDeadline) {
 		s.logger.Warning("Error walking directory %s: %v", dir, err)
 	}
 }
 
+// walkError handles a path the walk couldn't read. With an error budget,
+// or when IO errors are allowed, it counts against the budget rather than
+// ending the walk.
+func (s *Scanner) walkError(progress *scanProgress, path string, err error) error {
+	if !s.options.AllowIOErrors && s.options.ErrorBudget <= 0 {
+		return err
+	}
+	s.logger.Warning("Error accessing %s: %v", path, err)
+	atomic.AddInt64(&s.stats.FilesErrored, 1)
+	s.errs.RecordError(StageWalk, path, err)
+	s.events.emit(ProgressEvent{Type: ProgressError, Stage: StageWalk, Path: path, Error: err.Error()})
+	s.checkErrorBudget(progress, errorBudgetMinFiles)
+	return nil
+}
+
+// resolveSymlink returns the absolute target of a symlink found walking,
+// and its file info, or nil info if it isn't followed: symlinks aren't
+// followed, it is broken, or its target was already visited
+func (s *Scanner) resolveSymlink(path string, visited map[string]bool) (string, os.FileInfo) {
+	if !s.options.FollowSymlinks {
+		s.skipFile(StageWalk, path, CodeSymlinkNotFollowed, "symlinks are not followed")
+		return "", nil
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		s.logger.Debug("Cannot resolve symlink %s: %v", path, err)
+		return "", nil
+	}
+
+	// Check for loops
+	if absResolved, err := filepath.Abs(resolved); err == nil {
+		resolved = absResolved
+	}
+	if visited[resolved] {
+		return "", nil
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", nil
+	}
+	return resolved, info
+}
+
+// walkStopped returns why a walk should stop before its next entry: the
+// scan was cancelled, or reached its deadline
+func walkStopped(ctx context.Context, progress *scanProgress) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if progress.pastDeadline() {
+		return errScanDeadline
+	}
+	return nil
+}
+
+// skipEntry skips a walk entry, and everything beneath it if it is a
+// directory
+func skipEntry(d fs.DirEntry) error {
+	if d.IsDir() {
+		return fs.SkipDir
+	}
+	return nil
+}
+
+// fileTask is a file for a worker to scan
+type fileTask struct {
+	path string
+	// phpOnly is set for files the filter doesn't include, which are walked
+	// only to look for PHP in them
+	phpOnly bool
+	// seq is the order the file was sent in and pos its place in the walk,
+	// to checkpoint the scan
+	seq int
+	pos walkPos
+}
+
 // sendFile sends a file path to the files channel if it passes the filter
-func (s *Scanner) sendFile(ctx context.Context, path string, files chan<- string, visited map[string]bool) {
+func (s *Scanner) sendFile(ctx context.Context, progress *scanProgress, path string, pos walkPos, shard Shard, files chan<- fileTask, visited map[string]bool) {
 	// Skip already visited files
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -280,111 +1160,276 @@ func (s *Scanner) sendFile(ctx context.Context, path string, files chan<- string
 	}
 	visited[absPath] = true
 
-	// Apply filter
-	if s.options.Filter != nil && !s.options.Filter.Filter(path) {
-		atomic.AddInt64(&s.stats.FilesSkipped, 1)
+	// Files of other shards are left to their processes without counting
+	// them, so the shards' statistics add up to those of a whole scan
+	if !shard.Contains(absPath) {
+		return
+	}
+
+	if s.options.MaxPathLength > 0 && len(path) > s.options.MaxPathLength {
+		s.skipFile(StageWalk, path, CodePathTooLong, fmt.Sprintf("path exceeds %d bytes", s.options.MaxPathLength))
 		return
 	}
 
+	// Apply filter, still looking for PHP in files it leaves out by type
+	task := fileTask{path: path, seq: progress.sent, pos: pos}
+	if s.options.Filter != nil && !s.options.Filter.Filter(path) {
+		if !s.walksForPHP(path) || s.options.Filter.Excluded(path) {
+			atomic.AddInt64(&s.stats.FilesSkipped, 1)
+			return
+		}
+		task.phpOnly = true
+	}
+
 	select {
 	case <-ctx.Done():
 		return
-	case files <- path:
+	case files <- task:
+		progress.sent++
 	}
 }
 
-// worker processes files from the files channel
-func (s *Scanner) worker(ctx context.Context, files <-chan string, results chan<- *ScanResult, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case path, ok := <-files:
-			if !ok {
-				return
-			}
+// finishTask records the result of scanning a file and sends it, reporting
+// false if the scan was cancelled first
+func (s *Scanner) finishTask(ctx context.Context, progress *scanProgress, task fileTask, result *ScanResult, results chan<- *ScanResult) bool {
+	s.recordResult(result)
+	progress.scanned(task.seq, task.pos)
+	s.events.emit(ProgressEvent{Type: ProgressFileFinished, Path: result.Path, Result: result})
+	if result.Error != nil {
+		s.events.emit(ProgressEvent{Type: ProgressError, Stage: StageScan, Path: result.Path, Error: result.Error.Error()})
+		s.checkErrorBudget(progress, errorBudgetMinFiles)
+	}
 
-			result := s.scanFile(ctx, path)
+	select {
+	case <-ctx.Done():
+		return false
+	case results <- result:
+		return true
+	}
+}
 
-			if result.Error != nil {
-				atomic.AddInt64(&s.stats.FilesErrored, 1)
-			} else {
-				atomic.AddInt64(&s.stats.FilesScanned, 1)
-				atomic.AddInt64(&s.stats.BytesScanned, result.ScannedBytes)
-				if result.HasMatches() {
-					atomic.AddInt64(&s.stats.FilesMatched, 1)
-				}
-			}
+// checkErrorBudget fails the scan if more of its files have failed than
+// the error budget allows, once minFiles have been checked
+func (s *Scanner) checkErrorBudget(progress *scanProgress, minFiles int64) {
+	errored, scanned := atomic.LoadInt64(&s.stats.FilesErrored), atomic.LoadInt64(&s.stats.FilesScanned)
+	if err := overErrorBudget(s.options.ErrorBudget, errored, scanned, minFiles); err != nil {
+		progress.fail(err)
+	}
+}
 
-			select {
-			case <-ctx.Done():
-				return
-			case results <- result:
-			}
+// recordResult adds a scan result to the statistics
+func (s *Scanner) recordResult(result *ScanResult) {
+	if result.Error != nil {
+		atomic.AddInt64(&s.stats.FilesErrored, 1)
+		if !errors.Is(result.Error, context.Canceled) {
+			s.errs.RecordError(StageScan, result.Path, result.Error)
 		}
+		return
+	}
+	if result.Skipped != "" {
+		return
+	}
+	atomic.AddInt64(&s.stats.FilesScanned, 1)
+	atomic.AddInt64(&s.stats.BytesScanned, result.ScannedBytes)
+	if result.HasMatches() {
+		atomic.AddInt64(&s.stats.FilesMatched, 1)
 	}
 }
 
-// scanFile scans a single file
-func (s *Scanner) scanFile(ctx context.Context, path string) *ScanResult {
-	start := time.Now()
-	result := &ScanResult{
-		Path: path,
+// readSize returns how much of a file of size bytes is read
+func (s *Scanner) readSize(size int64) int64 {
+	if s.options.ContentLimit > 0 && size > s.options.ContentLimit {
+		return s.options.ContentLimit
 	}
+	return size
+}
 
-	file, err := os.Open(path) // #nosec G304 -- scanning user-specified paths
+// matchReader reads up to limit bytes (no limit if negative) from r and
+// matches them against the signatures, filling in result. phpOnly content
+// is matched only if it has PHP in it.
+func (s *Scanner) matchReader(ctx context.Context, result *ScanResult, r io.Reader, limit int64, phpOnly bool) {
+	content, err := readContent(r, limit)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to open file: %w", err)
-		return result
+		result.Error = err
+		return
 	}
-	defer func() { _ = file.Close() }()
+	s.matchContent(ctx, result, content, phpOnly)
+}
 
-	// Get file size
-	info, err := file.Stat()
-	if err != nil {
-		result.Error = fmt.Errorf("failed to stat file: %w", err)
-		return result
+// matchContent matches content against the signatures, filling in result
+func (s *Scanner) matchContent(ctx context.Context, result *ScanResult, content []byte, phpOnly bool) {
+	result.ScannedBytes = int64(len(content))
+	if s.options.Redact {
+		defer func() { redactMatches(result.Matches) }()
 	}
 
-	// Read file content
-	size := info.Size()
-	if s.options.ContentLimit > 0 && size > s.options.ContentLimit {
-		size = s.options.ContentLimit
+	// The test file is detected whatever signatures are loaded
+	if IsTestFile(content) {
+		result.Matches = []*MatchResult{{Category: TestCategory, MatchedString: TestString}}
+		describeMatches(result.Matches)
+		return
 	}
 
-	content, err := io.ReadAll(io.LimitReader(file, size))
-	if err != nil {
-		result.Error = fmt.Errorf("failed to read file: %w", err)
-		return result
+	// Exact matches against the hash blocklist need no regex matching
+	if hashes := s.hashes.Load(); hashes != nil && hashes.Count() > 0 {
+		if known := hashes.Lookup(sha256.Sum256(content)); known != nil {
+			result.Matches = []*MatchResult{{
+				Category:      known.Category,
+				MatchedString: known.SHA256,
+				KnownHash:     known,
+			}}
+			describeMatches(result.Matches)
+			return
+		}
 	}
 
-	result.ScannedBytes = int64(len(content))
-
-	// Match against signatures
-	matchCtx := s.matcher.NewMatchContext()
-	if err := matchCtx.Match(ctx, content); err != nil {
-		if !errors.Is(err, context.Canceled) {
-			s.logger.Debug("Match error for %s: %v", path, err)
+	// Files the filter leaves out are only looked at for PHP, and binary
+	// files may be skipped
+	embedded := s.findEmbeddedPHP(result.Path, content)
+	if phpOnly && !(s.matchesImagesWithPHP(result.Path) && ContainsPHP(content)) {
+		if len(embedded) == 0 {
+			result.Skipped = "no PHP in a file type not scanned"
+			s.skipFile(StageScan, result.Path, "", result.Skipped)
+			return
 		}
+		result.Matches = embedded
+		describeMatches(result.Matches)
+		return
+	}
+	if reason := s.skipBinary(content); reason != "" {
+		result.Skipped = reason
+		s.skipFile(StageScan, result.Path, CodeBinary, reason)
+		return
+	}
+
+	// Match against signatures, within the per-file time limit
+	matchCtx := s.matcher.Load().NewMatchContext()
+	fileCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.options.FileTimeout > 0 {
+		fileCtx, cancel = context.WithTimeout(ctx, s.options.FileTimeout)
+	}
+	err := matchCtx.Match(fileCtx, content)
+	cancel()
+	switch {
+	case err == nil:
+	case ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded):
+		result.Incomplete = true
+		s.logger.Warning("Stopped matching %s after %v: file time limit reached", result.Path, s.options.FileTimeout)
+	case !errors.Is(err, context.Canceled):
+		s.logger.Debug("Match error for %s: %v", result.Path, err)
 	}
 
 	result.Matches = matchCtx.GetMatches()
 	result.Timeouts = matchCtx.GetTimeouts()
-	result.ScanDuration = time.Since(start)
+	result.Truncated = matchCtx.Truncated()
+	for _, sigID := range result.Timeouts {
+		s.logger.Verbose("%s: %v", result.Path, &ErrMatchTimeout{SignatureID: sigID})
+	}
+	for sigID, d := range matchCtx.GetSlowSignatures() {
+		s.logger.Debug("%s: signature %d took %v", result.Path, sigID, d.Round(time.Millisecond))
+	}
 
-	return result
+	for _, found := range ExtractIndicators(content, s.iocs.Load()) {
+		result.Matches = append(result.Matches, &MatchResult{
+			Category:      "ioc",
+			MatchedString: found.Indicator,
+			Position:      found.Position,
+			IOC:           found.IOC,
+		})
+	}
+
+	if s.detectsNulled() {
+		for _, found := range DetectNulled(content) {
+			result.Matches = append(result.Matches, &MatchResult{
+				Category:      NulledCategory,
+				MatchedString: found.MatchedString,
+				Position:      found.Position,
+				Nulled:        found.Rule,
+			})
+		}
+	}
+	result.Matches = append(result.Matches, embedded...)
+	describeMatches(result.Matches)
+}
+
+// describeMatches joins names and descriptions into hash, IOC and nulled
+// software matches; signature matches are described by the matcher
+func describeMatches(matches []*MatchResult) {
+	for _, match := range matches {
+		if match.Name == "" {
+			match.Name, match.Description = match.Describe(nil)
+		}
+	}
+}
+
+// detectsNulled reports whether nulled software detection is enabled and
+// not excluded by a category filter
+func (s *Scanner) detectsNulled() bool {
+	return s.options.DetectNulled && s.inCategories(NulledCategory)
+}
+
+// detectsEmbeddedPHP reports whether embedded PHP detection is enabled and
+// not excluded by a category filter
+func (s *Scanner) detectsEmbeddedPHP() bool {
+	return s.options.DetectEmbeddedPHP && s.inCategories(EmbeddedPHPCategory)
+}
+
+// inCategories reports whether the category filter, if any, includes category
+func (s *Scanner) inCategories(category string) bool {
+	return len(s.options.Categories) == 0 || slices.Contains(s.options.Categories, category)
 }
 
 // GetStats returns the current scanning statistics
 func (s *Scanner) GetStats() ScanStats {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.stats
+	stats := s.stats
+	stats.Errors = s.errs.Counts()
+	stats.Circuits = s.circuits.states()
+	stats.ReadLatency = s.backoff.states()
+	if s.options.Adaptive {
+		throttle := s.monitor.states()
+		stats.Throttle = &throttle
+	}
+	if s.memory != nil {
+		memory := s.memory.states()
+		stats.Memory = &memory
+	}
+	stats.ReadPool = s.pool.states()
+	return stats
 }
 
 // ScanSingleFile scans a single file and returns the result
 func (s *Scanner) ScanSingleFile(ctx context.Context, path string) *ScanResult {
-	return s.scanFile(ctx, path)
+	return s.scanFile(ctx, fileTask{path: path})
+}
+
+// ScanReader scans content read from r as a single file reported under
+// name. Statistics are reset and updated as for Scan.
+func (s *Scanner) ScanReader(ctx context.Context, name string, r io.Reader) *ScanResult {
+	start := time.Now()
+	s.mu.Lock()
+	s.stats = ScanStats{
+		StartTime: start,
+	}
+	s.mu.Unlock()
+
+	result := &ScanResult{
+		Path: name,
+	}
+
+	limit := int64(-1)
+	if s.options.ContentLimit > 0 {
+		limit = s.options.ContentLimit
+	}
+	s.matchReader(ctx, result, r, limit, false)
+	result.ScanDuration = time.Since(start)
+	s.recordResult(result)
+
+	s.mu.Lock()
+	s.stats.EndTime = time.Now()
+	s.stats.TotalDuration = s.stats.EndTime.Sub(s.stats.StartTime)
+	s.mu.Unlock()
+
+	return result
 }
@@ -0,0 +1,294 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+func TestScanPrunesExcludedDirectories(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, file := range []string{
+		"index.php",
+		filepath.Join("node_modules", "pkg", "index.js"),
+		filepath.Join("wp-content", "cache", "page.php"),
+		filepath.Join("wp-content", "plugins", "plugin.php"),
+	} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("<?php"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	filter, err := NewFilterFromConfig(&FilterConfig{
+		ExcludeDirs:  []string{"node_modules"},
+		ExcludeGlobs: []string{"wp-content/cache/**"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithScanFilter(filter))
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var scanned []string
+	for result := range results {
+		rel, _ := filepath.Rel(dir, result.Path)
+		scanned = append(scanned, filepath.ToSlash(rel))
+	}
+
+	if len(scanned) != 2 {
+		t.Errorf("expected 2 scanned files, got %v", scanned)
+	}
+
+	stats := s.GetStats()
+	if stats.DirsSkipped != 2 {
+		t.Errorf("expected 2 skipped directories, got %d", stats.DirsSkipped)
+	}
+	if stats.FilesSkipped != 0 {
+		t.Errorf("expected pruned files not to be counted as skipped, got %d", stats.FilesSkipped)
+	}
+}
+
+func TestScanWalkLimits(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"index.php", "a/b/c/deep.php", "a/b/shallow.php"}
+	for i := range 5 {
+		files = append(files, fmt.Sprintf("spam/page%d.php", i))
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("<?php"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		scanned     int64
+		skipped     int64
+		dirsSkipped int64
+	}{
+		{"unlimited", nil, 8, 0, 0},
+		{"max depth", []Option{WithMaxDepth(2)}, 7, 0, 1},
+		{"max files per dir", []Option{WithMaxFilesPerDir(2)}, 5, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner(createTestSignatureSet(), tt.opts...)
+			results, err := s.Scan(context.Background(), dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for range results {
+			}
+			stats := s.GetStats()
+			if stats.FilesScanned != tt.scanned || stats.FilesSkipped != tt.skipped || stats.DirsSkipped != tt.dirsSkipped {
+				t.Errorf("expected %d scanned, %d skipped files and %d skipped directories, got %+v", tt.scanned, tt.skipped, tt.dirsSkipped, stats)
+			}
+		})
+	}
+}
+
+func TestScanReader(t *testing.T) {
+	s := NewScanner(createTestSignatureSet())
+
+	result := s.ScanReader(context.Background(), StdinPath, strings.NewReader("<?php eval($_POST['x']);"))
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.Path != StdinPath {
+		t.Errorf("expected path %q, got %q", StdinPath, result.Path)
+	}
+	if !result.HasMatches() {
+		t.Error("expected content to match")
+	}
+
+	stats := s.GetStats()
+	if stats.FilesScanned != 1 || stats.FilesMatched != 1 {
+		t.Errorf("expected 1 scanned and matched file, got %d/%d", stats.FilesScanned, stats.FilesMatched)
+	}
+}
+
+func TestScanMatchAll(t *testing.T) {
+	content := "<?php eval(base64_decode($x)); system($y);"
+
+	first := NewScanner(createTestSignatureSet()).ScanReader(context.Background(), StdinPath, strings.NewReader(content))
+	if len(first.Matches) != 1 || !first.Truncated {
+		t.Errorf("expected one match with matching truncated, got %d, truncated %v", len(first.Matches), first.Truncated)
+	}
+
+	all := NewScanner(createTestSignatureSet(), WithScanMatchAll(true)).ScanReader(context.Background(), StdinPath, strings.NewReader(content))
+	if len(all.Matches) != 3 || all.Truncated {
+		t.Errorf("expected all 3 matches, got %d, truncated %v", len(all.Matches), all.Truncated)
+	}
+
+	clean := NewScanner(createTestSignatureSet()).ScanReader(context.Background(), StdinPath, strings.NewReader("<?php echo 'hello';"))
+	if clean.Truncated {
+		t.Error("expected a clean file not to be truncated")
+	}
+}
+
+func TestScanCategories(t *testing.T) {
+	sigSet := createTestSignatureSet()
+	sigSet.Signatures[1].Category = "backdoor"
+	sigSet.Signatures[3].Category = "shell"
+
+	s := NewScanner(sigSet, WithCategories([]string{"Backdoor"}))
+	content := "<?php eval($x); system('id');"
+
+	result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(content))
+	if len(result.Matches) != 1 || result.Matches[0].SignatureID != 1 {
+		t.Fatalf("expected only signature 1 to match, got %+v", result.Matches)
+	}
+	if result.Matches[0].Category != "backdoor" {
+		t.Errorf("expected category backdoor, got %q", result.Matches[0].Category)
+	}
+	if s.SignatureSet().Count() != sigSet.Count() {
+		t.Error("expected the full signature set to remain available for lookups")
+	}
+}
+
+func TestScanKnownMalwareHash(t *testing.T) {
+	content := "<?php /* dropper */ echo 1;"
+	sum := sha256.Sum256([]byte(content))
+
+	hashes := intel.NewHashSet()
+	if err := hashes.Add(&intel.KnownHash{SHA256: hex.EncodeToString(sum[:]), Name: "Dropper"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithHashSet(hashes))
+	result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(content))
+	if len(result.Matches) != 1 || result.Matches[0].KnownHash == nil {
+		t.Fatalf("expected a single known-hash match, got %+v", result.Matches)
+	}
+	if name, _ := result.Matches[0].Describe(s.SignatureSet()); name != "Dropper" {
+		t.Errorf("expected match name Dropper, got %q", name)
+	}
+
+	result = s.ScanReader(context.Background(), StdinPath, strings.NewReader(content+" "))
+	if result.HasMatches() {
+		t.Error("expected modified content not to match")
+	}
+}
+
+func TestScanTestFile(t *testing.T) {
+	s := NewScanner(intel.NewSignatureSet())
+	result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(string(TestFileContent())))
+	if len(result.Matches) != 1 || result.Matches[0].Category != TestCategory {
+		t.Fatalf("expected the test file to be detected without signatures, got %+v", result.Matches)
+	}
+	if result.Matches[0].Name != "Wordfence test file" {
+		t.Errorf("unexpected match name %q", result.Matches[0].Name)
+	}
+
+	quoted := "<?php $s = '" + TestString + "';"
+	if result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(quoted)); result.HasMatches() {
+		t.Error("expected a file quoting the test string not to match")
+	}
+}
+
+func TestScanResultDescribesMatches(t *testing.T) {
+	s := NewScanner(createTestSignatureSet())
+	result := s.ScanReader(context.Background(), StdinPath, strings.NewReader("<?php system($cmd);"))
+	if len(result.Matches) != 1 {
+		t.Fatalf("expected a single match, got %+v", result.Matches)
+	}
+	match := result.Matches[0]
+	if match.Name != "System Call" || match.Description != "Detects system() calls" {
+		t.Errorf("expected signature metadata in the result, got %q, %q", match.Name, match.Description)
+	}
+
+	// Results written after the signatures are replaced keep their names
+	s.SetSignatures(intel.NewSignatureSet())
+	if name, _ := match.Describe(s.SignatureSet()); name != "System Call" {
+		t.Errorf("expected name to survive a signature refresh, got %q", name)
+	}
+}
+
+func TestRefreshSignatures(t *testing.T) {
+	s := NewScanner(intel.NewSignatureSet())
+
+	content := "<?php system($cmd);"
+	if result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(content)); result.HasMatches() {
+		t.Fatal("expected no matches with an empty signature set")
+	}
+
+	updated := createTestSignatureSet()
+	updated.UpdateTime = 100
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetched := make(chan struct{})
+	go s.RefreshSignatures(ctx, 10*time.Millisecond, func(_ context.Context, current *intel.SignatureSet) (*intel.SignatureSet, error) {
+		if current.UpdateTime >= updated.UpdateTime {
+			return nil, nil
+		}
+		defer close(fetched)
+		return updated, nil
+	})
+
+	select {
+	case <-fetched:
+	case <-time.After(2 * time.Second):
+		t.Fatal("refresh was not called")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.SignatureSet() != updated && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s.SignatureSet() != updated {
+		t.Fatal("expected signature set to be swapped")
+	}
+
+	if result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(content)); !result.HasMatches() {
+		t.Error("expected match after signature refresh")
+	}
+}
+
+func TestScanFileTimeout(t *testing.T) {
+	const signatures = 50
+	s := NewScanner(catastrophicSignatures(signatures),
+		WithSignatureTimeout(10*time.Millisecond),
+		WithFileTimeout(50*time.Millisecond),
+		WithScanLogger(logging.New(logging.LevelCritical)),
+	)
+
+	start := time.Now()
+	result := s.ScanReader(context.Background(), "slow.php", strings.NewReader(strings.Repeat("a", 64)))
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if !result.Incomplete {
+		t.Error("expected matching to stop at the file time limit")
+	}
+	if len(result.Timeouts) == 0 || len(result.Timeouts) == signatures {
+		t.Errorf("expected some but not all signatures to time out, got %d", len(result.Timeouts))
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the file time limit to bound matching, took %v", elapsed)
+	}
+}
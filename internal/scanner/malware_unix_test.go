@@ -0,0 +1,81 @@
+//go:build unix
+
+package scanner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestScanSkipsSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "clean.php"), []byte("<?php echo 1;"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	fifo := filepath.Join(dir, "pipe.php")
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		t.Skipf("mkfifo not supported: %v", err)
+	}
+
+	s := NewScanner(createTestSignatureSet())
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var scanned []string
+	for result := range results {
+		scanned = append(scanned, result.Path)
+	}
+
+	if len(scanned) != 1 || filepath.Base(scanned[0]) != "clean.php" {
+		t.Errorf("expected only clean.php to be scanned, got %v", scanned)
+	}
+	if stats := s.GetStats(); stats.FilesSkipped != 1 {
+		t.Errorf("expected 1 skipped file, got %d", stats.FilesSkipped)
+	}
+	if counts := s.ScanErrors().Counts(); counts[CodeSpecialFile] != 1 {
+		t.Errorf("expected the FIFO to be reported, got %v", counts)
+	}
+
+	// Scanning the FIFO directly must not block
+	result := s.ScanSingleFile(context.Background(), fifo)
+	if !errors.Is(result.Error, ErrSpecialFile) {
+		t.Errorf("expected ErrSpecialFile, got %v", result.Error)
+	}
+}
+
+func TestScanSymlinkLoop(t *testing.T) {
+	dir := t.TempDir()
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "test.php"), []byte("<?php eval($x);"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink(dir, filepath.Join(sub, "loop")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithFollowSymlinks(true))
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	count := 0
+	for range results {
+		count++
+	}
+
+	if count != 1 {
+		t.Errorf("expected 1 scanned file, got %d", count)
+	}
+}
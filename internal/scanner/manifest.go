@@ -0,0 +1,114 @@
+// Package scanner provides scan manifests recording how a scan was run
+package scanner
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+)
+
+// ScanIDInputs are the values GenerateScanID built a scan's ID from
+type ScanIDInputs struct {
+	Started time.Time `json:"started"`
+	Nonce   string    `json:"nonce"`
+}
+
+// ManifestSignatures identifies the signature set a scan matched against
+type ManifestSignatures struct {
+	Count      int    `json:"count"`
+	Hash       string `json:"hash"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// ManifestFeed identifies the vulnerability feed a scan checked against
+type ManifestFeed struct {
+	Count        int    `json:"count"`
+	LatestUpdate string `json:"latest_update,omitempty"`
+}
+
+// ManifestResults summarises what a scan found. Scans with the same digest
+// found exactly the same things.
+type ManifestResults struct {
+	FilesScanned    int64  `json:"files_scanned,omitempty"`
+	FilesMatched    int64  `json:"files_matched,omitempty"`
+	Matches         int    `json:"matches,omitempty"`
+	Vulnerabilities int    `json:"vulnerabilities,omitempty"`
+	Digest          string `json:"digest"`
+}
+
+// ScanManifest records everything needed to audit a scan's findings and
+// run the scan again the same way: the CLI build, the intelligence it used,
+// its settings and flags, and how its ID was generated
+type ScanManifest struct {
+	ScanID            string                 `json:"scan_id"`
+	ScanIDInputs      ScanIDInputs           `json:"scan_id_inputs"`
+	Kind              string                 `json:"kind"`
+	Started           time.Time              `json:"started"`
+	Finished          time.Time              `json:"finished"`
+	Paths             []string               `json:"paths"`
+	CLI               version.BuildInfo      `json:"cli"`
+	Hostname          string                 `json:"hostname,omitempty"`
+	Signatures        *ManifestSignatures    `json:"signatures,omitempty"`
+	VulnerabilityFeed *ManifestFeed          `json:"vulnerability_feed,omitempty"`
+	Config            map[string]interface{} `json:"config,omitempty"`
+	Flags             map[string]string      `json:"flags,omitempty"`
+	Results           ManifestResults        `json:"results"`
+	Output            string                 `json:"output,omitempty"`
+}
+
+// NewScanManifest creates the manifest of a finished scan from its record
+func NewScanManifest(record *ScanRecord, build version.BuildInfo) *ScanManifest {
+	hostname, _ := os.Hostname()
+	return &ScanManifest{
+		ScanID:       record.ID,
+		ScanIDInputs: ScanIDInputs{Started: record.Started, Nonce: record.Nonce},
+		Kind:         record.Kind,
+		Started:      record.Started,
+		Finished:     record.Finished,
+		Paths:        record.Paths,
+		CLI:          build,
+		Hostname:     hostname,
+		Results: ManifestResults{
+			FilesScanned:    record.FilesScanned,
+			FilesMatched:    record.FilesMatched,
+			Matches:         record.Matches,
+			Vulnerabilities: record.Vulnerabilities,
+			Digest:          record.Digest,
+		},
+	}
+}
+
+// SetSignatures records the signature set the scan used
+func (m *ScanManifest) SetSignatures(sigSet *intel.SignatureSet) {
+	m.Signatures = &ManifestSignatures{
+		Count:      sigSet.Count(),
+		Hash:       hex.EncodeToString(sigSet.GetHash()),
+		UpdateTime: sigSet.UpdateTime,
+	}
+}
+
+// SetVulnerabilityFeed records the vulnerability feed the scan used
+func (m *ScanManifest) SetVulnerabilityFeed(index *intel.VulnerabilityIndex) {
+	m.VulnerabilityFeed = &ManifestFeed{
+		Count:        index.Count(),
+		LatestUpdate: index.LatestUpdate(),
+	}
+}
+
+// Write writes the manifest as indented JSON
+func (m *ScanManifest) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding scan manifest: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing scan manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+)
+
+func TestScanManifest(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	record := NewScanRecord(ScanKindMalware, []string{"/var/www"}, start)
+	record.AddScanResult(&ScanResult{Path: "/var/www/a.php", Matches: []*MatchResult{{SignatureID: 1}}}, []string{"Eval Pattern"})
+	record.FilesScanned = 10
+	record.Finish(start.Add(time.Minute))
+
+	sigSet := intel.NewSignatureSet()
+	sigSet.Signatures[1] = intel.NewSignature(1, "eval", "Eval Pattern", "", nil)
+	sigSet.UpdateTime = 1700000000
+
+	m := NewScanManifest(record, version.BuildInfo{Version: "1.2.3"})
+	m.SetSignatures(sigSet)
+
+	if got := GenerateScanID(m.ScanIDInputs.Started, m.ScanIDInputs.Nonce); got != m.ScanID {
+		t.Errorf("expected the ID inputs to regenerate %s, got %s", m.ScanID, got)
+	}
+	if m.Results.Digest != record.Digest || m.Results.FilesScanned != 10 || m.Results.Matches != 1 {
+		t.Errorf("unexpected results %+v", m.Results)
+	}
+	if m.Signatures.Count != 1 || m.Signatures.Hash != hex.EncodeToString(sigSet.GetHash()) || m.Signatures.UpdateTime != 1700000000 {
+		t.Errorf("unexpected signatures %+v", m.Signatures)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("manifest is not JSON: %v", err)
+	}
+	if _, ok := decoded["vulnerability_feed"]; ok {
+		t.Error("expected no vulnerability feed in a malware scan manifest")
+	}
+	if cli, ok := decoded["cli"].(map[string]interface{}); !ok || cli["version"] != "1.2.3" {
+		t.Errorf("unexpected cli %v", decoded["cli"])
+	}
+}
@@ -2,11 +2,13 @@
 package scanner
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/dlclark/regexp2"
 
@@ -17,6 +19,14 @@ import (
 // DefaultMatchTimeout is the default timeout for pattern matching
 const DefaultMatchTimeout = 1 * time.Second
 
+// DefaultHintSlack is how many characters either side of a signature's
+// common strings are searched for a match
+const DefaultHintSlack = 32 << 10
+
+// SlowMatchThreshold is how long a signature may take on a file before it
+// is reported as slow, by default
+const SlowMatchThreshold = 100 * time.Millisecond
+
 // ErrMatchTimeout indicates a pattern match timed out
 type ErrMatchTimeout struct {
 	SignatureID int
@@ -26,11 +36,88 @@ func (e *ErrMatchTimeout) Error() string {
 	return fmt.Sprintf("match timeout for signature %d", e.SignatureID)
 }
 
+// isMatchTimeout reports whether err is regexp2's timeout error. regexp2
+// has no error type for it and its message ends with the whole input, so
+// only the message's fixed prefix is checked.
+func isMatchTimeout(err error) bool {
+	return strings.HasPrefix(err.Error(), "match timeout after ")
+}
+
 // MatchResult represents a successful pattern match
 type MatchResult struct {
 	SignatureID   int
+	Category      string
+	Type          int
 	MatchedString string
 	Position      int
+
+	// Name and Description describe what matched. They are joined in when
+	// the file is scanned, so they stay correct if the signature set is
+	// replaced before the result is written.
+	Name        string
+	Description string
+
+	// KnownHash is set when the whole file matched the malware hash
+	// blocklist instead of a signature; SignatureID is then 0
+	KnownHash *intel.KnownHash
+
+	// IOC is set when MatchedString is an extracted domain or IP address
+	// found on an indicator list; SignatureID is then 0
+	IOC *intel.IOC
+
+	// Nulled is set when MatchedString is a footprint of pirated/nulled
+	// software; SignatureID is then 0
+	Nulled *NulledRule
+}
+
+// Describe returns the name and description of what matched. Results
+// without them joined in look signatures up in sigSet; both are empty for
+// unknown signatures.
+func (r *MatchResult) Describe(sigSet *intel.SignatureSet) (string, string) {
+	if r.Name != "" {
+		return r.Name, r.Description
+	}
+
+	if r.KnownHash != nil {
+		name := r.KnownHash.Name
+		if name == "" {
+			name = "Known malware file"
+		}
+		return name, "File hash matches known malware (SHA256 " + r.KnownHash.SHA256 + ")"
+	}
+
+	if r.IOC != nil {
+		name := "File contacts known malicious domain"
+		if r.IOC.Kind != intel.IOCDomain {
+			name = "File contacts known malicious IP address"
+		}
+		desc := fmt.Sprintf("%s matches IOC %s", r.MatchedString, r.IOC.Value)
+		if r.IOC.Description != "" {
+			desc += " (" + r.IOC.Description + ")"
+		}
+		return name, desc
+	}
+
+	if r.Category == TestCategory {
+		return "Wordfence test file", "harmless file for testing that detection and alerting work"
+	}
+
+	if r.Category == EmbeddedPHPCategory {
+		return "PHP code in a non-PHP file", fmt.Sprintf("contains a PHP open tag at byte %d, so it runs as PHP if included", r.Position)
+	}
+
+	if r.Nulled != nil {
+		return "Pirated/nulled software – high risk", fmt.Sprintf("%s: %s", r.Nulled.Name, r.Nulled.Description)
+	}
+
+	if sigSet == nil {
+		return "", ""
+	}
+	sig, err := sigSet.GetSignature(r.SignatureID)
+	if err != nil {
+		return "", ""
+	}
+	return sig.Name, sig.Description
 }
 
 // CompiledPattern represents a compiled regex pattern
@@ -45,12 +132,19 @@ type CompiledSignature struct {
 	Pattern       *CompiledPattern
 	AnchoredStart bool
 	CompileError  error
+
+	gateError error         // Why the signature can't be gated, if it can't
+	window    *searchWindow // Where in content its matches can be, if known
+	hintable  bool          // Whether it can be searched for around its common strings
 }
 
-// CompiledCommonString represents a common string with a compiled pattern
+// CompiledCommonString represents a common string prepared for searching.
+// Common strings only narrow down which signatures are tried, so they are
+// matched ignoring ASCII case: most signatures are case-insensitive, and a
+// case-sensitive prefilter would let mixed-case payloads skip them.
 type CompiledCommonString struct {
 	CommonString *intel.CommonString
-	Pattern      *CompiledPattern
+	Folded       []byte
 }
 
 // Matcher compiles and matches signatures against content
@@ -58,8 +152,13 @@ type Matcher struct {
 	signatures      map[int]*CompiledSignature
 	commonStrings   []*CompiledCommonString
 	noCommonStrSigs []*CompiledSignature // Signatures without common strings
+	sets            []*signatureSet      // Gates over noCommonStrSigs
+	ungrouped       []*CompiledSignature // noCommonStrSigs that can't be gated
 	timeout         time.Duration
+	slowThreshold   time.Duration
+	hintSlack       int
 	matchAll        bool
+	engine          string
 	logger          *logging.Logger
 	mu              sync.RWMutex
 	prepared        bool
@@ -75,6 +174,23 @@ func WithMatchTimeout(timeout time.Duration) MatcherOption {
 	}
 }
 
+// WithSlowMatchThreshold sets how long a signature may take on a file
+// before it is reported as slow
+func WithSlowMatchThreshold(threshold time.Duration) MatcherOption {
+	return func(m *Matcher) {
+		m.slowThreshold = threshold
+	}
+}
+
+// WithHintSlack sets how many characters either side of a signature's
+// common strings are searched for a match (0 searches the whole content).
+// Matches reaching further than that from their common strings are missed.
+func WithHintSlack(slack int) MatcherOption {
+	return func(m *Matcher) {
+		m.hintSlack = slack
+	}
+}
+
 // WithMatchAll configures whether to find all matches or stop at first
 func WithMatchAll(matchAll bool) MatcherOption {
 	return func(m *Matcher) {
@@ -82,6 +198,16 @@ func WithMatchAll(matchAll bool) MatcherOption {
 	}
 }
 
+// WithRegexEngine sets the engine signatures are matched with, one of
+// RegexEngines
+func WithRegexEngine(engine string) MatcherOption {
+	return func(m *Matcher) {
+		if engine != "" {
+			m.engine = engine
+		}
+	}
+}
+
 // WithMatcherLogger sets the logger
 func WithMatcherLogger(logger *logging.Logger) MatcherOption {
 	return func(m *Matcher) {
@@ -95,7 +221,10 @@ func NewMatcher(sigSet *intel.SignatureSet, opts ...MatcherOption) *Matcher {
 		signatures:    make(map[int]*CompiledSignature),
 		commonStrings: make([]*CompiledCommonString, 0),
 		timeout:       DefaultMatchTimeout,
+		slowThreshold: SlowMatchThreshold,
+		hintSlack:     DefaultHintSlack,
 		matchAll:      false,
+		engine:        RegexEngineAuto,
 		logger:        logging.New(logging.LevelInfo),
 	}
 
@@ -114,20 +243,12 @@ func (m *Matcher) compileSignatures(sigSet *intel.SignatureSet) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Compile common strings
+	// Prepare common strings
 	for _, cs := range sigSet.CommonStrings {
-		compiled := &CompiledCommonString{
+		m.commonStrings = append(m.commonStrings, &CompiledCommonString{
 			CommonString: cs,
-		}
-
-		pattern, err := m.compilePattern(regexp2.Escape(cs.String))
-		if err != nil {
-			m.logger.Debug("Failed to compile common string pattern: %v", err)
-		} else {
-			compiled.Pattern = pattern
-		}
-
-		m.commonStrings = append(m.commonStrings, compiled)
+			Folded:       foldASCII([]byte(cs.String)),
+		})
 	}
 
 	// Compile signatures
@@ -135,6 +256,8 @@ func (m *Matcher) compileSignatures(sigSet *intel.SignatureSet) {
 		compiled := &CompiledSignature{
 			Signature:     sig,
 			AnchoredStart: strings.HasPrefix(sig.Rule, "^"),
+			window:        analyzeRule(sig.Rule),
+			hintable:      sig.HasCommonStrings() && hintableRule(sig.Rule),
 		}
 
 		pattern, err := m.compilePattern(sig.Rule)
@@ -153,6 +276,16 @@ func (m *Matcher) compileSignatures(sigSet *intel.SignatureSet) {
 		}
 	}
 
+	// Signatures without common strings are tried on every file, so gate
+	// them in groups with a single linear-time pass each
+	if m.engine == RegexEngineRegexp2 {
+		m.ungrouped = m.noCommonStrSigs
+	} else {
+		m.sets, m.ungrouped = buildSignatureSets(m.noCommonStrSigs, m.engine)
+		m.logger.Debug("Grouped %d signatures into %d sets, %d ungrouped",
+			len(m.noCommonStrSigs)-len(m.ungrouped), len(m.sets), len(m.ungrouped))
+	}
+
 	m.prepared = true
 }
 
@@ -180,8 +313,19 @@ type MatchContext struct {
 	matcher            *Matcher
 	matches            map[int]*MatchResult
 	timeouts           map[int]bool
+	slow               map[int]time.Duration
 	commonStringStates []bool
+	truncated          bool
 	mu                 sync.Mutex
+
+	// The chunk being matched, as regexp2 reads it and with ASCII
+	// lowercased, each converted once when first needed
+	chunk  []byte
+	runes  []rune
+	folded []byte
+
+	// Where the common strings of signatures that may match are in the chunk
+	hints map[int]commonStringHint
 }
 
 // NewMatchContext creates a new match context
@@ -190,6 +334,7 @@ func (m *Matcher) NewMatchContext() *MatchContext {
 		matcher:            m,
 		matches:            make(map[int]*MatchResult),
 		timeouts:           make(map[int]bool),
+		slow:               make(map[int]time.Duration),
 		commonStringStates: make([]bool, len(m.commonStrings)),
 	}
 }
@@ -199,50 +344,92 @@ func (mc *MatchContext) Match(ctx context.Context, content []byte) error {
 	return mc.MatchChunk(ctx, content, true)
 }
 
-// MatchChunk matches a chunk of content
+// MatchChunk matches a chunk of content. Each signature's match is bounded
+// by the matcher's timeout, and ctx is checked between signatures, so a
+// deadline on ctx bounds the whole chunk to within one match timeout.
 func (mc *MatchContext) MatchChunk(ctx context.Context, content []byte, isStart bool) error {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 
-	contentStr := string(content)
+	mc.chunk = content
+	defer func() { mc.chunk, mc.runes, mc.folded, mc.hints = nil, nil, nil, nil }()
 
 	// Check common strings first to narrow down possible signatures
-	possibleSigs := mc.checkCommonStrings(contentStr)
+	possibleSigs, err := mc.checkCommonStrings(ctx, content)
+	if err != nil {
+		return err
+	}
 
-	// Match signatures without common strings
-	for _, sig := range mc.matcher.noCommonStrSigs {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("context cancelled: %w", ctx.Err())
-		default:
+	// Match signatures without common strings, skipping sets that can't
+	// match this content
+	if !canGate(content, mc.matcher.engine) {
+		if done, err := mc.matchSignatures(ctx, mc.matcher.noCommonStrSigs, isStart); done || err != nil {
+			return err
 		}
-
-		if mc.matchSignature(sig, contentStr, isStart) && !mc.matcher.matchAll {
-			return nil
+	} else {
+		if done, err := mc.matchSignatures(ctx, mc.matcher.ungrouped, isStart); done || err != nil {
+			return err
+		}
+		for _, set := range mc.matcher.sets {
+			if !set.mayMatch(content) {
+				continue
+			}
+			if done, err := mc.matchSignatures(ctx, set.signatures, isStart); done || err != nil {
+				return err
+			}
 		}
 	}
 
 	// Match possible signatures (those whose common strings all matched)
-	for _, sig := range possibleSigs {
+	_, err = mc.matchSignatures(ctx, possibleSigs, isStart)
+	return err
+}
+
+// matchSignatures matches each of sigs in turn, reporting whether matching
+// is done because one matched and the matcher stops at the first match
+func (mc *MatchContext) matchSignatures(ctx context.Context, sigs []*CompiledSignature, isStart bool) (bool, error) {
+	for _, sig := range sigs {
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("context cancelled: %w", ctx.Err())
+			return false, fmt.Errorf("context cancelled: %w", ctx.Err())
 		default:
 		}
 
-		if mc.matchSignature(sig, contentStr, isStart) && !mc.matcher.matchAll {
-			return nil
+		if mc.matchSignature(sig, isStart) && !mc.matcher.matchAll {
+			mc.truncated = true
+			return true, nil
 		}
 	}
-
-	return nil
+	return false, nil
 }
 
-// checkCommonStrings checks which common strings match and returns possible signatures
-func (mc *MatchContext) checkCommonStrings(content string) []*CompiledSignature {
+// checkCommonStrings checks which common strings match and returns possible
+// signatures, noting where their common strings are in the chunk when all
+// of them are in it
+func (mc *MatchContext) checkCommonStrings(ctx context.Context, content []byte) ([]*CompiledSignature, error) {
 	commonStringCounts := make(map[int]int)
 
+	// Byte offsets of the common strings found in this chunk. Strings found
+	// in earlier chunks aren't searched for again, so signatures with any
+	// of those get no hint. Nor do any in content with letters regexp2
+	// folds to ASCII, as their matches may not contain the folded strings.
+	type located struct {
+		count, first, end int
+	}
+	var locations map[int]*located
+	if mc.matcher.hintSlack > 0 && !bytes.Contains(content, dottedCapitalI) && !bytes.Contains(content, kelvinSign) {
+		locations = make(map[int]*located)
+	}
+
+	var folded []byte
+	if len(mc.matcher.commonStrings) > 0 {
+		folded = mc.foldedChunk()
+	}
+
 	for idx, cs := range mc.matcher.commonStrings {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("context cancelled: %w", err)
+		}
 		if mc.commonStringStates[idx] {
 			// Already matched
 			for _, sigID := range cs.CommonString.SignatureIDs {
@@ -253,21 +440,25 @@ func (mc *MatchContext) checkCommonStrings(content string) []*CompiledSignature
 			continue
 		}
 
-		if cs.Pattern == nil {
-			continue
-		}
-
-		match, err := cs.Pattern.Pattern.FindStringMatch(content)
-		if err != nil {
-			mc.matcher.logger.Debug("Common string match error: %v", err)
-			continue
-		}
-
-		if match != nil {
+		if first := bytes.Index(folded, cs.Folded); first >= 0 {
 			mc.commonStringStates[idx] = true
+			end := first + len(cs.Folded)
+			if locations != nil {
+				end = bytes.LastIndex(folded, cs.Folded) + len(cs.Folded)
+			}
 			for _, sigID := range cs.CommonString.SignatureIDs {
 				if _, ok := mc.matches[sigID]; !ok {
 					commonStringCounts[sigID]++
+					if locations == nil {
+						continue
+					}
+					if loc, ok := locations[sigID]; ok {
+						loc.count++
+						loc.first = min(loc.first, first)
+						loc.end = max(loc.end, end)
+					} else {
+						locations[sigID] = &located{count: 1, first: first, end: end}
+					}
 				}
 			}
 		}
@@ -280,16 +471,56 @@ func (mc *MatchContext) checkCommonStrings(content string) []*CompiledSignature
 		if !ok {
 			continue
 		}
-		if count == sig.Signature.GetCommonStringCount() {
-			possibleSigs = append(possibleSigs, sig)
+		if count != sig.Signature.GetCommonStringCount() {
+			continue
+		}
+		possibleSigs = append(possibleSigs, sig)
+		if loc, ok := locations[sigID]; ok && loc.count == count && sig.hintable {
+			if mc.hints == nil {
+				mc.hints = make(map[int]commonStringHint)
+			}
+			mc.hints[sigID] = commonStringHint{
+				first: utf8.RuneCount(content[:loc.first]),
+				end:   utf8.RuneCount(content[:loc.end]),
+			}
 		}
 	}
 
-	return possibleSigs
+	return possibleSigs, nil
 }
 
-// matchSignature attempts to match a single signature
-func (mc *MatchContext) matchSignature(sig *CompiledSignature, content string, isStart bool) bool {
+// foldASCII returns a copy of b with ASCII letters lowercased. Other bytes,
+// including invalid UTF-8, are kept as they are, so binary content and
+// common strings compare byte for byte.
+func foldASCII(b []byte) []byte {
+	folded := make([]byte, len(b))
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		folded[i] = c
+	}
+	return folded
+}
+
+// chunkRunes returns the chunk as regexp2 reads it
+func (mc *MatchContext) chunkRunes() []rune {
+	if mc.runes == nil {
+		mc.runes = []rune(string(mc.chunk))
+	}
+	return mc.runes
+}
+
+// foldedChunk returns the chunk with ASCII letters lowercased
+func (mc *MatchContext) foldedChunk() []byte {
+	if mc.folded == nil {
+		mc.folded = foldASCII(mc.chunk)
+	}
+	return mc.folded
+}
+
+// matchSignature attempts to match a single signature in the chunk
+func (mc *MatchContext) matchSignature(sig *CompiledSignature, isStart bool) bool {
 	if sig.Pattern == nil {
 		return false
 	}
@@ -304,21 +535,60 @@ func (mc *MatchContext) matchSignature(sig *CompiledSignature, content string, i
 		return false
 	}
 
-	match, err := sig.Pattern.Pattern.FindStringMatch(content)
+	// Skip content the signature can't match, and start searching where
+	// its first match can start
+	startAt := 0
+	if sig.window != nil {
+		var folded []byte
+		if sig.window.fold {
+			folded = mc.foldedChunk()
+		}
+		var ok bool
+		if startAt, ok = sig.window.start(mc.chunk, folded); !ok {
+			return false
+		}
+	}
+
+	// Search only around the common strings when they were found
+	runes := mc.chunkRunes()
+	end := len(runes)
+	if hint, ok := mc.hints[sig.Signature.ID]; ok {
+		var first int
+		first, end = hint.bounds(mc.matcher.hintSlack, len(runes))
+		startAt = max(startAt, first)
+	}
+	if startAt > end {
+		return false
+	}
+
+	start := time.Now()
+	match, err := sig.Pattern.Pattern.FindRunesMatchStartingAt(runes[:end], startAt)
+	if match != nil && end < len(runes) {
+		// $ and lookaheads took the end of the window for the end of the
+		// chunk, so confirm the match against all of it
+		match, err = sig.Pattern.Pattern.FindRunesMatchStartingAt(runes, startAt)
+	}
+	if elapsed := time.Since(start); elapsed >= mc.matcher.slowThreshold {
+		mc.slow[sig.Signature.ID] += elapsed
+	}
 	if err != nil {
-		// Check for timeout
-		if strings.Contains(err.Error(), "timeout") {
+		if isMatchTimeout(err) {
 			mc.timeouts[sig.Signature.ID] = true
+		} else {
+			mc.matcher.logger.Debug("Signature %d match error: %v", sig.Signature.ID, err)
 		}
-		mc.matcher.logger.Debug("Signature %d match error: %v", sig.Signature.ID, err)
 		return false
 	}
 
 	if match != nil {
 		mc.matches[sig.Signature.ID] = &MatchResult{
 			SignatureID:   sig.Signature.ID,
+			Category:      sig.Signature.Category,
+			Type:          sig.Signature.Type,
 			MatchedString: match.String(),
 			Position:      match.Index,
+			Name:          sig.Signature.Name,
+			Description:   sig.Signature.Description,
 		}
 		return true
 	}
@@ -350,6 +620,27 @@ func (mc *MatchContext) GetTimeouts() []int {
 	return timeouts
 }
 
+// GetSlowSignatures returns how long each signature that took at least
+// the matcher's slow threshold spent matching
+func (mc *MatchContext) GetSlowSignatures() map[int]time.Duration {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	slow := make(map[int]time.Duration, len(mc.slow))
+	for sigID, d := range mc.slow {
+		slow[sigID] = d
+	}
+	return slow
+}
+
+// Truncated reports whether matching stopped at the first match, so some
+// signatures may not have been checked
+func (mc *MatchContext) Truncated() bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.truncated
+}
+
 // HasMatches returns true if any matches were found
 func (mc *MatchContext) HasMatches() bool {
 	mc.mu.Lock()
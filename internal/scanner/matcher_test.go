@@ -3,6 +3,7 @@ package scanner
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -238,6 +239,140 @@ func TestErrMatchTimeout(t *testing.T) {
 	}
 }
 
+func TestCommonStringsIgnoreCase(t *testing.T) {
+	ss := intel.NewSignatureSet()
+	ss.CommonStrings = append(ss.CommonStrings, intel.NewCommonString("gzinflate"), intel.NewCommonString("eval"))
+	ss.Signatures[1] = intel.NewSignature(1, `(?i)eval\s*\(\s*gzinflate`, "Packed Eval", "Evaluates compressed code", []int{0, 1})
+	ss.Signatures[2] = intel.NewSignature(2, `eval\(`, "Lowercase Eval", "Case-sensitive eval", []int{1})
+	ss.CommonStrings[0].SignatureIDs = []int{1}
+	ss.CommonStrings[1].SignatureIDs = []int{1, 2}
+	m := NewMatcher(ss, WithMatchAll(true))
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    []int
+	}{
+		{"mixed case", []byte(`<?php EvAl( GzInFlAtE(base64_decode($x)));`), []int{1}},
+		{"lowercase", []byte(`<?php eval(gzinflate($x));`), []int{1, 2}},
+		{"binary", append([]byte{0xff, 0xfe, 0x00, 0xc3}, []byte("EVAL(GZINFLATE(\x00\xff")...), []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := m.NewMatchContext()
+			if err := mc.Match(context.Background(), tt.content); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make(map[int]bool)
+			for _, match := range mc.GetMatches() {
+				got[match.SignatureID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("expected signatures %v, got %v", tt.want, got)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("expected signature %d to match", id)
+				}
+			}
+		})
+	}
+}
+
+func TestCommonStringHints(t *testing.T) {
+	padding := strings.Repeat("x", 1000)
+	tests := []struct {
+		name    string
+		rule    string
+		strings []string
+		content string
+		slack   int
+		want    int // position of the match, -1 for none
+	}{
+		{"near common strings", `eval\(.{0,20}gzinflate`, []string{"eval", "gzinflate"}, padding + "eval(gzinflate($x))" + padding, 16, 1000},
+		{"beyond the slack", `eval\(.*marker`, []string{"eval"}, "eval(" + padding + "marker", 16, -1},
+		{"no slack", `eval\(.*marker`, []string{"eval"}, "eval(" + padding + "marker", 0, 0},
+		{"lookahead past the window", `evalx?(?!x)`, []string{"eval"}, "eval" + padding, 1, -1},
+		{"kelvin sign", `(?i)kill\(`, []string{"kill"}, "\u212aill(" + padding + "kill", 16, 0},
+		{"search start", `\G.{0,2000}?eval`, []string{"eval"}, padding + "eval", 16, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := intel.NewSignatureSet()
+			var ids []int
+			for i, str := range tt.strings {
+				cs := intel.NewCommonString(str)
+				cs.SignatureIDs = []int{1}
+				ss.CommonStrings = append(ss.CommonStrings, cs)
+				ids = append(ids, i)
+			}
+			ss.Signatures[1] = intel.NewSignature(1, tt.rule, "Hinted", "", ids)
+
+			mc := NewMatcher(ss, WithHintSlack(tt.slack)).NewMatchContext()
+			if err := mc.Match(context.Background(), []byte(tt.content)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			matches := mc.GetMatches()
+			switch {
+			case tt.want < 0 && len(matches) != 0:
+				t.Errorf("expected no match, got %q at %d", matches[0].MatchedString, matches[0].Position)
+			case tt.want >= 0 && len(matches) == 0:
+				t.Errorf("expected a match at %d", tt.want)
+			case tt.want >= 0 && matches[0].Position != tt.want:
+				t.Errorf("expected a match at %d, got %d", tt.want, matches[0].Position)
+			}
+		})
+	}
+}
+
+func TestFoldASCII(t *testing.T) {
+	in := []byte("AbC\xffZ\x00é")
+	if got := foldASCII(in); string(got) != "abc\xffz\x00é" {
+		t.Errorf("unexpected fold %q", got)
+	}
+	if string(in) != "AbC\xffZ\x00é" {
+		t.Error("expected input to be left unchanged")
+	}
+}
+
+// catastrophicSignatures returns signatures that backtrack exponentially
+// on a long run of "a" not followed by "b". The lookahead keeps them out of
+// signature sets, whose gate would otherwise skip them.
+func catastrophicSignatures(n int) *intel.SignatureSet {
+	ss := intel.NewSignatureSet()
+	for id := 1; id <= n; id++ {
+		ss.Signatures[id] = intel.NewSignature(id, `(a+)+(?=b)`, "Backtracking", "Catastrophic backtracking", nil)
+	}
+	return ss
+}
+
+func TestMatchContextTimeout(t *testing.T) {
+	// A signature reaching the timeout is slow whatever regexp2's timeout
+	// clock overshoots by
+	m := NewMatcher(catastrophicSignatures(1), WithMatchTimeout(10*time.Millisecond), WithSlowMatchThreshold(time.Millisecond))
+	mc := m.NewMatchContext()
+
+	// The word "timeout" in the content must not be mistaken for one
+	content := []byte("timeout " + strings.Repeat("a", 64))
+	start := time.Now()
+	if err := mc.Match(context.Background(), content); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected match to stop at the timeout, took %v", elapsed)
+	}
+
+	if timeouts := mc.GetTimeouts(); len(timeouts) != 1 || timeouts[0] != 1 {
+		t.Errorf("expected signature 1 to time out, got %v", timeouts)
+	}
+	if slow := mc.GetSlowSignatures(); slow[1] == 0 {
+		t.Errorf("expected signature 1 reported as slow, got %v", slow)
+	}
+	if mc.HasMatches() {
+		t.Error("expected no matches")
+	}
+}
+
 func TestMatcherComplexPatterns(t *testing.T) {
 	ss := intel.NewSignatureSet()
 
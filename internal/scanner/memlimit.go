@@ -0,0 +1,128 @@
+// Package scanner provides a guard keeping a scan under its memory limit
+package scanner
+
+import (
+	"context"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+const (
+	// memoryCheckInterval is how often the memory guard checks the
+	// process's memory
+	memoryCheckInterval = time.Second
+	// shrinkAbove and growBelow are the shares of the memory limit, in
+	// percent, above which the read budget is halved and below which it
+	// is doubled back towards its size
+	shrinkAbove = 80
+	growBelow   = 60
+	// minReadBudget is the least the read budget shrinks to
+	minReadBudget = 4 << 20
+)
+
+// MemoryState describes how a scan with a memory limit kept under it
+type MemoryState struct {
+	Limit int64 `json:"limit"`
+	// Peak is the most memory the process held from the system, as
+	// counted by the Go memory limit
+	Peak int64 `json:"peak"`
+	// ReadMemory is the read budget now, and MinReadMemory the smallest
+	// it was shrunk to
+	ReadMemory    int64 `json:"read_memory"`
+	MinReadMemory int64 `json:"min_read_memory"`
+	Shrinks       int64 `json:"shrinks"`
+}
+
+// memoryGuard shrinks the read budget while the process nears its memory
+// limit, so less file content is held at once, and grows it back once
+// there is room again
+type memoryGuard struct {
+	mu     sync.Mutex
+	budget *byteBudget
+	size   int64
+	usage  func() int64
+	state  MemoryState
+	// pool's idle buffers are freed when the read budget shrinks
+	pool *readPool
+}
+
+// newMemoryGuard keeps a scan under limit bytes, starting the read budget
+// at no more than half of it
+func newMemoryGuard(limit int64, budget *byteBudget, size int64) *memoryGuard {
+	size = min(size, limit/2)
+	budget.resize(size)
+	return &memoryGuard{
+		budget: budget,
+		size:   size,
+		usage:  processMemory,
+		state:  MemoryState{Limit: limit, ReadMemory: size, MinReadMemory: size},
+	}
+}
+
+// run checks the process's memory every interval until ctx is done
+func (g *memoryGuard) run(ctx context.Context) {
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.check()
+		}
+	}
+}
+
+// check resizes the read budget for the memory in use
+func (g *memoryGuard) check() {
+	used := g.usage()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.state.Peak = max(g.state.Peak, used)
+	read := g.state.ReadMemory
+	switch {
+	case used > g.state.Limit/100*shrinkAbove:
+		read = max(read/2, min(minReadBudget, g.size))
+	case used < g.state.Limit/100*growBelow:
+		read = min(read*2, g.size)
+	}
+	if read == g.state.ReadMemory {
+		return
+	}
+	if read < g.state.ReadMemory {
+		g.state.Shrinks++
+		g.pool.drain()
+	}
+	g.state.ReadMemory = read
+	g.state.MinReadMemory = min(g.state.MinReadMemory, read)
+	g.budget.resize(read)
+}
+
+// states returns how the scan kept under its limit so far
+func (g *memoryGuard) states() MemoryState {
+	used := g.usage()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.state.Peak = max(g.state.Peak, used)
+	return g.state
+}
+
+// reset restores the read budget, for a new scan
+func (g *memoryGuard) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.state = MemoryState{Limit: g.state.Limit, ReadMemory: g.size, MinReadMemory: g.size}
+	g.budget.resize(g.size)
+}
+
+// processMemory returns the memory the process holds from the system, as
+// the Go memory limit counts it
+func processMemory() int64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	return int64(samples[0].Value.Uint64() - samples[1].Value.Uint64()) // #nosec G115 -- bounded by the address space
+}
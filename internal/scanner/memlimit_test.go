@@ -0,0 +1,48 @@
+package scanner
+
+import (
+	"testing"
+)
+
+func TestMemoryGuard(t *testing.T) {
+	budget := newByteBudget(256 << 20)
+	g := newMemoryGuard(100<<20, budget, 256<<20)
+	if g.size != 50<<20 || budget.size != 50<<20 {
+		t.Fatalf("expected the budget to start at half the limit, got %d", budget.size)
+	}
+
+	used := int64(90 << 20)
+	g.usage = func() int64 { return used }
+	for i := 0; i < 5; i++ {
+		g.check()
+	}
+	state := g.states()
+	if budget.size != minReadBudget || state.ReadMemory != minReadBudget || state.Shrinks != 4 || state.Peak != used {
+		t.Errorf("expected the budget halved down to %d near the limit, got %d and %+v", minReadBudget, budget.size, state)
+	}
+
+	used = 70 << 20
+	g.check()
+	if budget.size != minReadBudget {
+		t.Errorf("expected the budget kept between the thresholds, got %d", budget.size)
+	}
+
+	used = 10 << 20
+	for i := 0; i < 10; i++ {
+		g.check()
+	}
+	if state := g.states(); budget.size != 50<<20 || state.MinReadMemory != minReadBudget {
+		t.Errorf("expected the budget grown back to its size, got %d and %+v", budget.size, state)
+	}
+
+	g.reset()
+	if state := g.states(); state.Shrinks != 0 || state.ReadMemory != 50<<20 {
+		t.Errorf("unexpected state after reset %+v", state)
+	}
+}
+
+func TestProcessMemory(t *testing.T) {
+	if n := processMemory(); n <= 0 {
+		t.Errorf("expected the process to hold memory, got %d", n)
+	}
+}
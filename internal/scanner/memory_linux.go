@@ -0,0 +1,80 @@
+//go:build linux
+
+// Package scanner provides the memory available to a scan on Linux, within
+// its cgroup
+package scanner
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cgroupUnlimited is the v1 memory limit above which a cgroup is taken to
+// have none, since v1 reports "no limit" as a huge page-aligned number
+const cgroupUnlimited = 1 << 60
+
+// availableMemory returns the bytes of memory free for the scan: the
+// system's MemAvailable, or less if a cgroup limit leaves less room. It
+// returns 0 if neither can be read
+func availableMemory() int64 {
+	available := meminfoAvailable()
+	if room := cgroupRoom(); room > 0 && (available == 0 || room < available) {
+		available = room
+	}
+	return available
+}
+
+// meminfoAvailable returns MemAvailable from /proc/meminfo in bytes
+func meminfoAvailable() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = file.Close() }()
+
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		rest, ok := strings.CutPrefix(lines.Text(), "MemAvailable:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb << 10
+	}
+	return 0
+}
+
+// cgroupRoom returns how far the process's cgroup is below its memory
+// limit, from cgroup v2 or else v1, or 0 if it has no limit
+func cgroupRoom() int64 {
+	for _, files := range [][2]string{
+		{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory.current"},
+		{"/sys/fs/cgroup/memory/memory.limit_in_bytes", "/sys/fs/cgroup/memory/memory.usage_in_bytes"},
+	} {
+		limit := readCgroupValue(files[0])
+		if limit <= 0 || limit >= cgroupUnlimited {
+			continue
+		}
+		return max(limit-readCgroupValue(files[1]), 0)
+	}
+	return 0
+}
+
+// readCgroupValue reads a cgroup file holding one number, returning 0 for
+// "max" or anything unreadable
+func readCgroupValue(path string) int64 {
+	data, err := os.ReadFile(path) // #nosec G304 -- fixed cgroup paths
+	if err != nil {
+		return 0
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+// Package scanner provides the memory available to a scan on platforms
+// where it isn't read
+package scanner
+
+// availableMemory returns 0 on platforms where free memory isn't read
+func availableMemory() int64 {
+	return 0
+}
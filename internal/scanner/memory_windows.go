@@ -0,0 +1,39 @@
+//go:build windows
+
+// Package scanner provides the memory available to a scan on Windows
+package scanner
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGlobalMemoryStatusEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+// memoryStatusEx is MEMORYSTATUSEX
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// availableMemory returns the physical memory available in bytes, or 0 if
+// it can't be read
+func availableMemory() int64 {
+	status := memoryStatusEx{Length: uint32(unsafe.Sizeof(memoryStatusEx{}))}
+	if procGlobalMemoryStatusEx.Find() != nil {
+		return 0
+	}
+	ok, _, _ := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status))) // #nosec G103 -- Win32 call filling in status
+	if ok == 0 {
+		return 0
+	}
+	return int64(status.AvailPhys) // #nosec G115 -- bounded by the physical memory
+}
@@ -0,0 +1,205 @@
+// Package scanner provides a monitor that throttles a scan while the host
+// is under pressure
+package scanner
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultMonitorInterval is how often an adaptive scan samples the host
+const DefaultMonitorInterval = 5 * time.Second
+
+const (
+	// maxLoadPerCPU is the 1-minute load average per CPU above which the
+	// host is busy
+	maxLoadPerCPU = 1.0
+	// minFreeMemory is the available memory below which the host is short
+	// of it
+	minFreeMemory = 256 << 20
+	// memoryLimitShare is the share of the memory limit the process may
+	// use before it is under pressure, in percent
+	memoryLimitShare = 90
+	// minThrottleDelay and maxThrottleDelay bound the wait before each
+	// file while throttled
+	minThrottleDelay = 10 * time.Millisecond
+	maxThrottleDelay = time.Second
+	// idleLoadPerCPU and idleMemoryShare are the load per CPU and share of
+	// the memory limit, in percent, below which the host is idle
+	idleLoadPerCPU  = 0.5
+	idleMemoryShare = 60
+	// idleSamples is how many samples in a row must find the host idle
+	// before a throttled scan speeds up again, 30s at the default interval
+	idleSamples = 6
+)
+
+// ResourcePressure is a sample of how busy the host and the scan are
+type ResourcePressure struct {
+	// Load is the 1-minute load average per CPU, 0 if it can't be read
+	Load float64 `json:"load"`
+	// AvailableMemory is the memory free in bytes, 0 if it can't be read
+	AvailableMemory int64 `json:"available_memory"`
+	// ProcessMemory is the memory the scan holds from the system
+	ProcessMemory int64 `json:"process_memory"`
+}
+
+// MonitorSupport says which of the host's resources an adaptive scan can
+// sample on this platform. Load is read from /proc/loadavg on Linux, the
+// vm.loadavg sysctl on macOS and FreeBSD, and the processor queue length
+// counter on Windows. Available memory is read from /proc/meminfo and the
+// cgroup limits on Linux, and GlobalMemoryStatusEx on Windows
+type MonitorSupport struct {
+	Load            bool `json:"load"`
+	AvailableMemory bool `json:"available_memory"`
+}
+
+// ProbeMonitor samples the host once to find what an adaptive scan can
+// sample on it
+func ProbeMonitor() MonitorSupport {
+	_, load := loadAverage()
+	return MonitorSupport{Load: load, AvailableMemory: availableMemory() > 0}
+}
+
+// ThrottleState describes how an adaptive scan was throttled
+type ThrottleState struct {
+	// Workers is how many workers may take files, of MaxWorkers
+	Workers    int `json:"workers"`
+	MaxWorkers int `json:"max_workers"`
+	// Delay is the wait before each file on top of the file delay
+	Delay time.Duration `json:"delay"`
+	// Throttles counts the times the scan was slowed, and ScaleUps the
+	// times it sped up again
+	Throttles int64            `json:"throttles"`
+	ScaleUps  int64            `json:"scale_ups"`
+	Last      ResourcePressure `json:"last"`
+}
+
+// resourceMonitor samples the host's load and memory during a scan. While
+// either is under pressure it halves the workers allowed to take files and
+// doubles a wait before each file. Once the host has been idle for a while
+// it adds a worker back and halves the wait, up to the scan's own settings
+type resourceMonitor struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	memoryLimit int64
+	sample      func() ResourcePressure
+	gate        *workerGate
+	state       ThrottleState
+	// idle counts the samples in a row that found the host idle
+	idle int
+	// changed is called with the throttling after each change (nil is
+	// none)
+	changed func(ThrottleState)
+}
+
+func newResourceMonitor(workers int, memoryLimit int64) *resourceMonitor {
+	return &resourceMonitor{
+		interval:    DefaultMonitorInterval,
+		memoryLimit: memoryLimit,
+		sample:      sampleResources,
+		gate:        newWorkerGate(workers),
+		state:       ThrottleState{Workers: workers, MaxWorkers: workers},
+	}
+}
+
+// run samples the host every interval until ctx is done
+func (m *resourceMonitor) run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.adjust(m.sample()) && m.changed != nil {
+				m.changed(m.states())
+			}
+		}
+	}
+}
+
+// adjust throttles the scan further if p shows pressure, and speeds it up
+// once enough samples in a row show the host idle, reporting whether
+// either happened
+func (m *resourceMonitor) adjust(p ResourcePressure) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.Last = p
+	switch {
+	case m.underPressure(p):
+		m.idle = 0
+		m.state.Throttles++
+		m.state.Workers = max(m.state.Workers/2, 1)
+		m.state.Delay = min(max(m.state.Delay*2, minThrottleDelay), maxThrottleDelay)
+	case m.isIdle(p):
+		m.idle++
+		if m.idle < idleSamples || (m.state.Workers == m.state.MaxWorkers && m.state.Delay == 0) {
+			return false
+		}
+		m.idle = 0
+		m.state.ScaleUps++
+		m.state.Workers = min(m.state.Workers+1, m.state.MaxWorkers)
+		if m.state.Delay /= 2; m.state.Delay < minThrottleDelay {
+			m.state.Delay = 0
+		}
+	default:
+		m.idle = 0
+		return false
+	}
+	m.gate.set(m.state.Workers)
+	return true
+}
+
+// underPressure reports whether the host is busy or short of memory, or
+// the scan is near its memory limit
+func (m *resourceMonitor) underPressure(p ResourcePressure) bool {
+	return p.Load > maxLoadPerCPU ||
+		(p.AvailableMemory > 0 && p.AvailableMemory < minFreeMemory) ||
+		(m.memoryLimit > 0 && p.ProcessMemory > m.memoryLimit/100*memoryLimitShare)
+}
+
+// isIdle reports whether the host has CPU and memory to spare, and the
+// scan is well below its memory limit
+func (m *resourceMonitor) isIdle(p ResourcePressure) bool {
+	return p.Load < idleLoadPerCPU &&
+		(p.AvailableMemory == 0 || p.AvailableMemory >= 2*minFreeMemory) &&
+		(m.memoryLimit == 0 || p.ProcessMemory < m.memoryLimit/100*idleMemoryShare)
+}
+
+// delay returns the wait before each file
+func (m *resourceMonitor) delay() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.Delay
+}
+
+// states returns the throttling so far
+func (m *resourceMonitor) states() ThrottleState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// reset lets every worker take files again, for a new scan
+func (m *resourceMonitor) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = ThrottleState{Workers: m.state.MaxWorkers, MaxWorkers: m.state.MaxWorkers}
+	m.idle = 0
+	m.gate.set(m.state.Workers)
+}
+
+// sampleResources reads the load average, the memory available and the
+// memory the process holds
+func sampleResources() ResourcePressure {
+	p := ResourcePressure{
+		AvailableMemory: availableMemory(),
+		ProcessMemory:   processMemory(),
+	}
+	if load, ok := loadAverage(); ok {
+		p.Load = load / float64(runtime.NumCPU())
+	}
+	return p
+}
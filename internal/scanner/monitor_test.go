@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"os"
+	"testing"
+)
+
+func TestResourceMonitorAdjust(t *testing.T) {
+	m := newResourceMonitor(8, 512<<20)
+
+	m.adjust(ResourcePressure{Load: 0.5, AvailableMemory: 4 << 30, ProcessMemory: 100 << 20})
+	if state := m.states(); state.Workers != 8 || state.Delay != 0 || state.Throttles != 0 {
+		t.Fatalf("expected no throttling when idle, got %+v", state)
+	}
+
+	pressures := []ResourcePressure{
+		{Load: 1.5},
+		{AvailableMemory: 100 << 20},
+		{ProcessMemory: 500 << 20},
+		{Load: 3},
+	}
+	for _, p := range pressures {
+		m.adjust(p)
+	}
+	state := m.states()
+	if state.Workers != 1 || state.Throttles != 4 || state.Delay != 8*minThrottleDelay {
+		t.Errorf("unexpected state after pressure %+v", state)
+	}
+	if m.gate.limit != 1 {
+		t.Errorf("expected the gate limited to 1 worker, got %d", m.gate.limit)
+	}
+
+	// A busy sample, or one neither busy nor idle, restarts the count
+	idle := ResourcePressure{Load: 0.1, AvailableMemory: 4 << 30, ProcessMemory: 100 << 20}
+	for i := 0; i < idleSamples-1; i++ {
+		m.adjust(idle)
+	}
+	m.adjust(ResourcePressure{Load: 0.8})
+	for i := 0; i < idleSamples-1; i++ {
+		m.adjust(idle)
+	}
+	if state := m.states(); state.Workers != 1 || state.ScaleUps != 0 {
+		t.Fatalf("expected no scale-up before a sustained idle window, got %+v", state)
+	}
+	m.adjust(idle)
+	if state := m.states(); state.Workers != 2 || state.Delay != 4*minThrottleDelay || state.ScaleUps != 1 || m.gate.limit != 2 {
+		t.Errorf("expected a worker added and the delay halved, got %+v", state)
+	}
+	for i := 0; i < 20*idleSamples; i++ {
+		m.adjust(idle)
+	}
+	if state := m.states(); state.Workers != 8 || state.Delay != 0 || state.ScaleUps != 7 {
+		t.Errorf("expected the scan back at its own settings, got %+v", state)
+	}
+
+	m.reset()
+	if state := m.states(); state.Workers != 8 || state.Delay != 0 || m.gate.limit != 8 {
+		t.Errorf("expected reset to restore the workers, got %+v", state)
+	}
+}
+
+func TestSampleResources(t *testing.T) {
+	if p := sampleResources(); p.ProcessMemory <= 0 || p.Load < 0 {
+		t.Errorf("unexpected sample %+v", p)
+	}
+}
+
+func TestProbeMonitor(t *testing.T) {
+	support := ProbeMonitor()
+	if _, err := os.Stat("/proc/loadavg"); err == nil && !support.Load {
+		t.Error("expected the load average to be read from /proc/loadavg")
+	}
+	if load, ok := loadAverage(); ok && load < 0 {
+		t.Errorf("unexpected load average %v", load)
+	}
+}
@@ -0,0 +1,79 @@
+// Package scanner provides detection of nulled (pirated) premium extensions
+package scanner
+
+import (
+	"regexp"
+)
+
+// NulledCategory is the match category for pirated/nulled software
+const NulledCategory = "nulled"
+
+// NulledRule is a footprint left by nulling a premium plugin or theme
+type NulledRule struct {
+	ID          string
+	Name        string
+	Description string
+	Pattern     *regexp.Regexp
+}
+
+// nulledRules are footprints of license-check bypasses and of the sites
+// that distribute nulled copies. Nulled extensions routinely ship with
+// backdoors, so any of them is a high risk on its own.
+var nulledRules = []*NulledRule{
+	{
+		ID:          "nulled-distributor",
+		Name:        "Nulled software distributor marker",
+		Description: "references a site known for distributing nulled plugins and themes",
+		Pattern: regexp.MustCompile(`(?i)\b(?:www\.)?(?:null24|nulled|wpnull|nulljungle|nulledfire|nullphp|babiato|wplocker|themelock|freshwp|weadown|vestathemes|downloadfreethemes|wordpress-nulled|gpldl|wpnulled|codelist)\.` +
+			`(?:com|net|org|cc|info|me|to|sh|xyz|club|in|io|ws|co)\b`),
+	},
+	{
+		ID:          "nulled-credit",
+		Name:        "Nulled by credit",
+		Description: "contains a release-group credit for removing the license check",
+		Pattern:     regexp.MustCompile(`(?i)\b(?:nulled|cracked|licen[cs]e\s+(?:bypass(?:ed)?|removed|cracked))\s+(?:by|from)\b`),
+	},
+	{
+		ID:          "license-forced-valid",
+		Name:        "License check forced valid",
+		Description: "overwrites the license server's response with a valid status",
+		Pattern:     regexp.MustCompile(`(?i)\$[a-z0-9_]*licen[cs]e[a-z0-9_]*(?:->|\[\s*['"])(?:license|status|license_status)(?:['"]\s*\])?\s*=\s*['"](?:valid|active|activated)['"]`),
+	},
+	{
+		ID:          "license-request-intercept",
+		Name:        "License request interception",
+		Description: "intercepts outgoing HTTP requests to fake the license server's reply",
+		Pattern:     regexp.MustCompile(`(?is)add_filter\(\s*['"]pre_http_request['"].{0,400}?licen[cs]e`),
+	},
+	{
+		ID:          "license-placeholder-key",
+		Name:        "Placeholder license key",
+		Description: "contains a license key widely used by nulled copies",
+		Pattern:     regexp.MustCompile(`(?i)\b(?:B5E0B5F8DD8689E6ACA49DD6E6E1A930|GPL001122334455AA6677BB8899CC000|activated_by_nulled|nulled_license_key)\b`),
+	},
+}
+
+// NulledMatch is a nulling footprint found in content
+type NulledMatch struct {
+	Rule          *NulledRule
+	MatchedString string
+	Position      int
+}
+
+// DetectNulled finds nulling footprints in content. Each rule is reported
+// once, at its first occurrence.
+func DetectNulled(content []byte) []*NulledMatch {
+	var matches []*NulledMatch
+	for _, rule := range nulledRules {
+		loc := rule.Pattern.FindIndex(content)
+		if loc == nil {
+			continue
+		}
+		matches = append(matches, &NulledMatch{
+			Rule:          rule,
+			MatchedString: string(content[loc[0]:loc[1]]),
+			Position:      loc[0],
+		})
+	}
+	return matches
+}
@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDetectNulled(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    string
+	}{
+		{"distributor", `<?php /* Downloaded from https://www.babiato.co/ */`, "nulled-distributor"},
+		{"credit", `<?php // Nulled by WeaDown`, "nulled-credit"},
+		{"forced valid", `<?php $license_data->license = 'valid'; update_option('x_license', $license_data);`, "license-forced-valid"},
+		{"forced valid array", `<?php $license_response['status'] = "active";`, "license-forced-valid"},
+		{"intercept", `<?php add_filter( 'pre_http_request', function( $pre, $args, $url ) { if ( strpos( $url, 'license' ) ) {`, "license-request-intercept"},
+		{"placeholder key", `<?php update_option( 'elementor_pro_license_key', 'B5E0B5F8DD8689E6ACA49DD6E6E1A930' );`, "license-placeholder-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := DetectNulled([]byte(tt.content))
+			if len(matches) != 1 || matches[0].Rule.ID != tt.rule {
+				t.Fatalf("expected rule %s, got %+v", tt.rule, matches)
+			}
+			if !strings.Contains(tt.content, matches[0].MatchedString) || matches[0].Position < 0 {
+				t.Errorf("unexpected match %+v", matches[0])
+			}
+		})
+	}
+}
+
+func TestDetectNulledIgnoresLegitimateLicensing(t *testing.T) {
+	content := `<?php
+$response = wp_remote_post( 'https://example.com/edd-sl', array( 'body' => $api_params ) );
+$license_data = json_decode( wp_remote_retrieve_body( $response ) );
+if ( 'valid' === $license_data->license ) {
+	update_option( 'shop_license_status', $license_data->license );
+}`
+	if matches := DetectNulled([]byte(content)); len(matches) != 0 {
+		t.Errorf("expected no matches, got %+v", matches)
+	}
+}
+
+func TestScanReportsNulled(t *testing.T) {
+	content := `<?php // Nulled by GPL Team`
+
+	s := NewScanner(createTestSignatureSet())
+	result := s.ScanReader(context.Background(), StdinPath, strings.NewReader(content))
+	if len(result.Matches) != 1 || result.Matches[0].Category != NulledCategory {
+		t.Fatalf("expected one nulled match, got %+v", result.Matches)
+	}
+	name, _ := result.Matches[0].Describe(s.SignatureSet())
+	if !strings.Contains(name, "nulled") {
+		t.Errorf("unexpected name %q", name)
+	}
+
+	disabled := NewScanner(createTestSignatureSet(), WithNulledDetection(false))
+	if result := disabled.ScanReader(context.Background(), StdinPath, strings.NewReader(content)); result.HasMatches() {
+		t.Errorf("expected no matches with detection disabled, got %+v", result.Matches)
+	}
+
+	filtered := NewScanner(createTestSignatureSet(), WithCategories([]string{"backdoor"}))
+	if result := filtered.ScanReader(context.Background(), StdinPath, strings.NewReader(content)); result.HasMatches() {
+		t.Errorf("expected category filter to exclude nulled matches, got %+v", result.Matches)
+	}
+}
@@ -0,0 +1,148 @@
+// Package scanner provides the order files are scanned in
+package scanner
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Orders files can be scanned in
+const (
+	// OrderWalk scans files in the order they are found
+	OrderWalk = "walk"
+
+	// OrderNewestFirst scans recently modified files first, as they are
+	// the likeliest to be an infection
+	OrderNewestFirst = "newest-first"
+
+	// OrderOldestFirst scans the least recently modified files first
+	OrderOldestFirst = "oldest-first"
+
+	// OrderLargestFirst scans the largest files first
+	OrderLargestFirst = "largest-first"
+)
+
+// DefaultOrderBuffer is how many files are held to be ordered. Files are
+// only ordered among those held, so a scan starts before the walk ends and
+// memory stays bounded on large trees.
+const DefaultOrderBuffer = 10000
+
+// ScanOrders returns the orders files can be scanned in
+func ScanOrders() []string {
+	return []string{OrderWalk, OrderNewestFirst, OrderOldestFirst, OrderLargestFirst}
+}
+
+// ValidateScanOrder checks that order is one of ScanOrders
+func ValidateScanOrder(order string) error {
+	if !slices.Contains(ScanOrders(), order) {
+		return fmt.Errorf("unknown scan order %q (available: %s)", order, strings.Join(ScanOrders(), ", "))
+	}
+	return nil
+}
+
+// orderedTask is a file held for ordering, with the key it is ordered by:
+// files with higher keys are scanned first
+type orderedTask struct {
+	task fileTask
+	key  int64
+	seq  int // walk order, to break ties
+}
+
+// taskHeap is a max-heap of held files
+type taskHeap []orderedTask
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].key != h[j].key {
+		return h[i].key > h[j].key
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x any)   { *h = append(*h, x.(orderedTask)) }
+func (h *taskHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// orderKey returns the key a file is ordered by. Files that can't be
+// stat'ed go last, and fail when they are scanned.
+func orderKey(order, path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return minOrderKey
+	}
+	switch order {
+	case OrderNewestFirst:
+		return info.ModTime().UnixNano()
+	case OrderOldestFirst:
+		return -info.ModTime().UnixNano()
+	case OrderLargestFirst:
+		return info.Size()
+	}
+	return 0
+}
+
+// minOrderKey orders a file after all others
+const minOrderKey = -1 << 63
+
+// orderFiles passes files from in to out in the scanner's order, holding up
+// to the buffer's worth and sending the first of them whenever the buffer
+// is full or out is ready. It closes out once in is closed and drained.
+func (s *Scanner) orderFiles(ctx context.Context, in <-chan fileTask, out chan<- fileTask) {
+	defer close(out)
+
+	held := &taskHeap{}
+	seq := 0
+	hold := func(task fileTask) {
+		heap.Push(held, orderedTask{task: task, key: orderKey(s.options.Order, task.path), seq: seq})
+		seq++
+	}
+	for in != nil || held.Len() > 0 {
+		// Only take more files while there is room for them, preferring
+		// those already found so each is ordered among as many as possible
+		recv := in
+		if held.Len() >= s.options.OrderBuffer {
+			recv = nil
+		}
+		if recv != nil {
+			select {
+			case task, ok := <-recv:
+				if !ok {
+					in = nil
+				} else {
+					hold(task)
+				}
+				continue
+			default:
+			}
+		}
+
+		// Only offer a file while some are held
+		var send chan<- fileTask
+		var next fileTask
+		if held.Len() > 0 {
+			send = out
+			next = (*held)[0].task
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case task, ok := <-recv:
+			if !ok {
+				in = nil
+				continue
+			}
+			hold(task)
+		case send <- next:
+			heap.Pop(held)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateScanOrder(t *testing.T) {
+	for _, order := range ScanOrders() {
+		if err := ValidateScanOrder(order); err != nil {
+			t.Errorf("expected %q to be valid: %v", order, err)
+		}
+	}
+	if err := ValidateScanOrder("random"); err == nil {
+		t.Error("expected an unknown order to be rejected")
+	}
+}
+
+func TestOrderFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	// Walk order, with ages in hours and sizes in bytes
+	files := []struct {
+		name string
+		age  int
+		size int
+	}{
+		{"a.php", 3, 10},
+		{"b.php", 1, 40},
+		{"c.php", 4, 30},
+		{"d.php", 2, 20},
+	}
+	var paths []string
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", f.size)), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		modified := now.Add(-time.Duration(f.age) * time.Hour)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatalf("failed to set times: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.php"))
+
+	tests := []struct {
+		order  string
+		buffer int
+		want   string
+	}{
+		{OrderNewestFirst, 10, "b d a c missing"},
+		{OrderOldestFirst, 10, "c a d b missing"},
+		{OrderLargestFirst, 10, "b c d a missing"},
+		// Only files held together are ordered
+		{OrderNewestFirst, 2, "b a d c missing"},
+	}
+	for _, tt := range tests {
+		s := NewScanner(createTestSignatureSet(), WithScanOrder(tt.order), WithOrderBuffer(tt.buffer))
+		in := make(chan fileTask, len(paths))
+		for _, path := range paths {
+			in <- fileTask{path: path}
+		}
+		close(in)
+		out := make(chan fileTask)
+		go s.orderFiles(context.Background(), in, out)
+
+		var got []string
+		for task := range out {
+			got = append(got, strings.TrimSuffix(filepath.Base(task.path), ".php"))
+		}
+		if want := strings.Fields(tt.want); !slices.Equal(got, want) {
+			t.Errorf("%s with a buffer of %d: got %v, want %v", tt.order, tt.buffer, got, want)
+		}
+	}
+}
+
+func TestScanOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.php", "b.php", "c.php"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("<?php eval($x);"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithScanOrder(OrderLargestFirst))
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	matched := 0
+	for result := range results {
+		if result.HasMatches() {
+			matched++
+		}
+	}
+	if matched != 3 {
+		t.Errorf("expected every ordered file to be scanned, got %d matches", matched)
+	}
+}
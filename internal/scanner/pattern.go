@@ -0,0 +1,874 @@
+// Package scanner provides parsing of signature patterns for RE2 gating
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxRE2Repeat is the largest repetition count Go's regexp accepts
+const maxRE2Repeat = 1000
+
+// Shorthand classes as regexp2 defines them. Go's own are ASCII-only.
+const (
+	spaceRanges = `\t-\r\x20\x{85}\x{A0}\x{1680}\x{2000}-\x{200A}\x{2028}\x{2029}\x{202F}\x{205F}\x{3000}`
+	wordRanges  = `\p{L}\p{Mn}\p{Nd}\p{Pc}\x{200C}\x{200D}`
+	digitRanges = `\p{Nd}`
+)
+
+// Reasons a pattern can't be gated, as reported in MatcherStats
+const (
+	reasonBackreference = "backreference"
+	reasonConditional   = "conditional"
+	reasonBalancing     = "balancing group"
+	reasonQuoting       = `\Q...\E quoting`
+	reasonPossessive    = "possessive quantifier"
+	reasonInvalid       = "invalid syntax"
+	reasonGoRegexp      = "rejected by Go's regexp"
+)
+
+// patternError explains why a pattern can't be translated for RE2
+type patternError struct {
+	reason string
+	detail string
+	pos    int
+}
+
+func (e *patternError) Error() string {
+	if e.detail != "" {
+		return fmt.Sprintf("%s at offset %d: %s", e.reason, e.pos, e.detail)
+	}
+	return fmt.Sprintf("%s at offset %d", e.reason, e.pos)
+}
+
+// gateReason returns the reason in an error from translating or compiling
+// a gate
+func gateReason(err error) string {
+	var perr *patternError
+	if errors.As(err, &perr) {
+		return perr.reason
+	}
+	return reasonGoRegexp
+}
+
+// translateRE2 rewrites a regexp2 (.NET-style) pattern for Go's regexp
+// package so that it matches at least everything the original does. The
+// pattern is parsed, not scanned: constructs that only restrict a match,
+// such as lookarounds, word boundaries, atomic groups and class
+// subtraction, are dropped, and shorthand classes are spelled out as
+// regexp2 defines them. Constructs whose meaning depends on captured text,
+// such as backreferences and conditionals, can't be translated.
+func translateRE2(rule string) (string, error) {
+	p := &patternParser{src: rule}
+	tree, err := p.parse()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	tree.writeRE2(&b)
+	return b.String(), nil
+}
+
+// syntaxKind is the kind of a syntaxNode
+type syntaxKind int
+
+const (
+	syntaxLiteral   syntaxKind = iota // rune
+	syntaxAnyChar                     // .
+	syntaxClass                       // class
+	syntaxAssertion                   // text: ^, $, \A or \z
+	syntaxEmpty                       // zero-width constructs RE2 drops; text is \b, \B, \G or \Z
+	syntaxGroup                       // sub[0], with text as its flags
+	syntaxFlags                       // text: flags for the rest of the group
+	syntaxConcat                      // sub
+	syntaxAlternate                   // sub
+	syntaxRepeat                      // sub[0]{min,max}, max -1 unbounded
+)
+
+// syntaxNode is a node of a parsed regexp2 pattern
+type syntaxNode struct {
+	kind     syntaxKind
+	rune     rune
+	text     string
+	class    *charClass
+	min, max int
+	sub      []*syntaxNode
+}
+
+// charClass is a parsed character class. Shorthands are the letters of
+// \s, \d, \w and their negations; props are \p and \P escapes as written.
+type charClass struct {
+	negated    bool
+	ranges     [][2]rune
+	shorthands []byte
+	props      []string
+}
+
+// patternParser parses regexp2 syntax with the Multiline and Singleline
+// options the matcher compiles signatures with
+type patternParser struct {
+	src      string
+	pos      int
+	extended bool // (?x): whitespace and # comments are ignored
+}
+
+func (p *patternParser) parse() (*syntaxNode, error) {
+	node, err := p.parseAlternate()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf(reasonInvalid, "unmatched )")
+	}
+	return node, nil
+}
+
+func (p *patternParser) errorf(reason, format string, args ...any) error {
+	return &patternError{reason: reason, detail: fmt.Sprintf(format, args...), pos: p.pos}
+}
+
+func (p *patternParser) more() bool {
+	return p.pos < len(p.src)
+}
+
+func (p *patternParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *patternParser) lookingAt(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+// next consumes one rune
+func (p *patternParser) next() rune {
+	r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.pos += size
+	return r
+}
+
+// skipIgnored skips (?#...) comments, and whitespace and # comments in
+// extended mode. regexp2 skips them between an atom and its quantifier too.
+func (p *patternParser) skipIgnored() {
+	for p.more() {
+		c := p.peek()
+		switch {
+		case p.lookingAt("(?#"):
+			end := strings.IndexByte(p.src[p.pos:], ')')
+			if end < 0 {
+				// Left for parseGroup to report
+				return
+			}
+			p.pos += end + 1
+		case p.extended && (c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'):
+			p.pos++
+		case p.extended && c == '#':
+			if end := strings.IndexByte(p.src[p.pos:], '\n'); end >= 0 {
+				p.pos += end + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *patternParser) parseAlternate() (*syntaxNode, error) {
+	var branches []*syntaxNode
+	for {
+		branch, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+		if !p.more() || p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+	if len(branches) == 1 {
+		return branches[0], nil
+	}
+	return &syntaxNode{kind: syntaxAlternate, sub: branches}, nil
+}
+
+func (p *patternParser) parseConcat() (*syntaxNode, error) {
+	concat := &syntaxNode{kind: syntaxConcat}
+	for {
+		p.skipIgnored()
+		if !p.more() || p.peek() == '|' || p.peek() == ')' {
+			return concat, nil
+		}
+		atom, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		if atom.kind == syntaxFlags {
+			// Options aren't an atom to repeat
+			p.skipIgnored()
+			if p.startsQuantifier() {
+				return nil, p.errorf(reasonInvalid, "nothing to repeat")
+			}
+			concat.sub = append(concat.sub, atom)
+			continue
+		}
+		if atom, err = p.parseRepeat(atom); err != nil {
+			return nil, err
+		}
+		concat.sub = append(concat.sub, atom)
+	}
+}
+
+func (p *patternParser) parseAtom() (*syntaxNode, error) {
+	switch c := p.peek(); c {
+	case '(':
+		return p.parseGroup()
+	case '[':
+		p.pos++
+		class, err := p.parseClass()
+		if err != nil {
+			return nil, err
+		}
+		return &syntaxNode{kind: syntaxClass, class: class}, nil
+	case '.':
+		p.pos++
+		return &syntaxNode{kind: syntaxAnyChar}, nil
+	case '^', '$':
+		p.pos++
+		return &syntaxNode{kind: syntaxAssertion, text: string(c)}, nil
+	case '\\':
+		return p.parseEscape()
+	}
+	if p.startsQuantifier() {
+		return nil, p.errorf(reasonInvalid, "nothing to repeat")
+	}
+	return &syntaxNode{kind: syntaxLiteral, rune: p.next()}, nil
+}
+
+// startsQuantifier reports whether a quantifier starts at the current
+// position
+func (p *patternParser) startsQuantifier() bool {
+	if !p.more() {
+		return false
+	}
+	if c := p.peek(); c == '*' || c == '+' || c == '?' {
+		return true
+	}
+	_, _, size := p.repeatBounds()
+	return size > 0
+}
+
+// repeatBounds reads {n}, {n,} or {n,m} at the current position without
+// consuming it. Anything else is a literal brace in both dialects.
+func (p *patternParser) repeatBounds() (lo, hi, size int) {
+	rest := p.src[p.pos:]
+	end := strings.IndexByte(rest, '}')
+	if !strings.HasPrefix(rest, "{") || end < 0 {
+		return 0, 0, 0
+	}
+	body := rest[1:end]
+	minText, maxText, hasComma := strings.Cut(body, ",")
+	lo, err := strconv.Atoi(minText)
+	if err != nil || lo < 0 || strings.ContainsAny(minText, "+-") {
+		return 0, 0, 0
+	}
+	hi = lo
+	if hasComma {
+		hi = -1
+		if maxText != "" {
+			if hi, err = strconv.Atoi(maxText); err != nil || hi < 0 || strings.ContainsAny(maxText, "+-") {
+				return 0, 0, 0
+			}
+		}
+	}
+	return lo, hi, end + 1
+}
+
+func (p *patternParser) parseRepeat(atom *syntaxNode) (*syntaxNode, error) {
+	p.skipIgnored()
+	if !p.more() {
+		return atom, nil
+	}
+	lo, hi := 0, -1
+	switch p.peek() {
+	case '*':
+		p.pos++
+	case '+':
+		lo = 1
+		p.pos++
+	case '?':
+		hi = 1
+		p.pos++
+	case '{':
+		var size int
+		if lo, hi, size = p.repeatBounds(); size == 0 {
+			return atom, nil
+		}
+		if hi >= 0 && hi < lo {
+			return nil, p.errorf(reasonInvalid, "repeat bounds out of order")
+		}
+		p.pos += size
+	default:
+		return atom, nil
+	}
+
+	// Laziness doesn't change whether a pattern matches
+	p.skipIgnored()
+	if p.more() && p.peek() == '?' {
+		p.pos++
+	} else if p.more() && p.peek() == '+' {
+		return nil, p.errorf(reasonPossessive, "")
+	}
+	p.skipIgnored()
+	if p.startsQuantifier() {
+		return nil, p.errorf(reasonInvalid, "nested quantifier")
+	}
+	return &syntaxNode{kind: syntaxRepeat, min: lo, max: hi, sub: []*syntaxNode{atom}}, nil
+}
+
+// parseGroup parses a parenthesized construct. Lookarounds and comments
+// parse to nodes RE2 drops; capturing and atomic groups to plain groups.
+func (p *patternParser) parseGroup() (*syntaxNode, error) {
+	start := p.pos
+	p.pos++
+	outer := p.extended
+	flags := ""
+	empty := false
+
+	switch {
+	case !p.lookingAt("?"):
+	case p.lookingAt("?#"):
+		return nil, p.errorf(reasonInvalid, "unterminated comment")
+	case p.lookingAt("?:"), p.lookingAt("?>"):
+		p.pos += 2
+	case p.lookingAt("?="), p.lookingAt("?!"):
+		p.pos += 2
+		empty = true
+	case p.lookingAt("?<="), p.lookingAt("?<!"):
+		p.pos += 3
+		empty = true
+	case p.lookingAt("?("):
+		return nil, p.errorf(reasonConditional, "")
+	case p.lookingAt("?<"), p.lookingAt("?'"):
+		term := byte('>')
+		if p.src[p.pos+1] == '\'' {
+			term = '\''
+		}
+		p.pos += 2
+		end := strings.IndexByte(p.src[p.pos:], term)
+		if end < 0 {
+			return nil, p.errorf(reasonInvalid, "unterminated group name")
+		}
+		name := p.src[p.pos : p.pos+end]
+		if strings.Contains(name, "-") {
+			return nil, p.errorf(reasonBalancing, "")
+		}
+		if !validGroupName(name) {
+			return nil, p.errorf(reasonInvalid, "invalid group name %q", name)
+		}
+		p.pos += end + 1
+	default:
+		p.pos++
+		var err error
+		var scoped bool
+		if flags, scoped, err = p.parseFlags(); err != nil {
+			return nil, err
+		}
+		if !scoped {
+			// The flags apply to the rest of the enclosing group
+			return &syntaxNode{kind: syntaxFlags, text: flags}, nil
+		}
+	}
+
+	sub, err := p.parseAlternate()
+	if err != nil {
+		return nil, err
+	}
+	if !p.more() {
+		p.pos = start
+		return nil, p.errorf(reasonInvalid, "unterminated group")
+	}
+	p.pos++
+	p.extended = outer
+	if empty {
+		return &syntaxNode{kind: syntaxEmpty}, nil
+	}
+	return &syntaxNode{kind: syntaxGroup, text: flags, sub: []*syntaxNode{sub}}, nil
+}
+
+// parseFlags parses inline options up to ')' or ':', returning those RE2
+// shares (i, m and s) and whether they are scoped to a group that follows
+func (p *patternParser) parseFlags() (string, bool, error) {
+	var on, off strings.Builder
+	negate, empty := false, true
+	for p.more() {
+		c := p.peek()
+		p.pos++
+		if c != ')' && c != ':' && c != '-' {
+			empty = false
+		}
+		switch c {
+		case ')', ':':
+			if empty {
+				return "", false, p.errorf(reasonInvalid, "missing options")
+			}
+			flags := on.String()
+			if off.Len() > 0 {
+				flags += "-" + off.String()
+			}
+			return flags, c == ':', nil
+		case '-':
+			if negate {
+				return "", false, p.errorf(reasonInvalid, "repeated - in options")
+			}
+			negate = true
+		case 'i', 'm', 's':
+			if negate {
+				off.WriteByte(c)
+			} else {
+				on.WriteByte(c)
+			}
+		case 'x':
+			p.extended = !negate
+		case 'n':
+			// Explicit capture only changes which groups capture
+		default:
+			p.pos--
+			return "", false, p.errorf(reasonInvalid, "unknown option %q", c)
+		}
+	}
+	return "", false, p.errorf(reasonInvalid, "unterminated options")
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func validGroupName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseEscape parses an escape outside a class
+func (p *patternParser) parseEscape() (*syntaxNode, error) {
+	p.pos++
+	if !p.more() {
+		return nil, p.errorf(reasonInvalid, "trailing backslash")
+	}
+	switch c := p.peek(); c {
+	case 'b', 'B', 'G', 'Z':
+		// Assertions that only restrict where a match may be
+		p.pos++
+		return &syntaxNode{kind: syntaxEmpty, text: `\` + string(c)}, nil
+	case 'A', 'z':
+		p.pos++
+		return &syntaxNode{kind: syntaxAssertion, text: `\` + string(c)}, nil
+	case 'k':
+		return nil, p.errorf(reasonBackreference, "")
+	case 's', 'd', 'w', 'S', 'D', 'W', 'p', 'P':
+		class := &charClass{}
+		if err := p.parseClassEscape(class); err != nil {
+			return nil, err
+		}
+		return &syntaxNode{kind: syntaxClass, class: class}, nil
+	default:
+		if c >= '1' && c <= '9' {
+			return nil, p.errorf(reasonBackreference, "")
+		}
+		r, err := p.parseCharEscape()
+		if err != nil {
+			return nil, err
+		}
+		return &syntaxNode{kind: syntaxLiteral, rune: r}, nil
+	}
+}
+
+// parseCharEscape parses an escape for a single character, after the
+// backslash
+func (p *patternParser) parseCharEscape() (rune, error) {
+	c := p.next()
+	switch c {
+	case 'a':
+		return '\a', nil
+	case 'e':
+		return 0x1B, nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'v':
+		return '\v', nil
+	case '0':
+		// Up to two more octal digits
+		r := rune(0)
+		for i := 0; i < 2 && p.more() && p.peek() >= '0' && p.peek() <= '7'; i++ {
+			r = r*8 + rune(p.peek()-'0')
+			p.pos++
+		}
+		return r, nil
+	case 'x':
+		if p.lookingAt("{") {
+			end := strings.IndexByte(p.src[p.pos:], '}')
+			if end < 0 {
+				return 0, p.errorf(reasonInvalid, "unterminated \\x{")
+			}
+			digits := p.src[p.pos+1 : p.pos+end]
+			if len(digits) == 0 || len(digits) > 8 {
+				return 0, p.errorf(reasonInvalid, "invalid hex escape")
+			}
+			return p.hexRune(digits, end+1)
+		}
+		return p.hexRune(p.src[p.pos:min(p.pos+2, len(p.src))], 2)
+	case 'u':
+		return p.hexRune(p.src[p.pos:min(p.pos+4, len(p.src))], 4)
+	case 'c':
+		if !p.more() {
+			return 0, p.errorf(reasonInvalid, "missing control character")
+		}
+		letter := unicode.ToUpper(p.next())
+		if letter < '@' || letter > '_' {
+			return 0, p.errorf(reasonInvalid, "invalid control character")
+		}
+		return letter - '@', nil
+	case 'Q', 'E':
+		return 0, p.errorf(reasonQuoting, "")
+	}
+	if c < utf8.RuneSelf && (c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)) {
+		return 0, p.errorf(reasonInvalid, "unrecognized escape \\%c", c)
+	}
+	return c, nil
+}
+
+// hexRune parses hex digits that take size bytes of the pattern. Digits
+// without braces must fill size.
+func (p *patternParser) hexRune(digits string, size int) (rune, error) {
+	if !strings.HasPrefix(p.src[p.pos:], "{") && len(digits) != size {
+		return 0, p.errorf(reasonInvalid, "invalid hex escape")
+	}
+	v, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || v > unicode.MaxRune {
+		return 0, p.errorf(reasonInvalid, "invalid hex escape")
+	}
+	p.pos += size
+	return rune(v), nil
+}
+
+// parseClass parses a character class after its '['. A '[' inside a class
+// is a literal; regexp2 doesn't support POSIX classes, and skips the rest of
+// one such as [:alpha:] after its '['.
+func (p *patternParser) parseClass() (*charClass, error) {
+	start := p.pos - 1
+	class := &charClass{}
+	if p.lookingAt("^") {
+		class.negated = true
+		p.pos++
+	}
+	first := true
+	for {
+		if !p.more() {
+			p.pos = start
+			return nil, p.errorf(reasonInvalid, "unterminated class")
+		}
+		c := p.peek()
+		switch {
+		case c == ']' && !first:
+			p.pos++
+			return class, nil
+		case c == '-' && !first && p.pos+1 < len(p.src) && p.src[p.pos+1] == '[':
+			// Subtraction only removes characters, so RE2 drops it
+			p.pos += 2
+			if _, err := p.parseClass(); err != nil {
+				return nil, err
+			}
+			if !p.lookingAt("]") {
+				return nil, p.errorf(reasonInvalid, "subtraction must be last in a class")
+			}
+			p.pos++
+			return class, nil
+		case p.lookingAtClassEscape():
+			p.pos++
+			if err := p.parseClassEscape(class); err != nil {
+				return nil, err
+			}
+		default:
+			if err := p.parseClassRange(class); err != nil {
+				return nil, err
+			}
+		}
+		first = false
+	}
+}
+
+// lookingAtClassEscape reports whether the parser is at a shorthand or
+// Unicode property escape, such as \d or \p{L}
+func (p *patternParser) lookingAtClassEscape() bool {
+	return p.lookingAt(`\`) && p.pos+1 < len(p.src) && strings.IndexByte("sdwSDWpP", p.src[p.pos+1]) >= 0
+}
+
+// parseClassRange parses a character or range of a class into class
+func (p *patternParser) parseClassRange(class *charClass) error {
+	lo, err := p.parseClassChar()
+	if err != nil {
+		return err
+	}
+	if lo == '[' {
+		p.skipPOSIXClass()
+	}
+	hi := lo
+	if p.lookingAt("-") && p.pos+1 < len(p.src) && p.src[p.pos+1] != ']' && p.src[p.pos+1] != '[' {
+		p.pos++
+		if p.lookingAtClassEscape() {
+			return p.errorf(reasonInvalid, "class in range")
+		}
+		if hi, err = p.parseClassChar(); err != nil {
+			return err
+		}
+		if hi < lo {
+			return p.errorf(reasonInvalid, "range out of order")
+		}
+	}
+	class.ranges = append(class.ranges, [2]rune{lo, hi})
+	return nil
+}
+
+// skipPOSIXClass skips the rest of a POSIX class such as [:alpha:] after
+// its '[', as regexp2 does
+func (p *patternParser) skipPOSIXClass() {
+	if !p.lookingAt(":") {
+		return
+	}
+	end := p.pos + 1
+	for end < len(p.src) && isWordByte(p.src[end]) {
+		end++
+	}
+	if strings.HasPrefix(p.src[end:], ":]") {
+		p.pos = end + 2
+	}
+}
+
+// parseClassChar parses one character of a class, where \b is a backspace
+func (p *patternParser) parseClassChar() (rune, error) {
+	if !p.lookingAt(`\`) {
+		return p.next(), nil
+	}
+	p.pos++
+	if !p.more() {
+		return 0, p.errorf(reasonInvalid, "trailing backslash")
+	}
+	if p.peek() == 'b' {
+		p.pos++
+		return '\b', nil
+	}
+	return p.parseCharEscape()
+}
+
+// parseClassEscape parses a shorthand or Unicode property escape, after
+// the backslash, into class
+func (p *patternParser) parseClassEscape(class *charClass) error {
+	c := p.peek()
+	p.pos++
+	if c != 'p' && c != 'P' {
+		class.shorthands = append(class.shorthands, c)
+		return nil
+	}
+	name := ""
+	switch {
+	case p.lookingAt("{"):
+		end := strings.IndexByte(p.src[p.pos:], '}')
+		if end < 0 {
+			return p.errorf(reasonInvalid, "unterminated property")
+		}
+		name = p.src[p.pos+1 : p.pos+end]
+		p.pos += end + 1
+	case p.more():
+		name = string(p.next())
+	}
+	if name == "" {
+		return p.errorf(reasonInvalid, "missing property name")
+	}
+	// Go rejects names it doesn't know, such as .NET's block names
+	class.props = append(class.props, `\`+string(c)+"{"+name+"}")
+	return nil
+}
+
+// writeRE2 writes the node in Go's syntax
+func (n *syntaxNode) writeRE2(b *strings.Builder) {
+	switch n.kind {
+	case syntaxLiteral:
+		writeRE2Rune(b, n.rune)
+	case syntaxAnyChar:
+		b.WriteByte('.')
+	case syntaxClass:
+		n.class.writeRE2(b)
+	case syntaxAssertion:
+		b.WriteString(n.text)
+	case syntaxEmpty:
+	case syntaxFlags:
+		if n.text != "" {
+			b.WriteString("(?" + n.text + ")")
+		}
+	case syntaxGroup:
+		b.WriteString("(?" + n.text + ":")
+		n.sub[0].writeRE2(b)
+		b.WriteByte(')')
+	case syntaxConcat:
+		for _, sub := range n.sub {
+			sub.writeRE2(b)
+		}
+	case syntaxAlternate:
+		for i, sub := range n.sub {
+			if i > 0 {
+				b.WriteByte('|')
+			}
+			sub.writeRE2(b)
+		}
+	case syntaxRepeat:
+		n.writeRE2Repeat(b)
+	}
+}
+
+// writeRE2Repeat writes a repetition, widening counts beyond Go's limit
+func (n *syntaxNode) writeRE2Repeat(b *strings.Builder) {
+	var sub strings.Builder
+	n.sub[0].writeRE2(&sub)
+	if sub.Len() == 0 {
+		// Repeating a dropped assertion
+		return
+	}
+	b.WriteString(sub.String())
+
+	lo, hi := n.min, n.max
+	if hi > maxRE2Repeat {
+		hi = -1
+	}
+	lo = min(lo, maxRE2Repeat)
+	switch {
+	case lo == 0 && hi == -1:
+		b.WriteByte('*')
+	case lo == 1 && hi == -1:
+		b.WriteByte('+')
+	case lo == 0 && hi == 1:
+		b.WriteByte('?')
+	case lo == hi:
+		fmt.Fprintf(b, "{%d}", lo)
+	case hi == -1:
+		fmt.Fprintf(b, "{%d,}", lo)
+	default:
+		fmt.Fprintf(b, "{%d,%d}", lo, hi)
+	}
+}
+
+func writeRE2Rune(b *strings.Builder, r rune) {
+	if r < utf8.RuneSelf && unicode.IsPrint(r) {
+		b.WriteString(regexp.QuoteMeta(string(r)))
+		return
+	}
+	fmt.Fprintf(b, `\x{%X}`, r)
+}
+
+// mixesNegatedProp reports whether the class has a \P property among other
+// items. regexp2 matches such classes inconsistently ([\P{L}\w] doesn't
+// match "a"), so they are widened to any character.
+func (c *charClass) mixesNegatedProp() bool {
+	if len(c.ranges)+len(c.shorthands)+len(c.props) < 2 {
+		return false
+	}
+	for _, prop := range c.props {
+		if strings.HasPrefix(prop, `\P`) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRE2ClassRune writes a character of a class, escaping punctuation
+// such as ']', '-' and '^' that Go would read as class syntax
+func writeRE2ClassRune(b *strings.Builder, r rune) {
+	switch {
+	case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+		b.WriteRune(r)
+	case r < utf8.RuneSelf && (unicode.IsPunct(r) || unicode.IsSymbol(r)):
+		b.WriteByte('\\')
+		b.WriteRune(r)
+	default:
+		fmt.Fprintf(b, `\x{%X}`, r)
+	}
+}
+
+// writeRE2 writes the class in Go's syntax. Go can't negate a shorthand
+// inside a class, so \S and \W are written as alternatives of a class
+// matching everything but their ranges.
+func (c *charClass) writeRE2(b *strings.Builder) {
+	if c.mixesNegatedProp() {
+		b.WriteString(`(?s:.)`)
+		return
+	}
+
+	var positive strings.Builder
+	var negatedRanges []string
+	for _, r := range c.ranges {
+		writeRE2ClassRune(&positive, r[0])
+		if r[1] != r[0] {
+			positive.WriteByte('-')
+			writeRE2ClassRune(&positive, r[1])
+		}
+	}
+	for _, s := range c.shorthands {
+		switch s {
+		case 's':
+			positive.WriteString(spaceRanges)
+		case 'd':
+			positive.WriteString(digitRanges)
+		case 'w':
+			positive.WriteString(wordRanges)
+		case 'D':
+			positive.WriteString(`\P{Nd}`)
+		case 'S':
+			negatedRanges = append(negatedRanges, spaceRanges)
+		case 'W':
+			negatedRanges = append(negatedRanges, wordRanges)
+		}
+	}
+	for _, prop := range c.props {
+		positive.WriteString(prop)
+	}
+
+	if c.negated {
+		if len(negatedRanges) > 0 {
+			// [^\S...] only matches within \s, a superset of what it matches
+			b.WriteString("[" + negatedRanges[0] + "]")
+			return
+		}
+		b.WriteString("[^" + positive.String() + "]")
+		return
+	}
+
+	alternatives := make([]string, 0, len(negatedRanges)+1)
+	for _, ranges := range negatedRanges {
+		alternatives = append(alternatives, "[^"+ranges+"]")
+	}
+	if positive.Len() > 0 {
+		alternatives = append(alternatives, "["+positive.String()+"]")
+	}
+	if len(alternatives) == 1 {
+		b.WriteString(alternatives[0])
+		return
+	}
+	b.WriteString("(?:" + strings.Join(alternatives, "|") + ")")
+}
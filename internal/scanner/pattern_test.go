@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/dlclark/regexp2"
+)
+
+// checkSuperset fails unless the translation of rule matches every input
+// regexp2 matches
+func checkSuperset(t *testing.T, rule, translated string, inputs []string) {
+	t.Helper()
+	re, err := regexp.Compile("(?ms)" + translated)
+	if err != nil {
+		t.Errorf("translation %q of %q does not compile: %v", translated, rule, err)
+		return
+	}
+	original := regexp2.MustCompile(rule, regexp2.Multiline|regexp2.Singleline)
+	for _, input := range inputs {
+		if ok, _ := original.MatchString(input); !ok {
+			t.Fatalf("bad test: %q does not match %q", rule, input)
+		}
+		if !re.MatchString(input) {
+			t.Errorf("translation %q of %q does not match %q", translated, rule, input)
+		}
+	}
+}
+
+func TestTranslateRE2(t *testing.T) {
+	tests := []struct {
+		rule   string
+		inputs []string // matched by regexp2, so must be matched by the translation
+	}{
+		{`eval\s*\(`, []string{"eval (", "eval　(", "eval ("}},
+		{`\w+_decode`, []string{"base64_decode", "données_decode", "a‍z_decode"}},
+		{`id=\d+`, []string{"id=42", "id=٤٢"}},
+		{`[\s\d]x`, []string{" x", " x", "٣x"}},
+		{`a\Sb`, []string{"a-b", "aéb"}},
+		{`a\Wb`, []string{"a-b", "a b"}},
+		{`[^\D]z`, []string{"1z", "٣z"}},
+		{`[]a]+`, []string{"]a"}},
+		{`[a[]`, []string{"["}},
+		{`(?i)select`, []string{"SeLeCt"}},
+		{`\beval\b`, []string{"eval", "(eval)"}},
+		{`foo\Z`, []string{"foo", "foo\n"}},
+		{`[a-z-[aeiou]]`, []string{"b"}},
+		{`[[:alpha:]]`, []string{"["}},
+		{`[a[:digit:]b]`, []string{"a", "b"}},
+		{`[[:foo]`, []string{"[", "f"}},
+		{`[\S]`, []string{"x"}},
+		{`[\W_]`, []string{"_", "-"}},
+		{`[-^\]]{3}`, []string{"-^]"}},
+		{`[^\P{L}\w]`, []string{"a"}},
+		{`a{2,5000}`, []string{"aa"}},
+		{`(?x) a  b # comment`, []string{"ab"}},
+		{`(?x: a b ) c`, []string{"ab c"}},
+	}
+	for _, tt := range tests {
+		translated, err := translateRE2(tt.rule)
+		if err != nil {
+			t.Errorf("expected %q to translate: %v", tt.rule, err)
+			continue
+		}
+		checkSuperset(t, tt.rule, translated, tt.inputs)
+	}
+}
+
+func TestTranslateRE2Rejects(t *testing.T) {
+	for _, tt := range []struct {
+		rule, reason string
+	}{
+		{`(a)\1`, reasonBackreference},
+		{`(?<n>a)\k<n>`, reasonBackreference},
+		{`(?(a)a|b)`, reasonConditional},
+		{`(?<a-b>x)`, reasonBalancing},
+		{`\Qa.b\E`, reasonQuoting},
+		{`a++`, reasonPossessive},
+		{`a{2}+`, reasonPossessive},
+		{`[abc`, reasonInvalid},
+		{`foo\`, reasonInvalid},
+		{`(foo`, reasonInvalid},
+		{`foo)`, reasonInvalid},
+		{`*a`, reasonInvalid},
+		{`a**`, reasonInvalid},
+		{`\_`, reasonInvalid},
+		{`[a-\d]`, reasonInvalid},
+		{`(?P<n>a)`, reasonInvalid},
+	} {
+		translated, err := translateRE2(tt.rule)
+		if err == nil {
+			t.Errorf("expected %q to be rejected, got %q", tt.rule, translated)
+			continue
+		}
+		if reason := gateReason(err); reason != tt.reason {
+			t.Errorf("%q: got reason %q, want %q (%v)", tt.rule, reason, tt.reason, err)
+		}
+	}
+}
+
+// TestGatePatternFixtures checks rules in the style of the signature feed:
+// those with a reason must fall back to regexp2 for it, and the rest must
+// translate to gates matching everything regexp2 matches, in their inputs
+// and in the corpus
+func TestGatePatternFixtures(t *testing.T) {
+	data, err := os.ReadFile("testdata/patterns.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Patterns []struct {
+			Rule    string   `json:"rule"`
+			Reason  string   `json:"reason"`
+			Matches []string `json:"matches"`
+		} `json:"patterns"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	files, err := LoadBenchCorpus([]string{corpusDir}, DefaultFilter(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range fixture.Patterns {
+		translated, err := gatePattern(p.Rule)
+		if p.Reason != "" {
+			if err == nil {
+				t.Errorf("expected %q to fall back for %s, got %q", p.Rule, p.Reason, translated)
+			} else if reason := gateReason(err); reason != p.Reason {
+				t.Errorf("%q: got reason %q, want %q (%v)", p.Rule, reason, p.Reason, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expected %q to be gated: %v", p.Rule, err)
+			continue
+		}
+
+		inputs := p.Matches
+		original := regexp2.MustCompile(p.Rule, regexp2.Multiline|regexp2.Singleline)
+		for _, file := range files {
+			if ok, _ := original.MatchString(string(file.Content)); ok {
+				inputs = append(inputs, string(file.Content))
+			}
+		}
+		checkSuperset(t, p.Rule, translated, inputs)
+	}
+}
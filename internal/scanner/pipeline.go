@@ -0,0 +1,248 @@
+// Package scanner provides the read and match stages of a scan
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/telemetry"
+)
+
+// readFile is a file whose content has been read, waiting to be matched
+type readFile struct {
+	task    fileTask
+	result  *ScanResult
+	content []byte
+	start   time.Time
+	// release returns the content's share of the read budget
+	release func()
+}
+
+// stageWorkers returns how many workers read files and how many match
+// them. Matching is bound by the CPUs, so it takes the workers. Reads
+// spend most of their time waiting on the disk, so twice as many readers
+// keep the matchers busy, unless the read rate bounds them anyway.
+func (o *ScanOptions) stageWorkers() (read, match int) {
+	match = o.MatchWorkers
+	if match <= 0 {
+		match = max(o.Workers, 1)
+	}
+	read = o.ReadWorkers
+	switch {
+	case read > 0:
+	case o.ReadRate > 0:
+		read = max(match/2, 1)
+	default:
+		read = match * 2
+	}
+	return read, match
+}
+
+// readWorker reads the content of files from the files channel and passes
+// it on to the match workers
+func (s *Scanner) readWorker(ctx context.Context, progress *scanProgress, files <-chan fileTask, read chan<- *readFile, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case task, ok := <-files:
+			if !ok {
+				return
+			}
+
+			// Past the deadline, files found but not started are left
+			// for a resumed scan
+			if progress.pastDeadline() {
+				progress.dropped.Add(1)
+				continue
+			}
+
+			// Between batches every reader waits, and the matchers
+			// finish the files already read
+			if s.batch.wait(ctx) != nil {
+				return
+			}
+			s.events.emit(ProgressEvent{Type: ProgressFileStarted, Path: task.path})
+			file := s.readFile(ctx, task)
+			select {
+			case <-ctx.Done():
+				file.release()
+				return
+			case read <- file:
+			}
+		}
+	}
+}
+
+// matchWorker matches the files read against the signatures and finishes
+// them
+func (s *Scanner) matchWorker(ctx context.Context, progress *scanProgress, read <-chan *readFile, retries *retryQueue, results chan<- *ScanResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case file, ok := <-read:
+			if !ok {
+				return
+			}
+
+			// Past the throttled limit, wait for another worker to finish
+			if s.monitor.gate.acquire(ctx) != nil {
+				file.release()
+				return
+			}
+			result := s.matchFile(ctx, file)
+			s.monitor.gate.release()
+			s.traceSlowFile(ctx, file)
+			if !s.queueRetry(retries, file.task, result) && !s.finishTask(ctx, progress, file.task, result, results) {
+				return
+			}
+			if sleepContext(ctx, s.options.FileDelay+s.monitor.delay()) != nil {
+				return
+			}
+		}
+	}
+}
+
+// scanFile scans a single file
+func (s *Scanner) scanFile(ctx context.Context, task fileTask) *ScanResult {
+	return s.matchFile(ctx, s.readFile(ctx, task))
+}
+
+// matchFile matches the content of a file read, unless reading it failed,
+// and returns its share of the read budget
+func (s *Scanner) matchFile(ctx context.Context, file *readFile) *ScanResult {
+	defer file.release()
+	if file.result.Error == nil {
+		s.matchContent(ctx, file.result, file.content, file.task.phpOnly)
+		file.result.ScanDuration = time.Since(file.start)
+	}
+	return file.result
+}
+
+// traceSlowFile traces a file that took longer than the slow file
+// threshold to read and match, from when it was first read
+func (s *Scanner) traceSlowFile(ctx context.Context, file *readFile) {
+	threshold := s.options.SlowFileThreshold
+	if threshold <= 0 || !telemetry.Enabled() || time.Since(file.start) < threshold {
+		return
+	}
+	_, span := telemetry.StartAt(ctx, "file", file.start,
+		telemetry.String("file.path", file.task.path),
+		telemetry.Int64("file.bytes", file.result.ScannedBytes),
+		telemetry.Int("file.matches", len(file.result.Matches)),
+		telemetry.Bool("file.incomplete", file.result.Incomplete))
+	span.RecordError(file.result.Error)
+	span.End()
+}
+
+// readFile reads the content of a single file, holding its share of the
+// read budget until it is released
+func (s *Scanner) readFile(ctx context.Context, task fileTask) *readFile {
+	path := task.path
+	file := &readFile{
+		task:    task,
+		result:  &ScanResult{Path: path},
+		start:   time.Now(),
+		release: func() {},
+	}
+	result := file.result
+	read := &countingReader{}
+
+	// Never open FIFOs or devices: reads may block forever or never end
+	var device uint64
+	if info, err := os.Stat(path); err == nil {
+		if reason := specialFileReason(info.Mode()); reason != "" {
+			result.Error = fmt.Errorf("%w: %s", ErrSpecialFile, reason)
+			return file
+		}
+
+		// Leave the files of failing devices be, until they recover
+		device = fileDevice(info)
+		if err := s.circuits.allow(device, path); err != nil {
+			result.Error = err
+			return file
+		}
+		defer func() { s.circuits.record(device, result.Error) }()
+
+		// Back off a slowing device, and keep to the read rate, before
+		// holding any of the read budget
+		if err := s.backoff.wait(ctx, device, path); err != nil {
+			result.Error = err
+			return file
+		}
+		reservation := s.rate.reserve(s.readSize(info.Size()))
+		if err := reservation.wait(ctx); err != nil {
+			result.Error = err
+			return file
+		}
+		// Give back the rate of what isn't read
+		defer func() { reservation.settle(read.n) }()
+
+		// Wait for room for the content before taking a file descriptor
+		if s.readBudget != nil {
+			reserved, err := s.readBudget.acquire(ctx, s.readSize(info.Size()))
+			if err != nil {
+				result.Error = err
+				return file
+			}
+			file.release = func() { s.readBudget.release(reserved) }
+		}
+	}
+
+	if s.openFiles != nil {
+		select {
+		case s.openFiles <- struct{}{}:
+		case <-ctx.Done():
+			result.Error = fmt.Errorf("waiting for file descriptor: %w", ctx.Err())
+			return file
+		}
+		defer func() { <-s.openFiles }()
+	}
+
+	f, err := os.Open(path) // #nosec G304 -- scanning user-specified paths
+	if err != nil {
+		result.Error = fmt.Errorf("failed to open file: %w", err)
+		return file
+	}
+	defer func() { _ = f.Close() }()
+
+	// Get file size
+	info, err := f.Stat()
+	if err != nil {
+		result.Error = fmt.Errorf("failed to stat file: %w", err)
+		return file
+	}
+
+	read.r = s.backoff.reader(device, f)
+	file.content, result.Error = s.pool.readPooled(read, s.readSize(info.Size()))
+	if file.content != nil {
+		// Matching is done with the content once the file is released
+		content, release := file.content, file.release
+		file.release = func() {
+			s.pool.put(content)
+			release()
+		}
+	}
+	return file
+}
+
+// readContent reads up to limit bytes (no limit if negative) from r
+func readContent(r io.Reader, limit int64) ([]byte, error) {
+	if limit >= 0 {
+		r = io.LimitReader(r, limit)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return content, nil
+}
@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStageWorkers(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  ScanOptions
+		read  int
+		match int
+	}{
+		{"derived", ScanOptions{Workers: 4}, 8, 4},
+		{"read rate", ScanOptions{Workers: 4, ReadRate: 20 << 20}, 2, 4},
+		{"set", ScanOptions{Workers: 4, ReadWorkers: 3, MatchWorkers: 6}, 3, 6},
+		{"match workers only", ScanOptions{Workers: 4, MatchWorkers: 2}, 4, 2},
+		{"no workers", ScanOptions{}, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read, match := tt.opts.stageWorkers()
+			if read != tt.read || match != tt.match {
+				t.Errorf("got %d read and %d match workers, want %d and %d", read, match, tt.read, tt.match)
+			}
+		})
+	}
+}
+
+func TestScanStages(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 20 {
+		content := "<?php echo 'hello';"
+		if i%5 == 0 {
+			content = "<?php eval($_POST['x']);"
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.php", i)), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	// A read budget of one file at a time makes the readers wait for the
+	// matcher to finish each file before reading the next
+	s := NewScanner(createTestSignatureSet(), WithReadWorkers(4), WithMatchWorkers(1), WithMaxBytesInFlight(32))
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range results {
+	}
+	stats := s.GetStats()
+	if stats.FilesScanned != 20 || stats.FilesMatched != 4 || stats.FilesErrored != 0 {
+		t.Errorf("expected 20 files scanned and 4 matched, got %+v", stats)
+	}
+	if used := s.readBudget.used; used != 0 {
+		t.Errorf("expected the read budget returned, %d bytes still held", used)
+	}
+}
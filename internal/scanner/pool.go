@@ -0,0 +1,190 @@
+// Package scanner provides pooling of the buffers file content is read into
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// DefaultReadPoolMemory bounds the idle read buffers kept for reuse
+const DefaultReadPoolMemory = 32 << 20
+
+const (
+	// minPoolBuffer and maxPoolBuffer are the smallest and largest pooled
+	// buffers. Larger files are read into buffers of their own.
+	minPoolBuffer = 4 << 10
+	maxPoolBuffer = 16 << 20
+)
+
+// poolSizes are the buffer sizes of the tiers: powers of two from
+// minPoolBuffer to maxPoolBuffer and halfway between each, so content
+// fills at least two thirds of its buffer
+var poolSizes = func() []int64 {
+	var sizes []int64
+	for size := int64(minPoolBuffer); size <= maxPoolBuffer; size *= 2 {
+		sizes = append(sizes, size)
+		if size < maxPoolBuffer {
+			sizes = append(sizes, size+size/2)
+		}
+	}
+	return sizes
+}()
+
+// PoolTierStats describes how the buffers of one size were reused
+type PoolTierStats struct {
+	Size int64 `json:"size"`
+	// Gets counts the files read into a buffer of this size, and Hits
+	// those that reused an idle one
+	Gets int64 `json:"gets"`
+	Hits int64 `json:"hits"`
+	// Dropped counts the buffers not kept, for want of room in the pool
+	Dropped int64 `json:"dropped"`
+	Idle    int   `json:"idle"`
+}
+
+// ReadPoolState describes the pool of read buffers
+type ReadPoolState struct {
+	Limit int64 `json:"limit"`
+	// Idle is the memory in idle buffers now, and PeakIdle the most held
+	Idle     int64 `json:"idle"`
+	PeakIdle int64 `json:"peak_idle"`
+	// Drains counts the times idle buffers were freed near the memory limit
+	Drains int64           `json:"drains"`
+	Tiers  []PoolTierStats `json:"tiers"`
+}
+
+// readPool keeps the buffers of files matched for the files read next, so
+// content isn't allocated afresh for every file. Buffers come in tiers of
+// sizes; a file is read into the smallest that holds it. The idle buffers
+// are bounded in total, keeping the process's memory bounded on small
+// hosts. A nil pool allocates every buffer.
+type readPool struct {
+	mu    sync.Mutex
+	idle  [][][]byte
+	state ReadPoolState
+}
+
+func newReadPool(limit int64) *readPool {
+	if limit <= 0 {
+		return nil
+	}
+	p := &readPool{
+		idle:  make([][][]byte, len(poolSizes)),
+		state: ReadPoolState{Limit: limit, Tiers: make([]PoolTierStats, len(poolSizes))},
+	}
+	for i, size := range poolSizes {
+		p.state.Tiers[i].Size = size
+	}
+	return p
+}
+
+// poolTier returns the tier of the smallest buffers holding n bytes, or -1
+// if n is too large to pool
+func poolTier(n int64) int {
+	i := sort.Search(len(poolSizes), func(i int) bool { return poolSizes[i] >= n })
+	if i == len(poolSizes) {
+		return -1
+	}
+	return i
+}
+
+// get returns an empty buffer with room for n bytes
+func (p *readPool) get(n int64) []byte {
+	tier := poolTier(n)
+	if p == nil || n <= 0 || tier < 0 {
+		return make([]byte, 0, n)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := &p.state.Tiers[tier]
+	stats.Gets++
+	if idle := p.idle[tier]; len(idle) > 0 {
+		buf := idle[len(idle)-1]
+		p.idle[tier] = idle[:len(idle)-1]
+		stats.Hits++
+		stats.Idle--
+		p.state.Idle -= stats.Size
+		return buf[:0]
+	}
+	return make([]byte, 0, stats.Size)
+}
+
+// put keeps buf for reuse, if it came from the pool and there is room
+func (p *readPool) put(buf []byte) {
+	if p == nil || buf == nil {
+		return
+	}
+	tier := poolTier(int64(cap(buf)))
+	if tier < 0 || poolSizes[tier] != int64(cap(buf)) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := &p.state.Tiers[tier]
+	if p.state.Idle+stats.Size > p.state.Limit {
+		stats.Dropped++
+		return
+	}
+	p.idle[tier] = append(p.idle[tier], buf)
+	stats.Idle++
+	p.state.Idle += stats.Size
+	p.state.PeakIdle = max(p.state.PeakIdle, p.state.Idle)
+}
+
+// drain frees the idle buffers, for the memory guard near the limit
+func (p *readPool) drain() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state.Idle == 0 {
+		return
+	}
+	for i := range p.idle {
+		p.idle[i] = nil
+		p.state.Tiers[i].Idle = 0
+	}
+	p.state.Idle = 0
+	p.state.Drains++
+}
+
+// states returns the pool's state and the stats of the tiers used
+func (p *readPool) states() *ReadPoolState {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state := p.state
+	state.Tiers = nil
+	for _, tier := range p.state.Tiers {
+		if tier.Gets > 0 {
+			state.Tiers = append(state.Tiers, tier)
+		}
+	}
+	return &state
+}
+
+// readPooled reads up to n bytes from r into a buffer from the pool. A
+// file that shrank since its size was taken is read as it is now.
+func (p *readPool) readPooled(r io.Reader, n int64) ([]byte, error) {
+	buf := p.get(n)
+	read, err := io.ReadFull(r, buf[:n])
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		p.put(buf)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return buf[:read], nil
+}
+
+// WithReadPoolMemory bounds the idle read buffers kept for reuse by n
+// bytes (0 disables pooling)
+func WithReadPoolMemory(n int64) Option {
+	return func(s *Scanner) {
+		s.options.ReadPoolMemory = n
+	}
+}
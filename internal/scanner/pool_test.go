@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPoolTier(t *testing.T) {
+	tests := []struct {
+		n    int64
+		size int64
+	}{
+		{1, 4 << 10},
+		{4 << 10, 4 << 10},
+		{4<<10 + 1, 6 << 10},
+		{100 << 10, 128 << 10},
+		{130 << 10, 192 << 10},
+		{16 << 20, 16 << 20},
+	}
+	for _, tt := range tests {
+		if tier := poolTier(tt.n); tier < 0 || poolSizes[tier] != tt.size {
+			t.Errorf("%d bytes: expected %d byte buffers, got tier %d", tt.n, tt.size, tier)
+		}
+	}
+	if tier := poolTier(16<<20 + 1); tier != -1 {
+		t.Errorf("expected content over the largest tier not to be pooled, got tier %d", tier)
+	}
+}
+
+func TestReadPoolReuse(t *testing.T) {
+	p := newReadPool(256 << 10)
+
+	buf := p.get(100 << 10)
+	if len(buf) != 0 || cap(buf) != 128<<10 {
+		t.Fatalf("unexpected buffer of length %d and capacity %d", len(buf), cap(buf))
+	}
+	p.put(buf[:100<<10])
+	if again := p.get(110 << 10); cap(again) != 128<<10 || &again[:1][0] != &buf[:1][0] {
+		t.Error("expected the idle buffer to be reused")
+	}
+
+	// Buffers not from the pool, or past its limit, aren't kept
+	p.put(make([]byte, 0, 100<<10))
+	p.put(make([]byte, 0, 128<<10))
+	p.put(make([]byte, 0, 128<<10))
+	p.put(make([]byte, 0, 128<<10))
+
+	state := p.states()
+	if state.Idle != 256<<10 || state.PeakIdle != 256<<10 {
+		t.Errorf("expected the pool to stop at its limit, got %+v", state)
+	}
+	if len(state.Tiers) != 1 {
+		t.Fatalf("expected only the tier used to be reported, got %+v", state.Tiers)
+	}
+	if tier := state.Tiers[0]; tier.Size != 128<<10 || tier.Gets != 2 || tier.Hits != 1 || tier.Dropped != 1 || tier.Idle != 2 {
+		t.Errorf("unexpected tier stats %+v", tier)
+	}
+
+	p.drain()
+	if state := p.states(); state.Idle != 0 || state.Drains != 1 || state.Tiers[0].Idle != 0 {
+		t.Errorf("expected the idle buffers to be freed, got %+v", state)
+	}
+
+	var none *readPool
+	if buf := none.get(10); cap(buf) != 10 {
+		t.Errorf("expected a buffer of its own without a pool, got capacity %d", cap(buf))
+	}
+	none.put(buf)
+	none.drain()
+	if none.states() != nil {
+		t.Error("expected no state without a pool")
+	}
+}
+
+// failingReader fails after its content
+type failingReader struct{ r io.Reader }
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errors.New("input/output error")
+	}
+	return n, err
+}
+
+func TestReadPooled(t *testing.T) {
+	p := newReadPool(1 << 20)
+
+	content, err := p.readPooled(strings.NewReader("<?php echo 'hello';"), 19)
+	if err != nil || string(content) != "<?php echo 'hello';" {
+		t.Errorf("unexpected content %q, %v", content, err)
+	}
+
+	// Files that shrank are read as they are, and those that grew only up
+	// to their size
+	if content, err := p.readPooled(strings.NewReader("short"), 100); err != nil || string(content) != "short" {
+		t.Errorf("unexpected content of a shrunk file %q, %v", content, err)
+	}
+	if content, err := p.readPooled(strings.NewReader("grown file"), 5); err != nil || string(content) != "grown" {
+		t.Errorf("unexpected content of a grown file %q, %v", content, err)
+	}
+	if content, err := p.readPooled(strings.NewReader(""), 0); err != nil || content == nil || len(content) != 0 {
+		t.Errorf("expected empty content, got %q, %v", content, err)
+	}
+
+	// The buffer of a failed read goes back to the pool
+	if _, err := p.readPooled(failingReader{bytes.NewReader(make([]byte, 10))}, 100); err == nil {
+		t.Error("expected the read to fail")
+	}
+	if state := p.states(); state.Idle != 4<<10 {
+		t.Errorf("expected the failed read's buffer to be idle, got %+v", state)
+	}
+}
+
+func TestScanReusesReadBuffers(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 20 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.php", i)), []byte("<?php echo 'hello';"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithScanWorkers(1), WithReadWorkers(1))
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for result := range results {
+		if result.Error != nil {
+			t.Errorf("%s: %v", result.Path, result.Error)
+		}
+	}
+
+	pool := s.GetStats().ReadPool
+	if pool == nil || len(pool.Tiers) != 1 || pool.Tiers[0].Gets != 20 || pool.Tiers[0].Hits == 0 {
+		t.Fatalf("expected the read buffers to be reused, got %+v", pool)
+	}
+	if pool.Limit != DefaultReadPoolMemory {
+		t.Errorf("expected the default limit, got %d", pool.Limit)
+	}
+}
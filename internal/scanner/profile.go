@@ -0,0 +1,92 @@
+// Package scanner provides built-in profiles of scan settings
+package scanner
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Built-in profiles, from lightest on the host to heaviest
+const (
+	ProfileGentle     = "gentle"
+	ProfileBalanced   = "balanced"
+	ProfileAggressive = "aggressive"
+	ProfileAdaptive   = "adaptive"
+)
+
+// ProfileSettings are the settings that decide how hard a scan works the
+// host
+type ProfileSettings struct {
+	// Workers is how many files are scanned at once (0 is one per CPU)
+	Workers int `json:"workers"`
+	// MaxReadMemoryMB bounds the file content held at once, in MiB
+	MaxReadMemoryMB int64 `json:"max_read_memory_mb"`
+	// IOLimitMBps bounds how fast files are read in all (0 is unbounded)
+	IOLimitMBps float64 `json:"io_limit_mbps"`
+	// ContentLimitMB is how much of each file is read (0 is all of it)
+	ContentLimitMB int64 `json:"content_limit_mb"`
+	// FileDelay is how long each worker waits after each file
+	FileDelay time.Duration `json:"file_delay"`
+	// BatchSize and BatchPause stop all reads for BatchPause after every
+	// BatchSize files (0 never does)
+	BatchSize  int           `json:"batch_size"`
+	BatchPause time.Duration `json:"batch_pause"`
+	// ReadLatencyTarget slows reads from a device once its latency passes
+	// it (0 never does)
+	ReadLatencyTarget time.Duration `json:"read_latency_target"`
+	// MemoryLimitMB is the memory an adaptive scan stays under (0 is none)
+	MemoryLimitMB int64 `json:"memory_limit_mb"`
+	// Adaptive throttles the scan while the host is busy or short of
+	// memory
+	Adaptive bool `json:"adaptive"`
+}
+
+// DefaultProfiles returns the built-in profiles, sized to this host's CPUs:
+// gentle for hosts serving traffic, balanced for most scheduled scans,
+// aggressive for idle hosts and incident response, and adaptive to back
+// off whenever the sites get busy
+func DefaultProfiles() map[string]ProfileSettings {
+	cpus := runtime.NumCPU()
+	return map[string]ProfileSettings{
+		ProfileGentle: {
+			Workers:           max(cpus/4, 1),
+			MaxReadMemoryMB:   64,
+			IOLimitMBps:       20,
+			FileDelay:         5 * time.Millisecond,
+			ReadLatencyTarget: DefaultReadLatencyTarget,
+		},
+		ProfileBalanced: {
+			Workers:           max(cpus/2, 1),
+			MaxReadMemoryMB:   128,
+			ReadLatencyTarget: 50 * time.Millisecond,
+		},
+		ProfileAggressive: {
+			Workers:         cpus,
+			MaxReadMemoryMB: 512,
+		},
+		ProfileAdaptive: {
+			Workers:           cpus,
+			MaxReadMemoryMB:   DefaultMaxBytesInFlight >> 20,
+			ReadLatencyTarget: DefaultReadLatencyTarget,
+			MemoryLimitMB:     512,
+			Adaptive:          true,
+		},
+	}
+}
+
+// Flags returns the settings as the malware-scan flags that set them
+func (p ProfileSettings) Flags() map[string]string {
+	return map[string]string{
+		"workers":             strconv.Itoa(p.Workers),
+		"max-read-memory":     strconv.FormatInt(p.MaxReadMemoryMB, 10),
+		"max-read-rate":       strconv.FormatFloat(p.IOLimitMBps, 'f', -1, 64),
+		"content-limit":       strconv.FormatInt(p.ContentLimitMB, 10),
+		"file-delay":          p.FileDelay.String(),
+		"batch-size":          strconv.Itoa(p.BatchSize),
+		"batch-pause":         p.BatchPause.String(),
+		"read-latency-target": p.ReadLatencyTarget.String(),
+		"memory-limit":        strconv.FormatInt(p.MemoryLimitMB, 10),
+		"adaptive":            strconv.FormatBool(p.Adaptive),
+	}
+}
@@ -0,0 +1,117 @@
+// Package scanner provides retries of files that failed to read for a while
+package scanner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/telemetry"
+)
+
+// DefaultMaxRetries is how many times a file that failed with a retryable
+// error is scanned again
+const DefaultMaxRetries = 3
+
+// DefaultRetryWait is how long the scan waits before the first retry; the
+// wait doubles on each retry after it
+const DefaultRetryWait = time.Second
+
+// maxRetryWait caps the wait between retries
+const maxRetryWait = 30 * time.Second
+
+// retryQueueSize bounds the files held to be retried; files failing once
+// it is full are reported as failed
+const retryQueueSize = 10000
+
+// retryQueue holds files that failed with retryable errors, to scan again
+// once the rest of the scan is done
+type retryQueue struct {
+	mu    sync.Mutex
+	tasks []fileTask
+}
+
+// add queues a file to retry, reporting false if the queue is full
+func (q *retryQueue) add(task fileTask) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.tasks) >= retryQueueSize {
+		return false
+	}
+	q.tasks = append(q.tasks, task)
+	return true
+}
+
+// take empties the queue, returning the files in it
+func (q *retryQueue) take() []fileTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tasks := q.tasks
+	q.tasks = nil
+	return tasks
+}
+
+// retryable reports whether a file that failed with err may scan if tried
+// again
+func retryable(err error) bool {
+	return err != nil && ScanError{Code: ClassifyError(err)}.IsRetryable()
+}
+
+// retryWait returns how long to wait before the given retry, counting from 1
+func (s *Scanner) retryWait(attempt int) time.Duration {
+	wait := s.options.RetryWait
+	for i := 1; i < attempt && wait < maxRetryWait; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetryWait)
+}
+
+// retryFailed scans the queued files again, backing off between attempts,
+// until they scan or run out of retries, and finishes them
+func (s *Scanner) retryFailed(ctx context.Context, progress *scanProgress, queue *retryQueue, results chan<- *ScanResult) {
+	tasks := queue.take()
+	if len(tasks) > 0 {
+		var span *telemetry.Span
+		ctx, span = telemetry.Start(ctx, StageRetry, telemetry.Int("retry.files", len(tasks)))
+		defer span.End()
+		s.events.emit(ProgressEvent{Type: ProgressStage, Stage: StageRetry})
+		defer s.events.emit(ProgressEvent{Type: ProgressStage, Stage: StageRetry, Done: true})
+	}
+	for attempt := 1; len(tasks) > 0; attempt++ {
+		s.logger.Verbose("Retrying %d files in %v (attempt %d of %d)", len(tasks), s.retryWait(attempt), attempt, s.options.MaxRetries)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.retryWait(attempt)):
+		}
+
+		var again []fileTask
+		for _, task := range tasks {
+			if progress.pastDeadline() {
+				progress.dropped.Add(1)
+				continue
+			}
+			result := s.scanFile(ctx, task)
+			if attempt < s.options.MaxRetries && retryable(result.Error) {
+				again = append(again, task)
+				continue
+			}
+			if !s.finishTask(ctx, progress, task, result, results) {
+				return
+			}
+		}
+		tasks = again
+	}
+}
+
+// queueRetry holds a file that failed to scan to be retried, reporting
+// whether it was
+func (s *Scanner) queueRetry(queue *retryQueue, task fileTask, result *ScanResult) bool {
+	if s.options.MaxRetries <= 0 || !retryable(result.Error) || !queue.add(task) {
+		return false
+	}
+	s.logger.Debug("Will retry %s: %v", task.path, result.Error)
+	atomic.AddInt64(&s.stats.FilesRetried, 1)
+	return true
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("failed to read file: %w", &fs.PathError{Op: "read", Path: "x", Err: syscall.EAGAIN}), true},
+		{fmt.Errorf("failed to open file: %w", syscall.ESTALE), true},
+		{fmt.Errorf("failed to open file: %w", fs.ErrPermission), false},
+		{fmt.Errorf("%w: socket", ErrSpecialFile), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	s := NewScanner(createTestSignatureSet())
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: maxRetryWait} {
+		if got := s.retryWait(attempt); got != want {
+			t.Errorf("retryWait(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shell.php")
+	s := NewScanner(createTestSignatureSet(), WithRetryWait(time.Millisecond))
+	queue := &retryQueue{}
+	task := fileTask{path: path}
+
+	// A file that can't be read for now is held rather than reported
+	failed := &ScanResult{Path: path, Error: fmt.Errorf("failed to read file: %w", syscall.EAGAIN)}
+	if !s.queueRetry(queue, task, failed) {
+		t.Fatal("expected the file to be queued to retry")
+	}
+	if stats := s.GetStats(); stats.FilesRetried != 1 || stats.FilesErrored != 0 {
+		t.Errorf("expected 1 retried file and none errored, got %+v", stats)
+	}
+	if s.queueRetry(queue, task, &ScanResult{Path: path, Error: fs.ErrPermission}) {
+		t.Error("expected a permission error not to be retried")
+	}
+
+	// and scanned once it can be
+	if err := os.WriteFile(path, []byte("<?php eval($x);"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	results := make(chan *ScanResult, 1)
+	s.retryFailed(context.Background(), newScanProgress([]string{path}, 0, nil), queue, results)
+	close(results)
+	result := <-results
+	if result == nil || result.Error != nil || !result.HasMatches() {
+		t.Errorf("expected the retried file to match, got %+v", result)
+	}
+	if stats := s.GetStats(); stats.FilesScanned != 1 {
+		t.Errorf("expected the retried file to be counted as scanned, got %+v", stats)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+// Package scanner provides the open file limit on platforms without one
+package scanner
+
+// openFileLimit returns 0 on platforms without RLIMIT_NOFILE
+func openFileLimit() uint64 {
+	return 0
+}
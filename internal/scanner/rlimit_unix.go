@@ -0,0 +1,15 @@
+//go:build unix
+
+// Package scanner provides the open file limit on Unix
+package scanner
+
+import "syscall"
+
+// openFileLimit returns the soft RLIMIT_NOFILE value, or 0 if it cannot be read
+func openFileLimit() uint64 {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0
+	}
+	return uint64(rl.Cur) // #nosec G115 -- Cur is never negative
+}
@@ -0,0 +1,231 @@
+// Package scanner provides the classification of paths that couldn't be scanned
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// ScanErrorCode classifies why a path was not scanned, or what else failed
+type ScanErrorCode string
+
+// Codes of paths that failed to scan
+const (
+	CodePermissionDenied ScanErrorCode = "permission-denied"
+	CodeNotFound         ScanErrorCode = "not-found"
+	CodeSpecialFile      ScanErrorCode = "special-file"
+	CodeTimeout          ScanErrorCode = "timeout"
+	CodeUnavailable      ScanErrorCode = "temporarily-unavailable"
+	CodeCircuitOpen      ScanErrorCode = "circuit-open"
+	CodeIOError          ScanErrorCode = "io-error"
+)
+
+// Codes of failures talking to Wordfence or other services
+const (
+	CodeRateLimited ScanErrorCode = "rate-limited"
+	CodeHTTPError   ScanErrorCode = "http-error"
+)
+
+// Codes of paths skipped by the scan's limits and options
+const (
+	CodeSymlinkNotFollowed ScanErrorCode = "symlink-not-followed"
+	CodePathTooLong        ScanErrorCode = "path-too-long"
+	CodeDepthLimit         ScanErrorCode = "depth-limit"
+	CodeDirectoryTruncated ScanErrorCode = "directory-truncated"
+	CodeBinary             ScanErrorCode = "binary"
+)
+
+// Skip reports whether the code is for a path skipped rather than failed
+func (c ScanErrorCode) Skip() bool {
+	switch c {
+	case CodeSymlinkNotFollowed, CodePathTooLong, CodeDepthLimit, CodeDirectoryTruncated, CodeBinary:
+		return true
+	}
+	return false
+}
+
+// ClassifyError returns the code of an error during a scan
+func ClassifyError(err error) ScanErrorCode {
+	if httpErr, ok := api.IsHTTPError(err); ok {
+		if httpErr.StatusCode == http.StatusTooManyRequests {
+			return CodeRateLimited
+		}
+		return CodeHTTPError
+	}
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return CodePermissionDenied
+	case errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, ErrSpecialFile):
+		return CodeSpecialFile
+	case errors.Is(err, ErrCircuitOpen):
+		return CodeCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EINTR), errors.Is(err, syscall.EBUSY), errors.Is(err, syscall.ESTALE):
+		return CodeUnavailable
+	}
+	return CodeIOError
+}
+
+// Stages of a scan errors are recorded at
+const (
+	StageWalk        = "walk"
+	StageScan        = "scan"
+	StageSignatures  = "signatures"
+	StageRemediation = "remediation"
+)
+
+// ScanError is a path that was not scanned, or another failure during a
+// scan. Path is empty for failures that aren't of a path, such as a
+// signature refresh.
+type ScanError struct {
+	Stage   string        `json:"stage"`
+	Path    string        `json:"path,omitempty"`
+	Code    ScanErrorCode `json:"code"`
+	Message string        `json:"message"`
+}
+
+// IsRetryable reports whether the path may scan if tried again, as the
+// failure is likely to pass
+func (e ScanError) IsRetryable() bool {
+	switch e.Code {
+	case CodeUnavailable, CodeTimeout, CodeRateLimited, CodeCircuitOpen:
+		return true
+	}
+	return false
+}
+
+// DefaultErrorPathLimit is how many paths are kept for each code; the rest
+// are only counted
+const DefaultErrorPathLimit = 1000
+
+// ScanErrorStats collects the paths a scan could not scan, and its other
+// failures, by code
+type ScanErrorStats struct {
+	mu     sync.Mutex
+	limit  int
+	counts map[ScanErrorCode]int64
+	paths  map[ScanErrorCode][]ScanError
+}
+
+// NewScanErrorStats creates a collector keeping up to limit paths per code
+func NewScanErrorStats(limit int) *ScanErrorStats {
+	e := &ScanErrorStats{limit: limit}
+	e.Reset()
+	return e
+}
+
+// Reset forgets every path recorded
+func (e *ScanErrorStats) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.counts = make(map[ScanErrorCode]int64)
+	e.paths = make(map[ScanErrorCode][]ScanError)
+}
+
+// Record adds a path that was not scanned
+func (e *ScanErrorStats) Record(scanErr ScanError) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.counts[scanErr.Code]++
+	if len(e.paths[scanErr.Code]) < e.limit {
+		e.paths[scanErr.Code] = append(e.paths[scanErr.Code], scanErr)
+	}
+}
+
+// RecordError adds an error at stage, classifying it
+func (e *ScanErrorStats) RecordError(stage, path string, err error) {
+	e.Record(ScanError{Stage: stage, Path: path, Code: ClassifyError(err), Message: err.Error()})
+}
+
+// Counts returns how many errors were recorded with each code
+func (e *ScanErrorStats) Counts() map[ScanErrorCode]int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	counts := make(map[ScanErrorCode]int64, len(e.counts))
+	for code, count := range e.counts {
+		counts[code] = count
+	}
+	return counts
+}
+
+// ScanErrorGroup is the paths recorded with one code. Count may exceed the
+// paths listed.
+type ScanErrorGroup struct {
+	Code  ScanErrorCode `json:"code"`
+	Count int64         `json:"count"`
+	Paths []ScanError   `json:"paths"`
+}
+
+// ScanErrorReport lists the paths a scan failed to scan and those it skipped
+type ScanErrorReport struct {
+	Errored []ScanErrorGroup `json:"errored"`
+	Skipped []ScanErrorGroup `json:"skipped"`
+}
+
+// Report groups the paths recorded by code, most frequent first
+func (e *ScanErrorStats) Report() *ScanErrorReport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	report := &ScanErrorReport{Errored: []ScanErrorGroup{}, Skipped: []ScanErrorGroup{}}
+	for code, count := range e.counts {
+		group := ScanErrorGroup{Code: code, Count: count, Paths: append([]ScanError(nil), e.paths[code]...)}
+		if code.Skip() {
+			report.Skipped = append(report.Skipped, group)
+		} else {
+			report.Errored = append(report.Errored, group)
+		}
+	}
+	for _, groups := range [][]ScanErrorGroup{report.Errored, report.Skipped} {
+		sort.Slice(groups, func(i, k int) bool {
+			if groups[i].Count != groups[k].Count {
+				return groups[i].Count > groups[k].Count
+			}
+			return groups[i].Code < groups[k].Code
+		})
+	}
+	return report
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *ScanErrorReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("encoding error report: %w", err)
+	}
+	return nil
+}
+
+// ErrErrorBudget indicates a scan was stopped because too many files failed
+var ErrErrorBudget = errors.New("error budget exceeded")
+
+// errorBudgetMinFiles is how many files are scanned before the error
+// budget can stop a scan, so a few early failures don't
+const errorBudgetMinFiles = 100
+
+// overErrorBudget returns an ErrErrorBudget error if more than budget
+// percent of the files checked failed, once at least minFiles have been
+func overErrorBudget(budget float64, errored, scanned, minFiles int64) error {
+	checked := errored + scanned
+	if budget <= 0 || checked == 0 || checked < minFiles {
+		return nil
+	}
+	if percent := float64(errored) * 100 / float64(checked); percent > budget {
+		return fmt.Errorf("%w: %d of %d files (%.1f%%) failed, more than %g%%", ErrErrorBudget, errored, checked, percent, budget)
+	}
+	return nil
+}
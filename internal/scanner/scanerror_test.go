@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ScanErrorCode
+	}{
+		{fmt.Errorf("failed to open file: %w", &fs.PathError{Op: "open", Path: "x", Err: fs.ErrPermission}), CodePermissionDenied},
+		{fmt.Errorf("failed to open file: %w", fs.ErrNotExist), CodeNotFound},
+		{fmt.Errorf("%w: named pipe", ErrSpecialFile), CodeSpecialFile},
+		{fmt.Errorf("waiting: %w", context.DeadlineExceeded), CodeTimeout},
+		{errors.New("input/output error"), CodeIOError},
+		{fmt.Errorf("request failed after 3 attempts: %w", &api.HTTPError{StatusCode: http.StatusTooManyRequests}), CodeRateLimited},
+		{&api.HTTPError{StatusCode: http.StatusBadGateway}, CodeHTTPError},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestScanErrorStatsReport(t *testing.T) {
+	e := NewScanErrorStats(2)
+	for i := range 3 {
+		e.Record(ScanError{Path: fmt.Sprintf("/denied/%d.php", i), Code: CodePermissionDenied})
+	}
+	e.Record(ScanError{Path: "/missing.php", Code: CodeNotFound})
+	e.Record(ScanError{Path: "/link.php", Code: CodeSymlinkNotFollowed})
+
+	report := e.Report()
+	if len(report.Errored) != 2 || len(report.Skipped) != 1 {
+		t.Fatalf("expected 2 error groups and 1 skip group, got %+v", report)
+	}
+	denied := report.Errored[0]
+	if denied.Code != CodePermissionDenied || denied.Count != 3 || len(denied.Paths) != 2 {
+		t.Errorf("expected 3 denied paths with 2 listed first, got %+v", denied)
+	}
+	if report.Skipped[0].Code != CodeSymlinkNotFollowed {
+		t.Errorf("expected the symlink to be reported as skipped, got %+v", report.Skipped)
+	}
+
+	e.Reset()
+	if counts := e.Counts(); len(counts) != 0 {
+		t.Errorf("expected no counts after a reset, got %v", counts)
+	}
+}
+
+func TestOverErrorBudget(t *testing.T) {
+	tests := []struct {
+		budget                     float64
+		errored, scanned, minFiles int64
+		over                       bool
+	}{
+		{10, 11, 89, 100, true},
+		{10, 10, 90, 100, false},
+		{10, 5, 5, 100, false},
+		{10, 5, 5, 0, true},
+		{0, 100, 0, 0, false},
+	}
+	for _, tt := range tests {
+		err := overErrorBudget(tt.budget, tt.errored, tt.scanned, tt.minFiles)
+		if over := errors.Is(err, ErrErrorBudget); over != tt.over {
+			t.Errorf("overErrorBudget(%g, %d, %d, %d) = %v, want over %v", tt.budget, tt.errored, tt.scanned, tt.minFiles, err, tt.over)
+		}
+	}
+}
+
+func TestScanReportsSkippedPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.php", "a/b/deep.php", "photo.php"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		content := "<?php echo 1;"
+		if name == "photo.php" {
+			content = jpegHeader
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "index.php"), filepath.Join(dir, "link.php")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	s := NewScanner(createTestSignatureSet(), WithMaxDepth(1), WithSkipBinary(true), WithErrorBudget(1))
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range results {
+	}
+
+	counts := s.GetStats().Errors
+	for _, code := range []ScanErrorCode{CodeDepthLimit, CodeBinary, CodeSymlinkNotFollowed} {
+		if counts[code] != 1 {
+			t.Errorf("expected one %s path, got %v", code, counts)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("expected skipped paths not to count against the error budget, got %v", err)
+	}
+}
+
+func TestRefreshSignaturesRecordsErrors(t *testing.T) {
+	s := NewScanner(createTestSignatureSet())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.RefreshSignatures(ctx, time.Millisecond, func(context.Context, *intel.SignatureSet) (*intel.SignatureSet, error) {
+		return nil, &api.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.GetStats().Errors[CodeRateLimited] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the failed refresh to be recorded as rate limited")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	group := s.ScanErrors().Report().Errored[0]
+	if group.Paths[0].Stage != StageSignatures || group.Paths[0].Path != "" {
+		t.Errorf("expected a signatures stage error without a path, got %+v", group.Paths[0])
+	}
+}
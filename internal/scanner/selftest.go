@@ -0,0 +1,188 @@
+// Package scanner provides a self-test measuring how signatures do on a
+// sample corpus
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+// Subdirectories of a self-test corpus holding samples that should and
+// should not match
+const (
+	CorpusMalicious = "malicious"
+	CorpusClean     = "clean"
+)
+
+// SignatureHits is how often a signature matched the corpus
+type SignatureHits struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Malicious int    `json:"malicious"`
+	Clean     int    `json:"clean"`
+}
+
+// SelfTestMiss is a sample the signatures got wrong: a malicious one they
+// missed, or a clean one they matched
+type SelfTestMiss struct {
+	Path       string `json:"path"`
+	Signatures []int  `json:"signatures,omitempty"`
+	// Timeouts are signatures that ran out of time on the sample, which
+	// may be why a malicious one was missed
+	Timeouts []int `json:"timeouts,omitempty"`
+}
+
+// SelfTestResult is how the signatures did against a corpus
+type SelfTestResult struct {
+	MatcherStats
+	CompileSeconds float64 `json:"compile_seconds"`
+	MatchSeconds   float64 `json:"match_seconds"`
+
+	Malicious         int     `json:"malicious"`
+	Detected          int     `json:"detected"`
+	DetectionRate     float64 `json:"detection_rate"`
+	Clean             int     `json:"clean"`
+	FalsePositives    int     `json:"false_positives"`
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+	Timeouts          int     `json:"timeouts"`
+
+	Missed             []SelfTestMiss  `json:"missed"`
+	FalsePositiveFiles []SelfTestMiss  `json:"false_positive_files"`
+	Hits               []SignatureHits `json:"hits"`
+}
+
+// SelfTest matches every sample in the malicious and clean subdirectories
+// of corpus against every signature, and reports how many malicious
+// samples were detected, how many clean ones matched, and how often each
+// signature matched. A corpus needs at least one of the subdirectories.
+func SelfTest(ctx context.Context, sigSet *intel.SignatureSet, corpus string, opts ...MatcherOption) (*SelfTestResult, error) {
+	samples := make(map[string][]string)
+	for _, kind := range []string{CorpusMalicious, CorpusClean} {
+		paths, err := corpusSamples(filepath.Join(corpus, kind))
+		if err != nil {
+			return nil, err
+		}
+		samples[kind] = paths
+	}
+	if len(samples[CorpusMalicious]) == 0 && len(samples[CorpusClean]) == 0 {
+		return nil, fmt.Errorf("no samples in %s: put them in its %s and %s directories", corpus, CorpusMalicious, CorpusClean)
+	}
+
+	start := time.Now()
+	m := NewMatcher(sigSet, append(opts, WithMatchAll(true))...)
+	result := &SelfTestResult{
+		MatcherStats:       m.Stats(),
+		CompileSeconds:     time.Since(start).Seconds(),
+		Malicious:          len(samples[CorpusMalicious]),
+		Clean:              len(samples[CorpusClean]),
+		Missed:             []SelfTestMiss{},
+		FalsePositiveFiles: []SelfTestMiss{},
+	}
+
+	start = time.Now()
+	hits := make(map[int]*SignatureHits)
+	for _, kind := range []string{CorpusMalicious, CorpusClean} {
+		for _, path := range samples[kind] {
+			content, err := os.ReadFile(path) // #nosec G304 -- sample in the corpus the user named
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+			mc := m.NewMatchContext()
+			if err := mc.Match(ctx, content); err != nil {
+				return nil, fmt.Errorf("matching %s: %w", path, err)
+			}
+			ids := matchedSignatures(mc.GetMatches())
+			sample := SelfTestMiss{Path: path, Signatures: ids, Timeouts: mc.GetTimeouts()}
+			result.Timeouts += len(sample.Timeouts)
+			for _, id := range ids {
+				h := hits[id]
+				if h == nil {
+					h = &SignatureHits{ID: id}
+					if sig, err := sigSet.GetSignature(id); err == nil {
+						h.Name = sig.Name
+					}
+					hits[id] = h
+				}
+				if kind == CorpusMalicious {
+					h.Malicious++
+				} else {
+					h.Clean++
+				}
+			}
+
+			switch {
+			case kind == CorpusMalicious && len(ids) > 0:
+				result.Detected++
+			case kind == CorpusMalicious:
+				result.Missed = append(result.Missed, sample)
+			case len(ids) > 0:
+				result.FalsePositives++
+				result.FalsePositiveFiles = append(result.FalsePositiveFiles, sample)
+			}
+		}
+	}
+	result.MatchSeconds = time.Since(start).Seconds()
+	if result.Malicious > 0 {
+		result.DetectionRate = float64(result.Detected) / float64(result.Malicious)
+	}
+	if result.Clean > 0 {
+		result.FalsePositiveRate = float64(result.FalsePositives) / float64(result.Clean)
+	}
+
+	result.Hits = make([]SignatureHits, 0, len(hits))
+	for _, h := range hits {
+		result.Hits = append(result.Hits, *h)
+	}
+	// Signatures matching clean samples first, as those need looking at
+	sort.Slice(result.Hits, func(i, j int) bool {
+		a, b := result.Hits[i], result.Hits[j]
+		if a.Clean != b.Clean {
+			return a.Clean > b.Clean
+		}
+		if a.Malicious != b.Malicious {
+			return a.Malicious > b.Malicious
+		}
+		return a.ID < b.ID
+	})
+	return result, nil
+}
+
+// corpusSamples returns the regular files under dir, which may not exist
+func corpusSamples(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && len(paths) == 0) {
+		return nil, fmt.Errorf("reading corpus: %w", err)
+	}
+	return paths, nil
+}
+
+// matchedSignatures returns the sorted IDs of the signatures that matched
+func matchedSignatures(matches []*MatchResult) []int {
+	seen := make(map[int]bool, len(matches))
+	var ids []int
+	for _, match := range matches {
+		if !seen[match.SignatureID] {
+			seen[match.SignatureID] = true
+			ids = append(ids, match.SignatureID)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
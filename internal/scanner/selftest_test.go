@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+func TestSelfTest(t *testing.T) {
+	ss := intel.NewSignatureSet()
+	ss.Signatures[1] = intel.NewSignature(1, `eval\(base64_decode\(`, "Eval Base64", "", []int{})
+	ss.Signatures[2] = intel.NewSignature(2, `\$_POST\[`, "Post Input", "", []int{})
+	ss.Signatures[3] = intel.NewSignature(3, `never_matches_anything`, "Unused", "", []int{})
+
+	corpus := t.TempDir()
+	samples := map[string]string{
+		"malicious/shell.php":      `<?php eval(base64_decode($_POST['x']));`,
+		"malicious/nested/b64.php": `<?php eval(base64_decode('ZWNobyAx'));`,
+		"malicious/missed.php":     `<?php system($_GET['c']);`,
+		"clean/form.php":           `<?php echo htmlspecialchars($_POST['name']);`,
+		"clean/hello.php":          `<?php echo "hello";`,
+	}
+	for name, content := range samples {
+		path := filepath.Join(corpus, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := SelfTest(context.Background(), ss, corpus)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Malicious != 3 || result.Detected != 2 || result.Clean != 2 || result.FalsePositives != 1 {
+		t.Errorf("unexpected counts %+v", result)
+	}
+	if result.DetectionRate != 2.0/3 || result.FalsePositiveRate != 0.5 {
+		t.Errorf("unexpected rates %v and %v", result.DetectionRate, result.FalsePositiveRate)
+	}
+	if len(result.Missed) != 1 || result.Missed[0].Path != filepath.Join(corpus, "malicious/missed.php") {
+		t.Errorf("unexpected missed samples %+v", result.Missed)
+	}
+	if fp := result.FalsePositiveFiles; len(fp) != 1 || len(fp[0].Signatures) != 1 || fp[0].Signatures[0] != 2 {
+		t.Errorf("unexpected false positives %+v", fp)
+	}
+
+	want := []SignatureHits{
+		{ID: 2, Name: "Post Input", Malicious: 1, Clean: 1},
+		{ID: 1, Name: "Eval Base64", Malicious: 2},
+	}
+	if len(result.Hits) != len(want) {
+		t.Fatalf("unexpected hits %+v", result.Hits)
+	}
+	for i, h := range want {
+		if result.Hits[i] != h {
+			t.Errorf("hit %d = %+v, want %+v", i, result.Hits[i], h)
+		}
+	}
+}
+
+func TestSelfTestEmptyCorpus(t *testing.T) {
+	if _, err := SelfTest(context.Background(), intel.NewSignatureSet(), t.TempDir()); err == nil {
+		t.Error("expected an error for a corpus without samples")
+	}
+}
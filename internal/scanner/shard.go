@@ -0,0 +1,228 @@
+// Package scanner provides partitioning of a scan across cooperating processes
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shard is the part of a scan handled by one of several processes or
+// hosts. Files are assigned by a hash of their absolute path, so processes
+// walking the same paths agree on the split without coordinating.
+type Shard struct {
+	// Index is this shard's number, from 1 to Count
+	Index int
+	Count int
+}
+
+// ParseShard parses a shard given as "index/count", such as "2/8"
+func ParseShard(s string) (Shard, error) {
+	index, count, ok := strings.Cut(s, "/")
+	if !ok {
+		return Shard{}, fmt.Errorf("invalid shard %q: expected index/count, such as 2/8", s)
+	}
+	i, err1 := strconv.Atoi(strings.TrimSpace(index))
+	n, err2 := strconv.Atoi(strings.TrimSpace(count))
+	if err1 != nil || err2 != nil || n < 1 || i < 1 || i > n {
+		return Shard{}, fmt.Errorf("invalid shard %q: expected index/count with 1 <= index <= count", s)
+	}
+	return Shard{Index: i, Count: n}, nil
+}
+
+// String formats the shard as "index/count"
+func (sh Shard) String() string {
+	return fmt.Sprintf("%d/%d", sh.Index, sh.Count)
+}
+
+// Enabled reports whether the scan is split at all
+func (sh Shard) Enabled() bool {
+	return sh.Count > 1
+}
+
+// Contains reports whether the file at absPath belongs to this shard
+func (sh Shard) Contains(absPath string) bool {
+	if !sh.Enabled() {
+		return true
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(absPath))
+	return h.Sum64()%uint64(sh.Count) == uint64(sh.Index-1) // #nosec G115 -- Count and Index are validated positive
+}
+
+// WithShard limits the scan to the files in shard
+func WithShard(shard Shard) Option {
+	return func(s *Scanner) {
+		s.options.Shard = shard
+	}
+}
+
+// ShardReport is the outcome of one shard of a scan, saved so the shards'
+// statistics can be merged
+type ShardReport struct {
+	Index        int       `json:"index"`
+	Count        int       `json:"count"`
+	Host         string    `json:"host"`
+	Paths        []string  `json:"paths"`
+	Started      time.Time `json:"started"`
+	Finished     time.Time `json:"finished"`
+	FilesScanned int64     `json:"files_scanned"`
+	FilesMatched int64     `json:"files_matched"`
+	FilesSkipped int64     `json:"files_skipped"`
+	FilesErrored int64     `json:"files_errored"`
+	DirsSkipped  int64     `json:"dirs_skipped"`
+	BytesScanned int64     `json:"bytes_scanned"`
+	Matches      int       `json:"matches"`
+}
+
+// NewShardReport records the statistics of a finished shard
+func NewShardReport(shard Shard, paths []string, stats ScanStats, matches int) *ShardReport {
+	host, _ := os.Hostname()
+	return &ShardReport{
+		Index:        shard.Index,
+		Count:        shard.Count,
+		Host:         host,
+		Paths:        paths,
+		Started:      stats.StartTime,
+		Finished:     stats.EndTime,
+		FilesScanned: stats.FilesScanned,
+		FilesMatched: stats.FilesMatched,
+		FilesSkipped: stats.FilesSkipped,
+		FilesErrored: stats.FilesErrored,
+		DirsSkipped:  stats.DirsSkipped,
+		BytesScanned: stats.BytesScanned,
+		Matches:      matches,
+	}
+}
+
+// SaveShardReport writes a shard's report to dir, replacing any earlier
+// report for the same shard
+func SaveShardReport(dir string, report *ShardReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding shard report: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating shard report directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".shard-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing shard report: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing shard report: %w", err)
+	}
+	name := fmt.Sprintf("shard-%d-of-%d.json", report.Index, report.Count)
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("saving shard report: %w", err)
+	}
+	return nil
+}
+
+// LoadShardReports reads the shard reports in dir, ordered by index
+func LoadShardReports(dir string) ([]*ShardReport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading shard reports: %w", err)
+	}
+
+	var reports []*ShardReport
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "shard-") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name)) // #nosec G304 -- file in the shard report directory
+		if err != nil {
+			return nil, fmt.Errorf("reading shard report: %w", err)
+		}
+		var report ShardReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("parsing shard report %s: %w", name, err)
+		}
+		reports = append(reports, &report)
+	}
+	sort.Slice(reports, func(i, k int) bool { return reports[i].Index < reports[k].Index })
+	return reports, nil
+}
+
+// ShardSummary merges the statistics of a sharded scan
+type ShardSummary struct {
+	Count        int       `json:"count"`
+	Complete     []int     `json:"complete"`
+	Missing      []int     `json:"missing,omitempty"`
+	Started      time.Time `json:"started"`
+	Finished     time.Time `json:"finished"`
+	FilesScanned int64     `json:"files_scanned"`
+	FilesMatched int64     `json:"files_matched"`
+	FilesSkipped int64     `json:"files_skipped"`
+	FilesErrored int64     `json:"files_errored"`
+	DirsSkipped  int64     `json:"dirs_skipped"`
+	BytesScanned int64     `json:"bytes_scanned"`
+	Matches      int       `json:"matches"`
+}
+
+// Done reports whether every shard has reported
+func (s *ShardSummary) Done() bool {
+	return len(s.Missing) == 0
+}
+
+// MergeShardReports totals the reports of one sharded scan. Every shard
+// walks all directories, so skipped directories are not summed.
+func MergeShardReports(reports []*ShardReport) (*ShardSummary, error) {
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no shard reports")
+	}
+
+	summary := &ShardSummary{Count: reports[0].Count}
+	seen := make(map[int]bool)
+	for _, r := range reports {
+		if r.Count != summary.Count {
+			return nil, fmt.Errorf("shard reports are from scans split %d and %d ways", summary.Count, r.Count)
+		}
+		if seen[r.Index] {
+			continue
+		}
+		seen[r.Index] = true
+		summary.Complete = append(summary.Complete, r.Index)
+
+		if summary.Started.IsZero() || r.Started.Before(summary.Started) {
+			summary.Started = r.Started
+		}
+		if r.Finished.After(summary.Finished) {
+			summary.Finished = r.Finished
+		}
+		summary.FilesScanned += r.FilesScanned
+		summary.FilesMatched += r.FilesMatched
+		summary.FilesSkipped += r.FilesSkipped
+		summary.FilesErrored += r.FilesErrored
+		summary.DirsSkipped = max(summary.DirsSkipped, r.DirsSkipped)
+		summary.BytesScanned += r.BytesScanned
+		summary.Matches += r.Matches
+	}
+	sort.Ints(summary.Complete)
+
+	for i := 1; i <= summary.Count; i++ {
+		if !seen[i] {
+			summary.Missing = append(summary.Missing, i)
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseShard(t *testing.T) {
+	shard, err := ParseShard("2/8")
+	if err != nil || shard.Index != 2 || shard.Count != 8 || shard.String() != "2/8" {
+		t.Errorf("unexpected shard %+v, %v", shard, err)
+	}
+	for _, invalid := range []string{"", "2", "0/8", "9/8", "a/b", "1/0"} {
+		if _, err := ParseShard(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+	if !(Shard{}).Contains("/any/path") {
+		t.Error("expected an unsharded scan to contain every file")
+	}
+}
+
+func TestShardsPartitionFiles(t *testing.T) {
+	dir := t.TempDir()
+	const files = 40
+	for i := 0; i < files; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.php", i)), []byte("<?php"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	const count = 3
+	seen := make(map[string]int)
+	var reports []*ShardReport
+	for index := 1; index <= count; index++ {
+		shard := Shard{Index: index, Count: count}
+		s := NewScanner(createTestSignatureSet(), WithShard(shard))
+		results, err := s.Scan(context.Background(), dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n := 0
+		for result := range results {
+			seen[result.Path]++
+			n++
+		}
+		if n == 0 || n == files {
+			t.Errorf("expected shard %s to scan part of the files, got %d", shard, n)
+		}
+		reports = append(reports, NewShardReport(shard, []string{dir}, s.GetStats(), 0))
+	}
+
+	if len(seen) != files {
+		t.Errorf("expected every file scanned, got %d of %d", len(seen), files)
+	}
+	for path, n := range seen {
+		if n != 1 {
+			t.Errorf("expected %s scanned by one shard, got %d", path, n)
+		}
+	}
+
+	summary, err := MergeShardReports(reports)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !summary.Done() || summary.FilesScanned != files {
+		t.Errorf("expected merged stats for all files, got %+v", summary)
+	}
+}
+
+func TestShardReportsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	for _, index := range []int{3, 1} {
+		report := NewShardReport(Shard{Index: index, Count: 4}, []string{"/srv"}, ScanStats{
+			StartTime:    start.Add(time.Duration(index) * time.Second),
+			EndTime:      start.Add(time.Duration(index) * time.Minute),
+			FilesScanned: 10,
+			FilesMatched: 1,
+			DirsSkipped:  2,
+		}, 2)
+		if err := SaveShardReport(dir, report); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+
+	reports, err := LoadShardReports(dir)
+	if err != nil || len(reports) != 2 || reports[0].Index != 1 {
+		t.Fatalf("expected reports ordered by index, got %v, %v", reports, err)
+	}
+	summary, err := MergeShardReports(reports)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Done() || len(summary.Missing) != 2 || summary.Missing[0] != 2 || summary.Missing[1] != 4 {
+		t.Errorf("expected shards 2 and 4 missing, got %v", summary.Missing)
+	}
+	if summary.FilesScanned != 20 || summary.Matches != 4 || summary.DirsSkipped != 2 {
+		t.Errorf("unexpected totals %+v", summary)
+	}
+	if !summary.Started.Equal(reports[0].Started) || !summary.Finished.Equal(reports[1].Finished) {
+		t.Errorf("expected merged time span, got %v - %v", summary.Started, summary.Finished)
+	}
+
+	mixed := append(reports, &ShardReport{Index: 1, Count: 2})
+	if _, err := MergeShardReports(mixed); err == nil {
+		t.Error("expected error for reports with different shard counts")
+	}
+}
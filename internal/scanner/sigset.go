@@ -0,0 +1,129 @@
+// Package scanner provides single-pass gating of signature groups
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSetSize bounds how many signatures are combined into one set, keeping
+// each combined pattern small enough to compile and match quickly
+const maxSetSize = 32
+
+// dottedCapitalI is the one letter regexp2 folds to an ASCII letter ("i")
+// that Go's case folding does not
+var dottedCapitalI = []byte("İ")
+
+// signatureSet gates a group of signatures with a single pass of Go's
+// linear-time RE2 engine. The combined pattern matches at least everything
+// its signatures do, so when it doesn't match a file none of them can and
+// they are skipped without running regexp2.
+type signatureSet struct {
+	category   string
+	re         gate
+	signatures []*CompiledSignature
+}
+
+// gate is a compiled combined pattern, from Go's regexp package or, with
+// RegexEngineRE2, the native RE2 library
+type gate interface {
+	Match(content []byte) bool
+}
+
+// compileGate compiles a combined pattern for the engine. Patterns the
+// native library rejects, such as those over its memory limit, fall back
+// to Go's regexp package.
+func compileGate(engine, pattern string) (gate, error) {
+	if engine == RegexEngineRE2 && compileNativeGate != nil {
+		if g, err := compileNativeGate(pattern); err == nil {
+			return g, nil
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re, nil
+}
+
+// buildSignatureSets groups the RE2-compatible signatures by category,
+// compiling each group's gate for the engine. Signatures that can't be
+// translated are returned to be matched one by one, with their gateError
+// set.
+func buildSignatureSets(sigs []*CompiledSignature, engine string) ([]*signatureSet, []*CompiledSignature) {
+	var ungrouped []*CompiledSignature
+	patterns := make(map[*CompiledSignature]string)
+	byCategory := make(map[string][]*CompiledSignature)
+	for _, sig := range sigs {
+		pattern, err := gatePattern(sig.Signature.Rule)
+		if err != nil {
+			sig.gateError = err
+			ungrouped = append(ungrouped, sig)
+			continue
+		}
+		patterns[sig] = pattern
+		byCategory[sig.Signature.Category] = append(byCategory[sig.Signature.Category], sig)
+	}
+
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var sets []*signatureSet
+	for _, category := range categories {
+		group := byCategory[category]
+		sort.Slice(group, func(i, k int) bool { return group[i].Signature.ID < group[k].Signature.ID })
+
+		for start := 0; start < len(group); start += maxSetSize {
+			members := group[start:min(start+maxSetSize, len(group))]
+			alternatives := make([]string, 0, len(members))
+			for _, sig := range members {
+				alternatives = append(alternatives, "(?:"+patterns[sig]+")")
+			}
+			re, err := compileGate(engine, "(?ms)"+strings.Join(alternatives, "|"))
+			if err != nil {
+				for _, sig := range members {
+					sig.gateError = fmt.Errorf("%s: %w", reasonGoRegexp, err)
+				}
+				ungrouped = append(ungrouped, members...)
+				continue
+			}
+			sets = append(sets, &signatureSet{category: category, re: re, signatures: members})
+		}
+	}
+	return sets, ungrouped
+}
+
+// gatePattern translates a rule for a gate, checking that Go's regexp
+// accepts the translation
+func gatePattern(rule string) (string, error) {
+	pattern, err := translateRE2(rule)
+	if err != nil {
+		return "", err
+	}
+	if _, err := regexp.Compile("(?ms)" + pattern); err != nil {
+		return "", fmt.Errorf("%s: %w", reasonGoRegexp, err)
+	}
+	return pattern, nil
+}
+
+// mayMatch reports whether any of the set's signatures can match content
+func (s *signatureSet) mayMatch(content []byte) bool {
+	return s.re.Match(content)
+}
+
+// canGate reports whether content can be gated by the engine's signature
+// sets at all. Go reads invalid UTF-8 as U+FFFD, as regexp2 does, but the
+// native RE2 library doesn't, so it only gates valid UTF-8.
+func canGate(content []byte, engine string) bool {
+	if engine == RegexEngineRE2 && !utf8.Valid(content) {
+		return false
+	}
+	return !bytes.Contains(content, dottedCapitalI)
+}
@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+func TestBuildSignatureSets(t *testing.T) {
+	var sigs []*CompiledSignature
+	for id := 1; id <= maxSetSize+1; id++ {
+		sig := intel.NewSignature(id, fmt.Sprintf("marker%d;", id), "Marker", "", nil)
+		sig.Category = "backdoor"
+		sigs = append(sigs, &CompiledSignature{Signature: sig})
+	}
+	other := intel.NewSignature(100, `shell_exec`, "Shell", "", nil)
+	other.Category = "webshell"
+	backref := intel.NewSignature(101, `(foo)\1`, "Backreference", "", nil)
+	sigs = append(sigs, &CompiledSignature{Signature: other}, &CompiledSignature{Signature: backref})
+
+	sets, ungrouped := buildSignatureSets(sigs, RegexEngineAuto)
+	if len(ungrouped) != 1 || ungrouped[0].Signature.ID != 101 {
+		t.Fatalf("expected only the backreference signature ungrouped, got %d", len(ungrouped))
+	}
+	if reason := gateReason(ungrouped[0].gateError); reason != reasonBackreference {
+		t.Errorf("expected the backreference reported, got %q", reason)
+	}
+	if len(sets) != 3 {
+		t.Fatalf("expected 3 sets, got %d", len(sets))
+	}
+	if sets[0].category != "backdoor" || len(sets[0].signatures) != maxSetSize || len(sets[1].signatures) != 1 {
+		t.Errorf("expected backdoor signatures split at %d, got %d and %d", maxSetSize, len(sets[0].signatures), len(sets[1].signatures))
+	}
+	if sets[2].category != "webshell" {
+		t.Errorf("expected webshell set last, got %s", sets[2].category)
+	}
+
+	if !sets[0].mayMatch([]byte("x marker7; y")) || sets[0].mayMatch([]byte("clean")) {
+		t.Error("expected set to match only content with one of its markers")
+	}
+}
+
+func TestCanGate(t *testing.T) {
+	invalid := []byte("eval(\xff)")
+	if !canGate(invalid, RegexEngineAuto) {
+		t.Error("expected Go's engine to gate invalid UTF-8")
+	}
+	if canGate(invalid, RegexEngineRE2) {
+		t.Error("expected the native engine not to gate invalid UTF-8")
+	}
+	if canGate([]byte("İ"), RegexEngineAuto) {
+		t.Error("expected content with a dotted capital I not to be gated")
+	}
+}
+
+func TestMatcherSkipsGatedSets(t *testing.T) {
+	// Backtracks exponentially if run, but Go's engine proves it can't match
+	ss := intel.NewSignatureSet()
+	ss.Signatures[1] = intel.NewSignature(1, `(a+)+b`, "Backtracking", "", nil)
+	ss.Signatures[2] = intel.NewSignature(2, `eval\s*\(`, "Eval", "", nil)
+	ss.Signatures[1].Category = "test"
+	ss.Signatures[2].Category = "backdoor"
+	m := NewMatcher(ss, WithMatchTimeout(10*time.Millisecond), WithMatchAll(true))
+
+	mc := m.NewMatchContext()
+	content := []byte(strings.Repeat("a", 64) + " eval　($x)")
+	if err := mc.Match(context.Background(), content); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mc.GetTimeouts()) != 0 {
+		t.Errorf("expected backtracking signature skipped, got timeouts %v", mc.GetTimeouts())
+	}
+	if matches := mc.GetMatches(); len(matches) != 1 || matches[0].SignatureID != 2 {
+		t.Errorf("expected gated signature 2 to match, got %v", matches)
+	}
+
+	// Content Go's case folding differs on runs every signature
+	mc = m.NewMatchContext()
+	if err := mc.Match(context.Background(), []byte("İ "+strings.Repeat("a", 64))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timeouts := mc.GetTimeouts(); len(timeouts) != 1 || timeouts[0] != 1 {
+		t.Errorf("expected signature 1 run without gating, got timeouts %v", timeouts)
+	}
+}
@@ -0,0 +1,168 @@
+// Package scanner provides content sniffing to skip genuine media files
+package scanner
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// PatternImageUploads matches the image extensions uploads are commonly
+// given to disguise PHP
+var PatternImageUploads = regexp.MustCompile(`(?i)\.(?:jpe?g|png|gif|webp|bmp|ico|tiff?)$`)
+
+// FilterImageUploads returns true if the path has an image extension
+func FilterImageUploads(path string) bool {
+	return PatternImageUploads.MatchString(path)
+}
+
+// EmbeddedPHPCategory is the match category for PHP code in files that
+// should never contain it
+const EmbeddedPHPCategory = "embedded-php"
+
+// PatternAssets matches images, stylesheets and fonts: files that never
+// contain PHP, whatever the filter includes
+var PatternAssets = regexp.MustCompile(`(?i)\.(?:jpe?g|png|gif|webp|bmp|ico|tiff?|css|woff2?|ttf|otf|eot)$`)
+
+// FilterAssets returns true if the path has an image, stylesheet or font
+// extension
+func FilterAssets(path string) bool {
+	return PatternAssets.MatchString(path)
+}
+
+// phpOpenTags start PHP code. The short "<?" tag is left out: it turns up
+// by chance in binary data far too often.
+var phpOpenTags = [][]byte{[]byte("<?php"), []byte("<?=")}
+
+// ContainsPHP reports whether content has a PHP open tag, so PHP would run
+// it whatever its extension or format
+func ContainsPHP(content []byte) bool {
+	for _, tag := range phpOpenTags {
+		if bytes.Contains(content, tag) {
+			return true
+		}
+	}
+	// <?php is case-insensitive, and checking the common case first avoids
+	// folding most files
+	return bytes.Contains(foldASCII(content), phpOpenTags[0])
+}
+
+// phpOpenTag returns the position of the first <?php tag that opens code,
+// being followed by whitespace or the end of content, or -1 if there is
+// none. Unlike "<?=", it is too long to turn up by chance in binary data.
+func phpOpenTag(content []byte) int {
+	folded := foldASCII(content)
+	for offset := 0; ; {
+		i := bytes.Index(folded[offset:], phpOpenTags[0])
+		if i < 0 {
+			return -1
+		}
+		end := offset + i + len(phpOpenTags[0])
+		if end == len(folded) || isSpaceByte(folded[end]) {
+			return offset + i
+		}
+		offset = end
+	}
+}
+
+// isSpaceByte reports whether c is ASCII whitespace
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// maxEmbeddedPHPSnippet bounds the code reported for embedded PHP
+const maxEmbeddedPHPSnippet = 80
+
+// findEmbeddedPHP reports PHP code in an image, stylesheet or font. Any of
+// them carrying PHP is a polyglot upload waiting for an include to run it.
+func (s *Scanner) findEmbeddedPHP(path string, content []byte) []*MatchResult {
+	if !s.detectsEmbeddedPHP() || !FilterAssets(path) {
+		return nil
+	}
+	pos := phpOpenTag(content)
+	if pos < 0 {
+		return nil
+	}
+	snippet := content[pos:min(pos+maxEmbeddedPHPSnippet, len(content))]
+	return []*MatchResult{{
+		Category:      EmbeddedPHPCategory,
+		MatchedString: string(bytes.ToValidUTF8(snippet, []byte("\uFFFD"))),
+		Position:      pos,
+	}}
+}
+
+// SniffBinary returns the name of the binary format content starts with,
+// recognized by its magic bytes, or "" if it isn't a known binary format
+func SniffBinary(content []byte) string {
+	has := func(offset int, magic string) bool {
+		return len(content) >= offset+len(magic) && string(content[offset:offset+len(magic)]) == magic
+	}
+	switch {
+	case has(0, "\xff\xd8\xff"):
+		return "JPEG"
+	case has(0, "\x89PNG\r\n\x1a\n"):
+		return "PNG"
+	case has(0, "GIF87a"), has(0, "GIF89a"):
+		return "GIF"
+	case has(0, "RIFF") && has(8, "WEBP"):
+		return "WebP"
+	case has(0, "BM") && has(6, "\x00\x00\x00\x00"):
+		return "BMP"
+	case has(0, "\x00\x00\x01\x00"):
+		return "ICO"
+	case has(0, "II*\x00"), has(0, "MM\x00*"):
+		return "TIFF"
+	case has(0, "RIFF") && has(8, "WAVE"):
+		return "WAV"
+	case has(0, "RIFF") && has(8, "AVI "):
+		return "AVI"
+	case has(4, "ftyp"):
+		return "MP4"
+	case has(0, "ID3"), has(0, "\xff\xfb"):
+		return "MP3"
+	case has(0, "OggS"):
+		return "Ogg"
+	case has(0, "\x1aE\xdf\xa3"):
+		return "WebM"
+	case has(0, "%PDF-"):
+		return "PDF"
+	case has(0, "PK\x03\x04"):
+		return "ZIP"
+	case has(0, "\x1f\x8b"):
+		return "gzip"
+	case has(0, "\xfd7zXZ\x00"):
+		return "xz"
+	case has(0, "7z\xbc\xaf\x27\x1c"):
+		return "7-Zip"
+	case has(0, "Rar!\x1a\x07"):
+		return "RAR"
+	case has(0, "\x7fELF"):
+		return "ELF"
+	case has(0, "wOFF"), has(0, "wOF2"):
+		return "WOFF"
+	}
+	return ""
+}
+
+// skipBinary returns why content shouldn't be matched with SkipBinary, or
+// "" if it should be: binary formats are skipped unless they contain PHP
+func (s *Scanner) skipBinary(content []byte) string {
+	if !s.options.SkipBinary || ContainsPHP(content) {
+		return ""
+	}
+	if name := SniffBinary(content); name != "" {
+		return name + " content"
+	}
+	return ""
+}
+
+// matchesImagesWithPHP reports whether path is an image that is matched
+// against the signatures when it contains PHP
+func (s *Scanner) matchesImagesWithPHP(path string) bool {
+	return s.options.ScanImagesWithPHP && FilterImageUploads(path)
+}
+
+// walksForPHP reports whether a file the filter leaves out is still read
+// to look for PHP in it
+func (s *Scanner) walksForPHP(path string) bool {
+	return s.matchesImagesWithPHP(path) || s.detectsEmbeddedPHP() && FilterAssets(path)
+}
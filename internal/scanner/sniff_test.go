@@ -0,0 +1,180 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// jpegHeader is the start of a JFIF file
+const jpegHeader = "\xff\xd8\xff\xe0\x00\x10JFIF\x00"
+
+func TestSniffBinary(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{jpegHeader, "JPEG"},
+		{"\x89PNG\r\n\x1a\n\x00\x00", "PNG"},
+		{"GIF89a\x01\x00", "GIF"},
+		{"RIFF\x24\x00\x00\x00WEBPVP8 ", "WebP"},
+		{"\x00\x00\x00\x18ftypmp42", "MP4"},
+		{"PK\x03\x04\x14\x00", "ZIP"},
+		{"\x7fELF\x02\x01", "ELF"},
+		{"<?php eval($x);", ""},
+		{"GIF", ""},
+		{"BMW cars", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := SniffBinary([]byte(tt.content)); got != tt.want {
+			t.Errorf("SniffBinary(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestContainsPHP(t *testing.T) {
+	for content, want := range map[string]bool{
+		jpegHeader + "<?php system($_GET['c']); ?>": true,
+		jpegHeader + "<?PhP system($_GET['c']);":    true,
+		"<?= $x ?>":                                 true,
+		jpegHeader + "<? \x00\xff":                  false,
+		"<script>alert(1)</script>":                 false,
+	} {
+		if got := ContainsPHP([]byte(content)); got != want {
+			t.Errorf("ContainsPHP(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
+
+func TestScanSniffsContent(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.php":     "<?php eval($_POST['x']);",
+		"renamed.php":   jpegHeader + "eval(not code)",
+		"polyglot.php":  jpegHeader + "<?php eval($_POST['x']);",
+		"photo.jpg":     jpegHeader + "eval(not code)",
+		"upload.gif":    "GIF89a<?php eval($_POST['x']);",
+		"readme.txt":    "eval(",
+		"page.html":     "<script>eval(atob(x))</script>",
+		"shell.php.jpg": "<?php eval($_POST['x']);",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		matched []string
+		skipped int64
+	}{
+		{"by extension", nil, []string{"index.php", "page.html", "polyglot.php", "renamed.php", "shell.php.jpg"}, 3},
+		{"skip binary", []Option{WithSkipBinary(true)}, []string{"index.php", "page.html", "polyglot.php", "shell.php.jpg"}, 4},
+		{"images with PHP", []Option{WithScanImagesWithPHP(true)}, []string{"index.php", "page.html", "polyglot.php", "renamed.php", "shell.php.jpg", "upload.gif"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append(tt.opts, WithScanMatchAll(true), WithEmbeddedPHPDetection(false))
+			s := NewScanner(createTestSignatureSet(), opts...)
+			results, err := s.Scan(context.Background(), dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var matched []string
+			for result := range results {
+				if result.HasMatches() {
+					matched = append(matched, filepath.Base(result.Path))
+				}
+			}
+			sort.Strings(matched)
+			if !slices.Equal(matched, tt.matched) {
+				t.Errorf("expected matches in %v, got %v", tt.matched, matched)
+			}
+			if stats := s.GetStats(); stats.FilesSkipped != tt.skipped {
+				t.Errorf("expected %d skipped files, got %d", tt.skipped, stats.FilesSkipped)
+			}
+		})
+	}
+}
+
+func TestPHPOpenTag(t *testing.T) {
+	for content, want := range map[string]int{
+		jpegHeader + "<?php system($_GET['c']);": len(jpegHeader),
+		"GIF89a<?PHP\nsystem($x);":               6,
+		"<?phpinfo <?php":                        10,
+		"<?php":                                  0,
+		"<?= $x ?> <? x":                         -1,
+	} {
+		if got := phpOpenTag([]byte(content)); got != want {
+			t.Errorf("phpOpenTag(%q) = %d, want %d", content, got, want)
+		}
+	}
+}
+
+func TestScanEmbeddedPHP(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.php":                      "<?php echo 1;",
+		"uploads/avatar.jpg":             jpegHeader + "<?php system($_GET['c']); ?>",
+		"uploads/photo.jpg":              jpegHeader + "<?= not code",
+		"uploads/favicon.ico":            "\x00\x00\x01\x00<?php\n@eval($_POST[1]);",
+		"style.css":                      "body { color: red; }",
+		"readme.txt":                     "<?php example(); ?>",
+		"uploads/excluded/shell.png":     "<?php system($x);",
+		"uploads/excluded/ignored.woff2": "<?php system($x);",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	filter, err := NewFilterFromConfig(&FilterConfig{ExcludeGlobs: []string{"uploads/excluded/*"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		s := NewScanner(createTestSignatureSet(), WithScanFilter(filter), WithEmbeddedPHPDetection(enabled))
+		results, err := s.Scan(context.Background(), dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var matched []string
+		for result := range results {
+			for _, match := range result.Matches {
+				if match.Category != EmbeddedPHPCategory {
+					t.Errorf("unexpected %s match in %s", match.Category, result.Path)
+					continue
+				}
+				if match.Name == "" || match.MatchedString[:5] != "<?php" {
+					t.Errorf("unexpected match %+v", match)
+				}
+				rel, _ := filepath.Rel(dir, result.Path)
+				matched = append(matched, filepath.ToSlash(rel))
+			}
+		}
+		sort.Strings(matched)
+
+		want := []string{"uploads/avatar.jpg", "uploads/favicon.ico"}
+		scanned := int64(3)
+		if !enabled {
+			want, scanned = nil, 1
+		}
+		if !slices.Equal(matched, want) {
+			t.Errorf("detection %v: expected embedded PHP in %v, got %v", enabled, want, matched)
+		}
+		if stats := s.GetStats(); stats.FilesScanned != scanned || stats.FilesScanned+stats.FilesSkipped != int64(len(files)) {
+			t.Errorf("detection %v: expected %d scanned files of %d, got %+v", enabled, scanned, len(files), stats)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package scanner provides the machine-readable totals of a scan
+package scanner
+
+import (
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+// ScanStatsReport is the machine-readable totals of a finished scan,
+// written with its results so automation doesn't have to count them
+type ScanStatsReport struct {
+	Kind         string `json:"kind"`
+	FilesScanned int64  `json:"files_scanned"`
+	FilesMatched int64  `json:"files_matched"`
+	FilesSkipped int64  `json:"files_skipped"`
+	FilesErrored int64  `json:"files_errored"`
+	FilesRetried int64  `json:"files_retried"`
+	DirsSkipped  int64  `json:"dirs_skipped"`
+	BytesScanned int64  `json:"bytes_scanned"`
+	Matches      int    `json:"matches"`
+	Suppressed   int    `json:"suppressed"`
+	// Errors counts the paths not scanned, and other failures, by code
+	Errors     map[ScanErrorCode]int64 `json:"errors"`
+	Signatures *ManifestSignatures     `json:"signatures,omitempty"`
+
+	Started         time.Time `json:"started"`
+	Finished        time.Time `json:"finished"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	// Stopped is set when the scan was cancelled or stopped early, so its
+	// totals cover only part of the paths
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// NewScanStatsReport reports the totals of a scan of kind, matched against
+// sigSet if not nil
+func NewScanStatsReport(kind string, stats ScanStats, sigSet *intel.SignatureSet) *ScanStatsReport {
+	r := &ScanStatsReport{
+		Kind:            kind,
+		FilesScanned:    stats.FilesScanned,
+		FilesMatched:    stats.FilesMatched,
+		FilesSkipped:    stats.FilesSkipped,
+		FilesErrored:    stats.FilesErrored,
+		FilesRetried:    stats.FilesRetried,
+		DirsSkipped:     stats.DirsSkipped,
+		BytesScanned:    stats.BytesScanned,
+		Errors:          make(map[ScanErrorCode]int64, len(stats.Errors)),
+		Started:         stats.StartTime,
+		Finished:        stats.EndTime,
+		DurationSeconds: stats.TotalDuration.Seconds(),
+	}
+	for code, count := range stats.Errors {
+		r.Errors[code] = count
+	}
+	if sigSet != nil {
+		m := &ScanManifest{}
+		m.SetSignatures(sigSet)
+		r.Signatures = m.Signatures
+	}
+	return r
+}
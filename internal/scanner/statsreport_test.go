@@ -0,0 +1,59 @@
+package scanner
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewScanStatsReport(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"bad.php": "<?php eval($x);", "good.php": "<?php echo 1;"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sigSet := createTestSignatureSet()
+	s := NewScanner(sigSet)
+	results, err := s.Scan(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range results {
+	}
+
+	report := NewScanStatsReport(ScanKindMalware, s.GetStats(), sigSet)
+	if report.FilesScanned != 2 || report.FilesMatched != 1 {
+		t.Errorf("expected 2 files scanned and 1 matched, got %+v", report)
+	}
+	if report.Signatures == nil || report.Signatures.Hash != hex.EncodeToString(sigSet.GetHash()) {
+		t.Errorf("expected the signature set hash, got %+v", report.Signatures)
+	}
+	if report.Finished.Before(report.Started) || report.DurationSeconds <= 0 {
+		t.Errorf("unexpected timing %v to %v (%vs)", report.Started, report.Finished, report.DurationSeconds)
+	}
+
+	stats := ScanStats{Errors: map[ScanErrorCode]int64{CodePermissionDenied: 2}}
+	if report := NewScanStatsReport(ScanKindMalware, stats, nil); report.Errors[CodePermissionDenied] != 2 {
+		t.Errorf("expected errors counted by code, got %v", report.Errors)
+	}
+
+	data, err := json.Marshal(NewScanStatsReport(ScanKindMalware, ScanStats{}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded["errors"].(map[string]any); !ok {
+		t.Errorf("expected an empty errors object without errors, got %s", data)
+	}
+	if _, ok := decoded["signatures"]; ok {
+		t.Errorf("expected no signatures without a signature set, got %s", data)
+	}
+}
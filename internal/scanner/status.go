@@ -0,0 +1,243 @@
+// Package scanner provides WordPress.org directory status checks
+package scanner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api/wporg"
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// DefaultAbandonedAge is how long an extension can go without an update
+// before it is considered abandoned
+const DefaultAbandonedAge = 2 * 365 * 24 * time.Hour
+
+// StatusFlag describes a maintenance problem with an installed extension
+type StatusFlag string
+
+// Status flags reported for installed extensions and core
+const (
+	StatusCurrent   StatusFlag = "current"
+	StatusOutdated  StatusFlag = "outdated"
+	StatusInsecure  StatusFlag = "insecure"
+	StatusEndOfLife StatusFlag = "eol"
+	StatusAbandoned StatusFlag = "abandoned"
+	StatusRemoved   StatusFlag = "removed"
+)
+
+// DirectoryClient looks up extensions and core releases on WordPress.org
+type DirectoryClient interface {
+	PluginInfo(ctx context.Context, slug string) (*wporg.ExtensionInfo, error)
+	ThemeInfo(ctx context.Context, slug string) (*wporg.ExtensionInfo, error)
+	CoreReleases(ctx context.Context) (map[string]string, error)
+}
+
+// ExtensionStatus is the directory status of an installed extension
+type ExtensionStatus struct {
+	SoftwareType  intel.SoftwareType
+	Slug          string
+	Name          string
+	Version       string
+	Path          string
+	LatestVersion string
+	// SecurityRelease is the newest release in the installed core branch,
+	// the smallest upgrade that picks up all security fixes
+	SecurityRelease string
+	LastUpdated     time.Time
+	ClosedReason    string
+	Flags           []StatusFlag
+}
+
+// HasFlag reports whether the status includes flag
+func (s *ExtensionStatus) HasFlag(flag StatusFlag) bool {
+	for _, f := range s.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChecker flags outdated, abandoned and removed extensions
+type StatusChecker struct {
+	client       DirectoryClient
+	abandonedAge time.Duration
+	now          func() time.Time
+	logger       *logging.Logger
+
+	coreOnce     sync.Once
+	coreReleases map[string]string
+	coreErr      error
+}
+
+// StatusCheckerOption configures a StatusChecker
+type StatusCheckerOption func(*StatusChecker)
+
+// WithAbandonedAge sets how long without updates counts as abandoned
+func WithAbandonedAge(age time.Duration) StatusCheckerOption {
+	return func(c *StatusChecker) {
+		c.abandonedAge = age
+	}
+}
+
+// WithStatusLogger sets the logger
+func WithStatusLogger(logger *logging.Logger) StatusCheckerOption {
+	return func(c *StatusChecker) {
+		c.logger = logger
+	}
+}
+
+// NewStatusChecker creates a status checker backed by client
+func NewStatusChecker(client DirectoryClient, opts ...StatusCheckerOption) *StatusChecker {
+	c := &StatusChecker{
+		client:       client,
+		abandonedAge: DefaultAbandonedAge,
+		now:          time.Now,
+		logger:       logging.New(logging.LevelInfo),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CheckSite returns the status of every flagged plugin and theme in site.
+// Extensions that are not in the directory are skipped.
+func (c *StatusChecker) CheckSite(ctx context.Context, site *wordpress.Site, checkPlugins, checkThemes bool) []*ExtensionStatus {
+	var statuses []*ExtensionStatus
+
+	if checkPlugins {
+		for _, plugin := range site.Plugins {
+			info, err := c.client.PluginInfo(ctx, plugin.Slug)
+			if status := c.evaluate(intel.SoftwareTypePlugin, &plugin.Extension, info, err); status != nil {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+
+	if checkThemes {
+		for _, theme := range site.Themes {
+			info, err := c.client.ThemeInfo(ctx, theme.Slug)
+			if status := c.evaluate(intel.SoftwareTypeTheme, &theme.Extension, info, err); status != nil {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+
+	return statuses
+}
+
+// evaluate compares an installed extension with its directory entry
+func (c *StatusChecker) evaluate(softwareType intel.SoftwareType, ext *wordpress.Extension, info *wporg.ExtensionInfo, err error) *ExtensionStatus {
+	if err != nil {
+		if !errors.Is(err, wporg.ErrNotInDirectory) {
+			c.logger.Debug("Directory lookup for %s %s failed: %v", softwareType, ext.Slug, err)
+		}
+		return nil
+	}
+
+	status := &ExtensionStatus{
+		SoftwareType:  softwareType,
+		Slug:          ext.Slug,
+		Name:          ext.Name,
+		Version:       ext.Version,
+		Path:          ext.Path,
+		LatestVersion: info.Version,
+		LastUpdated:   info.LastUpdated,
+	}
+
+	if info.Closed {
+		status.Flags = append(status.Flags, StatusRemoved)
+		status.ClosedReason = info.ClosedReason
+	}
+	if info.Version != "" && ext.Version != "" && intel.CompareVersions(ext.Version, info.Version) < 0 {
+		status.Flags = append(status.Flags, StatusOutdated)
+	}
+	if !info.LastUpdated.IsZero() && c.now().Sub(info.LastUpdated) > c.abandonedAge {
+		status.Flags = append(status.Flags, StatusAbandoned)
+	}
+
+	if len(status.Flags) == 0 {
+		return nil
+	}
+	return status
+}
+
+// CheckCore returns the release status of the site's WordPress core
+func (c *StatusChecker) CheckCore(ctx context.Context, site *wordpress.Site) (*ExtensionStatus, error) {
+	if site.Version == "" {
+		return nil, nil
+	}
+
+	c.coreOnce.Do(func() {
+		c.coreReleases, c.coreErr = c.client.CoreReleases(ctx)
+	})
+	if c.coreErr != nil {
+		return nil, c.coreErr
+	}
+
+	status := EvaluateCoreVersion(site.Version, c.coreReleases)
+	status.Path = site.CorePath
+	return status, nil
+}
+
+// EvaluateCoreVersion classifies a core version against the stable-check
+// release list. A branch whose releases are all insecure no longer
+// receives security fixes and is end-of-life.
+func EvaluateCoreVersion(version string, releases map[string]string) *ExtensionStatus {
+	status := &ExtensionStatus{
+		SoftwareType: intel.SoftwareTypeCore,
+		Slug:         "wordpress",
+		Name:         "WordPress",
+		Version:      version,
+	}
+
+	branch := coreBranch(version)
+	supported := false
+	for release, state := range releases {
+		if state == wporg.CoreLatest {
+			status.LatestVersion = release
+		}
+		if coreBranch(release) != branch {
+			continue
+		}
+		if state == wporg.CoreLatest || state == wporg.CoreOutdated {
+			supported = true
+		}
+		if status.SecurityRelease == "" || intel.CompareVersions(release, status.SecurityRelease) > 0 {
+			status.SecurityRelease = release
+		}
+	}
+	if intel.CompareVersions(status.SecurityRelease, version) <= 0 {
+		status.SecurityRelease = ""
+	}
+
+	switch {
+	case releases[version] == wporg.CoreLatest ||
+		(status.LatestVersion != "" && intel.CompareVersions(version, status.LatestVersion) >= 0):
+		status.Flags = []StatusFlag{StatusCurrent}
+	case !supported:
+		status.Flags = []StatusFlag{StatusEndOfLife}
+	case releases[version] == wporg.CoreInsecure || status.SecurityRelease != "":
+		// Only the newest release of a supported branch has every fix
+		status.Flags = []StatusFlag{StatusInsecure}
+	default:
+		status.Flags = []StatusFlag{StatusOutdated}
+	}
+	return status
+}
+
+// coreBranch returns the major.minor branch of a core version
+func coreBranch(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + strings.SplitN(parts[1], "-", 2)[0]
+}
@@ -0,0 +1,218 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/api/wporg"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+// fakeDirectory serves canned directory entries
+type fakeDirectory map[string]*wporg.ExtensionInfo
+
+func (f fakeDirectory) PluginInfo(_ context.Context, slug string) (*wporg.ExtensionInfo, error) {
+	return f.lookup("plugin:" + slug)
+}
+
+func (f fakeDirectory) ThemeInfo(_ context.Context, slug string) (*wporg.ExtensionInfo, error) {
+	return f.lookup("theme:" + slug)
+}
+
+func (f fakeDirectory) CoreReleases(_ context.Context) (map[string]string, error) {
+	return testCoreReleases, nil
+}
+
+func (f fakeDirectory) lookup(key string) (*wporg.ExtensionInfo, error) {
+	if key == "plugin:broken" {
+		return nil, errors.New("connection refused")
+	}
+	info, ok := f[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, wporg.ErrNotInDirectory)
+	}
+	return info, nil
+}
+
+func TestStatusCheckerCheckSite(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := fakeDirectory{
+		"plugin:current":  {Version: "1.2.0", LastUpdated: now.AddDate(0, -1, 0)},
+		"plugin:old":      {Version: "3.1", LastUpdated: now.AddDate(0, -2, 0)},
+		"plugin:stale":    {Version: "1.0", LastUpdated: now.AddDate(-3, 0, 0)},
+		"plugin:closed":   {Version: "2.0", LastUpdated: now.AddDate(0, -6, 0), Closed: true, ClosedReason: "Security Issue"},
+		"theme:ancient":   {Version: "1.0", LastUpdated: now.AddDate(-5, 0, 0)},
+		"theme:twentytwo": {Version: "1.9", LastUpdated: now.AddDate(0, -1, 0)},
+	}
+
+	plugin := func(slug, version string) *wordpress.Plugin {
+		return &wordpress.Plugin{Extension: wordpress.Extension{Slug: slug, Name: slug, Version: version}}
+	}
+	theme := func(slug, version string) *wordpress.Theme {
+		return &wordpress.Theme{Extension: wordpress.Extension{Slug: slug, Name: slug, Version: version}}
+	}
+	site := &wordpress.Site{
+		Plugins: []*wordpress.Plugin{
+			plugin("current", "1.2.0"),
+			plugin("old", "2.9"),
+			plugin("stale", "1.0"),
+			plugin("closed", "2.0"),
+			plugin("premium", "1.0"),
+			plugin("broken", "1.0"),
+		},
+		Themes: []*wordpress.Theme{
+			theme("ancient", "0.9"),
+			theme("twentytwo", "1.9"),
+		},
+	}
+
+	checker := NewStatusChecker(dir)
+	checker.now = func() time.Time { return now }
+	statuses := checker.CheckSite(context.Background(), site, true, true)
+
+	expect := map[string][]StatusFlag{
+		"old":     {StatusOutdated},
+		"stale":   {StatusAbandoned},
+		"closed":  {StatusRemoved},
+		"ancient": {StatusOutdated, StatusAbandoned},
+	}
+	if len(statuses) != len(expect) {
+		t.Fatalf("expected %d flagged extensions, got %d", len(expect), len(statuses))
+	}
+	for _, status := range statuses {
+		flags, ok := expect[status.Slug]
+		if !ok {
+			t.Errorf("unexpected status for %s: %v", status.Slug, status.Flags)
+			continue
+		}
+		for _, flag := range flags {
+			if !status.HasFlag(flag) {
+				t.Errorf("%s: expected flag %s, got %v", status.Slug, flag, status.Flags)
+			}
+		}
+		if len(status.Flags) != len(flags) {
+			t.Errorf("%s: expected flags %v, got %v", status.Slug, flags, status.Flags)
+		}
+	}
+
+	for _, status := range statuses {
+		if status.Slug == "closed" && status.ClosedReason != "Security Issue" {
+			t.Errorf("expected closed reason, got %q", status.ClosedReason)
+		}
+		if status.Slug == "old" && status.LatestVersion != "3.1" {
+			t.Errorf("expected latest version 3.1, got %q", status.LatestVersion)
+		}
+	}
+}
+
+func TestStatusCheckerWithDirectoryAPI(t *testing.T) {
+	// The directory's own answers, as parsed by the wporg client
+	responses := map[string]struct {
+		status int
+		body   string
+	}{
+		"outdated":  {http.StatusOK, `{"slug": "outdated", "version": "2.1.0", "last_updated": "2024-11-05 9:41pm GMT"}`},
+		"abandoned": {http.StatusOK, `{"slug": "abandoned", "version": "1.0", "last_updated": "2016-04-12 3:04pm GMT", "versions": []}`},
+		"closed":    {http.StatusNotFound, `{"error": "closed", "slug": "closed", "closed": true, "reason_text": "Security Issue"}`},
+		"premium":   {http.StatusNotFound, `{"error": "Plugin not found."}`},
+		"broken":    {http.StatusInternalServerError, `Internal Server Error`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := responses[r.URL.Query().Get("request[slug]")]
+		w.WriteHeader(resp.status)
+		_, _ = w.Write([]byte(resp.body))
+	}))
+	defer server.Close()
+
+	site := &wordpress.Site{}
+	for _, slug := range []string{"outdated", "abandoned", "closed", "premium", "broken"} {
+		site.Plugins = append(site.Plugins, &wordpress.Plugin{Extension: wordpress.Extension{Slug: slug, Version: "1.0"}})
+	}
+	client := wporg.NewClient(wporg.WithBaseURL(server.URL), wporg.WithClientOptions(api.WithRetries(0)))
+	checker := NewStatusChecker(client)
+	checker.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+	statuses := checker.CheckSite(context.Background(), site, true, false)
+
+	expect := map[string][]StatusFlag{
+		"outdated":  {StatusOutdated},
+		"abandoned": {StatusAbandoned},
+		"closed":    {StatusRemoved},
+	}
+	if len(statuses) != len(expect) {
+		t.Fatalf("expected %d flagged extensions, got %d", len(expect), len(statuses))
+	}
+	for _, status := range statuses {
+		if flags := expect[status.Slug]; len(status.Flags) != len(flags) || !status.HasFlag(flags[0]) {
+			t.Errorf("%s: expected flags %v, got %v", status.Slug, flags, status.Flags)
+		}
+	}
+}
+
+func TestStatusCheckerSkipsThemes(t *testing.T) {
+	dir := fakeDirectory{"theme:old": {Version: "2.0"}}
+	site := &wordpress.Site{Themes: []*wordpress.Theme{{Extension: wordpress.Extension{Slug: "old", Version: "1.0"}}}}
+
+	if statuses := NewStatusChecker(dir).CheckSite(context.Background(), site, true, false); len(statuses) != 0 {
+		t.Errorf("expected themes to be skipped, got %d statuses", len(statuses))
+	}
+}
+
+// testCoreReleases is a trimmed stable-check response
+var testCoreReleases = map[string]string{
+	"6.6.2":  "latest",
+	"6.6.1":  "insecure",
+	"6.6":    "insecure",
+	"6.5.5":  "outdated",
+	"6.5.4":  "insecure",
+	"6.5":    "insecure",
+	"4.7.1":  "insecure",
+	"4.7":    "insecure",
+	"3.9.40": "insecure",
+	"3.9":    "insecure",
+}
+
+func TestEvaluateCoreVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		flag     StatusFlag
+		security string
+	}{
+		{"6.6.2", StatusCurrent, ""},
+		{"6.7-beta1", StatusCurrent, ""},
+		{"6.6.1", StatusInsecure, "6.6.2"},
+		{"6.5.5", StatusOutdated, ""},
+		{"6.5.4", StatusInsecure, "6.5.5"},
+		{"6.5.3", StatusInsecure, "6.5.5"},
+		{"4.7", StatusEndOfLife, "4.7.1"},
+		{"3.9.40", StatusEndOfLife, ""},
+	}
+	for _, tt := range tests {
+		status := EvaluateCoreVersion(tt.version, testCoreReleases)
+		if !status.HasFlag(tt.flag) || len(status.Flags) != 1 {
+			t.Errorf("%s: expected %s, got %v", tt.version, tt.flag, status.Flags)
+		}
+		if status.SecurityRelease != tt.security {
+			t.Errorf("%s: expected security release %q, got %q", tt.version, tt.security, status.SecurityRelease)
+		}
+		if status.LatestVersion != "6.6.2" {
+			t.Errorf("%s: expected latest 6.6.2, got %q", tt.version, status.LatestVersion)
+		}
+	}
+}
+
+func TestStatusCheckerCheckCore(t *testing.T) {
+	site := &wordpress.Site{Version: "6.5.4", CorePath: "/var/www"}
+	status, err := NewStatusChecker(fakeDirectory{}).CheckCore(context.Background(), site)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.HasFlag(StatusInsecure) || status.Path != "/var/www" {
+		t.Errorf("unexpected core status: %+v", status)
+	}
+}
@@ -0,0 +1,214 @@
+// Package scanner provides per-site and fleet-level rollups of scan results
+package scanner
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+// DefaultSummaryTop is how many signatures and extensions a summary ranks
+const DefaultSummaryTop = 10
+
+// UnattributedRoot is the root reported for results outside every site
+const UnattributedRoot = "(other)"
+
+// SiteSummary totals the results for one site
+type SiteSummary struct {
+	Root            string `json:"root"`
+	Owner           string `json:"owner,omitempty"`
+	Domain          string `json:"domain,omitempty"`
+	FilesMatched    int    `json:"files_matched"`
+	Matches         int    `json:"matches"`
+	Vulnerabilities int    `json:"vulnerabilities"`
+}
+
+// Infected reports whether any file in the site matched
+func (s *SiteSummary) Infected() bool {
+	return s.FilesMatched > 0
+}
+
+// Vulnerable reports whether the site has known vulnerabilities
+func (s *SiteSummary) Vulnerable() bool {
+	return s.Vulnerabilities > 0
+}
+
+// RankedItem is a signature or extension and how widely it was found
+type RankedItem struct {
+	Name  string `json:"name"`
+	Type  string `json:"type,omitempty"`
+	Sites int    `json:"sites"`
+	Count int    `json:"count"`
+}
+
+// FleetSummary rolls up results across every scanned site
+type FleetSummary struct {
+	SitesScanned    int            `json:"sites_scanned"`
+	SitesClean      int            `json:"sites_clean"`
+	SitesInfected   int            `json:"sites_infected"`
+	SitesVulnerable int            `json:"sites_vulnerable"`
+	FilesMatched    int            `json:"files_matched"`
+	Matches         int            `json:"matches"`
+	Vulnerabilities int            `json:"vulnerabilities"`
+	TopSignatures   []*RankedItem  `json:"top_signatures,omitempty"`
+	TopVulnerable   []*RankedItem  `json:"top_vulnerable_extensions,omitempty"`
+	Sites           []*SiteSummary `json:"sites"`
+}
+
+// tally counts occurrences of an item and the sites it was found in
+type tally struct {
+	item  *RankedItem
+	sites map[*SiteSummary]bool
+}
+
+// Aggregator attributes scan results to sites and summarizes them
+type Aggregator struct {
+	sites      []*SiteSummary
+	signatures map[string]*tally
+	vulnerable map[string]*tally
+}
+
+// NewAggregator creates an empty aggregator
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		signatures: make(map[string]*tally),
+		vulnerable: make(map[string]*tally),
+	}
+}
+
+// AddSite registers a site so it is counted even when clean. Results are
+// attributed to the site with the longest root containing their path.
+func (a *Aggregator) AddSite(root, owner, domain string) *SiteSummary {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	for _, site := range a.sites {
+		if site.Root == root {
+			return site
+		}
+	}
+	site := &SiteSummary{Root: root, Owner: owner, Domain: domain}
+	a.sites = append(a.sites, site)
+	return site
+}
+
+// site returns the site owning path, registering an unattributed site for
+// paths outside every root
+func (a *Aggregator) site(path string) *SiteSummary {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	var best *SiteSummary
+	for _, site := range a.sites {
+		if site.Root == UnattributedRoot {
+			continue
+		}
+		within := path == site.Root ||
+			strings.HasPrefix(path, strings.TrimSuffix(site.Root, string(filepath.Separator))+string(filepath.Separator))
+		if within && (best == nil || len(site.Root) > len(best.Root)) {
+			best = site
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	for _, site := range a.sites {
+		if site.Root == UnattributedRoot {
+			return site
+		}
+	}
+	best = &SiteSummary{Root: UnattributedRoot}
+	a.sites = append(a.sites, best)
+	return best
+}
+
+// AddScanResult records the malware matches of a scanned file
+func (a *Aggregator) AddScanResult(result *ScanResult, sigSet *intel.SignatureSet) {
+	if !result.HasMatches() {
+		return
+	}
+
+	site := a.site(result.Path)
+	site.FilesMatched++
+	site.Matches += len(result.Matches)
+	for _, match := range result.Matches {
+		name, _ := match.Describe(sigSet)
+		if name == "" {
+			name = "Unknown signature"
+		}
+		a.count(a.signatures, name, "", site)
+	}
+}
+
+// AddVulnMatches records the vulnerabilities found in the site at root
+func (a *Aggregator) AddVulnMatches(root string, matches []*VulnMatch) {
+	site := a.AddSite(root, "", "")
+	site.Vulnerabilities += len(matches)
+	for _, m := range matches {
+		a.count(a.vulnerable, m.Slug, string(m.SoftwareType), site)
+	}
+}
+
+// count adds one occurrence of an item found in site
+func (a *Aggregator) count(tallies map[string]*tally, name, kind string, site *SiteSummary) {
+	key := kind + ":" + name
+	t, ok := tallies[key]
+	if !ok {
+		t = &tally{item: &RankedItem{Name: name, Type: kind}, sites: make(map[*SiteSummary]bool)}
+		tallies[key] = t
+	}
+	t.item.Count++
+	t.sites[site] = true
+}
+
+// Summary returns the fleet rollup, ranking at most top signatures and
+// vulnerable extensions by the number of sites they affect
+func (a *Aggregator) Summary(top int) *FleetSummary {
+	summary := &FleetSummary{
+		Sites:         a.sites,
+		TopSignatures: rank(a.signatures, top),
+		TopVulnerable: rank(a.vulnerable, top),
+	}
+	for _, site := range a.sites {
+		summary.SitesScanned++
+		summary.FilesMatched += site.FilesMatched
+		summary.Matches += site.Matches
+		summary.Vulnerabilities += site.Vulnerabilities
+		if site.Infected() {
+			summary.SitesInfected++
+		}
+		if site.Vulnerable() {
+			summary.SitesVulnerable++
+		}
+		if !site.Infected() && !site.Vulnerable() {
+			summary.SitesClean++
+		}
+	}
+	return summary
+}
+
+// rank orders tallied items by sites affected, then occurrences and name
+func rank(tallies map[string]*tally, top int) []*RankedItem {
+	items := make([]*RankedItem, 0, len(tallies))
+	for _, t := range tallies {
+		t.item.Sites = len(t.sites)
+		items = append(items, t.item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Sites != items[j].Sites {
+			return items[i].Sites > items[j].Sites
+		}
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Name < items[j].Name
+	})
+	if top > 0 && len(items) > top {
+		items = items[:top]
+	}
+	return items
+}
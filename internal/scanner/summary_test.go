@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+func TestAggregatorSummary(t *testing.T) {
+	sigSet := createTestSignatureSet()
+	root := t.TempDir()
+	alice := filepath.Join(root, "alice")
+	shop := filepath.Join(alice, "shop")
+	bob := filepath.Join(root, "bob")
+	carol := filepath.Join(root, "carol")
+
+	a := NewAggregator()
+	a.AddSite(alice, "alice", "alice.example")
+	a.AddSite(shop, "alice", "shop.example")
+	a.AddSite(bob, "bob", "bob.example")
+	a.AddSite(carol, "carol", "carol.example")
+
+	a.AddScanResult(&ScanResult{Path: filepath.Join(alice, "index.php"), Matches: []*MatchResult{{SignatureID: 1}, {SignatureID: 2}}}, sigSet)
+	a.AddScanResult(&ScanResult{Path: filepath.Join(shop, "wp-load.php"), Matches: []*MatchResult{{SignatureID: 1}}}, sigSet)
+	a.AddScanResult(&ScanResult{Path: filepath.Join(bob, "clean.php")}, sigSet)
+	a.AddScanResult(&ScanResult{Path: "/elsewhere/x.php", Matches: []*MatchResult{{SignatureID: 3}}}, sigSet)
+
+	vuln := &intel.Vulnerability{ID: "v1"}
+	a.AddVulnMatches(bob, []*VulnMatch{
+		{Vulnerability: vuln, SoftwareType: intel.SoftwareTypePlugin, Slug: "forms"},
+		{Vulnerability: vuln, SoftwareType: intel.SoftwareTypePlugin, Slug: "slider"},
+	})
+	a.AddVulnMatches(shop, []*VulnMatch{{Vulnerability: vuln, SoftwareType: intel.SoftwareTypePlugin, Slug: "forms"}})
+
+	s := a.Summary(DefaultSummaryTop)
+	if s.SitesScanned != 5 || s.SitesInfected != 3 || s.SitesVulnerable != 2 || s.SitesClean != 1 {
+		t.Errorf("unexpected site counts: %+v", s)
+	}
+	if s.FilesMatched != 3 || s.Matches != 4 || s.Vulnerabilities != 3 {
+		t.Errorf("unexpected totals: %+v", s)
+	}
+
+	if len(s.TopSignatures) != 3 || s.TopSignatures[0].Name != "Eval Pattern" || s.TopSignatures[0].Sites != 2 {
+		t.Errorf("unexpected top signatures: %+v", s.TopSignatures)
+	}
+	if len(s.TopVulnerable) != 2 || s.TopVulnerable[0].Name != "forms" || s.TopVulnerable[0].Sites != 2 || s.TopVulnerable[0].Type != "plugin" {
+		t.Errorf("unexpected top vulnerable: %+v", s.TopVulnerable)
+	}
+
+	byRoot := make(map[string]*SiteSummary)
+	for _, site := range s.Sites {
+		byRoot[site.Root] = site
+	}
+	if site := byRoot[shop]; site == nil || site.FilesMatched != 1 || site.Domain != "shop.example" {
+		t.Errorf("expected nested site to own its results, got %+v", site)
+	}
+	if site := byRoot[UnattributedRoot]; site == nil || site.Matches != 1 {
+		t.Errorf("expected unattributed result, got %+v", site)
+	}
+
+	if top := a.Summary(1); len(top.TopSignatures) != 1 {
+		t.Errorf("expected ranking limited to 1, got %d", len(top.TopSignatures))
+	}
+}
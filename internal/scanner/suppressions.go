@@ -0,0 +1,243 @@
+// Package scanner provides a persistent store of suppressed matches
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AnySignature as a Suppression.SignatureID suppresses every signature
+const AnySignature = 0
+
+// Suppression marks a match as known-benign. It applies only while the file
+// at Path still has the recorded SHA256, so modified files are reported again.
+type Suppression struct {
+	Path        string    `json:"path"`
+	SignatureID int       `json:"signature_id"`
+	SHA256      string    `json:"sha256"`
+	Reason      string    `json:"reason,omitempty"`
+	Added       time.Time `json:"added"`
+}
+
+// SuppressionStore is a JSON file of suppressions keyed by path+signature+hash
+type SuppressionStore struct {
+	path         string
+	suppressions []*Suppression
+	mu           sync.RWMutex
+}
+
+// LoadSuppressions reads the store at path. A missing file yields an empty store.
+func LoadSuppressions(path string) (*SuppressionStore, error) {
+	store := &SuppressionStore{path: path}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- user-configured suppression store
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("reading suppressions: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.suppressions); err != nil {
+		return nil, fmt.Errorf("parsing suppressions %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Save writes the store back to disk atomically
+func (s *SuppressionStore) Save() error {
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s.suppressions, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encoding suppressions: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating suppressions directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".suppressions-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing suppressions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing suppressions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("saving suppressions: %w", err)
+	}
+	return nil
+}
+
+// Path returns the store's file path
+func (s *SuppressionStore) Path() string {
+	return s.path
+}
+
+// List returns the suppressions sorted by path and signature
+func (s *SuppressionStore) List() []*Suppression {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Suppression, len(s.suppressions))
+	copy(list, s.suppressions)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Path != list[j].Path {
+			return list[i].Path < list[j].Path
+		}
+		return list[i].SignatureID < list[j].SignatureID
+	})
+	return list
+}
+
+// Len returns the number of suppressions
+func (s *SuppressionStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.suppressions)
+}
+
+// Add suppresses signatureID for the current contents of path, replacing
+// any existing suppression for the same path and signature
+func (s *SuppressionStore) Add(path string, signatureID int, reason string) (*Suppression, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving path: %w", err)
+	}
+	hash, err := HashFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sup := &Suppression{
+		Path:        absPath,
+		SignatureID: signatureID,
+		SHA256:      hash,
+		Reason:      reason,
+		Added:       time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.suppressions {
+		if existing.Path == absPath && existing.SignatureID == signatureID {
+			s.suppressions[i] = sup
+			return sup, nil
+		}
+	}
+	s.suppressions = append(s.suppressions, sup)
+	return sup, nil
+}
+
+// Remove deletes suppressions for path. AnySignature removes all of them;
+// otherwise only the given signature is removed. It returns the number removed.
+func (s *SuppressionStore) Remove(path string, signatureID int) (int, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return 0, fmt.Errorf("resolving path: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.suppressions[:0]
+	removed := 0
+	for _, sup := range s.suppressions {
+		if sup.Path == absPath && (signatureID == AnySignature || sup.SignatureID == signatureID) {
+			removed++
+			continue
+		}
+		kept = append(kept, sup)
+	}
+	s.suppressions = kept
+	return removed, nil
+}
+
+// Apply removes suppressed matches from result and returns how many were
+// removed. The file is only hashed if a suppression exists for its path.
+func (s *SuppressionStore) Apply(result *ScanResult) int {
+	if result == nil || !result.HasMatches() {
+		return 0
+	}
+
+	absPath, err := filepath.Abs(result.Path)
+	if err != nil {
+		return 0
+	}
+
+	s.mu.RLock()
+	var candidates []*Suppression
+	for _, sup := range s.suppressions {
+		if sup.Path == absPath {
+			candidates = append(candidates, sup)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	hash, err := HashFile(absPath)
+	if err != nil {
+		return 0
+	}
+
+	kept := result.Matches[:0]
+	suppressed := 0
+	for _, match := range result.Matches {
+		if suppressionCovers(candidates, match.SignatureID, hash) {
+			suppressed++
+			continue
+		}
+		kept = append(kept, match)
+	}
+	result.Matches = kept
+	return suppressed
+}
+
+// suppressionCovers reports whether any candidate suppresses signatureID for hash
+func suppressionCovers(candidates []*Suppression, signatureID int, hash string) bool {
+	for _, sup := range candidates {
+		if sup.SHA256 != hash {
+			continue
+		}
+		if sup.SignatureID == AnySignature || sup.SignatureID == signatureID {
+			return true
+		}
+	}
+	return false
+}
+
+// HashFile returns the hex-encoded SHA256 of a file's contents
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path) // #nosec G304 -- path of a scanned or user-specified file
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("hashing file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
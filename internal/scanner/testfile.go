@@ -0,0 +1,30 @@
+// Package scanner provides the harmless test file every scan detects
+package scanner
+
+import (
+	"bytes"
+)
+
+// TestCategory is the match category of the built-in test file
+const TestCategory = "test"
+
+// TestString starts the built-in test file. Like the EICAR antivirus test
+// file it is harmless, and every scan detects it whatever signatures are
+// loaded, so operators can check that findings reach their outputs and
+// alerts.
+const TestString = "WORDFENCE-GO-MALWARE-TEST-FILE!$H+H*"
+
+// TestFileName is the name the test file is written under. Its .php
+// extension is scanned by default.
+const TestFileName = "wordfence-test-file.php"
+
+// TestFileContent returns the content of a test file
+func TestFileContent() []byte {
+	return []byte(TestString + "\nThis file is harmless. It tests that malware detection and alerting work.\n")
+}
+
+// IsTestFile reports whether content is a test file. Only files starting
+// with TestString are, so files quoting it, such as this one, are not.
+func IsTestFile(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(TestString))
+}
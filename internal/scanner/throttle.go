@@ -0,0 +1,219 @@
+// Package scanner provides throttling that keeps a scan from crowding out
+// the sites on a host
+package scanner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// readRate spreads reads over time so that together the workers read at
+// most a number of bytes a second
+type readRate struct {
+	mu   sync.Mutex
+	rate int64
+	next time.Time
+}
+
+func newReadRate(bytesPerSecond int64) *readRate {
+	return &readRate{rate: bytesPerSecond}
+}
+
+// rateReservation is the time reserved for a read at the read rate. A nil
+// reservation, of an unlimited rate, waits for nothing.
+type rateReservation struct {
+	rate  *readRate
+	n     int64
+	start time.Time
+	end   time.Time
+}
+
+// reserve reserves the time to read n bytes at the rate, after those
+// already reserved. The whole read is reserved at once, however large.
+func (r *readRate) reserve(n int64) *rateReservation {
+	if r == nil || r.rate <= 0 || n <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	start := r.next
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	r.next = start.Add(r.duration(n))
+	return &rateReservation{rate: r, n: n, start: start, end: r.next}
+}
+
+// wait reserves the time to read n bytes at the rate and sleeps until its
+// turn
+func (r *readRate) wait(ctx context.Context, n int64) error {
+	return r.reserve(n).wait(ctx)
+}
+
+// duration is how long reading n bytes takes at the rate
+func (r *readRate) duration(n int64) time.Duration {
+	return time.Duration(float64(n) / float64(r.rate) * float64(time.Second))
+}
+
+// delay is how long the read waits for its turn
+func (res *rateReservation) delay() time.Duration {
+	if res == nil {
+		return 0
+	}
+	return max(time.Until(res.start), 0)
+}
+
+// wait sleeps until the read's turn. If ctx is done first, the read won't
+// happen and its time is given back.
+func (res *rateReservation) wait(ctx context.Context) error {
+	if res == nil {
+		return nil
+	}
+	if err := sleepContext(ctx, res.delay()); err != nil {
+		res.settle(0)
+		return err
+	}
+	return nil
+}
+
+// settle accounts for the bytes the read used, once it is done. The time
+// of bytes reserved but not read, by a short or failed read, is given back
+// less any reserved by later reads, which keep their turns, as
+// golang.org/x/time/rate does. Bytes read beyond the reservation, from a
+// file that grew, push back the reads after it.
+func (res *rateReservation) settle(used int64) {
+	if res == nil || used == res.n {
+		return
+	}
+	r := res.rate
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if used > res.n {
+		r.next = r.next.Add(r.duration(used - res.n))
+	} else if refund := r.duration(res.n-used) - r.next.Sub(res.end); refund > 0 {
+		r.next = r.next.Add(-refund)
+	}
+	res.n = used
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// batchPause stops the reads of all the workers for a while after each
+// batch of files
+type batchPause struct {
+	mu    sync.Mutex
+	size  int64
+	pause time.Duration
+	count int64
+	until time.Time
+}
+
+func newBatchPause(size int, pause time.Duration) *batchPause {
+	return &batchPause{size: int64(size), pause: pause}
+}
+
+// wait counts a file, starting a pause if it ends a batch, and sleeps
+// until any pause has ended
+func (b *batchPause) wait(ctx context.Context) error {
+	if b == nil || b.size <= 0 || b.pause <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.count++
+	if b.count%b.size == 0 {
+		b.until = now.Add(b.pause)
+	}
+	until := b.until
+	b.mu.Unlock()
+	return sleepContext(ctx, until.Sub(now))
+}
+
+// reset starts counting batches again, for a new scan
+func (b *batchPause) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.count = 0
+	b.until = time.Time{}
+}
+
+// workerGate bounds how many workers scan files at once, to a limit that
+// can be lowered while a scan runs
+type workerGate struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	changed chan struct{}
+}
+
+func newWorkerGate(limit int) *workerGate {
+	return &workerGate{limit: limit, changed: make(chan struct{})}
+}
+
+// acquire waits until fewer workers than the limit are scanning
+func (g *workerGate) acquire(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if g.active < g.limit {
+			g.active++
+			g.mu.Unlock()
+			return nil
+		}
+		changed := g.changed
+		g.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// release lets a waiting worker scan
+func (g *workerGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active--
+	g.notify()
+}
+
+// set changes the limit, waking the workers waiting
+func (g *workerGate) set(limit int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.limit = limit
+	g.notify()
+}
+
+// notify wakes the workers waiting; g.mu must be held
+func (g *workerGate) notify() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// sleepContext sleeps for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadRate(t *testing.T) {
+	r := newReadRate(10 << 20)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := r.wait(ctx, 1<<20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first read goes at once; the other two wait 100ms each
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("3 MiB at 10 MiB/s took only %v", elapsed)
+	}
+
+	var unlimited *readRate
+	if err := unlimited.wait(ctx, 1<<30); err != nil {
+		t.Errorf("expected no wait without a rate, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := r.wait(cancelled, 10<<20); err == nil {
+		t.Error("expected a cancelled wait to fail")
+	}
+}
+
+func TestReadRateReservations(t *testing.T) {
+	const mib = 1 << 20
+	near := func(got, want time.Duration) bool {
+		return got > want-50*time.Millisecond && got <= want
+	}
+
+	// A read that fails gives back all its time
+	r := newReadRate(mib)
+	r.reserve(mib).settle(0)
+	if d := r.reserve(1).delay(); d > 50*time.Millisecond {
+		t.Errorf("expected no wait after a refund, got %v", d)
+	}
+
+	// A short read gives back the time of what it didn't read, less what
+	// later reads reserved, which keep their turns
+	r = newReadRate(mib)
+	short := r.reserve(mib)
+	later := r.reserve(mib / 2)
+	short.settle(mib / 4)
+	if d := later.delay(); !near(d, time.Second) {
+		t.Errorf("expected the later read to keep its turn in 1s, got %v", d)
+	}
+	if d := r.reserve(1).delay(); !near(d, 1250*time.Millisecond) {
+		t.Errorf("expected the next read in 1.25s, got %v", d)
+	}
+
+	// A file that grew pushes back the reads after it
+	r = newReadRate(mib)
+	r.reserve(mib / 2).settle(mib)
+	if d := r.reserve(1).delay(); !near(d, time.Second) {
+		t.Errorf("expected the next read in 1s, got %v", d)
+	}
+
+	// A cancelled wait gives back all its time
+	r = newReadRate(mib)
+	r.reserve(mib)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.reserve(mib).wait(cancelled); err == nil {
+		t.Fatal("expected a cancelled wait to fail")
+	}
+	if d := r.reserve(1).delay(); !near(d, time.Second) {
+		t.Errorf("expected the cancelled read's turn to be given back, got %v", d)
+	}
+
+	var unlimited *readRate
+	if res := unlimited.reserve(mib); res.delay() != 0 || res.wait(context.Background()) != nil {
+		t.Error("expected no wait without a rate")
+	}
+	unlimited.reserve(mib).settle(0)
+}
+
+func TestWorkerGate(t *testing.T) {
+	g := newWorkerGate(4)
+	g.set(2)
+	ctx := context.Background()
+
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := g.acquire(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			n := active.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			active.Add(-1)
+			g.release()
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 workers at once, saw %d", peak.Load())
+	}
+
+	g.set(0)
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := g.acquire(cancelled); err == nil {
+		t.Error("expected acquire to wait past the deadline with no room")
+	}
+}
+
+func TestBatchPause(t *testing.T) {
+	b := newBatchPause(3, 50*time.Millisecond)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := b.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("expected no pause within a batch, took %v", elapsed)
+	}
+	// The third file ends the batch and waits, as does a file read by
+	// another worker during the pause
+	if err := b.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected a pause after the batch, took %v", elapsed)
+	}
+
+	b.reset()
+	b.until = time.Now().Add(time.Hour)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := b.wait(cancelled); err == nil {
+		t.Error("expected a cancelled pause to fail")
+	}
+
+	var none *batchPause
+	if err := none.wait(ctx); err != nil {
+		t.Errorf("expected no pause without batches, got %v", err)
+	}
+}
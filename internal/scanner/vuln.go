@@ -4,6 +4,7 @@ package scanner
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/greysquirr3l/wordfence-go/internal/intel"
@@ -28,6 +29,13 @@ type VulnMatch struct {
 	Name          string
 	Version       string
 	Path          string
+	// IdentifiedBy records how the software was matched to the feed;
+	// Software.Slug is the feed's slug when it differs from Slug
+	IdentifiedBy intel.Identification
+	// Activity and Modified are copied from the plugin or theme and are
+	// unset for core
+	Activity wordpress.Activity
+	Modified time.Time
 }
 
 // VulnScanOptions configures the vulnerability scanner
@@ -38,6 +46,7 @@ type VulnScanOptions struct {
 	Informational  bool
 	IncludeVulnIDs []string
 	ExcludeVulnIDs []string
+	PackageURLs    map[string]string
 }
 
 // VulnScanner scans WordPress sites for vulnerabilities
@@ -78,6 +87,15 @@ func WithVulnInformational(include bool) VulnScannerOption {
 	}
 }
 
+// WithVulnPackageURLs identifies plugins and themes by package URL, such
+// as pkg:wordpress-plugin/elementor-pro, keyed by their path or directory
+// name. Extensions installed under other names are then still checked.
+func WithVulnPackageURLs(purls map[string]string) VulnScannerOption {
+	return func(s *VulnScanner) {
+		s.options.PackageURLs = purls
+	}
+}
+
 // WithVulnLogger sets the logger
 func WithVulnLogger(logger *logging.Logger) VulnScannerOption {
 	return func(s *VulnScanner) {
@@ -134,52 +152,14 @@ func (s *VulnScanner) ScanSite(_ context.Context, site *wordpress.Site) *VulnSca
 	// Check plugins
 	if s.options.CheckPlugins {
 		for _, plugin := range site.Plugins {
-			if plugin.Version == "" {
-				continue
-			}
-
-			vulns := s.index.GetVulnerabilities(intel.SoftwareTypePlugin, plugin.Slug, plugin.Version)
-			for _, vuln := range vulns {
-				if s.shouldInclude(vuln) {
-					sw := vuln.IsAffected(intel.SoftwareTypePlugin, plugin.Slug, plugin.Version)
-					match := &VulnMatch{
-						Vulnerability: vuln,
-						Software:      sw,
-						SoftwareType:  intel.SoftwareTypePlugin,
-						Slug:          plugin.Slug,
-						Name:          plugin.Name,
-						Version:       plugin.Version,
-						Path:          plugin.Path,
-					}
-					result.Vulnerabilities = append(result.Vulnerabilities, match)
-				}
-			}
+			s.checkExtension(result, intel.SoftwareTypePlugin, &plugin.Extension)
 		}
 	}
 
 	// Check themes
 	if s.options.CheckThemes {
 		for _, theme := range site.Themes {
-			if theme.Version == "" {
-				continue
-			}
-
-			vulns := s.index.GetVulnerabilities(intel.SoftwareTypeTheme, theme.Slug, theme.Version)
-			for _, vuln := range vulns {
-				if s.shouldInclude(vuln) {
-					sw := vuln.IsAffected(intel.SoftwareTypeTheme, theme.Slug, theme.Version)
-					match := &VulnMatch{
-						Vulnerability: vuln,
-						Software:      sw,
-						SoftwareType:  intel.SoftwareTypeTheme,
-						Slug:          theme.Slug,
-						Name:          theme.Name,
-						Version:       theme.Version,
-						Path:          theme.Path,
-					}
-					result.Vulnerabilities = append(result.Vulnerabilities, match)
-				}
-			}
+			s.checkExtension(result, intel.SoftwareTypeTheme, &theme.Extension)
 		}
 	}
 
@@ -187,6 +167,60 @@ func (s *VulnScanner) ScanSite(_ context.Context, site *wordpress.Site) *VulnSca
 	return result
 }
 
+// checkExtension adds the vulnerabilities of a plugin or theme to result.
+// Extensions are identified in the feed by directory name, package URL or
+// header, so renamed directories are still checked.
+func (s *VulnScanner) checkExtension(result *VulnScanResult, softwareType intel.SoftwareType, ext *wordpress.Extension) {
+	purl := s.packageURL(ext)
+	slug, how := s.index.Identify(softwareType, intel.SoftwareIdentity{
+		Slug:       ext.Slug,
+		Name:       ext.Name,
+		TextDomain: ext.GetHeader("TextDomain"),
+		PackageURL: purl,
+	})
+	if slug == "" {
+		return
+	}
+
+	version := ext.Version
+	if how == intel.IdentifiedByPackageURL && version == "" {
+		_, _, version, _ = intel.ParsePackageURL(purl)
+	}
+	if version == "" {
+		return
+	}
+	if how != intel.IdentifiedBySlug {
+		s.logger.Verbose("Identified %s %s as %s by %s", softwareType, ext.Path, slug, how)
+	}
+
+	for _, vuln := range s.index.GetVulnerabilities(softwareType, slug, version) {
+		if !s.shouldInclude(vuln) {
+			continue
+		}
+		result.Vulnerabilities = append(result.Vulnerabilities, &VulnMatch{
+			Vulnerability: vuln,
+			Software:      vuln.IsAffected(softwareType, slug, version),
+			SoftwareType:  softwareType,
+			Slug:          ext.Slug,
+			Name:          ext.Name,
+			Version:       version,
+			Path:          ext.Path,
+			IdentifiedBy:  how,
+			Activity:      ext.Activity,
+			Modified:      ext.Modified,
+		})
+	}
+}
+
+// packageURL returns the package URL given for an extension's path or
+// directory name, if any
+func (s *VulnScanner) packageURL(ext *wordpress.Extension) string {
+	if purl, ok := s.options.PackageURLs[filepath.Clean(ext.Path)]; ok {
+		return purl
+	}
+	return s.options.PackageURLs[ext.Slug]
+}
+
 // shouldInclude checks if a vulnerability should be included in results
 func (s *VulnScanner) shouldInclude(vuln *intel.Vulnerability) bool {
 	// Check informational filter
@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+	"github.com/greysquirr3l/wordfence-go/internal/wordpress"
+)
+
+func TestVulnScannerIdentifiesRenamedPlugins(t *testing.T) {
+	index := intel.NewVulnerabilityIndex()
+	for _, slug := range []string{"elementor-pro", "contact-form-7"} {
+		index.Add(&intel.Vulnerability{
+			ID: slug + "-xss",
+			Software: []*intel.Software{{
+				Type: intel.SoftwareTypePlugin,
+				Slug: slug,
+				AffectedVersions: map[string]*intel.VersionRange{
+					"< 9.0": {FromVersion: intel.VersionAny, ToVersion: "9.0"},
+				},
+			}},
+		})
+	}
+
+	plugin := func(slug, version string) *wordpress.Plugin {
+		return &wordpress.Plugin{Extension: wordpress.Extension{Slug: slug, Name: slug, Version: version, Path: "/site/wp-content/plugins/" + slug}}
+	}
+	site := &wordpress.Site{Plugins: []*wordpress.Plugin{
+		plugin("elementor-pro-nulled", "3.18.0"),
+		plugin("forms", ""),
+		plugin("unrelated", "1.0"),
+	}}
+
+	s := NewVulnScanner(index,
+		WithVulnCheckCore(false),
+		WithVulnPackageURLs(map[string]string{"/site/wp-content/plugins/forms": "pkg:wordpress-plugin/contact-form-7@5.8"}),
+		WithVulnLogger(logging.New(logging.LevelCritical)),
+	)
+	result := s.ScanSite(context.Background(), site)
+
+	found := make(map[string]*VulnMatch)
+	for _, m := range result.Vulnerabilities {
+		found[m.Slug] = m
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 vulnerable plugins, got %v", found)
+	}
+	if m := found["elementor-pro-nulled"]; m == nil || m.IdentifiedBy != intel.IdentifiedByDirectory || m.Software.Slug != "elementor-pro" {
+		t.Errorf("expected the nulled copy identified by directory name, got %+v", m)
+	}
+	// The package URL's version stands in for a missing header version
+	if m := found["forms"]; m == nil || m.IdentifiedBy != intel.IdentifiedByPackageURL || m.Version != "5.8" {
+		t.Errorf("expected forms identified by package URL at 5.8, got %+v", m)
+	}
+}
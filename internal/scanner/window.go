@@ -0,0 +1,254 @@
+// Package scanner provides search windows derived from parsed signatures
+package scanner
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// minWindowLiteral is the shortest literal worth searching for before
+// running a signature
+const minWindowLiteral = 3
+
+// maxWindowWidth bounds the widths tracked while analyzing a rule; wider
+// prefixes are treated as unbounded
+const maxWindowWidth = 1 << 20
+
+// kelvinSign is the other letter regexp2 folds to an ASCII letter ("k")
+var kelvinSign = []byte("K")
+
+// searchWindow is what a signature's rule says about where its matches
+// can be. Every match contains literal, so content without it can't match,
+// and starts at most maxBefore runes before it, so regexp2 can start
+// searching there instead of at the start of the content.
+type searchWindow struct {
+	literal   []byte // ASCII; lowercase when fold is set
+	fold      bool   // the literal is matched ignoring case
+	maxBefore int    // -1 when unbounded
+	anchored  bool   // matches start at the start of the content
+}
+
+// analyzeRule derives a search window from a rule, or returns nil when
+// the rule has no literal worth searching for
+func analyzeRule(rule string) *searchWindow {
+	p := &patternParser{src: rule}
+	tree, err := p.parse()
+	if err != nil {
+		return nil
+	}
+	w := &windowWalker{multiline: true}
+	w.walk(tree)
+	w.flush()
+	if w.best == nil {
+		return nil
+	}
+	// \G matches where the search starts, so starting later would move it
+	if usesSearchStart(tree) {
+		w.best.maxBefore = -1
+		w.best.anchored = false
+	}
+	return w.best
+}
+
+// usesSearchStart reports whether a rule has \G, which matches where the
+// search starts
+func usesSearchStart(tree *syntaxNode) bool {
+	return containsSyntax(tree, func(n *syntaxNode) bool { return n.kind == syntaxEmpty && n.text == `\G` })
+}
+
+// hintableRule reports whether a rule can be searched for only around its
+// common strings, which moves where the search starts
+func hintableRule(rule string) bool {
+	p := &patternParser{src: rule}
+	tree, err := p.parse()
+	return err == nil && !usesSearchStart(tree)
+}
+
+// commonStringHint is where in a chunk all of a signature's common strings
+// were found, in runes: from where the earliest starts to where the last
+// ends. Every match contains each common string, so matches of all but
+// unusually long signatures lie within a little slack of the hint.
+type commonStringHint struct {
+	first, end int
+}
+
+// bounds returns the runes of a chunk of n runes to search, widened by
+// slack on both sides
+func (h commonStringHint) bounds(slack, n int) (int, int) {
+	return max(h.first-slack, 0), min(h.end+slack, n)
+}
+
+// windowWalker walks the top level of a rule, where every node is part
+// of every match, looking for the longest run of literal characters
+type windowWalker struct {
+	width     int // most runes matched so far, -1 once unbounded
+	fold      bool
+	multiline bool
+	anchored  bool
+
+	run      []byte
+	runStart int
+	best     *searchWindow
+}
+
+func (w *windowWalker) walk(n *syntaxNode) {
+	switch n.kind {
+	case syntaxConcat:
+		for _, sub := range n.sub {
+			w.walk(sub)
+		}
+	case syntaxGroup:
+		w.flush()
+		fold, multiline := w.fold, w.multiline
+		w.applyFlags(n.text)
+		w.walk(n.sub[0])
+		w.flush()
+		w.fold, w.multiline = fold, multiline
+	case syntaxFlags:
+		w.flush()
+		w.applyFlags(n.text)
+	case syntaxLiteral:
+		if n.rune >= utf8.RuneSelf {
+			w.flush()
+			w.advance(1)
+			return
+		}
+		c := byte(n.rune)
+		if w.fold && 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if len(w.run) == 0 {
+			w.runStart = w.width
+		}
+		w.run = append(w.run, c)
+		w.advance(1)
+	case syntaxAssertion:
+		w.flush()
+		if w.width == 0 && (n.text == `\A` || n.text == "^" && !w.multiline) {
+			w.anchored = true
+		}
+	default:
+		// Literals in one alternative, or in something repeated, aren't
+		// in every match
+		w.flush()
+		w.advance(maxSyntaxWidth(n))
+	}
+}
+
+// advance adds to the width matched so far
+func (w *windowWalker) advance(width int) {
+	if w.width < 0 || width < 0 || w.width+width > maxWindowWidth {
+		w.width = -1
+		return
+	}
+	w.width += width
+}
+
+// flush ends the current run of literals, keeping it if it is the longest
+func (w *windowWalker) flush() {
+	if len(w.run) >= minWindowLiteral && (w.best == nil || len(w.run) > len(w.best.literal)) {
+		w.best = &searchWindow{
+			literal:   bytes.Clone(w.run),
+			fold:      w.fold,
+			maxBefore: w.runStart,
+			anchored:  w.anchored,
+		}
+	}
+	w.run = w.run[:0]
+}
+
+// applyFlags applies inline options such as "i-m"
+func (w *windowWalker) applyFlags(flags string) {
+	on := true
+	for _, c := range flags {
+		switch c {
+		case '-':
+			on = false
+		case 'i':
+			w.fold = on
+		case 'm':
+			w.multiline = on
+		}
+	}
+}
+
+// maxSyntaxWidth returns the most runes a node can match, or -1 if it is
+// unbounded
+func maxSyntaxWidth(n *syntaxNode) int {
+	switch n.kind {
+	case syntaxLiteral, syntaxAnyChar, syntaxClass:
+		return 1
+	case syntaxGroup:
+		return maxSyntaxWidth(n.sub[0])
+	case syntaxConcat:
+		total := 0
+		for _, sub := range n.sub {
+			width := maxSyntaxWidth(sub)
+			if width < 0 || total+width > maxWindowWidth {
+				return -1
+			}
+			total += width
+		}
+		return total
+	case syntaxAlternate:
+		widest := 0
+		for _, sub := range n.sub {
+			width := maxSyntaxWidth(sub)
+			if width < 0 {
+				return -1
+			}
+			widest = max(widest, width)
+		}
+		return widest
+	case syntaxRepeat:
+		width := maxSyntaxWidth(n.sub[0])
+		switch {
+		case width == 0:
+			return 0
+		case width < 0 || n.max < 0 || width*n.max > maxWindowWidth:
+			return -1
+		}
+		return width * n.max
+	}
+	return 0
+}
+
+// containsSyntax reports whether any node of the tree satisfies match
+func containsSyntax(n *syntaxNode, match func(*syntaxNode) bool) bool {
+	if match(n) {
+		return true
+	}
+	for _, sub := range n.sub {
+		if containsSyntax(sub, match) {
+			return true
+		}
+	}
+	return false
+}
+
+// start returns the rune offset to start searching content at, and false
+// when the content can't match. folded is content with ASCII lowercased.
+func (w *searchWindow) start(content, folded []byte) (int, bool) {
+	haystack := content
+	if w.fold {
+		// regexp2 also folds these to the ASCII letters the literal has
+		if bytes.Contains(content, dottedCapitalI) || bytes.Contains(content, kelvinSign) {
+			return 0, true
+		}
+		haystack = folded
+	}
+	i := bytes.Index(haystack, w.literal)
+	if i < 0 {
+		return 0, false
+	}
+	if w.maxBefore < 0 {
+		return 0, true
+	}
+	// Invalid UTF-8 counts a rune per byte here as in regexp2's input, and
+	// ASCII bytes are never part of another rune
+	offset := utf8.RuneCount(content[:i]) - w.maxBefore
+	if w.anchored {
+		return 0, offset <= 0
+	}
+	return max(offset, 0), true
+}
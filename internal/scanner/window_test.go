@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dlclark/regexp2"
+
+	"github.com/greysquirr3l/wordfence-go/internal/intel"
+)
+
+func TestAnalyzeRule(t *testing.T) {
+	tests := []struct {
+		rule      string
+		literal   string // empty for no window
+		fold      bool
+		maxBefore int
+		anchored  bool
+	}{
+		{`eval\s*\(`, "eval", false, 0, false},
+		{`(?i)\$default_action\s*=\s*['"]FilesMan`, "$default_action", true, 0, false},
+		{`\$[a-z]{2,5}\s*=\s*str_rot13\(`, "str_rot13(", false, -1, false},
+		{`\$[a-z]{2,5}=str_rot13\(`, "=str_rot13(", false, 6, false},
+		{`^<\?php\s*@error_reporting`, "@error_reporting", false, -1, false},
+		{`\A<\?php @eval`, "<?php @eval", false, 0, true},
+		{`(?-m)^.{0,20}<\?php`, "<?php", false, 20, true},
+		{`(?<=\$_POST\[)cmd\]`, "cmd]", false, 0, false},
+		{`(?i:passthru)\s*\(\s*\$_`, "passthru", true, 0, false},
+		{`(eval|assert)\(`, "", false, 0, false},
+		{`(?:base64_decode)+\(`, "", false, 0, false},
+		{`a|bcdef`, "", false, 0, false},
+		{`\Gshell_exec`, "shell_exec", false, -1, false},
+		{`(a)\1foo`, "", false, 0, false},
+	}
+	for _, tt := range tests {
+		w := analyzeRule(tt.rule)
+		if tt.literal == "" {
+			if w != nil {
+				t.Errorf("%q: expected no window, got %+v", tt.rule, w)
+			}
+			continue
+		}
+		if w == nil {
+			t.Errorf("%q: expected a window", tt.rule)
+			continue
+		}
+		if string(w.literal) != tt.literal || w.fold != tt.fold || w.maxBefore != tt.maxBefore || w.anchored != tt.anchored {
+			t.Errorf("%q: got %q fold=%v maxBefore=%d anchored=%v, want %q fold=%v maxBefore=%d anchored=%v",
+				tt.rule, w.literal, w.fold, w.maxBefore, w.anchored, tt.literal, tt.fold, tt.maxBefore, tt.anchored)
+		}
+	}
+}
+
+// TestSearchWindowMatches checks that windowed matching finds exactly the
+// match a search of the whole content does
+func TestSearchWindowMatches(t *testing.T) {
+	rules := []string{
+		`eval\s*\(`,
+		`(?i)\$default_action\s*=\s*['"]FilesMan`,
+		`\$[a-z]{2,5}=str_rot13\(`,
+		`^\s*<\?php\s+@eval`,
+		`\A<\?php @eval`,
+		`(?-m)^.{0,20}<\?php @eval`,
+		`(?<=\$_POST\[)cmd\]`,
+		`\b[a-z]{1,3}kit\b`,
+		`(?i)skip`,
+		`\Gshell_exec`,
+	}
+	contents := []string{
+		"<?php @eval($_POST['x']);",
+		"<?php\n// header\n\t<?php @eval($x)",
+		"\n  <?php @eval(1)",
+		"x <?php @eval(1)",
+		"$abc=str_rot13('riny'); $defghij=str_rot13(",
+		"$DEFAULT_ACTION = \"filesman\"",
+		"$_POST[cmd] and cmd]",
+		"a Kit and the abkit done",
+		"İ \xff\xfe SKİP skip",
+		"\xff\xfe\xfd eval (1) éé eval(",
+		"shell_exec($x)",
+		"echo 1; shell_exec($x)",
+		"clean content",
+	}
+	for id, rule := range rules {
+		ss := intel.NewSignatureSet()
+		ss.Signatures[id+1] = intel.NewSignature(id+1, rule, "Rule", "", nil)
+		m := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(RegexEngineRegexp2))
+		if m.signatures[id+1].window == nil {
+			t.Fatalf("%q: expected a window", rule)
+		}
+		re := regexp2.MustCompile(rule, regexp2.Multiline|regexp2.Singleline)
+
+		for _, content := range contents {
+			want, err := re.FindStringMatch(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mc := m.NewMatchContext()
+			if err := mc.Match(context.Background(), []byte(content)); err != nil {
+				t.Fatal(err)
+			}
+			got := mc.GetMatches()
+			switch {
+			case want == nil && len(got) != 0:
+				t.Errorf("%q in %q: got match %q, want none", rule, content, got[0].MatchedString)
+			case want != nil && len(got) == 0:
+				t.Errorf("%q in %q: got no match, want %q", rule, content, want.String())
+			case want != nil && (got[0].Position != want.Index || got[0].MatchedString != want.String()):
+				t.Errorf("%q in %q: got %q at %d, want %q at %d", rule, content, got[0].MatchedString, got[0].Position, want.String(), want.Index)
+			}
+		}
+	}
+}
+
+// BenchmarkSearchWindow compares matching signatures on a large clean file
+// with and without search windows. Run it with
+//
+//	go test -run '^$' -bench SearchWindow -benchmem ./internal/scanner
+func BenchmarkSearchWindow(b *testing.B) {
+	ss := intel.NewSignatureSet()
+	for id := 1; id <= 200; id++ {
+		rule := fmt.Sprintf(`\$[a-z_]{1,16}\s*=\s*marker%d_decode\s*\(`, id)
+		ss.Signatures[id] = intel.NewSignature(id, rule, "Marker", "", nil)
+	}
+	content := []byte(strings.Repeat("<?php $title = esc_html( get_the_title() ); echo $title; ?>\n", 4000))
+
+	for _, windowed := range []bool{true, false} {
+		b.Run(fmt.Sprintf("windowed=%v", windowed), func(b *testing.B) {
+			m := NewMatcher(ss, WithMatchAll(true), WithRegexEngine(RegexEngineRegexp2))
+			if !windowed {
+				for _, sig := range m.signatures {
+					sig.window = nil
+				}
+			}
+			b.SetBytes(int64(len(content)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := m.NewMatchContext().Match(context.Background(), content); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
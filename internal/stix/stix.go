@@ -0,0 +1,353 @@
+// Package stix converts malware-scan findings into STIX 2.1 bundles of
+// indicators and malware, for threat intelligence platforms.
+//
+// Each infected file becomes an Indicator, matching the file's SHA-256 hash
+// when it is known and its path otherwise, and each signature that matched
+// becomes a Malware object the indicator "indicates". Object IDs are derived
+// from their content, so exporting the same findings again updates the
+// objects a platform already holds rather than duplicating them.
+package stix
+
+import (
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- UUIDv5 is defined over SHA-1
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpecVersion is the STIX version of the objects produced
+const SpecVersion = "2.1"
+
+// SourceName names Wordfence signatures in external references
+const SourceName = "wordfence"
+
+// timeFormat is the STIX timestamp format, in UTC to the millisecond
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// namespace is the UUIDv5 namespace object IDs are derived in
+var namespace = [16]byte{
+	0xfb, 0xf9, 0xe9, 0xd5, 0x27, 0x6a, 0x40, 0xe1,
+	0x88, 0xbf, 0xf8, 0x03, 0xf8, 0x3e, 0xf6, 0x5b,
+}
+
+// malwareTypes are the STIX malware types of signature categories; other
+// categories are "unknown"
+var malwareTypes = map[string]string{
+	"backdoor": "backdoor",
+	"uploader": "backdoor",
+	"webshell": "webshell",
+}
+
+// patternEscaper escapes a string literal in a STIX pattern
+var patternEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// Signature is a signature that matched a file
+type Signature struct {
+	ID          int
+	Name        string
+	Description string
+	Category    string
+}
+
+// File is an infected file and the signatures that matched it
+type File struct {
+	Path   string
+	SHA256 string // Hex digest, or "" if the file could not be read
+	Owner  string
+	Domain string
+	// Signatures that matched; files without any are skipped
+	Signatures []Signature
+}
+
+// ExternalReference points at the source of an object
+type ExternalReference struct {
+	SourceName  string `json:"source_name"`
+	ExternalID  string `json:"external_id,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// common holds the properties every STIX domain object has
+type common struct {
+	Type         string `json:"type"`
+	SpecVersion  string `json:"spec_version"`
+	ID           string `json:"id"`
+	Created      string `json:"created"`
+	Modified     string `json:"modified"`
+	CreatedByRef string `json:"created_by_ref,omitempty"`
+}
+
+// Identity is the producer of the bundle's objects
+type Identity struct {
+	common
+	Name          string `json:"name"`
+	IdentityClass string `json:"identity_class"`
+}
+
+// Malware is a signature's malware family
+type Malware struct {
+	common
+	Name               string              `json:"name"`
+	Description        string              `json:"description,omitempty"`
+	IsFamily           bool                `json:"is_family"`
+	MalwareTypes       []string            `json:"malware_types"`
+	ExternalReferences []ExternalReference `json:"external_references,omitempty"`
+}
+
+// Indicator matches an infected file by hash or by path
+type Indicator struct {
+	common
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	IndicatorTypes []string `json:"indicator_types"`
+	Pattern        string   `json:"pattern"`
+	PatternType    string   `json:"pattern_type"`
+	ValidFrom      string   `json:"valid_from"`
+	Labels         []string `json:"labels,omitempty"`
+	// Paths are where the file was found, as a custom property
+	Paths []string `json:"x_wordfence_paths,omitempty"`
+}
+
+// Relationship links an indicator to the malware it indicates
+type Relationship struct {
+	common
+	RelationshipType string `json:"relationship_type"`
+	SourceRef        string `json:"source_ref"`
+	TargetRef        string `json:"target_ref"`
+}
+
+// Bundle is a STIX bundle: the identity, then the malware, indicators and
+// relationships
+type Bundle struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Objects []any  `json:"objects"`
+}
+
+// Builder collects files into a bundle. Files sharing a hash share an
+// indicator, and signatures matching several files share a malware object.
+type Builder struct {
+	now           string
+	identity      *Identity
+	malware       []*Malware
+	malwareByKey  map[string]*Malware
+	indicators    []*Indicator
+	indicatorByID map[string]*indicatorState
+	relationships []*Relationship
+	related       map[string]bool
+}
+
+// indicatorState is what an indicator is built from
+type indicatorState struct {
+	indicator  *Indicator
+	sites      []string
+	signatures []string
+	categories map[string]bool
+}
+
+// NewBuilder returns a builder stamping objects created at now
+func NewBuilder(now time.Time) *Builder {
+	stamp := now.UTC().Format(timeFormat)
+	return &Builder{
+		now: stamp,
+		identity: &Identity{
+			common:        newCommon("identity", "wordfence-cli", stamp, ""),
+			Name:          "Wordfence CLI",
+			IdentityClass: "system",
+		},
+		malwareByKey:  make(map[string]*Malware),
+		indicatorByID: make(map[string]*indicatorState),
+		related:       make(map[string]bool),
+	}
+}
+
+// newCommon returns the common properties of an object of type kind whose
+// ID is derived from key
+func newCommon(kind, key, stamp, createdBy string) common {
+	return common{
+		Type:         kind,
+		SpecVersion:  SpecVersion,
+		ID:           kind + "--" + uuidV5(kind+"\x00"+key),
+		Created:      stamp,
+		Modified:     stamp,
+		CreatedByRef: createdBy,
+	}
+}
+
+// Add adds a file's indicator, and the malware it indicates
+func (b *Builder) Add(f *File) {
+	if len(f.Signatures) == 0 {
+		return
+	}
+	state := b.indicatorFor(f)
+	if !slices.Contains(state.indicator.Paths, f.Path) {
+		state.indicator.Paths = append(state.indicator.Paths, f.Path)
+	}
+	site := f.Domain
+	if site == "" {
+		site = f.Owner
+	}
+	if site != "" && !slices.Contains(state.sites, site) {
+		state.sites = append(state.sites, site)
+	}
+
+	for _, sig := range f.Signatures {
+		m := b.malwareFor(sig)
+		if !slices.Contains(state.signatures, m.Name) {
+			state.signatures = append(state.signatures, m.Name)
+		}
+		if sig.Category != "" {
+			state.categories[sig.Category] = true
+		}
+		if key := state.indicator.ID + " " + m.ID; !b.related[key] {
+			b.related[key] = true
+			b.relationships = append(b.relationships, &Relationship{
+				common:           newCommon("relationship", key, b.now, b.identity.ID),
+				RelationshipType: "indicates",
+				SourceRef:        state.indicator.ID,
+				TargetRef:        m.ID,
+			})
+		}
+	}
+}
+
+// indicatorFor returns the indicator matching f, adding it if need be
+func (b *Builder) indicatorFor(f *File) *indicatorState {
+	pattern := filePattern(f)
+	ind := &Indicator{
+		common:         newCommon("indicator", pattern, b.now, b.identity.ID),
+		IndicatorTypes: []string{"malicious-activity"},
+		Pattern:        pattern,
+		PatternType:    "stix",
+		ValidFrom:      b.now,
+	}
+	if state, ok := b.indicatorByID[ind.ID]; ok {
+		return state
+	}
+	state := &indicatorState{indicator: ind, categories: make(map[string]bool)}
+	b.indicatorByID[ind.ID] = state
+	b.indicators = append(b.indicators, ind)
+	return state
+}
+
+// malwareFor returns the malware object of a signature, adding it if need
+// be
+func (b *Builder) malwareFor(sig Signature) *Malware {
+	key := "category:" + sig.Category
+	if sig.ID != 0 {
+		key = "signature:" + strconv.Itoa(sig.ID)
+	}
+	if m, ok := b.malwareByKey[key]; ok {
+		return m
+	}
+
+	name := sig.Name
+	if name == "" {
+		name = fmt.Sprintf("Wordfence signature %d", sig.ID)
+	}
+	kind, ok := malwareTypes[sig.Category]
+	if !ok {
+		kind = "unknown"
+	}
+	m := &Malware{
+		common:       newCommon("malware", key, b.now, b.identity.ID),
+		Name:         name,
+		Description:  sig.Description,
+		IsFamily:     true,
+		MalwareTypes: []string{kind},
+	}
+	if sig.ID != 0 {
+		m.ExternalReferences = []ExternalReference{{SourceName: SourceName, ExternalID: strconv.Itoa(sig.ID)}}
+	}
+	b.malwareByKey[key] = m
+	b.malware = append(b.malware, m)
+	return m
+}
+
+// Len returns the number of indicators added
+func (b *Builder) Len() int {
+	return len(b.indicators)
+}
+
+// Bundle returns the objects added so far in a bundle with a new ID
+func (b *Builder) Bundle() (*Bundle, error) {
+	id, err := uuidV4()
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]any, 0, 1+len(b.malware)+len(b.indicators)+len(b.relationships))
+	objects = append(objects, b.identity)
+	for _, m := range b.malware {
+		objects = append(objects, m)
+	}
+	for _, ind := range b.indicators {
+		b.describe(b.indicatorByID[ind.ID])
+		objects = append(objects, ind)
+	}
+	for _, r := range b.relationships {
+		objects = append(objects, r)
+	}
+	return &Bundle{Type: "bundle", ID: "bundle--" + id, Objects: objects}, nil
+}
+
+// describe names an indicator after the files and signatures it covers
+func (b *Builder) describe(state *indicatorState) {
+	ind := state.indicator
+	ind.Name = "Malicious file " + path.Base(ind.Paths[0])
+	if len(ind.Paths) > 1 {
+		ind.Name += fmt.Sprintf(" (%d copies)", len(ind.Paths))
+	}
+	ind.Description = "Matched " + strings.Join(state.signatures, ", ")
+	if len(state.sites) > 0 {
+		ind.Description += " on " + strings.Join(state.sites, ", ")
+	}
+	ind.Labels = ind.Labels[:0]
+	for category := range state.categories {
+		ind.Labels = append(ind.Labels, category)
+	}
+	sort.Strings(ind.Labels)
+}
+
+// filePattern matches a file by its hash if known, and by its path if not
+func filePattern(f *File) string {
+	if f.SHA256 != "" {
+		return fmt.Sprintf("[file:hashes.'SHA-256' = '%s']", strings.ToLower(f.SHA256))
+	}
+	dir, name := path.Split(f.Path)
+	if dir != "/" {
+		dir = strings.TrimSuffix(dir, "/")
+	}
+	return fmt.Sprintf("[file:name = '%s' AND file:parent_directory_ref.path = '%s']",
+		patternEscaper.Replace(name), patternEscaper.Replace(dir))
+}
+
+// uuidV5 returns the name-based UUID of name in the package's namespace
+func uuidV5(name string) string {
+	h := sha1.New() // #nosec G401 -- UUIDv5 is defined over SHA-1
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u)
+}
+
+// uuidV4 returns a random UUID
+func uuidV4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("generating bundle ID: %w", err)
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u), nil
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
@@ -0,0 +1,91 @@
+package stix
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	backdoor := Signature{ID: 101, Name: "PHP Backdoor", Description: "Runs posted code", Category: "backdoor"}
+	nulled := Signature{ID: 202, Name: "Nulled plugin", Category: "nulled"}
+
+	b := NewBuilder(now)
+	b.Add(&File{Path: "/www/a/shell.php", SHA256: "ABCDEF", Domain: "a.example", Signatures: []Signature{backdoor}})
+	b.Add(&File{Path: "/www/b/copy.php", SHA256: "abcdef", Owner: "b", Signatures: []Signature{backdoor, nulled}})
+	b.Add(&File{Path: "/www/c/it's.php", Signatures: []Signature{nulled}})
+	b.Add(&File{Path: "/www/c/clean.php"})
+
+	if b.Len() != 2 {
+		t.Fatalf("expected 2 indicators, got %d", b.Len())
+	}
+	bundle, err := b.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^bundle--[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(bundle.ID) {
+		t.Errorf("unexpected bundle ID %q", bundle.ID)
+	}
+	// identity, 2 malware, 2 indicators, 3 relationships
+	if len(bundle.Objects) != 8 {
+		t.Fatalf("expected 8 objects, got %d", len(bundle.Objects))
+	}
+
+	shared := bundle.Objects[3].(*Indicator)
+	if shared.Pattern != "[file:hashes.'SHA-256' = 'abcdef']" || len(shared.Paths) != 2 {
+		t.Errorf("expected the copies to share an indicator, got %+v", shared)
+	}
+	if shared.Name != "Malicious file shell.php (2 copies)" || shared.Description != "Matched PHP Backdoor, Nulled plugin on a.example, b" {
+		t.Errorf("unexpected name %q and description %q", shared.Name, shared.Description)
+	}
+	if strings.Join(shared.Labels, ",") != "backdoor,nulled" || shared.Created != "2025-03-01T10:00:00.000Z" {
+		t.Errorf("unexpected labels %v or created %q", shared.Labels, shared.Created)
+	}
+	byPath := bundle.Objects[4].(*Indicator)
+	if byPath.Pattern != `[file:name = 'it\'s.php' AND file:parent_directory_ref.path = '/www/c']` {
+		t.Errorf("unexpected path pattern %q", byPath.Pattern)
+	}
+
+	m := bundle.Objects[1].(*Malware)
+	if m.MalwareTypes[0] != "backdoor" || !m.IsFamily || m.ExternalReferences[0].ExternalID != "101" {
+		t.Errorf("unexpected malware %+v", m)
+	}
+	if types := bundle.Objects[2].(*Malware).MalwareTypes; types[0] != "unknown" {
+		t.Errorf("expected nulled code to be of unknown type, got %v", types)
+	}
+	rel := bundle.Objects[5].(*Relationship)
+	if rel.SourceRef != shared.ID || rel.TargetRef != m.ID || rel.CreatedByRef != bundle.Objects[0].(*Identity).ID {
+		t.Errorf("unexpected relationship %+v", rel)
+	}
+
+	// The same findings get the same object IDs in a later export
+	again := NewBuilder(now.Add(time.Hour))
+	again.Add(&File{Path: "/elsewhere/x.php", SHA256: "abcdef", Signatures: []Signature{backdoor}})
+	later, err := again.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if later.Objects[2].(*Indicator).ID != shared.ID || later.Objects[1].(*Malware).ID != m.ID || later.ID == bundle.ID {
+		t.Error("expected stable object IDs and a new bundle ID")
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"type":"bundle"`, `"spec_version":"2.1"`, `"x_wordfence_paths"`, `"relationship_type":"indicates"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in %s", want, data)
+		}
+	}
+}
+
+func TestUUIDV5(t *testing.T) {
+	// Python: uuid.uuid5(uuid.UUID("fbf9e9d5-276a-40e1-88bf-f803f83ef65b"), "example")
+	if got := uuidV5("example"); got != "f64a8a9c-27c5-55fc-ac8c-d29b37b77dff" {
+		t.Errorf("unexpected UUID %s", got)
+	}
+}
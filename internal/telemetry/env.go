@@ -0,0 +1,186 @@
+// Package telemetry provides tracing configured by the OTEL_* environment
+// variables
+package telemetry
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// Defaults of the OTEL_* settings
+const (
+	DefaultEndpoint    = "http://localhost:4318"
+	DefaultServiceName = "wordfence-cli"
+	DefaultTimeout     = 10 * time.Second
+	tracesPath         = "/v1/traces"
+)
+
+// Exporters OTEL_TRACES_EXPORTER may name
+const (
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+	ExporterNone    = "none"
+)
+
+// FromEnv creates the tracer configured by the standard OpenTelemetry
+// environment variables, or returns nil if tracing is off. Tracing is on
+// when OTEL_TRACES_EXPORTER is otlp or console, or an OTLP endpoint is set
+// and OTEL_TRACES_EXPORTER isn't none. Spans are exported as OTLP/HTTP
+// JSON, the only protocol supported. errs is called with failed exports.
+func FromEnv(version string, errs func(error)) (*Tracer, error) {
+	return fromEnv(os.Getenv, version, errs)
+}
+
+func fromEnv(getenv func(string) string, version string, errs func(error)) (*Tracer, error) {
+	if disabled, _ := strconv.ParseBool(getenv("OTEL_SDK_DISABLED")); disabled {
+		return nil, nil
+	}
+	// signal reads a setting for traces, or for every signal
+	signal := func(name string) string {
+		if value := getenv("OTEL_EXPORTER_OTLP_TRACES_" + name); value != "" {
+			return value
+		}
+		return getenv("OTEL_EXPORTER_OTLP_" + name)
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(getenv("OTEL_TRACES_EXPORTER")))
+	if kind == "" && signal("ENDPOINT") != "" {
+		kind = ExporterOTLP
+	}
+
+	opts := []TracerOption{WithResource(resourceFromEnv(getenv, version)...), WithErrorHandler(errs)}
+	if value := getenv("TRACEPARENT"); value != "" {
+		parent, err := ParseTraceparent(value)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRemoteParent(parent))
+	}
+	if value := getenv("OTEL_BSP_SCHEDULE_DELAY"); value != "" {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("invalid OTEL_BSP_SCHEDULE_DELAY %q", value)
+		}
+		opts = append(opts, WithScheduleDelay(time.Duration(ms)*time.Millisecond))
+	}
+
+	switch kind {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterConsole:
+		return newTracer(&consoleExporter{w: os.Stderr, version: version}, opts...), nil
+	case ExporterOTLP:
+		exp, err := otlpFromEnv(getenv, signal, version)
+		if err != nil {
+			return nil, err
+		}
+		return newTracer(exp, opts...), nil
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q: use %s, %s or %s", kind, ExporterOTLP, ExporterConsole, ExporterNone)
+	}
+}
+
+// otlpFromEnv creates the OTLP exporter configured by signal settings
+func otlpFromEnv(getenv, signal func(string) string, version string) (*otlpExporter, error) {
+	if protocol := signal("PROTOCOL"); protocol != "" && protocol != "http/json" {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q: only http/json is supported, on the collector's HTTP port (4318)", protocol)
+	}
+
+	// A traces endpoint is used as it is; the endpoint of every signal
+	// has the traces path added
+	endpoint := strings.TrimSpace(getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"))
+	if endpoint == "" {
+		base := strings.TrimSpace(getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+		if base == "" {
+			base = DefaultEndpoint
+		}
+		endpoint = strings.TrimSuffix(base, "/") + tracesPath
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+
+	timeout := DefaultTimeout
+	if value := signal("TIMEOUT"); value != "" {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("invalid OTLP timeout %q", value)
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	headers, err := parseKeyValues(signal("HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP headers: %w", err)
+	}
+
+	clientOpts := []api.ClientOption{api.WithTimeout(timeout), api.WithRetries(2)}
+	if cert, key, ca := signal("CLIENT_CERTIFICATE"), signal("CLIENT_KEY"), signal("CERTIFICATE"); cert != "" || key != "" || ca != "" {
+		tlsConfig, err := api.LoadTLSConfig(cert, key, ca)
+		if err != nil {
+			return nil, fmt.Errorf("OTLP TLS: %w", err)
+		}
+		clientOpts = append(clientOpts, api.WithTLSConfig(tlsConfig))
+	}
+	return &otlpExporter{client: api.NewClient(endpoint, clientOpts...), headers: headers, version: version}, nil
+}
+
+// resourceFromEnv describes the process from OTEL_RESOURCE_ATTRIBUTES and
+// OTEL_SERVICE_NAME, which takes precedence
+func resourceFromEnv(getenv func(string) string, version string) []Attribute {
+	attrs, _ := parseKeyValues(getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	service := getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = attrs["service.name"]
+	}
+	if service == "" {
+		service = DefaultServiceName
+	}
+	resource := []Attribute{String("service.name", service), String("service.version", version)}
+	if host, err := os.Hostname(); err == nil {
+		resource = append(resource, String("host.name", host))
+	}
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		if key != "service.name" && key != "service.version" && key != "host.name" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		resource = append(resource, String(key, attrs[key]))
+	}
+	return resource
+}
+
+// parseKeyValues reads a comma-separated list of URL-encoded key=value
+// pairs, as OTEL_EXPORTER_OTLP_HEADERS and OTEL_RESOURCE_ATTRIBUTES hold
+func parseKeyValues(list string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("missing = in %q", pair)
+		}
+		key, err := url.QueryUnescape(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("decoding %q: %w", pair, err)
+		}
+		value, err = url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("decoding %q: %w", pair, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
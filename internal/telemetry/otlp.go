@@ -0,0 +1,164 @@
+// Package telemetry provides the exporters sending spans to an OTLP/HTTP
+// collector or the console
+package telemetry
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+)
+
+// scopeName names the instrumentation in exported spans
+const scopeName = "github.com/greysquirr3l/wordfence-go"
+
+// OTLP span kinds and status codes
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// otlpExporter posts spans to an OTLP/HTTP collector as JSON
+type otlpExporter struct {
+	client  *api.Client
+	headers map[string]string
+	version string
+}
+
+func (e *otlpExporter) export(ctx context.Context, resource []Attribute, spans []*Span) error {
+	headers := make(map[string]string, len(e.headers))
+	for key, value := range e.headers {
+		headers[key] = value
+	}
+	if _, err := e.client.PostJSON(ctx, "", encodeSpans(resource, e.version, spans), headers); err != nil {
+		return fmt.Errorf("exporting %d spans: %w", len(spans), err)
+	}
+	return nil
+}
+
+// consoleExporter writes each batch of spans as a line of OTLP JSON, for
+// OTEL_TRACES_EXPORTER=console
+type consoleExporter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	version string
+}
+
+func (e *consoleExporter) export(_ context.Context, resource []Attribute, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := json.NewEncoder(e.w).Encode(encodeSpans(resource, e.version, spans)); err != nil {
+		return fmt.Errorf("writing %d spans: %w", len(spans), err)
+	}
+	return nil
+}
+
+// OTLP JSON encoding of ExportTraceServiceRequest, in which IDs are hex
+// and 64-bit integers are strings
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// encodeSpans returns an export request of spans from a process described
+// by resource
+func encodeSpans(resource []Attribute, version string, spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.ctx.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.ctx.SpanID[:]),
+			Name:              span.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.attrs),
+		}
+		if span.parent != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(span.parent[:])
+		}
+		if span.failed {
+			s.Status = &otlpStatus{Code: statusCodeError, Message: span.status}
+		}
+		span.mu.Unlock()
+		encoded = append(encoded, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: encodeAttributes(resource)},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: scopeName, Version: version},
+			Spans: encoded,
+		}},
+	}}}
+}
+
+// encodeAttributes encodes attributes, those of unknown types as strings
+func encodeAttributes(attrs []Attribute) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(attrs))
+	for _, attr := range attrs {
+		var value otlpValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		encoded = append(encoded, otlpAttribute{Key: attr.Key, Value: value})
+	}
+	return encoded
+}
@@ -0,0 +1,369 @@
+// Package telemetry traces scans as OpenTelemetry spans, exported over
+// OTLP/HTTP with JSON encoding, so they show up beside the rest of a
+// platform's traces. Tracing is off unless a tracer is set, and spans
+// started without one cost nothing.
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Attribute is a key and a string, int64, float64 or bool value describing
+// a span
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: int64(value)} }
+
+// Int64 returns an integer attribute
+func Int64(key string, value int64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Float returns a floating point attribute
+func Float(key string, value float64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// SpanContext identifies a span within its trace
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid reports whether the trace and span IDs are set
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != [16]byte{} && c.SpanID != [8]byte{}
+}
+
+// ParseTraceparent reads a W3C traceparent header, such as the TRACEPARENT
+// environment variable a traced parent process passes on
+func ParseTraceparent(value string) (SpanContext, error) {
+	var c SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return c, fmt.Errorf("invalid traceparent %q", value)
+	}
+	trace, err := hex.DecodeString(parts[1])
+	if err != nil || len(trace) != len(c.TraceID) {
+		return c, fmt.Errorf("invalid trace ID in traceparent %q", value)
+	}
+	span, err := hex.DecodeString(parts[2])
+	if err != nil || len(span) != len(c.SpanID) {
+		return c, fmt.Errorf("invalid span ID in traceparent %q", value)
+	}
+	copy(c.TraceID[:], trace)
+	copy(c.SpanID[:], span)
+	if !c.IsValid() {
+		return c, fmt.Errorf("invalid traceparent %q", value)
+	}
+	return c, nil
+}
+
+// Span is an operation being traced. Its methods do nothing on a nil span,
+// which is what Start returns without a tracer.
+type Span struct {
+	tracer *Tracer
+	name   string
+	ctx    SpanContext
+	parent [8]byte
+	start  time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []Attribute
+	failed bool
+	status string
+	ended  bool
+}
+
+// SpanContext returns the IDs of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+// SetAttributes adds attributes describing the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed with err, unless err is nil
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.status = err.Error()
+}
+
+// End ends the span and hands it to the tracer's exporter. Only the first
+// call has any effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.tracer.enqueue(s)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns ctx with span as the parent of spans started
+// from it
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span of ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Defaults for batching spans
+const (
+	DefaultScheduleDelay = 5 * time.Second
+	DefaultBatchSize     = 512
+	// maxQueuedSpans is how many ended spans are held for export before
+	// more are dropped
+	maxQueuedSpans = 8 * DefaultBatchSize
+)
+
+// exporter sends ended spans to where they are collected
+type exporter interface {
+	export(ctx context.Context, resource []Attribute, spans []*Span) error
+}
+
+// Tracer starts spans and exports them in batches in the background.
+// Shutdown must be called to export the last of them.
+type Tracer struct {
+	exporter exporter
+	resource []Attribute
+	// parent is the remote span of the process that started this one, if
+	// any, the parent of spans started without a span in their context
+	parent SpanContext
+	delay  time.Duration
+	// errs is called with export failures (nil ignores them)
+	errs func(error)
+
+	mu      sync.Mutex
+	pending []*Span
+	dropped int64
+	flush   chan struct{}
+	done    chan struct{}
+	stop    sync.Once
+}
+
+// TracerOption configures a Tracer
+type TracerOption func(*Tracer)
+
+// WithResource sets attributes describing the process, such as
+// service.name
+func WithResource(attrs ...Attribute) TracerOption {
+	return func(t *Tracer) {
+		t.resource = append(t.resource, attrs...)
+	}
+}
+
+// WithRemoteParent makes spans started without a parent children of a
+// span in another process
+func WithRemoteParent(parent SpanContext) TracerOption {
+	return func(t *Tracer) {
+		t.parent = parent
+	}
+}
+
+// WithScheduleDelay sets how long ended spans wait for a full batch
+func WithScheduleDelay(delay time.Duration) TracerOption {
+	return func(t *Tracer) {
+		t.delay = delay
+	}
+}
+
+// WithErrorHandler sets a function called with failed exports
+func WithErrorHandler(handle func(error)) TracerOption {
+	return func(t *Tracer) {
+		t.errs = handle
+	}
+}
+
+func newTracer(exp exporter, opts ...TracerOption) *Tracer {
+	t := &Tracer{
+		exporter: exp,
+		delay:    DefaultScheduleDelay,
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.delay <= 0 {
+		t.delay = DefaultScheduleDelay
+	}
+	go t.run()
+	return t
+}
+
+// Start starts a span named name, a child of the span of ctx or of the
+// tracer's remote parent, and returns ctx with the span in it
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return t.StartAt(ctx, name, time.Now(), attrs...)
+}
+
+// StartAt is Start for a span that started at start, such as one only
+// traced once it turned out to be slow
+func (t *Tracer) StartAt(ctx context.Context, name string, start time.Time, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{tracer: t, name: name, start: start, attrs: attrs}
+	switch parent := SpanFromContext(ctx); {
+	case parent != nil:
+		span.ctx.TraceID = parent.ctx.TraceID
+		span.parent = parent.ctx.SpanID
+	case t.parent.IsValid():
+		span.ctx.TraceID = t.parent.TraceID
+		span.parent = t.parent.SpanID
+	default:
+		_, _ = rand.Read(span.ctx.TraceID[:])
+	}
+	_, _ = rand.Read(span.ctx.SpanID[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+// enqueue holds an ended span for the next export
+func (t *Tracer) enqueue(span *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) >= maxQueuedSpans {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, span)
+	if len(t.pending) >= DefaultBatchSize {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Dropped returns how many spans were dropped because the exporter fell
+// behind
+func (t *Tracer) Dropped() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+// run exports spans every schedule delay, or sooner once a batch is full,
+// until the tracer is shut down
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.delay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		case <-t.flush:
+		}
+		t.export(context.Background())
+	}
+}
+
+// export sends the spans pending, a batch at a time
+func (t *Tracer) export(ctx context.Context) error {
+	var errs []error
+	for {
+		t.mu.Lock()
+		n := min(len(t.pending), DefaultBatchSize)
+		batch := t.pending[:n:n]
+		t.pending = t.pending[n:]
+		t.mu.Unlock()
+		if n == 0 {
+			return errors.Join(errs...)
+		}
+		if err := t.exporter.export(ctx, t.resource, batch); err != nil {
+			if t.errs != nil {
+				t.errs(err)
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				return errors.Join(errs...)
+			}
+		}
+	}
+}
+
+// Shutdown stops the background exports and exports the spans ended so
+// far, giving up once ctx is done
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.stop.Do(func() { close(t.done) })
+	return t.export(ctx)
+}
+
+// global is the tracer the package functions start spans with
+var global atomic.Pointer[Tracer]
+
+// SetTracer sets the tracer Start uses (nil turns tracing off)
+func SetTracer(t *Tracer) {
+	global.Store(t)
+}
+
+// Enabled reports whether a tracer is set
+func Enabled() bool {
+	return global.Load() != nil
+}
+
+// Start starts a span with the tracer set, returning ctx and a nil span if
+// there is none
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return global.Load().Start(ctx, name, attrs...)
+}
+
+// StartAt starts a span that started at start with the tracer set
+func StartAt(ctx context.Context, name string, start time.Time, attrs ...Attribute) (context.Context, *Span) {
+	return global.Load().StartAt(ctx, name, start, attrs...)
+}
+
+// Shutdown exports the last spans of the tracer set
+func Shutdown(ctx context.Context) error {
+	return global.Load().Shutdown(ctx)
+}
@@ -0,0 +1,131 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTracerExportsOTLP(t *testing.T) {
+	var mu sync.Mutex
+	var requests []otlpRequest
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request to %s with %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		auth = r.Header.Get("Authorization")
+		requests = append(requests, req)
+	}))
+	defer server.Close()
+
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": server.URL + "/",
+		"OTEL_EXPORTER_OTLP_HEADERS":  "Authorization=Bearer%20token",
+		"OTEL_SERVICE_NAME":           "scanner",
+		"OTEL_RESOURCE_ATTRIBUTES":    "deployment.environment=prod,service.name=ignored",
+		"TRACEPARENT":                 "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+	tracer, err := fromEnv(func(key string) string { return env[key] }, "1.2.3", nil)
+	if err != nil || tracer == nil {
+		t.Fatalf("expected a tracer, got %v, %v", tracer, err)
+	}
+
+	ctx, scan := tracer.Start(context.Background(), "scan", String("path", "/var/www"))
+	_, stage := tracer.Start(ctx, "walk")
+	stage.End()
+	_, file := tracer.StartAt(ctx, "file", time.Now().Add(-2*time.Second), Int64("size", 42))
+	file.RecordError(errors.New("read failed"))
+	file.End()
+	scan.End()
+	scan.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 || auth != "Bearer token" {
+		t.Fatalf("expected one export with the headers, got %d with %q", len(requests), auth)
+	}
+	resource := requests[0].ResourceSpans[0].Resource.Attributes
+	if resource[0].Key != "service.name" || *resource[0].Value.StringValue != "scanner" || resource[len(resource)-1].Key != "deployment.environment" {
+		t.Errorf("unexpected resource %+v", resource)
+	}
+	spans := requests[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	byName := make(map[string]otlpSpan)
+	for _, span := range spans {
+		if span.TraceID != "0af7651916cd43dd8448eb211c80319c" {
+			t.Errorf("expected span %s in the parent's trace, got %s", span.Name, span.TraceID)
+		}
+		byName[span.Name] = span
+	}
+	if byName["scan"].ParentSpanID != "b7ad6b7169203331" || byName["walk"].ParentSpanID != byName["scan"].SpanID {
+		t.Errorf("unexpected parents: scan %s, walk %s", byName["scan"].ParentSpanID, byName["walk"].ParentSpanID)
+	}
+	if status := byName["file"].Status; status == nil || status.Code != statusCodeError || status.Message != "read failed" {
+		t.Errorf("expected the file span failed, got %+v", status)
+	}
+	if attrs := byName["file"].Attributes; len(attrs) != 1 || *attrs[0].Value.IntValue != "42" {
+		t.Errorf("unexpected file attributes %+v", attrs)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		tracing bool
+		wantErr bool
+	}{
+		{"unset", nil, false, false},
+		{"endpoint", map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://collector:4318/v1/traces"}, true, false},
+		{"exporter", map[string]string{"OTEL_TRACES_EXPORTER": "console"}, true, false},
+		{"none", map[string]string{"OTEL_TRACES_EXPORTER": "none", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"}, false, false},
+		{"disabled", map[string]string{"OTEL_SDK_DISABLED": "true", "OTEL_TRACES_EXPORTER": "otlp"}, false, false},
+		{"grpc", map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}, false, true},
+		{"unknown exporter", map[string]string{"OTEL_TRACES_EXPORTER": "zipkin"}, false, true},
+		{"bad traceparent", map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "TRACEPARENT": "00-xyz"}, false, true},
+		{"bad headers", map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_HEADERS": "token"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, err := fromEnv(func(key string) string { return tt.env[key] }, "dev", nil)
+			if (err != nil) != tt.wantErr || (tracer != nil) != tt.tracing {
+				t.Errorf("got %v, %v", tracer, err)
+			}
+			_ = tracer.Shutdown(context.Background())
+		})
+	}
+}
+
+func TestStartWithoutTracer(t *testing.T) {
+	SetTracer(nil)
+	ctx := context.Background()
+	got, span := Start(ctx, "scan")
+	if got != ctx || span != nil {
+		t.Errorf("expected no span without a tracer")
+	}
+	span.SetAttributes(Int("files", 1))
+	span.RecordError(errors.New("failed"))
+	span.End()
+	if err := Shutdown(ctx); err != nil {
+		t.Error(err)
+	}
+}
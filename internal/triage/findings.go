@@ -0,0 +1,164 @@
+// Package triage provides loading of malware-scan findings for review
+package triage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// remediationRecord marks the remediation records malware-scan writes
+// beside the matches of a file. Output from before record_type existed
+// marked them with the signature category instead.
+const remediationRecord = "remediation"
+
+// findingRecord marks the --check-persistence findings malware-scan writes
+const findingRecord = "finding"
+
+// Match is one signature match, or other finding, in a file
+type Match struct {
+	SignatureID int
+	Name        string
+	Description string
+	Category    string
+	MatchedText string
+	Audit       bool // A --check-persistence finding rather than a signature match
+}
+
+// Finding is a file with matches, and what the analyst decided to do
+type Finding struct {
+	Path        string
+	Owner       string
+	Domain      string
+	Matches     []*Match
+	Truncated   bool   // Scanned with --first-match-only; there may be more matches
+	Remediation string // Outcome of malware-scan --remediate, if it ran
+	Action      Action
+}
+
+// record is one result in malware-scan JSON output
+type record struct {
+	RecordType           string `json:"record_type"`
+	Filename             string `json:"filename"`
+	SignatureID          int    `json:"signature_id"`
+	SignatureName        string `json:"signature_name"`
+	SignatureDescription string `json:"signature_description"`
+	SignatureCategory    string `json:"signature_category"`
+	MatchedText          string `json:"matched_text"`
+	MatchingTruncated    bool   `json:"matching_truncated"`
+	Remediation          string `json:"remediation"`
+	Owner                string `json:"owner"`
+	Domain               string `json:"domain"`
+}
+
+// LoadFindings reads malware-scan JSON output, as an array, an object
+// with a summary, or one result per line (NDJSON), and groups its matches
+// by file in the order the files first appear
+func LoadFindings(r io.Reader) ([]*Finding, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading results: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var records []*record
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("parsing results: %w", err)
+		}
+	} else {
+		// A summary-wrapped document is a single object holding results
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var value struct {
+				record
+				Results []*record `json:"results"`
+			}
+			if err := dec.Decode(&value); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("parsing results: %w", err)
+			}
+			if value.Results != nil {
+				records = append(records, value.Results...)
+			} else {
+				records = append(records, &value.record)
+			}
+		}
+	}
+
+	var findings []*Finding
+	index := make(map[string]*Finding)
+	for _, rec := range records {
+		if rec.Filename == "" {
+			continue
+		}
+		f, ok := index[rec.Filename]
+		if !ok {
+			f = &Finding{Path: rec.Filename, Owner: rec.Owner, Domain: rec.Domain}
+			index[rec.Filename] = f
+			findings = append(findings, f)
+		}
+		if rec.RecordType == remediationRecord || (rec.RecordType == "" && rec.SignatureCategory == remediationRecord) {
+			f.Remediation = rec.Remediation
+			continue
+		}
+		f.Truncated = f.Truncated || rec.MatchingTruncated
+		f.Matches = append(f.Matches, &Match{
+			SignatureID: rec.SignatureID,
+			Name:        rec.SignatureName,
+			Description: rec.SignatureDescription,
+			Category:    rec.SignatureCategory,
+			MatchedText: rec.MatchedText,
+			Audit:       rec.RecordType == findingRecord,
+		})
+	}
+	return findings, nil
+}
+
+// ContextLine is a line of a file shown around a match
+type ContextLine struct {
+	Number int
+	Text   string
+	Match  bool // Part of the matched text
+}
+
+// MatchContext returns the lines of a file holding matched text and up to
+// radius lines either side. It returns nothing if the text is no longer in
+// the file.
+func MatchContext(path, matched string, radius int) ([]ContextLine, error) {
+	if matched == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path) // #nosec G304 -- file named in the scan results under review
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	pos := bytes.Index(content, []byte(matched))
+	if pos < 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(content), "\n")
+	first := bytes.Count(content[:pos], []byte("\n"))
+	last := first + strings.Count(matched, "\n")
+
+	start := max(first-radius, 0)
+	end := min(last+radius, len(lines)-1)
+	context := make([]ContextLine, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		context = append(context, ContextLine{
+			Number: i + 1,
+			Text:   strings.TrimRight(lines[i], "\r"),
+			Match:  i >= first && i <= last,
+		})
+	}
+	return context, nil
+}
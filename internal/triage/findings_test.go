@@ -0,0 +1,84 @@
+package triage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFindings(t *testing.T) {
+	array := `[
+  {"filename": "/www/a.php", "signature_id": 1, "signature_name": "Backdoor", "matched_text": "eval("},
+  {"filename": "/www/b.php", "signature_id": 2, "signature_name": "Shell"},
+  {"filename": "/www/a.php", "signature_id": 3, "signature_name": "Dropper", "matching_truncated": true},
+  {"filename": "/www/a.php", "signature_category": "remediation", "signature_name": "remediated", "remediation": "remediated"},
+  {"record_type": "finding", "filename": "/www/b.php", "signature_name": "Cron job", "signature_category": "cron"}
+]`
+	wrapped := `{"results": ` + array + `, "summary": {"sites": []}}`
+	ndjson := strings.Join([]string{
+		`{"filename": "/www/a.php", "signature_id": 1, "signature_name": "Backdoor", "matched_text": "eval("}`,
+		`{"filename": "/www/b.php", "signature_id": 2, "signature_name": "Shell"}`,
+		`{"filename": "/www/a.php", "signature_id": 3, "signature_name": "Dropper", "matching_truncated": true}`,
+		`{"record_type": "remediation", "filename": "/www/a.php", "remediation": "remediated"}`,
+		`{"record_type": "finding", "filename": "/www/b.php", "signature_name": "Cron job", "signature_category": "cron"}`,
+	}, "\n")
+
+	for name, input := range map[string]string{"array": array, "wrapped": wrapped, "ndjson": ndjson} {
+		findings, err := LoadFindings(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(findings) != 2 || findings[0].Path != "/www/a.php" || findings[1].Path != "/www/b.php" {
+			t.Fatalf("%s: expected a.php then b.php, got %+v", name, findings)
+		}
+		a := findings[0]
+		if len(a.Matches) != 2 || a.Matches[1].SignatureID != 3 || !a.Truncated || a.Remediation != "remediated" {
+			t.Errorf("%s: unexpected finding %+v", name, a)
+		}
+		if b := findings[1]; len(b.Matches) != 2 || b.Matches[0].Audit || !b.Matches[1].Audit {
+			t.Errorf("%s: expected the persistence finding marked, got %+v", name, b.Matches)
+		}
+	}
+
+	if findings, err := LoadFindings(strings.NewReader("  \n")); err != nil || findings != nil {
+		t.Errorf("expected no findings from empty input, got %v (%v)", findings, err)
+	}
+	if _, err := LoadFindings(strings.NewReader("{not json")); err == nil {
+		t.Error("expected an error for invalid input")
+	}
+}
+
+func TestMatchContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shell.php")
+	content := "<?php\n// one\n// two\n$x = base64_decode(\n  'ZXZhbA==');\n// five\n// six\n// seven\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	context, err := MatchContext(path, "base64_decode(\n  'ZXZhbA==')", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var numbers []int
+	var matched []int
+	for _, line := range context {
+		numbers = append(numbers, line.Number)
+		if line.Match {
+			matched = append(matched, line.Number)
+		}
+	}
+	if len(numbers) != 6 || numbers[0] != 2 || numbers[5] != 7 {
+		t.Errorf("expected lines 2-7, got %v", numbers)
+	}
+	if len(matched) != 2 || matched[0] != 4 || matched[1] != 5 {
+		t.Errorf("expected lines 4-5 matched, got %v", matched)
+	}
+
+	if context, err := MatchContext(path, "not in the file", 2); err != nil || context != nil {
+		t.Errorf("expected no context for missing text, got %v (%v)", context, err)
+	}
+	if _, err := MatchContext(filepath.Join(t.TempDir(), "gone.php"), "x", 2); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,129 @@
+// Package triage provides action plans of wordfence commands
+package triage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Action is what the analyst decided to do with a finding
+type Action string
+
+const (
+	// ActionNone leaves the file alone
+	ActionNone Action = ""
+	// ActionRemediate restores the file to its original WordPress version
+	ActionRemediate Action = "remediate"
+	// ActionQuarantine moves the file into the quarantine directory
+	ActionQuarantine Action = "quarantine"
+	// ActionIgnore suppresses the file's matches as benign
+	ActionIgnore Action = "ignore"
+)
+
+// IgnoreReason is the reason recorded for suppressions made in triage
+const IgnoreReason = "Reviewed in triage"
+
+// WritePlan writes the decisions as a shell script of wordfence commands,
+// which carries them out when run. Suppressions are by signature, except
+// for matches without one, such as known-malware hashes, which suppress
+// every match in the file.
+func WritePlan(w io.Writer, findings []*Finding, source string, now time.Time) error {
+	var remediate, quarantine, ignoreAll []string
+	ignore := make(map[int][]string)
+	for _, f := range findings {
+		switch f.Action {
+		case ActionRemediate:
+			remediate = append(remediate, f.Path)
+		case ActionQuarantine:
+			quarantine = append(quarantine, f.Path)
+		case ActionIgnore:
+			ids := signatureIDs(f)
+			if len(ids) == 0 {
+				ignoreAll = append(ignoreAll, f.Path)
+				continue
+			}
+			for _, id := range ids {
+				ignore[id] = append(ignore[id], f.Path)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&sb, "# Action plan from reviewing %s with \"wordfence triage\" on %s.\n", source, now.Format(time.RFC3339))
+	sb.WriteString("# Check it, then carry it out with: sh <this file>\n")
+	sb.WriteString("set -e\n")
+
+	if len(remediate) > 0 {
+		fmt.Fprintf(&sb, "\n# Restore %s to their original WordPress versions\n", files(len(remediate)))
+		writeCommand(&sb, "wordfence remediate", remediate)
+	}
+	if len(quarantine) > 0 {
+		fmt.Fprintf(&sb, "\n# Quarantine %s\n", files(len(quarantine)))
+		writeCommand(&sb, "wordfence remediate --quarantine", quarantine)
+	}
+	if len(ignore) > 0 || len(ignoreAll) > 0 {
+		fmt.Fprintf(&sb, "\n# Suppress matches reviewed as benign\n")
+		ids := make([]int, 0, len(ignore))
+		for id := range ignore {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			writeCommand(&sb, fmt.Sprintf("wordfence ignore add --signature %d --reason %s", id, shellQuote(IgnoreReason)), ignore[id])
+		}
+		if len(ignoreAll) > 0 {
+			writeCommand(&sb, "wordfence ignore add --reason "+shellQuote(IgnoreReason), ignoreAll)
+		}
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	return nil
+}
+
+// signatureIDs returns the distinct signatures matching a finding, or
+// none if any match has no signature
+func signatureIDs(f *Finding) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, m := range f.Matches {
+		if m.SignatureID <= 0 {
+			return nil
+		}
+		if !seen[m.SignatureID] {
+			seen[m.SignatureID] = true
+			ids = append(ids, m.SignatureID)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// writeCommand writes a command taking paths, one per line
+func writeCommand(sb *strings.Builder, command string, paths []string) {
+	sb.WriteString(command)
+	sb.WriteString(" --")
+	for _, path := range paths {
+		sb.WriteString(" \\\n  ")
+		sb.WriteString(shellQuote(path))
+	}
+	sb.WriteString("\n")
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// files describes a number of files
+func files(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
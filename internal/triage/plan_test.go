@@ -0,0 +1,44 @@
+package triage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePlan(t *testing.T) {
+	findings := []*Finding{
+		{Path: "/www/wp-includes/load.php", Action: ActionRemediate},
+		{Path: "/www/uploads/it's.php", Action: ActionQuarantine},
+		{Path: "/www/plugins/sec/scan.php", Action: ActionIgnore, Matches: []*Match{{SignatureID: 7}, {SignatureID: 3}, {SignatureID: 7}}},
+		{Path: "/www/plugins/sec/hash.php", Action: ActionIgnore, Matches: []*Match{{SignatureID: 3}, {SignatureID: 0}}},
+		{Path: "/www/undecided.php"},
+	}
+
+	var sb strings.Builder
+	if err := WritePlan(&sb, findings, "results.json", time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	plan := sb.String()
+
+	for _, want := range []string{
+		"#!/bin/sh\n",
+		"results.json",
+		"set -e\n",
+		"wordfence remediate -- \\\n  '/www/wp-includes/load.php'\n",
+		"wordfence remediate --quarantine -- \\\n  '/www/uploads/it'\\''s.php'\n",
+		"wordfence ignore add --signature 3 --reason 'Reviewed in triage' -- \\\n  '/www/plugins/sec/scan.php'\n",
+		"wordfence ignore add --signature 7 --reason 'Reviewed in triage' -- \\\n  '/www/plugins/sec/scan.php'\n",
+		"wordfence ignore add --reason 'Reviewed in triage' -- \\\n  '/www/plugins/sec/hash.php'\n",
+	} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("plan missing %q:\n%s", want, plan)
+		}
+	}
+	if strings.Contains(plan, "undecided") {
+		t.Errorf("plan includes an undecided file:\n%s", plan)
+	}
+	if strings.Index(plan, "--signature 3") > strings.Index(plan, "--signature 7") {
+		t.Errorf("expected signatures in order:\n%s", plan)
+	}
+}
@@ -0,0 +1,222 @@
+// Package triage provides an interactive review of findings
+package triage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// DefaultContextLines is how many lines around a match are shown
+const DefaultContextLines = 3
+
+// maxLineWidth truncates long lines, such as minified or obfuscated code,
+// when showing a match's context
+const maxLineWidth = 160
+
+const sessionHelp = `Commands:
+  r  remediate: restore the file to its original WordPress version
+  q  quarantine: move the file into the quarantine directory
+  i  ignore: suppress the file's matches as benign
+  c  clear the decision
+  n  next finding (or just Enter)    p  previous finding
+  g <n>  go to finding n             l  list decisions
+  w  write the plan and quit         x  quit without writing a plan
+  ?  show this help`
+
+// Session steps an analyst through findings, one file at a time
+type Session struct {
+	findings     []*Finding
+	contextLines int
+	current      int
+	out          io.Writer
+	screen       bool // Full screen: each finding clears the last
+}
+
+// NewSession creates a session over findings, showing contextLines lines
+// around each match
+func NewSession(findings []*Finding, contextLines int) *Session {
+	return &Session{findings: findings, contextLines: contextLines}
+}
+
+// Run reads commands from in and shows findings on out until the analyst
+// writes the plan or quits. It reports whether the plan should be written,
+// which only w does; the end of input quits without a plan, as x does, so
+// a closed terminal or a short script never writes a half-finished plan.
+func (s *Session) Run(in io.Reader, out io.Writer) (bool, error) {
+	s.out = out
+	if len(s.findings) == 0 {
+		s.printf("No findings to review.\n")
+		return false, nil
+	}
+
+	lines := bufio.NewScanner(in)
+	s.show()
+	for {
+		s.printf("[r]emediate [q]uarantine [i]gnore [n]ext [p]revious [w]rite [?] > ")
+		if !lines.Scan() {
+			if err := lines.Err(); err != nil {
+				return false, fmt.Errorf("reading command: %w", err)
+			}
+			s.printf("\nEnd of input; no plan written.\n")
+			return false, nil
+		}
+
+		command, arg, _ := strings.Cut(strings.TrimSpace(lines.Text()), " ")
+		if done, write := s.execute(command, arg); done {
+			return write, nil
+		}
+	}
+}
+
+// execute carries out one command. It reports whether the session is
+// done, and if so whether the plan should be written.
+func (s *Session) execute(command, arg string) (bool, bool) {
+	switch strings.ToLower(command) {
+	case "r":
+		s.decide(ActionRemediate)
+	case "q":
+		s.decide(ActionQuarantine)
+	case "i":
+		s.decide(ActionIgnore)
+	case "c":
+		s.findings[s.current].Action = ActionNone
+		s.show()
+	case "", "n":
+		s.move(s.current + 1)
+	case "p":
+		s.move(s.current - 1)
+	case "g":
+		n, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil || n < 1 || n > len(s.findings) {
+			s.printf("Findings are numbered 1 to %d\n", len(s.findings))
+			return false, false
+		}
+		s.move(n - 1)
+	case "l":
+		s.list()
+	case "w":
+		return true, true
+	case "x":
+		return true, false
+	case "?", "h", "help":
+		s.printf("%s\n", sessionHelp)
+	default:
+		s.printf("Unknown command %q; ? shows the commands\n", command)
+	}
+	return false, false
+}
+
+// decide records an action for the current finding and moves on
+func (s *Session) decide(action Action) {
+	s.findings[s.current].Action = action
+	if s.current == len(s.findings)-1 {
+		s.printf("Marked %s. That was the last finding; w writes the plan.\n", action)
+		return
+	}
+	s.move(s.current + 1)
+}
+
+// move shows finding i, staying within the list
+func (s *Session) move(i int) {
+	switch {
+	case i < 0:
+		s.printf("This is the first finding.\n")
+	case i >= len(s.findings):
+		s.printf("This is the last finding; w writes the plan.\n")
+	default:
+		s.current = i
+		s.show()
+	}
+}
+
+// show prints the current finding with the context of its matches
+func (s *Session) show() {
+	f := s.findings[s.current]
+	bold := color.New(color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+	yellow := color.New(color.FgYellow)
+	faint := color.New(color.Faint)
+
+	if s.screen {
+		s.printf("\x1b[H\x1b[2J")
+	} else {
+		s.printf("\n")
+	}
+	_, _ = bold.Fprintf(s.out, "[%d/%d] ", s.current+1, len(s.findings))
+	_, _ = red.Fprintf(s.out, "%s\n", f.Path)
+	if f.Owner != "" || f.Domain != "" {
+		s.printf("  Site: %s (%s)\n", f.Domain, f.Owner)
+	}
+	if f.Remediation != "" {
+		s.printf("  Remediation during the scan: %s\n", f.Remediation)
+	}
+
+	for _, m := range f.Matches {
+		name := m.Name
+		if name == "" {
+			name = fmt.Sprintf("Signature %d", m.SignatureID)
+		}
+		_, _ = yellow.Fprintf(s.out, "  %s", name)
+		if m.Category != "" {
+			s.printf(" [%s]", m.Category)
+		}
+		if m.Description != "" {
+			s.printf(" - %s", m.Description)
+		}
+		s.printf("\n")
+
+		context, err := MatchContext(f.Path, m.MatchedText, s.contextLines)
+		switch {
+		case err != nil:
+			_, _ = faint.Fprintf(s.out, "    (%v)\n", err)
+		case context == nil && m.MatchedText != "":
+			_, _ = faint.Fprintf(s.out, "    (matched text no longer in the file) %s\n", truncate(m.MatchedText))
+		}
+		for _, line := range context {
+			if line.Match {
+				_, _ = red.Fprintf(s.out, "  > %5d  %s\n", line.Number, truncate(line.Text))
+			} else {
+				_, _ = faint.Fprintf(s.out, "    %5d  %s\n", line.Number, truncate(line.Text))
+			}
+		}
+	}
+	if f.Truncated {
+		s.printf("  Scanned with --first-match-only; the file may have more matches\n")
+	}
+	if f.Action != ActionNone {
+		_, _ = bold.Fprintf(s.out, "  Decision: %s\n", f.Action)
+	}
+}
+
+// list prints the decision for every finding
+func (s *Session) list() {
+	counts := make(map[Action]int)
+	for i, f := range s.findings {
+		action := f.Action
+		if action == ActionNone {
+			action = "-"
+		}
+		counts[f.Action]++
+		s.printf("%4d  %-10s  %s\n", i+1, action, f.Path)
+	}
+	s.printf("%d to remediate, %d to quarantine, %d to ignore, %d undecided\n",
+		counts[ActionRemediate], counts[ActionQuarantine], counts[ActionIgnore], counts[ActionNone])
+}
+
+func (s *Session) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(s.out, format, args...)
+}
+
+// truncate shortens a line for display
+func truncate(line string) string {
+	runes := []rune(line)
+	if len(runes) <= maxLineWidth {
+		return line
+	}
+	return string(runes[:maxLineWidth]) + "…"
+}
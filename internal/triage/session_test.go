@@ -0,0 +1,54 @@
+package triage
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestSessionRun(t *testing.T) {
+	newFindings := func() []*Finding {
+		return []*Finding{{Path: "/www/a.php"}, {Path: "/www/b.php"}, {Path: "/www/c.php"}}
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		write   bool
+		actions []Action
+	}{
+		{"decide and write", "r\nq\ni\nw\n", true, []Action{ActionRemediate, ActionQuarantine, ActionIgnore}},
+		{"skip, go back and clear", "\nr\np\nc\nw\n", true, []Action{ActionNone, ActionNone, ActionNone}},
+		{"go to a finding", "g 3\nq\ng 9\nx\n", false, []Action{ActionNone, ActionNone, ActionQuarantine}},
+		{"end of input quits", "i\n", false, []Action{ActionIgnore, ActionNone, ActionNone}},
+	}
+	for _, tt := range tests {
+		findings := newFindings()
+		var out strings.Builder
+		write, err := NewSession(findings, DefaultContextLines).Run(strings.NewReader(tt.input), &out)
+		if err != nil || write != tt.write {
+			t.Errorf("%s: expected write=%v, got %v (%v)", tt.name, tt.write, write, err)
+		}
+		for i, f := range findings {
+			if f.Action != tt.actions[i] {
+				t.Errorf("%s: finding %d: expected %q, got %q", tt.name, i+1, tt.actions[i], f.Action)
+			}
+		}
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	keys := bufio.NewReader(strings.NewReader("\x1b[C\x1b[Dr Q\x1b[Z\x03"))
+	var commands []string
+	for {
+		command, err := readKey(keys)
+		if err != nil {
+			break
+		}
+		commands = append(commands, command)
+	}
+	expected := []string{"n", "p", "r", "n", "q", "", "x"}
+	if strings.Join(commands, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+}
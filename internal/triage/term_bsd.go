@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || netbsd
+
+// Package triage provides the terminal ioctls of macOS and the BSDs
+package triage
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
@@ -0,0 +1,11 @@
+//go:build linux
+
+// Package triage provides the terminal ioctls of Linux
+package triage
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd
+
+// Package triage provides line input on platforms without raw terminals
+package triage
+
+// makeRaw reports that single-key input is not supported, so triage reads
+// line commands instead
+func makeRaw(_ int) (func(), error) {
+	return nil, ErrNotTerminal
+}
@@ -0,0 +1,137 @@
+// Package triage provides the terminal prompts of an interactive review
+package triage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ErrNotTerminal is returned by RunTerminal when its input is not a
+// terminal
+var ErrNotTerminal = errors.New("not a terminal")
+
+const (
+	keyCtrlC  = 0x03
+	keyCtrlD  = 0x04
+	keyEscape = 0x1b
+	keyDelete = 0x7f
+)
+
+const terminalPrompt = "[r]emediate [q]uarantine [i]gnore [←/→] move [g]o to [l]ist [w]rite [x] quit [?] "
+
+// RunTerminal runs the session full screen on a terminal: each finding
+// replaces the last on screen and commands are single keys, with the
+// arrow keys moving between findings. It reports whether the plan should
+// be written, as Run does, and returns ErrNotTerminal when in is not a
+// terminal so callers can fall back to Run.
+func (s *Session) RunTerminal(in *os.File, out io.Writer) (bool, error) {
+	if len(s.findings) == 0 {
+		s.out = out
+		s.printf("No findings to review.\n")
+		return false, nil
+	}
+	restore, err := makeRaw(int(in.Fd())) // #nosec G115 -- file descriptors fit in an int
+	if err != nil {
+		return false, err
+	}
+	defer restore()
+
+	s.out = out
+	s.screen = true
+	defer func() { s.screen = false }()
+
+	keys := bufio.NewReader(in)
+	s.show()
+	for {
+		s.printf("\r\x1b[K%s", terminalPrompt)
+		command, err := readKey(keys)
+		if errors.Is(err, io.EOF) {
+			s.printf("\nEnd of input; no plan written.\n")
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("reading key: %w", err)
+		}
+		if command == "" {
+			continue
+		}
+		s.printf("\n")
+
+		var arg string
+		if command == "g" {
+			if arg, err = s.readNumber(keys); err != nil {
+				return false, fmt.Errorf("reading key: %w", err)
+			}
+		}
+		if done, write := s.execute(command, arg); done {
+			s.printf("\n")
+			return write, nil
+		}
+	}
+}
+
+// readKey reads one key press and returns the session command it stands
+// for, or "" for keys without one. Enter, space and the right and down
+// arrows move to the next finding; the left and up arrows to the previous
+// one. Ctrl-C and Ctrl-D quit without a plan.
+func readKey(keys *bufio.Reader) (string, error) {
+	r, _, err := keys.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch r {
+	case keyCtrlC, keyCtrlD:
+		return "x", nil
+	case '\r', '\n', ' ', 'j':
+		return "n", nil
+	case 'k':
+		return "p", nil
+	case keyEscape:
+		// Arrow keys arrive as ESC [ A..D; a lone escape does nothing
+		if keys.Buffered() < 2 {
+			return "", nil
+		}
+		if b, _ := keys.ReadByte(); b != '[' {
+			return "", nil
+		}
+		switch b, _ := keys.ReadByte(); b {
+		case 'B', 'C':
+			return "n", nil
+		case 'A', 'D':
+			return "p", nil
+		}
+		return "", nil
+	}
+	return strings.ToLower(string(r)), nil
+}
+
+// readNumber reads the finding number for g, echoing digits until Enter.
+// Escape cancels.
+func (s *Session) readNumber(keys *bufio.Reader) (string, error) {
+	s.printf("Go to finding (1-%d): ", len(s.findings))
+	var digits []rune
+	for {
+		r, _, err := keys.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case r == '\r' || r == '\n':
+			s.printf("\n")
+			return string(digits), nil
+		case r == keyEscape || r == keyCtrlC:
+			s.printf("\n")
+			return "", nil
+		case (r == keyDelete || r == '\b') && len(digits) > 0:
+			digits = digits[:len(digits)-1]
+			s.printf("\b \b")
+		case unicode.IsDigit(r):
+			digits = append(digits, r)
+			s.printf("%c", r)
+		}
+	}
+}
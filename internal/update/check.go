@@ -0,0 +1,63 @@
+// Package update provides the cached check behind the new version notice
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckInterval is how often the new version notice asks GitHub for the
+// latest release
+const CheckInterval = 24 * time.Hour
+
+// checkState is the outcome of the last check, saved between runs
+type checkState struct {
+	Checked time.Time `json:"checked"`
+	Latest  string    `json:"latest,omitempty"`
+	URL     string    `json:"url,omitempty"`
+}
+
+// LatestVersion returns the latest released version and its release page,
+// asking GitHub at most once per CheckInterval and otherwise answering
+// from the state saved at statePath. A failed check is also remembered,
+// so an unreachable GitHub isn't retried on every run.
+func LatestVersion(ctx context.Context, c *Client, statePath string, now time.Time) (string, string, error) {
+	var state checkState
+	if data, err := os.ReadFile(statePath); err == nil { // #nosec G304 -- state file in the cache directory
+		_ = json.Unmarshal(data, &state)
+	}
+	if now.Sub(state.Checked) < CheckInterval && now.After(state.Checked) {
+		return state.Latest, state.URL, nil
+	}
+
+	release, checkErr := c.Latest(ctx)
+	state.Checked = now
+	if checkErr == nil {
+		state.Latest, state.URL = release.Version(), release.URL
+	}
+	if err := saveCheckState(statePath, &state); err != nil {
+		return "", "", err
+	}
+	if checkErr != nil {
+		return "", "", checkErr
+	}
+	return state.Latest, state.URL, nil
+}
+
+func saveCheckState(path string, state *checkState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding update check: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("saving update check: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("saving update check: %w", err)
+	}
+	return nil
+}
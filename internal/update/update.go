@@ -0,0 +1,207 @@
+// Package update provides checking for and installing new releases of the
+// CLI from GitHub
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/version"
+)
+
+// BaseURL is the default GitHub API base URL
+const BaseURL = "https://api.github.com"
+
+// Repository is the GitHub repository releases are published to
+const Repository = "greysquirr3l/wordfence-go"
+
+// DownloadTimeout is the HTTP timeout for release assets
+const DownloadTimeout = 5 * time.Minute
+
+// Release assets listing the SHA-256 of every binary, and its signature
+const (
+	ChecksumsAsset = "SHA256SUMS"
+	SignatureAsset = "SHA256SUMS.sig"
+)
+
+// ErrNoAsset is returned when a release has no binary for this platform
+var ErrNoAsset = errors.New("no release binary for this platform")
+
+// Release is a published release of the CLI
+type Release struct {
+	Tag       string    `json:"tag_name"`
+	Name      string    `json:"name"`
+	URL       string    `json:"html_url"`
+	Published time.Time `json:"published_at"`
+	Assets    []Asset   `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Version returns the release's version, without the tag's leading v
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Asset returns the asset of that name, if the release has one
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Client is a client for the GitHub releases API
+type Client struct {
+	*api.Client
+	downloads *api.Client
+	repo      string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL sets the API base URL
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithRepository sets the repository releases are read from, as owner/name
+func WithRepository(repo string) Option {
+	return func(c *Client) {
+		c.repo = repo
+	}
+}
+
+// WithClientOptions applies options to the underlying HTTP clients
+func WithClientOptions(opts ...api.ClientOption) Option {
+	return func(c *Client) {
+		for _, opt := range opts {
+			opt(c.Client)
+			opt(c.downloads)
+		}
+	}
+}
+
+// NewClient creates a new releases client
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		Client: api.NewClient(BaseURL),
+		// Assets are fetched by their full download URLs
+		downloads: api.NewClient("", api.WithTimeout(DownloadTimeout)),
+		repo:      Repository,
+	}
+	userAgent := "wordfence-go/" + version.GetVersion()
+	c.UserAgent, c.downloads.UserAgent = userAgent, userAgent
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Latest fetches the latest release, ignoring drafts and prereleases
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	resp, err := c.Get(ctx, "/repos/"+c.repo+"/releases/latest", map[string]string{
+		"Accept": "application/vnd.github+json",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the latest release: %w", err)
+	}
+
+	var release Release
+	if err := json.Unmarshal(resp, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	if release.Tag == "" {
+		return nil, errors.New("latest release has no tag")
+	}
+	return &release, nil
+}
+
+// Download downloads a release asset
+func (c *Client) Download(ctx context.Context, asset Asset) ([]byte, error) {
+	data, err := c.downloads.Get(ctx, asset.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	return data, nil
+}
+
+// AssetName returns the name of the release binary for a platform, as
+// built by make build-all
+func AssetName(goos, goarch string) string {
+	name := "wordfence-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// PlatformAsset returns the release binary for the running platform
+func (r *Release) PlatformAsset() (Asset, error) {
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	asset, ok := r.Asset(name)
+	if !ok {
+		return Asset{}, fmt.Errorf("%s: %w (%s)", r.Tag, ErrNoAsset, name)
+	}
+	return asset, nil
+}
+
+// CompareVersions compares two dotted versions, such as 1.2.10 and v1.3.0,
+// returning -1, 0 or 1. A prerelease (1.3.0-rc1) is older than its release,
+// and build metadata (+abc) is ignored.
+func CompareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,203 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api"
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.9", "1.2.10", -1},
+		{"1.3", "1.2.10", 1},
+		{"1.3.0-rc1", "1.3.0", -1},
+		{"1.3.0-rc1", "1.3.0-rc2", -1},
+		{"1.3.0+abc", "1.3.0", 0},
+		{"0.1.7", "0.2.0", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLatestAndDownload(t *testing.T) {
+	binary := []byte("new binary")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/example/cli/releases/latest":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"tag_name": "v1.4.0",
+				"html_url": "https://example.com/releases/v1.4.0",
+				"assets": []map[string]interface{}{
+					{"name": "wordfence-linux-amd64", "browser_download_url": "http://" + r.Host + "/download/wordfence-linux-amd64"},
+				},
+			})
+		case "/download/wordfence-linux-amd64":
+			_, _ = w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(WithBaseURL(srv.URL), WithRepository("example/cli"),
+		WithClientOptions(api.WithRetries(0), api.WithLogger(logging.New(logging.LevelCritical))))
+	release, err := client.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if release.Version() != "1.4.0" {
+		t.Errorf("expected version 1.4.0, got %s", release.Version())
+	}
+
+	asset, ok := release.Asset(AssetName("linux", "amd64"))
+	if !ok {
+		t.Fatal("expected the linux/amd64 asset")
+	}
+	data, err := client.Download(context.Background(), asset)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if string(data) != string(binary) {
+		t.Errorf("unexpected asset content %q", data)
+	}
+
+	if _, ok := release.Asset(AssetName("windows", "amd64")); ok {
+		t.Error("expected no windows asset")
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("binary")
+	sum := sha256.Sum256(data)
+	sums := hex.EncodeToString(sum[:]) + "  wordfence-linux-amd64\n" +
+		hex.EncodeToString(make([]byte, sha256.Size)) + " *wordfence-linux-arm64\n"
+
+	checksums, err := ParseChecksums([]byte(sums))
+	if err != nil {
+		t.Fatalf("ParseChecksums failed: %v", err)
+	}
+	if err := VerifyChecksum(checksums, "wordfence-linux-amd64", data); err != nil {
+		t.Errorf("expected the checksum to match: %v", err)
+	}
+	if err := VerifyChecksum(checksums, "wordfence-linux-arm64", data); err == nil {
+		t.Error("expected a checksum mismatch")
+	}
+	if err := VerifyChecksum(checksums, "wordfence-darwin-arm64", data); err == nil {
+		t.Error("expected a missing checksum to fail")
+	}
+	if _, err := ParseChecksums([]byte("not a checksum\n")); err == nil {
+		t.Error("expected a malformed SHA256SUMS to fail")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(pub)
+	sums := []byte("abc  wordfence-linux-amd64\n")
+	sig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, sums)) + "\n")
+
+	if err := verifySignature(key, sums, sig); err != nil {
+		t.Errorf("expected the signature to verify: %v", err)
+	}
+	if err := verifySignature(key, []byte("tampered"), sig); err == nil {
+		t.Error("expected a tampered SHA256SUMS to fail")
+	}
+	if err := verifySignature(key, sums, []byte("garbage")); err == nil {
+		t.Error("expected a malformed signature to fail")
+	}
+	if err := verifySignature("", sums, sig); !errors.Is(err, ErrNoPublicKey) {
+		t.Errorf("expected ErrNoPublicKey, got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wordfence")
+	if err := os.WriteFile(path, []byte("old"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Replace(path, []byte("new")); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("expected the new binary, got %q", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o750 {
+		t.Errorf("expected mode 0750 to be kept, got %o", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestLatestVersionCached(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"tag_name": "v2.0.0", "html_url": "https://example.com/v2.0.0"}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(WithBaseURL(srv.URL),
+		WithClientOptions(api.WithRetries(0), api.WithLogger(logging.New(logging.LevelCritical))))
+	statePath := filepath.Join(t.TempDir(), "update-check.json")
+	now := time.Now()
+
+	for _, at := range []time.Time{now, now.Add(time.Hour)} {
+		latest, url, err := LatestVersion(context.Background(), client, statePath, at)
+		if err != nil {
+			t.Fatalf("LatestVersion failed: %v", err)
+		}
+		if latest != "2.0.0" || url != "https://example.com/v2.0.0" {
+			t.Errorf("unexpected latest %s at %s", latest, url)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected one request within the check interval, got %d", n)
+	}
+
+	if _, _, err := LatestVersion(context.Background(), client, statePath, now.Add(CheckInterval+time.Minute)); err != nil {
+		t.Fatalf("LatestVersion failed: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected a new request after the check interval, got %d", n)
+	}
+}
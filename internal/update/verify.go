@@ -0,0 +1,83 @@
+// Package update provides verification of release checksums and signatures
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PublicKey is the base64 ed25519 key that signs SHA256SUMS, set via
+// ldflags. Builds without it refuse to install releases.
+var PublicKey = ""
+
+// ErrNoPublicKey is returned when verifying a signature in a build without
+// a release key
+var ErrNoPublicKey = errors.New("no release signing key built in")
+
+// ParseChecksums parses a SHA256SUMS file, as written by sha256sum, into
+// the checksum of each file
+func ParseChecksums(data []byte) (map[string]string, error) {
+	checksums := make(map[string]string)
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("%s line %d: expected a checksum and a file name", ChecksumsAsset, n)
+		}
+		// sha256sum marks binary mode with a leading *
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		checksums[name] = strings.ToLower(sum)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ChecksumsAsset, err)
+	}
+	return checksums, nil
+}
+
+// VerifyChecksum checks data against its file's entry in SHA256SUMS
+func VerifyChecksum(checksums map[string]string, name string, data []byte) error {
+	want, ok := checksums[name]
+	if !ok {
+		return fmt.Errorf("%s has no checksum for %s", ChecksumsAsset, name)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	return nil
+}
+
+// VerifySignature checks the base64 ed25519 signature of SHA256SUMS
+// against the release key
+func VerifySignature(checksums, signature []byte) error {
+	return verifySignature(PublicKey, checksums, signature)
+}
+
+func verifySignature(publicKey string, message, signature []byte) error {
+	if publicKey == "" {
+		return ErrNoPublicKey
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("invalid release signing key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("%s is not a base64 ed25519 signature", SignatureAsset)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), message, sig) {
+		return fmt.Errorf("%s signature does not match the release key", ChecksumsAsset)
+	}
+	return nil
+}
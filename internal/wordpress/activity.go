@@ -0,0 +1,113 @@
+// Package wordpress provides plugin and theme activity and modification times
+package wordpress
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Activity is whether a site has a plugin or theme enabled
+type Activity string
+
+const (
+	// ActivityUnknown means the site's settings weren't read
+	ActivityUnknown Activity = ""
+	// ActivityActive means the plugin is activated or the theme is in use
+	ActivityActive Activity = "active"
+	// ActivityInactive means the extension is installed but not loaded
+	ActivityInactive Activity = "inactive"
+)
+
+// ActiveExtensions lists what a site has enabled, as stored in its options
+type ActiveExtensions struct {
+	Plugins    []string // Plugin files, e.g. akismet/akismet.php or hello.php
+	Template   string   // Parent theme, or the theme itself
+	Stylesheet string   // Theme in use
+}
+
+// ApplyActivity marks each plugin and theme active or inactive. A parent
+// theme is active when its child theme is.
+func (s *Site) ApplyActivity(active *ActiveExtensions) {
+	plugins := make(map[string]bool, len(active.Plugins))
+	for _, file := range active.Plugins {
+		plugins[activePluginSlug(file)] = true
+	}
+	for _, p := range s.Plugins {
+		p.Activity = activity(plugins[p.Slug])
+	}
+	for _, t := range s.Themes {
+		t.Activity = activity(t.Slug == active.Template || t.Slug == active.Stylesheet)
+	}
+}
+
+// AssumeAllActive marks every plugin and theme active
+func (s *Site) AssumeAllActive() {
+	for _, p := range s.Plugins {
+		p.Activity = ActivityActive
+	}
+	for _, t := range s.Themes {
+		t.Activity = ActivityActive
+	}
+}
+
+// LoadModified sets the modification time of every plugin and theme
+func (s *Site) LoadModified() error {
+	for _, p := range s.Plugins {
+		if err := p.LoadModified(); err != nil {
+			return err
+		}
+	}
+	for _, t := range s.Themes {
+		if err := t.LoadModified(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadModified sets Modified to the newest modification time of the
+// extension's files. Directories are skipped, since copying or unpacking
+// touches them without changing any code.
+func (e *Extension) LoadModified() error {
+	var newest time.Time
+	err := filepath.WalkDir(e.Path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reading modification times of %s: %w", e.Path, err)
+	}
+	e.Modified = newest
+	return nil
+}
+
+// activePluginSlug returns the slug of a plugin file from active_plugins:
+// its directory, or the file name for single-file plugins
+func activePluginSlug(file string) string {
+	if dir, _, ok := strings.Cut(file, "/"); ok {
+		return dir
+	}
+	return strings.TrimSuffix(file, ".php")
+}
+
+func activity(active bool) Activity {
+	if active {
+		return ActivityActive
+	}
+	return ActivityInactive
+}
@@ -0,0 +1,91 @@
+package wordpress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyActivity(t *testing.T) {
+	plugin := func(slug string) *Plugin {
+		return &Plugin{Extension: Extension{Slug: slug}}
+	}
+	theme := func(slug string) *Theme {
+		return &Theme{Extension: Extension{Slug: slug}}
+	}
+	site := &Site{
+		Plugins: []*Plugin{plugin("akismet"), plugin("hello"), plugin("unused")},
+		Themes:  []*Theme{theme("astra"), theme("astra-child"), theme("twentytwenty")},
+	}
+
+	site.ApplyActivity(&ActiveExtensions{
+		Plugins:    []string{"akismet/akismet.php", "hello.php"},
+		Template:   "astra",
+		Stylesheet: "astra-child",
+	})
+
+	want := map[string]Activity{
+		"akismet":      ActivityActive,
+		"hello":        ActivityActive,
+		"unused":       ActivityInactive,
+		"astra":        ActivityActive,
+		"astra-child":  ActivityActive,
+		"twentytwenty": ActivityInactive,
+	}
+	for _, p := range site.Plugins {
+		if p.Activity != want[p.Slug] {
+			t.Errorf("plugin %s: expected %q, got %q", p.Slug, want[p.Slug], p.Activity)
+		}
+	}
+	for _, th := range site.Themes {
+		if th.Activity != want[th.Slug] {
+			t.Errorf("theme %s: expected %q, got %q", th.Slug, want[th.Slug], th.Activity)
+		}
+	}
+
+	site.AssumeAllActive()
+	if site.Plugins[2].Activity != ActivityActive || site.Themes[2].Activity != ActivityActive {
+		t.Error("expected every extension active")
+	}
+}
+
+//nolint:gosec // test file using temp directories with standard permissions
+func TestLoadModified(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "includes"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newest := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for file, mtime := range map[string]time.Time{
+		"plugin.php":          old,
+		"includes/shell.php":  newest,
+		"includes/helper.php": old,
+	} {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, []byte("<?php"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ext := &Extension{Path: dir}
+	if err := ext.LoadModified(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ext.Modified.Equal(newest) {
+		t.Errorf("expected %v, got %v", newest, ext.Modified)
+	}
+
+	// Single-file plugins are their own newest file
+	single := &Extension{Path: filepath.Join(dir, "plugin.php")}
+	if err := single.LoadModified(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !single.Modified.Equal(old) {
+		t.Errorf("expected %v, got %v", old, single.Modified)
+	}
+}
@@ -0,0 +1,169 @@
+// Package wordpress provides verification of installed extensions against
+// their official release packages
+package wordpress
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DifferenceType describes how an installed file differs from the release
+type DifferenceType string
+
+const (
+	// DifferenceModified is a file whose content differs from the release
+	DifferenceModified DifferenceType = "modified"
+	// DifferenceExtra is a file that is not part of the release
+	DifferenceExtra DifferenceType = "extra"
+	// DifferenceMissing is a release file that is not installed
+	DifferenceMissing DifferenceType = "missing"
+)
+
+// FileDifference is an installed file that does not match the release
+type FileDifference struct {
+	Path     string         `json:"path"`
+	Type     DifferenceType `json:"type"`
+	Expected string         `json:"expected_sha256,omitempty"`
+	Actual   string         `json:"actual_sha256,omitempty"`
+}
+
+// ArchiveChecksums returns the SHA-256 of every file in a release zip, keyed
+// by slash-separated path relative to the package's top-level directory
+func ArchiveChecksums(data []byte) (map[string]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package: %w", err)
+	}
+
+	checksums := make(map[string]string, len(reader.File))
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		// Packages unpack into a single directory named after the slug
+		_, name, ok := strings.Cut(path.Clean(file.Name), "/")
+		if !ok || name == "" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from package: %w", file.Name, err)
+		}
+		sum, err := hashReader(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from package: %w", file.Name, err)
+		}
+		checksums[name] = sum
+	}
+	return checksums, nil
+}
+
+// ArchiveFile returns one file of a release zip, by slash-separated path
+// relative to the package's top-level directory
+func ArchiveFile(data []byte, name string) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package: %w", err)
+	}
+
+	name = path.Clean(name)
+	for _, file := range reader.File {
+		_, fileName, ok := strings.Cut(path.Clean(file.Name), "/")
+		if !ok || fileName != name || file.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from package: %w", file.Name, err)
+		}
+		defer func() { _ = rc.Close() }()
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from package: %w", file.Name, err)
+		}
+		return content, nil
+	}
+	return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+}
+
+// VerifyChecksums compares the files under dir with the release checksums and
+// returns every modified, extra and missing file, sorted by path
+func VerifyChecksums(dir string, expected map[string]string) ([]*FileDifference, error) {
+	var differences []*FileDifference
+	seen := make(map[string]bool, len(expected))
+
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		actual, err := hashFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		want, ok := expected[rel]
+		switch {
+		case !ok:
+			differences = append(differences, &FileDifference{Path: rel, Type: DifferenceExtra, Actual: actual})
+		case want != actual:
+			differences = append(differences, &FileDifference{Path: rel, Type: DifferenceModified, Expected: want, Actual: actual})
+		}
+		seen[rel] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify %s: %w", dir, err)
+	}
+
+	for name, want := range expected {
+		if !seen[name] {
+			differences = append(differences, &FileDifference{Path: name, Type: DifferenceMissing, Expected: want})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Path < differences[j].Path
+	})
+	return differences, nil
+}
+
+// hashFile returns the hex SHA-256 of a file
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath) // #nosec G304 -- walking the extension directory
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+	return hashReader(file)
+}
+
+// hashReader returns the hex SHA-256 of everything read from r
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
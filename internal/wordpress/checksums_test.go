@@ -0,0 +1,99 @@
+package wordpress
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildPackage zips files under a top-level slug directory
+func buildPackage(t *testing.T, slug string, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create(slug + "/"); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		w, err := zw.Create(slug + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+//nolint:gosec // test file using temp directories with standard permissions
+func TestVerifyChecksums(t *testing.T) {
+	release := map[string]string{
+		"shop.php":         "<?php // shop",
+		"inc/cart.php":     "<?php // cart",
+		"inc/checkout.php": "<?php // checkout",
+		"readme.txt":       "=== Shop ===",
+	}
+	checksums, err := ArchiveChecksums(buildPackage(t, "shop", release))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(checksums) != len(release) {
+		t.Fatalf("expected %d checksums, got %v", len(release), checksums)
+	}
+
+	dir := t.TempDir()
+	installed := map[string]string{
+		"shop.php":      "<?php // shop",
+		"inc/cart.php":  "<?php // cart @eval($_POST['x']);",
+		"inc/cache.php": "<?php // backdoor",
+		"readme.txt":    "=== Shop ===",
+	}
+	for name, content := range installed {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	differences, err := VerifyChecksums(dir, checksums)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expect := []struct {
+		path string
+		kind DifferenceType
+	}{
+		{"inc/cache.php", DifferenceExtra},
+		{"inc/cart.php", DifferenceModified},
+		{"inc/checkout.php", DifferenceMissing},
+	}
+	if len(differences) != len(expect) {
+		t.Fatalf("expected %d differences, got %d", len(expect), len(differences))
+	}
+	for i, want := range expect {
+		got := differences[i]
+		if got.Path != want.path || got.Type != want.kind {
+			t.Errorf("difference %d: expected %s %s, got %s %s", i, want.kind, want.path, got.Type, got.Path)
+		}
+	}
+	if differences[1].Expected == "" || differences[1].Actual == "" || differences[1].Expected == differences[1].Actual {
+		t.Errorf("expected differing checksums for modified file, got %+v", differences[1])
+	}
+}
+
+func TestArchiveChecksumsInvalid(t *testing.T) {
+	if _, err := ArchiveChecksums([]byte("not a zip")); err == nil {
+		t.Error("expected error for invalid package")
+	}
+}
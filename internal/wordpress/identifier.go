@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 // FileType represents the type of WordPress file
@@ -59,8 +60,10 @@ func (fi *FileIdentity) GetExtensionVersion() string {
 	}
 }
 
-// FileIdentifier identifies WordPress files
+// FileIdentifier identifies WordPress files. It is safe for concurrent
+// use.
 type FileIdentifier struct {
+	mu         sync.Mutex
 	knownSites map[string]*Site
 }
 
@@ -85,7 +88,9 @@ func (fi *FileIdentifier) Identify(path string) (*FileIdentity, error) {
 	}
 
 	// Try to find the WordPress site this file belongs to
+	fi.mu.Lock()
 	site, localPath, fileType, extension := fi.findSiteForPath(absPath)
+	fi.mu.Unlock()
 	if site == nil {
 		return &FileIdentity{Type: FileTypeUnknown}, nil
 	}
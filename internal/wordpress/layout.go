@@ -0,0 +1,234 @@
+// Package wordpress provides resolution of custom content directories and
+// Bedrock/Composer site layouts
+package wordpress
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WPCLIConfigFiles are the project configuration files WP-CLI reads, in
+// order of precedence
+var WPCLIConfigFiles = []string{"wp-cli.local.yml", "wp-cli.yml"}
+
+var (
+	// bedrockDefineRegex matches Config::define('NAME', 'value') in a
+	// Bedrock config/application.php
+	bedrockDefineRegex = regexp.MustCompile(`Config::define\s*\(\s*['"]([A-Za-z0-9_]+)['"]\s*,\s*('(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*")\s*\)`)
+
+	// dirnameRegex matches dirname(...) with an optional number of levels
+	dirnameRegex = regexp.MustCompile(`^dirname\s*\(\s*(.+?)\s*(?:,\s*(\d+)\s*)?\)$`)
+)
+
+// ResolveCorePath returns where WordPress core is for a site or project
+// directory. Like WP-CLI, it follows the path: setting of a wp-cli.yml in
+// the directory, so a Bedrock project root resolves to its web/wp.
+func ResolveCorePath(path string) string {
+	if isCoreDirectory(path) {
+		return path
+	}
+	for _, name := range WPCLIConfigFiles {
+		corePath, ok := wpCLIPath(filepath.Join(path, name))
+		if ok && isCoreDirectory(corePath) {
+			return corePath
+		}
+	}
+	return path
+}
+
+// wpCLIPath reads the top-level path: setting of a wp-cli.yml, relative to
+// the file's directory
+func wpCLIPath(configPath string) (string, bool) {
+	file, err := os.Open(configPath) // #nosec G304 -- wp-cli.yml of the scanned site
+	if err != nil {
+		return "", false
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "path:")
+		if !ok {
+			continue
+		}
+		if comment := strings.Index(value, " #"); comment >= 0 {
+			value = value[:comment]
+		}
+		value = strings.Trim(strings.TrimSpace(value), `'"`)
+		if value == "" {
+			return "", false
+		}
+		if !filepath.IsAbs(value) {
+			value = filepath.Join(filepath.Dir(configPath), value)
+		}
+		return filepath.Clean(value), true
+	}
+	return "", false
+}
+
+// contentPaths returns a site's content and plugins directories. Custom
+// WP_CONTENT_DIR and WP_PLUGIN_DIR settings in wp-config.php come first,
+// then Bedrock's CONTENT_DIR, then wp-content and the ../app and
+// ../content directories of Composer layouts.
+func contentPaths(corePath string) (string, string) {
+	var contentPath, pluginsPath string
+
+	if configPath, err := FindWPConfig(corePath); err == nil {
+		if cfg, err := ParseWPConfig(configPath); err == nil {
+			vars := map[string]string{"ABSPATH": corePath + string(filepath.Separator)}
+			if dir, ok := cfg.resolvePath("WP_CONTENT_DIR", vars); ok {
+				contentPath = dir
+				vars["WP_CONTENT_DIR"] = dir
+			} else if dir, ok := bedrockContentPath(configPath); ok {
+				contentPath = dir
+				vars["WP_CONTENT_DIR"] = dir
+			}
+			if dir, ok := cfg.resolvePath("WP_PLUGIN_DIR", vars); ok {
+				pluginsPath = dir
+			}
+		}
+	}
+
+	if contentPath == "" {
+		contentPath = filepath.Join(corePath, "wp-content")
+		if _, err := os.Stat(contentPath); os.IsNotExist(err) {
+			for _, alt := range []string{"../app", "../content"} {
+				altPath := filepath.Join(corePath, alt)
+				if _, err := os.Stat(altPath); err == nil {
+					contentPath = altPath
+					break
+				}
+			}
+		}
+	}
+	if pluginsPath == "" {
+		pluginsPath = filepath.Join(contentPath, "plugins")
+	}
+	return contentPath, pluginsPath
+}
+
+// bedrockContentPath returns the content directory of a Bedrock site,
+// whose web/wp-config.php loads ../config/application.php
+func bedrockContentPath(configPath string) (string, bool) {
+	webRoot := filepath.Dir(configPath)
+	data, err := os.ReadFile(filepath.Join(webRoot, "..", "config", "application.php")) // #nosec G304 -- Bedrock config of the scanned site
+	if err != nil {
+		return "", false
+	}
+	for _, m := range bedrockDefineRegex.FindAllStringSubmatch(string(data), -1) {
+		if m[1] == "CONTENT_DIR" {
+			return filepath.Join(webRoot, unquotePHP(m[2])), true
+		}
+	}
+	return "", false
+}
+
+// resolvePath evaluates a constant defined as a path expression, such as
+// __DIR__ . '/content' or dirname(__FILE__, 2) . '/app'. Only string
+// literals, __DIR__, __FILE__, dirname() and the constants in vars are
+// understood; other expressions can't be resolved without running PHP.
+func (c *WPConfig) resolvePath(name string, vars map[string]string) (string, bool) {
+	expr, ok := c.Expressions[name]
+	if !ok {
+		return "", false
+	}
+	path, ok := evalPathExpr(expr, c.Path, vars)
+	if !ok || path == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(c.Path), path)
+	}
+	return filepath.Clean(path), true
+}
+
+// evalPathExpr evaluates a concatenation of path terms
+func evalPathExpr(expr, file string, vars map[string]string) (string, bool) {
+	var sb strings.Builder
+	for _, term := range splitConcat(expr) {
+		value, ok := evalPathTerm(term, file, vars)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(value)
+	}
+	return sb.String(), true
+}
+
+// evalPathTerm evaluates one term of a path expression
+func evalPathTerm(term, file string, vars map[string]string) (string, bool) {
+	term = strings.TrimSpace(term)
+	switch {
+	case term == "":
+		return "", false
+	case term[0] == '\'' || term[0] == '"':
+		return unquotePHP(term), true
+	case term == "__FILE__":
+		return file, true
+	case term == "__DIR__":
+		return filepath.Dir(file), true
+	}
+	if m := dirnameRegex.FindStringSubmatch(term); m != nil {
+		path, ok := evalPathExpr(m[1], file, vars)
+		if !ok {
+			return "", false
+		}
+		levels := 1
+		if m[2] != "" {
+			n, err := strconv.Atoi(m[2])
+			if err != nil {
+				return "", false
+			}
+			levels = n
+		}
+		for i := 0; i < levels; i++ {
+			path = filepath.Dir(path)
+		}
+		return path, true
+	}
+	value, ok := vars[term]
+	return value, ok
+}
+
+// splitConcat splits a PHP expression at the concatenation operators
+// outside strings and parentheses
+func splitConcat(expr string) []string {
+	var terms []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '.' && depth == 0:
+			terms = append(terms, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(terms, expr[start:])
+}
+
+// entryIsDir reports whether a directory entry is a directory, following
+// symbolic links, so plugins and themes linked in from elsewhere are found
+func entryIsDir(dir string, entry os.DirEntry) bool {
+	if entry.Type()&os.ModeSymlink == 0 {
+		return entry.IsDir()
+	}
+	info, err := os.Stat(filepath.Join(dir, entry.Name()))
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,127 @@
+package wordpress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//nolint:gosec // test file using temp directories with standard permissions
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// coreFiles are the files that make a directory look like WordPress core
+func coreFiles(dir string) map[string]string {
+	return map[string]string{
+		dir + "/wp-blog-header.php":      "<?php",
+		dir + "/wp-load.php":             "<?php",
+		dir + "/wp-settings.php":         "<?php",
+		dir + "/wp-admin/index.php":      "<?php",
+		dir + "/wp-includes/version.php": "<?php $wp_version = '6.5';",
+	}
+}
+
+func TestDetectBedrock(t *testing.T) {
+	root := t.TempDir()
+	files := coreFiles("web/wp")
+	files["wp-cli.yml"] = "path: web/wp # core\nserver:\n  docroot: web\n"
+	files["config/application.php"] = "<?php\nConfig::define('CONTENT_DIR', '/app');\nConfig::define('WP_CONTENT_DIR', $webroot_dir . Config::get('CONTENT_DIR'));\n"
+	files["web/wp-config.php"] = "<?php\nrequire_once dirname(__DIR__) . '/config/application.php';\n"
+	files["web/app/plugins/akismet/akismet.php"] = "<?php\n/*\nPlugin Name: Akismet\nVersion: 5.3\n*/"
+	writeTestFiles(t, root, files)
+
+	site, err := Detect(root)
+	if err != nil {
+		t.Fatalf("failed to detect Bedrock site: %v", err)
+	}
+	if site.CorePath != filepath.Join(root, "web", "wp") || site.Version != "6.5" {
+		t.Errorf("expected core in web/wp, got %s (%s)", site.CorePath, site.Version)
+	}
+	if site.ContentPath != filepath.Join(root, "web", "app") {
+		t.Errorf("expected content in web/app, got %s", site.ContentPath)
+	}
+	if len(site.Plugins) != 1 || site.Plugins[0].Slug != "akismet" {
+		t.Errorf("expected the plugin in web/app/plugins, got %v", site.Plugins)
+	}
+}
+
+func TestDetectCustomContentDir(t *testing.T) {
+	root := t.TempDir()
+	files := coreFiles(".")
+	files["wp-config.php"] = "<?php\n" +
+		"define( 'WP_CONTENT_DIR', dirname( __FILE__ ) . '/assets' );\n" +
+		"define( 'WP_PLUGIN_DIR', WP_CONTENT_DIR . '/extensions' );\n"
+	files["assets/extensions/hello.php"] = "<?php\n/*\nPlugin Name: Hello Dolly\nVersion: 1.7.2\n*/"
+	writeTestFiles(t, root, files)
+
+	site, err := Detect(root)
+	if err != nil {
+		t.Fatalf("failed to detect site: %v", err)
+	}
+	if site.ContentPath != filepath.Join(root, "assets") || site.PluginsPath != filepath.Join(root, "assets", "extensions") {
+		t.Errorf("unexpected content paths %s and %s", site.ContentPath, site.PluginsPath)
+	}
+	if len(site.Plugins) != 1 || site.Plugins[0].Slug != "hello" {
+		t.Errorf("expected the plugin in the custom plugin directory, got %v", site.Plugins)
+	}
+}
+
+func TestLoadSymlinkedExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"shared/akismet/akismet.php": "<?php\n/*\nPlugin Name: Akismet\nVersion: 5.3\n*/",
+		"shared/astra/style.css":     "/*\nTheme Name: Astra\nVersion: 4.6\n*/",
+		"content/plugins/.keep":      "",
+		"content/themes/.keep":       "",
+	})
+	for _, link := range []struct{ target, name string }{
+		{"shared/akismet", "content/plugins/akismet"},
+		{"shared/astra", "content/themes/astra"},
+	} {
+		if err := os.Symlink(filepath.Join(root, link.target), filepath.Join(root, link.name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	plugins, err := NewPluginLoader(filepath.Join(root, "content", "plugins")).LoadAll()
+	if err != nil || len(plugins) != 1 || plugins[0].Version != "5.3" {
+		t.Errorf("expected the symlinked plugin, got %v (%v)", plugins, err)
+	}
+	themes, err := NewThemeLoader(filepath.Join(root, "content", "themes")).LoadAll()
+	if err != nil || len(themes) != 1 || themes[0].Version != "4.6" {
+		t.Errorf("expected the symlinked theme, got %v (%v)", themes, err)
+	}
+}
+
+func TestEvalPathExpr(t *testing.T) {
+	file := "/srv/site/wp-config.php"
+	vars := map[string]string{"ABSPATH": "/srv/site/"}
+	tests := map[string]string{
+		`'/var/content'`:                  "/var/content",
+		`__DIR__ . '/content'`:            "/srv/site/content",
+		`dirname(__FILE__) . "/content"`:  "/srv/site/content",
+		`dirname( __FILE__, 2 ) . '/app'`: "/srv/app",
+		`dirname(dirname(__FILE__))`:      "/srv",
+		`ABSPATH . 'wp-content'`:          "/srv/site/wp-content",
+	}
+	for expr, want := range tests {
+		if got, ok := evalPathExpr(expr, file, vars); !ok || got != want {
+			t.Errorf("evalPathExpr(%q) = %q, %v, want %q", expr, got, ok, want)
+		}
+	}
+	for _, expr := range []string{`$_SERVER['DOCUMENT_ROOT'] . '/app'`, `getenv('CONTENT')`, `UNKNOWN . '/x'`} {
+		if got, ok := evalPathExpr(expr, file, vars); ok {
+			t.Errorf("evalPathExpr(%q) = %q, expected it to be unresolvable", expr, got)
+		}
+	}
+}
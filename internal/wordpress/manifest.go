@@ -0,0 +1,312 @@
+// Package wordpress provides remediation manifests and their rollback
+package wordpress
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrManifestNotFound is returned when no manifest matches a run ID
+	ErrManifestNotFound = errors.New("remediation run not found")
+
+	// ErrRolledBack is returned when rolling back a run a second time
+	ErrRolledBack = errors.New("remediation run already rolled back")
+)
+
+// ManifestEntry is a file rewritten by a remediation run
+type ManifestEntry struct {
+	Path         string      `json:"path"`
+	OriginalHash string      `json:"original_hash"`
+	BackupPath   string      `json:"backup_path,omitempty"`
+	NewHash      string      `json:"new_hash,omitempty"`
+	Quarantined  bool        `json:"quarantined,omitempty"`
+	Mode         os.FileMode `json:"mode,omitempty"`
+	Time         time.Time   `json:"time"`
+}
+
+// RemediationManifest records the files changed by one remediation run, so
+// the run can be rolled back
+type RemediationManifest struct {
+	ID         string           `json:"id"`
+	Started    time.Time        `json:"started"`
+	Finished   time.Time        `json:"finished"`
+	Paths      []string         `json:"paths"`
+	Entries    []*ManifestEntry `json:"entries"`
+	RolledBack *time.Time       `json:"rolled_back,omitempty"`
+}
+
+// NewRemediationManifest starts a manifest for a run over paths
+func NewRemediationManifest(paths []string, started time.Time) *RemediationManifest {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+
+	absPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		absPaths = append(absPaths, path)
+	}
+
+	return &RemediationManifest{
+		ID:      started.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		Started: started,
+		Paths:   absPaths,
+	}
+}
+
+// AddResult records a file the run rewrote or quarantined. Failures and
+// dry runs changed nothing and are skipped.
+func (m *RemediationManifest) AddResult(result *RemediationResult) {
+	if result.Error != nil || result.RemediatedAt.IsZero() {
+		return
+	}
+	path := result.Path
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	m.Entries = append(m.Entries, &ManifestEntry{
+		Path:         path,
+		OriginalHash: result.OriginalHash,
+		BackupPath:   result.BackupPath,
+		NewHash:      result.NewHash,
+		Quarantined:  result.Quarantined,
+		Mode:         result.Mode,
+		Time:         result.RemediatedAt,
+	})
+}
+
+// Finish marks the manifest complete
+func (m *RemediationManifest) Finish(finished time.Time) {
+	m.Finished = finished
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+}
+
+// stagedRestore is a backup copied next to the file it restores, ready to
+// be renamed over it
+type stagedRestore struct {
+	entry   *ManifestEntry
+	tmpPath string
+	current []byte
+	mode    os.FileMode
+}
+
+// Rollback restores every file of the run from its backup. All backups are
+// checked and staged next to their files first, so a missing backup or a
+// file changed since the run aborts the rollback before anything is
+// touched; force restores changed files anyway. The staged copies are then
+// renamed into place, and if one rename fails the files already restored
+// are put back to their remediated content. Quarantined files are moved
+// back to where they were.
+func (m *RemediationManifest) Rollback(force bool) error {
+	if m.RolledBack != nil && !force {
+		return fmt.Errorf("%s: %w", m.ID, ErrRolledBack)
+	}
+
+	staged := make([]*stagedRestore, 0, len(m.Entries))
+	cleanup := func() {
+		for _, s := range staged {
+			_ = os.Remove(s.tmpPath)
+		}
+	}
+	for _, entry := range m.Entries {
+		s, err := stageRestore(entry, force)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		staged = append(staged, s)
+	}
+
+	for i, s := range staged {
+		if err := os.Rename(s.tmpPath, s.entry.Path); err != nil {
+			cleanup()
+			for _, done := range staged[:i] {
+				if done.entry.Quarantined {
+					_ = os.Remove(done.entry.Path)
+				} else {
+					_ = replaceFile(done.entry.Path, done.current, done.mode)
+				}
+			}
+			return fmt.Errorf("restoring %s: %w", s.entry.Path, err)
+		}
+		syncDir(filepath.Dir(s.entry.Path))
+	}
+
+	now := time.Now()
+	m.RolledBack = &now
+	return nil
+}
+
+// stageRestore checks an entry's file and backup and copies the backup to
+// a temporary file beside the file
+func stageRestore(entry *ManifestEntry, force bool) (*stagedRestore, error) {
+	if entry.BackupPath == "" {
+		return nil, fmt.Errorf("%s was not backed up", entry.Path)
+	}
+	if entry.Quarantined {
+		return stageUnquarantine(entry, force)
+	}
+	info, err := os.Stat(entry.Path)
+	if err != nil {
+		return nil, fmt.Errorf("checking %s: %w", entry.Path, err)
+	}
+	current, err := os.ReadFile(entry.Path) // #nosec G304 -- path recorded by the remediation run
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", entry.Path, err)
+	}
+	if sha256Hex(current) != entry.NewHash && !force {
+		return nil, fmt.Errorf("%s has changed since it was remediated (use --force to restore it anyway)", entry.Path)
+	}
+	original, err := os.ReadFile(entry.BackupPath) // #nosec G304 -- backup recorded by the remediation run
+	if err != nil {
+		return nil, fmt.Errorf("reading backup of %s: %w", entry.Path, err)
+	}
+	if sha256Hex(original) != entry.OriginalHash {
+		return nil, fmt.Errorf("backup %s does not match the original %s", entry.BackupPath, entry.Path)
+	}
+
+	tmpPath, err := stageFile(entry.Path, original, info)
+	if err != nil {
+		return nil, fmt.Errorf("staging %s: %w", entry.Path, err)
+	}
+	return &stagedRestore{entry: entry, tmpPath: tmpPath, current: current, mode: info.Mode().Perm()}, nil
+}
+
+// stageUnquarantine checks a quarantined file and copies it back beside
+// where it was
+func stageUnquarantine(entry *ManifestEntry, force bool) (*stagedRestore, error) {
+	if _, err := os.Lstat(entry.Path); err == nil && !force {
+		return nil, fmt.Errorf("%s has been recreated since it was quarantined (use --force to replace it anyway)", entry.Path)
+	}
+	original, err := os.ReadFile(entry.BackupPath) // #nosec G304 -- quarantined copy recorded by the remediation run
+	if err != nil {
+		return nil, fmt.Errorf("reading quarantined %s: %w", entry.Path, err)
+	}
+	if sha256Hex(original) != entry.OriginalHash {
+		return nil, fmt.Errorf("quarantined %s does not match the original %s", entry.BackupPath, entry.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0o750); err != nil {
+		return nil, fmt.Errorf("staging %s: %w", entry.Path, err)
+	}
+	tmpPath, err := writeTemp(entry.Path, original, entry.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("staging %s: %w", entry.Path, err)
+	}
+	return &stagedRestore{entry: entry, tmpPath: tmpPath}, nil
+}
+
+// ManifestStore is a directory of remediation manifests, one JSON file per
+// run
+type ManifestStore struct {
+	dir string
+}
+
+// OpenManifests opens the manifest store in dir. The directory is created
+// when the first manifest is saved.
+func OpenManifests(dir string) *ManifestStore {
+	return &ManifestStore{dir: dir}
+}
+
+// Dir returns the store's directory
+func (s *ManifestStore) Dir() string {
+	return s.dir
+}
+
+// Save writes a manifest to the store
+func (s *ManifestStore) Save(m *RemediationManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding remediation manifest: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("creating manifest directory: %w", err)
+	}
+	if err := replaceFile(filepath.Join(s.dir, m.ID+".json"), append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("saving remediation manifest: %w", err)
+	}
+	return nil
+}
+
+// List returns every recorded run, oldest first
+func (s *ManifestStore) List() ([]*RemediationManifest, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading remediation manifests: %w", err)
+	}
+
+	var manifests []*RemediationManifest
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		m, err := s.read(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Started.Before(manifests[j].Started)
+	})
+	return manifests, nil
+}
+
+// Load returns the run with the given ID or unique ID prefix, or the most
+// recent run for "latest"
+func (s *ManifestStore) Load(id string) (*RemediationManifest, error) {
+	manifests, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "latest" {
+		if len(manifests) > 0 {
+			return manifests[len(manifests)-1], nil
+		}
+		return nil, fmt.Errorf("%s: %w", id, ErrManifestNotFound)
+	}
+	var found *RemediationManifest
+	for _, m := range manifests {
+		if m.ID == id {
+			return m, nil
+		}
+		if strings.HasPrefix(m.ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("run ID %s is ambiguous", id)
+			}
+			found = m
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s: %w", id, ErrManifestNotFound)
+	}
+	return found, nil
+}
+
+// read decodes a manifest file
+func (s *ManifestStore) read(path string) (*RemediationManifest, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- file in the manifest directory
+	if err != nil {
+		return nil, fmt.Errorf("reading remediation manifest: %w", err)
+	}
+	var m RemediationManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing remediation manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
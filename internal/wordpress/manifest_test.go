@@ -0,0 +1,151 @@
+package wordpress
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+type staticSource []byte
+
+func (s staticSource) GetCorrectContent(_ context.Context, _ *FileIdentity) ([]byte, error) {
+	return s, nil
+}
+
+// remediateForManifest remediates infected files in a mock site and
+// returns their paths and the saved run
+func remediateForManifest(t *testing.T, store *ManifestStore) ([]string, *RemediationManifest) {
+	t.Helper()
+	dir := createMockWordPressSite(t)
+	var paths []string
+	for _, name := range []string{"load.php", "post.php"} {
+		path := filepath.Join(dir, "wp-includes", name)
+		if err := os.WriteFile(path, []byte("<?php // infected "+name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	remediator := NewRemediator(staticSource("<?php // clean"), &RemediatorConfig{CreateBackup: true, BackupDir: t.TempDir()})
+	remediator.SetLogger(logging.New(logging.LevelCritical))
+	manifest := NewRemediationManifest([]string{dir}, time.Now())
+	for _, path := range paths {
+		manifest.AddResult(remediator.RemediateFile(context.Background(), path))
+	}
+	manifest.Finish(time.Now())
+	if len(manifest.Entries) != len(paths) {
+		t.Fatalf("expected %d manifest entries, got %d", len(paths), len(manifest.Entries))
+	}
+	if err := store.Save(manifest); err != nil {
+		t.Fatal(err)
+	}
+	return paths, manifest
+}
+
+func TestManifestRollback(t *testing.T) {
+	store := OpenManifests(t.TempDir())
+	paths, saved := remediateForManifest(t, store)
+
+	manifest, err := store.Load("latest")
+	if err != nil || manifest.ID != saved.ID {
+		t.Fatalf("expected run %s, got %+v (%v)", saved.ID, manifest, err)
+	}
+	if err := manifest.Rollback(false); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	for _, path := range paths {
+		content, _ := os.ReadFile(path)
+		if want := "<?php // infected " + filepath.Base(path); string(content) != want {
+			t.Errorf("%s: expected %q restored, got %q", path, want, content)
+		}
+	}
+	if err := manifest.Rollback(false); !errors.Is(err, ErrRolledBack) {
+		t.Errorf("expected ErrRolledBack rolling back twice, got %v", err)
+	}
+}
+
+func TestManifestRollbackRefusesChangedFiles(t *testing.T) {
+	store := OpenManifests(t.TempDir())
+	paths, manifest := remediateForManifest(t, store)
+
+	if err := os.WriteFile(paths[1], []byte("<?php // edited"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.Rollback(false); err == nil {
+		t.Fatal("expected rollback of a changed file to fail")
+	}
+	if content, _ := os.ReadFile(paths[0]); string(content) != "<?php // clean" {
+		t.Errorf("failed rollback touched %s: %q", paths[0], content)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(paths[0]))
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".php" {
+			t.Errorf("staged file %s left behind", entry.Name())
+		}
+	}
+
+	if err := manifest.Rollback(true); err != nil {
+		t.Fatalf("forced rollback failed: %v", err)
+	}
+	if content, _ := os.ReadFile(paths[1]); string(content) != "<?php // infected post.php" {
+		t.Errorf("forced rollback left %q", content)
+	}
+}
+
+func TestManifestStoreLoad(t *testing.T) {
+	store := OpenManifests(t.TempDir())
+	if _, err := store.Load("latest"); !errors.Is(err, ErrManifestNotFound) {
+		t.Errorf("expected ErrManifestNotFound from an empty store, got %v", err)
+	}
+	manifest := NewRemediationManifest(nil, time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC))
+	if err := store.Save(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Load("20250301"); err != nil || got.ID != manifest.ID {
+		t.Errorf("expected %s by prefix, got %v (%v)", manifest.ID, got, err)
+	}
+}
+
+func TestManifestRollbackQuarantined(t *testing.T) {
+	dir := createMockWordPressSite(t)
+	path := filepath.Join(dir, "wp-content", "uploads", "shell.php")
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("<?php eval($_POST['x']);"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	quarantineDir := t.TempDir()
+	remediator := NewRemediator(staticSource("<?php // clean"), &RemediatorConfig{QuarantineDir: quarantineDir})
+	remediator.SetLogger(logging.New(logging.LevelCritical))
+	result := remediator.RemediateFile(context.Background(), path)
+	if result.Error != nil || !result.Quarantined {
+		t.Fatalf("expected %s quarantined, got %+v", path, result)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s removed, got %v", path, err)
+	}
+	rel, err := filepath.Rel(quarantineDir, result.BackupPath)
+	if err != nil || filepath.IsAbs(rel) || rel[0] == '.' {
+		t.Errorf("expected quarantined copy under %s, got %s", quarantineDir, result.BackupPath)
+	}
+
+	manifest := NewRemediationManifest([]string{dir}, time.Now())
+	manifest.AddResult(result)
+	if err := manifest.Rollback(false); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Fatalf("expected %s restored with mode 0640, got %v (%v)", path, info, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "<?php eval($_POST['x']);" {
+		t.Errorf("unexpected restored content %q", content)
+	}
+}
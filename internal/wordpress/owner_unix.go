@@ -0,0 +1,27 @@
+//go:build unix
+
+// Package wordpress provides keeping the owner of remediated files on Unix
+package wordpress
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// copyOwner gives path the owner and group in info, when they differ from
+// its own
+func copyOwner(path string, info fs.FileInfo) error {
+	want, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if have, ok := current.Sys().(*syscall.Stat_t); ok && have.Uid == want.Uid && have.Gid == want.Gid {
+		return nil
+	}
+	return os.Chown(path, int(want.Uid), int(want.Gid))
+}
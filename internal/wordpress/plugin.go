@@ -68,7 +68,7 @@ func (l *PluginLoader) LoadAll() ([]*Plugin, error) {
 func (l *PluginLoader) processEntry(entry os.DirEntry) *Plugin {
 	entryPath := filepath.Join(l.directory, entry.Name())
 
-	if entry.IsDir() {
+	if entryIsDir(l.directory, entry) {
 		// Look for PHP files in the plugin directory
 		return l.loadFromDirectory(entry.Name(), entryPath)
 	}
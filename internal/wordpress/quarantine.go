@@ -0,0 +1,36 @@
+// Package wordpress provides quarantining of files that can't be remediated
+package wordpress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// quarantineFile moves a file with the given content into dir, under its own absolute path so
+// files with the same name don't collide, and returns where it went. The
+// quarantined copy is only readable by its owner, so it can't be served or
+// included.
+func quarantineFile(path string, content []byte, dir string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", path, err)
+	}
+
+	rel := strings.TrimLeft(strings.TrimPrefix(abs, filepath.VolumeName(abs)), `/\`)
+	dest := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return "", fmt.Errorf("creating quarantine directory: %w", err)
+	}
+	dest, err = writeBackup(dest, content)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(abs); err != nil {
+		_ = os.Remove(dest)
+		return "", fmt.Errorf("removing %s: %w", path, err)
+	}
+	syncDir(filepath.Dir(abs))
+	return dest, nil
+}
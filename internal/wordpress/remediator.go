@@ -5,14 +5,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/greysquirr3l/wordfence-go/internal/logging"
 )
 
-// RemediationSource provides correct file content for remediation
+// DefaultRemediationWorkers is the default number of files remediated at
+// once by RemediateDirectory
+const DefaultRemediationWorkers = 4
+
+// RemediationSource provides correct file content for remediation. Sources
+// are called from several goroutines at once.
 type RemediationSource interface {
 	// GetCorrectContent retrieves the correct content for a file
 	GetCorrectContent(ctx context.Context, identity *FileIdentity) ([]byte, error)
@@ -20,13 +27,21 @@ type RemediationSource interface {
 
 // RemediationResult represents the result of remediating a file
 type RemediationResult struct {
-	Path       string
-	Identity   *FileIdentity
-	Known      bool
-	Remediated bool
-	BackupPath string
-	Error      error
-	TargetPath string
+	Path        string
+	Identity    *FileIdentity
+	Known       bool
+	Remediated  bool
+	Quarantined bool // Unknown file moved to BackupPath
+	BackupPath  string
+	Error       error
+	TargetPath  string
+
+	// Set when the file is rewritten or quarantined, for the run's
+	// rollback manifest
+	OriginalHash string
+	NewHash      string
+	Mode         os.FileMode
+	RemediatedAt time.Time
 }
 
 // Success returns true if remediation was successful
@@ -40,6 +55,11 @@ type RemediatorConfig struct {
 	BackupDir      string
 	DryRun         bool
 	FollowSymlinks bool
+	Workers        int // Files remediated at once; DefaultRemediationWorkers if 0
+
+	// QuarantineDir, if set, receives files that aren't known WordPress
+	// files and so can't be restored
+	QuarantineDir string
 }
 
 // Remediator remediates infected WordPress files
@@ -86,6 +106,10 @@ func (r *Remediator) RemediateFile(ctx context.Context, path string) *Remediatio
 	result.Identity = identity
 
 	if !identity.IsKnown() {
+		if r.config.QuarantineDir != "" {
+			r.quarantine(result)
+			return result
+		}
 		r.logger.Warning("Unable to identify %s as a WordPress file", path)
 		return result
 	}
@@ -117,9 +141,17 @@ func (r *Remediator) RemediateFile(ctx context.Context, path string) *Remediatio
 		return result
 	}
 
+	original, err := os.ReadFile(path) // #nosec G304 -- path is from internal directory walk
+	if err != nil {
+		result.Error = fmt.Errorf("failed to read file: %w", err)
+		r.logger.Error("Failed to read %s: %v", path, err)
+		return result
+	}
+	result.OriginalHash = sha256Hex(original)
+
 	// Create backup if configured
 	if r.config.CreateBackup {
-		backupPath, err := r.createBackup(path)
+		backupPath, err := r.createBackup(path, original)
 		if err != nil {
 			result.Error = fmt.Errorf("failed to create backup: %w", err)
 			r.logger.Error("Failed to create backup for %s: %v", path, err)
@@ -130,25 +162,63 @@ func (r *Remediator) RemediateFile(ctx context.Context, path string) *Remediatio
 	}
 
 	// Write the correct content
-	if err := os.WriteFile(path, correctContent, 0600); err != nil { //nolint:gosec // intentional file write for remediation
+	if err := replaceFile(path, correctContent, 0600); err != nil {
 		result.Error = fmt.Errorf("failed to write file: %w", err)
 		r.logger.Error("Failed to write remediated content to %s: %v", path, err)
 		return result
 	}
 
 	result.Remediated = true
+	result.NewHash = sha256Hex(correctContent)
+	result.RemediatedAt = time.Now()
 	r.logger.Info("Successfully remediated %s", path)
 	return result
 }
 
-// createBackup creates a backup of the file
-func (r *Remediator) createBackup(path string) (string, error) {
-	// Read the original content
-	content, err := os.ReadFile(path) // #nosec G304 -- path is from internal directory walk
+// QuarantineFile moves a file into the quarantine directory without trying
+// to restore it
+func (r *Remediator) QuarantineFile(_ context.Context, path string) *RemediationResult {
+	result := &RemediationResult{Path: path, TargetPath: path}
+	if r.config.QuarantineDir == "" {
+		result.Error = fmt.Errorf("no quarantine directory configured")
+		return result
+	}
+	r.quarantine(result)
+	return result
+}
+
+// quarantine moves a file into the quarantine directory
+func (r *Remediator) quarantine(result *RemediationResult) {
+	path := result.Path
+	if r.config.DryRun {
+		r.logger.Info("[DRY RUN] Would quarantine %s", path)
+		result.Quarantined = true
+		return
+	}
+
+	info, err := os.Stat(path)
+	var content []byte
+	if err == nil {
+		content, err = os.ReadFile(path) // #nosec G304 -- path is from internal directory walk
+	}
+	if err == nil {
+		result.OriginalHash = sha256Hex(content)
+		result.Mode = info.Mode().Perm()
+		result.BackupPath, err = quarantineFile(path, content, r.config.QuarantineDir)
+	}
 	if err != nil {
-		return "", fmt.Errorf("reading original file: %w", err)
+		result.Error = fmt.Errorf("failed to quarantine file: %w", err)
+		r.logger.Error("Failed to quarantine %s: %v", path, err)
+		return
 	}
 
+	result.Quarantined = true
+	result.RemediatedAt = time.Now()
+	r.logger.Info("Quarantined %s to %s", path, result.BackupPath)
+}
+
+// createBackup creates a backup of the file's original content
+func (r *Remediator) createBackup(path string, content []byte) (string, error) {
 	// Determine backup path
 	var backupPath string
 	if r.config.BackupDir != "" {
@@ -165,20 +235,62 @@ func (r *Remediator) createBackup(path string) (string, error) {
 		backupPath = fmt.Sprintf("%s.%s.bak", path, timestamp)
 	}
 
-	// Write backup
-	if err := os.WriteFile(backupPath, content, 0600); err != nil {
-		return "", fmt.Errorf("writing backup file: %w", err)
-	}
+	return writeBackup(backupPath, content)
+}
 
-	return backupPath, nil
+// writeBackup writes a backup without overwriting another, numbering it
+// when files with the same name are backed up in the same second
+func writeBackup(backupPath string, content []byte) (string, error) {
+	candidate := backupPath
+	for i := 1; ; i++ {
+		file, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 -- backup path built from the remediated file
+		if errors.Is(err, fs.ErrExist) {
+			candidate = fmt.Sprintf("%s.%d", backupPath, i)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("writing backup file: %w", err)
+		}
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("writing backup file: %w", err)
+		}
+		return candidate, nil
+	}
 }
 
-// RemediateDirectory remediates all files in a directory
+// RemediateDirectory remediates all files in a directory, several at once.
+// Results are sent as each file is done, so in no particular order.
 func (r *Remediator) RemediateDirectory(ctx context.Context, dir string) <-chan *RemediationResult {
 	results := make(chan *RemediationResult, 100)
+	paths := make(chan string)
+
+	workers := r.config.Workers
+	if workers <= 0 {
+		workers = DefaultRemediationWorkers
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				results <- r.RemediateFile(ctx, path)
+			}
+		}()
+	}
+	// Workers finish after the walk closes paths, so nothing is sent once
+	// they are done
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
 	go func() {
-		defer close(results)
+		defer close(paths)
 
 		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 			select {
@@ -205,9 +317,12 @@ func (r *Remediator) RemediateDirectory(ctx context.Context, dir string) <-chan
 				return nil
 			}
 
-			result := r.RemediateFile(ctx, path)
-			results <- result
-			return nil
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		})
 
 		if err != nil && !errors.Is(err, context.Canceled) {
@@ -220,11 +335,12 @@ func (r *Remediator) RemediateDirectory(ctx context.Context, dir string) <-chan
 
 // RemediationStats holds remediation statistics
 type RemediationStats struct {
-	Total      int
-	Remediated int
-	Skipped    int
-	Failed     int
-	Unknown    int
+	Total       int
+	Remediated  int
+	Quarantined int
+	Skipped     int
+	Failed      int
+	Unknown     int
 }
 
 // CollectResults collects results and computes statistics
@@ -238,6 +354,8 @@ func CollectResults(results <-chan *RemediationResult) ([]*RemediationResult, *R
 
 		if result.Error != nil {
 			stats.Failed++
+		} else if result.Quarantined {
+			stats.Quarantined++
 		} else if !result.Known {
 			stats.Unknown++
 		} else if result.Remediated {
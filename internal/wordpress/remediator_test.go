@@ -0,0 +1,71 @@
+package wordpress
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/wordfence-go/internal/logging"
+)
+
+// slowSource returns fixed content, tracking how many calls overlap
+type slowSource struct {
+	inFlight, peak atomic.Int32
+}
+
+func (s *slowSource) GetCorrectContent(_ context.Context, _ *FileIdentity) ([]byte, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return []byte("<?php // clean"), nil
+}
+
+func TestRemediateDirectoryInParallel(t *testing.T) {
+	dir := createMockWordPressSite(t)
+	for _, name := range []string{"load.php", "plugin.php", "post.php", "query.php", "user.php"} {
+		if err := os.WriteFile(filepath.Join(dir, "wp-includes", name), []byte("<?php // infected"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source := &slowSource{}
+	remediator := NewRemediator(source, &RemediatorConfig{Workers: 3})
+	remediator.SetLogger(logging.New(logging.LevelCritical))
+
+	results, stats := CollectResults(remediator.RemediateDirectory(context.Background(), filepath.Join(dir, "wp-includes")))
+	if stats.Total == 0 || stats.Remediated != stats.Total {
+		t.Fatalf("expected every file remediated, got %+v", stats)
+	}
+	if peak := source.peak.Load(); peak < 2 || peak > 3 {
+		t.Errorf("expected 2-3 files remediated at once, got %d", peak)
+	}
+	for _, r := range results {
+		content, err := os.ReadFile(r.Path)
+		if err != nil || string(content) != "<?php // clean" {
+			t.Errorf("%s not remediated: %q, %v", r.Path, content, err)
+		}
+	}
+}
+
+func TestWriteBackupKeepsExisting(t *testing.T) {
+	backupPath := filepath.Join(t.TempDir(), "load.php.bak")
+	first, err := writeBackup(backupPath, []byte("first"))
+	if err != nil || first != backupPath {
+		t.Fatalf("expected %s, got %s (%v)", backupPath, first, err)
+	}
+	second, err := writeBackup(backupPath, []byte("second"))
+	if err != nil || second != backupPath+".1" {
+		t.Fatalf("expected %s.1, got %s (%v)", backupPath, second, err)
+	}
+	if content, _ := os.ReadFile(first); string(content) != "first" {
+		t.Errorf("first backup overwritten with %q", content)
+	}
+}
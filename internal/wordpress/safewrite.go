@@ -0,0 +1,117 @@
+// Package wordpress provides crash-safe replacement of remediated files
+package wordpress
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// replaceFile replaces path with content without ever leaving it truncated:
+// the content is written to a temporary file beside it, synced, given the
+// original's mode, owner and SELinux context, and renamed over it. When
+// the owner can't be kept, because only root can give files away, the file
+// is rewritten in place instead, as it is when the directory isn't
+// writable; the caller's backup covers a crash part way through. A missing
+// file is created with mode.
+func replaceFile(path string, content []byte, mode fs.FileMode) error {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	var tmpPath string
+	if info == nil {
+		tmpPath, err = writeTemp(path, content, mode)
+	} else {
+		tmpPath, err = stageFile(path, content, info)
+		if errors.Is(err, fs.ErrPermission) {
+			return rewriteFile(path, content)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// stageFile writes content to a temporary file beside path with the mode,
+// owner and SELinux context of the original, described by info
+func stageFile(path string, content []byte, info fs.FileInfo) (string, error) {
+	tmpPath, err := writeTemp(path, content, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	if err := copyOwner(tmpPath, info); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("keeping owner of %s: %w", path, err)
+	}
+	if err := copySecurityContext(path, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("keeping SELinux context of %s: %w", path, err)
+	}
+	return tmpPath, nil
+}
+
+// writeTemp writes and syncs content to a temporary file in path's
+// directory
+func writeTemp(path string, content []byte, mode fs.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wordfence-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// rewriteFile overwrites a file in place and syncs it, keeping its inode
+// and so its owner, mode and context
+func rewriteFile(path string, content []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0) // #nosec G304 -- file being remediated
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// syncDir syncs a directory so a rename in it survives a crash. Not every
+// platform can open directories for syncing, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir) // #nosec G304 -- directory of the replaced file
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,45 @@
+package wordpress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceFileKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "load.php")
+	if err := os.WriteFile(path, []byte("<?php // infected, and longer than the clean file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := replaceFile(path, []byte("<?php // clean"), 0o600); err != nil {
+		t.Fatalf("replaceFile failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("expected mode 0644 kept, got %v", info.Mode().Perm())
+	}
+	if content, _ := os.ReadFile(path); string(content) != "<?php // clean" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	created := filepath.Join(dir, "new.php")
+	if err := replaceFile(created, []byte("<?php"), 0o600); err != nil {
+		t.Fatalf("replaceFile of a missing file failed: %v", err)
+	}
+	if info, err := os.Stat(created); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected new file with mode 0600, got %v (%v)", info, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
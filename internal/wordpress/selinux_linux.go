@@ -0,0 +1,37 @@
+//go:build linux
+
+// Package wordpress provides keeping the SELinux context of remediated
+// files
+package wordpress
+
+import (
+	"errors"
+	"syscall"
+)
+
+// selinuxXattr holds a file's SELinux security context
+const selinuxXattr = "security.selinux"
+
+// copySecurityContext gives dst the SELinux context of src. Files without
+// one, and filesystems without extended attributes, are left alone.
+func copySecurityContext(src, dst string) error {
+	buf := make([]byte, 256)
+	n, err := syscall.Getxattr(src, selinuxXattr, buf)
+	if errors.Is(err, syscall.ERANGE) {
+		if n, err = syscall.Getxattr(src, selinuxXattr, nil); err == nil {
+			buf = make([]byte, n)
+			n, err = syscall.Getxattr(src, selinuxXattr, buf)
+		}
+	}
+	if errors.Is(err, syscall.ENODATA) || errors.Is(err, syscall.ENOTSUP) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = syscall.Setxattr(dst, selinuxXattr, buf[:n], 0)
+	if errors.Is(err, syscall.ENOTSUP) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+// Package wordpress provides keeping the SELinux context of remediated
+// files on platforms without SELinux
+package wordpress
+
+// copySecurityContext does nothing on platforms without SELinux
+func copySecurityContext(_, _ string) error {
+	return nil
+}
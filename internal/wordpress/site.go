@@ -7,7 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Expected files and directories for WordPress core
@@ -27,6 +30,7 @@ type Site struct {
 	Path        string
 	CorePath    string
 	ContentPath string
+	PluginsPath string
 	Version     string
 	Plugins     []*Plugin
 	Themes      []*Theme
@@ -51,12 +55,14 @@ func Detect(path string) (*Site, error) {
 	return DetectWithOptions(path)
 }
 
-// DetectWithOptions detects a WordPress installation with options
+// DetectWithOptions detects a WordPress installation with options. The
+// path may also be a project root whose wp-cli.yml points to core.
 func DetectWithOptions(path string, opts ...SiteOption) (*Site, error) {
 	cfg := &siteConfig{}
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	path = ResolveCorePath(path)
 
 	// Check if this is a WordPress core directory
 	if !isCoreDirectory(path) {
@@ -68,18 +74,7 @@ func DetectWithOptions(path string, opts ...SiteOption) (*Site, error) {
 		CorePath: path,
 	}
 
-	// Determine content path
-	site.ContentPath = filepath.Join(path, "wp-content")
-	if _, err := os.Stat(site.ContentPath); os.IsNotExist(err) {
-		// Try alternate content paths
-		for _, alt := range []string{"../app", "../content"} {
-			altPath := filepath.Join(path, alt)
-			if _, err := os.Stat(altPath); err == nil {
-				site.ContentPath = altPath
-				break
-			}
-		}
-	}
+	site.ContentPath, site.PluginsPath = contentPaths(path)
 
 	// Get WordPress version
 	version, err := parseWordPressVersion(path)
@@ -88,9 +83,8 @@ func DetectWithOptions(path string, opts ...SiteOption) (*Site, error) {
 	}
 
 	// Load plugins
-	pluginsDir := filepath.Join(site.ContentPath, "plugins")
-	if _, err := os.Stat(pluginsDir); err == nil {
-		loader := NewPluginLoader(pluginsDir)
+	if _, err := os.Stat(site.PluginsPath); err == nil {
+		loader := NewPluginLoader(site.PluginsPath)
 		site.Plugins, _ = loader.LoadAll()
 	}
 
@@ -151,10 +145,41 @@ func parseWordPressVersion(corePath string) (string, error) {
 	return "", fmt.Errorf("version not found in version.php")
 }
 
+// DefaultLocatorWorkers is the default number of directories read at once
+const DefaultLocatorWorkers = 8
+
+// locateProgressInterval is how many directories are searched between
+// progress reports
+const locateProgressInterval = 1000
+
+// DefaultSkipDirs are directory names that never contain a WordPress
+// installation worth searching for, and are often huge
+var DefaultSkipDirs = []string{
+	".git",
+	".svn",
+	".hg",
+	"node_modules",
+	".cache",
+	".npm",
+	".composer",
+	"__pycache__",
+}
+
+// LocateProgress reports how far a search has got
+type LocateProgress struct {
+	Directories int    // Directories searched so far
+	Sites       int    // Installations found so far
+	Path        string // Directory just searched
+}
+
 // Locator finds WordPress installations in a directory tree
 type Locator struct {
 	allowNested   bool
 	allowIOErrors bool
+	maxDepth      int
+	workers       int
+	skipDirs      map[string]bool
+	progress      func(LocateProgress)
 }
 
 // LocatorOption configures a Locator
@@ -174,60 +199,211 @@ func WithLocatorAllowIOErrors(allow bool) LocatorOption {
 	}
 }
 
+// WithMaxDepth sets how many levels below the search path are searched;
+// 0 means no limit
+func WithMaxDepth(depth int) LocatorOption {
+	return func(l *Locator) {
+		l.maxDepth = depth
+	}
+}
+
+// WithLocatorWorkers sets how many directories are read at once
+func WithLocatorWorkers(workers int) LocatorOption {
+	return func(l *Locator) {
+		if workers > 0 {
+			l.workers = workers
+		}
+	}
+}
+
+// WithSkipDirs sets the directory names that aren't searched, replacing
+// DefaultSkipDirs
+func WithSkipDirs(names []string) LocatorOption {
+	return func(l *Locator) {
+		l.skipDirs = make(map[string]bool, len(names))
+		for _, name := range names {
+			l.skipDirs[name] = true
+		}
+	}
+}
+
+// WithLocateProgress sets a function called every few thousand
+// directories and whenever an installation is found. Calls are not
+// concurrent.
+func WithLocateProgress(fn func(LocateProgress)) LocatorOption {
+	return func(l *Locator) {
+		l.progress = fn
+	}
+}
+
 // NewLocator creates a new WordPress locator
 func NewLocator(opts ...LocatorOption) *Locator {
 	l := &Locator{
 		allowNested:   true,
 		allowIOErrors: false,
+		workers:       DefaultLocatorWorkers,
 	}
+	WithSkipDirs(DefaultSkipDirs)(l)
 	for _, opt := range opts {
 		opt(l)
 	}
 	return l
 }
 
-// Locate finds all WordPress installations under the given path
+// Locate finds all WordPress installations under the given path, sorted
+// by path. Directories are read concurrently, each once: the core files
+// are looked for among its entries, so only likely installations are
+// examined further. Symbolic links are not followed.
 func (l *Locator) Locate(path string) ([]*Site, error) {
-	var sites []*Site
-	visited := make(map[string]bool)
+	info, err := os.Stat(path)
+	if err != nil {
+		if l.allowIOErrors {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("walking directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, nil
+	}
+
+	w := &locateWalk{locator: l, queue: []locateDir{{path: path}}, pending: 1}
+	w.cond = sync.NewCond(&w.mu)
+
+	var wg sync.WaitGroup
+	for i := 0; i < l.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				dir, ok := w.next()
+				if !ok {
+					return
+				}
+				subdirs, site, err := l.searchDir(dir)
+				w.done(dir, subdirs, site, err)
+			}
+		}()
+	}
+	wg.Wait()
 
-	err := filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
-		if err != nil {
-			if l.allowIOErrors {
-				return nil
+	sort.Slice(w.sites, func(i, j int) bool {
+		return w.sites[i].Path < w.sites[j].Path
+	})
+	if w.err != nil {
+		return w.sites, fmt.Errorf("walking directory: %w", w.err)
+	}
+	return w.sites, nil
+}
+
+// searchDir reads one directory, returning the subdirectories to search
+// and the installation there, if any
+func (l *Locator) searchDir(dir locateDir) ([]locateDir, *Site, error) {
+	entries, err := os.ReadDir(dir.path)
+	if err != nil && !l.allowIOErrors {
+		return nil, nil, err
+	}
+
+	var site *Site
+	if hasCoreEntries(entries) && isCoreDirectory(dir.path) {
+		if detected, err := DetectWithOptions(dir.path, WithAllowIOErrors(l.allowIOErrors)); err == nil {
+			site = detected
+			if !l.allowNested {
+				return nil, site, nil
 			}
-			return err
 		}
+	}
+	if l.maxDepth > 0 && dir.depth >= l.maxDepth {
+		return nil, site, nil
+	}
 
-		if !d.IsDir() {
-			return nil
+	var subdirs []locateDir
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || l.skipDirs[name] {
+			continue
+		}
+		// Core directories never hold another installation
+		if site != nil && (name == "wp-admin" || name == "wp-includes") {
+			continue
 		}
+		subdirs = append(subdirs, locateDir{path: filepath.Join(dir.path, name), depth: dir.depth + 1})
+	}
+	return subdirs, site, nil
+}
 
-		// Skip already visited
-		absPath, _ := filepath.Abs(p)
-		if visited[absPath] {
-			return filepath.SkipDir
+// hasCoreEntries reports whether a directory's entries include every
+// expected core file and directory name, before they are stat'ed
+func hasCoreEntries(entries []os.DirEntry) bool {
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+	for _, name := range ExpectedCoreFiles {
+		if !names[name] {
+			return false
 		}
-		visited[absPath] = true
-
-		// Check if this is a WordPress installation
-		if isCoreDirectory(p) {
-			site, err := DetectWithOptions(p, WithAllowIOErrors(l.allowIOErrors))
-			if err == nil {
-				sites = append(sites, site)
-				if !l.allowNested {
-					return filepath.SkipDir
-				}
-			}
+	}
+	for _, name := range ExpectedCoreDirs {
+		if !names[name] {
+			return false
 		}
+	}
+	return true
+}
 
-		return nil
-	})
+// locateDir is a directory waiting to be searched
+type locateDir struct {
+	path  string
+	depth int
+}
 
-	if err != nil {
-		return sites, fmt.Errorf("walking directory: %w", err)
+// locateWalk is the shared state of one Locate call
+type locateWalk struct {
+	locator *Locator
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []locateDir
+	pending int // Directories queued or being searched
+	dirs    int
+	sites   []*Site
+	err     error
+}
+
+// next returns a directory to search, waiting while other workers may
+// still find more. It returns false once the search is over.
+func (w *locateWalk) next() (locateDir, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 && w.err == nil {
+		w.cond.Wait()
 	}
-	return sites, nil
+	if w.err != nil || len(w.queue) == 0 {
+		return locateDir{}, false
+	}
+	// Depth first, like filepath.WalkDir, keeps the queue short
+	dir := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return dir, true
+}
+
+// done records the result of searching a directory
+func (w *locateWalk) done(dir locateDir, subdirs []locateDir, site *Site, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending += len(subdirs) - 1
+	w.queue = append(w.queue, subdirs...)
+	w.dirs++
+	if site != nil {
+		w.sites = append(w.sites, site)
+	}
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	if w.locator.progress != nil && (site != nil || w.dirs%locateProgressInterval == 0) {
+		w.locator.progress(LocateProgress{Directories: w.dirs, Sites: len(w.sites), Path: dir.path})
+	}
+	w.cond.Broadcast()
 }
 
 // Extension represents a WordPress extension (plugin or theme)
@@ -237,6 +413,9 @@ type Extension struct {
 	Version string
 	Path    string
 	Header  map[string]string
+
+	Activity Activity  // Whether the site has it enabled, if known
+	Modified time.Time // Newest file modification time, once loaded
 }
 
 // GetHeader returns a header value
@@ -187,6 +187,66 @@ func TestLocatorNestedSites(t *testing.T) {
 	}
 }
 
+//nolint:gosec // test file using temp directories
+func TestLocatorPrunesAndCapsDepth(t *testing.T) {
+	root := t.TempDir()
+	makeSite := func(dir string) {
+		t.Helper()
+		for _, d := range []string{"wp-admin", "wp-includes"} {
+			if err := os.MkdirAll(filepath.Join(dir, d), 0750); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, f := range []string{"wp-blog-header.php", "wp-load.php"} {
+			if err := os.WriteFile(filepath.Join(dir, f), []byte("<?php"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	makeSite(filepath.Join(root, "a"))
+	makeSite(filepath.Join(root, "b", "deep", "site"))
+	makeSite(filepath.Join(root, "node_modules", "fixture"))
+	makeSite(filepath.Join(root, ".git", "fixture"))
+
+	var progress []LocateProgress
+	sites, err := NewLocator(WithLocateProgress(func(p LocateProgress) {
+		progress = append(progress, p)
+	})).Locate(root)
+	if err != nil {
+		t.Fatalf("failed to locate WordPress: %v", err)
+	}
+	if len(sites) != 2 || sites[0].Path != filepath.Join(root, "a") || sites[1].Path != filepath.Join(root, "b", "deep", "site") {
+		t.Errorf("expected the sites outside skipped directories, got %v", sitePaths(sites))
+	}
+	if len(progress) != 2 || progress[1].Sites != 2 {
+		t.Errorf("expected progress for each site found, got %+v", progress)
+	}
+
+	sites, err = NewLocator(WithMaxDepth(2)).Locate(root)
+	if err != nil {
+		t.Fatalf("failed to locate WordPress: %v", err)
+	}
+	if len(sites) != 1 || sites[0].Path != filepath.Join(root, "a") {
+		t.Errorf("expected only the shallow site, got %v", sitePaths(sites))
+	}
+
+	sites, err = NewLocator(WithSkipDirs(nil), WithLocatorWorkers(1)).Locate(root)
+	if err != nil {
+		t.Fatalf("failed to locate WordPress: %v", err)
+	}
+	if len(sites) != 4 {
+		t.Errorf("expected every site without skipped directories, got %v", sitePaths(sites))
+	}
+}
+
+func sitePaths(sites []*Site) []string {
+	paths := make([]string, len(sites))
+	for i, site := range sites {
+		paths[i] = site.Path
+	}
+	return paths
+}
+
 func TestPluginLoader(t *testing.T) {
 	wpDir := createMockWordPressSite(t)
 	pluginsDir := filepath.Join(wpDir, "wp-content", "plugins")
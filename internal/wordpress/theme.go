@@ -57,7 +57,7 @@ func (l *ThemeLoader) LoadAll() ([]*Theme, error) {
 
 	for _, entry := range entries {
 		// Skip hidden files and non-directories
-		if strings.HasPrefix(entry.Name(), ".") || !entry.IsDir() {
+		if strings.HasPrefix(entry.Name(), ".") || !entryIsDir(l.directory, entry) {
 			continue
 		}
 
@@ -0,0 +1,132 @@
+// Package wordpress provides wp-config.php parsing
+package wordpress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultTablePrefix is WordPress's default database table prefix
+const DefaultTablePrefix = "wp_"
+
+var (
+	// defineRegex matches define('NAME', value) with a quoted or bare value
+	defineRegex = regexp.MustCompile(`(?m)^\s*define\s*\(\s*['"]([A-Za-z0-9_]+)['"]\s*,\s*('(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"|[^)\s]+)\s*\)`)
+
+	// defineExprRegex matches define('NAME', expression);
+	defineExprRegex = regexp.MustCompile(`(?m)^\s*define\s*\(\s*['"]([A-Za-z0-9_]+)['"]\s*,\s*(.+?)\s*\)\s*;`)
+
+	// tablePrefixRegex matches the $table_prefix assignment
+	tablePrefixRegex = regexp.MustCompile(`(?m)^\s*\$table_prefix\s*=\s*['"]([^'"]*)['"]`)
+
+	// validTablePrefix guards prefixes interpolated into SQL
+	validTablePrefix = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// WPConfig holds settings read from wp-config.php
+type WPConfig struct {
+	Path        string
+	DBName      string
+	DBUser      string
+	DBPassword  string
+	DBHost      string
+	TablePrefix string
+
+	// Constants holds every define()d value; quoted strings are unquoted
+	// and bare values (true, false, numbers) are kept verbatim
+	Constants map[string]string
+
+	// Expressions holds the PHP source of every define()d value, for
+	// values such as __DIR__ . '/content' that Constants can't hold
+	Expressions map[string]string
+}
+
+// FindWPConfig returns the wp-config.php for a site. Like WordPress, it
+// also looks one directory above the site root, unless that directory is
+// another installation.
+func FindWPConfig(sitePath string) (string, error) {
+	if candidate := filepath.Join(sitePath, "wp-config.php"); isRegularFile(candidate) {
+		return candidate, nil
+	}
+	parent := filepath.Dir(filepath.Clean(sitePath))
+	if candidate := filepath.Join(parent, "wp-config.php"); isRegularFile(candidate) && !isRegularFile(filepath.Join(parent, "wp-settings.php")) {
+		return candidate, nil
+	}
+	return "", fmt.Errorf("wp-config.php not found for %s", sitePath)
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// ParseWPConfig reads the settings from a wp-config.php file without
+// executing it
+func ParseWPConfig(path string) (*WPConfig, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- wp-config.php of the audited site
+	if err != nil {
+		return nil, fmt.Errorf("reading wp-config.php: %w", err)
+	}
+
+	cfg := &WPConfig{
+		Path:        path,
+		TablePrefix: DefaultTablePrefix,
+		Constants:   make(map[string]string),
+		Expressions: make(map[string]string),
+	}
+
+	for _, m := range defineRegex.FindAllStringSubmatch(string(data), -1) {
+		cfg.Constants[m[1]] = unquotePHP(m[2])
+	}
+	for _, m := range defineExprRegex.FindAllStringSubmatch(string(data), -1) {
+		cfg.Expressions[m[1]] = m[2]
+	}
+	cfg.DBName = cfg.Constants["DB_NAME"]
+	cfg.DBUser = cfg.Constants["DB_USER"]
+	cfg.DBPassword = cfg.Constants["DB_PASSWORD"]
+	cfg.DBHost = cfg.Constants["DB_HOST"]
+
+	if m := tablePrefixRegex.FindStringSubmatch(string(data)); m != nil {
+		cfg.TablePrefix = m[1]
+	}
+
+	return cfg, nil
+}
+
+// Bool returns whether a constant is defined as a truthy value
+func (c *WPConfig) Bool(name string) bool {
+	switch strings.ToLower(c.Constants[name]) {
+	case "true", "1", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// ValidTablePrefix reports whether the table prefix is safe to use in SQL
+func (c *WPConfig) ValidTablePrefix() bool {
+	return validTablePrefix.MatchString(c.TablePrefix)
+}
+
+// unquotePHP strips quotes and backslash escapes from a PHP string literal
+func unquotePHP(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	quote := value[0]
+	if (quote != '\'' && quote != '"') || value[len(value)-1] != quote {
+		return value
+	}
+
+	inner := value[1 : len(value)-1]
+	var sb strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) && (inner[i+1] == quote || inner[i+1] == '\\') {
+			i++
+		}
+		sb.WriteByte(inner[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package wordpress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseWPConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wp-config.php")
+	content := `<?php
+define( 'DB_NAME', 'wordpress' );
+define('DB_USER', "wp_user");
+define( 'DB_PASSWORD', 'p@ss\'word' );
+define( 'DB_HOST', 'localhost:3307' );
+define( 'WP_DEBUG', true );
+define( 'DISALLOW_FILE_EDIT', false );
+$table_prefix = 'wp7_';
+require_once ABSPATH . 'wp-settings.php';
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ParseWPConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.DBName != "wordpress" || cfg.DBUser != "wp_user" || cfg.DBHost != "localhost:3307" {
+		t.Errorf("unexpected database settings: %+v", cfg)
+	}
+	if cfg.DBPassword != "p@ss'word" {
+		t.Errorf("expected unescaped password, got %q", cfg.DBPassword)
+	}
+	if cfg.TablePrefix != "wp7_" || !cfg.ValidTablePrefix() {
+		t.Errorf("expected valid prefix wp7_, got %q", cfg.TablePrefix)
+	}
+	if !cfg.Bool("WP_DEBUG") || cfg.Bool("DISALLOW_FILE_EDIT") || cfg.Bool("UNDEFINED") {
+		t.Error("unexpected boolean constant values")
+	}
+}
+
+func TestFindWPConfigParentDirectory(t *testing.T) {
+	root := t.TempDir()
+	site := filepath.Join(root, "public_html")
+	if err := os.Mkdir(site, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "wp-config.php"), []byte("<?php"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := FindWPConfig(site)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(root, "wp-config.php") {
+		t.Errorf("expected parent wp-config.php, got %s", path)
+	}
+}
@@ -0,0 +1,68 @@
+package wordpress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/greysquirr3l/wordfence-go/internal/api/wporg"
+	"github.com/greysquirr3l/wordfence-go/internal/cache"
+)
+
+func TestWPOrgRemediationSource(t *testing.T) {
+	corePackage := buildPackage(t, "wordpress", map[string]string{"wp-includes/load.php": "<?php // load"})
+	pluginPackage := buildPackage(t, "akismet", map[string]string{"akismet.php": "<?php // akismet", "tampered.php": "<?php // x"})
+	themePackage := buildPackage(t, "astra", map[string]string{"functions.php": "<?php // astra"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/release/wordpress-6.5.zip", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(corePackage) })
+	mux.HandleFunc("/plugin/akismet.5.3.zip", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(pluginPackage) })
+	mux.HandleFunc("/theme/astra.4.6.zip", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(themePackage) })
+	mux.HandleFunc("/core/checksums/1.0/", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"checksums": map[string]string{"wp-includes/load.php": md5Hex([]byte("<?php // load"))},
+		})
+	})
+	mux.HandleFunc("/plugin-checksums/akismet/5.3.json", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"files": map[string]interface{}{
+				"akismet.php":  map[string]interface{}{"sha256": []string{"0000", sha256Hex([]byte("<?php // akismet"))}},
+				"tampered.php": map[string]interface{}{"sha256": "0000"},
+			},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := wporg.NewClient(wporg.WithBaseURL(srv.URL), wporg.WithDownloadsURL(srv.URL))
+	source := NewWPOrgRemediationSource(client, wporg.NewPackageLoader(client, cache.WithContext(cache.NewNoOpCache())))
+	plugin := &Plugin{Extension: Extension{Slug: "akismet", Version: "5.3"}}
+	theme := &Theme{Extension: Extension{Slug: "astra", Version: "4.6"}}
+
+	tests := []struct {
+		name     string
+		identity *FileIdentity
+		want     string
+	}{
+		{"core", &FileIdentity{Type: FileTypeCore, LocalPath: "wp-includes/load.php", CoreVersion: "6.5"}, "<?php // load"},
+		{"plugin", &FileIdentity{Type: FileTypePlugin, LocalPath: "akismet.php", Extension: plugin}, "<?php // akismet"},
+		{"theme", &FileIdentity{Type: FileTypeTheme, LocalPath: "functions.php", Extension: theme}, "<?php // astra"},
+	}
+	for _, tt := range tests {
+		content, err := source.GetCorrectContent(context.Background(), tt.identity)
+		if err != nil || string(content) != tt.want {
+			t.Errorf("%s: expected %q, got %q (%v)", tt.name, tt.want, content, err)
+		}
+	}
+
+	_, err := source.GetCorrectContent(context.Background(), &FileIdentity{Type: FileTypePlugin, LocalPath: "tampered.php", Extension: plugin})
+	if !errors.Is(err, errChecksumMismatch) {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := source.GetCorrectContent(context.Background(), &FileIdentity{Type: FileTypePlugin, LocalPath: "missing.php", Extension: plugin}); err == nil {
+		t.Error("expected an error for a file not in the release")
+	}
+}